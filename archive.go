@@ -0,0 +1,165 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ArchiveMigrationReader discovers migrations inside a .tar, .tar.gz/.tgz or
+// .sql.gz file. Entries are read into memory, so nothing is extracted to disk.
+type ArchiveMigrationReader struct {
+	options migrationParseOptions
+}
+
+func NewArchiveMigrationReader(opts ...MigrationReaderOption) MigrationReader {
+	return &ArchiveMigrationReader{options: newMigrationParseOptions(opts)}
+}
+
+// isMigrationArchive reports whether path has an extension the archive reader
+// understands
+func isMigrationArchive(path string) bool {
+	for _, ext := range []string{".tar", ".tar.gz", ".tgz", ".sql.gz"} {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// DiscoverMigrations reads the migrations contained in the archive at
+// archivePath. UpFile and DownFile are set to "<archive>:<entry>" so that
+// reports point at the original entry.
+func (r *ArchiveMigrationReader) DiscoverMigrations(archivePath string) ([]Migration, error) {
+	slog.Debug("reading migration archive", "archive", archivePath)
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migration archive: %w", err)
+	}
+	defer f.Close()
+
+	var reader io.Reader = f
+	if strings.HasSuffix(archivePath, ".gz") || strings.HasSuffix(archivePath, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip archive %s: %w", archivePath, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	if strings.HasSuffix(archivePath, ".sql.gz") {
+		return readGzippedMigration(archivePath, reader)
+	}
+
+	migrations := make(map[string]*Migration)
+	upEntries := make(map[string][]string)
+	downEntries := make(map[string][]string)
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar archive %s: %w", archivePath, err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		baseName, up, ok := migrationFileName(path.Base(header.Name))
+		if !ok {
+			continue
+		}
+
+		entry := archivePath + ":" + header.Name
+		migration, exists := migrations[baseName]
+		if !exists {
+			migration = &Migration{Name: baseName}
+			migrations[baseName] = migration
+		}
+
+		if up {
+			upEntries[baseName] = append(upEntries[baseName], header.Name)
+			if len(upEntries[baseName]) > 1 {
+				continue
+			}
+		} else {
+			downEntries[baseName] = append(downEntries[baseName], header.Name)
+			if len(downEntries[baseName]) > 1 {
+				continue
+			}
+		}
+
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive entry %s: %w", entry, err)
+		}
+
+		if up {
+			migration.UpFile = entry
+			migration.UpSQL = content
+			slog.Debug("found up migration", "name", baseName, "entry", header.Name)
+		} else {
+			migration.DownFile = entry
+			migration.DownSQL = content
+			slog.Debug("found down migration", "name", baseName, "entry", header.Name)
+		}
+	}
+
+	if err := checkMigrationNames(upEntries, downEntries, func(name string) string {
+		return archivePath + ":" + name
+	}, r.options); err != nil {
+		return nil, err
+	}
+
+	var result []Migration
+	for _, migration := range migrations {
+		if migration.UpFile == "" {
+			continue
+		}
+		result = append(result, *migration)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	slog.Info("parsed migrations from archive", "archive", archivePath, "count", len(result))
+	return result, nil
+}
+
+// readGzippedMigration reads a single gzip-compressed up migration. The
+// migration name is the file name without ".sql.gz" (or ".up.sql.gz").
+func readGzippedMigration(archivePath string, r io.Reader) ([]Migration, error) {
+	fileName := strings.TrimSuffix(filepath.Base(archivePath), ".gz")
+	baseName, up, ok := migrationFileName(fileName)
+	if !ok {
+		baseName, up = strings.TrimSuffix(fileName, ".sql"), true
+	}
+	if !up {
+		return nil, nil
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gzip archive %s: %w", archivePath, err)
+	}
+
+	return []Migration{{
+		Name:   baseName,
+		UpFile: archivePath,
+		UpSQL:  content,
+	}}, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestArchive(t *testing.T, name string, files map[string]string, compress bool) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "migrations/", Typeflag: tar.TypeDir, Mode: 0755}))
+	for fileName, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     fileName,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(content)),
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	data := buf.Bytes()
+	if compress {
+		data = gzipBytes(t, data)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(archivePath, data, 0644))
+	return archivePath
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestArchiveMigrationReader(t *testing.T) {
+	files := map[string]string{
+		"migrations/002_posts.up.sql":    "create table posts (id int);",
+		"migrations/001_users.up.sql":    "create table users (id int);",
+		"migrations/001_users.down.sql":  "drop table users;",
+		"migrations/003_orphan.down.sql": "drop table orphan;",
+		"migrations/README.md":           "not a migration",
+	}
+
+	for _, tc := range []struct {
+		name     string
+		compress bool
+	}{
+		{name: "migrations.tar.gz", compress: true},
+		{name: "migrations.tgz", compress: true},
+		{name: "migrations.tar", compress: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			archivePath := writeTestArchive(t, tc.name, files, tc.compress)
+
+			migrations, err := NewArchiveMigrationReader().DiscoverMigrations(archivePath)
+			require.NoError(t, err)
+			require.Len(t, migrations, 2)
+
+			assert.Equal(t, "001_users", migrations[0].Name)
+			assert.Equal(t, archivePath+":migrations/001_users.up.sql", migrations[0].UpFile)
+			assert.Equal(t, archivePath+":migrations/001_users.down.sql", migrations[0].DownFile)
+			assert.Equal(t, "create table users (id int);", string(migrations[0].UpSQL))
+			assert.Equal(t, "drop table users;", string(migrations[0].DownSQL))
+
+			assert.Equal(t, "002_posts", migrations[1].Name)
+			assert.Empty(t, migrations[1].DownFile)
+
+			content, err := migrations[1].readUpSQL()
+			require.NoError(t, err)
+			assert.Equal(t, "create table posts (id int);", string(content))
+		})
+	}
+
+	t.Run("sql_gz", func(t *testing.T) {
+		archivePath := filepath.Join(t.TempDir(), "001_init.sql.gz")
+		require.NoError(t, os.WriteFile(archivePath, gzipBytes(t, []byte("create table t (id int);")), 0644))
+
+		migrations, err := NewArchiveMigrationReader().DiscoverMigrations(archivePath)
+		require.NoError(t, err)
+		require.Len(t, migrations, 1)
+		assert.Equal(t, "001_init", migrations[0].Name)
+		assert.Equal(t, "create table t (id int);", string(migrations[0].UpSQL))
+	})
+
+	t.Run("corrupt_archive", func(t *testing.T) {
+		archivePath := filepath.Join(t.TempDir(), "broken.tar.gz")
+		require.NoError(t, os.WriteFile(archivePath, []byte("not gzip"), 0644))
+
+		_, err := NewArchiveMigrationReader().DiscoverMigrations(archivePath)
+		assert.Error(t, err)
+	})
+
+	t.Run("missing_archive", func(t *testing.T) {
+		_, err := NewArchiveMigrationReader().DiscoverMigrations("/non/existent/migrations.tar.gz")
+		assert.Error(t, err)
+	})
+}
+
+func TestArchiveMigrationReaderDuplicateNames(t *testing.T) {
+	files := map[string]string{
+		"v1/001_users.up.sql": "create table users (id int);",
+		"v2/001_users.up.sql": "create table users (id bigint);",
+	}
+	archivePath := writeTestArchive(t, "migrations.tar", files, false)
+
+	_, err := NewArchiveMigrationReader().DiscoverMigrations(archivePath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate migration names: 001_users")
+	assert.Contains(t, err.Error(), archivePath+":v1/001_users.up.sql")
+
+	migrations, err := NewArchiveMigrationReader(WithDuplicateWarnings()).DiscoverMigrations(archivePath)
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	assert.Equal(t, "001_users", migrations[0].Name)
+}
+
+func TestMigrationSource(t *testing.T) {
+	defer func() { migrationsArchive = "" }()
+
+	dir, reader, err := migrationSource([]string{"./migrations"})
+	require.NoError(t, err)
+	assert.Equal(t, "./migrations", dir)
+	assert.IsType(t, &FileMigrationReader{}, reader)
+
+	migrationsArchive = "release.tar.gz"
+	dir, reader, err = migrationSource(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "release.tar.gz", dir)
+	assert.IsType(t, &ArchiveMigrationReader{}, reader)
+
+	migrationsArchive = "release.zip"
+	_, _, err = migrationSource(nil)
+	assert.Error(t, err)
+}
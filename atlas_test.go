@@ -0,0 +1,101 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSchemaAtlasHCL(t *testing.T) {
+	expected := `table "users" {
+  schema = schema.public
+  column "id" {
+    null = false
+    type = serial
+  }
+  column "email" {
+    null = false
+    type = character_varying(320)
+  }
+  column "status" {
+    null    = false
+    type    = text
+    default = "active"
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+  primary_key {
+    columns = [column.id]
+  }
+  unique "users_email_key" {
+    columns = [column.email]
+  }
+}
+table "posts" {
+  schema = schema.public
+  column "id" {
+    null    = false
+    type    = uuid
+    default = sql("gen_random_uuid()")
+  }
+  column "user_id" {
+    null = false
+    type = integer
+  }
+  column "tags" {
+    null = true
+    type = sql("text[]")
+  }
+  column "location" {
+    null = true
+    type = sql("geometry(Point,4326)")
+  }
+  primary_key {
+    columns = [column.id]
+  }
+  foreign_key "posts_user_id_fkey" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+  index "idx_posts_user_id" {
+    columns = [column.user_id]
+  }
+}
+table "audit_log" {
+  schema = schema.public
+  column "message" {
+    null = false
+    type = text
+  }
+}
+schema "public" {
+}
+`
+	assert.Equal(t, expected, providers.FormatSchemaAtlasHCL(ormTestTables()))
+}
+
+func TestFormatSchemaAtlasHCLColumns(t *testing.T) {
+	tables := []providers.Table{{
+		Name: "accounts",
+		Columns: []providers.Column{
+			{Name: "user id", DataType: "integer", IsPrimaryKey: true},
+			{Name: "balance", DataType: "numeric", IsNullable: true, DefaultValue: defaultOf("0"),
+				NumericPrecision: sql.NullInt64{Int64: 12, Valid: true}, NumericScale: sql.NullInt64{Int64: 2, Valid: true}},
+		},
+		Indexes: []providers.Index{{Name: "idx_accounts_balance", Columns: []string{"balance", "user id"}}},
+	}}
+
+	output := providers.FormatSchemaAtlasHCL(tables)
+	// Names that are not identifiers are referenced by index expression
+	assert.Contains(t, output, "    columns = [column[\"user id\"]]\n")
+	assert.Contains(t, output, "    columns = [column.balance, column[\"user id\"]]\n")
+	// Numeric literals are written unquoted, with the precision in the type
+	assert.Contains(t, output, "    type    = numeric(12,2)\n    default = 0\n")
+}
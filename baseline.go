@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/alc6/mig2schema/providers"
+)
+
+// baselineSource returns the location of the --baseline migrations and the
+// reader for it: a migration directory, a migration archive or a single .sql
+// file
+func baselineSource(path string) (string, MigrationReader, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, withExitCode(exitMigrationDirNotFound, fmt.Errorf("baseline does not exist: %s", path))
+	}
+	switch {
+	case info.IsDir():
+		return path, NewFileMigrationReader(), nil
+	case isMigrationArchive(path):
+		return path, NewArchiveMigrationReader(), nil
+	case strings.HasSuffix(path, ".sql"):
+		return path, sqlFileMigrationReader{}, nil
+	default:
+		return "", nil, withExitCode(exitUsage, fmt.Errorf("unsupported baseline %s: expected a migration directory, a migration archive or a .sql file", path))
+	}
+}
+
+// sqlFileMigrationReader reads a single SQL file as one up migration named
+// after the file
+type sqlFileMigrationReader struct{}
+
+func (sqlFileMigrationReader) DiscoverMigrations(path string) ([]Migration, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return []Migration{{
+		Name:   strings.TrimSuffix(filepath.Base(path), ".sql"),
+		UpFile: path,
+		UpSQL:  content,
+	}}, nil
+}
+
+// loadBaseline runs the baseline migrations at path in a fresh database and
+// returns its schema, with the index filter and extension objects option of
+// the run applied so that they do not show up as changes
+func loadBaseline(ctx context.Context, path string, dbManager DatabaseManager) (*providers.Schema, error) {
+	params, err := extractParams()
+	if err != nil {
+		return nil, err
+	}
+
+	source, reader, err := baselineSource(path)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := reader.DiscoverMigrations(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse baseline migrations: %w", err)
+	}
+	if len(migrations) == 0 {
+		return nil, withExitCode(exitNoMigrations, fmt.Errorf("no migration files found in baseline: %s", path))
+	}
+
+	slog.Info("setting up baseline database", "baseline", path, "migrations", len(migrations))
+	if err := dbManager.Setup(ctx); err != nil {
+		return nil, withExitCode(exitSetupFailed, fmt.Errorf("failed to setup baseline database: %w", err))
+	}
+	defer func() {
+		if err := dbManager.Close(context.WithoutCancel(ctx)); err != nil {
+			slog.Error("failed to cleanup baseline database", "error", err)
+		}
+	}()
+
+	if err := dbManager.RunMigrations(ctx, migrations); err != nil {
+		return nil, withExitCode(exitMigrationFailed, fmt.Errorf("failed to run baseline migrations: %w", err))
+	}
+
+	schema, err := providers.ExtractFullSchemaWithOptions(dbManager.GetDB(), providers.ExtractOptions{
+		IncludeExtensionObjects: params.IncludeExtensionObjects,
+	})
+	if err != nil {
+		return nil, withExitCode(exitExtractionFailed, fmt.Errorf("failed to extract baseline schema: %w", err))
+	}
+	schema.Tables = params.IndexFilter.Apply(schema.Tables)
+	return &schema, nil
+}
+
+// baselineChanges is what a schema adds on top of a baseline schema
+type baselineChanges struct {
+	// Tables holds the tables the baseline does not have and the changes
+	// made to those it has. Baseline tables missing from the schema are not
+	// reported, they are not part of the result either way.
+	Tables providers.SchemaDiff
+	// Objects holds the other objects to create or replace
+	Objects baselineObjects
+}
+
+// baselineObjects are the objects other than tables that a schema adds on
+// top of a baseline: those the baseline does not have, and the functions and
+// views whose definition changed, which are replaced. EnumValues holds the
+// labels added to baseline enums. Lists are never null.
+type baselineObjects struct {
+	Extensions     []string                  `json:"extensions"`
+	Enums          []providers.Enum          `json:"enums"`
+	EnumValues     []providers.Enum          `json:"enum_values"`
+	CompositeTypes []providers.CompositeType `json:"composite_types"`
+	Sequences      []providers.Sequence      `json:"sequences"`
+	Functions      []providers.Function      `json:"functions"`
+	Views          []providers.View          `json:"views"`
+}
+
+// isEmpty reports whether the schema adds nothing to the baseline
+func (c baselineChanges) isEmpty() bool {
+	o := c.Objects
+	return c.Tables.IsEmpty() && len(o.Extensions) == 0 && len(o.Enums) == 0 && len(o.EnumValues) == 0 &&
+		len(o.CompositeTypes) == 0 && len(o.Sequences) == 0 && len(o.Functions) == 0 && len(o.Views) == 0
+}
+
+// subtractBaseline returns what schema adds on top of baseline. Tables are
+// compared as by --diff-from and other objects by name. Changes that cannot
+// be expressed on top of the baseline, such as a composite type with other
+// attributes or an enum that lost labels, are an error.
+func subtractBaseline(baseline, schema providers.Schema) (baselineChanges, error) {
+	diff := providers.DiffSchemas(baseline.Tables, schema.Tables)
+	diff.RemovedTables = nil
+	changes := baselineChanges{Tables: diff, Objects: baselineObjects{
+		Extensions:     []string{},
+		Enums:          []providers.Enum{},
+		EnumValues:     []providers.Enum{},
+		CompositeTypes: []providers.CompositeType{},
+		Sequences:      []providers.Sequence{},
+		Functions:      []providers.Function{},
+		Views:          []providers.View{},
+	}}
+	objects := &changes.Objects
+
+	for _, extension := range schema.Extensions {
+		if !slices.Contains(baseline.Extensions, extension) {
+			objects.Extensions = append(objects.Extensions, extension)
+		}
+	}
+
+	for _, enum := range schema.Enums {
+		i := slices.IndexFunc(baseline.Enums, func(e providers.Enum) bool { return e.Name == enum.Name })
+		if i < 0 {
+			objects.Enums = append(objects.Enums, enum)
+			continue
+		}
+		kept := slices.DeleteFunc(slices.Clone(enum.Values), func(value string) bool { return !slices.Contains(baseline.Enums[i].Values, value) })
+		if !slices.Equal(kept, baseline.Enums[i].Values) {
+			return baselineChanges{}, fmt.Errorf("--baseline cannot express the change to enum %s: labels were removed or reordered", enum.Name)
+		}
+		if len(enum.Values) > len(kept) {
+			objects.EnumValues = append(objects.EnumValues, enum)
+		}
+	}
+
+	for _, compositeType := range schema.CompositeTypes {
+		i := slices.IndexFunc(baseline.CompositeTypes, func(c providers.CompositeType) bool { return c.Name == compositeType.Name })
+		switch {
+		case i < 0:
+			objects.CompositeTypes = append(objects.CompositeTypes, compositeType)
+		case !slices.Equal(compositeType.Attributes, baseline.CompositeTypes[i].Attributes):
+			return baselineChanges{}, fmt.Errorf("--baseline cannot express the change to composite type %s", compositeType.Name)
+		}
+	}
+
+	// Sequences of serial columns are created with their column
+	for _, sequence := range providers.StandaloneSequences(schema) {
+		i := slices.IndexFunc(baseline.Sequences, func(s providers.Sequence) bool { return s.Name == sequence.Name })
+		switch {
+		case i < 0:
+			objects.Sequences = append(objects.Sequences, sequence)
+		case sequence != baseline.Sequences[i]:
+			return baselineChanges{}, fmt.Errorf("--baseline cannot express the change to sequence %s", sequence.Name)
+		}
+	}
+
+	for _, function := range schema.Functions {
+		if !slices.Contains(baseline.Functions, function) {
+			objects.Functions = append(objects.Functions, function)
+		}
+	}
+	for _, view := range schema.Views {
+		if !slices.Contains(baseline.Views, view) {
+			objects.Views = append(objects.Views, view)
+		}
+	}
+	return changes, nil
+}
+
+// addedEnumValuesSQL returns the statements adding the labels of enums that
+// their baseline version does not have
+func addedEnumValuesSQL(baseline providers.Schema, enums []providers.Enum) string {
+	var sb strings.Builder
+	for _, enum := range enums {
+		i := slices.IndexFunc(baseline.Enums, func(e providers.Enum) bool { return e.Name == enum.Name })
+		sb.WriteString(providers.AddEnumValuesSQL(enum, baseline.Enums[i].Values))
+	}
+	return sb.String()
+}
+
+// renderBaselineResult renders the part of an extraction result that is not
+// in baseline. SQL output creates the new objects with the format options of
+// the run and alters the baseline tables and enums that changed, info output
+// describes the new objects followed by the changes to baseline tables and
+// enums, and JSON output is the diff report of the tables with the other
+// objects under "objects".
+func renderBaselineResult(params providers.ExtractParams, result *providers.SchemaResult, baseline providers.Schema) (string, error) {
+	if len(result.Tables) == 0 && result.RawSQL != "" {
+		return "", withExitCode(exitUsage, fmt.Errorf("--baseline needs table details, which the provider does not return"))
+	}
+	changes, err := subtractBaseline(baseline, result.Schema)
+	if err != nil {
+		return "", err
+	}
+	objects := changes.Objects
+
+	switch params.Format {
+	case providers.FormatSQL:
+		if changes.isEmpty() {
+			return "", nil
+		}
+		output := providers.FormatFullSchemaSQL(providers.Schema{
+			Extensions:     objects.Extensions,
+			Enums:          objects.Enums,
+			CompositeTypes: objects.CompositeTypes,
+			Sequences:      objects.Sequences,
+			Functions:      objects.Functions,
+		}, providers.FormatOptions{})
+		if len(objects.EnumValues) > 0 {
+			output += addedEnumValuesSQL(baseline, objects.EnumValues) + "\n"
+		}
+		output += providers.FormatMigrationSQLWithOptions(changes.Tables, params.FormatOptions)
+		if len(objects.Views) > 0 {
+			// Views of the baseline whose definition changed are replaced
+			output += "\n" + providers.FormatFullSchemaSQL(providers.Schema{Views: objects.Views}, providers.FormatOptions{IfNotExists: true})
+		}
+		return output, nil
+	case providers.FormatJSON:
+		report := struct {
+			providers.DiffReport
+			Objects baselineObjects `json:"objects"`
+		}{providers.NewDiffReport("baseline", "schema", changes.Tables), objects}
+		var data []byte
+		if jsonCompact {
+			data, err = json.Marshal(report)
+		} else {
+			data, err = json.MarshalIndent(report, "", "  ")
+		}
+		if err != nil {
+			return "", err
+		}
+		return string(data) + "\n", nil
+	case providers.FormatInfo:
+		output := "\n=== DATABASE SCHEMA ===\n" + providers.FormatFullSchemaInfoWithOptions(providers.Schema{
+			Extensions:     objects.Extensions,
+			Enums:          objects.Enums,
+			CompositeTypes: objects.CompositeTypes,
+			Sequences:      objects.Sequences,
+			Tables:         changes.Tables.AddedTables,
+			Views:          objects.Views,
+			Functions:      objects.Functions,
+		}, providers.InfoOptions{
+			Summary:   showSummary,
+			TypeMap:   params.FormatOptions.TypeMap,
+			RowCounts: tableRowCounts(params.DB, changes.Tables.AddedTables),
+			Color:     colorOutput(),
+		})
+		if len(changes.Tables.ModifiedTables) > 0 {
+			output += "=== CHANGES TO BASELINE TABLES ===\n" + providers.FormatDiffReport(providers.SchemaDiff{ModifiedTables: changes.Tables.ModifiedTables})
+		}
+		if len(objects.EnumValues) > 0 {
+			output += "=== CHANGES TO BASELINE ENUMS ===\n"
+			for _, enum := range objects.EnumValues {
+				output += fmt.Sprintf("~ enum %s (%s)\n", enum.Name, strings.Join(enum.Values, ", "))
+			}
+		}
+		return output, nil
+	default:
+		return "", withExitCode(exitUsage, fmt.Errorf("--baseline only supports info, sql and json output"))
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBaselineSource(t *testing.T) {
+	root := t.TempDir()
+	writeMigrationTree(t, root, map[string]string{
+		"migrations/001_init.up.sql": "create table audit_log (id int);",
+		"shared.sql":                 "create table audit_log (id int);\ncreate table metrics (id int);\n",
+		"notes.txt":                  "not a baseline",
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		source, reader, err := baselineSource(filepath.Join(root, "migrations"))
+		require.NoError(t, err)
+		migrations, err := reader.DiscoverMigrations(source)
+		require.NoError(t, err)
+		require.Len(t, migrations, 1)
+		assert.Equal(t, "001_init", migrations[0].Name)
+	})
+
+	t.Run("sql_file", func(t *testing.T) {
+		path := filepath.Join(root, "shared.sql")
+		source, reader, err := baselineSource(path)
+		require.NoError(t, err)
+		migrations, err := reader.DiscoverMigrations(source)
+		require.NoError(t, err)
+		require.Len(t, migrations, 1)
+		assert.Equal(t, "shared", migrations[0].Name)
+		assert.Equal(t, path, migrations[0].UpFile)
+		assert.Contains(t, string(migrations[0].UpSQL), "create table metrics")
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, _, err := baselineSource(filepath.Join(root, "missing"))
+		assert.Equal(t, exitMigrationDirNotFound, exitCode(err))
+	})
+
+	t.Run("unsupported_file", func(t *testing.T) {
+		_, _, err := baselineSource(filepath.Join(root, "notes.txt"))
+		assert.Equal(t, exitUsage, exitCode(err))
+		assert.Contains(t, err.Error(), "unsupported baseline")
+	})
+}
+
+func TestLoadBaselineFailures(t *testing.T) {
+	defer resetCommand()
+	resetCommand()
+
+	root := t.TempDir()
+	writeMigrationTree(t, root, map[string]string{"shared.sql": "create table audit_log (id int);"})
+	path := filepath.Join(root, "shared.sql")
+
+	dbManager := &MockDatabaseManager{
+		SetupFunc: func(ctx context.Context) error { return errors.New("cannot connect to docker") },
+	}
+	_, err := loadBaseline(context.Background(), path, dbManager)
+	assert.Equal(t, exitSetupFailed, exitCode(err))
+	assert.False(t, dbManager.RunMigrationsCalled)
+
+	dbManager = &MockDatabaseManager{
+		RunMigrationsFunc: func(ctx context.Context, migrations []Migration) error {
+			return errors.New(`syntax error at or near "tabel"`)
+		},
+	}
+	_, err = loadBaseline(context.Background(), path, dbManager)
+	assert.Equal(t, exitMigrationFailed, exitCode(err))
+	assert.Contains(t, err.Error(), "failed to run baseline migrations")
+	assert.True(t, dbManager.CloseCalled)
+}
+
+func TestRenderBaselineResult(t *testing.T) {
+	defer resetCommand()
+	resetCommand()
+
+	baseline := providers.Schema{
+		Extensions: []string{"pg_stat_statements"},
+		Enums:      []providers.Enum{{Name: "mood", Values: []string{"ok", "sad"}}},
+		Tables: []providers.Table{
+			{Name: "audit_log", Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}}},
+			{Name: "metrics", Columns: []providers.Column{{Name: "id", DataType: "integer"}}},
+		},
+		Views: []providers.View{{Name: "recent_audit", Definition: " SELECT id FROM audit_log"}},
+	}
+	result := &providers.SchemaResult{Schema: providers.Schema{
+		Extensions: []string{"pg_stat_statements", "citext"},
+		Enums: []providers.Enum{
+			{Name: "mood", Values: []string{"happy", "ok", "meh", "sad"}},
+			{Name: "status", Values: []string{"active"}},
+		},
+		Tables: []providers.Table{
+			{Name: "audit_log", Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "user_id", DataType: "integer", IsNullable: true},
+			}},
+			{Name: "users", Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}}},
+		},
+		Views: []providers.View{{Name: "recent_audit", Definition: " SELECT id, user_id FROM audit_log"}},
+		Functions: []providers.Function{{
+			Name: "touch", Kind: "function", Definition: "CREATE OR REPLACE FUNCTION public.touch()\n RETURNS integer\n LANGUAGE sql\nAS $function$ select 1 $function$",
+		}},
+	}}
+
+	sqlOutput, err := renderBaselineResult(providers.ExtractParams{Format: providers.FormatSQL}, result, baseline)
+	require.NoError(t, err)
+	assert.Equal(t, "create extension if not exists citext;\n\n"+
+		"create type status as enum ('active');\n\n"+
+		"set check_function_bodies = off;\n\n"+
+		"CREATE OR REPLACE FUNCTION public.touch()\n RETURNS integer\n LANGUAGE sql\nAS $function$ select 1 $function$;\n\n"+
+		"alter type mood add value 'happy' before 'ok';\n"+
+		"alter type mood add value 'meh' after 'ok';\n\n"+
+		"create table users (\n"+
+		"    id integer not null,\n"+
+		"    primary key (id)\n"+
+		");\n\n"+
+		"alter table audit_log add column user_id integer;\n\n"+
+		"create or replace view recent_audit as\n SELECT id, user_id FROM audit_log;\n\n", sqlOutput)
+	assert.NotContains(t, sqlOutput, "metrics", "baseline tables missing from the schema are not dropped")
+	assert.NotContains(t, sqlOutput, "pg_stat_statements")
+
+	info, err := renderBaselineResult(providers.ExtractParams{Format: providers.FormatInfo}, result, baseline)
+	require.NoError(t, err)
+	assert.Contains(t, info, "Table: users\n")
+	assert.NotContains(t, info, "Table: audit_log")
+	assert.Contains(t, info, "Enums:\n  - status (active)\n")
+	assert.Contains(t, info, "Views:\n  - recent_audit\n")
+	assert.Contains(t, info, "=== CHANGES TO BASELINE TABLES ===\n~ table audit_log\n    + column user_id integer\n")
+	assert.Contains(t, info, "=== CHANGES TO BASELINE ENUMS ===\n~ enum mood (happy, ok, meh, sad)\n")
+
+	jsonOutput, err := renderBaselineResult(providers.ExtractParams{Format: providers.FormatJSON}, result, baseline)
+	require.NoError(t, err)
+	var report struct {
+		AddedTables []providers.DiffTable `json:"added_tables"`
+		Objects     baselineObjects       `json:"objects"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(jsonOutput), &report))
+	require.Len(t, report.AddedTables, 1)
+	assert.Equal(t, []string{"citext"}, report.Objects.Extensions)
+	assert.Equal(t, []providers.Enum{{Name: "status", Values: []string{"active"}}}, report.Objects.Enums)
+	assert.Equal(t, result.Views, report.Objects.Views)
+	assert.Empty(t, report.Objects.Sequences)
+	assert.Contains(t, jsonOutput, `"sequences": []`)
+
+	// Nothing beyond the baseline
+	sqlOutput, err = renderBaselineResult(providers.ExtractParams{Format: providers.FormatSQL}, &providers.SchemaResult{Schema: baseline}, baseline)
+	require.NoError(t, err)
+	assert.Empty(t, sqlOutput)
+
+	_, err = renderBaselineResult(providers.ExtractParams{Format: providers.FormatSQL}, &providers.SchemaResult{RawSQL: "CREATE TABLE users (id integer);"}, baseline)
+	assert.Equal(t, exitUsage, exitCode(err))
+}
+
+func TestRenderBaselineResultTables(t *testing.T) {
+	defer resetCommand()
+	resetCommand()
+
+	audit := providers.Trigger{Name: "users_audit", Table: "users", Definition: "CREATE TRIGGER users_audit AFTER UPDATE ON public.users FOR EACH ROW EXECUTE FUNCTION audit()"}
+	touch := providers.Trigger{Name: "posts_touch", Table: "posts", Definition: "CREATE TRIGGER posts_touch BEFORE UPDATE ON public.posts FOR EACH ROW EXECUTE FUNCTION touch()"}
+	users := providers.Table{Name: "users", Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}}}
+	events := providers.Table{Name: "events", Columns: []providers.Column{{Name: "at", DataType: "date"}}, PartitionStrategy: "range", PartitionKey: "at"}
+	baseline := providers.Schema{Tables: []providers.Table{users, events}}
+
+	withTrigger := users
+	withTrigger.Triggers = []providers.Trigger{audit}
+	result := &providers.SchemaResult{Schema: providers.Schema{Tables: []providers.Table{
+		withTrigger,
+		events,
+		{Name: "events_2026", PartitionOf: "events", PartitionBound: "FOR VALUES FROM ('2026-01-01') TO ('2027-01-01')"},
+		{
+			Name:        "posts",
+			Columns:     []providers.Column{{Name: "user_id", DataType: "integer"}},
+			ForeignKeys: []providers.ForeignKey{{Name: "posts_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}}},
+			Triggers:    []providers.Trigger{touch},
+			Grants:      []providers.GrantInfo{{Grantee: "reader", Privileges: []string{"select"}}},
+		},
+	}}}
+
+	params := providers.ExtractParams{Format: providers.FormatSQL, FormatOptions: providers.FormatOptions{
+		ForeignKeyStyle: providers.ForeignKeyAlter,
+		TypeMap:         providers.TypeMap{"integer": "int4"},
+	}}
+	output, err := renderBaselineResult(params, result, baseline)
+	require.NoError(t, err)
+	assert.Contains(t, output, "create table events_2026 partition of events FOR VALUES FROM ('2026-01-01') TO ('2027-01-01');\n")
+	assert.Contains(t, output, "create table posts (\n    user_id int4 not null\n);\n\ngrant select on posts to reader;\n")
+	assert.Contains(t, output, "alter table posts add constraint posts_user_id_fkey foreign key (user_id) references users (id);\n")
+	assert.Contains(t, output, touch.Definition+";\n")
+	assert.Contains(t, output, audit.Definition+";\n", "triggers added to baseline tables are created")
+	assert.Less(t, strings.Index(output, "add constraint posts_user_id_fkey"), strings.Index(output, "CREATE TRIGGER"))
+
+	info, err := renderBaselineResult(providers.ExtractParams{Format: providers.FormatInfo}, result, baseline)
+	require.NoError(t, err)
+	assert.Contains(t, info, "~ table users\n    + trigger users_audit\n")
+}
+
+func TestSubtractBaselineUnsupportedChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseline providers.Schema
+		schema   providers.Schema
+		wantErr  string
+	}{
+		{
+			name:     "enum_label_removed",
+			baseline: providers.Schema{Enums: []providers.Enum{{Name: "mood", Values: []string{"ok", "sad"}}}},
+			schema:   providers.Schema{Enums: []providers.Enum{{Name: "mood", Values: []string{"ok"}}}},
+			wantErr:  "cannot express the change to enum mood",
+		},
+		{
+			name:     "enum_labels_reordered",
+			baseline: providers.Schema{Enums: []providers.Enum{{Name: "mood", Values: []string{"ok", "sad"}}}},
+			schema:   providers.Schema{Enums: []providers.Enum{{Name: "mood", Values: []string{"sad", "ok"}}}},
+			wantErr:  "cannot express the change to enum mood",
+		},
+		{
+			name: "composite_type",
+			baseline: providers.Schema{CompositeTypes: []providers.CompositeType{{Name: "money_amount", Attributes: []providers.CompositeAttribute{
+				{Name: "amount", Type: "numeric"},
+			}}}},
+			schema: providers.Schema{CompositeTypes: []providers.CompositeType{{Name: "money_amount", Attributes: []providers.CompositeAttribute{
+				{Name: "amount", Type: "numeric"}, {Name: "currency", Type: "text"},
+			}}}},
+			wantErr: "cannot express the change to composite type money_amount",
+		},
+		{
+			name:     "sequence",
+			baseline: providers.Schema{Sequences: []providers.Sequence{{Name: "invoice_numbers", DataType: "bigint", Start: 1, Increment: 1}}},
+			schema:   providers.Schema{Sequences: []providers.Sequence{{Name: "invoice_numbers", DataType: "bigint", Start: 1, Increment: 10}}},
+			wantErr:  "cannot express the change to sequence invoice_numbers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := subtractBaseline(tt.baseline, tt.schema)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// cacheFormatVersion is part of every cache key so that entries written by an
+// older version with different output are not reused
+const cacheFormatVersion = "18"
+
+// schemaCache stores rendered schema output on disk, keyed by the content of
+// the migrations and the settings that affect the output
+type schemaCache struct {
+	dir string
+}
+
+// cacheEntry is a cached output with the warnings raised while producing it,
+// which a cache hit repeats
+type cacheEntry struct {
+	Output   string         `json:"output"`
+	Warnings schemaWarnings `json:"warnings"`
+}
+
+// newSchemaCache returns a cache rooted at dir, or at mig2schema under the
+// user cache directory ($XDG_CACHE_HOME on Linux) when dir is empty
+func newSchemaCache(dir string) (*schemaCache, error) {
+	if dir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate user cache directory: %w", err)
+		}
+		dir = filepath.Join(userCacheDir, "mig2schema")
+	}
+	return &schemaCache{dir: dir}, nil
+}
+
+// migrationsCacheKey hashes the name and content of every migration together
+// with the given settings. Any change to a migration file produces a new key,
+// so stale entries are never returned.
+func migrationsCacheKey(migrations []Migration, settings ...string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "v%s\x00", cacheFormatVersion)
+	for _, setting := range settings {
+		fmt.Fprintf(h, "%s\x00", setting)
+	}
+
+	for _, migration := range migrations {
+		content, err := migration.readUpSQL()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", migration.Name, len(content))
+		h.Write(content)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *schemaCache) path(key string) string {
+	return filepath.Join(c.dir, key)
+}
+
+// get returns the cached entry for key
+func (c *schemaCache) get(key string) (cacheEntry, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("failed to read schema cache", "error", err)
+		}
+		return cacheEntry{}, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		slog.Warn("failed to decode schema cache entry", "error", err)
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// put stores entry under key. The file is written to a temporary name and
+// renamed so that concurrent runs never read a partial entry.
+func (c *schemaCache) put(key string, entry cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, key+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		return fmt.Errorf("failed to store cache file: %w", err)
+	}
+	return nil
+}
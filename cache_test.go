@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationsCacheKey(t *testing.T) {
+	migrations := []Migration{
+		{Name: "001_users", UpSQL: []byte("create table users (id integer);")},
+		{Name: "002_posts", UpSQL: []byte("create table posts (id integer);")},
+	}
+
+	key, err := migrationsCacheKey(migrations, "native", "sql")
+	require.NoError(t, err)
+
+	again, err := migrationsCacheKey(migrations, "native", "sql")
+	require.NoError(t, err)
+	assert.Equal(t, key, again)
+
+	otherFormat, err := migrationsCacheKey(migrations, "native", "info")
+	require.NoError(t, err)
+	assert.NotEqual(t, key, otherFormat)
+
+	changed := []Migration{migrations[0], {Name: "002_posts", UpSQL: []byte("create table posts (id bigint);")}}
+	changedKey, err := migrationsCacheKey(changed, "native", "sql")
+	require.NoError(t, err)
+	assert.NotEqual(t, key, changedKey)
+
+	_, err = migrationsCacheKey([]Migration{{Name: "001_missing", UpFile: "/non/existent/001_missing.up.sql"}}, "native")
+	assert.Error(t, err)
+}
+
+func TestSchemaCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested", "cache")
+	cache, err := newSchemaCache(dir)
+	require.NoError(t, err)
+
+	_, ok := cache.get("abc")
+	assert.False(t, ok)
+
+	entry := cacheEntry{
+		Output:   "create table users (id integer);\n",
+		Warnings: schemaWarnings{Unmapped: []providers.UnmappedType{{Table: "users", Column: "location", DataType: "geometry"}}},
+	}
+	require.NoError(t, cache.put("abc", entry))
+	cached, ok := cache.get("abc")
+	assert.True(t, ok)
+	assert.Equal(t, entry, cached)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files should not be left behind")
+}
+
+func TestNewSchemaCacheDefaultDir(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("XDG_CACHE_HOME is only used on Linux")
+	}
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	cache, err := newSchemaCache("")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(os.Getenv("XDG_CACHE_HOME"), "mig2schema"), cache.dir)
+}
+
+func TestCachedRunRepeatsWarnings(t *testing.T) {
+	defer resetCommand()
+	defer slog.SetDefault(slog.Default())
+
+	reader := &MockMigrationReader{
+		DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+			return []Migration{{Name: "001_places", UpSQL: []byte("create table places (id integer primary key, address postal_address);")}}, nil
+		},
+	}
+	provider := &MockSchemaProvider{
+		ExtractSchemaFunc: func(ctx context.Context, params providers.ExtractParams) (*providers.SchemaResult, error) {
+			return &providers.SchemaResult{Format: params.Format, Schema: providers.Schema{Tables: []providers.Table{{
+				Name: "places",
+				Columns: []providers.Column{
+					{Name: "id", DataType: "integer", IsPrimaryKey: true},
+					{Name: "address", DataType: "postal_address"},
+				},
+			}}}}, nil
+		},
+	}
+	cache, err := newSchemaCache(t.TempDir())
+	require.NoError(t, err)
+	tempDir := t.TempDir()
+
+	for _, name := range []string{"miss", "hit"} {
+		dbManager := &MockDatabaseManager{}
+		_, stderr := captureStreams(t, func() {
+			require.NoError(t, configureLogging(os.Stderr))
+			require.NoError(t, processSchemaWithProvider(context.Background(), tempDir, reader, dbManager, provider, cache, nil))
+		})
+		assert.Contains(t, stderr, "unmapped data type passed through as-is", name)
+		assert.Equal(t, name == "miss", dbManager.SetupCalled, name)
+	}
+}
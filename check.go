@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// runCheck discovers the migrations in migrationDir and runs them against a
+// fresh database without extracting the schema. Nothing is written to
+// stdout: a failure is returned with the exit code of the step that failed.
+// A positive timeout bounds the database setup and the migrations together.
+func runCheck(ctx context.Context, migrationDir string, migrationReader MigrationReader, dbManager DatabaseManager, timeout time.Duration) error {
+	if migrationDirMissing(migrationDir) {
+		return withExitCode(exitMigrationDirNotFound, fmt.Errorf("migration directory does not exist: %s", migrationDir))
+	}
+
+	migrations, err := migrationReader.DiscoverMigrations(migrationDir)
+	if err != nil {
+		return fmt.Errorf("failed to parse migrations: %w", err)
+	}
+
+	if len(migrations) == 0 {
+		return withExitCode(exitNoMigrations, fmt.Errorf("no migration files found in directory: %s", migrationDir))
+	}
+
+	if targetVersion != "" {
+		migrations, err = targetMigrations(migrations, targetVersion)
+		if err != nil {
+			return withExitCode(exitUsage, err)
+		}
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if err := dbManager.Setup(ctx); err != nil {
+		return withExitCode(exitSetupFailed, fmt.Errorf("failed to setup database: %w", checkTimedOut(ctx, timeout, err)))
+	}
+	defer func() {
+		// The run context may have expired; cleanup still has to happen
+		if err := dbManager.Close(context.WithoutCancel(ctx)); err != nil {
+			slog.Error("failed to cleanup", "error", err)
+		}
+	}()
+
+	if err := dbManager.RunMigrations(ctx, migrations); err != nil {
+		return withExitCode(exitMigrationFailed, fmt.Errorf("failed to run migrations: %w", checkTimedOut(ctx, timeout, err)))
+	}
+
+	slog.Info("migrations applied cleanly", "count", len(migrations))
+	return nil
+}
+
+// checkTimedOut adds the --timeout to err when it was caused by the run
+// context expiring, so that the failure does not read as a database error
+func checkTimedOut(ctx context.Context, timeout time.Duration, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s: %w", timeout, err)
+	}
+	return err
+}
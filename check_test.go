@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCheck(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "001_create_users.up.sql"), []byte("create table users (id serial primary key);"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "002_add_email.up.sql"), []byte("alter table users add column email text;"), 0644))
+
+	t.Run("success", func(t *testing.T) {
+		var ran []Migration
+		dbManager := &MockDatabaseManager{
+			RunMigrationsFunc: func(ctx context.Context, migrations []Migration) error {
+				ran = migrations
+				return nil
+			},
+		}
+		require.NoError(t, runCheck(context.Background(), dir, NewFileMigrationReader(), dbManager, 0))
+		assert.Len(t, ran, 2)
+		assert.True(t, dbManager.CloseCalled)
+		assert.False(t, dbManager.GetDBCalled, "the schema is not extracted")
+	})
+
+	t.Run("migration_failure", func(t *testing.T) {
+		dbManager := &MockDatabaseManager{
+			RunMigrationsFunc: func(ctx context.Context, migrations []Migration) error {
+				return errors.New(`relation "users" already exists`)
+			},
+		}
+		err := runCheck(context.Background(), dir, NewFileMigrationReader(), dbManager, 0)
+		require.Error(t, err)
+		assert.Equal(t, exitMigrationFailed, exitCode(err))
+		assert.Contains(t, err.Error(), `relation "users" already exists`)
+		assert.True(t, dbManager.CloseCalled)
+	})
+
+	t.Run("setup_failure", func(t *testing.T) {
+		dbManager := &MockDatabaseManager{
+			SetupFunc: func(ctx context.Context) error {
+				return errors.New("cannot connect to docker")
+			},
+		}
+		err := runCheck(context.Background(), dir, NewFileMigrationReader(), dbManager, 0)
+		assert.Equal(t, exitSetupFailed, exitCode(err))
+		assert.False(t, dbManager.RunMigrationsCalled)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		dbManager := &MockDatabaseManager{
+			RunMigrationsFunc: func(ctx context.Context, migrations []Migration) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		}
+		err := runCheck(context.Background(), dir, NewFileMigrationReader(), dbManager, 10*time.Millisecond)
+		require.Error(t, err)
+		assert.Equal(t, exitMigrationFailed, exitCode(err))
+		assert.Contains(t, err.Error(), "timed out after 10ms")
+	})
+
+	t.Run("missing_directory", func(t *testing.T) {
+		err := runCheck(context.Background(), filepath.Join(dir, "missing"), NewFileMigrationReader(), &MockDatabaseManager{}, 0)
+		assert.Equal(t, exitMigrationDirNotFound, exitCode(err))
+	})
+
+	t.Run("no_migrations", func(t *testing.T) {
+		err := runCheck(context.Background(), t.TempDir(), NewFileMigrationReader(), &MockDatabaseManager{}, 0)
+		assert.Equal(t, exitNoMigrations, exitCode(err))
+	})
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileName is the config file looked up in the migration directory,
+// then in the working directory
+const configFileName = "mig2schema.yaml"
+
+// configSettings lists the flags a config file can set: the provider and
+// images, the output format, what is extracted, the index filters and the
+// database credentials. Flags that pick a mode, such as --mcp or --golden, or
+// that write or read other files are left to the command line, since a
+// config file is picked up from the migration directory without being named.
+var configSettings = map[string]bool{
+	"provider":                  true,
+	"pg-image":                  true,
+	"container-startup-timeout": true,
+	"container-wait":            true,
+	"format":                    true,
+	"dialect":                   true,
+	"fk-style":                  true,
+	"json-compact":              true,
+	"include-extensions":        true,
+	"include-extension-objects": true,
+	"include-privileges":        true,
+	"include-index-pattern":     true,
+	"exclude-index-pattern":     true,
+	"database-url":              true,
+}
+
+// sliceFlagValue is implemented by the values of list flags such as
+// --pg-image, the only flags a config file may give a list
+type sliceFlagValue interface {
+	Replace(values []string) error
+}
+
+// findConfigFile returns the config file to load: explicit when given,
+// otherwise mig2schema.yaml in the migration directory or the working
+// directory. It returns "" when there is none.
+func findConfigFile(explicit string, args []string) (string, error) {
+	if explicit != "" {
+		if _, err := os.Stat(explicit); err != nil {
+			return "", fmt.Errorf("config file not found: %w", err)
+		}
+		return explicit, nil
+	}
+
+	var candidates []string
+	if len(args) > 0 {
+		candidates = append(candidates, filepath.Join(args[0], configFileName))
+	}
+	candidates = append(candidates, configFileName)
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", nil
+}
+
+// loadConfigFile finds the config file for the command and sets the flags it
+// holds that were not given on the command line
+func loadConfigFile(cmd *cobra.Command, args []string) error {
+	path, err := findConfigFile(configPath, args)
+	if err != nil || path == "" {
+		return err
+	}
+
+	settings, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+	if err := applyConfig(cmd, path, settings); err != nil {
+		return err
+	}
+	slog.Debug("loaded config file", "path", path, "settings", len(settings))
+	return nil
+}
+
+// readConfigFile parses a config file: a mapping from long flag names to
+// their values
+func readConfigFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var settings map[string]any
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&settings); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// applyConfig sets the flags of cmd from settings, skipping those changed on
+// the command line so that they take precedence. Flags are set as on the
+// command line, so that they count as changed. Strings have environment
+// variables expanded, so that credentials can stay out of the file. Lists
+// replace the default of list flags.
+func applyConfig(cmd *cobra.Command, path string, settings map[string]any) error {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil {
+			return fmt.Errorf("config file %s: unknown setting %q, expected a flag name such as pg-image", path, name)
+		}
+		if !configSettings[name] {
+			return fmt.Errorf("config file %s: %s can only be given on the command line", path, name)
+		}
+		if flag.Changed {
+			continue
+		}
+
+		switch value := settings[name].(type) {
+		case []any:
+			slice, ok := flag.Value.(sliceFlagValue)
+			if !ok {
+				return fmt.Errorf("config file %s: %s takes a single value, not a list", path, name)
+			}
+			if len(value) == 0 {
+				if err := slice.Replace(nil); err != nil {
+					return fmt.Errorf("config file %s: invalid value for %s: %w", path, name, err)
+				}
+			}
+			// The first value replaces the default, the others are appended
+			for _, item := range value {
+				if err := cmd.Flags().Set(name, configValue(item)); err != nil {
+					return fmt.Errorf("config file %s: invalid value for %s: %w", path, name, err)
+				}
+			}
+		case map[string]any, nil:
+			return fmt.Errorf("config file %s: %s must be a value or a list", path, name)
+		default:
+			if err := cmd.Flags().Set(name, configValue(value)); err != nil {
+				return fmt.Errorf("config file %s: invalid value for %s: %w", path, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// configValue renders a YAML scalar as a flag value
+func configValue(value any) string {
+	if s, ok := value.(string); ok {
+		return os.ExpandEnv(s)
+	}
+	return fmt.Sprint(value)
+}
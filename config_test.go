@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// configTestCommand returns a command with a few flags of each kind and the
+// variables they are bound to
+func configTestCommand() (*cobra.Command, *string, *[]string, *bool, *time.Duration) {
+	var provider string
+	var images []string
+	var privileges bool
+	var timeout time.Duration
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().StringVarP(&provider, "provider", "p", "native", "")
+	cmd.Flags().StringSliceVar(&images, "pg-image", []string{"postgres:16-alpine"}, "")
+	cmd.Flags().BoolVar(&privileges, "include-privileges", false, "")
+	cmd.Flags().DurationVar(&timeout, "container-startup-timeout", 0, "")
+	cmd.Flags().String("config", "", "")
+	cmd.Flags().Bool("mcp", false, "")
+	return cmd, &provider, &images, &privileges, &timeout
+}
+
+func writeConfigFile(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, configFileName)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestApplyConfig(t *testing.T) {
+	t.Setenv("MIG2SCHEMA_TEST_IMAGE", "postgres:17-alpine")
+	path := writeConfigFile(t, t.TempDir(), `
+provider: pg_dump
+pg-image:
+  - ${MIG2SCHEMA_TEST_IMAGE}
+  - postgres:15-alpine
+include-privileges: true
+container-startup-timeout: 90s
+`)
+	settings, err := readConfigFile(path)
+	require.NoError(t, err)
+
+	t.Run("sets_defaults", func(t *testing.T) {
+		cmd, provider, images, privileges, timeout := configTestCommand()
+		require.NoError(t, cmd.ParseFlags(nil))
+		require.NoError(t, applyConfig(cmd, path, settings))
+
+		assert.Equal(t, "pg_dump", *provider)
+		assert.Equal(t, []string{"postgres:17-alpine", "postgres:15-alpine"}, *images)
+		assert.True(t, *privileges)
+		assert.Equal(t, 90*time.Second, *timeout)
+		for _, name := range []string{"provider", "pg-image", "include-privileges", "container-startup-timeout"} {
+			assert.True(t, cmd.Flags().Changed(name), name)
+		}
+	})
+
+	t.Run("command_line_wins", func(t *testing.T) {
+		cmd, provider, images, _, _ := configTestCommand()
+		require.NoError(t, cmd.ParseFlags([]string{"-p", "native", "--pg-image", "postgres:14"}))
+		require.NoError(t, applyConfig(cmd, path, settings))
+
+		assert.Equal(t, "native", *provider)
+		assert.Equal(t, []string{"postgres:14"}, *images)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			settings map[string]any
+			wantErr  string
+		}{
+			{"unknown_setting", map[string]any{"exclude-tables": "x"}, `unknown setting "exclude-tables"`},
+			{"config_itself", map[string]any{"config": "other.yaml"}, "config can only be given on the command line"},
+			{"mode", map[string]any{"mcp": true}, "mcp can only be given on the command line"},
+			{"list_for_scalar", map[string]any{"provider": []any{"native"}}, "provider takes a single value, not a list"},
+			{"mapping", map[string]any{"provider": map[string]any{"name": "native"}}, "provider must be a value or a list"},
+			{"invalid_value", map[string]any{"include-privileges": "sometimes"}, "invalid value for include-privileges"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				cmd, _, _, _, _ := configTestCommand()
+				err := applyConfig(cmd, path, tt.settings)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			})
+		}
+	})
+}
+
+func TestFindConfigFile(t *testing.T) {
+	migrationDir := t.TempDir()
+	workDir := t.TempDir()
+	t.Chdir(workDir)
+
+	path, err := findConfigFile("", []string{migrationDir})
+	require.NoError(t, err)
+	assert.Empty(t, path)
+
+	writeConfigFile(t, workDir, "provider: native\n")
+	path, err = findConfigFile("", []string{migrationDir})
+	require.NoError(t, err)
+	assert.Equal(t, configFileName, path)
+
+	inMigrationDir := writeConfigFile(t, migrationDir, "provider: native\n")
+	path, err = findConfigFile("", []string{migrationDir})
+	require.NoError(t, err)
+	assert.Equal(t, inMigrationDir, path)
+
+	explicit := filepath.Join(t.TempDir(), "ci.yaml")
+	require.NoError(t, os.WriteFile(explicit, []byte("provider: native\n"), 0644))
+	path, err = findConfigFile(explicit, []string{migrationDir})
+	require.NoError(t, err)
+	assert.Equal(t, explicit, path)
+
+	_, err = findConfigFile(filepath.Join(workDir, "missing.yaml"), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "config file not found")
+}
+
+func TestReadConfigFile(t *testing.T) {
+	settings, err := readConfigFile(writeConfigFile(t, t.TempDir(), ""))
+	require.NoError(t, err)
+	assert.Empty(t, settings)
+
+	_, err = readConfigFile(writeConfigFile(t, t.TempDir(), "provider: [native"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse config file")
+}
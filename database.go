@@ -3,17 +3,95 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
-	"os"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
+// Defaults for connectWithRetry
+const (
+	defaultConnectAttempts = 5
+	defaultConnectBackoff  = 500 * time.Millisecond
+	maxConnectBackoff      = 5 * time.Second
+)
+
+// connectRetry bounds how often connectWithRetry tries to reach the database.
+// The wait between attempts starts at backoff and doubles up to
+// maxConnectBackoff.
+type connectRetry struct {
+	attempts int
+	backoff  time.Duration
+}
+
+func defaultConnectRetry() connectRetry {
+	return connectRetry{attempts: defaultConnectAttempts, backoff: defaultConnectBackoff}
+}
+
+// retryableConnectError reports whether a failed connection attempt may
+// succeed later. A refused connection or a server that is still starting up
+// can recover; rejected credentials or a missing database will not.
+func retryableConnectError(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code.Class() != "28" && pqErr.Code != "3D000"
+	}
+	return true
+}
+
+// connectWithRetry opens a connection to connStr and pings it until it
+// succeeds, the attempts are used up or ctx is done. A container that reports
+// readiness can still refuse the first connection on a busy host. Errors
+// that retrying cannot fix, see retryableConnectError, are returned at once.
+func connectWithRetry(ctx context.Context, connStr string, retry connectRetry) (*sql.DB, error) {
+	attempts := retry.attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := retry.backoff
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		db, err := sql.Open("postgres", connStr)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to open database connection: %w", err)
+		} else if err := db.PingContext(ctx); err != nil {
+			db.Close()
+			lastErr = fmt.Errorf("failed to ping database: %w", err)
+			if !retryableConnectError(err) {
+				return nil, fmt.Errorf("%w (not retried: the server rejected the connection)", lastErr)
+			}
+		} else {
+			return db, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%w (gave up after %d attempt(s): %v)", lastErr, attempt, ctx.Err())
+		}
+		if attempt == attempts {
+			break
+		}
+
+		slog.Debug("database not reachable yet, retrying", "attempt", attempt, "maxAttempts", attempts, "backoff", backoff, "error", lastErr)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w (gave up after %d attempt(s): %v)", lastErr, attempt, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+
+	return nil, fmt.Errorf("%w (after %d attempt(s))", lastErr, attempts)
+}
+
 type Database struct {
 	Container testcontainers.Container
 	DB        *sql.DB
@@ -42,13 +120,9 @@ func SetupPostgreSQL(ctx context.Context) (*Database, error) {
 	}
 	slog.Debug("got database connection string", "connStr", connStr)
 
-	db, err := sql.Open("postgres", connStr)
+	db, err := connectWithRetry(ctx, connStr, defaultConnectRetry())
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database connection: %w", err)
-	}
-
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		return nil, err
 	}
 
 	slog.Info("postgresql container ready")
@@ -69,17 +143,17 @@ func (d *Database) Close(ctx context.Context) error {
 	return nil
 }
 
-func (d *Database) RunMigrations(migrations []Migration) error {
+func (d *Database) RunMigrations(ctx context.Context, migrations []Migration) error {
 	for _, migration := range migrations {
 		slog.Debug("running migration", "name", migration.Name, "file", migration.UpFile)
 
-		content, err := os.ReadFile(migration.UpFile)
+		content, err := migration.readUpSQL()
 		if err != nil {
-			return fmt.Errorf("failed to read migration file %s: %w", migration.UpFile, err)
+			return err
 		}
 
-		if _, err := d.DB.Exec(string(content)); err != nil {
-			return fmt.Errorf("failed to execute migration %s: %w", migration.Name, err)
+		if err := execMigration(ctx, d.DB, migration, string(content), nil); err != nil {
+			return err
 		}
 
 		slog.Debug("migration completed successfully", "name", migration.Name)
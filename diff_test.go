@@ -0,0 +1,470 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	current := []providers.Table{
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "email", DataType: "character varying", CharacterLength: sql.NullInt64{Int64: 255, Valid: true}},
+				{Name: "legacy", DataType: "text", IsNullable: true},
+			},
+			Indexes: []providers.Index{
+				{Name: "idx_users_email", Columns: []string{"email"}},
+			},
+		},
+		{
+			Name:    "audit_log",
+			Columns: []providers.Column{{Name: "id", DataType: "bigint", IsPrimaryKey: true}},
+		},
+	}
+
+	target := []providers.Table{
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "email", DataType: "text"},
+				{Name: "name", DataType: "text", IsNullable: true},
+			},
+			Indexes: []providers.Index{
+				{Name: "idx_users_email", Columns: []string{"email"}, IsUnique: true},
+			},
+		},
+		{
+			Name: "posts",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "user_id", DataType: "integer"},
+			},
+			ForeignKeys: []providers.ForeignKey{
+				{Name: "posts_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+			},
+		},
+	}
+
+	diff := providers.DiffSchemas(current, target)
+
+	require.Len(t, diff.AddedTables, 1)
+	assert.Equal(t, "posts", diff.AddedTables[0].Name)
+	require.Len(t, diff.RemovedTables, 1)
+	assert.Equal(t, "audit_log", diff.RemovedTables[0].Name)
+	require.Len(t, diff.ModifiedTables, 1)
+
+	users := diff.ModifiedTables[0]
+	assert.Equal(t, "users", users.Name)
+	require.Len(t, users.AddedColumns, 1)
+	assert.Equal(t, "name", users.AddedColumns[0].Name)
+	require.Len(t, users.RemovedColumns, 1)
+	assert.Equal(t, "legacy", users.RemovedColumns[0].Name)
+	require.Len(t, users.ChangedColumns, 1)
+	assert.Equal(t, "email", users.ChangedColumns[0].Name)
+	assert.False(t, users.PrimaryKeyChanged())
+	require.Len(t, users.AddedIndexes, 1)
+	require.Len(t, users.RemovedIndexes, 1)
+
+	assert.True(t, providers.DiffSchemas(current, current).IsEmpty())
+}
+
+func TestFormatMigrationSQL(t *testing.T) {
+	t.Run("orders_and_marks_destructive_operations", func(t *testing.T) {
+		current := []providers.Table{
+			{
+				Name: "users",
+				Columns: []providers.Column{
+					{Name: "id", DataType: "integer", IsPrimaryKey: true},
+					{Name: "legacy", DataType: "text", IsNullable: true},
+				},
+			},
+			{Name: "old_stuff", Columns: []providers.Column{{Name: "id", DataType: "integer"}}},
+		}
+		target := []providers.Table{
+			{
+				Name: "users",
+				Columns: []providers.Column{
+					{Name: "id", DataType: "integer", IsPrimaryKey: true},
+					{Name: "email", DataType: "text", IsNullable: false},
+				},
+			},
+			{
+				Name: "comments",
+				Columns: []providers.Column{
+					{Name: "id", DataType: "integer", IsPrimaryKey: true},
+					{Name: "post_id", DataType: "integer"},
+				},
+				ForeignKeys: []providers.ForeignKey{
+					{Name: "comments_post_id_fkey", Columns: []string{"post_id"}, ReferencedTable: "posts", ReferencedColumns: []string{"id"}},
+				},
+			},
+			{
+				Name: "posts",
+				Columns: []providers.Column{
+					{Name: "id", DataType: "integer", IsPrimaryKey: true},
+					{Name: "user_id", DataType: "integer"},
+				},
+				ForeignKeys: []providers.ForeignKey{
+					{Name: "posts_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+				},
+			},
+		}
+
+		result := providers.FormatMigrationSQL(providers.DiffSchemas(current, target))
+
+		postsAt := strings.Index(result, "create table posts")
+		commentsAt := strings.Index(result, "create table comments")
+		require.NotEqual(t, -1, postsAt)
+		require.NotEqual(t, -1, commentsAt)
+		assert.Less(t, postsAt, commentsAt, "referenced tables must be created first")
+
+		assert.Contains(t, result, "constraint posts_user_id_fkey foreign key (user_id) references users (id)")
+		assert.Contains(t, result, "alter table users add column email text not null;")
+		assert.Contains(t, result, "-- DESTRUCTIVE: drops column users.legacy and its data\nalter table users drop column legacy;")
+		assert.Contains(t, result, "-- DESTRUCTIVE: drops table old_stuff and all of its data\ndrop table old_stuff;")
+	})
+
+	t.Run("column_changes", func(t *testing.T) {
+		current := []providers.Table{{
+			Name: "items",
+			Columns: []providers.Column{
+				{Name: "price", DataType: "integer", IsNullable: true, DefaultValue: sql.NullString{String: "0", Valid: true}},
+			},
+		}}
+		target := []providers.Table{{
+			Name: "items",
+			Columns: []providers.Column{
+				{Name: "price", DataType: "bigint", IsNullable: false},
+			},
+		}}
+
+		result := providers.FormatMigrationSQL(providers.DiffSchemas(current, target))
+
+		assert.Contains(t, result, "-- DESTRUCTIVE: changing items.price from integer to bigint may fail or lose data")
+		assert.Contains(t, result, "alter table items alter column price type bigint;")
+		assert.Contains(t, result, "alter table items alter column price drop default;")
+		assert.Contains(t, result, "alter table items alter column price set not null;")
+	})
+
+	t.Run("primary_key_change_drops_the_named_constraint", func(t *testing.T) {
+		current := []providers.Table{{
+			Name:           "accounts",
+			PrimaryKeyName: "accounts_pk",
+			Columns:        []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}, {Name: "code", DataType: "text"}},
+		}}
+		target := []providers.Table{{
+			Name:    "accounts",
+			Columns: []providers.Column{{Name: "id", DataType: "integer"}, {Name: "code", DataType: "text", IsPrimaryKey: true}},
+		}}
+
+		result := providers.FormatMigrationSQL(providers.DiffSchemas(current, target))
+		assert.Contains(t, result, "alter table accounts drop constraint accounts_pk;\n")
+		assert.Contains(t, result, "alter table accounts add primary key (code);\n")
+
+		current[0].PrimaryKeyName = ""
+		result = providers.FormatMigrationSQL(providers.DiffSchemas(current, target))
+		assert.Contains(t, result, "alter table accounts drop constraint accounts_pkey;\n", "an unknown name falls back to the default")
+	})
+
+	t.Run("collation_changes", func(t *testing.T) {
+		current := []providers.Table{{Name: "users", Columns: []providers.Column{{Name: "name", DataType: "text"}}}}
+		target := []providers.Table{{Name: "users", Columns: []providers.Column{{Name: "name", DataType: "text", Collation: "C"}}}}
+
+		result := providers.FormatMigrationSQL(providers.DiffSchemas(current, target))
+		assert.Equal(t, "alter table users alter column name type text collate \"C\";\n", result)
+
+		result = providers.FormatMigrationSQL(providers.DiffSchemas(target, current))
+		assert.Equal(t, "alter table users alter column name type text;\n", result)
+	})
+
+	t.Run("cyclic_foreign_keys_are_deferred", func(t *testing.T) {
+		target := []providers.Table{
+			{
+				Name:    "a",
+				Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}, {Name: "b_id", DataType: "integer"}},
+				ForeignKeys: []providers.ForeignKey{
+					{Name: "a_b_id_fkey", Columns: []string{"b_id"}, ReferencedTable: "b", ReferencedColumns: []string{"id"}},
+				},
+			},
+			{
+				Name:    "b",
+				Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}, {Name: "a_id", DataType: "integer"}},
+				ForeignKeys: []providers.ForeignKey{
+					{Name: "b_a_id_fkey", Columns: []string{"a_id"}, ReferencedTable: "a", ReferencedColumns: []string{"id"}},
+				},
+			},
+		}
+
+		result := providers.FormatMigrationSQL(providers.DiffSchemas(nil, target))
+
+		assert.Less(t, strings.Index(result, "create table a"), strings.Index(result, "create table b"))
+		assert.Contains(t, result, "constraint b_a_id_fkey foreign key (a_id) references a (id)")
+		assert.Contains(t, result, "alter table a add constraint a_b_id_fkey foreign key (b_id) references b (id);")
+		createA := result[strings.Index(result, "create table a"):strings.Index(result, "create table b")]
+		assert.NotContains(t, createA, "a_b_id_fkey")
+	})
+}
+
+func TestDiffSchemasColumnOrder(t *testing.T) {
+	before := []providers.Table{{Name: "users", Columns: []providers.Column{
+		{Name: "id", DataType: "integer"}, {Name: "email", DataType: "text"}, {Name: "name", DataType: "text"},
+	}}}
+	after := []providers.Table{{Name: "users", Columns: []providers.Column{
+		{Name: "id", DataType: "integer"}, {Name: "name", DataType: "text"}, {Name: "email", DataType: "text"},
+	}}}
+
+	diff := providers.DiffSchemas(before, after)
+	require.Len(t, diff.ModifiedTables, 1)
+	assert.True(t, diff.ModifiedTables[0].ColumnOrderChanged)
+	assert.Equal(t, "-- column order of users differs; columns cannot be reordered in place\n", providers.FormatMigrationSQL(diff))
+
+	added := []providers.Table{{Name: "users", Columns: append(before[0].Columns, providers.Column{Name: "age", DataType: "integer"})}}
+	assert.False(t, providers.DiffSchemas(before, added).ModifiedTables[0].ColumnOrderChanged)
+}
+
+func TestFormatMigrationSQLSerialColumn(t *testing.T) {
+	plain := []providers.Table{{Name: "users", Columns: []providers.Column{{Name: "id", DataType: "integer"}}}}
+	serial := []providers.Table{{Name: "users", Columns: []providers.Column{{
+		Name: "id", DataType: "integer", IsSerial: true, DefaultValue: sql.NullString{String: "nextval('users_id_seq'::regclass)", Valid: true},
+	}}}}
+
+	gained := providers.DiffSchemas(plain, serial)
+	assert.Equal(t, "create sequence users_id_seq owned by users.id;\n"+
+		"alter table users alter column id set default nextval('users_id_seq'::regclass);\n", providers.FormatMigrationSQL(gained))
+	assert.Contains(t, providers.FormatDiffReport(gained), "default none -> nextval('users_id_seq'::regclass)")
+	assert.NotContains(t, providers.FormatDiffReport(gained), "type")
+
+	lost := providers.FormatMigrationSQL(providers.DiffSchemas(serial, plain))
+	assert.Equal(t, "alter table users alter column id drop default;\n", lost)
+
+	bigserial := []providers.Table{{Name: "users", Columns: []providers.Column{{
+		Name: "id", DataType: "bigint", IsSerial: true, DefaultValue: sql.NullString{String: "nextval('users_id_seq'::regclass)", Valid: true},
+	}}}}
+	widened := providers.FormatMigrationSQL(providers.DiffSchemas(serial, bigserial))
+	assert.Equal(t, "-- DESTRUCTIVE: changing users.id from integer to bigint may fail or lose data\n"+
+		"alter table users alter column id type bigint;\n", widened)
+}
+
+func TestFormatMigrationSQLForeignKeyToModifiedTable(t *testing.T) {
+	users := providers.Table{Name: "users", Columns: []providers.Column{{Name: "id", DataType: "integer"}, {Name: "email", DataType: "text"}}}
+	keyed := users
+	keyed.UniqueConstraints = []providers.UniqueConstraint{{Name: "users_email_key", Columns: []string{"email"}}}
+	posts := providers.Table{
+		Name:        "posts",
+		Columns:     []providers.Column{{Name: "author", DataType: "text"}},
+		ForeignKeys: []providers.ForeignKey{{Name: "posts_author_fkey", Columns: []string{"author"}, ReferencedTable: "users", ReferencedColumns: []string{"email"}}},
+	}
+
+	// The foreign key needs the unique constraint added to users
+	output := providers.FormatMigrationSQL(providers.DiffSchemas([]providers.Table{users}, []providers.Table{keyed, posts}))
+	assert.Equal(t, "create table posts (\n    author text not null\n);\n\n"+
+		"alter table users add constraint users_email_key unique (email);\n\n"+
+		"alter table posts add constraint posts_author_fkey foreign key (author) references users (email);\n", output)
+}
+
+func TestDiffFilter(t *testing.T) {
+	current := []providers.Table{
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "status", DataType: "text", DefaultValue: sql.NullString{String: "'new'::text", Valid: true}},
+				{Name: "email", DataType: "text"},
+			},
+			Indexes: []providers.Index{{Name: "idx_users_email", Columns: []string{"email"}}},
+		},
+		{Name: "audit_log", Columns: []providers.Column{{Name: "id", DataType: "bigint"}}},
+	}
+	target := []providers.Table{
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "status", DataType: "text", DefaultValue: sql.NullString{String: "'active'::text", Valid: true}},
+				{Name: "email", DataType: "text", IsNullable: true},
+			},
+			Indexes: []providers.Index{{Name: "idx_users_email_lower", Columns: []string{"email"}}},
+		},
+	}
+	diff := providers.DiffSchemas(current, target)
+
+	t.Run("empty_filter_keeps_everything", func(t *testing.T) {
+		assert.Equal(t, diff, providers.DiffFilter{}.Apply(diff))
+	})
+
+	t.Run("ignore", func(t *testing.T) {
+		filter, err := providers.NewDiffFilter(nil, []string{"indexes", "defaults"})
+		require.NoError(t, err)
+		filtered := filter.Apply(diff)
+
+		require.Len(t, filtered.RemovedTables, 1)
+		require.Len(t, filtered.ModifiedTables, 1)
+		users := filtered.ModifiedTables[0]
+		assert.Empty(t, users.AddedIndexes)
+		assert.Empty(t, users.RemovedIndexes)
+		require.Len(t, users.ChangedColumns, 1)
+		assert.Equal(t, "email", users.ChangedColumns[0].Name)
+	})
+
+	t.Run("only", func(t *testing.T) {
+		filter, err := providers.NewDiffFilter([]string{"tables", "defaults"}, nil)
+		require.NoError(t, err)
+		filtered := filter.Apply(diff)
+
+		require.Len(t, filtered.RemovedTables, 1)
+		require.Len(t, filtered.ModifiedTables, 1)
+		users := filtered.ModifiedTables[0]
+		require.Len(t, users.ChangedColumns, 1)
+		assert.Equal(t, "status", users.ChangedColumns[0].Name)
+		assert.Empty(t, users.AddedIndexes)
+	})
+
+	t.Run("everything_filtered_out", func(t *testing.T) {
+		filter, err := providers.NewDiffFilter([]string{"constraints", "column-order"}, []string{"comments"})
+		require.NoError(t, err)
+		assert.True(t, filter.Apply(diff).IsEmpty())
+	})
+
+	t.Run("unknown_category", func(t *testing.T) {
+		_, err := providers.NewDiffFilter(nil, []string{"sequences"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown diff category "sequences"`)
+	})
+}
+
+func TestFormatDiffReport(t *testing.T) {
+	current := []providers.Table{
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "email", DataType: "character varying", CharacterLength: sql.NullInt64{Int64: 255, Valid: true}},
+				{Name: "legacy", DataType: "text", IsNullable: true},
+			},
+			Indexes: []providers.Index{{Name: "idx_users_email", Columns: []string{"email"}}},
+		},
+		{Name: "audit_log", Columns: []providers.Column{{Name: "id", DataType: "bigint"}}},
+	}
+	target := []providers.Table{
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "email", DataType: "text", IsNullable: true, DefaultValue: sql.NullString{String: "''::text", Valid: true}},
+				{Name: "name", DataType: "text", IsNullable: true},
+			},
+			Indexes: []providers.Index{{Name: "idx_users_email", Columns: []string{"email"}, IsUnique: true}},
+		},
+		{
+			Name:    "posts",
+			Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}},
+		},
+	}
+
+	expected := `+ table posts
+- table audit_log
+~ table users
+    + column name text
+    - column legacy text
+    ~ column email: type varchar(255) -> text, not null -> null, default none -> ''::text
+    + index idx_users_email (email) unique
+    - index idx_users_email (email)
+`
+	assert.Equal(t, expected, providers.FormatDiffReport(providers.DiffSchemas(current, target)))
+}
+
+func TestWriteSchemaChanges(t *testing.T) {
+	users := providers.Table{Name: "users", Columns: []providers.Column{{Name: "id", DataType: "integer"}}}
+	indexed := users
+	indexed.Indexes = []providers.Index{{Name: "idx_users_id", Columns: []string{"id"}}}
+	changes := schemaChanges{From: "001_users", To: "002_index", Diff: providers.DiffSchemas([]providers.Table{users}, []providers.Table{indexed})}
+
+	var out strings.Builder
+	found, err := writeSchemaChanges(&out, changes, providers.DiffFilter{}, diffFormatText)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "Schema changes from 001_users to 002_index:\n~ table users\n    + index idx_users_id (id)\n", out.String())
+
+	out.Reset()
+	found, err = writeSchemaChanges(&out, changes, providers.DiffFilter{Ignore: []providers.DiffCategory{providers.DiffIndexes}}, diffFormatText)
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, "No schema changes from 001_users to 002_index outside the ignored categories\n", out.String())
+
+	out.Reset()
+	found, err = writeSchemaChanges(&out, changes, providers.DiffFilter{Ignore: []providers.DiffCategory{providers.DiffIndexes}}, diffFormatJSON)
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.JSONEq(t, `{"from": "001_users", "to": "002_index", "added_tables": [], "removed_tables": [], "modified_tables": []}`, out.String())
+}
+
+func TestFormatDiffJSON(t *testing.T) {
+	current := []providers.Table{
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "email", DataType: "character varying", CharacterLength: sql.NullInt64{Int64: 255, Valid: true}},
+				{Name: "legacy", DataType: "text", IsNullable: true},
+			},
+		},
+		{Name: "audit_log", Columns: []providers.Column{{Name: "id", DataType: "bigint"}}},
+	}
+	target := []providers.Table{
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "email", DataType: "text", IsNullable: true, DefaultValue: sql.NullString{String: "''::text", Valid: true}},
+			},
+			Indexes: []providers.Index{{Name: "idx_users_email", Columns: []string{"email"}}},
+		},
+	}
+
+	output, err := providers.FormatDiffJSON("001_init", "002_change", providers.DiffSchemas(current, target))
+	require.NoError(t, err)
+
+	expected := `{
+		"from": "001_init",
+		"to": "002_change",
+		"added_tables": [],
+		"removed_tables": [
+			{"name": "audit_log", "columns": [{"name": "id", "type": "bigint", "nullable": false, "default": null, "collation": null}]}
+		],
+		"modified_tables": [
+			{
+				"name": "users",
+				"added_columns": [],
+				"removed_columns": [{"name": "legacy", "type": "text", "nullable": true, "default": null, "collation": null}],
+				"changed_columns": [
+					{
+						"name": "email",
+						"before": {"name": "email", "type": "varchar(255)", "nullable": false, "default": null, "collation": null},
+						"after": {"name": "email", "type": "text", "nullable": true, "default": "''::text", "collation": null}
+					}
+				],
+				"column_order_changed": false,
+				"primary_key": null,
+				"added_indexes": [{"name": "idx_users_email", "columns": ["email"]}],
+				"removed_indexes": [],
+				"added_unique_constraints": [],
+				"removed_unique_constraints": [],
+				"added_exclusion_constraints": [],
+				"removed_exclusion_constraints": [],
+				"added_foreign_keys": [],
+				"removed_foreign_keys": [],
+				"added_triggers": [],
+				"removed_triggers": []
+			}
+		]
+	}`
+	assert.JSONEq(t, expected, output)
+}
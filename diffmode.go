@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/alc6/mig2schema/providers"
+)
+
+// Values of --diff-format
+const (
+	diffFormatText = "text"
+	diffFormatJSON = "json"
+)
+
+// writeSchemaChanges writes the report of the changes kept by filter to w in
+// the given format, checked with the flags, and reports whether there were
+// any
+func writeSchemaChanges(w io.Writer, changes schemaChanges, filter providers.DiffFilter, format string) (bool, error) {
+	diff := filter.Apply(changes.Diff)
+
+	switch format {
+	case diffFormatJSON:
+		output, err := providers.FormatDiffJSON(changes.From, changes.To, diff)
+		if err != nil {
+			return false, fmt.Errorf("failed to encode diff: %w", err)
+		}
+		fmt.Fprint(w, output)
+	default:
+		switch {
+		case !diff.IsEmpty():
+			fmt.Fprintf(w, "Schema changes from %s to %s:\n", changes.From, changes.To)
+			fmt.Fprint(w, providers.FormatDiffReport(diff))
+		case changes.Diff.IsEmpty():
+			fmt.Fprintf(w, "No schema changes from %s to %s\n", changes.From, changes.To)
+		default:
+			fmt.Fprintf(w, "No schema changes from %s to %s outside the ignored categories\n", changes.From, changes.To)
+		}
+	}
+	return !diff.IsEmpty(), nil
+}
+
+// diffFilter returns the filter selected by --diff-only and --diff-ignore,
+// or an empty one with --diff-verbose
+func diffFilter() (providers.DiffFilter, error) {
+	if diffVerbose {
+		return providers.DiffFilter{}, nil
+	}
+	return providers.NewDiffFilter(diffOnly, diffIgnore)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// dockerProbeTimeout bounds how long the Docker daemon may take to answer a
+// ping before it is considered unavailable
+const dockerProbeTimeout = 3 * time.Second
+
+// dockerProbe caches the result of the first ping, the daemon is not probed
+// again during a run
+var dockerProbe struct {
+	once sync.Once
+	err  error
+}
+
+// checkDocker reports whether the Docker daemon is reachable, returning the
+// reason when it is not
+func checkDocker() error {
+	dockerProbe.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), dockerProbeTimeout)
+		defer cancel()
+		dockerProbe.err = pingDocker(ctx)
+	})
+	return dockerProbe.err
+}
+
+// pingDocker connects to the daemon testcontainers would use and pings it.
+// testcontainers panics when it finds no Docker host at all, which is
+// reported as an error instead.
+func pingDocker(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	client, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	_, err = client.Ping(ctx)
+	return err
+}
+
+// dockerCheck is the check behind requireDocker, replaced in tests
+var dockerCheck = checkDocker
+
+// requireDocker fails early when the migrations need a container and Docker
+// is not running, rather than with an error from deep inside testcontainers
+func requireDocker() error {
+	if err := dockerCheck(); err != nil {
+		return withExitCode(exitSetupFailed, fmt.Errorf("Docker is required but not available: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireDocker(t *testing.T) {
+	defer func(check func() error) { dockerCheck = check }(dockerCheck)
+
+	dockerCheck = func() error { return nil }
+	assert.NoError(t, requireDocker())
+
+	dockerCheck = func() error { return errors.New("cannot connect to the Docker daemon") }
+	err := requireDocker()
+	require.Error(t, err)
+	assert.Equal(t, "Docker is required but not available: cannot connect to the Docker daemon", err.Error())
+	assert.Equal(t, exitSetupFailed, exitCode(err))
+}
+
+func TestCheckDockerIsCached(t *testing.T) {
+	first := checkDocker()
+	assert.Equal(t, first, checkDocker())
+}
+
+func TestUsesSchemaCache(t *testing.T) {
+	defer resetCommand()
+	defer func(images []string) { pgImages = images }(pgImages)
+
+	assert.True(t, usesSchemaCache())
+
+	noCache = true
+	assert.False(t, usesSchemaCache())
+	noCache = false
+
+	watchMode = true
+	assert.False(t, usesSchemaCache())
+	watchMode = false
+
+	pgImages = []string{"postgres:15-alpine", "postgres:16-alpine"}
+	assert.False(t, usesSchemaCache())
+}
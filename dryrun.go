@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/alc6/mig2schema/providers"
+)
+
+// checkMigrationSyntax reads the up file of a migration and checks its SQL
+// without executing it
+func checkMigrationSyntax(migration Migration) ([]providers.SyntaxError, error) {
+	content, err := migration.readUpSQL()
+	if err != nil {
+		return nil, err
+	}
+	return providers.CheckSyntax(string(content)), nil
+}
+
+// syntaxCheckDescription tells what checkMigrationSyntax does in this build,
+// for the help of --dry-run and strict_validate
+func syntaxCheckDescription() string {
+	if providers.SyntaxParser {
+		return "parses it with the PostgreSQL parser"
+	}
+	return "scans it for lexical errors (unterminated strings, unbalanced parentheses, unknown commands); a heuristic, not a parse, unless built with -tags pg_query"
+}
+
+// runDryRun discovers the migrations in migrationDir and checks the syntax of
+// every up file, writing a per-file report to w. It reports whether all files
+// passed. No database is started.
+func runDryRun(migrationDir string, migrationReader MigrationReader, w io.Writer) (bool, error) {
+	if migrationDirMissing(migrationDir) {
+		return false, withExitCode(exitMigrationDirNotFound, fmt.Errorf("migration directory does not exist: %s", migrationDir))
+	}
+
+	migrations, err := migrationReader.DiscoverMigrations(migrationDir)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse migrations: %w", err)
+	}
+
+	if len(migrations) == 0 {
+		return false, withExitCode(exitNoMigrations, fmt.Errorf("no migration files found in directory: %s", migrationDir))
+	}
+
+	errorCount := 0
+	failedFiles := 0
+	for _, migration := range migrations {
+		syntaxErrors, err := checkMigrationSyntax(migration)
+		if err != nil {
+			return false, err
+		}
+
+		if len(syntaxErrors) == 0 {
+			fmt.Fprintf(w, "%s: ok\n", migration.UpFile)
+			continue
+		}
+
+		failedFiles++
+		errorCount += len(syntaxErrors)
+		for _, syntaxErr := range syntaxErrors {
+			fmt.Fprintf(w, "%s:%d: %s\n", migration.UpFile, syntaxErr.Line, syntaxErr.Message)
+		}
+	}
+
+	if errorCount > 0 {
+		fmt.Fprintf(w, "dry run found %d syntax error(s) in %d of %d migration(s)\n", errorCount, failedFiles, len(migrations))
+		return false, nil
+	}
+
+	fmt.Fprintf(w, "dry run passed for %d migration(s)\n", len(migrations))
+	return true, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLexicalCheck(t *testing.T) {
+	t.Run("valid_script", func(t *testing.T) {
+		sql := `-- users
+create table users (
+	id serial primary key,
+	name text default 'it''s (fine)'
+);
+create function touch() returns trigger as $$
+begin
+	new.updated_at = now();
+	return new;
+end;
+$$ language plpgsql;`
+		assert.Empty(t, providers.LexicalCheck(sql))
+	})
+
+	t.Run("escape_string", func(t *testing.T) {
+		assert.Empty(t, providers.LexicalCheck(`insert into t values (E'it\'s (fine)', e'\\');`))
+		assert.NotEmpty(t, providers.LexicalCheck(`insert into t values (note'it\'s');`), "only a lone E prefix starts an escape string")
+	})
+
+	t.Run("nested_comment", func(t *testing.T) {
+		assert.Empty(t, providers.LexicalCheck("/* outer /* inner */ still a comment; */\nselect 1;"))
+	})
+
+	t.Run("unterminated_string", func(t *testing.T) {
+		sql := "create table t (id int);\ninsert into t values ('oops);\n"
+		errs := providers.LexicalCheck(sql)
+		require.NotEmpty(t, errs)
+		assert.Equal(t, 2, errs[0].Line)
+		assert.Contains(t, errs[0].Message, "unterminated string literal")
+	})
+
+	t.Run("unclosed_parenthesis", func(t *testing.T) {
+		sql := "create table t (\n\tid int,\n\tname text\n;\n"
+		errs := providers.LexicalCheck(sql)
+		require.Len(t, errs, 1)
+		assert.Equal(t, 1, errs[0].Line)
+		assert.Equal(t, "unclosed parenthesis", errs[0].Message)
+	})
+
+	t.Run("unexpected_closing_parenthesis", func(t *testing.T) {
+		errs := providers.LexicalCheck("select 1);")
+		require.Len(t, errs, 1)
+		assert.Equal(t, "unexpected closing parenthesis", errs[0].Message)
+	})
+
+	t.Run("unknown_statement", func(t *testing.T) {
+		errs := providers.LexicalCheck("create table t (id int);\n\ncraete index i on t (id);")
+		require.Len(t, errs, 1)
+		assert.Equal(t, 3, errs[0].Line)
+		assert.Contains(t, errs[0].Message, `"CRAETE"`)
+	})
+
+	t.Run("unterminated_dollar_quote", func(t *testing.T) {
+		errs := providers.LexicalCheck("do $body$ begin perform 1; end;")
+		require.NotEmpty(t, errs)
+		assert.Contains(t, errs[0].Message, "unterminated dollar-quoted string $body$")
+	})
+}
+
+func TestRunDryRun(t *testing.T) {
+	t.Run("valid_migrations", func(t *testing.T) {
+		tempDir := t.TempDir()
+		files := map[string]string{
+			"001_users.up.sql": "create table users (id serial primary key);",
+			"002_posts.up.sql": "create table posts (id serial primary key, user_id int references users(id));",
+		}
+		for filename, content := range files {
+			require.NoError(t, os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644))
+		}
+
+		var buf bytes.Buffer
+		passed, err := runDryRun(tempDir, NewFileMigrationReader(), &buf)
+		require.NoError(t, err)
+		assert.True(t, passed)
+		assert.Contains(t, buf.String(), "001_users.up.sql: ok")
+		assert.Contains(t, buf.String(), "dry run passed for 2 migration(s)")
+	})
+
+	t.Run("syntax_errors_reported_with_line", func(t *testing.T) {
+		tempDir := t.TempDir()
+		files := map[string]string{
+			"001_users.up.sql":  "create table users (id serial primary key);",
+			"002_broken.up.sql": "create table posts (\n\tid serial primary key,\n\ttitle text default 'untitled\n);",
+		}
+		for filename, content := range files {
+			require.NoError(t, os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644))
+		}
+
+		var buf bytes.Buffer
+		passed, err := runDryRun(tempDir, NewFileMigrationReader(), &buf)
+		require.NoError(t, err)
+		assert.False(t, passed)
+		assert.Contains(t, buf.String(), "002_broken.up.sql:3: unterminated string literal")
+		assert.Contains(t, buf.String(), "in 1 of 2 migration(s)")
+	})
+
+	t.Run("nonexistent_directory", func(t *testing.T) {
+		_, err := runDryRun("/non/existent/path", NewFileMigrationReader(), &bytes.Buffer{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "migration directory does not exist")
+	})
+}
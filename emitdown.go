@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/alc6/mig2schema/providers"
+)
+
+// runEmitDown discovers the migrations in migrationDir and writes their down
+// files to w in reverse order, as a single script that tears the schema down
+// again. Nothing is executed. Migrations without a down file are reported
+// with a warning and a comment in the script.
+func runEmitDown(migrationDir string, migrationReader MigrationReader, w io.Writer) error {
+	if migrationDirMissing(migrationDir) {
+		return withExitCode(exitMigrationDirNotFound, fmt.Errorf("migration directory does not exist: %s", migrationDir))
+	}
+
+	migrations, err := migrationReader.DiscoverMigrations(migrationDir)
+	if err != nil {
+		return fmt.Errorf("failed to parse migrations: %w", err)
+	}
+
+	if len(migrations) == 0 {
+		return withExitCode(exitNoMigrations, fmt.Errorf("no migration files found in directory: %s", migrationDir))
+	}
+
+	script, err := composeDownScript(migrations)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, script)
+	return err
+}
+
+// composeDownScript concatenates the down migrations, last migration first
+func composeDownScript(migrations []Migration) (string, error) {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("-- Teardown of %d migration(s), generated by mig2schema\n", len(migrations)))
+
+	missing := 0
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		content, err := migration.readDownSQL()
+		if err != nil {
+			return "", err
+		}
+
+		if migration.DownFile == "" {
+			missing++
+			slog.Warn("migration has no down file, its changes are not undone", "name", migration.Name)
+			sb.WriteString(fmt.Sprintf("\n-- %s: no down migration\n", migration.Name))
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("\n-- %s\n", migration.DownFile))
+		sb.WriteString(terminateScript(string(content)))
+	}
+
+	if missing > 0 {
+		slog.Warn("teardown script is incomplete", "missing", missing, "migrations", len(migrations))
+	}
+	return sb.String(), nil
+}
+
+// terminateScript trims a script and makes sure its last statement ends with
+// a semicolon, so that the script appended after it starts a new statement
+func terminateScript(content string) string {
+	content = strings.TrimSpace(content)
+	code := strings.TrimSpace(providers.StripComments(content))
+	switch {
+	case content == "":
+		return ""
+	case code == "" || strings.HasSuffix(code, ";"):
+	case strings.HasSuffix(content, code):
+		content += ";"
+	default:
+		// the script ends in a comment that would swallow the semicolon
+		content += "\n;"
+	}
+	return content + "\n"
+}
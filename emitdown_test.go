@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunEmitDown(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"001_create_users.up.sql":   "create table users (id serial primary key);",
+		"001_create_users.down.sql": "drop table users;\n",
+		"002_create_posts.up.sql":   "create table posts (id serial primary key);",
+		"002_create_posts.down.sql": "drop table posts -- no semicolon",
+		"003_seed.up.sql":           "insert into users default values;",
+		"004_add_email.up.sql":      "alter table users add column email text;",
+		"004_add_email.down.sql":    "alter table users drop column email",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, runEmitDown(dir, NewFileMigrationReader(), &out))
+
+	expected := "-- Teardown of 4 migration(s), generated by mig2schema\n" +
+		"\n-- " + filepath.Join(dir, "004_add_email.down.sql") + "\n" +
+		"alter table users drop column email;\n" +
+		"\n-- 003_seed: no down migration\n" +
+		"\n-- " + filepath.Join(dir, "002_create_posts.down.sql") + "\n" +
+		"drop table posts -- no semicolon\n;\n" +
+		"\n-- " + filepath.Join(dir, "001_create_users.down.sql") + "\n" +
+		"drop table users;\n"
+	assert.Equal(t, expected, out.String())
+}
+
+func TestRunEmitDownErrors(t *testing.T) {
+	t.Run("missing_directory", func(t *testing.T) {
+		err := runEmitDown(filepath.Join(t.TempDir(), "missing"), NewFileMigrationReader(), &bytes.Buffer{})
+		assert.Equal(t, exitMigrationDirNotFound, exitCode(err))
+	})
+
+	t.Run("no_migrations", func(t *testing.T) {
+		err := runEmitDown(t.TempDir(), NewFileMigrationReader(), &bytes.Buffer{})
+		assert.Equal(t, exitNoMigrations, exitCode(err))
+	})
+}
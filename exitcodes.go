@@ -0,0 +1,52 @@
+package main
+
+import "errors"
+
+// Exit codes of the command line tool. They are part of the documented
+// interface, so existing values must not change.
+const (
+	exitOK = 0
+	// exitFailure covers failed checks (--dry-run, --check-sequence, --golden,
+	// --strict-types, --fail-on-empty-schema, --lint, --validate-all, image
+	// matrix), changes found by --diff-from and errors without a more
+	// specific code
+	exitFailure              = 1
+	exitUsage                = 2
+	exitMigrationDirNotFound = 3
+	exitNoMigrations         = 4
+	exitMigrationFailed      = 5
+	exitExtractionFailed     = 6
+	exitSetupFailed          = 7
+)
+
+// exitError attaches an exit code to an error without changing its message
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode tags err with the exit code the command should end with
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// exitCode returns the exit code for err: exitOK for nil, the code attached
+// with withExitCode, or exitFailure
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return exitFailure
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, exitOK, exitCode(nil))
+	assert.Equal(t, exitFailure, exitCode(errors.New("boom")))
+	assert.Equal(t, exitSetupFailed, exitCode(fmt.Errorf("wrapped: %w", withExitCode(exitSetupFailed, errors.New("boom")))))
+	assert.Equal(t, "boom", withExitCode(exitUsage, errors.New("boom")).Error())
+}
+
+func TestProcessSchemaWithProviderExitCodes(t *testing.T) {
+	defer resetCommand()
+
+	oneMigration := &MockMigrationReader{
+		DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+			return []Migration{{Name: "001_test", UpSQL: []byte("create table users (id integer);")}}, nil
+		},
+	}
+	noMigrations := &MockMigrationReader{
+		DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+			return nil, nil
+		},
+	}
+	provider := &MockSchemaProvider{}
+	failingProvider := &MockSchemaProvider{
+		ExtractSchemaFunc: func(ctx context.Context, params providers.ExtractParams) (*providers.SchemaResult, error) {
+			return nil, errors.New("permission denied")
+		},
+	}
+
+	tests := []struct {
+		name      string
+		dir       string
+		reader    MigrationReader
+		dbManager DatabaseManager
+		provider  providers.SchemaProvider
+		setup     func()
+		expected  int
+	}{
+		{
+			name:      "invalid_format",
+			reader:    oneMigration,
+			dbManager: &MockDatabaseManager{},
+			provider:  provider,
+			setup:     func() { outputFormat = "yaml" },
+			expected:  exitUsage,
+		},
+		{
+			name:      "directory_not_found",
+			dir:       "/non/existent/directory",
+			reader:    oneMigration,
+			dbManager: &MockDatabaseManager{},
+			provider:  provider,
+			expected:  exitMigrationDirNotFound,
+		},
+		{
+			name:      "no_migrations",
+			reader:    noMigrations,
+			dbManager: &MockDatabaseManager{},
+			provider:  provider,
+			expected:  exitNoMigrations,
+		},
+		{
+			name:   "setup_failure",
+			reader: oneMigration,
+			dbManager: &MockDatabaseManager{
+				SetupFunc: func(ctx context.Context) error { return errors.New("docker not running") },
+			},
+			provider: provider,
+			expected: exitSetupFailed,
+		},
+		{
+			name:   "migration_failure",
+			reader: oneMigration,
+			dbManager: &MockDatabaseManager{
+				RunMigrationsFunc: func(ctx context.Context, migrations []Migration) error { return errors.New("syntax error") },
+			},
+			provider: provider,
+			expected: exitMigrationFailed,
+		},
+		{
+			name:      "extraction_failure",
+			reader:    oneMigration,
+			dbManager: &MockDatabaseManager{},
+			provider:  failingProvider,
+			expected:  exitExtractionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetCommand()
+			if tt.setup != nil {
+				tt.setup()
+			}
+			dir := tt.dir
+			if dir == "" {
+				dir = t.TempDir()
+			}
+
+			err := processSchemaWithProvider(context.Background(), dir, tt.reader, tt.dbManager, tt.provider, nil, nil)
+			assert.Error(t, err)
+			assert.Equal(t, tt.expected, exitCode(err))
+		})
+	}
+}
+
+func TestExecuteMig2SchemaExitCodes(t *testing.T) {
+	defer resetCommand()
+
+	t.Run("unknown_provider", func(t *testing.T) {
+		resetCommand()
+		providerName = "nonexistent"
+		err := executeMig2Schema(context.Background(), []string{t.TempDir()}, providers.NewDefaultRegistry())
+		assert.Equal(t, exitUsage, exitCode(err))
+		assert.Contains(t, err.Error(), "unknown provider: nonexistent")
+	})
+
+	t.Run("unsupported_archive", func(t *testing.T) {
+		resetCommand()
+		migrationsArchive = "migrations.zip"
+		err := executeMig2Schema(context.Background(), nil, providers.NewDefaultRegistry())
+		assert.Equal(t, exitUsage, exitCode(err))
+	})
+
+	t.Run("dry_run_directory_not_found", func(t *testing.T) {
+		resetCommand()
+		dryRun = true
+		err := executeMig2Schema(context.Background(), []string{"/non/existent/directory"}, providers.NewDefaultRegistry())
+		assert.Equal(t, exitMigrationDirNotFound, exitCode(err))
+	})
+
+	t.Run("dry_run_syntax_errors", func(t *testing.T) {
+		resetCommand()
+		dryRun = true
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "001_broken.up.sql"), []byte("create table broken (id int;"), 0644))
+		err := executeMig2Schema(context.Background(), []string{dir}, providers.NewDefaultRegistry())
+		assert.Equal(t, exitFailure, exitCode(err))
+	})
+}
+
+func TestSelectProvider(t *testing.T) {
+	registry := providers.NewProviderRegistry()
+	registry.Register(&MockSchemaProvider{ProviderName: "pg_dump", Unavailable: true})
+	registry.Register(&MockSchemaProvider{ProviderName: "native"})
+
+	t.Run("single", func(t *testing.T) {
+		provider, err := selectProvider(registry, "native")
+		require.NoError(t, err)
+		assert.Equal(t, "native", provider.Name())
+	})
+
+	t.Run("single_unavailable", func(t *testing.T) {
+		_, err := selectProvider(registry, "pg_dump")
+		require.Error(t, err)
+		assert.Equal(t, "provider pg_dump is not available in this environment", err.Error())
+		assert.Equal(t, exitUsage, exitCode(err))
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		provider, err := selectProvider(registry, "pg_dump, native")
+		require.NoError(t, err)
+		assert.Equal(t, "native", provider.Name())
+	})
+
+	t.Run("first_available_wins", func(t *testing.T) {
+		provider, err := selectProvider(registry, "native,pg_dump")
+		require.NoError(t, err)
+		assert.Equal(t, "native", provider.Name())
+	})
+
+	t.Run("none_available", func(t *testing.T) {
+		_, err := selectProvider(registry, "pg_dump,sqlc")
+		require.Error(t, err)
+		assert.Equal(t, "no available provider, tried: pg_dump (not available), sqlc (unknown)", err.Error())
+		assert.Equal(t, exitUsage, exitCode(err))
+	})
+}
+
+func TestProviderRegistryOptions(t *testing.T) {
+	custom := &MockSchemaProvider{ProviderName: "cockroach"}
+	native := &MockSchemaProvider{ProviderName: "native"}
+	registry := providers.NewDefaultRegistry(providers.WithProvider(custom), providers.WithProvider(native))
+
+	provider, err := selectProvider(registry, "cockroach")
+	require.NoError(t, err)
+	assert.Same(t, custom, provider)
+
+	provider, err = selectProvider(registry, "native")
+	require.NoError(t, err)
+	assert.Same(t, native, provider, "custom providers replace built-in ones of the same name")
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/alc6/mig2schema/providers"
+)
+
+// targetDatabaseName is the scratch database the target schema is created in
+const targetDatabaseName = "mig2schema_target"
+
+// generateMigrationCore computes the SQL needed to go from the schema produced
+// by the migrations in migrationDir to the target schema
+func generateMigrationCore(ctx context.Context, migrationDir, targetSchema, pgImage string) (string, error) {
+	return generateMigrationCoreWithDeps(ctx, migrationDir, targetSchema, NewFileMigrationReader(), NewPostgreSQLManager(pgImage))
+}
+
+// generateMigrationCoreWithDeps is the testable version with dependency injection.
+// The target schema is created in a scratch database on the same server so that
+// both sides are described by PostgreSQL itself before being compared.
+func generateMigrationCoreWithDeps(ctx context.Context, migrationDir, targetSchema string,
+	migrationReader MigrationReader, dbManager DatabaseManager) (string, error) {
+	targetSQL, err := targetSchemaSQL(targetSchema)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(migrationDir); os.IsNotExist(err) {
+		return "", fmt.Errorf("migration directory does not exist: %s", migrationDir)
+	}
+
+	migrations, err := migrationReader.DiscoverMigrations(migrationDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse migrations: %v", err)
+	}
+
+	if err := dbManager.Setup(ctx); err != nil {
+		return "", fmt.Errorf("failed to setup postgresql: %v", err)
+	}
+	defer func() {
+		if err := dbManager.Close(ctx); err != nil {
+			slog.Error("failed to cleanup database", "error", err)
+		}
+	}()
+
+	if err := dbManager.RunMigrations(ctx, migrations); err != nil {
+		return "", fmt.Errorf("failed to run migrations: %v", err)
+	}
+
+	current, err := providers.ExtractSchemaFromDB(dbManager.GetDB())
+	if err != nil {
+		return "", fmt.Errorf("failed to extract current schema: %v", err)
+	}
+
+	target, err := extractTargetSchema(ctx, dbManager, targetSQL)
+	if err != nil {
+		return "", err
+	}
+
+	diff := providers.DiffSchemas(current, target)
+	if diff.IsEmpty() {
+		return "-- schema is already up to date\n", nil
+	}
+
+	return providers.FormatMigrationSQL(diff), nil
+}
+
+// targetSchemaSQL returns the DDL describing the target schema. Input that
+// looks like JSON is decoded as a list of tables (or an object with a
+// "tables" key) using the same representation as the extracted schema and
+// rendered to SQL; anything else is treated as SQL.
+func targetSchemaSQL(targetSchema string) (string, error) {
+	trimmed := strings.TrimSpace(targetSchema)
+	if trimmed == "" {
+		return "", fmt.Errorf("target schema is empty")
+	}
+
+	if !strings.HasPrefix(trimmed, "[") && !strings.HasPrefix(trimmed, "{") {
+		return targetSchema, nil
+	}
+
+	tables, err := parseTargetTables([]byte(trimmed))
+	if err != nil {
+		return "", err
+	}
+	if len(tables) == 0 {
+		return "", fmt.Errorf("target schema does not define any tables")
+	}
+
+	return providers.FormatMigrationSQL(providers.DiffSchemas(nil, tables)), nil
+}
+
+func parseTargetTables(data []byte) ([]providers.Table, error) {
+	var tables []providers.Table
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &tables); err != nil {
+			return nil, fmt.Errorf("failed to parse target schema JSON: %w", err)
+		}
+		return tables, nil
+	}
+
+	var wrapper struct {
+		Tables []providers.Table `json:"tables"`
+	}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return nil, fmt.Errorf("failed to parse target schema JSON: %w", err)
+	}
+	return wrapper.Tables, nil
+}
+
+// extractTargetSchema creates a scratch database next to the migrated one,
+// applies the target DDL to it and extracts the resulting tables
+func extractTargetSchema(ctx context.Context, dbManager DatabaseManager, targetSQL string) ([]providers.Table, error) {
+	db := dbManager.GetDB()
+	if _, err := db.ExecContext(ctx, "create database "+targetDatabaseName); err != nil {
+		return nil, fmt.Errorf("failed to create target database: %w", err)
+	}
+	defer func() {
+		if _, err := db.Exec("drop database if exists " + targetDatabaseName); err != nil {
+			slog.Warn("failed to drop target database", "error", err)
+		}
+	}()
+
+	connStr, err := withDatabaseName(dbManager.GetConnectionString(), targetDatabaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	targetDB, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to target database: %w", err)
+	}
+	defer targetDB.Close()
+
+	if err := execMigration(ctx, targetDB, Migration{Name: "target_schema"}, targetSQL, nil); err != nil {
+		return nil, fmt.Errorf("failed to apply target schema: %w", err)
+	}
+
+	tables, err := providers.ExtractSchemaFromDB(targetDB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract target schema: %w", err)
+	}
+	return tables, nil
+}
+
+// withDatabaseName returns the URL connection string pointing at another
+// database on the same server
+func withDatabaseName(connStr, dbName string) (string, error) {
+	u, err := url.Parse(connStr)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse connection string: %w", err)
+	}
+	u.Path = "/" + dbName
+	return u.String(), nil
+}
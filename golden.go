@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// compareGolden compares output with the golden file at path and reports
+// whether they match. A mismatch is written to w as a unified diff from the
+// golden file to the current output. With update, the golden file is
+// rewritten with output instead.
+func compareGolden(path, output string, update bool, w io.Writer) (bool, error) {
+	if update {
+		if err := os.WriteFile(path, []byte(output), 0644); err != nil {
+			return false, fmt.Errorf("failed to write golden file: %w", err)
+		}
+		slog.Info("golden file updated", "path", path)
+		return true, nil
+	}
+
+	golden, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("golden file %s does not exist, create it with --update-golden", path)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read golden file: %w", err)
+	}
+
+	if string(golden) == output {
+		slog.Info("schema matches golden file", "path", path)
+		return true, nil
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        goldenLines(string(golden)),
+		B:        goldenLines(output),
+		FromFile: path,
+		ToFile:   "current schema",
+		Context:  3,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to diff golden file: %w", err)
+	}
+	fmt.Fprint(w, diff)
+	return false, nil
+}
+
+// goldenLines splits text into lines for the differ, keeping line endings. A
+// missing newline at the end is marked like diff does, so that it shows up
+// as a change.
+func goldenLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	last := len(lines) - 1
+	if lines[last] == "" {
+		return lines[:last]
+	}
+	lines[last] += "\n\\ No newline at end of file\n"
+	return lines
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.golden")
+	output := "Table: users\nColumns:\n  - id INTEGER NOT NULL\n  - email TEXT NOT NULL\n"
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := compareGolden(path, output, false, &bytes.Buffer{})
+		assert.ErrorContains(t, err, "create it with --update-golden")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		var buf bytes.Buffer
+		matches, err := compareGolden(path, output, true, &buf)
+		require.NoError(t, err)
+		assert.True(t, matches)
+		assert.Empty(t, buf.String())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, output, string(data))
+	})
+
+	t.Run("matches", func(t *testing.T) {
+		var buf bytes.Buffer
+		matches, err := compareGolden(path, output, false, &buf)
+		require.NoError(t, err)
+		assert.True(t, matches)
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("differs", func(t *testing.T) {
+		drifted := "Table: users\nColumns:\n  - id INTEGER NOT NULL\n  - email CHARACTER VARYING NOT NULL\n"
+		var buf bytes.Buffer
+		matches, err := compareGolden(path, drifted, false, &buf)
+		require.NoError(t, err)
+		assert.False(t, matches)
+		assert.Equal(t, "--- "+path+"\n"+
+			"+++ current schema\n"+
+			"@@ -1,4 +1,4 @@\n"+
+			" Table: users\n"+
+			" Columns:\n"+
+			"   - id INTEGER NOT NULL\n"+
+			"-  - email TEXT NOT NULL\n"+
+			"+  - email CHARACTER VARYING NOT NULL\n", buf.String())
+	})
+}
+
+func TestGoldenFlags(t *testing.T) {
+	defer resetCommand()
+
+	updateGolden = true
+	err := executeMig2Schema(context.Background(), []string{"migrations"}, providers.NewDefaultRegistry())
+	require.Error(t, err)
+	assert.Equal(t, exitUsage, exitCode(err))
+	assert.Contains(t, err.Error(), "--update-golden requires --golden")
+}
+
+func TestGoldenLines(t *testing.T) {
+	assert.Equal(t, []string{"a\n", "b\n"}, goldenLines("a\nb\n"))
+	assert.Equal(t, []string{"a\n", "b\n\\ No newline at end of file\n"}, goldenLines("a\nb"))
+	assert.Empty(t, goldenLines(""))
+}
@@ -3,9 +3,12 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
-	"os"
+	"path"
+	"regexp"
 	"time"
 
 	"github.com/testcontainers/testcontainers-go"
@@ -16,31 +19,146 @@ import (
 	"github.com/alc6/mig2schema/providers"
 )
 
+// defaultStartupTimeout is how long Setup waits for the container to be ready
+const defaultStartupTimeout = 5 * time.Minute
+
+// imageReferencePattern matches a Docker image reference: an optional
+// registry host, a repository path, an optional tag and an optional digest
+var imageReferencePattern = regexp.MustCompile(`^(?:[a-zA-Z0-9.-]+(?::[0-9]+)?/)?[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*(?::[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?(?:@sha256:[a-f0-9]{64})?$`)
+
+// errDockerUnreachable is returned by Setup when the Docker daemon does not
+// answer, wrapping the error of the probe
+var errDockerUnreachable = errors.New("Docker daemon not reachable; start Docker or use --provider with --database-url")
+
 type PostgreSQLManager struct {
-	container testcontainers.Container
-	db        *sql.DB
-	connStr   string
-	image     string
+	container      testcontainers.Container
+	db             *sql.DB
+	connStr        string
+	image          string
+	startupTimeout time.Duration
+	waitStrategy   wait.Strategy
+	connectRetry   connectRetry
+	// variables are substituted into migrations, see WithVariables
+	variables map[string]string
+	// settings are applied to the session by Setup, see WithSettings
+	settings []DBSetting
+	// dockerCheck probes the daemon before any container work, see checkDocker
+	dockerCheck func() error
+	// statementHook sees each migration statement, see WithStatementHook
+	statementHook StatementHook
+}
+
+// PostgreSQLOption configures a PostgreSQLManager
+type PostgreSQLOption func(*PostgreSQLManager)
+
+// WithStartupTimeout sets how long to wait for the container to become ready
+func WithStartupTimeout(d time.Duration) PostgreSQLOption {
+	return func(p *PostgreSQLManager) {
+		p.startupTimeout = d
+	}
+}
+
+// WithWaitStrategy replaces the readiness check, for images that do not log
+// the standard PostgreSQL readiness message. The startup timeout is not
+// applied to a custom strategy.
+func WithWaitStrategy(strategy wait.Strategy) PostgreSQLOption {
+	return func(p *PostgreSQLManager) {
+		p.waitStrategy = strategy
+	}
+}
+
+// WithConnectRetry sets how many times Setup tries to connect to the started
+// container and the initial wait between attempts, which doubles after each
+// failure
+func WithConnectRetry(attempts int, backoff time.Duration) PostgreSQLOption {
+	return func(p *PostgreSQLManager) {
+		p.connectRetry = connectRetry{attempts: attempts, backoff: backoff}
+	}
+}
+
+// WithVariables substitutes psql-style :'name', :"name" and :name references
+// in migrations with the given values before they are executed. Without
+// this option migrations run as written.
+func WithVariables(vars map[string]string) PostgreSQLOption {
+	return func(p *PostgreSQLManager) {
+		p.variables = vars
+	}
+}
+
+// WithSettings applies session settings, such as search_path, in order once
+// Setup has connected, so that they are in effect when migrations run and
+// when the schema is extracted
+func WithSettings(settings []DBSetting) PostgreSQLOption {
+	return func(p *PostgreSQLManager) {
+		p.settings = settings
+	}
+}
+
+// WithStatementHook calls hook with each migration statement before it is
+// executed, see StatementHook. Variables are already substituted.
+func WithStatementHook(hook StatementHook) PostgreSQLOption {
+	return func(p *PostgreSQLManager) {
+		p.statementHook = hook
+	}
+}
+
+func NewPostgreSQLManager(image string, opts ...PostgreSQLOption) DatabaseManager {
+	p := &PostgreSQLManager{image: image, startupTimeout: defaultStartupTimeout, connectRetry: defaultConnectRetry(), dockerCheck: checkDocker}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
-func NewPostgreSQLManager(image string) DatabaseManager {
-	return &PostgreSQLManager{image: image}
+// validateImageReference rejects image strings Docker would not accept, so
+// that a typo fails before any container work starts
+func validateImageReference(image string) error {
+	if image == "" {
+		return fmt.Errorf("postgresql image must not be empty")
+	}
+	if !imageReferencePattern.MatchString(image) {
+		return fmt.Errorf("invalid postgresql image reference: %q", image)
+	}
+	return nil
 }
 
-func (p *PostgreSQLManager) Setup(ctx context.Context) error {
-	slog.Debug("starting postgresql container", "image", p.image)
+func (p *PostgreSQLManager) Setup(ctx context.Context) (err error) {
+	if err := validateImageReference(p.image); err != nil {
+		return err
+	}
+	if err := p.dockerCheck(); err != nil {
+		return fmt.Errorf("%w: %w", errDockerUnreachable, err)
+	}
+
+	waitStrategy := p.waitStrategy
+	if waitStrategy == nil {
+		waitStrategy = wait.ForLog("database system is ready to accept connections").
+			WithOccurrence(2).
+			WithStartupTimeout(p.startupTimeout)
+	}
+
+	slog.Debug("starting postgresql container", "image", p.image, "startupTimeout", p.startupTimeout)
 	container, err := postgres.Run(ctx,
 		p.image,
 		postgres.WithDatabase("testdb"),
 		postgres.WithUsername("testuser"),
 		postgres.WithPassword("testpass"),
-		testcontainers.WithWaitStrategy(
-			wait.ForLog("database system is ready to accept connections").
-				WithOccurrence(2).
-				WithStartupTimeout(5*time.Minute)),
+		testcontainers.WithWaitStrategy(waitStrategy),
 	)
+	// The container is terminated on any failure below, so that it does not
+	// outlive a failed Setup
+	defer func() {
+		if err != nil && container != nil {
+			if termErr := container.Terminate(ctx); termErr != nil {
+				slog.Warn("failed to terminate container", "error", termErr)
+			}
+		}
+	}()
 	if err != nil {
-		return fmt.Errorf("failed to start container: %w", err)
+		if p.waitStrategy == nil {
+			return fmt.Errorf("postgresql container %s failed to start or become ready within %s: %w", p.image, p.startupTimeout, err)
+		}
+		return fmt.Errorf("postgresql container %s failed to start or become ready: %w", p.image, err)
 	}
 
 	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
@@ -49,13 +167,14 @@ func (p *PostgreSQLManager) Setup(ctx context.Context) error {
 	}
 	slog.Debug("got database connection string", "connStr", connStr)
 
-	db, err := sql.Open("postgres", connStr)
+	db, err := connectWithRetry(ctx, connStr, p.connectRetry)
 	if err != nil {
-		return fmt.Errorf("failed to open database connection: %w", err)
+		return err
 	}
 
-	if err := db.Ping(); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
+	if err := applyDBSettings(db, p.settings); err != nil {
+		db.Close()
+		return err
 	}
 
 	p.container = container
@@ -76,17 +195,25 @@ func (p *PostgreSQLManager) Close(ctx context.Context) error {
 	return nil
 }
 
-func (p *PostgreSQLManager) RunMigrations(migrations []Migration) error {
+func (p *PostgreSQLManager) RunMigrations(ctx context.Context, migrations []Migration) error {
 	for _, migration := range migrations {
 		slog.Info("running migration", "name", migration.Name, "file", migration.UpFile)
 		
-		content, err := os.ReadFile(migration.UpFile)
+		content, err := migration.readUpSQL()
 		if err != nil {
-			return fmt.Errorf("failed to read migration file %s: %w", migration.UpFile, err)
+			return err
 		}
 
-		if _, err := p.db.Exec(string(content)); err != nil {
-			return fmt.Errorf("failed to execute migration %s: %w", migration.Name, err)
+		script := string(content)
+		if p.variables != nil {
+			script, err = providers.SubstituteVariables(script, p.variables)
+			if err != nil {
+				return fmt.Errorf("failed to substitute variables in migration %s: %w", migration.Name, err)
+			}
+		}
+
+		if err := execMigration(ctx, p.db, migration, script, p.statementHook); err != nil {
+			return err
 		}
 		
 		slog.Debug("migration completed successfully", "name", migration.Name)
@@ -103,14 +230,31 @@ func (p *PostgreSQLManager) GetConnectionString() string {
 	return p.connStr
 }
 
-type PostgreSQLSchemaExtractor struct{}
+type PostgreSQLSchemaExtractor struct {
+	// schemaName is the schema to extract, public when empty
+	schemaName   string
+	includeViews bool
+}
 
 func NewPostgreSQLSchemaExtractor() SchemaExtractor {
 	return &PostgreSQLSchemaExtractor{}
 }
 
+// newScopedSchemaExtractor creates a SchemaExtractor reading schemaName
+// instead of public, optionally including its views
+func newScopedSchemaExtractor(schemaName string, includeViews bool) SchemaExtractor {
+	return &PostgreSQLSchemaExtractor{schemaName: schemaName, includeViews: includeViews}
+}
+
 func (e *PostgreSQLSchemaExtractor) ExtractSchema(db *sql.DB) ([]providers.Table, error) {
-	return ExtractSchema(db)
+	if e.schemaName == "" && !e.includeViews {
+		return ExtractSchema(db)
+	}
+	schemaName := e.schemaName
+	if schemaName == "" {
+		schemaName = "public"
+	}
+	return providers.ExtractTables(db, schemaName, e.includeViews)
 }
 
 func (e *PostgreSQLSchemaExtractor) FormatSchema(tables []providers.Table) string {
@@ -121,12 +265,38 @@ func (e *PostgreSQLSchemaExtractor) FormatSchemaAsSQL(tables []providers.Table)
 	return FormatSchemaAsSQL(tables)
 }
 
-type FileMigrationReader struct{}
+type FileMigrationReader struct {
+	options migrationParseOptions
+}
 
-func NewFileMigrationReader() MigrationReader {
-	return &FileMigrationReader{}
+func NewFileMigrationReader(opts ...MigrationReaderOption) MigrationReader {
+	return &FileMigrationReader{options: newMigrationParseOptions(opts)}
 }
 
 func (r *FileMigrationReader) DiscoverMigrations(dir string) ([]Migration, error) {
-	return ParseMigrations(dir)
+	return parseMigrationsDir(dir, r.options)
+}
+
+// FSMigrationReader discovers migrations in an fs.FS, such as an embed.FS
+// compiled into the binary
+type FSMigrationReader struct {
+	fsys    fs.FS
+	root    string
+	options migrationParseOptions
+}
+
+func NewFSMigrationReader(fsys fs.FS, root string, opts ...MigrationReaderOption) MigrationReader {
+	return &FSMigrationReader{fsys: fsys, root: root, options: newMigrationParseOptions(opts)}
+}
+
+// DiscoverMigrations walks dir relative to the reader's root; an empty dir
+// walks the root itself. UpFile and DownFile are the paths inside the FS.
+func (r *FSMigrationReader) DiscoverMigrations(dir string) ([]Migration, error) {
+	root := path.Join(r.root, dir)
+	if root == "" {
+		root = "."
+	}
+	return parseMigrationsFS(r.fsys, root, func(name string) string {
+		return name
+	}, r.options)
 }
\ No newline at end of file
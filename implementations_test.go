@@ -2,9 +2,16 @@ package main
 
 import (
 	"context"
+	"embed"
+	"errors"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/wait"
 	"github.com/alc6/mig2schema/providers"
 )
 
@@ -13,7 +20,61 @@ func TestPostgreSQLManager(t *testing.T) {
 		manager := NewPostgreSQLManager("postgres:16-alpine")
 		assert.NotNil(t, manager)
 		var _ DatabaseManager = manager
+		assert.Equal(t, defaultStartupTimeout, manager.(*PostgreSQLManager).startupTimeout)
+		assert.Equal(t, defaultConnectRetry(), manager.(*PostgreSQLManager).connectRetry)
 	})
+
+	t.Run("options", func(t *testing.T) {
+		strategy := wait.ForListeningPort("5432/tcp")
+		manager := NewPostgreSQLManager("postgres:16-alpine",
+			WithStartupTimeout(30*time.Second),
+			WithWaitStrategy(strategy),
+			WithConnectRetry(10, time.Second),
+		).(*PostgreSQLManager)
+
+		assert.Equal(t, 30*time.Second, manager.startupTimeout)
+		assert.Equal(t, strategy, manager.waitStrategy)
+		assert.Equal(t, connectRetry{attempts: 10, backoff: time.Second}, manager.connectRetry)
+	})
+
+	t.Run("setup_rejects_invalid_image", func(t *testing.T) {
+		err := NewPostgreSQLManager("Postgres 16").Setup(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid postgresql image reference")
+	})
+
+	t.Run("setup_reports_unreachable_docker", func(t *testing.T) {
+		probeErr := errors.New("Cannot connect to the Docker daemon at unix:///var/run/docker.sock")
+		manager := NewPostgreSQLManager("postgres:16-alpine").(*PostgreSQLManager)
+		manager.dockerCheck = func() error { return probeErr }
+
+		err := manager.Setup(context.Background())
+		require.Error(t, err)
+		assert.ErrorIs(t, err, errDockerUnreachable)
+		assert.ErrorIs(t, err, probeErr)
+		assert.True(t, strings.HasPrefix(err.Error(), "Docker daemon not reachable; start Docker or use --provider with --database-url: "))
+		assert.Nil(t, manager.container)
+	})
+}
+
+func TestValidateImageReference(t *testing.T) {
+	valid := []string{
+		"postgres:16-alpine",
+		"postgres",
+		"pgvector/pgvector:pg16",
+		"postgis/postgis:16-3.4",
+		"ghcr.io/org/postgres:latest",
+		"localhost:5000/team/postgres:16",
+		"postgres@sha256:" + strings.Repeat("a", 64),
+	}
+	for _, image := range valid {
+		assert.NoError(t, validateImageReference(image), image)
+	}
+
+	invalid := []string{"", "Postgres:16", "postgres 16", "postgres:", ":16", "postgres::16"}
+	for _, image := range invalid {
+		assert.Error(t, validateImageReference(image), image)
+	}
 }
 
 func TestPostgreSQLSchemaExtractor(t *testing.T) {
@@ -51,7 +112,53 @@ func TestFileMigrationReader(t *testing.T) {
 	})
 }
 
+//go:embed examples/up_and_down
+var embeddedMigrations embed.FS
+
+func TestFSMigrationReader(t *testing.T) {
+	t.Run("embed_fs", func(t *testing.T) {
+		reader := NewFSMigrationReader(embeddedMigrations, "examples")
+		var _ MigrationReader = reader
+
+		migrations, err := reader.DiscoverMigrations("up_and_down")
+		require.NoError(t, err)
+		require.Len(t, migrations, 2)
+
+		assert.Equal(t, "001_create_users", migrations[0].Name)
+		assert.Equal(t, "examples/up_and_down/001_create_users.up.sql", migrations[0].UpFile)
+		assert.Equal(t, "examples/up_and_down/001_create_users.down.sql", migrations[0].DownFile)
+		assert.Contains(t, string(migrations[0].UpSQL), "create table users")
+		assert.NotEmpty(t, migrations[0].DownSQL)
+	})
+
+	t.Run("root_only", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"002_posts.up.sql":    {Data: []byte("create table posts (id int);")},
+			"001_users.up.sql":    {Data: []byte("create table users (id int);")},
+			"nested/003_x.up.sql": {Data: []byte("create table x (id int);")},
+			"nested/notes.txt":    {Data: []byte("ignored")},
+			"001_users.down.sql":  {Data: []byte("drop table users;")},
+		}
+
+		migrations, err := NewFSMigrationReader(fsys, ".").DiscoverMigrations("")
+		require.NoError(t, err)
+		require.Len(t, migrations, 3)
+		assert.Equal(t, "001_users", migrations[0].Name)
+		assert.Equal(t, "drop table users;", string(migrations[0].DownSQL))
+		assert.Equal(t, "nested/003_x.up.sql", migrations[2].UpFile)
+	})
+
+	t.Run("missing_directory", func(t *testing.T) {
+		_, err := NewFSMigrationReader(fstest.MapFS{}, "migrations").DiscoverMigrations("")
+		assert.Error(t, err)
+	})
+}
+
 func TestImplementationsIntegration(t *testing.T) {
+	if !isDockerAvailable() {
+		t.Skip("docker not available, skipping integration test")
+	}
+
 	t.Run("manager_lifecycle", func(t *testing.T) {
 		ctx := context.Background()
 		manager := NewPostgreSQLManager("postgres:16-alpine").(*PostgreSQLManager)
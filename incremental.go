@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alc6/mig2schema/providers"
+)
+
+// findMigration returns the position of the migration called name. The name
+// may also be given as the up file name, with or without its directory.
+func findMigration(migrations []Migration, name string) (int, error) {
+	name = strings.TrimSuffix(filepath.Base(name), ".up.sql")
+
+	names := make([]string, len(migrations))
+	for i, migration := range migrations {
+		if migration.Name == name {
+			return i, nil
+		}
+		names[i] = migration.Name
+	}
+
+	message := fmt.Sprintf("migration %q not found", name)
+	if suggestions := closestNames(name, names, maxTableSuggestions); len(suggestions) > 0 {
+		message += fmt.Sprintf("; did you mean %s?", strings.Join(suggestions, ", "))
+	}
+	return 0, fmt.Errorf("%s", message)
+}
+
+// targetMigrations returns the migrations up to and including target, given
+// as a migration name, an up file name or a version number. A version is
+// compared by value, so 3 selects 003_add_posts; when several migrations
+// share the version, all of them are included.
+func targetMigrations(migrations []Migration, target string) ([]Migration, error) {
+	name := strings.TrimSuffix(filepath.Base(target), ".up.sql")
+	version, versionErr := strconv.ParseInt(target, 10, 64)
+
+	last := -1
+	for i, migration := range migrations {
+		if migration.Name == name {
+			last = i
+			break
+		}
+		if versionErr == nil {
+			if v, err := strconv.ParseInt(migrationVersion(migration.Name), 10, 64); err == nil && v == version {
+				last = i
+			}
+		}
+	}
+	if last >= 0 {
+		return migrations[:last+1], nil
+	}
+
+	var versions []string
+	for _, migration := range migrations {
+		if version := migrationVersion(migration.Name); version != "" && !slices.Contains(versions, version) {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("target version %q not found and no migration has a version prefix", target)
+	}
+	return nil, fmt.Errorf("target version %q not found; available versions: %s", target, strings.Join(versions, ", "))
+}
+
+// discoverIncrementalMigrations returns the migrations of a directory, failing
+// when there are none
+func discoverIncrementalMigrations(migrationDir string, migrationReader MigrationReader) ([]Migration, error) {
+	if migrationDirMissing(migrationDir) {
+		return nil, withExitCode(exitMigrationDirNotFound, fmt.Errorf("migration directory does not exist: %s", migrationDir))
+	}
+
+	migrations, err := migrationReader.DiscoverMigrations(migrationDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse migrations: %w", err)
+	}
+
+	if len(migrations) == 0 {
+		return nil, withExitCode(exitNoMigrations, fmt.Errorf("no migration files found in directory: %s", migrationDir))
+	}
+	return migrations, nil
+}
+
+// migrationChanges returns the DDL produced by a single migration: the
+// migrations before it are run and the schema extracted, then the migration
+// itself is run on top and the schema extracted again. The output is the
+// difference between both schemas, rendered with the diff engine.
+func migrationChanges(ctx context.Context, migrationDir string, migrationReader MigrationReader,
+	dbManager DatabaseManager, name string) (string, error) {
+	migrations, err := discoverIncrementalMigrations(migrationDir, migrationReader)
+	if err != nil {
+		return "", err
+	}
+
+	at, err := findMigration(migrations, name)
+	if err != nil {
+		return "", withExitCode(exitUsage, err)
+	}
+	migration := migrations[at]
+
+	slog.Info("setting up database")
+	if err := dbManager.Setup(ctx); err != nil {
+		return "", withExitCode(exitSetupFailed, fmt.Errorf("failed to setup database: %w", err))
+	}
+	defer func() {
+		if err := dbManager.Close(context.WithoutCancel(ctx)); err != nil {
+			slog.Error("failed to cleanup", "error", err)
+		}
+	}()
+
+	slog.Info("running earlier migrations", "count", at)
+	if err := dbManager.RunMigrations(ctx, migrations[:at]); err != nil {
+		return "", withExitCode(exitMigrationFailed, fmt.Errorf("failed to run migrations: %w", err))
+	}
+
+	before, err := providers.ExtractSchemaFromDB(dbManager.GetDB())
+	if err != nil {
+		return "", withExitCode(exitExtractionFailed, fmt.Errorf("failed to extract schema before %s: %w", migration.Name, err))
+	}
+
+	slog.Info("running migration", "name", migration.Name)
+	if err := dbManager.RunMigrations(ctx, []Migration{migration}); err != nil {
+		return "", withExitCode(exitMigrationFailed, fmt.Errorf("failed to run migration %s: %w", migration.Name, err))
+	}
+
+	after, err := providers.ExtractSchemaFromDB(dbManager.GetDB())
+	if err != nil {
+		return "", withExitCode(exitExtractionFailed, fmt.Errorf("failed to extract schema after %s: %w", migration.Name, err))
+	}
+
+	diff := providers.DiffSchemas(before, after)
+	if diff.IsEmpty() {
+		return fmt.Sprintf("-- migration %s made no schema changes\n", migration.Name), nil
+	}
+	return fmt.Sprintf("-- schema changes made by migration %s\n", migration.Name) + providers.FormatMigrationSQL(diff), nil
+}
+
+// explainSchema runs the migrations one at a time, extracting the schema
+// after each of them, and returns the final tables along with the migration
+// that created and last modified every table and column
+func explainSchema(ctx context.Context, migrationDir string, migrationReader MigrationReader,
+	dbManager DatabaseManager) ([]providers.Table, providers.ProvenanceMap, error) {
+	migrations, err := discoverIncrementalMigrations(migrationDir, migrationReader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	slog.Info("setting up database")
+	if err := dbManager.Setup(ctx); err != nil {
+		return nil, nil, withExitCode(exitSetupFailed, fmt.Errorf("failed to setup database: %w", err))
+	}
+	defer func() {
+		if err := dbManager.Close(context.WithoutCancel(ctx)); err != nil {
+			slog.Error("failed to cleanup", "error", err)
+		}
+	}()
+
+	var tables []providers.Table
+	provenance := make(providers.ProvenanceMap)
+	for _, migration := range migrations {
+		slog.Info("running migration", "name", migration.Name)
+		if err := dbManager.RunMigrations(ctx, []Migration{migration}); err != nil {
+			return nil, nil, withExitCode(exitMigrationFailed, fmt.Errorf("failed to run migration %s: %w", migration.Name, err))
+		}
+
+		after, err := providers.ExtractSchemaFromDB(dbManager.GetDB())
+		if err != nil {
+			return nil, nil, withExitCode(exitExtractionFailed, fmt.Errorf("failed to extract schema after %s: %w", migration.Name, err))
+		}
+
+		provenance.Record(migration.Name, providers.DiffSchemas(tables, after))
+		tables = after
+	}
+
+	return tables, provenance, nil
+}
+
+// schemaChanges is the difference between the schemas after two migrations
+type schemaChanges struct {
+	From string
+	To   string
+	Diff providers.SchemaDiff
+}
+
+// schemaChangesSince returns the changes made by the migrations after from:
+// the migrations up to and including from are run and the schema extracted,
+// then the remaining migrations, up to --target when set, are run on top and
+// the schema extracted again.
+func schemaChangesSince(ctx context.Context, migrationDir string, migrationReader MigrationReader,
+	dbManager DatabaseManager, from, target string) (schemaChanges, error) {
+	migrations, err := discoverIncrementalMigrations(migrationDir, migrationReader)
+	if err != nil {
+		return schemaChanges{}, err
+	}
+
+	if target != "" {
+		if migrations, err = targetMigrations(migrations, target); err != nil {
+			return schemaChanges{}, withExitCode(exitUsage, err)
+		}
+	}
+	base, err := targetMigrations(migrations, from)
+	if err != nil {
+		return schemaChanges{}, withExitCode(exitUsage, fmt.Errorf("--diff-from: %w", err))
+	}
+	fromName, toName := base[len(base)-1].Name, migrations[len(migrations)-1].Name
+
+	slog.Info("setting up database")
+	if err := dbManager.Setup(ctx); err != nil {
+		return schemaChanges{}, withExitCode(exitSetupFailed, fmt.Errorf("failed to setup database: %w", err))
+	}
+	defer func() {
+		if err := dbManager.Close(context.WithoutCancel(ctx)); err != nil {
+			slog.Error("failed to cleanup", "error", err)
+		}
+	}()
+
+	slog.Info("running migrations up to diff base", "from", fromName, "count", len(base))
+	if err := dbManager.RunMigrations(ctx, base); err != nil {
+		return schemaChanges{}, withExitCode(exitMigrationFailed, fmt.Errorf("failed to run migrations: %w", err))
+	}
+
+	before, err := providers.ExtractSchemaFromDB(dbManager.GetDB())
+	if err != nil {
+		return schemaChanges{}, withExitCode(exitExtractionFailed, fmt.Errorf("failed to extract schema after %s: %w", fromName, err))
+	}
+
+	slog.Info("running remaining migrations", "count", len(migrations)-len(base))
+	if err := dbManager.RunMigrations(ctx, migrations[len(base):]); err != nil {
+		return schemaChanges{}, withExitCode(exitMigrationFailed, fmt.Errorf("failed to run migrations: %w", err))
+	}
+
+	after, err := providers.ExtractSchemaFromDB(dbManager.GetDB())
+	if err != nil {
+		return schemaChanges{}, withExitCode(exitExtractionFailed, fmt.Errorf("failed to extract schema after %s: %w", toName, err))
+	}
+
+	return schemaChanges{From: fromName, To: toName, Diff: providers.DiffSchemas(before, after)}, nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindMigration(t *testing.T) {
+	migrations := []Migration{
+		{Name: "001_create_users", UpFile: "/migrations/001_create_users.up.sql"},
+		{Name: "002_create_posts", UpFile: "/migrations/002_create_posts.up.sql"},
+		{Name: "003_add_index", UpFile: "/migrations/003_add_index.up.sql"},
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr string
+	}{
+		{name: "by_name", input: "002_create_posts", want: 1},
+		{name: "by_file_name", input: "003_add_index.up.sql", want: 2},
+		{name: "by_path", input: "migrations/001_create_users.up.sql", want: 0},
+		{name: "suggestion", input: "003_add_indx", wantErr: `migration "003_add_indx" not found; did you mean 003_add_index?`},
+		{name: "unknown", input: "zzz", wantErr: `migration "zzz" not found`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findMigration(migrations, tt.input)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantErr, err.Error())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMigrationChangesErrors(t *testing.T) {
+	migrations := []Migration{{Name: "001_create_users"}, {Name: "002_create_posts"}}
+	reader := &MockMigrationReader{DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+		return migrations, nil
+	}}
+
+	t.Run("missing_directory", func(t *testing.T) {
+		dbManager := &MockDatabaseManager{}
+		_, err := migrationChanges(context.Background(), filepath.Join(t.TempDir(), "missing"), reader, dbManager, "001_create_users")
+		require.Error(t, err)
+		assert.Equal(t, exitMigrationDirNotFound, exitCode(err))
+		assert.False(t, dbManager.SetupCalled)
+	})
+
+	t.Run("unknown_migration", func(t *testing.T) {
+		dbManager := &MockDatabaseManager{}
+		_, err := migrationChanges(context.Background(), t.TempDir(), reader, dbManager, "003_missing")
+		require.Error(t, err)
+		assert.Equal(t, exitUsage, exitCode(err))
+		assert.False(t, dbManager.SetupCalled)
+	})
+
+	t.Run("earlier_migration_fails", func(t *testing.T) {
+		var ran [][]Migration
+		dbManager := &MockDatabaseManager{RunMigrationsFunc: func(ctx context.Context, m []Migration) error {
+			ran = append(ran, m)
+			return errors.New("syntax error")
+		}}
+		_, err := migrationChanges(context.Background(), t.TempDir(), reader, dbManager, "002_create_posts")
+		require.Error(t, err)
+		assert.Equal(t, exitMigrationFailed, exitCode(err))
+		assert.Equal(t, [][]Migration{migrations[:1]}, ran)
+		assert.True(t, dbManager.CloseCalled)
+	})
+}
+
+func TestMigrationToSchemaOnlyMigration(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping only-migration test")
+	}
+
+	tempDir := t.TempDir()
+	migrationContent := map[string]string{
+		"001_create_users.up.sql": `create table users (id serial primary key, email text not null);`,
+		"002_create_posts.up.sql": `
+			create table posts (id serial primary key, user_id integer not null references users(id), title text);
+			alter table users add column name text;
+			create index idx_posts_user_id on posts (user_id);
+		`,
+		"003_add_comment.up.sql": `comment on table users is 'people';`,
+	}
+	for filename, content := range migrationContent {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	output, err := migrationChanges(ctx, tempDir, &FileMigrationReader{}, NewPostgreSQLManager("postgres:16-alpine"), "002_create_posts")
+	require.NoError(t, err)
+	assert.Contains(t, output, "-- schema changes made by migration 002_create_posts")
+	assert.Contains(t, output, "create table posts")
+	assert.Contains(t, output, "add column name text")
+	assert.Contains(t, output, "idx_posts_user_id")
+	assert.NotContains(t, output, "create table users")
+}
+
+func TestMigrationToSchemaExplain(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping explain test")
+	}
+
+	tempDir := t.TempDir()
+	migrationContent := map[string]string{
+		"001_create_users.up.sql": `create table users (id serial primary key, email text not null);`,
+		"002_create_posts.up.sql": `create table posts (id serial primary key, user_id integer not null references users(id));`,
+		"003_add_name.up.sql":     `alter table users add column name text; alter table users alter column email type varchar(320);`,
+	}
+	for filename, content := range migrationContent {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	tables, provenance, err := explainSchema(ctx, tempDir, &FileMigrationReader{}, NewPostgreSQLManager("postgres:16-alpine"))
+	require.NoError(t, err)
+	assert.Len(t, tables, 2)
+
+	assert.Equal(t, providers.Provenance{CreatedBy: "001_create_users", ModifiedBy: "003_add_name"}, provenance["users"])
+	assert.Equal(t, providers.Provenance{CreatedBy: "003_add_name"}, provenance["users.name"])
+	assert.Equal(t, providers.Provenance{CreatedBy: "001_create_users", ModifiedBy: "003_add_name"}, provenance["users.email"])
+	assert.Equal(t, providers.Provenance{CreatedBy: "002_create_posts"}, provenance["posts"])
+}
+
+func TestTargetMigrations(t *testing.T) {
+	migrations := []Migration{
+		{Name: "001_create_users"},
+		{Name: "002_create_posts"},
+		{Name: "002_create_tags"},
+		{Name: "010_add_index"},
+	}
+	names := func(migrations []Migration) []string {
+		var names []string
+		for _, migration := range migrations {
+			names = append(names, migration.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		target   string
+		expected []string
+	}{
+		{"001_create_users", []string{"001_create_users"}},
+		{"migrations/002_create_posts.up.sql", []string{"001_create_users", "002_create_posts"}},
+		{"1", []string{"001_create_users"}},
+		{"002", []string{"001_create_users", "002_create_posts", "002_create_tags"}},
+		{"10", []string{"001_create_users", "002_create_posts", "002_create_tags", "010_add_index"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			selected, err := targetMigrations(migrations, tt.target)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, names(selected))
+		})
+	}
+
+	_, err := targetMigrations(migrations, "5")
+	assert.EqualError(t, err, `target version "5" not found; available versions: 001, 002, 010`)
+
+	_, err = targetMigrations([]Migration{{Name: "create_users"}}, "1")
+	assert.EqualError(t, err, `target version "1" not found and no migration has a version prefix`)
+}
+
+func TestSchemaChangesSinceErrors(t *testing.T) {
+	migrations := []Migration{{Name: "001_create_users"}, {Name: "002_create_posts"}, {Name: "003_add_name"}}
+	reader := &MockMigrationReader{DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+		return migrations, nil
+	}}
+
+	t.Run("unknown_version", func(t *testing.T) {
+		dbManager := &MockDatabaseManager{}
+		_, err := schemaChangesSince(context.Background(), t.TempDir(), reader, dbManager, "9", "")
+		require.Error(t, err)
+		assert.Equal(t, exitUsage, exitCode(err))
+		assert.False(t, dbManager.SetupCalled)
+	})
+
+	t.Run("from_after_target", func(t *testing.T) {
+		dbManager := &MockDatabaseManager{}
+		_, err := schemaChangesSince(context.Background(), t.TempDir(), reader, dbManager, "3", "2")
+		require.Error(t, err)
+		assert.Equal(t, exitUsage, exitCode(err))
+	})
+
+	t.Run("runs_base_then_remaining", func(t *testing.T) {
+		var ran [][]Migration
+		dbManager := &MockDatabaseManager{RunMigrationsFunc: func(ctx context.Context, m []Migration) error {
+			ran = append(ran, m)
+			return errors.New("syntax error")
+		}}
+		_, err := schemaChangesSince(context.Background(), t.TempDir(), reader, dbManager, "1", "")
+		require.Error(t, err)
+		assert.Equal(t, exitMigrationFailed, exitCode(err))
+		assert.Equal(t, [][]Migration{migrations[:1]}, ran)
+		assert.True(t, dbManager.CloseCalled)
+	})
+}
+
+func TestMigrationToSchemaDiffFrom(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping diff test")
+	}
+
+	tempDir := t.TempDir()
+	migrationContent := map[string]string{
+		"001_create_users.up.sql": `create table users (id serial primary key, email text not null);`,
+		"002_create_posts.up.sql": `create table posts (id serial primary key, user_id integer not null references users(id));`,
+		"003_add_name.up.sql":     `alter table users add column name text; create index idx_users_email on users (email);`,
+	}
+	for filename, content := range migrationContent {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	changes, err := schemaChangesSince(ctx, tempDir, &FileMigrationReader{}, NewPostgreSQLManager("postgres:16-alpine"), "1", "")
+	require.NoError(t, err)
+	assert.Equal(t, "001_create_users", changes.From)
+	assert.Equal(t, "003_add_name", changes.To)
+
+	report := providers.FormatDiffReport(changes.Diff)
+	assert.Contains(t, report, "+ table posts")
+	assert.Contains(t, report, "+ column name text")
+	assert.Contains(t, report, "+ index idx_users_email (email)")
+
+	filter, err := providers.NewDiffFilter([]string{"tables", "columns"}, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, providers.FormatDiffReport(filter.Apply(changes.Diff)), "idx_users_email")
+}
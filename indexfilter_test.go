@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alc6/mig2schema/providers"
+)
+
+func TestIndexFilter(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "events",
+			Indexes: []providers.Index{
+				{Name: "idx_events_created_at", Columns: []string{"created_at"}},
+				{Name: "idx_events_tenant", Columns: []string{"tenant_id"}},
+				{Name: "pgwatch_events_seq_scan", Columns: []string{"id"}},
+			},
+			UniqueConstraints: []providers.UniqueConstraint{{Name: "uq_events_key", Columns: []string{"key"}}},
+		},
+	}
+	names := func(tables []providers.Table) []string {
+		var names []string
+		for _, idx := range tables[0].Indexes {
+			names = append(names, idx.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected []string
+	}{
+		{name: "no_patterns", expected: []string{"idx_events_created_at", "idx_events_tenant", "pgwatch_events_seq_scan"}},
+		{name: "exclude", exclude: []string{"^pgwatch_"}, expected: []string{"idx_events_created_at", "idx_events_tenant"}},
+		{name: "several_excludes", exclude: []string{"^pgwatch_", "_tenant$"}, expected: []string{"idx_events_created_at"}},
+		{name: "include", include: []string{"^idx_"}, expected: []string{"idx_events_created_at", "idx_events_tenant"}},
+		{name: "include_and_exclude", include: []string{"^idx_"}, exclude: []string{"created"}, expected: []string{"idx_events_tenant"}},
+		{name: "nothing_kept", include: []string{"^nope"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := providers.NewIndexFilter(tt.include, tt.exclude)
+			require.NoError(t, err)
+			filtered := filter.Apply(tables)
+			assert.Equal(t, tt.expected, names(filtered))
+			assert.Len(t, filtered[0].UniqueConstraints, 1, "constraints are kept")
+			assert.Len(t, tables[0].Indexes, 3, "input should not be modified")
+		})
+	}
+
+	_, err := providers.NewIndexFilter(nil, []string{"idx_("})
+	assert.ErrorContains(t, err, `invalid index pattern "idx_("`)
+}
+
+func TestExtractParamsIndexFilter(t *testing.T) {
+	defer resetCommand()
+
+	excludeIndexes = []string{"^pgwatch_"}
+	params, err := extractParams()
+	require.NoError(t, err)
+	assert.False(t, params.IndexFilter.Keep("pgwatch_users"))
+	assert.True(t, params.IndexFilter.Keep("idx_users_email"))
+
+	excludeIndexes = []string{"["}
+	_, err = extractParams()
+	require.Error(t, err)
+	assert.Equal(t, exitUsage, exitCode(err))
+}
+
+func TestMigrationToSchemaIndexFilter(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping index filter test")
+	}
+
+	db, err := SetupPostgreSQL(context.Background())
+	require.NoError(t, err)
+	defer db.Close(context.Background())
+
+	require.NoError(t, db.RunMigrations(context.Background(), []Migration{{
+		Name: "001_create_events",
+		UpSQL: []byte(`create table events (id serial primary key, tenant_id int, created_at timestamptz);
+create index idx_events_tenant on events (tenant_id);
+create index pgwatch_events_created_at on events (created_at);`),
+	}}))
+
+	filter, err := providers.NewIndexFilter(nil, []string{"^pgwatch_"})
+	require.NoError(t, err)
+	result, err := providers.NewNativeProvider().ExtractSchema(context.Background(), providers.ExtractParams{
+		DB:          db.DB,
+		Format:      providers.FormatSQL,
+		IndexFilter: filter,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, result.RawSQL, "create index idx_events_tenant")
+	assert.NotContains(t, result.RawSQL, "pgwatch_events_created_at")
+	assert.Contains(t, result.RawSQL, "primary key")
+}
@@ -15,8 +15,9 @@ type DatabaseManager interface {
 	Setup(ctx context.Context) error
 	// Close cleans up database resources
 	Close(ctx context.Context) error
-	// RunMigrations executes the provided migrations
-	RunMigrations(migrations []Migration) error
+	// RunMigrations executes the provided migrations, stopping when ctx is
+	// done
+	RunMigrations(ctx context.Context, migrations []Migration) error
 	// GetDB returns the underlying database connection
 	GetDB() *sql.DB
 	// GetConnectionString returns the database connection string
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ledgerSchema holds the table --incremental records applied migrations in.
+// It is left out of the extracted schema.
+const ledgerSchema = "mig2schema"
+
+// appliedMigration is a migration together with the checksum of the content
+// it ran with
+type appliedMigration struct {
+	Name     string
+	Checksum string
+}
+
+// migrationLedger records the migrations applied to a database, in order
+type migrationLedger interface {
+	// Applied returns the recorded migrations, none when nothing was recorded
+	Applied(ctx context.Context) ([]appliedMigration, error)
+	// Record appends migrations to the ledger
+	Record(ctx context.Context, migrations []appliedMigration) error
+}
+
+// dbLedger keeps the ledger in a table of the migrated database, so that it
+// is dropped together with everything else when the database is reset
+type dbLedger struct {
+	db *sql.DB
+}
+
+func newMigrationLedger(db *sql.DB) migrationLedger {
+	return dbLedger{db: db}
+}
+
+func (l dbLedger) Applied(ctx context.Context) ([]appliedMigration, error) {
+	var exists bool
+	if err := l.db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", ledgerSchema+".applied_migrations").Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	rows, err := l.db.QueryContext(ctx, "SELECT name, checksum FROM "+ledgerSchema+".applied_migrations ORDER BY position")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var applied []appliedMigration
+	for rows.Next() {
+		var migration appliedMigration
+		if err := rows.Scan(&migration.Name, &migration.Checksum); err != nil {
+			return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		applied = append(applied, migration)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	return applied, nil
+}
+
+func (l dbLedger) Record(ctx context.Context, migrations []appliedMigration) error {
+	if _, err := l.db.ExecContext(ctx, `
+		CREATE SCHEMA IF NOT EXISTS `+ledgerSchema+`;
+		CREATE TABLE IF NOT EXISTS `+ledgerSchema+`.applied_migrations (
+			position serial PRIMARY KEY,
+			name text NOT NULL,
+			checksum text NOT NULL,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		)
+	`); err != nil {
+		return fmt.Errorf("failed to create migration ledger: %w", err)
+	}
+
+	for _, migration := range migrations {
+		if _, err := l.db.ExecContext(ctx, "INSERT INTO "+ledgerSchema+".applied_migrations (name, checksum) VALUES ($1, $2)",
+			migration.Name, migration.Checksum); err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", migration.Name, err)
+		}
+	}
+	return nil
+}
+
+// checksumMigrations hashes the up SQL of every migration, normalized by
+// checksumContent
+func checksumMigrations(migrations []Migration) ([]appliedMigration, error) {
+	checksums := make([]appliedMigration, len(migrations))
+	for i, migration := range migrations {
+		content, err := migration.readUpSQL()
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256([]byte(checksumContent(content)))
+		checksums[i] = appliedMigration{Name: migration.Name, Checksum: hex.EncodeToString(sum[:])}
+	}
+	return checksums, nil
+}
+
+// checksumContent returns the content of a migration as it is hashed: with
+// LF line endings and without trailing whitespace on each line or trailing
+// blank lines, so that an editor saving the file differently does not force
+// a rebuild. Any other change, even to whitespace inside a line, does.
+func checksumContent(content []byte) string {
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// pendingMigrations compares the current migrations with those applied and
+// returns the index of the first one to run. The applied migrations must be
+// an unchanged prefix of the current ones; otherwise the reason is returned
+// and the database has to be rebuilt from scratch.
+func pendingMigrations(current, applied []appliedMigration) (int, string) {
+	for i, migration := range applied {
+		switch {
+		case i >= len(current) || current[i].Name != migration.Name:
+			if !containsMigration(current, migration.Name) {
+				return 0, fmt.Sprintf("applied migration %s was removed", migration.Name)
+			}
+			return 0, fmt.Sprintf("a migration was added before applied migration %s", migration.Name)
+		case current[i].Checksum != migration.Checksum:
+			return 0, fmt.Sprintf("applied migration %s changed", migration.Name)
+		}
+	}
+	return len(applied), ""
+}
+
+func containsMigration(migrations []appliedMigration, name string) bool {
+	for _, migration := range migrations {
+		if migration.Name == name {
+			return true
+		}
+	}
+	return false
+}
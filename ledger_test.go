@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alc6/mig2schema/providers"
+)
+
+func TestPendingMigrations(t *testing.T) {
+	current := []appliedMigration{
+		{Name: "001_create_users", Checksum: "a"},
+		{Name: "002_create_posts", Checksum: "b"},
+		{Name: "003_add_email", Checksum: "c"},
+	}
+
+	tests := []struct {
+		name          string
+		applied       []appliedMigration
+		expectedStart int
+		reason        string
+	}{
+		{name: "nothing_applied", expectedStart: 0},
+		{name: "new_migration", applied: current[:2], expectedStart: 2},
+		{name: "up_to_date", applied: current, expectedStart: 3},
+		{
+			name:    "changed",
+			applied: []appliedMigration{current[0], {Name: "002_create_posts", Checksum: "old"}},
+			reason:  "applied migration 002_create_posts changed",
+		},
+		{
+			name:    "removed",
+			applied: []appliedMigration{current[0], {Name: "002_create_tags", Checksum: "d"}},
+			reason:  "applied migration 002_create_tags was removed",
+		},
+		{
+			name:    "removed_last",
+			applied: append(append([]appliedMigration{}, current...), appliedMigration{Name: "004_drop_tags", Checksum: "d"}),
+			reason:  "applied migration 004_drop_tags was removed",
+		},
+		{
+			name:    "inserted_before",
+			applied: []appliedMigration{current[0], current[2]},
+			reason:  "a migration was added before applied migration 003_add_email",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, reason := pendingMigrations(current, tt.applied)
+			assert.Equal(t, tt.reason, reason)
+			assert.Equal(t, tt.expectedStart, start)
+		})
+	}
+}
+
+func TestChecksumMigrations(t *testing.T) {
+	checksums, err := checksumMigrations([]Migration{
+		{Name: "001_create_users", UpSQL: []byte("create table users (id int);")},
+		{Name: "002_create_posts", UpSQL: []byte("create table posts (id int);")},
+	})
+	require.NoError(t, err)
+	require.Len(t, checksums, 2)
+	assert.Equal(t, "001_create_users", checksums[0].Name)
+	assert.Len(t, checksums[0].Checksum, 64)
+	assert.NotEqual(t, checksums[0].Checksum, checksums[1].Checksum)
+
+	_, err = checksumMigrations([]Migration{{Name: "001_missing", UpFile: "/non/existent/001_missing.up.sql"}})
+	assert.Error(t, err)
+}
+
+func TestChecksumContent(t *testing.T) {
+	content := "create table users (\n    id int\n);\n"
+	assert.Equal(t, checksumContent([]byte(content)), checksumContent([]byte("create table users (  \r\n    id int\t\r\n);\r\n\r\n")),
+		"line endings and trailing whitespace are ignored")
+	assert.NotEqual(t, checksumContent([]byte(content)), checksumContent([]byte("create table users (\n    id  int\n);\n")))
+	assert.NotEqual(t, checksumContent([]byte(content)), checksumContent([]byte("create table users (\n\n    id int\n);\n")))
+}
+
+func TestIncrementalRequiresWatch(t *testing.T) {
+	defer resetCommand()
+	resetCommand()
+
+	incremental = true
+	err := executeMig2Schema(context.Background(), []string{"migrations"}, providers.NewDefaultRegistry())
+	require.Error(t, err)
+	assert.Equal(t, exitUsage, exitCode(err))
+	assert.Contains(t, err.Error(), "--incremental requires --watch")
+}
+
+func TestMigrationToSchemaMigrationLedger(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping migration ledger test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	manager := NewPostgreSQLManager("postgres:16-alpine")
+	require.NoError(t, manager.Setup(ctx))
+	defer manager.Close(ctx)
+
+	ledger := newMigrationLedger(manager.GetDB())
+	applied, err := ledger.Applied(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	first := []appliedMigration{{Name: "001_create_users", Checksum: "a"}}
+	second := []appliedMigration{{Name: "002_create_posts", Checksum: "b"}}
+	require.NoError(t, ledger.Record(ctx, first))
+	require.NoError(t, ledger.Record(ctx, second))
+
+	applied, err = ledger.Applied(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, append(first, second...), applied)
+
+	// The ledger lives outside the public schema and is not extracted
+	tables, err := providers.ExtractSchemaFromDB(manager.GetDB())
+	require.NoError(t, err)
+	assert.Empty(t, tables)
+
+	// A reset drops the ledger together with everything else
+	require.NoError(t, resetDatabase(ctx, manager.GetDB()))
+	applied, err = ledger.Applied(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+}
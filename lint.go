@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alc6/mig2schema/providers"
+	"gopkg.in/yaml.v3"
+)
+
+// Lint rule IDs
+const (
+	// LintMissingPrimaryKey flags tables without a primary key, which break
+	// logical replication and most ORMs
+	LintMissingPrimaryKey = "missing_primary_key"
+	// LintTableSnakeCase flags table names that are not lower snake_case
+	LintTableSnakeCase = "table_snake_case"
+	// LintTablePlural flags table names whose last word does not end in s
+	LintTablePlural = "table_plural"
+	// LintIndexPrefix flags index names not starting with idx_
+	LintIndexPrefix = "index_prefix"
+	// LintForeignKeySuffix flags foreign key columns not ending in _id
+	LintForeignKeySuffix = "foreign_key_suffix"
+)
+
+// Severities of lint findings. Error findings fail the run with --lint or
+// --lint-config; off disables a rule.
+const (
+	lintSeverityError   = "error"
+	lintSeverityWarning = "warning"
+	lintSeverityOff     = "off"
+)
+
+// LintFinding is a problem found in the extracted schema
+type LintFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Table    string `json:"table"`
+	Message  string `json:"message"`
+}
+
+// lintRule is a check applied to each table. Check returns one message per
+// problem found.
+type lintRule struct {
+	ID string
+	// Severity is used when the configuration does not set one; off
+	// disables the rule by default
+	Severity string
+	Check    func(table providers.Table) []string
+}
+
+// lintRules are applied by lintSchema in order. The naming rules are
+// opinions, so they are off unless enabled in a --lint-config file.
+var lintRules = []lintRule{
+	{ID: LintMissingPrimaryKey, Severity: lintSeverityError, Check: lintPrimaryKey},
+	{ID: LintTableSnakeCase, Severity: lintSeverityOff, Check: lintTableSnakeCase},
+	{ID: LintTablePlural, Severity: lintSeverityOff, Check: lintTablePlural},
+	{ID: LintIndexPrefix, Severity: lintSeverityOff, Check: lintIndexPrefix},
+	{ID: LintForeignKeySuffix, Severity: lintSeverityOff, Check: lintForeignKeySuffix},
+}
+
+// lintConfig is the --lint-config file. Rules not listed keep their default
+// severity.
+//
+//	rules:
+//	  table_plural:
+//	    enabled: true
+//	  missing_primary_key:
+//	    severity: warning
+type lintConfig struct {
+	Rules map[string]lintRuleConfig `yaml:"rules"`
+}
+
+// lintRuleConfig overrides the defaults of one rule. Enabling a rule that is
+// off by default without a severity makes it a warning.
+type lintRuleConfig struct {
+	Enabled  *bool  `yaml:"enabled"`
+	Severity string `yaml:"severity"`
+}
+
+// loadLintConfig reads a --lint-config file. Unknown rules and severities
+// are errors so that typos do not silently disable a check.
+func loadLintConfig(path string) (lintConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return lintConfig{}, fmt.Errorf("failed to read lint config: %w", err)
+	}
+
+	var config lintConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && err != io.EOF {
+		return lintConfig{}, fmt.Errorf("failed to parse lint config %s: %w", path, err)
+	}
+
+	for id, rule := range config.Rules {
+		if findLintRule(id) == nil {
+			return lintConfig{}, fmt.Errorf("lint config %s: unknown rule %q", path, id)
+		}
+		switch rule.Severity {
+		case "", lintSeverityError, lintSeverityWarning, lintSeverityOff:
+		default:
+			return lintConfig{}, fmt.Errorf("lint config %s: rule %s: invalid severity %q, expected error, warning or off", path, id, rule.Severity)
+		}
+	}
+	return config, nil
+}
+
+// lintConfigCacheSetting identifies --lint-config for the output cache by
+// its path and content, so editing the file invalidates cached output
+func lintConfigCacheSetting() string {
+	if lintConfigPath == "" {
+		return ""
+	}
+	data, err := os.ReadFile(lintConfigPath)
+	if err != nil {
+		return lintConfigPath
+	}
+	return lintConfigPath + "\x00" + string(data)
+}
+
+// findLintRule returns the rule with the given ID, or nil
+func findLintRule(id string) *lintRule {
+	for i := range lintRules {
+		if lintRules[i].ID == id {
+			return &lintRules[i]
+		}
+	}
+	return nil
+}
+
+// severity returns the effective severity of rule under the configuration
+func (c lintConfig) severity(rule lintRule) string {
+	override, ok := c.Rules[rule.ID]
+	if !ok {
+		return rule.Severity
+	}
+	if override.Enabled != nil && !*override.Enabled {
+		return lintSeverityOff
+	}
+	if override.Severity != "" {
+		return override.Severity
+	}
+	if override.Enabled != nil && rule.Severity == lintSeverityOff {
+		return lintSeverityWarning
+	}
+	return rule.Severity
+}
+
+// lintSchema applies every enabled lint rule to each table. Findings are
+// grouped by table, in table order, then by rule order. Views are skipped,
+// and so are partitions since they repeat the findings of their parent.
+func lintSchema(tables []providers.Table, config lintConfig) []LintFinding {
+	var findings []LintFinding
+	for _, table := range tables {
+		if table.IsView || table.IsPartition() {
+			continue
+		}
+		for _, rule := range lintRules {
+			severity := config.severity(rule)
+			if severity == lintSeverityOff {
+				continue
+			}
+			for _, message := range rule.Check(table) {
+				findings = append(findings, LintFinding{Rule: rule.ID, Severity: severity, Table: table.Name, Message: message})
+			}
+		}
+	}
+	return findings
+}
+
+// lintErrorCount returns how many findings have error severity
+func lintErrorCount(findings []LintFinding) int {
+	count := 0
+	for _, finding := range findings {
+		if finding.Severity == lintSeverityError {
+			count++
+		}
+	}
+	return count
+}
+
+// writeLintReport writes findings grouped by table, one per line with its
+// severity and rule ID
+func writeLintReport(w io.Writer, findings []LintFinding) {
+	table := ""
+	for i, finding := range findings {
+		if i == 0 || finding.Table != table {
+			table = finding.Table
+			fmt.Fprintf(w, "%s:\n", table)
+		}
+		fmt.Fprintf(w, "  %-7s %s: %s\n", finding.Severity, finding.Rule, finding.Message)
+	}
+}
+
+// lintPrimaryKey reports a table without a primary key. The message says
+// whether a unique constraint or index could serve as one instead.
+func lintPrimaryKey(table providers.Table) []string {
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey {
+			return nil
+		}
+	}
+
+	message := "table has no primary key"
+	if !hasUniqueKey(table) {
+		message += " and no unique constraint or index"
+	}
+	return []string{message}
+}
+
+// hasUniqueKey reports whether a table has a unique constraint or index
+func hasUniqueKey(table providers.Table) bool {
+	if len(table.UniqueConstraints) > 0 {
+		return true
+	}
+	for _, idx := range table.Indexes {
+		if idx.IsUnique {
+			return true
+		}
+	}
+	return false
+}
+
+var snakeCasePattern = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// lintTableSnakeCase reports table names that are not lower snake_case
+func lintTableSnakeCase(table providers.Table) []string {
+	if snakeCasePattern.MatchString(table.Name) {
+		return nil
+	}
+	return []string{fmt.Sprintf("table name %q is not snake_case", table.Name)}
+}
+
+// lintTablePlural reports table names whose last word does not end in s.
+// This is a heuristic: irregular plurals such as people need to be disabled
+// or accepted as warnings.
+func lintTablePlural(table providers.Table) []string {
+	words := strings.Split(strings.ToLower(table.Name), "_")
+	if strings.HasSuffix(words[len(words)-1], "s") {
+		return nil
+	}
+	return []string{fmt.Sprintf("table name %q is not plural", table.Name)}
+}
+
+// lintIndexPrefix reports indexes whose name does not start with idx_.
+// Primary keys and unique constraints are not indexes here and keep the
+// names PostgreSQL gives them.
+func lintIndexPrefix(table providers.Table) []string {
+	var messages []string
+	for _, idx := range table.Indexes {
+		if !strings.HasPrefix(idx.Name, "idx_") {
+			messages = append(messages, fmt.Sprintf("index %q does not start with idx_", idx.Name))
+		}
+	}
+	return messages
+}
+
+// lintForeignKeySuffix reports foreign key columns that do not end in _id
+func lintForeignKeySuffix(table providers.Table) []string {
+	seen := make(map[string]bool)
+	for _, fk := range table.ForeignKeys {
+		for _, column := range fk.Columns {
+			if !strings.HasSuffix(column, "_id") {
+				seen[column] = true
+			}
+		}
+	}
+
+	columns := make([]string, 0, len(seen))
+	for column := range seen {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	messages := make([]string, len(columns))
+	for i, column := range columns {
+		messages[i] = fmt.Sprintf("foreign key column %q does not end in _id", column)
+	}
+	return messages
+}
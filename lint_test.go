@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintSchema(t *testing.T) {
+	tables := []providers.Table{
+		{Name: "users", Columns: []providers.Column{{Name: "id", IsPrimaryKey: true}}},
+		{Name: "audit_log", Columns: []providers.Column{{Name: "message"}}},
+		{
+			Name:              "settings",
+			Columns:           []providers.Column{{Name: "key"}},
+			UniqueConstraints: []providers.UniqueConstraint{{Name: "settings_key_key", Columns: []string{"key"}}},
+		},
+		{
+			Name:    "tags",
+			Columns: []providers.Column{{Name: "name"}},
+			Indexes: []providers.Index{{Name: "idx_tags_name", Columns: []string{"name"}, IsUnique: true}},
+		},
+		{Name: "active_users", Columns: []providers.Column{{Name: "id"}}, IsView: true},
+	}
+
+	assert.Equal(t, []LintFinding{
+		{Rule: LintMissingPrimaryKey, Severity: lintSeverityError, Table: "audit_log", Message: "table has no primary key and no unique constraint or index"},
+		{Rule: LintMissingPrimaryKey, Severity: lintSeverityError, Table: "settings", Message: "table has no primary key"},
+		{Rule: LintMissingPrimaryKey, Severity: lintSeverityError, Table: "tags", Message: "table has no primary key"},
+	}, lintSchema(tables, lintConfig{}))
+
+	assert.Empty(t, lintSchema(tables[:1], lintConfig{}))
+	assert.Empty(t, lintSchema(nil, lintConfig{}))
+}
+
+func TestLintSchemaNamingRules(t *testing.T) {
+	enabled := true
+	config := lintConfig{Rules: map[string]lintRuleConfig{
+		LintTableSnakeCase:   {Enabled: &enabled},
+		LintTablePlural:      {Enabled: &enabled},
+		LintIndexPrefix:      {Severity: lintSeverityError},
+		LintForeignKeySuffix: {Enabled: &enabled},
+	}}
+
+	tables := []providers.Table{
+		{
+			Name:    "UserProfile",
+			Columns: []providers.Column{{Name: "id", IsPrimaryKey: true}, {Name: "owner"}},
+			Indexes: []providers.Index{
+				{Name: "idx_user_profile_owner", Columns: []string{"owner"}},
+				{Name: "user_profile_owner_key", Columns: []string{"owner"}},
+			},
+			ForeignKeys: []providers.ForeignKey{
+				{Name: "fk_owner", Columns: []string{"owner"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+			},
+		},
+		{
+			Name:        "order_items",
+			Columns:     []providers.Column{{Name: "id", IsPrimaryKey: true}, {Name: "order_id"}},
+			ForeignKeys: []providers.ForeignKey{{Name: "fk_order", Columns: []string{"order_id"}, ReferencedTable: "orders", ReferencedColumns: []string{"id"}}},
+		},
+	}
+
+	assert.Equal(t, []LintFinding{
+		{Rule: LintTableSnakeCase, Severity: lintSeverityWarning, Table: "UserProfile", Message: `table name "UserProfile" is not snake_case`},
+		{Rule: LintTablePlural, Severity: lintSeverityWarning, Table: "UserProfile", Message: `table name "UserProfile" is not plural`},
+		{Rule: LintIndexPrefix, Severity: lintSeverityError, Table: "UserProfile", Message: `index "user_profile_owner_key" does not start with idx_`},
+		{Rule: LintForeignKeySuffix, Severity: lintSeverityWarning, Table: "UserProfile", Message: `foreign key column "owner" does not end in _id`},
+	}, lintSchema(tables, config))
+}
+
+func TestLintConfigSeverity(t *testing.T) {
+	enabled, disabled := true, false
+	config := lintConfig{Rules: map[string]lintRuleConfig{
+		LintMissingPrimaryKey: {Enabled: &disabled, Severity: lintSeverityError},
+		LintTablePlural:       {Enabled: &enabled},
+		LintTableSnakeCase:    {Enabled: &enabled, Severity: lintSeverityError},
+		LintIndexPrefix:       {},
+	}}
+
+	assert.Equal(t, lintSeverityOff, config.severity(*findLintRule(LintMissingPrimaryKey)))
+	assert.Equal(t, lintSeverityWarning, config.severity(*findLintRule(LintTablePlural)))
+	assert.Equal(t, lintSeverityError, config.severity(*findLintRule(LintTableSnakeCase)))
+	assert.Equal(t, lintSeverityOff, config.severity(*findLintRule(LintIndexPrefix)))
+	assert.Equal(t, lintSeverityOff, config.severity(*findLintRule(LintForeignKeySuffix)))
+}
+
+func TestLoadLintConfig(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "lint.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		config, err := loadLintConfig(write(t, "rules:\n  table_plural:\n    enabled: true\n  missing_primary_key:\n    severity: warning\n"))
+		require.NoError(t, err)
+		require.NotNil(t, config.Rules[LintTablePlural].Enabled)
+		assert.True(t, *config.Rules[LintTablePlural].Enabled)
+		assert.Equal(t, lintSeverityWarning, config.Rules[LintMissingPrimaryKey].Severity)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		config, err := loadLintConfig(write(t, ""))
+		require.NoError(t, err)
+		assert.Empty(t, config.Rules)
+	})
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "unknown_rule", content: "rules:\n  tables_plural:\n    enabled: true\n", wantErr: `unknown rule "tables_plural"`},
+		{name: "invalid_severity", content: "rules:\n  table_plural:\n    severity: fatal\n", wantErr: `invalid severity "fatal"`},
+		{name: "unknown_field", content: "rules:\n  table_plural:\n    enable: true\n", wantErr: "failed to parse lint config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadLintConfig(write(t, tt.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	t.Run("missing_file", func(t *testing.T) {
+		_, err := loadLintConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read lint config")
+	})
+}
+
+func TestWriteLintReport(t *testing.T) {
+	var buf bytes.Buffer
+	writeLintReport(&buf, []LintFinding{
+		{Rule: LintMissingPrimaryKey, Severity: lintSeverityError, Table: "audit_log", Message: "table has no primary key"},
+		{Rule: LintTablePlural, Severity: lintSeverityWarning, Table: "audit_log", Message: `table name "audit_log" is not plural`},
+		{Rule: LintIndexPrefix, Severity: lintSeverityWarning, Table: "users", Message: `index "users_email" does not start with idx_`},
+	})
+
+	assert.Equal(t, "audit_log:\n"+
+		"  error   missing_primary_key: table has no primary key\n"+
+		"  warning table_plural: table name \"audit_log\" is not plural\n"+
+		"users:\n"+
+		"  warning index_prefix: index \"users_email\" does not start with idx_\n", buf.String())
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Values of --log-format
+const (
+	logFormatJSON = "json"
+	logFormatText = "text"
+)
+
+// parseLogLevel parses a --log-level value: debug, info, warn or error
+func parseLogLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unsupported log level: %s (expected debug, info, warn or error)", s)
+	}
+}
+
+// newLogHandler returns the slog handler writing to w in the given format.
+// Secrets registered with logSecrets are redacted in both formats.
+func newLogHandler(w io.Writer, format string, level slog.Leveler) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: logSecrets.replaceAttr,
+	}
+	switch format {
+	case logFormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	case logFormatText:
+		return slog.NewTextHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("unsupported log format: %s (expected json or text)", format)
+	}
+}
+
+// configureLogging installs the default logger selected by --log-level and
+// --log-format, or one discarding every record with --silent. It runs once
+// the flags are parsed; until then run's JSON handler at info level is in
+// place.
+//
+// Logs only ever go to stderr and the schema only to stdout, so that the
+// output can be piped or redirected without diagnostics mixed in.
+func configureLogging(w io.Writer) error {
+	level, err := parseLogLevel(logLevel)
+	if err != nil {
+		return err
+	}
+	if silent {
+		slog.SetDefault(slog.New(slog.DiscardHandler))
+		return nil
+	}
+	handler, err := newLogHandler(w, logFormat, level)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alc6/mig2schema/providers"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected slog.Level
+	}{
+		{"debug", slog.LevelDebug},
+		{"info", slog.LevelInfo},
+		{"WARN", slog.LevelWarn},
+		{"warning", slog.LevelWarn},
+		{"error", slog.LevelError},
+	}
+	for _, tt := range tests {
+		level, err := parseLogLevel(tt.input)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, level)
+	}
+
+	_, err := parseLogLevel("verbose")
+	assert.EqualError(t, err, "unsupported log level: verbose (expected debug, info, warn or error)")
+}
+
+func TestNewLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	handler, err := newLogHandler(&buf, logFormatText, slog.LevelWarn)
+	require.NoError(t, err)
+	logger := slog.New(handler)
+	logger.Info("hidden")
+	logger.Warn("shown", "migration", "001_init")
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "level=WARN msg=shown migration=001_init")
+
+	buf.Reset()
+	handler, err = newLogHandler(&buf, logFormatJSON, slog.LevelDebug)
+	require.NoError(t, err)
+	slog.New(handler).Debug("details", "count", 2)
+	assert.Contains(t, buf.String(), `"level":"DEBUG","msg":"details","count":2`)
+
+	_, err = newLogHandler(&buf, "xml", slog.LevelInfo)
+	assert.EqualError(t, err, "unsupported log format: xml (expected json or text)")
+}
+
+func TestConfigureLogging(t *testing.T) {
+	defer resetCommand()
+	defer slog.SetDefault(slog.Default())
+
+	var buf bytes.Buffer
+	logLevel, logFormat = "error", logFormatText
+	require.NoError(t, configureLogging(&buf))
+	slog.Warn("filtered")
+	slog.Error("reported")
+	assert.NotContains(t, buf.String(), "filtered")
+	assert.Contains(t, buf.String(), "msg=reported")
+
+	logLevel = "loud"
+	assert.Error(t, configureLogging(&buf))
+	logLevel, logFormat = "info", "yaml"
+	assert.Error(t, configureLogging(&buf))
+}
+
+// captureStreams runs fn with os.Stdout and os.Stderr redirected and returns
+// what was written to each
+func captureStreams(t *testing.T, fn func()) (string, string) {
+	t.Helper()
+	oldStdout, oldStderr := os.Stdout, os.Stderr
+	defer func() { os.Stdout, os.Stderr = oldStdout, oldStderr }()
+
+	read := func() (*os.File, <-chan string) {
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		out := make(chan string)
+		go func() {
+			data, _ := io.ReadAll(r)
+			out <- string(data)
+		}()
+		return w, out
+	}
+	stdoutWriter, stdout := read()
+	stderrWriter, stderr := read()
+	os.Stdout, os.Stderr = stdoutWriter, stderrWriter
+
+	fn()
+
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	return <-stdout, <-stderr
+}
+
+func TestOutputStreams(t *testing.T) {
+	defer resetCommand()
+	defer slog.SetDefault(slog.Default())
+	tempDir := t.TempDir()
+
+	reader := &MockMigrationReader{
+		DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+			return []Migration{{Name: "001_test", UpFile: "001_test.up.sql"}}, nil
+		},
+	}
+	provider := &MockSchemaProvider{
+		ExtractSchemaFunc: func(ctx context.Context, params providers.ExtractParams) (*providers.SchemaResult, error) {
+			return &providers.SchemaResult{RawSQL: "create table users (id integer);\n", Format: params.Format}, nil
+		},
+	}
+	run := func() (string, string) {
+		return captureStreams(t, func() {
+			require.NoError(t, configureLogging(os.Stderr))
+			require.NoError(t, processSchemaWithProvider(context.Background(), tempDir, reader, &MockDatabaseManager{}, provider, nil, nil))
+		})
+	}
+
+	t.Run("logs_on_stderr", func(t *testing.T) {
+		resetCommand()
+		extractMode = true
+		stdout, stderr := run()
+		assert.Equal(t, "create table users (id integer);\n", stdout)
+		assert.Contains(t, stderr, `"msg":"processing migration directory"`)
+		assert.NotContains(t, stderr, "create table users")
+	})
+
+	t.Run("silent", func(t *testing.T) {
+		resetCommand()
+		extractMode = true
+		silent = true
+		logLevel = "debug"
+		stdout, stderr := run()
+		assert.Equal(t, "create table users (id integer);\n", stdout)
+		assert.Empty(t, stderr)
+	})
+}
@@ -2,20 +2,96 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/alc6/mig2schema/providers"
+	"github.com/testcontainers/testcontainers-go/wait"
 )
 
 var (
-	extractMode    bool
-	mcpMode        bool
-	providerName   string
-	listProviders  bool
-	pgImage        string
+	extractMode        bool
+	mcpMode            bool
+	mcpTransport       string
+	mcpAddr            string
+	configPath         string
+	providerName       string
+	listProviders      bool
+	pgImage            string
+	pgImages           []string
+	dryRun             bool
+	emitDown           bool
+	checkMode          bool
+	checkTimeout       time.Duration
+	validateAllDir     string
+	migrationsArchive  string
+	outputFormat       string
+	sqlDialect         string
+	tsOptionalDefaults bool
+	fkStyle            string
+	strictTypes        bool
+	includeExtensions  bool
+	includePrivileges  bool
+	extensionObjects   bool
+	dumpCatalog        bool
+	normalizeDefaults  bool
+	idempotent         bool
+	cleanOutput        bool
+	startupTimeout     time.Duration
+	containerWait      string
+	noCache            bool
+	cacheDir           string
+	allowDuplicates    bool
+	checkSequence      bool
+	sequenceScheme     string
+	failOnEmptySchema  bool
+	columnOrder        string
+	tableOrder         string
+	renameCase         string
+	databaseURL        string
+	connectRetries     int
+	connectTimeout     time.Duration
+	showSummary        bool
+	failOnLint         bool
+	lintConfigPath     string
+	onlyMigration      string
+	diffFrom           string
+	diffIgnore         []string
+	diffOnly           []string
+	diffVerbose        bool
+	diffFormat         string
+	explain            bool
+	varFilePath        string
+	dbSettings         []string
+	watchMode          bool
+	incremental        bool
+	goldenPath         string
+	updateGolden       bool
+	reportPath         string
+	targetVersion      string
+	logLevel           string
+	logFormat          string
+	silent             bool
+	noColor            bool
+	splitOutput        string
+	splitClean         bool
+	baselinePath       string
+	jsonCompact        bool
+	withRowCounts      bool
+	exactCounts        bool
+	includeIndexes     []string
+	excludeIndexes     []string
+	typeMapPath        string
+	typeMappings       []string
 )
 
 var rootCmd = &cobra.Command{
@@ -30,163 +106,1261 @@ and then extracts the schema information.
 Modes:
   info mode (default): Shows human-readable schema information
   extract mode (-e): Outputs SQL CREATE statements
-  mcp mode (--mcp): Run as Model Context Protocol server`,
+  typescript (--format typescript): Outputs one TypeScript interface per table
+  prisma (--format prisma): Outputs a Prisma schema with one model per table
+  sqlalchemy (--format sqlalchemy): Outputs SQLAlchemy declarative models
+  atlas (--format atlas): Outputs the schema as Atlas HCL
+  markdown (--format markdown): Outputs a Markdown data dictionary
+  json (--format json): Outputs the extracted schema as JSON
+  split (-e --split-output): Writes one SQL file per table to a directory
+  dry run (--dry-run): Checks migration SQL without starting a database
+  emit down (--emit-down): Prints the down migrations as a single teardown script
+  check (--check): Runs the migrations and prints nothing but an error when one fails
+  validate all (--validate-all): Validates every migration directory below a directory
+  watch (--watch): Prints the schema again whenever a migration changes
+  explain (--explain): Annotates info output with the migrations behind each table
+  diff (--diff-from): Reports the schema changes made by the migrations after a version
+  mcp mode (--mcp): Run as Model Context Protocol server
+
+Migrations can also be read from a .tar, .tar.gz/.tgz or .sql.gz file with
+--migrations-archive instead of a directory, or piped in as a single script
+with - as the directory.`,
 	Args: func(cmd *cobra.Command, args []string) error {
+		// Args runs before any pre-run hook, and the config file may set
+		// flags checked here such as --database-url
+		if err := loadConfigFile(cmd, args); err != nil {
+			return err
+		}
 		if mcpMode || listProviders {
 			return nil
 		}
+		if migrationsArchive != "" || databaseURL != "" || validateAllDir != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		if providerName == "pg_dump" && os.Getenv(databaseURLEnv) != "" {
+			return cobra.MaximumNArgs(1)(cmd, args)
+		}
 		return cobra.ExactArgs(1)(cmd, args)
 	},
-	Run: runMig2Schema,
+	Run: runWithRegistry(providers.NewDefaultRegistry()),
 }
 
 func main() {
 	if err := run(); err != nil {
+		// run only fails on invalid flags or arguments
 		slog.Error("command execution failed", "error", err)
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 }
 
-func run() error {
-	handler := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	})
+// run executes the command with the built-in providers plus those added by
+// opts. A build can add its own providers by passing them from main:
+//
+//	run(providers.WithProvider(newCockroachProvider()))
+func run(opts ...providers.RegistryOption) error {
+	handler, err := newLogHandler(os.Stderr, logFormatJSON, slog.LevelInfo)
+	if err != nil {
+		return err
+	}
 	slog.SetDefault(slog.New(handler))
 
+	if rootCmd.Flags().Lookup("config") == nil {
+		rootCmd.Flags().StringVar(&configPath, "config", "", "Read flag defaults from this YAML file instead of mig2schema.yaml in the migration or working directory")
+	}
 	if rootCmd.Flags().Lookup("extract") == nil {
 		rootCmd.Flags().BoolVarP(&extractMode, "extract", "e", false, "Extract schema as SQL CREATE statements")
 	}
 	if rootCmd.Flags().Lookup("mcp") == nil {
 		rootCmd.Flags().BoolVar(&mcpMode, "mcp", false, "Run as Model Context Protocol server")
 	}
+	if rootCmd.Flags().Lookup("mcp-transport") == nil {
+		rootCmd.Flags().StringVar(&mcpTransport, "mcp-transport", mcpTransportStdio, "Transport of the MCP server: stdio, sse or http (streamable HTTP)")
+	}
+	if rootCmd.Flags().Lookup("mcp-addr") == nil {
+		rootCmd.Flags().StringVar(&mcpAddr, "mcp-addr", defaultMCPAddr, "Address the MCP server listens on with --mcp-transport sse or http")
+	}
 	if rootCmd.Flags().Lookup("provider") == nil {
-		rootCmd.Flags().StringVarP(&providerName, "provider", "p", "native", "Schema extraction provider (native, pg_dump)")
+		rootCmd.Flags().StringVarP(&providerName, "provider", "p", "native", "Schema extraction provider (native, pg_dump), or a comma-separated preference list such as pg_dump,native")
 	}
 	if rootCmd.Flags().Lookup("list-providers") == nil {
 		rootCmd.Flags().BoolVar(&listProviders, "list-providers", false, "List available schema extraction providers")
 	}
 	if rootCmd.Flags().Lookup("pg-image") == nil {
-		rootCmd.Flags().StringVar(&pgImage, "pg-image", "postgres:16-alpine", "PostgreSQL Docker image to use")
+		rootCmd.Flags().StringSliceVar(&pgImages, "pg-image", []string{"postgres:16-alpine"}, "PostgreSQL Docker image to use; give several (comma-separated or repeated) to compare the schema across images")
+	}
+	if rootCmd.Flags().Lookup("dry-run") == nil {
+		rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Check migration SQL without starting a database: " + syntaxCheckDescription())
+	}
+	if rootCmd.Flags().Lookup("emit-down") == nil {
+		rootCmd.Flags().BoolVar(&emitDown, "emit-down", false, "Print the down migrations in reverse order as a single teardown script without running anything")
+	}
+	if rootCmd.Flags().Lookup("check") == nil {
+		rootCmd.Flags().BoolVar(&checkMode, "check", false, "Only run the migrations, exiting non-zero when one fails; logs below error level are hidden unless --log-level is given")
+	}
+	if rootCmd.Flags().Lookup("validate-all") == nil {
+		rootCmd.Flags().StringVar(&validateAllDir, "validate-all", "", "Validate every directory of migrations below this directory concurrently; add --dry-run to check SQL as it does and --check-sequence to fail on sequence issues")
+	}
+	if rootCmd.Flags().Lookup("timeout") == nil {
+		rootCmd.Flags().DurationVar(&checkTimeout, "timeout", 0, "Fail --check when starting the database and running the migrations take longer than this (default: no limit)")
+	}
+	if rootCmd.Flags().Lookup("format") == nil {
+		rootCmd.Flags().StringVar(&outputFormat, "format", "", "Output format (info, sql, typescript, prisma, sqlalchemy, atlas, markdown, json); -e is shorthand for sql")
+	}
+	if rootCmd.Flags().Lookup("dialect") == nil {
+		rootCmd.Flags().StringVar(&sqlDialect, "dialect", providers.DialectPostgres, "SQL dialect of the output: postgres, or mysql for a best-effort translation of the tables")
+	}
+	if rootCmd.Flags().Lookup("ts-optional-defaults") == nil {
+		rootCmd.Flags().BoolVar(&tsOptionalDefaults, "ts-optional-defaults", false, "Mark columns with defaults as optional fields in typescript output")
+	}
+	if rootCmd.Flags().Lookup("fk-style") == nil {
+		rootCmd.Flags().StringVar(&fkStyle, "fk-style", string(providers.ForeignKeyInline), "Where to emit foreign keys in SQL output (inline, alter)")
+	}
+	if rootCmd.Flags().Lookup("strict-types") == nil {
+		rootCmd.Flags().BoolVar(&strictTypes, "strict-types", false, "Fail when a column type is not known to the formatter")
+	}
+	if rootCmd.Flags().Lookup("include-extensions") == nil {
+		rootCmd.Flags().BoolVar(&includeExtensions, "include-extensions", false, "Keep CREATE EXTENSION statements in pg_dump output")
+	}
+	if rootCmd.Flags().Lookup("include-privileges") == nil {
+		rootCmd.Flags().BoolVar(&includePrivileges, "include-privileges", false, "Extract the privileges granted on tables and emit them as GRANT statements after each table")
+	}
+	if rootCmd.Flags().Lookup("include-extension-objects") == nil {
+		rootCmd.Flags().BoolVar(&extensionObjects, "include-extension-objects", false, "Keep the tables, views and sequences created by extensions, such as spatial_ref_sys of postgis")
+	}
+	if rootCmd.Flags().Lookup("dump-catalog") == nil {
+		rootCmd.Flags().BoolVar(&dumpCatalog, "dump-catalog", false, "Debug: print the raw table, column and index rows the native provider read as JSON to stderr")
+		_ = rootCmd.Flags().MarkHidden("dump-catalog")
+	}
+	if rootCmd.Flags().Lookup("normalize-defaults") == nil {
+		rootCmd.Flags().BoolVar(&normalizeDefaults, "normalize-defaults", false, "Simplify column defaults (drop the cast of a string literal default) in SQL output")
+	}
+	if rootCmd.Flags().Lookup("idempotent") == nil {
+		rootCmd.Flags().BoolVar(&idempotent, "idempotent", false, "Guard every CREATE statement in SQL output with IF NOT EXISTS, so it can be replayed on a database that has some of the objects")
+	}
+	if rootCmd.Flags().Lookup("clean") == nil {
+		rootCmd.Flags().BoolVar(&cleanOutput, "clean", false, "Start SQL output with DROP ... IF EXISTS statements for every object")
+	}
+	if rootCmd.Flags().Lookup("container-startup-timeout") == nil {
+		rootCmd.Flags().DurationVar(&startupTimeout, "container-startup-timeout", defaultStartupTimeout, "How long to wait for the PostgreSQL container to become ready")
+	}
+	if rootCmd.Flags().Lookup("container-wait") == nil {
+		rootCmd.Flags().StringVar(&containerWait, "container-wait", "", "How to tell the PostgreSQL container is ready, for images that do not log the standard message: port to wait for port 5432, or log=<message> to wait for a log line")
+	}
+	if rootCmd.Flags().Lookup("no-cache") == nil {
+		rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "Always run the migrations instead of reusing cached output")
+	}
+	if rootCmd.Flags().Lookup("allow-duplicate-migrations") == nil {
+		rootCmd.Flags().BoolVar(&allowDuplicates, "allow-duplicate-migrations", false, "Warn instead of failing when two files resolve to the same migration name (the first file found is used)")
+	}
+	if rootCmd.Flags().Lookup("type-map") == nil {
+		rootCmd.Flags().StringVar(&typeMapPath, "type-map", "", "YAML file mapping PostgreSQL type names to the types to render in info and SQL output")
+	}
+	if rootCmd.Flags().Lookup("map-type") == nil {
+		rootCmd.Flags().StringArrayVar(&typeMappings, "map-type", nil, "Render a type differently in info and SQL output, as name=type (e.g. citext=TEXT); may be repeated and overrides --type-map")
+	}
+	if rootCmd.Flags().Lookup("only-migration") == nil {
+		rootCmd.Flags().StringVar(&onlyMigration, "only-migration", "", "Output only the schema changes made by the named migration, as DDL")
+	}
+	if rootCmd.Flags().Lookup("diff-from") == nil {
+		rootCmd.Flags().StringVar(&diffFrom, "diff-from", "", "Report the schema changes made by the migrations after this version or migration name; exits 1 when there are any")
+	}
+	if rootCmd.Flags().Lookup("diff-ignore") == nil {
+		rootCmd.Flags().StringSliceVar(&diffIgnore, "diff-ignore", nil, "Leave these categories out of --diff-from: tables, columns, defaults, indexes, constraints, column-order, triggers, comments")
+	}
+	if rootCmd.Flags().Lookup("diff-only") == nil {
+		rootCmd.Flags().StringSliceVar(&diffOnly, "diff-only", nil, "Report only these categories with --diff-from, such as tables,columns")
+	}
+	if rootCmd.Flags().Lookup("diff-verbose") == nil {
+		rootCmd.Flags().BoolVar(&diffVerbose, "diff-verbose", false, "Report every change with --diff-from, overriding --diff-ignore and --diff-only")
+	}
+	if rootCmd.Flags().Lookup("diff-format") == nil {
+		rootCmd.Flags().StringVar(&diffFormat, "diff-format", diffFormatText, "Format of the --diff-from report (text, json)")
+	}
+	if rootCmd.Flags().Lookup("var-file") == nil {
+		rootCmd.Flags().StringVar(&varFilePath, "var-file", "", "YAML file of psql variables substituted for :'name', :\"name\" and :name in migrations; values are redacted from logs")
+	}
+	if rootCmd.Flags().Lookup("db-setting") == nil {
+		rootCmd.Flags().StringArrayVar(&dbSettings, "db-setting", nil, "Set a session setting before migrations run, as name=value (e.g. search_path=app,public); may be repeated and is applied in order")
+	}
+	if rootCmd.Flags().Lookup("log-level") == nil {
+		rootCmd.Flags().StringVar(&logLevel, "log-level", "info", "Minimum level of log messages on stderr: debug, info, warn or error")
+	}
+	if rootCmd.Flags().Lookup("log-format") == nil {
+		rootCmd.Flags().StringVar(&logFormat, "log-format", logFormatJSON, "Format of log messages on stderr: json or text")
+	}
+	if rootCmd.Flags().Lookup("silent") == nil {
+		rootCmd.Flags().BoolVar(&silent, "silent", false, "Disable all log messages, leaving only the schema on stdout")
+	}
+	if rootCmd.Flags().Lookup("no-color") == nil {
+		rootCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colors in info output, which are otherwise used when stdout is a terminal and NO_COLOR is not set")
+	}
+	if rootCmd.Flags().Lookup("json-compact") == nil {
+		rootCmd.Flags().BoolVar(&jsonCompact, "json-compact", false, "Write --format json output on a single line instead of indenting it")
+	}
+	if rootCmd.Flags().Lookup("baseline") == nil {
+		rootCmd.Flags().StringVar(&baselinePath, "baseline", "", "Migration directory, archive or .sql file whose schema is subtracted from the output, leaving only what the migrations or database add on top")
+	}
+	if rootCmd.Flags().Lookup("split-output") == nil {
+		rootCmd.Flags().StringVar(&splitOutput, "split-output", "", "Write SQL output to this directory, one file per table plus a "+providers.SchemaIndexFile+" file including them in dependency order")
+	}
+	if rootCmd.Flags().Lookup("split-clean") == nil {
+		rootCmd.Flags().BoolVar(&splitClean, "split-clean", false, "Remove .sql files left in the --split-output directory by earlier runs")
+	}
+	if rootCmd.Flags().Lookup("with-row-counts") == nil {
+		rootCmd.Flags().BoolVar(&withRowCounts, "with-row-counts", false, "Show the estimated number of rows of each table in info output")
+	}
+	if rootCmd.Flags().Lookup("exact-counts") == nil {
+		rootCmd.Flags().BoolVar(&exactCounts, "exact-counts", false, "Count rows exactly with count(*) instead of estimating them (with --with-row-counts)")
+	}
+	if rootCmd.Flags().Lookup("include-index-pattern") == nil {
+		rootCmd.Flags().StringArrayVar(&includeIndexes, "include-index-pattern", nil, "Only keep indexes whose name matches this regular expression (repeatable)")
+	}
+	if rootCmd.Flags().Lookup("exclude-index-pattern") == nil {
+		rootCmd.Flags().StringArrayVar(&excludeIndexes, "exclude-index-pattern", nil, "Drop indexes whose name matches this regular expression (repeatable)")
+	}
+	if rootCmd.Flags().Lookup("target") == nil {
+		rootCmd.Flags().StringVar(&targetVersion, "target", "", "Run migrations up to and including this version or migration name, then extract the schema")
+	}
+	if rootCmd.Flags().Lookup("golden") == nil {
+		rootCmd.Flags().StringVar(&goldenPath, "golden", "", "Compare the output with this file and fail with a unified diff when they differ")
+	}
+	if rootCmd.Flags().Lookup("report") == nil {
+		rootCmd.Flags().StringVar(&reportPath, "report", "", "Write a JSON report of the run (timings, migrations, table count, status) to this file, even when it fails")
+	}
+	if rootCmd.Flags().Lookup("update-golden") == nil {
+		rootCmd.Flags().BoolVar(&updateGolden, "update-golden", false, "Rewrite the --golden file with the current output")
+	}
+	if rootCmd.Flags().Lookup("watch") == nil {
+		rootCmd.Flags().BoolVar(&watchMode, "watch", false, "Keep the database running and print the schema again whenever a migration changes")
+	}
+	if rootCmd.Flags().Lookup("incremental") == nil {
+		rootCmd.Flags().BoolVar(&incremental, "incremental", false, "With --watch, only run the migrations added since the previous refresh")
+	}
+	if rootCmd.Flags().Lookup("explain") == nil {
+		rootCmd.Flags().BoolVar(&explain, "explain", false, "Run migrations one at a time and annotate tables and columns with the migrations that created and last modified them")
+	}
+	if rootCmd.Flags().Lookup("database-url") == nil {
+		rootCmd.Flags().StringVar(&databaseURL, "database-url", "", "Extract the schema of an existing database instead of running migrations (defaults to $DATABASE_URL with -p pg_dump and no migrations)")
+	}
+	if rootCmd.Flags().Lookup("connect-retries") == nil {
+		rootCmd.Flags().IntVar(&connectRetries, "connect-retries", 0, "Retry connecting to --database-url this many times, with backoff, while the database refuses connections")
+	}
+	if rootCmd.Flags().Lookup("connect-timeout") == nil {
+		rootCmd.Flags().DurationVar(&connectTimeout, "connect-timeout", 0, "Give up connecting to --database-url after this long, across all retries (0 for no limit)")
+	}
+	if rootCmd.Flags().Lookup("order-by") == nil {
+		rootCmd.Flags().StringVar(&tableOrder, "order-by", string(providers.TableOrderName), "Table order in the output: name, created (creation order) or dependency (referenced tables first)")
+	}
+	if rootCmd.Flags().Lookup("rename-case") == nil {
+		rootCmd.Flags().StringVar(&renameCase, "rename-case", string(providers.RenameCasePreserve), "Rewrite table, column, index and constraint names in the output only: snake, lower or preserve")
+	}
+	if rootCmd.Flags().Lookup("column-order") == nil {
+		rootCmd.Flags().StringVar(&columnOrder, "column-order", string(providers.ColumnOrderPhysical), "Column order in the output: physical (as in the database) or logical (primary key, required, nullable)")
+	}
+	if rootCmd.Flags().Lookup("lint") == nil {
+		rootCmd.Flags().BoolVar(&failOnLint, "lint", false, "Fail when the schema lint finds errors, such as tables without a primary key")
+	}
+	if rootCmd.Flags().Lookup("lint-config") == nil {
+		rootCmd.Flags().StringVar(&lintConfigPath, "lint-config", "", "YAML file enabling lint rules and setting their severity; implies --lint")
+	}
+	if rootCmd.Flags().Lookup("summary") == nil {
+		rootCmd.Flags().BoolVar(&showSummary, "summary", false, "Append schema totals and tables without a primary key to info output")
+	}
+	if rootCmd.Flags().Lookup("fail-on-empty-schema") == nil {
+		rootCmd.Flags().BoolVar(&failOnEmptySchema, "fail-on-empty-schema", false, "Exit with an error when the migrations create no tables")
+	}
+	if rootCmd.Flags().Lookup("check-sequence") == nil {
+		rootCmd.Flags().BoolVar(&checkSequence, "check-sequence", false, "Fail when migration versions have gaps, duplicates or are out of order")
+	}
+	if rootCmd.Flags().Lookup("sequence-scheme") == nil {
+		rootCmd.Flags().StringVar(&sequenceScheme, "sequence-scheme", sequenceAuto, "Migration numbering scheme for --check-sequence: auto, integer or timestamp")
+	}
+	if rootCmd.Flags().Lookup("cache-dir") == nil {
+		rootCmd.Flags().StringVar(&cacheDir, "cache-dir", "", "Directory for cached output (default: mig2schema under the user cache directory)")
+	}
+	if rootCmd.Flags().Lookup("migrations-archive") == nil {
+		rootCmd.Flags().StringVar(&migrationsArchive, "migrations-archive", "", "Read migrations from a .tar, .tar.gz/.tgz or .sql.gz file")
 	}
 
-	return rootCmd.Execute()
+	// Interrupting the run cancels the work in progress, so that the
+	// container is still cleaned up
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	rootCmd.Run = runWithRegistry(providers.NewDefaultRegistry(opts...))
+	return rootCmd.ExecuteContext(ctx)
+}
+
+// runWithRegistry returns the Run function of the command, selecting
+// providers from registry
+func runWithRegistry(registry *providers.ProviderRegistry) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		runMig2Schema(cmd, args, registry)
+	}
+}
+
+func runMig2Schema(cmd *cobra.Command, args []string, registry *providers.ProviderRegistry) {
+	// --check is meant for CI, where progress logs only bury the error
+	if checkMode && !cmd.Flags().Changed("log-level") {
+		logLevel = "error"
+	}
+	if err := configureLogging(os.Stderr); err != nil {
+		slog.Error("invalid logging flags", "error", err)
+		os.Exit(exitUsage)
+	}
+
+	if err := executeMig2Schema(cmd.Context(), args, registry); err != nil {
+		slog.Error("mig2schema failed", "error", err)
+		os.Exit(exitCode(err))
+	}
 }
 
-func runMig2Schema(cmd *cobra.Command, args []string) {
-	// Initialize provider registry
-	registry := providers.NewProviderRegistry()
-	registry.Register(providers.NewNativeProvider())
-	registry.Register(providers.NewPgDumpProvider())
+// errChecksFailed is returned when a check reported its findings on stdout
+var errChecksFailed = errors.New("checks failed")
+
+// executeMig2Schema runs the command for the parsed flags, selecting the
+// provider from registry, until ctx is done. The returned error carries the
+// exit code, see exitCode. With --report, the timings and outcome of the run
+// are written to the report file, also when it fails.
+func executeMig2Schema(ctx context.Context, args []string, registry *providers.ProviderRegistry) (err error) {
+	if reportPath != "" {
+		runReport = newRunReport()
+		defer func() {
+			report := runReport
+			runReport = nil
+			report.finish(err)
+			if writeErr := writeRunReport(reportPath, report); writeErr != nil {
+				if err == nil {
+					err = writeErr
+				} else {
+					slog.Error("failed to write run report", "error", writeErr)
+				}
+			}
+		}()
+	}
 
 	if listProviders {
 		fmt.Println("Available schema extraction providers:")
 		for _, name := range registry.ListAvailable() {
 			fmt.Printf("  - %s\n", name)
 		}
-		return
+		return nil
+	}
+
+	if !mcpMode && ((mcpTransport != "" && mcpTransport != mcpTransportStdio) || (mcpAddr != "" && mcpAddr != defaultMCPAddr)) {
+		return withExitCode(exitUsage, fmt.Errorf("--mcp-transport and --mcp-addr require --mcp"))
 	}
 
 	if mcpMode {
+		switch mcpTransport {
+		case "", mcpTransportStdio, mcpTransportSSE, mcpTransportHTTP:
+		default:
+			return withExitCode(exitUsage, fmt.Errorf("unsupported mcp transport: %s (expected stdio, sse or http)", mcpTransport))
+		}
 		slog.Info("starting mcp server")
-		if err := StartMCPServer(); err != nil {
-			slog.Error("failed to start mcp server", "error", err)
-			os.Exit(1)
+		if err := StartMCPServer(registry, mcpTransport, mcpAddr); err != nil {
+			return fmt.Errorf("failed to start mcp server: %w", err)
+		}
+		return nil
+	}
+
+	if updateGolden && goldenPath == "" {
+		return withExitCode(exitUsage, fmt.Errorf("--update-golden requires --golden"))
+	}
+
+	if incremental && !watchMode {
+		return withExitCode(exitUsage, fmt.Errorf("--incremental requires --watch"))
+	}
+
+	if diffFrom == "" && (len(diffIgnore) > 0 || len(diffOnly) > 0 || diffVerbose || (diffFormat != "" && diffFormat != diffFormatText)) {
+		return withExitCode(exitUsage, fmt.Errorf("--diff-ignore, --diff-only, --diff-verbose and --diff-format require --diff-from"))
+	}
+	switch diffFormat {
+	case "", diffFormatText, diffFormatJSON:
+	default:
+		return withExitCode(exitUsage, fmt.Errorf("unsupported diff format: %s (expected text or json)", diffFormat))
+	}
+	if targetVersion != "" && (watchMode || explain || onlyMigration != "") {
+		return withExitCode(exitUsage, fmt.Errorf("--target cannot be combined with --watch, --explain or --only-migration"))
+	}
+	if diffFrom != "" && (watchMode || explain || onlyMigration != "") {
+		return withExitCode(exitUsage, fmt.Errorf("--diff-from cannot be combined with --watch, --explain or --only-migration"))
+	}
+	if _, err := diffFilter(); err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	if checkTimeout != 0 && !checkMode {
+		return withExitCode(exitUsage, fmt.Errorf("--timeout requires --check"))
+	}
+	if checkMode && (watchMode || explain || onlyMigration != "" || diffFrom != "" || len(pgImages) > 1 ||
+		dryRun || emitDown || validateAllDir != "" || externalDatabaseURL(args) != "") {
+		return withExitCode(exitUsage, fmt.Errorf("--check cannot be combined with --watch, --explain, --only-migration, --diff-from, --dry-run, --emit-down, --validate-all, --database-url or several --pg-image values"))
+	}
+
+	if reportPath != "" && (watchMode || explain || onlyMigration != "" || diffFrom != "" || checkMode || len(pgImages) > 1) {
+		return withExitCode(exitUsage, fmt.Errorf("--report cannot be combined with --watch, --explain, --only-migration, --diff-from, --check or several --pg-image values"))
+	}
+
+	if splitClean && splitOutput == "" {
+		return withExitCode(exitUsage, fmt.Errorf("--split-clean requires --split-output"))
+	}
+	if splitOutput != "" {
+		if format, err := resolveOutputFormat(); err == nil && format != providers.FormatSQL {
+			return withExitCode(exitUsage, fmt.Errorf("--split-output only supports SQL output"))
+		}
+		if watchMode || explain || onlyMigration != "" || diffFrom != "" || checkMode || goldenPath != "" || len(pgImages) > 1 {
+			return withExitCode(exitUsage, fmt.Errorf("--split-output cannot be combined with --watch, --explain, --only-migration, --diff-from, --check, --golden or several --pg-image values"))
 		}
-		return
 	}
 
-	migrationDir := args[0]
+	nameCase, err := providers.ParseRenameCase(renameCase)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	if nameCase != providers.RenameCasePreserve && (explain || onlyMigration != "" || diffFrom != "") {
+		return withExitCode(exitUsage, fmt.Errorf("--rename-case cannot be combined with --explain, --only-migration or --diff-from"))
+	}
+
+	dialect, err := providers.ParseSQLDialect(sqlDialect)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	if dialect != providers.DialectPostgres {
+		if format, err := resolveOutputFormat(); err == nil && format != providers.FormatSQL {
+			return withExitCode(exitUsage, fmt.Errorf("--dialect only applies to sql output"))
+		}
+		if splitOutput != "" || baselinePath != "" || diffFrom != "" || onlyMigration != "" || explain || idempotent || cleanOutput {
+			return withExitCode(exitUsage, fmt.Errorf("--dialect %s cannot be combined with --split-output, --baseline, --diff-from, --only-migration, --explain, --idempotent or --clean", dialect))
+		}
+	}
+
+	if format, err := resolveOutputFormat(); err == nil && jsonCompact && format != providers.FormatJSON {
+		return withExitCode(exitUsage, fmt.Errorf("--json-compact requires --format json"))
+	}
+
+	if baselinePath != "" {
+		if format, err := resolveOutputFormat(); err == nil && format != providers.FormatInfo && format != providers.FormatSQL && format != providers.FormatJSON {
+			return withExitCode(exitUsage, fmt.Errorf("--baseline only supports info, sql and json output"))
+		}
+		if watchMode || explain || onlyMigration != "" || diffFrom != "" || checkMode || splitOutput != "" || len(pgImages) > 1 || nameCase != providers.RenameCasePreserve {
+			return withExitCode(exitUsage, fmt.Errorf("--baseline cannot be combined with --watch, --explain, --only-migration, --diff-from, --check, --split-output, --rename-case or several --pg-image values"))
+		}
+		// The changes to baseline tables are ALTER statements, which can
+		// neither be guarded nor preceded by drops
+		if idempotent || cleanOutput {
+			return withExitCode(exitUsage, fmt.Errorf("--baseline cannot be combined with --idempotent or --clean"))
+		}
+	}
+
+	if exactCounts && !withRowCounts {
+		return withExitCode(exitUsage, fmt.Errorf("--exact-counts requires --with-row-counts"))
+	}
+	if format, err := resolveOutputFormat(); err == nil && withRowCounts && format != providers.FormatInfo {
+		return withExitCode(exitUsage, fmt.Errorf("--with-row-counts only supports info output"))
+	}
+	if format, err := resolveOutputFormat(); err == nil && (idempotent || cleanOutput) && format != providers.FormatSQL {
+		return withExitCode(exitUsage, fmt.Errorf("--idempotent and --clean only support sql output"))
+	}
+
+	if connectRetries < 0 || connectTimeout < 0 {
+		return withExitCode(exitUsage, fmt.Errorf("--connect-retries and --connect-timeout cannot be negative"))
+	}
+	if (connectRetries != 0 || connectTimeout != 0) && externalDatabaseURL(args) == "" {
+		return withExitCode(exitUsage, fmt.Errorf("--connect-retries and --connect-timeout require --database-url"))
+	}
+
+	if validateAllDir != "" {
+		if migrationsArchive != "" || databaseURL != "" {
+			return withExitCode(exitUsage, fmt.Errorf("--validate-all cannot be combined with --migrations-archive or --database-url"))
+		}
+		opts := validateOptions{StrictValidate: dryRun, SequenceScheme: sequenceScheme}
+		passed, err := runValidateAll(validateAllDir, opts, checkSequence, 0, os.Stdout)
+		if err != nil {
+			return fmt.Errorf("validation failed: %w", err)
+		}
+		if !passed {
+			return errChecksFailed
+		}
+		return nil
+	}
+
+	if rawURL := externalDatabaseURL(args); rawURL != "" {
+		provider, err := selectProvider(registry, providerName)
+		if err != nil {
+			return err
+		}
+		if err := checkProviderOptions(provider); err != nil {
+			return err
+		}
+		var baseline *providers.Schema
+		if baselinePath != "" {
+			dbOptions, err := databaseOptions()
+			if err != nil {
+				return err
+			}
+			if err := requireDocker(); err != nil {
+				return err
+			}
+			image := ""
+			if len(pgImages) == 1 {
+				image = pgImages[0]
+			}
+			if baseline, err = loadBaseline(ctx, baselinePath, NewPostgreSQLManager(image, dbOptions...)); err != nil {
+				return err
+			}
+		}
+		output, err := extractFromDatabaseURL(ctx, rawURL, provider, baseline)
+		if err != nil {
+			return err
+		}
+		return printSchemaOutput(output)
+	}
+
+	migrationDir, migrationReader, err := migrationSource(args)
+	if err != nil {
+		return withExitCode(exitUsage, fmt.Errorf("invalid migration source: %w", err))
+	}
+	if splitClean {
+		if err := checkSplitClean(splitOutput, migrationDir); err != nil {
+			return err
+		}
+	}
+
+	if checkSequence {
+		passed, err := runSequenceCheck(migrationDir, migrationReader, sequenceScheme, os.Stdout)
+		if err != nil {
+			return fmt.Errorf("sequence check failed: %w", err)
+		}
+		if !passed {
+			return errChecksFailed
+		}
+		slog.Info("migration sequence is valid")
+	}
+
+	if dryRun {
+		passed, err := runDryRun(migrationDir, migrationReader, os.Stdout)
+		if err != nil {
+			return fmt.Errorf("dry run failed: %w", err)
+		}
+		if !passed {
+			return errChecksFailed
+		}
+		return nil
+	}
+
+	if emitDown {
+		if err := runEmitDown(migrationDir, migrationReader, os.Stdout); err != nil {
+			return fmt.Errorf("failed to compose teardown script: %w", err)
+		}
+		return nil
+	}
 	
-	// Get the selected provider
-	provider, exists := registry.Get(providerName)
-	if !exists {
-		slog.Error("unknown provider", "provider", providerName)
-		fmt.Printf("Unknown provider: %s\n", providerName)
-		fmt.Println("Use --list-providers to see available providers")
-		os.Exit(1)
+	provider, err := selectProvider(registry, providerName)
+	if err != nil {
+		return err
+	}
+	if err := checkProviderOptions(provider); err != nil {
+		return err
+	}
+
+	dbOptions, err := databaseOptions()
+	if err != nil {
+		return err
+	}
+
+	// A cached schema is printed without Docker, on a cache miss Setup reports
+	// an unreachable daemon itself
+	if !usesSchemaCache() {
+		if err := requireDocker(); err != nil {
+			return err
+		}
+	}
+
+	if len(pgImages) > 1 {
+		passed, err := runImageMatrix(ctx, migrationDir, migrationReader, provider, pgImages, func(image string) DatabaseManager {
+			return NewPostgreSQLManager(image, dbOptions...)
+		}, os.Stdout)
+		if err != nil {
+			return withExitCode(exitUsage, fmt.Errorf("image matrix failed: %w", err))
+		}
+		if !passed {
+			return errChecksFailed
+		}
+		return nil
+	}
+
+	pgImage = ""
+	if len(pgImages) == 1 {
+		pgImage = pgImages[0]
+	}
+	if err := validateImageReference(pgImage); err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	dbManager := NewPostgreSQLManager(pgImage, dbOptions...)
+
+	if checkMode {
+		return runCheck(ctx, migrationDir, migrationReader, dbManager, checkTimeout)
+	}
+
+	if watchMode {
+		if migrationsArchive != "" || migrationDir == stdinSource {
+			return withExitCode(exitUsage, fmt.Errorf("--watch requires a migration directory"))
+		}
+		return watchMigrations(ctx, migrationDir, migrationReader, dbManager, provider, os.Stdout, os.Stderr)
+	}
+
+	if explain {
+		params, err := extractParams()
+		if err != nil {
+			return err
+		}
+		if params.Format != providers.FormatInfo {
+			return withExitCode(exitUsage, fmt.Errorf("--explain only supports info output"))
+		}
+
+		tables, provenance, err := explainSchema(ctx, migrationDir, migrationReader, dbManager)
+		if err != nil {
+			return err
+		}
+		tables = providers.OrderColumns(providers.OrderTables(tables, params.FormatOptions.TableOrder), params.FormatOptions.ColumnOrder)
+		fmt.Print("\n=== DATABASE SCHEMA ===\n" + providers.FormatSchemaInfoWithOptions(tables, providers.InfoOptions{
+			Summary:    showSummary,
+			TypeMap:    params.FormatOptions.TypeMap,
+			Provenance: provenance,
+			Color:      colorOutput(),
+		}))
+		return nil
+	}
+
+	if onlyMigration != "" {
+		output, err := migrationChanges(ctx, migrationDir, migrationReader, dbManager, onlyMigration)
+		if err != nil {
+			return err
+		}
+		fmt.Print(output)
+		return nil
 	}
 
+	if diffFrom != "" {
+		changes, err := schemaChangesSince(ctx, migrationDir, migrationReader, dbManager, diffFrom, targetVersion)
+		if err != nil {
+			return err
+		}
+		filter, err := diffFilter()
+		if err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		found, err := writeSchemaChanges(os.Stdout, changes, filter, diffFormat)
+		if err != nil {
+			return err
+		}
+		if found {
+			return errChecksFailed
+		}
+		return nil
+	}
+
+	var baseline *providers.Schema
+	if baselinePath != "" {
+		baseline, err = loadBaseline(ctx, baselinePath, NewPostgreSQLManager(pgImage, dbOptions...))
+		if err != nil {
+			return err
+		}
+	}
+
+	var cache *schemaCache
+	if usesSchemaCache() {
+		cache, err = newSchemaCache(cacheDir)
+		if err != nil {
+			slog.Warn("schema cache disabled", "error", err)
+		}
+	}
+
+	if err := processSchemaWithProvider(ctx, migrationDir, migrationReader, dbManager, provider, cache, baseline); err != nil {
+		return fmt.Errorf("failed to process schema: %w", err)
+	}
+	return nil
+}
+
+// databaseOptions returns the options of the databases migrations run in:
+// the startup timeout, the --var-file variables, the --db-setting settings
+// and the --container-wait readiness check
+func databaseOptions() ([]PostgreSQLOption, error) {
+	vars, err := loadVariables(varFilePath)
+	if err != nil {
+		return nil, withExitCode(exitUsage, err)
+	}
+	for _, value := range vars {
+		logSecrets.add(value)
+	}
+
+	settings, err := parseDBSettings(dbSettings)
+	if err != nil {
+		return nil, withExitCode(exitUsage, err)
+	}
+	options := []PostgreSQLOption{WithStartupTimeout(containerStartupTimeout()), WithVariables(vars), WithSettings(settings)}
+
+	strategy, err := readinessWaitStrategy()
+	if err != nil {
+		return nil, withExitCode(exitUsage, err)
+	}
+	if strategy != nil {
+		options = append(options, WithWaitStrategy(strategy))
+	}
+	return options, nil
+}
+
+// readinessWaitStrategy returns the readiness check chosen with
+// --container-wait, bounded by the startup timeout, or nil for the standard
+// PostgreSQL readiness message
+func readinessWaitStrategy() (wait.Strategy, error) {
+	switch {
+	case containerWait == "":
+		return nil, nil
+	case containerWait == "port":
+		return wait.ForListeningPort("5432/tcp").WithStartupTimeout(containerStartupTimeout()), nil
+	case strings.HasPrefix(containerWait, "log=") && len(containerWait) > len("log="):
+		return wait.ForLog(strings.TrimPrefix(containerWait, "log=")).WithStartupTimeout(containerStartupTimeout()), nil
+	}
+	return nil, fmt.Errorf("invalid --container-wait %q: expected port or log=<message>", containerWait)
+}
+
+// containerStartupTimeout returns the --startup-timeout, or the default
+// when it is not set
+func containerStartupTimeout() time.Duration {
+	if startupTimeout <= 0 {
+		return defaultStartupTimeout
+	}
+	return startupTimeout
+}
+
+// usesSchemaCache reports whether the run may print a cached schema instead
+// of starting a container
+func usesSchemaCache() bool {
+	return !noCache && len(pgImages) <= 1 && !watchMode && !explain && onlyMigration == "" && diffFrom == "" && !checkMode && splitOutput == "" && baselinePath == "" && !dumpCatalog
+}
+
+// selectProvider looks up an available provider by name. A comma-separated
+// list selects the first available provider in order.
+func selectProvider(registry *providers.ProviderRegistry, name string) (providers.SchemaProvider, error) {
+	if names := strings.Split(name, ","); len(names) > 1 {
+		for i := range names {
+			names[i] = strings.TrimSpace(names[i])
+		}
+		provider, err := registry.GetFirstAvailable(names)
+		if err != nil {
+			return nil, withExitCode(exitUsage, err)
+		}
+		slog.Info("selected provider", "provider", provider.Name(), "preference", name)
+		return provider, nil
+	}
+
+	provider, exists := registry.Get(name)
+	if !exists {
+		return nil, withExitCode(exitUsage, fmt.Errorf("unknown provider: %s (use --list-providers to see available providers)", name))
+	}
 	if !provider.IsAvailable() {
-		slog.Error("provider not available", "provider", providerName)
-		fmt.Printf("Provider '%s' is not available in this environment\n", providerName)
-		os.Exit(1)
+		return nil, withExitCode(exitUsage, fmt.Errorf("provider %s is not available in this environment", name))
 	}
+	return provider, nil
+}
 
-	migrationReader := NewFileMigrationReader()
-	dbManager := NewPostgreSQLManager(pgImage)
-	
-	if err := processSchemaWithProvider(migrationDir, migrationReader, dbManager, provider); err != nil {
-		slog.Error("failed to process schema", "error", err)
-		os.Exit(1)
+// checkProviderOptions rejects the formatting flags the selected provider
+// does not apply. pg_dump returns its own SQL, without the table details they
+// reorder or restyle.
+func checkProviderOptions(provider providers.SchemaProvider) error {
+	if provider.Name() != "pg_dump" {
+		return nil
+	}
+	var ignored []string
+	if fkStyle != string(providers.ForeignKeyInline) {
+		ignored = append(ignored, "--fk-style")
+	}
+	if columnOrder != string(providers.ColumnOrderPhysical) {
+		ignored = append(ignored, "--column-order")
+	}
+	if tableOrder != string(providers.TableOrderName) {
+		ignored = append(ignored, "--order-by")
+	}
+	if len(ignored) > 0 {
+		return withExitCode(exitUsage, fmt.Errorf("%s cannot be used with the pg_dump provider, which returns its own SQL", strings.Join(ignored, ", ")))
 	}
+	return nil
 }
 
-func processSchemaWithProvider(migrationDir string, migrationReader MigrationReader, dbManager DatabaseManager, provider providers.SchemaProvider) error {
+// migrationSource returns the location of the migrations and the reader for
+// it: the archive given with --migrations-archive, or the directory argument
+func migrationSource(args []string) (string, MigrationReader, error) {
+	var opts []MigrationReaderOption
+	if allowDuplicates {
+		opts = append(opts, WithDuplicateWarnings())
+	}
+
+	if migrationsArchive == "" && args[0] == stdinSource {
+		if isTerminal(os.Stdin) {
+			return "", nil, fmt.Errorf("standard input is a terminal: pipe SQL into mig2schema %s", stdinSource)
+		}
+		return stdinSource, NewStdinMigrationReader(os.Stdin), nil
+	}
+	if migrationsArchive == "" {
+		return args[0], NewFileMigrationReader(opts...), nil
+	}
+	if !isMigrationArchive(migrationsArchive) {
+		return "", nil, fmt.Errorf("unsupported migration archive %s: expected .tar, .tar.gz, .tgz or .sql.gz", migrationsArchive)
+	}
+	return migrationsArchive, NewArchiveMigrationReader(opts...), nil
+}
+
+// processSchemaWithProvider runs the migrations, extracts the schema and
+// prints it. When cache is not nil, output is reused for migrations and
+// settings seen before and no database is started.
+func processSchemaWithProvider(ctx context.Context, migrationDir string, migrationReader MigrationReader, dbManager DatabaseManager, provider providers.SchemaProvider, cache *schemaCache, baseline *providers.Schema) error {
+	if runReport != nil {
+		runReport.Provider = provider.Name()
+	}
+	if cache == nil {
+		defer streamJSON()()
+	}
+
+	output, err := buildSchemaOutput(ctx, migrationDir, migrationReader, dbManager, provider, cache, baseline)
+	if err != nil {
+		return err
+	}
+	return printSchemaOutput(output)
+}
+
+// jsonStdout receives JSON output table by table as it is encoded, instead
+// of it being rendered to a string first. streamJSON sets it for the runs
+// that only print the output.
+var jsonStdout io.Writer
+
+// streamJSON writes JSON output straight to stdout, unless it is compared
+// with a --golden file, and returns the function undoing it
+func streamJSON() func() {
+	if goldenPath != "" {
+		return func() {}
+	}
+	jsonStdout = os.Stdout
+	return func() { jsonStdout = nil }
+}
+
+// printSchemaOutput prints the extracted schema, or compares it with the
+// --golden file and fails when they differ
+func printSchemaOutput(output string) error {
+	if goldenPath != "" {
+		matches, err := compareGolden(goldenPath, output, updateGolden, os.Stdout)
+		if err != nil {
+			return err
+		}
+		if !matches {
+			return errChecksFailed
+		}
+		return nil
+	}
+
+	fmt.Print(output)
+	return nil
+}
+
+// buildSchemaOutput runs the migrations, extracts the schema and returns it
+// rendered in the selected output format. When baseline is not nil, only
+// what the schema adds on top of it is rendered.
+func buildSchemaOutput(ctx context.Context, migrationDir string, migrationReader MigrationReader, dbManager DatabaseManager, provider providers.SchemaProvider, cache *schemaCache, baseline *providers.Schema) (string, error) {
 	slog.Info("processing migration directory", "directory", migrationDir, "provider", provider.Name())
 
-	if _, err := os.Stat(migrationDir); os.IsNotExist(err) {
-		return fmt.Errorf("migration directory does not exist: %s", migrationDir)
+	params, err := extractParams()
+	if err != nil {
+		return "", err
 	}
 
-	ctx := context.Background()
+	if migrationDirMissing(migrationDir) {
+		return "", withExitCode(exitMigrationDirNotFound, fmt.Errorf("migration directory does not exist: %s", migrationDir))
+	}
 
 	slog.Info("parsing migration files")
 	migrations, err := migrationReader.DiscoverMigrations(migrationDir)
 	if err != nil {
-		return fmt.Errorf("failed to parse migrations: %w", err)
+		return "", fmt.Errorf("failed to parse migrations: %w", err)
 	}
 
 	if len(migrations) == 0 {
-		return fmt.Errorf("no migration files found in directory: %s", migrationDir)
+		return "", withExitCode(exitNoMigrations, fmt.Errorf("no migration files found in directory: %s", migrationDir))
 	}
 
 	slog.Info("found migrations", "count", len(migrations))
 
+	if targetVersion != "" {
+		migrations, err = targetMigrations(migrations, targetVersion)
+		if err != nil {
+			return "", withExitCode(exitUsage, err)
+		}
+		slog.Info("running migrations up to target", "target", targetVersion, "count", len(migrations))
+	}
+
+	var cacheKey string
+	if cache != nil {
+		cacheKey, err = migrationsCacheKey(migrations,
+			provider.Name(), string(params.Format), pgImage, string(params.FormatOptions.ForeignKeyStyle), string(params.FormatOptions.ColumnOrder), string(params.FormatOptions.TableOrder), string(params.RenameCase),
+			fmt.Sprint(normalizeDefaults), fmt.Sprint(idempotent), fmt.Sprint(cleanOutput), fmt.Sprint(includeExtensions), fmt.Sprint(includePrivileges), fmt.Sprint(extensionObjects), fmt.Sprint(jsonCompact), sqlDialect, fmt.Sprint(tsOptionalDefaults),
+			fmt.Sprint(strictTypes), fmt.Sprint(failOnEmptySchema), fmt.Sprint(showSummary), fmt.Sprint(withRowCounts), fmt.Sprint(exactCounts), fmt.Sprint(failOnLint), lintConfigCacheSetting(), fmt.Sprint(colorOutput()),
+			typeMapCacheSetting(params.FormatOptions.TypeMap), variablesCacheSetting(), strings.Join(dbSettings, "\x00"),
+			strings.Join(includeIndexes, "\x00"), strings.Join(excludeIndexes, "\x00"))
+		if err != nil {
+			slog.Warn("failed to compute cache key, skipping cache", "error", err)
+		} else if entry, ok := cache.get(cacheKey); ok {
+			slog.Info("using cached schema", "key", cacheKey)
+			if runReport != nil {
+				runReport.Cached = true
+			}
+			entry.Warnings.emit()
+			return entry.Output, nil
+		}
+	}
+
 	slog.Info("setting up database")
+	setupStart := time.Now()
 	if err := dbManager.Setup(ctx); err != nil {
-		return fmt.Errorf("failed to setup database: %w", err)
+		return "", withExitCode(exitSetupFailed, fmt.Errorf("failed to setup database: %w", err))
+	}
+	if runReport != nil {
+		runReport.SetupMS = time.Since(setupStart).Milliseconds()
 	}
 	defer func() {
-		if err := dbManager.Close(ctx); err != nil {
+		// Cleanup also runs when the run was interrupted
+		if err := dbManager.Close(context.WithoutCancel(ctx)); err != nil {
 			slog.Error("failed to cleanup", "error", err)
 		}
 	}()
 
 	slog.Info("running migrations")
-	if err := dbManager.RunMigrations(migrations); err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
+	if err := runMigrationsTimed(ctx, dbManager, migrations); err != nil {
+		return "", withExitCode(exitMigrationFailed, fmt.Errorf("failed to run migrations: %w", err))
 	}
 
 	slog.Info("extracting schema")
-	
-	// Determine format based on extractMode flag
-	format := providers.FormatInfo
-	if extractMode {
-		format = providers.FormatSQL
+
+	params.DB = dbManager.GetDB()
+	params.ConnectionString = dbManager.GetConnectionString()
+
+	extractionStart := time.Now()
+	result, err := provider.ExtractSchema(ctx, params)
+	if err != nil {
+		return "", withExitCode(exitExtractionFailed, fmt.Errorf("failed to extract schema: %w", err))
+	}
+	if runReport != nil {
+		runReport.ExtractionMS = time.Since(extractionStart).Milliseconds()
+		runReport.Tables = len(result.Tables)
 	}
 
-	// Extract schema using the provider
-	params := providers.ExtractParams{
-		DB:               dbManager.GetDB(),
-		ConnectionString: dbManager.GetConnectionString(),
-		Format:           format,
+	if failOnEmptySchema && !resultHasTables(result) {
+		return "", fmt.Errorf("extracted schema is empty: %d migration(s) ran but created no tables in the public schema", len(migrations))
 	}
 
-	result, err := provider.ExtractSchema(ctx, params)
+	output, warnings, err := checkAndRenderResult(params, result, baseline)
 	if err != nil {
-		return fmt.Errorf("failed to extract schema: %w", err)
+		return "", err
 	}
 
-	// Output the result
-	if extractMode {
-		fmt.Print(result.RawSQL)
+	if splitOutput != "" {
+		return "", writeSplitResult(params, result, provider.Name())
+	}
+
+	if cache != nil && cacheKey != "" {
+		if err := cache.put(cacheKey, cacheEntry{Output: output, Warnings: warnings}); err != nil {
+			slog.Warn("failed to write schema cache", "error", err)
+		}
+	}
+
+	return output, nil
+}
+
+// extractParams returns the extraction parameters selected by the flags,
+// without a database connection
+func extractParams() (providers.ExtractParams, error) {
+	format, err := resolveOutputFormat()
+	if err != nil {
+		return providers.ExtractParams{}, withExitCode(exitUsage, err)
+	}
+
+	foreignKeyStyle, err := providers.ParseForeignKeyStyle(fkStyle)
+	if err != nil {
+		return providers.ExtractParams{}, withExitCode(exitUsage, err)
+	}
+
+	order, err := providers.ParseColumnOrder(columnOrder)
+	if err != nil {
+		return providers.ExtractParams{}, withExitCode(exitUsage, err)
+	}
+
+	tablesOrder, err := providers.ParseTableOrder(tableOrder)
+	if err != nil {
+		return providers.ExtractParams{}, withExitCode(exitUsage, err)
+	}
+
+	nameCase, err := providers.ParseRenameCase(renameCase)
+	if err != nil {
+		return providers.ExtractParams{}, withExitCode(exitUsage, err)
+	}
+
+	typeMap, err := loadTypeMap(typeMapPath, typeMappings)
+	if err != nil {
+		return providers.ExtractParams{}, withExitCode(exitUsage, err)
+	}
+
+	indexFilter, err := providers.NewIndexFilter(includeIndexes, excludeIndexes)
+	if err != nil {
+		return providers.ExtractParams{}, withExitCode(exitUsage, err)
+	}
+
+	params := providers.ExtractParams{
+		Format: format,
+		FormatOptions: providers.FormatOptions{
+			ForeignKeyStyle:   foreignKeyStyle,
+			NormalizeDefaults: normalizeDefaults,
+			ColumnOrder:       order,
+			TableOrder:        tablesOrder,
+			TypeMap:           typeMap,
+			IfNotExists:       idempotent,
+			Clean:             cleanOutput,
+		},
+		IncludeExtensions:       includeExtensions,
+		IncludeExtensionObjects: extensionObjects,
+		IncludePrivileges:       includePrivileges,
+		IndexFilter:             indexFilter,
+		RenameCase:              nameCase,
+	}
+	if dumpCatalog {
+		params.CatalogDump = os.Stderr
+	}
+	return params, nil
+}
+
+// renderCheckedResult applies --strict-types and the schema lint to an
+// extraction result and renders it, warning about unmapped types. With
+// --lint, findings are reported on stderr grouped by table. When baseline is
+// not nil, only what the result adds on top of it is rendered.
+func renderCheckedResult(params providers.ExtractParams, result *providers.SchemaResult, baseline *providers.Schema) (string, error) {
+	output, _, err := checkAndRenderResult(params, result, baseline)
+	return output, err
+}
+
+// schemaWarnings are the lint findings and unmapped types of an extraction
+// result. They are cached with its output, so that a cached run warns like
+// the run that filled the cache.
+type schemaWarnings struct {
+	Lint        []LintFinding            `json:"lint,omitempty"`
+	LintSkipped bool                     `json:"lint_skipped,omitempty"`
+	Unmapped    []providers.UnmappedType `json:"unmapped,omitempty"`
+}
+
+// emitLint reports the lint findings on stderr grouped by table, unless
+// --silent is set
+func (w schemaWarnings) emitLint() {
+	if w.LintSkipped {
+		slog.Warn("schema lint skipped: the provider returned no table details")
+	}
+	if !silent {
+		writeLintReport(os.Stderr, w.Lint)
+	}
+}
+
+// emitUnmapped logs a warning for each unmapped type
+func (w schemaWarnings) emitUnmapped() {
+	for _, u := range w.Unmapped {
+		slog.Warn("unmapped data type passed through as-is", "table", u.Table, "column", u.Column, "type", u.DataType)
+	}
+}
+
+// emit reports all warnings, as checkAndRenderResult does
+func (w schemaWarnings) emit() {
+	w.emitLint()
+	w.emitUnmapped()
+}
+
+// checkAndRenderResult is renderCheckedResult, also returning the warnings it
+// reported
+func checkAndRenderResult(params providers.ExtractParams, result *providers.SchemaResult, baseline *providers.Schema) (string, schemaWarnings, error) {
+	warnings := schemaWarnings{Unmapped: providers.UnmappedTypes(result.Tables, params.FormatOptions.TypeMap)}
+	if strictTypes && len(warnings.Unmapped) > 0 {
+		return "", warnings, fmt.Errorf("found %d column(s) with unmapped data types: %s", len(warnings.Unmapped), describeUnmappedTypes(warnings.Unmapped))
+	}
+
+	var config lintConfig
+	if lintConfigPath != "" {
+		var err error
+		if config, err = loadLintConfig(lintConfigPath); err != nil {
+			return "", warnings, withExitCode(exitUsage, err)
+		}
+	}
+	lintEnforced := failOnLint || lintConfigPath != ""
+	if lintEnforced {
+		warnings.LintSkipped = len(result.Tables) == 0 && result.RawSQL != ""
+		warnings.Lint = lintSchema(result.Tables, config)
+		warnings.emitLint()
+	}
+	if errorCount := lintErrorCount(warnings.Lint); lintEnforced && errorCount > 0 {
+		return "", warnings, fmt.Errorf("schema lint found %d error(s)", errorCount)
+	}
+
+	var output string
+	var err error
+	if baseline != nil {
+		output, err = renderBaselineResult(params, result, *baseline)
 	} else {
-		fmt.Println("\n=== DATABASE SCHEMA ===")
+		output, err = renderSchemaResult(params, result)
+	}
+	if err != nil {
+		return "", warnings, err
+	}
+
+	warnings.emitUnmapped()
+	return output, warnings, nil
+}
+
+// resultHasTables reports whether an extraction result contains at least one
+// table. Providers that only return SQL are checked for CREATE TABLE statements.
+func resultHasTables(result *providers.SchemaResult) bool {
+	if len(result.Tables) > 0 {
+		return true
+	}
+	for _, stmt := range providers.SplitStatements(result.RawSQL) {
+		if classifyStatement(stmt) == statementCreateTable {
+			return true
+		}
+	}
+	return false
+}
+
+// renderSchemaResult returns the text printed for an extraction result,
+// starting with a comment naming the server version when it is known
+func renderSchemaResult(params providers.ExtractParams, result *providers.SchemaResult) (string, error) {
+	output, err := formatSchemaResult(params, result)
+	if err != nil || output == "" {
+		return output, err
+	}
+	return versionHeader(params.Format, result.ServerVersion) + output, nil
+}
+
+// versionHeader returns the comment naming the server version that starts
+// output in format, or "" when the version is unknown. Info output has its
+// own header and JSON output has a server_version field.
+func versionHeader(format providers.SchemaFormat, version int) string {
+	if version == 0 {
+		return ""
+	}
+	line := "PostgreSQL version: " + providers.FormatServerVersion(version)
+	switch format {
+	case providers.FormatSQL:
+		return "-- " + line + "\n\n"
+	case providers.FormatTypeScript, providers.FormatPrisma:
+		return "// " + line + "\n\n"
+	case providers.FormatSQLAlchemy, providers.FormatAtlas:
+		return "# " + line + "\n\n"
+	case providers.FormatMarkdown:
+		return "<!-- " + line + " -->\n\n"
+	default:
+		return ""
+	}
+}
+
+// formatSchemaResult formats an extraction result in the requested format
+func formatSchemaResult(params providers.ExtractParams, result *providers.SchemaResult) (string, error) {
+	switch params.Format {
+	case providers.FormatSQL:
+		if sqlDialect == providers.DialectMySQL {
+			if len(result.Tables) == 0 && result.RawSQL != "" {
+				return "", withExitCode(exitUsage, fmt.Errorf("--dialect mysql needs table details, which the provider does not return"))
+			}
+			return providers.FormatSchemaSQLDialect(result.Tables, sqlDialect, params.FormatOptions), nil
+		}
+		return result.RawSQL, nil
+	case providers.FormatTypeScript:
+		return providers.FormatSchemaTypeScriptWithOptions(result.Tables, providers.TypeScriptOptions{
+			OptionalDefaults: tsOptionalDefaults,
+		}), nil
+	case providers.FormatPrisma:
+		return providers.FormatSchemaPrisma(result.Tables), nil
+	case providers.FormatSQLAlchemy:
+		return providers.FormatSchemaSQLAlchemy(result.Tables), nil
+	case providers.FormatAtlas:
+		return providers.FormatSchemaAtlasHCL(result.Tables), nil
+	case providers.FormatMarkdown:
+		return providers.FormatSchemaMarkdown(result.Tables), nil
+	case providers.FormatJSON:
+		opts := providers.JSONOptions{Compact: jsonCompact}
+		if jsonStdout != nil {
+			return "", providers.WriteSchemaJSON(jsonStdout, result.Schema, opts)
+		}
+		return providers.FormatSchemaJSONWithOptions(result.Schema, opts)
+	default:
 		// Use the native formatter for info mode
-		fmt.Print(providers.FormatSchemaInfo(result.Tables))
+		return "\n=== DATABASE SCHEMA ===\n" + providers.FormatFullSchemaInfoWithOptions(result.Schema, providers.InfoOptions{
+			Summary:   showSummary,
+			TypeMap:   params.FormatOptions.TypeMap,
+			RowCounts: tableRowCounts(params.DB, result.Tables),
+			Color:     colorOutput(),
+		}), nil
 	}
-	
-	return nil
+}
+
+// colorOutput reports whether info output is colored: only when stdout is a
+// terminal, neither --no-color nor NO_COLOR is set and the output is not
+// compared with a --golden file
+func colorOutput() bool {
+	return !noColor && os.Getenv("NO_COLOR") == "" && goldenPath == "" && isTerminal(os.Stdout)
+}
+
+// tableRowCounts returns the row counts shown with --with-row-counts, or nil
+// when they are not requested. Counting is best effort: a failure is logged
+// and the schema is shown without counts.
+func tableRowCounts(db *sql.DB, tables []providers.Table) providers.RowCounts {
+	if !withRowCounts {
+		return nil
+	}
+	if db == nil {
+		slog.Warn("row counts skipped: the provider has no database connection")
+		return nil
+	}
+	counts, err := providers.CountRows(context.Background(), db, "public", tables, exactCounts)
+	if err != nil {
+		slog.Warn("row counts skipped", "error", err)
+		return nil
+	}
+	return counts
+}
+
+// describeUnmappedTypes lists unmapped columns as table.column (type)
+func describeUnmappedTypes(unmapped []providers.UnmappedType) string {
+	descriptions := make([]string, len(unmapped))
+	for i, u := range unmapped {
+		descriptions[i] = fmt.Sprintf("%s.%s (%s)", u.Table, u.Column, u.DataType)
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// resolveOutputFormat combines --format and -e into the requested format
+func resolveOutputFormat() (providers.SchemaFormat, error) {
+	if outputFormat == "" {
+		if extractMode {
+			return providers.FormatSQL, nil
+		}
+		return providers.FormatInfo, nil
+	}
+
+	format := providers.SchemaFormat(outputFormat)
+	switch format {
+	case providers.FormatInfo, providers.FormatSQL, providers.FormatTypeScript, providers.FormatPrisma, providers.FormatSQLAlchemy,
+		providers.FormatAtlas, providers.FormatMarkdown, providers.FormatJSON:
+	default:
+		return "", fmt.Errorf("unsupported format: %s (expected info, sql, typescript, prisma, sqlalchemy, atlas, markdown or json)", outputFormat)
+	}
+
+	if extractMode && format != providers.FormatSQL {
+		return "", fmt.Errorf("--extract cannot be combined with --format %s", outputFormat)
+	}
+	return format, nil
 }
 
 func processSchema(migrationDir string, migrationReader MigrationReader, dbManager DatabaseManager, schemaExtractor SchemaExtractor) error {
@@ -221,7 +1395,7 @@ func processSchema(migrationDir string, migrationReader MigrationReader, dbManag
 	}()
 
 	slog.Info("running migrations")
-	if err := dbManager.RunMigrations(migrations); err != nil {
+	if err := dbManager.RunMigrations(ctx, migrations); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
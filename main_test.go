@@ -6,12 +6,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/alc6/mig2schema/providers"
+	"github.com/testcontainers/testcontainers-go/wait"
 )
 
 func TestMigrationToSchema(t *testing.T) {
@@ -38,6 +40,18 @@ func TestMigrationToSchema(t *testing.T) {
 			);
 			create index idx_posts_user_id on posts(user_id);
 		`,
+		"003_add_posts_trigger.up.sql": `
+			create function set_created_at() returns trigger as $$
+			begin
+				new.created_at := coalesce(new.created_at, now());
+				return new;
+			end;
+			$$ language plpgsql;
+
+			create trigger posts_set_created_at
+				before insert or update on posts
+				for each row execute function set_created_at();
+		`,
 	}
 
 	for filename, content := range migrationContent {
@@ -47,7 +61,7 @@ func TestMigrationToSchema(t *testing.T) {
 
 	migrations, err := ParseMigrations(tempDir)
 	require.NoError(t, err)
-	assert.Len(t, migrations, 2)
+	assert.Len(t, migrations, 3)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
@@ -60,7 +74,7 @@ func TestMigrationToSchema(t *testing.T) {
 		}
 	}()
 
-	err = db.RunMigrations(migrations)
+	err = db.RunMigrations(ctx, migrations)
 	require.NoError(t, err)
 
 	schema, err := ExtractSchema(db.DB)
@@ -85,6 +99,267 @@ func TestMigrationToSchema(t *testing.T) {
 		}
 	}
 	assert.True(t, hasPrimaryKey, "users table should have a primary key")
+
+	require.Len(t, usersTable.UniqueConstraints, 1)
+	assert.Equal(t, "users_email_key", usersTable.UniqueConstraints[0].Name)
+	assert.Equal(t, []string{"email"}, usersTable.UniqueConstraints[0].Columns)
+	for _, idx := range usersTable.Indexes {
+		assert.NotEqual(t, "users_email_key", idx.Name, "constraint backing index should not be listed as an index")
+	}
+
+	var postsTable *providers.Table
+	for i := range schema {
+		if schema[i].Name == "posts" {
+			postsTable = &schema[i]
+		}
+	}
+	require.NotNil(t, postsTable, "posts table not found in schema")
+	require.Len(t, postsTable.Triggers, 1, "multi-event trigger should be reported once")
+	trigger := postsTable.Triggers[0]
+	assert.Equal(t, "posts_set_created_at", trigger.Name)
+	assert.Equal(t, "BEFORE", trigger.Timing)
+	assert.ElementsMatch(t, []string{"INSERT", "UPDATE"}, trigger.Events)
+	assert.Equal(t, "set_created_at", trigger.FunctionName)
+	assert.Contains(t, trigger.Definition, "CREATE TRIGGER posts_set_created_at")
+
+	fullSchema, err := providers.ExtractFullSchema(db.DB)
+	require.NoError(t, err)
+	require.Len(t, fullSchema.Functions, 1)
+	assert.Equal(t, "set_created_at", fullSchema.Functions[0].Name)
+	assert.Equal(t, "function", fullSchema.Functions[0].Kind)
+	assert.Contains(t, fullSchema.Functions[0].Definition, "$function$")
+	assert.GreaterOrEqual(t, fullSchema.ServerVersion, 160000, "the default image runs PostgreSQL 16")
+
+	sqlOutput := providers.FormatFullSchemaSQL(fullSchema, providers.FormatOptions{})
+	assert.Less(t, strings.Index(sqlOutput, "FUNCTION public.set_created_at"), strings.Index(sqlOutput, "create table posts"))
+	assert.Contains(t, sqlOutput, "    id serial not null,\n", "serial columns should round-trip as serial")
+	assert.NotContains(t, sqlOutput, "nextval(")
+	assert.Contains(t, FormatSchema(schema), "  - id SERIAL NOT NULL (PRIMARY KEY)")
+}
+
+func TestMigrationToSchemaColumnOrder(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping column order test")
+	}
+
+	tempDir := t.TempDir()
+	migrationContent := map[string]string{
+		"001_create_accounts.up.sql": `
+			create table accounts (
+				note text,
+				legacy_code text,
+				id serial primary key,
+				name text not null
+			);
+		`,
+		"002_alter_accounts.up.sql": `
+			alter table accounts drop column legacy_code;
+			alter table accounts add column email text not null default '';
+		`,
+	}
+	for filename, content := range migrationContent {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644))
+	}
+
+	migrations, err := ParseMigrations(tempDir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	db, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer db.Close(ctx)
+	require.NoError(t, db.RunMigrations(ctx, migrations))
+
+	schema, err := ExtractSchema(db.DB)
+	require.NoError(t, err)
+	require.Len(t, schema, 1)
+
+	var names []string
+	var positions []int
+	for _, col := range schema[0].Columns {
+		names = append(names, col.Name)
+		positions = append(positions, col.OrdinalPosition)
+	}
+	assert.Equal(t, []string{"note", "id", "name", "email"}, names)
+	assert.Equal(t, []int{1, 3, 4, 5}, positions, "dropped column should leave a gap")
+
+	logical := providers.OrderColumns(schema, providers.ColumnOrderLogical)
+	var logicalNames []string
+	for _, col := range logical[0].Columns {
+		logicalNames = append(logicalNames, col.Name)
+	}
+	assert.Equal(t, []string{"id", "name", "email", "note"}, logicalNames)
+}
+
+func TestMigrationToSchemaPartitions(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping partition test")
+	}
+
+	tempDir := t.TempDir()
+	migrationContent := map[string]string{
+		"001_create_events.up.sql": `
+			create table events (
+				id integer not null,
+				created_at date not null,
+				kind text,
+				primary key (id, created_at)
+			) partition by range (created_at);
+			create index idx_events_kind on events (kind);
+			create table events_2024 partition of events for values from ('2024-01-01') to ('2025-01-01');
+			create table events_default partition of events default;
+		`,
+	}
+	for filename, content := range migrationContent {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644))
+	}
+
+	migrations, err := ParseMigrations(tempDir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	db, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer db.Close(ctx)
+	require.NoError(t, db.RunMigrations(ctx, migrations))
+
+	schema, err := ExtractSchema(db.DB)
+	require.NoError(t, err)
+	require.Len(t, schema, 3)
+
+	byName := make(map[string]providers.Table)
+	for _, table := range schema {
+		byName[table.Name] = table
+	}
+	assert.Equal(t, "range", byName["events"].PartitionStrategy)
+	assert.Equal(t, "created_at", byName["events"].PartitionKey)
+	assert.False(t, byName["events"].IsPartition())
+	assert.Equal(t, "events", byName["events_2024"].PartitionOf)
+	assert.Equal(t, "FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')", byName["events_2024"].PartitionBound)
+	assert.Equal(t, "DEFAULT", byName["events_default"].PartitionBound)
+
+	// The generated SQL must recreate the same partitioning
+	sqlOutput := FormatSchemaAsSQL(schema)
+	_, err = db.DB.Exec("drop table events cascade")
+	require.NoError(t, err)
+	_, err = db.DB.Exec(sqlOutput)
+	require.NoError(t, err, sqlOutput)
+
+	recreated, err := ExtractSchema(db.DB)
+	require.NoError(t, err)
+	assert.Equal(t, FormatSchemaAsSQL(recreated), sqlOutput)
+}
+
+func TestMigrationToSchemaForeignKeyActions(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping foreign key action test")
+	}
+
+	tempDir := t.TempDir()
+	migrationContent := map[string]string{
+		"001_create_users.up.sql": `create table users (id serial primary key);`,
+		"002_create_posts.up.sql": `
+			create table posts (
+				id serial primary key,
+				user_id integer references users(id) on delete cascade,
+				editor_id integer references users(id) on delete set null on update restrict,
+				reviewer_id integer references users(id)
+			);
+		`,
+	}
+	for filename, content := range migrationContent {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644))
+	}
+
+	migrations, err := ParseMigrations(tempDir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	db, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer db.Close(ctx)
+	require.NoError(t, db.RunMigrations(ctx, migrations))
+
+	schema, err := ExtractSchema(db.DB)
+	require.NoError(t, err)
+
+	var posts providers.Table
+	for _, table := range schema {
+		if table.Name == "posts" {
+			posts = table
+		}
+	}
+	actions := make(map[string][2]string)
+	for _, fk := range posts.ForeignKeys {
+		actions[fk.Name] = [2]string{fk.OnDelete, fk.OnUpdate}
+	}
+	assert.Equal(t, map[string][2]string{
+		"posts_editor_id_fkey":   {"SET NULL", "RESTRICT"},
+		"posts_reviewer_id_fkey": {"NO ACTION", "NO ACTION"},
+		"posts_user_id_fkey":     {"CASCADE", "NO ACTION"},
+	}, actions)
+
+	sqlOutput := FormatSchemaAsSQL(schema)
+	assert.Contains(t, sqlOutput, "references users (id) on delete cascade")
+	assert.Contains(t, sqlOutput, "references users (id) on delete set null on update restrict")
+	assert.Contains(t, sqlOutput, "constraint posts_reviewer_id_fkey foreign key (reviewer_id) references users (id),\n")
+}
+
+func TestMigrationToSchemaInheritance(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping inheritance test")
+	}
+
+	tempDir := t.TempDir()
+	migrationContent := map[string]string{
+		"001_create_records.up.sql": `
+			create table records (created_at date not null default current_date);
+			create table users (id integer primary key) inherits (records);
+			create table measurements (taken_at date not null) partition by range (taken_at);
+			create table measurements_2024 partition of measurements for values from ('2024-01-01') to ('2025-01-01');
+		`,
+	}
+	for filename, content := range migrationContent {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644))
+	}
+
+	migrations, err := ParseMigrations(tempDir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	db, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer db.Close(ctx)
+	require.NoError(t, db.RunMigrations(ctx, migrations))
+
+	schema, err := ExtractSchema(db.DB)
+	require.NoError(t, err)
+
+	byName := make(map[string]providers.Table)
+	for _, table := range schema {
+		byName[table.Name] = table
+	}
+	assert.Equal(t, []string{"records"}, byName["users"].Inherits)
+	assert.Empty(t, byName["measurements_2024"].Inherits, "partitions are not inheritance children")
+	assert.Equal(t, "measurements", byName["measurements_2024"].PartitionOf)
+	require.Len(t, byName["users"].Columns, 2)
+	assert.True(t, byName["users"].Columns[0].IsInherited)
+	assert.False(t, byName["users"].Columns[1].IsInherited)
+
+	sqlOutput := FormatSchemaAsSQL(schema)
+	assert.Contains(t, sqlOutput, ") inherits (records);")
+	_, err = db.DB.Exec("drop table records, measurements cascade")
+	require.NoError(t, err)
+	_, err = db.DB.Exec(sqlOutput)
+	require.NoError(t, err, sqlOutput)
 }
 
 func TestFormatSchemaOutputModes(t *testing.T) {
@@ -207,7 +482,7 @@ func TestProcessSchemaUnit(t *testing.T) {
 			},
 		}
 		mockDB := &MockDatabaseManager{
-			RunMigrationsFunc: func(migrations []Migration) error {
+			RunMigrationsFunc: func(ctx context.Context, migrations []Migration) error {
 				return fmt.Errorf("SQL syntax error")
 			},
 		}
@@ -337,14 +612,1230 @@ func TestProcessSchemaUnit(t *testing.T) {
 	})
 }
 
+func TestResolveOutputFormat(t *testing.T) {
+	defer resetCommand()
+
+	tests := []struct {
+		name    string
+		extract bool
+		format  string
+		want    providers.SchemaFormat
+		wantErr bool
+	}{
+		{name: "default_info", want: providers.FormatInfo},
+		{name: "extract_flag", extract: true, want: providers.FormatSQL},
+		{name: "format_typescript", format: "typescript", want: providers.FormatTypeScript},
+		{name: "format_prisma", format: "prisma", want: providers.FormatPrisma},
+		{name: "format_sqlalchemy", format: "sqlalchemy", want: providers.FormatSQLAlchemy},
+		{name: "format_atlas", format: "atlas", want: providers.FormatAtlas},
+		{name: "format_json", format: "json", want: providers.FormatJSON},
+		{name: "extract_with_format_sql", extract: true, format: "sql", want: providers.FormatSQL},
+		{name: "extract_with_other_format", extract: true, format: "typescript", wantErr: true},
+		{name: "unknown_format", format: "yaml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extractMode = tt.extract
+			outputFormat = tt.format
+
+			got, err := resolveOutputFormat()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func resetCommand() {
 	extractMode = false
 	mcpMode = false
+	mcpTransport = mcpTransportStdio
+	mcpAddr = defaultMCPAddr
+	configPath = ""
+	providerName = "native"
+	dryRun = false
+	pgImages = []string{"postgres:16-alpine"}
+	emitDown = false
+	checkMode = false
+	checkTimeout = 0
+	validateAllDir = ""
+	migrationsArchive = ""
+	outputFormat = ""
+	sqlDialect = providers.DialectPostgres
+	tsOptionalDefaults = false
+	fkStyle = string(providers.ForeignKeyInline)
+	strictTypes = false
+	includeExtensions = false
+	includePrivileges = false
+	extensionObjects = false
+	dumpCatalog = false
+	reportPath = ""
+	runReport = nil
+	normalizeDefaults = false
+	idempotent = false
+	cleanOutput = false
+	noCache = false
+	cacheDir = ""
+	allowDuplicates = false
+	checkSequence = false
+	sequenceScheme = sequenceAuto
+	failOnEmptySchema = false
+	columnOrder = string(providers.ColumnOrderPhysical)
+	tableOrder = string(providers.TableOrderName)
+	renameCase = string(providers.RenameCasePreserve)
+	databaseURL = ""
+	connectRetries = 0
+	connectTimeout = 0
+	containerWait = ""
+	showSummary = false
+	failOnLint = false
+	lintConfigPath = ""
+	onlyMigration = ""
+	diffFrom = ""
+	diffIgnore = nil
+	diffOnly = nil
+	diffVerbose = false
+	diffFormat = diffFormatText
+	explain = false
+	varFilePath = ""
+	dbSettings = nil
+	watchMode = false
+	incremental = false
+	goldenPath = ""
+	updateGolden = false
+	targetVersion = ""
+	logLevel = "info"
+	logFormat = logFormatJSON
+	silent = false
+	noColor = false
+	splitOutput = ""
+	splitClean = false
+	baselinePath = ""
+	jsonCompact = false
+	withRowCounts = false
+	exactCounts = false
+	includeIndexes = nil
+	excludeIndexes = nil
+	typeMapPath = ""
+	typeMappings = nil
 	rootCmd.ResetFlags()
 	rootCmd.Flags().BoolVarP(&extractMode, "extract", "e", false, "Extract schema as SQL CREATE statements")
 	rootCmd.Flags().BoolVar(&mcpMode, "mcp", false, "Run as Model Context Protocol server")
 }
 
 func isDockerAvailable() bool {
-	return true
+	return checkDocker() == nil
+}
+
+func TestProcessSchemaWithProviderStrictTypes(t *testing.T) {
+	defer resetCommand()
+	tempDir := t.TempDir()
+
+	reader := &MockMigrationReader{
+		DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+			return []Migration{{Name: "001_test", UpFile: "001_test.up.sql"}}, nil
+		},
+	}
+	provider := &MockSchemaProvider{
+		ExtractSchemaFunc: func(ctx context.Context, params providers.ExtractParams) (*providers.SchemaResult, error) {
+			return &providers.SchemaResult{
+				Schema: providers.Schema{Tables: []providers.Table{{
+					Name: "places",
+					Columns: []providers.Column{
+						{Name: "id", DataType: "integer", IsPrimaryKey: true},
+						{Name: "address", DataType: "postal_address"},
+					},
+				}}},
+				Format: params.Format,
+			}, nil
+		},
+	}
+
+	t.Run("warns_by_default", func(t *testing.T) {
+		strictTypes = false
+		err := processSchemaWithProvider(context.Background(), tempDir, reader, &MockDatabaseManager{}, provider, nil, nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("strict_fails", func(t *testing.T) {
+		strictTypes = true
+		err := processSchemaWithProvider(context.Background(), tempDir, reader, &MockDatabaseManager{}, provider, nil, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "places.address (postal_address)")
+	})
+}
+
+func TestProcessSchemaWithProviderLint(t *testing.T) {
+	defer resetCommand()
+	tempDir := t.TempDir()
+
+	reader := &MockMigrationReader{
+		DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+			return []Migration{{Name: "001_test", UpFile: "001_test.up.sql"}}, nil
+		},
+	}
+	provider := &MockSchemaProvider{
+		ExtractSchemaFunc: func(ctx context.Context, params providers.ExtractParams) (*providers.SchemaResult, error) {
+			return &providers.SchemaResult{
+				Schema: providers.Schema{Tables: []providers.Table{{
+					Name:    "audit_log",
+					Columns: []providers.Column{{Name: "message", DataType: "text"}},
+				}}},
+				Format: params.Format,
+			}, nil
+		},
+	}
+
+	t.Run("off_by_default", func(t *testing.T) {
+		failOnLint = false
+		_, stderr := captureStreams(t, func() {
+			require.NoError(t, processSchemaWithProvider(context.Background(), tempDir, reader, &MockDatabaseManager{}, provider, nil, nil))
+		})
+		assert.NotContains(t, stderr, "missing_primary_key")
+	})
+
+	t.Run("lint_fails", func(t *testing.T) {
+		failOnLint = true
+		var err error
+		_, stderr := captureStreams(t, func() {
+			err = processSchemaWithProvider(context.Background(), tempDir, reader, &MockDatabaseManager{}, provider, nil, nil)
+		})
+		require.Error(t, err)
+		assert.Equal(t, "schema lint found 1 error(s)", err.Error())
+		assert.Equal(t, exitFailure, exitCode(err))
+		assert.Contains(t, stderr, "missing_primary_key")
+	})
+
+	t.Run("silent_hides_findings", func(t *testing.T) {
+		failOnLint = true
+		silent = true
+		defer func() { silent = false }()
+		var err error
+		_, stderr := captureStreams(t, func() {
+			err = processSchemaWithProvider(context.Background(), tempDir, reader, &MockDatabaseManager{}, provider, nil, nil)
+		})
+		require.Error(t, err)
+		assert.Empty(t, stderr)
+	})
+
+	t.Run("config_downgrades_to_warning", func(t *testing.T) {
+		failOnLint = false
+		lintConfigPath = filepath.Join(t.TempDir(), "lint.yaml")
+		require.NoError(t, os.WriteFile(lintConfigPath, []byte("rules:\n  missing_primary_key:\n    severity: warning\n"), 0644))
+		err := processSchemaWithProvider(context.Background(), tempDir, reader, &MockDatabaseManager{}, provider, nil, nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid_config", func(t *testing.T) {
+		lintConfigPath = filepath.Join(t.TempDir(), "lint.yaml")
+		require.NoError(t, os.WriteFile(lintConfigPath, []byte("rules:\n  no_such_rule:\n    enabled: true\n"), 0644))
+		err := processSchemaWithProvider(context.Background(), tempDir, reader, &MockDatabaseManager{}, provider, nil, nil)
+		require.Error(t, err)
+		assert.Equal(t, exitUsage, exitCode(err))
+	})
+}
+
+func TestProcessSchemaWithProviderCancelled(t *testing.T) {
+	defer resetCommand()
+	resetCommand()
+
+	reader := &MockMigrationReader{
+		DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+			return []Migration{{Name: "001_test", UpSQL: []byte("create table users (id integer);")}}, nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var closeErr error
+	dbManager := &MockDatabaseManager{
+		RunMigrationsFunc: func(ctx context.Context, migrations []Migration) error {
+			cancel()
+			return ctx.Err()
+		},
+		CloseFunc: func(ctx context.Context) error {
+			closeErr = ctx.Err()
+			return nil
+		},
+	}
+
+	err := processSchemaWithProvider(ctx, t.TempDir(), reader, dbManager, &MockSchemaProvider{}, nil, nil)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.True(t, dbManager.CloseCalled)
+	assert.NoError(t, closeErr, "cleanup is not cancelled with the run")
+}
+
+func TestProcessSchemaWithProviderCache(t *testing.T) {
+	defer resetCommand()
+	tempDir := t.TempDir()
+	cache, err := newSchemaCache(t.TempDir())
+	require.NoError(t, err)
+
+	reader := &MockMigrationReader{
+		DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+			return []Migration{{Name: "001_test", UpSQL: []byte("create table users (id integer);")}}, nil
+		},
+	}
+	provider := &MockSchemaProvider{
+		ExtractSchemaFunc: func(ctx context.Context, params providers.ExtractParams) (*providers.SchemaResult, error) {
+			return &providers.SchemaResult{RawSQL: "create table users (id integer);\n", Format: params.Format}, nil
+		},
+	}
+
+	extractMode = true
+	first := &MockDatabaseManager{}
+	require.NoError(t, processSchemaWithProvider(context.Background(), tempDir, reader, first, provider, cache, nil))
+	assert.True(t, first.SetupCalled)
+
+	provider.ExtractSchemaCalled = false
+	second := &MockDatabaseManager{}
+	require.NoError(t, processSchemaWithProvider(context.Background(), tempDir, reader, second, provider, cache, nil))
+	assert.False(t, second.SetupCalled, "cache hit should not start a database")
+	assert.False(t, provider.ExtractSchemaCalled)
+
+	// A different output format is a different cache entry
+	extractMode = false
+	third := &MockDatabaseManager{}
+	require.NoError(t, processSchemaWithProvider(context.Background(), tempDir, reader, third, provider, cache, nil))
+	assert.True(t, third.SetupCalled)
+}
+
+func TestProcessSchemaWithProviderFailOnEmptySchema(t *testing.T) {
+	defer resetCommand()
+	tempDir := t.TempDir()
+
+	reader := &MockMigrationReader{
+		DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+			return []Migration{{Name: "001_noop", UpSQL: []byte("select 1;")}}, nil
+		},
+	}
+	provider := &MockSchemaProvider{
+		ExtractSchemaFunc: func(ctx context.Context, params providers.ExtractParams) (*providers.SchemaResult, error) {
+			return &providers.SchemaResult{Format: params.Format}, nil
+		},
+	}
+
+	t.Run("empty_output_by_default", func(t *testing.T) {
+		failOnEmptySchema = false
+		err := processSchemaWithProvider(context.Background(), tempDir, reader, &MockDatabaseManager{}, provider, nil, nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("fails_when_enabled", func(t *testing.T) {
+		failOnEmptySchema = true
+		err := processSchemaWithProvider(context.Background(), tempDir, reader, &MockDatabaseManager{}, provider, nil, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "extracted schema is empty: 1 migration(s) ran but created no tables")
+	})
+}
+
+func TestResultHasTables(t *testing.T) {
+	assert.False(t, resultHasTables(&providers.SchemaResult{}))
+	assert.True(t, resultHasTables(&providers.SchemaResult{Schema: providers.Schema{Tables: []providers.Table{{Name: "users"}}}}))
+	assert.True(t, resultHasTables(&providers.SchemaResult{RawSQL: "CREATE EXTENSION IF NOT EXISTS citext;\n\nCREATE TABLE users (id integer);"}))
+	assert.False(t, resultHasTables(&providers.SchemaResult{RawSQL: "CREATE EXTENSION IF NOT EXISTS citext;"}))
+}
+
+func TestMigrationToSchemaCompositeIndexOrder(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping composite index order test")
+	}
+
+	tempDir := t.TempDir()
+	migrationContent := map[string]string{
+		"001_create_events.up.sql": `
+			create table events (id serial primary key, tenant_id integer not null, created_at timestamptz not null);
+			create index idx_events_created_tenant on events (created_at, tenant_id);
+			alter table events add constraint uq_events_created_id unique (created_at, id);
+		`,
+	}
+	for filename, content := range migrationContent {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644))
+	}
+
+	migrations, err := ParseMigrations(tempDir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	db, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer db.Close(ctx)
+	require.NoError(t, db.RunMigrations(ctx, migrations))
+
+	schema, err := ExtractSchema(db.DB)
+	require.NoError(t, err)
+	require.Len(t, schema, 1)
+
+	// Key columns keep the order of the definition, not of the table
+	require.Len(t, schema[0].Indexes, 1)
+	assert.Equal(t, []string{"created_at", "tenant_id"}, schema[0].Indexes[0].Columns)
+	require.Len(t, schema[0].UniqueConstraints, 1)
+	assert.Equal(t, []string{"created_at", "id"}, schema[0].UniqueConstraints[0].Columns)
+}
+
+func TestMigrationToSchemaIndexMethods(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping index method test")
+	}
+
+	tempDir := t.TempDir()
+	migrationContent := map[string]string{
+		"001_create_documents.up.sql": `
+			create table documents (id serial primary key, tags jsonb not null, created_at timestamptz not null);
+			create index idx_documents_tags on documents using gin (tags);
+			create index idx_documents_created_at on documents using brin (created_at);
+			create index idx_documents_id_hash on documents using hash (id);
+			create index idx_documents_id on documents (id, created_at);
+		`,
+	}
+	for filename, content := range migrationContent {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644))
+	}
+
+	migrations, err := ParseMigrations(tempDir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	db, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer db.Close(ctx)
+	require.NoError(t, db.RunMigrations(ctx, migrations))
+
+	schema, err := ExtractSchema(db.DB)
+	require.NoError(t, err)
+	require.Len(t, schema, 1)
+
+	methods := make(map[string]string)
+	for _, idx := range schema[0].Indexes {
+		methods[idx.Name] = idx.Method
+	}
+	assert.Equal(t, map[string]string{
+		"idx_documents_created_at": "brin",
+		"idx_documents_tags":       "gin",
+		"idx_documents_id_hash":    "hash",
+		"idx_documents_id":         "",
+	}, methods)
+	assert.Contains(t, FormatSchemaAsSQL(schema), "create index idx_documents_tags on documents using gin (tags);")
+}
+
+func TestMigrationToSchemaExclusionConstraints(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping exclusion constraint test")
+	}
+
+	tempDir := t.TempDir()
+	migrationContent := map[string]string{
+		"001_create_bookings.up.sql": `
+			create table bookings (
+				id serial primary key,
+				room text not null,
+				during tstzrange not null,
+				cancelled boolean not null default false,
+				constraint bookings_no_overlap exclude using gist (during with &&) where (not cancelled)
+			);
+			create index idx_bookings_room on bookings (room);
+		`,
+	}
+	for filename, content := range migrationContent {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644))
+	}
+
+	migrations, err := ParseMigrations(tempDir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	db, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer db.Close(ctx)
+	require.NoError(t, db.RunMigrations(ctx, migrations))
+
+	schema, err := ExtractSchema(db.DB)
+	require.NoError(t, err)
+	require.Len(t, schema, 1)
+
+	require.Len(t, schema[0].ExclusionConstraints, 1)
+	exclusion := schema[0].ExclusionConstraints[0]
+	assert.Equal(t, "bookings_no_overlap", exclusion.Name)
+	assert.Equal(t, "gist", exclusion.Method)
+	assert.Equal(t, []providers.ExclusionElement{{Expression: "during", Operator: "&&"}}, exclusion.Elements)
+	assert.Equal(t, "NOT cancelled", exclusion.Where)
+
+	// The index backing the constraint is not listed separately
+	require.Len(t, schema[0].Indexes, 1)
+	assert.Equal(t, "idx_bookings_room", schema[0].Indexes[0].Name)
+
+	sqlOutput := FormatSchemaAsSQL(schema)
+	assert.Contains(t, sqlOutput, "    constraint bookings_no_overlap exclude using gist (during with &&) where (NOT cancelled)")
+
+	// The SQL output can be replayed
+	replay, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer replay.Close(ctx)
+	require.NoError(t, replay.RunMigrations(ctx, []Migration{{Name: "replay", UpSQL: []byte(sqlOutput)}}))
+}
+
+func TestMigrationToSchemaFullSchemaObjects(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping schema objects test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	db, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer db.Close(ctx)
+	require.NoError(t, db.RunMigrations(ctx, []Migration{{
+		Name: "001_create_orders",
+		UpSQL: []byte(`create type mood as enum ('happy', 'sad, really');
+create sequence order_number as bigint start with 1000 increment by 10;
+create table orders (id serial primary key, number bigint default nextval('order_number'), mood mood);
+create view happy_orders as select id, number from orders where mood = 'happy';
+create view first_happy_orders as select * from happy_orders where id < 10;`),
+	}}))
+
+	schema, err := providers.ExtractFullSchema(db.DB)
+	require.NoError(t, err)
+
+	assert.Equal(t, []providers.Enum{{Name: "mood", Values: []string{"happy", "sad, really"}}}, schema.Enums)
+	assert.Equal(t, []providers.Sequence{
+		{Name: "order_number", DataType: "bigint", Start: 1000, Increment: 10},
+		{Name: "orders_id_seq", DataType: "integer", Start: 1, Increment: 1, OwnedBy: "orders.id"},
+	}, schema.Sequences)
+	require.Len(t, schema.Views, 2)
+	assert.Equal(t, "happy_orders", schema.Views[0].Name)
+	assert.Equal(t, "first_happy_orders", schema.Views[1].Name)
+	require.Len(t, schema.Tables, 1)
+
+	// The SQL output replays on an empty database
+	replay, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer replay.Close(ctx)
+	require.NoError(t, replay.RunMigrations(ctx, []Migration{{
+		Name:  "001_replay",
+		UpSQL: []byte(providers.FormatFullSchemaSQL(schema, providers.FormatOptions{})),
+	}}))
+}
+
+func TestMigrationToSchemaCompositeTypes(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping composite type test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	db, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer db.Close(ctx)
+	require.NoError(t, db.RunMigrations(ctx, []Migration{{
+		Name: "001_create_customers",
+		UpSQL: []byte(`create type address as (street text, city varchar(100));
+create table customers (id serial primary key, billing address, shipping address not null);`),
+	}}))
+
+	schema, err := providers.ExtractFullSchema(db.DB)
+	require.NoError(t, err)
+
+	assert.Equal(t, []providers.CompositeType{{Name: "address", Attributes: []providers.CompositeAttribute{
+		{Name: "street", Type: "text"},
+		{Name: "city", Type: "character varying(100)"},
+	}}}, schema.CompositeTypes)
+	require.Len(t, schema.Tables, 1)
+	billing := schema.Tables[0].Columns[1]
+	assert.Equal(t, "address", billing.DataType)
+	assert.True(t, billing.IsComposite)
+
+	sqlOutput := providers.FormatFullSchemaSQL(schema, providers.FormatOptions{})
+	assert.Contains(t, sqlOutput, "create type address as (street text, city character varying(100));\n")
+	assert.Contains(t, sqlOutput, "    shipping address not null,\n")
+
+	// The SQL output replays on an empty database
+	replay, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer replay.Close(ctx)
+	require.NoError(t, replay.RunMigrations(ctx, []Migration{{Name: "001_replay", UpSQL: []byte(sqlOutput)}}))
+}
+
+func TestMigrationToSchemaGrants(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping grants test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	db, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer db.Close(ctx)
+	require.NoError(t, db.RunMigrations(ctx, []Migration{{
+		Name: "001_create_users",
+		UpSQL: []byte(`create role app_reader;
+create table users (id serial primary key, email text);
+grant select on users to app_reader;
+grant insert, select on users to public;`),
+	}}))
+
+	params := providers.ExtractParams{DB: db.DB, Format: providers.FormatSQL}
+	result, err := providers.NewNativeProvider().ExtractSchema(ctx, params)
+	require.NoError(t, err)
+	assert.NotContains(t, result.RawSQL, "grant")
+
+	params.IncludePrivileges = true
+	result, err = providers.NewNativeProvider().ExtractSchema(ctx, params)
+	require.NoError(t, err)
+	require.Len(t, result.Tables, 1)
+	assert.Equal(t, []providers.GrantInfo{
+		{Grantee: "PUBLIC", Privileges: []string{"insert", "select"}},
+		{Grantee: "app_reader", Privileges: []string{"select"}},
+	}, result.Tables[0].Grants)
+	assert.Contains(t, result.RawSQL, "grant insert, select on users to public;\ngrant select on users to app_reader;\n")
+}
+
+func TestMigrationToSchemaBaseline(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping baseline test")
+	}
+	defer resetCommand()
+	resetCommand()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	root := t.TempDir()
+	writeMigrationTree(t, root, map[string]string{
+		"shared.sql": "create table audit_log (id serial primary key, message text);",
+	})
+	baseline, err := loadBaseline(ctx, filepath.Join(root, "shared.sql"), NewPostgreSQLManager(""))
+	require.NoError(t, err)
+	require.Len(t, baseline.Tables, 1)
+	assert.Equal(t, "audit_log", baseline.Tables[0].Name)
+
+	db, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer db.Close(ctx)
+	require.NoError(t, db.RunMigrations(ctx, []Migration{{
+		Name: "001_create_users",
+		UpSQL: []byte(`create table audit_log (id serial primary key, message text);
+create table users (id serial primary key);
+alter table audit_log add column user_id integer references users (id);`),
+	}}))
+	schema, err := providers.ExtractFullSchema(db.DB)
+	require.NoError(t, err)
+
+	changes, err := subtractBaseline(*baseline, schema)
+	require.NoError(t, err)
+	diff := changes.Tables
+	require.Len(t, diff.AddedTables, 1)
+	assert.Equal(t, "users", diff.AddedTables[0].Name)
+	require.Len(t, diff.ModifiedTables, 1)
+	assert.Equal(t, "audit_log", diff.ModifiedTables[0].Name)
+	assert.Len(t, diff.ModifiedTables[0].AddedColumns, 1)
+	assert.Len(t, diff.ModifiedTables[0].AddedForeignKeys, 1)
+}
+
+func TestMigrationToSchemaCollation(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping collation test")
+	}
+
+	tempDir := t.TempDir()
+	migrationContent := map[string]string{
+		"001_create_users.up.sql": `
+			create domain sortable_text as text collate "C";
+			create table users (
+				id serial primary key,
+				name text collate "C" not null,
+				nickname sortable_text,
+				bio text
+			);
+		`,
+	}
+	for filename, content := range migrationContent {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644))
+	}
+
+	migrations, err := ParseMigrations(tempDir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	db, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer db.Close(ctx)
+	require.NoError(t, db.RunMigrations(ctx, migrations))
+
+	schema, err := ExtractSchema(db.DB)
+	require.NoError(t, err)
+	require.Len(t, schema, 1)
+
+	collations := make(map[string]string)
+	for _, col := range schema[0].Columns {
+		collations[col.Name] = col.Collation
+	}
+	// The domain already defaults to "C", only the explicit collation counts
+	assert.Equal(t, map[string]string{"id": "", "name": "C", "nickname": "", "bio": ""}, collations)
+	assert.Contains(t, FormatSchemaAsSQL(schema), `name text collate "C" not null`)
+}
+
+func TestMigrationToSchemaStorageParams(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping storage parameters test")
+	}
+
+	migrations := []Migration{{
+		Name: "001_create_events",
+		UpSQL: []byte(`
+			create table events (id int primary key) with (fillfactor = 70, autovacuum_enabled = false);
+			create table tags (id int primary key);
+		`),
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	db, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer db.Close(ctx)
+	require.NoError(t, db.RunMigrations(ctx, migrations))
+
+	schema, err := ExtractSchema(db.DB)
+	require.NoError(t, err)
+	require.Len(t, schema, 2)
+	assert.Equal(t, map[string]string{"fillfactor": "70", "autovacuum_enabled": "false"}, schema[0].StorageParams)
+	assert.Nil(t, schema[1].StorageParams)
+
+	sqlOutput := FormatSchemaAsSQL(schema)
+	assert.Contains(t, sqlOutput, ") with (autovacuum_enabled=false, fillfactor=70);")
+
+	// The parameters survive a replay of the SQL output
+	replay, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer replay.Close(ctx)
+	require.NoError(t, replay.RunMigrations(ctx, []Migration{{Name: "001_replay", UpSQL: []byte(sqlOutput)}}))
+
+	replayed, err := ExtractSchema(replay.DB)
+	require.NoError(t, err)
+	require.Len(t, replayed, 2)
+	assert.Equal(t, schema[0].StorageParams, replayed[0].StorageParams)
+}
+
+func TestMigrationToSchemaIdempotentReplay(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping idempotent replay test")
+	}
+
+	migrations := []Migration{{
+		Name: "001_create_schema",
+		UpSQL: []byte(`
+			create type mood as enum ('happy', 'sad');
+			create sequence order_number;
+			create table teams (id int primary key, owner_id int);
+			create table users (id int primary key, team_id int references teams (id), mood mood);
+			alter table teams add constraint teams_owner_id_fkey foreign key (owner_id) references users (id);
+			create index idx_users_team_id on users (team_id);
+			create function touch() returns trigger language plpgsql as $$ begin return new; end $$;
+			create trigger users_touch before update on users for each row execute function touch();
+			create view happy_users as select id from users where mood = 'happy';
+		`),
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	db, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer db.Close(ctx)
+	require.NoError(t, db.RunMigrations(ctx, migrations))
+
+	schema, err := providers.ExtractFullSchema(db.DB)
+	require.NoError(t, err)
+
+	// Guarded output replays on a database that already has every object
+	guarded := providers.FormatFullSchemaSQL(schema, providers.FormatOptions{IfNotExists: true})
+	require.NoError(t, db.RunMigrations(ctx, []Migration{{Name: "002_replay", UpSQL: []byte(guarded)}}))
+
+	// Clean output drops everything first and can be replayed any number of times
+	clean := providers.FormatFullSchemaSQL(schema, providers.FormatOptions{Clean: true})
+	require.NoError(t, db.RunMigrations(ctx, []Migration{{Name: "003_clean", UpSQL: []byte(clean)}}))
+	require.NoError(t, db.RunMigrations(ctx, []Migration{{Name: "004_clean", UpSQL: []byte(clean)}}))
+
+	replayed, err := providers.ExtractFullSchema(db.DB)
+	require.NoError(t, err)
+	assert.Equal(t, providers.FormatFullSchemaSQL(schema, providers.FormatOptions{}),
+		providers.FormatFullSchemaSQL(replayed, providers.FormatOptions{}))
+}
+
+func TestMigrationToSchemaDatetimePrecision(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping datetime precision test")
+	}
+
+	tempDir := t.TempDir()
+	migrationContent := map[string]string{
+		"001_create_events.up.sql": `
+			create table events (
+				id serial primary key,
+				created_at timestamp(0) not null,
+				updated_at timestamptz(6) not null,
+				seen_at timestamptz,
+				starts time(3)
+			);
+		`,
+	}
+	for filename, content := range migrationContent {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644))
+	}
+
+	migrations, err := ParseMigrations(tempDir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	db, err := SetupPostgreSQL(ctx)
+	require.NoError(t, err)
+	defer db.Close(ctx)
+	require.NoError(t, db.RunMigrations(ctx, migrations))
+
+	schema, err := ExtractSchema(db.DB)
+	require.NoError(t, err)
+
+	sqlOutput := FormatSchemaAsSQL(schema)
+	assert.Contains(t, sqlOutput, "created_at timestamp(0) not null")
+	assert.Contains(t, sqlOutput, "updated_at timestamptz not null")
+	assert.Contains(t, sqlOutput, "seen_at timestamptz,")
+	assert.Contains(t, sqlOutput, "starts time(3)")
+}
+
+func TestBuildSchemaOutputTarget(t *testing.T) {
+	defer resetCommand()
+	tempDir := t.TempDir()
+
+	reader := &MockMigrationReader{
+		DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+			return []Migration{{Name: "001_create_users"}, {Name: "002_create_posts"}}, nil
+		},
+	}
+	var ran []string
+	dbManager := &MockDatabaseManager{
+		RunMigrationsFunc: func(ctx context.Context, migrations []Migration) error {
+			for _, migration := range migrations {
+				ran = append(ran, migration.Name)
+			}
+			return nil
+		},
+	}
+
+	targetVersion = "001_create_users"
+	_, err := buildSchemaOutput(context.Background(), tempDir, reader, dbManager, &MockSchemaProvider{}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"001_create_users"}, ran)
+
+	targetVersion = "3"
+	_, err = buildSchemaOutput(context.Background(), tempDir, reader, &MockDatabaseManager{}, &MockSchemaProvider{}, nil, nil)
+	require.Error(t, err)
+	assert.Equal(t, exitUsage, exitCode(err))
+	assert.Contains(t, err.Error(), "available versions: 001, 002")
+}
+
+func TestTargetFlags(t *testing.T) {
+	defer resetCommand()
+
+	tests := []struct {
+		name string
+		set  func()
+	}{
+		{"with_watch", func() { watchMode = true }},
+		{"with_explain", func() { explain = true }},
+		{"with_only_migration", func() { onlyMigration = "001_create_users" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetCommand()
+			targetVersion = "001"
+			tt.set()
+
+			err := executeMig2Schema(context.Background(), []string{"migrations"}, providers.NewDefaultRegistry())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "--target cannot be combined with --watch, --explain or --only-migration")
+			assert.Equal(t, exitUsage, exitCode(err))
+		})
+	}
+}
+
+func TestIdempotentFlags(t *testing.T) {
+	defer resetCommand()
+
+	for _, set := range []func(){func() { idempotent = true }, func() { cleanOutput = true }} {
+		resetCommand()
+		set()
+		err := executeMig2Schema(context.Background(), []string{"migrations"}, providers.NewDefaultRegistry())
+		require.Error(t, err)
+		assert.Equal(t, exitUsage, exitCode(err))
+		assert.Contains(t, err.Error(), "--idempotent and --clean only support sql output")
+	}
+
+	resetCommand()
+	idempotent = true
+	cleanOutput = true
+	extractMode = true
+	params, err := extractParams()
+	require.NoError(t, err)
+	assert.True(t, params.FormatOptions.IfNotExists)
+	assert.True(t, params.FormatOptions.Clean)
+}
+
+func TestDiffFlags(t *testing.T) {
+	defer resetCommand()
+
+	tests := []struct {
+		name    string
+		set     func()
+		wantErr string
+	}{
+		{"ignore_requires_diff_from", func() { diffIgnore = []string{"indexes"} }, "require --diff-from"},
+		{"verbose_requires_diff_from", func() { diffVerbose = true }, "require --diff-from"},
+		{"format_requires_diff_from", func() { diffFormat = diffFormatJSON }, "require --diff-from"},
+		{"unknown_format", func() { diffFrom = "1"; diffFormat = "yaml" }, "unsupported diff format: yaml"},
+		{"unknown_category", func() { diffFrom = "1"; diffOnly = []string{"views"} }, `unknown diff category "views"`},
+		{"conflicts_with_explain", func() { diffFrom = "1"; explain = true }, "--diff-from cannot be combined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetCommand()
+			tt.set()
+			err := executeMig2Schema(context.Background(), []string{"migrations"}, providers.NewDefaultRegistry())
+			require.Error(t, err)
+			assert.Equal(t, exitUsage, exitCode(err))
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	resetCommand()
+	diffVerbose = true
+	diffIgnore = []string{"indexes"}
+	filter, err := diffFilter()
+	require.NoError(t, err)
+	assert.True(t, filter.IsEmpty())
+}
+
+func TestCheckFlags(t *testing.T) {
+	defer resetCommand()
+
+	tests := []struct {
+		name    string
+		set     func()
+		wantErr string
+	}{
+		{"timeout_requires_check", func() { checkTimeout = time.Minute }, "--timeout requires --check"},
+		{"conflicts_with_watch", func() { checkMode = true; watchMode = true }, "--check cannot be combined"},
+		{"conflicts_with_image_matrix", func() {
+			checkMode = true
+			pgImages = []string{"postgres:15-alpine", "postgres:16-alpine"}
+		}, "--check cannot be combined"},
+		{"conflicts_with_dry_run", func() { checkMode = true; dryRun = true }, "--check cannot be combined"},
+		{"conflicts_with_database_url", func() { checkMode = true; databaseURL = "postgres://app@db.example.com/app" }, "--check cannot be combined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetCommand()
+			tt.set()
+			err := executeMig2Schema(context.Background(), []string{"migrations"}, providers.NewDefaultRegistry())
+			require.Error(t, err)
+			assert.Equal(t, exitUsage, exitCode(err))
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestSplitOutputFlags(t *testing.T) {
+	defer resetCommand()
+
+	tests := []struct {
+		name    string
+		set     func()
+		wantErr string
+	}{
+		{"clean_requires_split_output", func() { splitClean = true }, "--split-clean requires --split-output"},
+		{"sql_only", func() { splitOutput = t.TempDir(); outputFormat = "json" }, "--split-output only supports SQL output"},
+		{"conflicts_with_golden", func() { splitOutput = t.TempDir(); extractMode = true; goldenPath = "schema.sql" }, "--split-output cannot be combined"},
+		{"clean_in_migration_directory", func() { splitOutput = "."; splitClean = true; extractMode = true }, "--split-clean cannot be used when --split-output contains the migration directory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetCommand()
+			tt.set()
+			err := executeMig2Schema(context.Background(), []string{"migrations"}, providers.NewDefaultRegistry())
+			require.Error(t, err)
+			assert.Equal(t, exitUsage, exitCode(err))
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestRenameCaseFlags(t *testing.T) {
+	defer resetCommand()
+
+	resetCommand()
+	renameCase = "camel"
+	err := executeMig2Schema(context.Background(), []string{"migrations"}, providers.NewDefaultRegistry())
+	require.Error(t, err)
+	assert.Equal(t, exitUsage, exitCode(err))
+	assert.Contains(t, err.Error(), "unsupported rename case: camel")
+
+	resetCommand()
+	renameCase = "snake"
+	explain = true
+	err = executeMig2Schema(context.Background(), []string{"migrations"}, providers.NewDefaultRegistry())
+	require.Error(t, err)
+	assert.Equal(t, exitUsage, exitCode(err))
+	assert.Contains(t, err.Error(), "--rename-case cannot be combined")
+}
+
+func TestBaselineFlags(t *testing.T) {
+	defer resetCommand()
+
+	tests := []struct {
+		name    string
+		set     func()
+		wantErr string
+	}{
+		{"unsupported_format", func() { baselinePath = "shared.sql"; outputFormat = "prisma" }, "--baseline only supports info, sql and json output"},
+		{"conflicts_with_diff_from", func() { baselinePath = "shared.sql"; diffFrom = "001" }, "--baseline cannot be combined"},
+		{"conflicts_with_rename_case", func() { baselinePath = "shared.sql"; renameCase = "snake" }, "--baseline cannot be combined"},
+		{"conflicts_with_idempotent", func() { baselinePath = "shared.sql"; idempotent = true }, "--baseline cannot be combined with --idempotent or --clean"},
+		{"conflicts_with_clean", func() { baselinePath = "shared.sql"; cleanOutput = true }, "--baseline cannot be combined with --idempotent or --clean"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetCommand()
+			tt.set()
+			err := executeMig2Schema(context.Background(), []string{"migrations"}, providers.NewDefaultRegistry())
+			require.Error(t, err)
+			assert.Equal(t, exitUsage, exitCode(err))
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestJSONCompactFlag(t *testing.T) {
+	defer resetCommand()
+
+	resetCommand()
+	jsonCompact = true
+	err := executeMig2Schema(context.Background(), []string{"migrations"}, providers.NewDefaultRegistry())
+	require.Error(t, err)
+	assert.Equal(t, exitUsage, exitCode(err))
+	assert.Contains(t, err.Error(), "--json-compact requires --format json")
+
+	resetCommand()
+	jsonCompact = true
+	params := providers.ExtractParams{Format: providers.FormatJSON}
+	output, err := renderSchemaResult(params, &providers.SchemaResult{Schema: providers.Schema{
+		Tables: []providers.Table{{Name: "users", Columns: []providers.Column{{Name: "id", DataType: "integer"}}}},
+	}})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(output, `{"tables":[{"name":"users",`))
+	assert.Equal(t, 1, strings.Count(output, "\n"))
+}
+
+func TestProcessSchemaWithProviderStreamsJSON(t *testing.T) {
+	defer resetCommand()
+	resetCommand()
+	outputFormat = "json"
+
+	reader := &MockMigrationReader{
+		DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+			return []Migration{{Name: "001_users", UpSQL: []byte("create table users (id integer);")}}, nil
+		},
+	}
+	provider := &MockSchemaProvider{
+		ExtractSchemaFunc: func(ctx context.Context, params providers.ExtractParams) (*providers.SchemaResult, error) {
+			assert.NotNil(t, jsonStdout, "JSON is written to stdout while it is encoded")
+			return &providers.SchemaResult{Format: params.Format, Schema: providers.Schema{
+				Tables: []providers.Table{{Name: "users", Columns: []providers.Column{{Name: "id", DataType: "integer"}}}},
+			}}, nil
+		},
+	}
+
+	stdout, _ := captureStreams(t, func() {
+		require.NoError(t, processSchemaWithProvider(context.Background(), t.TempDir(), reader, &MockDatabaseManager{}, provider, nil, nil))
+	})
+	assert.Nil(t, jsonStdout)
+	tables, err := parseTargetTables([]byte(stdout))
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	assert.Equal(t, "users", tables[0].Name)
+}
+
+func TestRenderSchemaResultVersionHeader(t *testing.T) {
+	defer resetCommand()
+	resetCommand()
+
+	result := &providers.SchemaResult{
+		RawSQL: "CREATE TABLE users (id integer);\n",
+		Schema: providers.Schema{
+			Tables:        []providers.Table{{Name: "users", Columns: []providers.Column{{Name: "id", DataType: "integer"}}}},
+			ServerVersion: 160004,
+		},
+	}
+
+	tests := []struct {
+		format providers.SchemaFormat
+		prefix string
+	}{
+		{providers.FormatSQL, "-- PostgreSQL version: 16.4\n\nCREATE TABLE users"},
+		{providers.FormatTypeScript, "// PostgreSQL version: 16.4\n\n"},
+		{providers.FormatPrisma, "// PostgreSQL version: 16.4\n\n"},
+		{providers.FormatSQLAlchemy, "# PostgreSQL version: 16.4\n\n"},
+		{providers.FormatAtlas, "# PostgreSQL version: 16.4\n\n"},
+		{providers.FormatMarkdown, "<!-- PostgreSQL version: 16.4 -->\n\n"},
+		{providers.FormatJSON, "{"},
+		{providers.FormatInfo, "\n=== DATABASE SCHEMA ===\nPostgreSQL version: 16.4\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			output, err := renderSchemaResult(providers.ExtractParams{Format: tt.format}, result)
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(output, tt.prefix), output)
+			assert.Equal(t, 1, strings.Count(output, "PostgreSQL version")+strings.Count(output, "server_version"), output)
+		})
+	}
+
+	unknown := &providers.SchemaResult{RawSQL: "CREATE TABLE users (id integer);\n"}
+	output, err := renderSchemaResult(providers.ExtractParams{Format: providers.FormatSQL}, unknown)
+	require.NoError(t, err)
+	assert.Equal(t, unknown.RawSQL, output)
+}
+
+func TestDumpCatalogFlag(t *testing.T) {
+	defer resetCommand()
+	resetCommand()
+
+	params, err := extractParams()
+	require.NoError(t, err)
+	assert.Nil(t, params.CatalogDump)
+
+	dumpCatalog = true
+	params, err = extractParams()
+	require.NoError(t, err)
+	assert.Equal(t, os.Stderr, params.CatalogDump)
+	assert.False(t, usesSchemaCache(), "cached output would skip the extraction")
+}
+
+func TestCheckProviderOptions(t *testing.T) {
+	defer resetCommand()
+
+	pgDump := &MockSchemaProvider{ProviderName: "pg_dump"}
+	tests := []struct {
+		name    string
+		set     func()
+		wantErr string
+	}{
+		{"defaults", func() {}, ""},
+		{"fk_style", func() { fkStyle = string(providers.ForeignKeyAlter) }, "--fk-style cannot be used with the pg_dump provider"},
+		{"column_order", func() { columnOrder = string(providers.ColumnOrderLogical) }, "--column-order cannot be used"},
+		{"order_by", func() {
+			tableOrder = string(providers.TableOrderDependency)
+			fkStyle = string(providers.ForeignKeyAlter)
+		}, "--fk-style, --order-by cannot be used"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetCommand()
+			tt.set()
+			err := checkProviderOptions(pgDump)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, exitUsage, exitCode(err))
+			assert.Contains(t, err.Error(), tt.wantErr)
+			assert.NoError(t, checkProviderOptions(&MockSchemaProvider{}), "the native provider applies every flag")
+		})
+	}
+}
+
+func TestDialectFlags(t *testing.T) {
+	defer resetCommand()
+
+	tests := []struct {
+		name    string
+		set     func()
+		wantErr string
+	}{
+		{"unknown_dialect", func() { extractMode = true; sqlDialect = "oracle" }, "unsupported dialect: oracle"},
+		{"info_output", func() { sqlDialect = providers.DialectMySQL }, "--dialect only applies to sql output"},
+		{"with_split_output", func() { extractMode = true; sqlDialect = providers.DialectMySQL; splitOutput = "schema" }, "cannot be combined with --split-output"},
+		{"with_idempotent", func() { extractMode = true; sqlDialect = providers.DialectMySQL; idempotent = true }, "cannot be combined with"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetCommand()
+			tt.set()
+			err := executeMig2Schema(context.Background(), []string{"migrations"}, providers.NewDefaultRegistry())
+			require.Error(t, err)
+			assert.Equal(t, exitUsage, exitCode(err))
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	resetCommand()
+	sqlDialect = providers.DialectMySQL
+	params := providers.ExtractParams{Format: providers.FormatSQL}
+	output, err := renderSchemaResult(params, &providers.SchemaResult{Schema: providers.Schema{
+		Tables: []providers.Table{{Name: "users", Columns: []providers.Column{{Name: "id", DataType: "integer", IsSerial: true, IsPrimaryKey: true}}}},
+	}, RawSQL: "create table users (id serial not null);\n"})
+	require.NoError(t, err)
+	assert.Contains(t, output, "`id` int not null auto_increment")
+
+	_, err = renderSchemaResult(params, &providers.SchemaResult{RawSQL: "CREATE TABLE users (id integer);\n"})
+	require.Error(t, err)
+	assert.Equal(t, exitUsage, exitCode(err))
+}
+
+func TestContainerWaitFlag(t *testing.T) {
+	defer resetCommand()
+
+	tests := []struct {
+		value    string
+		expected wait.Strategy
+		wantErr  bool
+	}{
+		{value: "", expected: nil},
+		{value: "port", expected: wait.ForListeningPort("5432/tcp").WithStartupTimeout(defaultStartupTimeout)},
+		{value: "log=acme postgres ready", expected: wait.ForLog("acme postgres ready").WithStartupTimeout(defaultStartupTimeout)},
+		{value: "log=", wantErr: true},
+		{value: "ready", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			resetCommand()
+			containerWait = tt.value
+			options, err := databaseOptions()
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Equal(t, exitUsage, exitCode(err))
+				assert.Contains(t, err.Error(), "expected port or log=<message>")
+				return
+			}
+			require.NoError(t, err)
+
+			manager := NewPostgreSQLManager("postgres:16-alpine", options...).(*PostgreSQLManager)
+			assert.Equal(t, tt.expected, manager.waitStrategy)
+		})
+	}
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSchemaMarkdown(t *testing.T) {
+	defaultOf := func(value string) sql.NullString {
+		return sql.NullString{String: value, Valid: true}
+	}
+
+	tables := []providers.Table{
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true, IsSerial: true, DefaultValue: defaultOf("nextval('users_id_seq'::regclass)")},
+				{Name: "email", DataType: "character varying", CharacterLength: sql.NullInt64{Int64: 100, Valid: true}},
+				{Name: "flags", DataType: "text", IsNullable: true, DefaultValue: defaultOf("'a|b'::text")},
+			},
+			Indexes: []providers.Index{{Name: "users_email_key", Columns: []string{"email"}, IsUnique: true}},
+		},
+		{
+			Name: "posts",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "uuid", IsPrimaryKey: true},
+				{Name: "user_id", DataType: "integer"},
+				{Name: "tags", DataType: "jsonb", IsNullable: true},
+			},
+			Indexes: []providers.Index{{Name: "idx_posts_tags", Columns: []string{"tags"}, Method: "gin"}},
+			ForeignKeys: []providers.ForeignKey{
+				{Name: "posts_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+			},
+		},
+		{Name: "active_users", Columns: []providers.Column{{Name: "id", DataType: "integer"}}, IsView: true},
+	}
+
+	expected := "# Data Dictionary\n" +
+		"\n" +
+		"- [users](#table-users)\n" +
+		"- [posts](#table-posts)\n" +
+		"\n" +
+		"## Table: users\n" +
+		"\n" +
+		"| Column | Type | Nullable | Default | PK | References | Comment |\n" +
+		"| --- | --- | --- | --- | --- | --- | --- |\n" +
+		"| id | `serial` | no |  | yes |  |  |\n" +
+		"| email | `varchar(100)` | no |  |  |  |  |\n" +
+		"| flags | `text` | yes | `'a\\|b'::text` |  |  |  |\n" +
+		"\n" +
+		"**Indexes**\n" +
+		"\n" +
+		"- `users_email_key` on (email), unique\n" +
+		"\n" +
+		"## Table: posts\n" +
+		"\n" +
+		"| Column | Type | Nullable | Default | PK | References | Comment |\n" +
+		"| --- | --- | --- | --- | --- | --- | --- |\n" +
+		"| id | `uuid` | no |  | yes |  |  |\n" +
+		"| user_id | `integer` | no |  |  | [users](#table-users).id |  |\n" +
+		"| tags | `jsonb` | yes |  |  |  |  |\n" +
+		"\n" +
+		"**Indexes**\n" +
+		"\n" +
+		"- `idx_posts_tags` on (tags), using gin\n"
+
+	assert.Equal(t, expected, providers.FormatSchemaMarkdown(tables))
+}
+
+func TestFormatSchemaMarkdownPartitions(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name:              "events",
+			Columns:           []providers.Column{{Name: "created_at", DataType: "date"}},
+			PartitionStrategy: "range",
+			PartitionKey:      "created_at",
+		},
+		{
+			Name:           "events_2024",
+			Columns:        []providers.Column{{Name: "created_at", DataType: "date"}},
+			PartitionOf:    "events",
+			PartitionBound: "FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')",
+		},
+	}
+
+	output := providers.FormatSchemaMarkdown(tables)
+	assert.Contains(t, output, "## Table: events\n\nPartitioned by range (created_at).\n\n| Column |")
+	assert.Contains(t, output, "- [events_2024](#table-events_2024)\n")
+	assert.Contains(t, output, "## Table: events_2024\n\nPartition of [events](#table-events) FOR VALUES FROM ('2024-01-01') TO ('2025-01-01').\n")
+}
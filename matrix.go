@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alc6/mig2schema/providers"
+)
+
+// imageResult is the outcome of extracting the schema with one image
+type imageResult struct {
+	Image  string
+	Output string
+	Err    error
+}
+
+// runImageMatrix extracts the schema once per image and writes a summary to
+// w: whether each image succeeded and whether its schema matches the first
+// successful image, followed by the lines that differ. It reports whether
+// every image succeeded. The cache is not used, so every image really runs
+// the migrations.
+func runImageMatrix(ctx context.Context, migrationDir string, migrationReader MigrationReader, provider providers.SchemaProvider,
+	images []string, newManager func(image string) DatabaseManager, w io.Writer) (bool, error) {
+	for _, image := range images {
+		if err := validateImageReference(image); err != nil {
+			return false, err
+		}
+	}
+
+	results := make([]imageResult, len(images))
+	for i, image := range images {
+		output, err := buildSchemaOutput(ctx, migrationDir, migrationReader, newManager(image), provider, nil, nil)
+		results[i] = imageResult{Image: image, Output: output, Err: err}
+	}
+
+	writeImageMatrix(w, results)
+
+	for _, result := range results {
+		if result.Err != nil {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// writeImageMatrix writes the per-image summary table and the schema
+// differences against the reference image
+func writeImageMatrix(w io.Writer, results []imageResult) {
+	reference := -1
+	for i, result := range results {
+		if result.Err == nil {
+			reference = i
+			break
+		}
+	}
+
+	fmt.Fprintln(w, "=== POSTGRESQL IMAGE MATRIX ===")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "IMAGE\tRESULT\tSCHEMA")
+	for i, result := range results {
+		switch {
+		case result.Err != nil:
+			fmt.Fprintf(tw, "%s\tfailed\t%v\n", result.Image, result.Err)
+		case i == reference:
+			fmt.Fprintf(tw, "%s\tok\treference\n", result.Image)
+		case result.Output == results[reference].Output:
+			fmt.Fprintf(tw, "%s\tok\tsame as %s\n", result.Image, results[reference].Image)
+		default:
+			fmt.Fprintf(tw, "%s\tok\tdiffers from %s\n", result.Image, results[reference].Image)
+		}
+	}
+	tw.Flush()
+
+	for i, result := range results {
+		if result.Err != nil || i == reference || result.Output == results[reference].Output {
+			continue
+		}
+		fmt.Fprintf(w, "\n--- %s\n+++ %s\n", results[reference].Image, result.Image)
+		removed, added := diffLines(results[reference].Output, result.Output)
+		for _, line := range removed {
+			fmt.Fprintf(w, "-%s\n", line)
+		}
+		for _, line := range added {
+			fmt.Fprintf(w, "+%s\n", line)
+		}
+	}
+}
+
+// diffLines returns the lines of a missing from b and the lines of b missing
+// from a, each in their original order. Repeated lines are matched by count.
+func diffLines(a, b string) (removed, added []string) {
+	count := make(map[string]int)
+	for _, line := range strings.Split(b, "\n") {
+		count[line]++
+	}
+	for _, line := range strings.Split(a, "\n") {
+		if count[line] > 0 {
+			count[line]--
+			continue
+		}
+		removed = append(removed, line)
+	}
+
+	count = make(map[string]int)
+	for _, line := range strings.Split(a, "\n") {
+		count[line]++
+	}
+	for _, line := range strings.Split(b, "\n") {
+		if count[line] > 0 {
+			count[line]--
+			continue
+		}
+		added = append(added, line)
+	}
+	return removed, added
+}
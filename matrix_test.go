@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunImageMatrix(t *testing.T) {
+	defer resetCommand()
+	extractMode = true
+	tempDir := t.TempDir()
+
+	reader := &MockMigrationReader{
+		DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+			return []Migration{{Name: "001_test", UpSQL: []byte("create table users (id integer);")}}, nil
+		},
+	}
+	schemas := map[string]string{
+		"postgres:16-alpine": "create table users (\n    id integer\n);\n",
+		"postgres:15-alpine": "create table users (\n    id integer\n);\n",
+		"postgres:13-alpine": "create table users (\n    id bigint\n);\n",
+	}
+	provider := &MockSchemaProvider{
+		ExtractSchemaFunc: func(ctx context.Context, params providers.ExtractParams) (*providers.SchemaResult, error) {
+			return &providers.SchemaResult{RawSQL: schemas[params.ConnectionString], Format: params.Format}, nil
+		},
+	}
+	newManager := func(image string) DatabaseManager {
+		manager := &MockDatabaseManager{
+			GetConnectionStringFunc: func() string { return image },
+		}
+		if image == "postgres:12-alpine" {
+			manager.SetupFunc = func(ctx context.Context) error { return errors.New("container exited") }
+		}
+		return manager
+	}
+
+	t.Run("reports_differences", func(t *testing.T) {
+		var out bytes.Buffer
+		images := []string{"postgres:16-alpine", "postgres:15-alpine", "postgres:13-alpine"}
+		passed, err := runImageMatrix(context.Background(), tempDir, reader, provider, images, newManager, &out)
+		require.NoError(t, err)
+		assert.True(t, passed)
+
+		output := out.String()
+		assert.Regexp(t, `postgres:16-alpine\s+ok\s+reference`, output)
+		assert.Regexp(t, `postgres:15-alpine\s+ok\s+same as postgres:16-alpine`, output)
+		assert.Regexp(t, `postgres:13-alpine\s+ok\s+differs from postgres:16-alpine`, output)
+		assert.Contains(t, output, "--- postgres:16-alpine\n+++ postgres:13-alpine\n-    id integer\n+    id bigint\n")
+	})
+
+	t.Run("reports_failures", func(t *testing.T) {
+		var out bytes.Buffer
+		images := []string{"postgres:12-alpine", "postgres:16-alpine"}
+		passed, err := runImageMatrix(context.Background(), tempDir, reader, provider, images, newManager, &out)
+		require.NoError(t, err)
+		assert.False(t, passed)
+		assert.Regexp(t, `postgres:12-alpine\s+failed\s+failed to setup database: container exited`, out.String())
+		assert.Regexp(t, `postgres:16-alpine\s+ok\s+reference`, out.String())
+	})
+
+	t.Run("rejects_invalid_image", func(t *testing.T) {
+		_, err := runImageMatrix(context.Background(), tempDir, reader, provider, []string{"postgres:16-alpine", "Postgres 13"}, newManager, &bytes.Buffer{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid postgresql image reference")
+	})
+}
+
+func TestDiffLines(t *testing.T) {
+	removed, added := diffLines("a\nb\nc\nb", "a\nc\nd\nb")
+	assert.Equal(t, []string{"b"}, removed)
+	assert.Equal(t, []string{"d"}, added)
+}
@@ -3,17 +3,103 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/alc6/mig2schema/providers"
 )
 
-// StartMCPServer starts the MCP server for schema extraction
-func StartMCPServer() error {
+// Values of --mcp-transport
+const (
+	mcpTransportStdio = "stdio"
+	mcpTransportSSE   = "sse"
+	mcpTransportHTTP  = "http"
+)
+
+// defaultMCPAddr is the address the network transports listen on by default
+const defaultMCPAddr = "localhost:8080"
+
+// mcpShutdownTimeout bounds how long in-flight requests may take to finish
+// once a network transport is asked to stop
+const mcpShutdownTimeout = 10 * time.Second
+
+// StartMCPServer starts the MCP server for schema extraction on the given
+// transport: stdio, or sse and http (streamable HTTP) listening on addr. The
+// network transports run until interrupted. Schemas are extracted with the
+// providers of registry.
+func StartMCPServer(registry *providers.ProviderRegistry, transport, addr string) error {
+	s := newMCPServer(registry)
+	if addr == "" {
+		addr = defaultMCPAddr
+	}
+
+	switch transport {
+	case "", mcpTransportStdio:
+		slog.Info("starting mig2schema mcp server", "transport", transport)
+		return server.ServeStdio(s)
+	case mcpTransportSSE:
+		sse := server.NewSSEServer(s)
+		slog.Info("starting mig2schema mcp server", "transport", transport,
+			"sse_endpoint", "http://"+addr+"/sse", "message_endpoint", "http://"+addr+"/message")
+		return serveMCPUntilInterrupted(addr, sse.Start, sse.Shutdown)
+	case mcpTransportHTTP:
+		streamable := server.NewStreamableHTTPServer(s)
+		slog.Info("starting mig2schema mcp server", "transport", transport, "endpoint", "http://"+addr+"/mcp")
+		return serveMCPUntilInterrupted(addr, streamable.Start, streamable.Shutdown)
+	default:
+		return fmt.Errorf("unsupported mcp transport: %s (expected stdio, sse or http)", transport)
+	}
+}
+
+// serveMCPUntilInterrupted runs a network transport until it fails or the
+// process receives SIGINT or SIGTERM
+func serveMCPUntilInterrupted(addr string, start func(addr string) error, shutdown func(ctx context.Context) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return serveMCP(ctx, addr, start, shutdown)
+}
+
+// serveMCP runs a network transport until it fails or ctx is done, then
+// shuts it down gracefully
+func serveMCP(ctx context.Context, addr string, start func(addr string) error, shutdown func(ctx context.Context) error) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- start(addr)
+	}()
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("mcp server stopped: %w", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("stopping mig2schema mcp server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), mcpShutdownTimeout)
+	defer cancel()
+	if err := shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to stop mcp server: %w", err)
+	}
+	if err := <-errs; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("mcp server stopped: %w", err)
+	}
+	return nil
+}
+
+// newMCPServer returns the MCP server with every tool and resource
+// registered. The handlers do not depend on the transport.
+func newMCPServer(registry *providers.ProviderRegistry) *server.MCPServer {
 	s := server.NewMCPServer(
 		"mig2schema",
 		"1.0.0",
@@ -21,22 +107,33 @@ func StartMCPServer() error {
 	)
 
 	extractSchemaTool := mcp.NewTool("extract_schema",
-		mcp.WithDescription("Extract database schema from PostgreSQL migration files using pg_dump"),
+		mcp.WithDescription("Extract database schema from PostgreSQL migration files with the native provider or pg_dump, "+
+			"as SQL DDL, a human-readable listing of tables, columns and indexes, or JSON"),
 		mcp.WithString("migration_directory",
 			mcp.Required(),
 			mcp.Description("Path to directory containing migration files"),
 		),
 		mcp.WithString("format",
-			mcp.Description("Output format: 'sql' for CREATE statements (default)"),
-			mcp.Enum("sql"),
+			mcp.Description("Output format: 'sql' for CREATE statements (default), 'info' for a listing of each table's columns, "+
+				"keys and indexes that is easier to reason about than DDL, or 'json' for the schema as JSON. pg_dump only supports 'sql'"),
+			mcp.Enum("sql", "info", "json"),
+			mcp.DefaultString("sql"),
+		),
+		mcp.WithString("provider",
+			mcp.Description("Schema extraction provider: 'native' (default) or 'pg_dump', which needs the PostgreSQL client tools"),
+			mcp.Enum("native", "pg_dump"),
+			mcp.DefaultString("native"),
 		),
 		mcp.WithString("postgres_image",
 			mcp.Description("PostgreSQL Docker image to use (default: postgres:16-alpine)"),
 		),
+		mcp.WithString("target_version",
+			mcp.Description("Run migrations up to and including this version or migration name, to inspect an earlier schema (default: all migrations)"),
+		),
 	)
 
 	s.AddTool(extractSchemaTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return handleExtractSchema(ctx, request)
+		return handleExtractSchema(ctx, request, registry)
 	})
 
 	validateMigrationsTool := mcp.NewTool("validate_migrations",
@@ -48,29 +145,122 @@ func StartMCPServer() error {
 		mcp.WithString("postgres_image",
 			mcp.Description("PostgreSQL Docker image to use (default: postgres:16-alpine)"),
 		),
+		mcp.WithBoolean("strict_validate",
+			mcp.Description("Also check the SQL of each up file without executing it: "+syntaxCheckDescription()+" (default: false)"),
+		),
+		mcp.WithString("format",
+			mcp.Description("Output format: 'json' for a single document or 'jsonl' for one object per migration followed by a summary line (default: json)"),
+			mcp.Enum(validateFormatJSON, validateFormatJSONL),
+		),
+		mcp.WithString("sequence_scheme",
+			mcp.Description("Numbering scheme used to check migration versions for gaps, duplicates and ordering: "+
+				"'integer', 'timestamp' or 'auto' to detect it (default: auto)"),
+			mcp.Enum(sequenceAuto, sequenceInteger, sequenceTimestamp),
+		),
 	)
 
 	s.AddTool(validateMigrationsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return handleValidateMigrations(ctx, request)
 	})
 
-	slog.Info("starting mig2schema mcp server")
-	return server.ServeStdio(s)
+	generateMigrationTool := mcp.NewTool("generate_migration",
+		mcp.WithDescription("Generate the migration SQL that turns the schema produced by existing migration files into a target schema. "+
+			"Destructive statements are preceded by a '-- DESTRUCTIVE:' comment"),
+		mcp.WithString("migration_directory",
+			mcp.Required(),
+			mcp.Description("Path to directory containing the migration files describing the current schema"),
+		),
+		mcp.WithString("target_schema",
+			mcp.Required(),
+			mcp.Description("Desired schema, either as SQL DDL or as JSON: a list of tables shaped like "+
+				`{"name": "users", "columns": [{"name": "id", "type": "integer", "nullable": false, "primary_key": true}], `+
+				`"indexes": [{"name": "idx", "columns": ["id"], "unique": false}], `+
+				`"foreign_keys": [{"name": "fk", "columns": ["a_id"], "referenced_table": "a", "referenced_columns": ["id"]}]}`),
+		),
+		mcp.WithString("postgres_image",
+			mcp.Description("PostgreSQL Docker image to use (default: postgres:16-alpine)"),
+		),
+	)
+
+	s.AddTool(generateMigrationTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handleGenerateMigration(ctx, request)
+	})
+
+	describeTableTool := mcp.NewTool("describe_table",
+		mcp.WithDescription("Describe a single table of the schema produced by migration files: columns, indexes, "+
+			"unique constraints, foreign keys and triggers as JSON. Smaller than extract_schema for large schemas"),
+		mcp.WithString("migration_directory",
+			mcp.Required(),
+			mcp.Description("Path to directory containing migration files"),
+		),
+		mcp.WithString("table_name",
+			mcp.Required(),
+			mcp.Description("Name of the table to describe"),
+		),
+		mcp.WithString("postgres_image",
+			mcp.Description("PostgreSQL Docker image to use (default: postgres:16-alpine)"),
+		),
+	)
+
+	s.AddTool(describeTableTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handleDescribeTable(ctx, request)
+	})
+
+	listTablesTool := mcp.NewTool("list_tables",
+		mcp.WithDescription("List the tables of the schema produced by migration files with their column and index "+
+			"counts and whether they have a primary key. A cheap overview before using describe_table"),
+		mcp.WithString("migration_directory",
+			mcp.Required(),
+			mcp.Description("Path to directory containing migration files"),
+		),
+		mcp.WithString("schema",
+			mcp.Description("Database schema to list (default: public)"),
+		),
+		mcp.WithBoolean("include_views",
+			mcp.Description("Also list views (default: false)"),
+		),
+		mcp.WithString("postgres_image",
+			mcp.Description("PostgreSQL Docker image to use (default: postgres:16-alpine)"),
+		),
+	)
+
+	s.AddTool(listTablesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handleListTables(ctx, request)
+	})
+
+	schemaResource := mcp.NewResourceTemplate("schema://{+migration_directory}",
+		"Database schema",
+		mcp.WithTemplateDescription("Schema extracted from the migration files in a directory using the native provider. "+
+			"The path may be URL-encoded; append ?format=info for the human-readable format instead of SQL."),
+		mcp.WithTemplateMIMEType("text/plain"),
+	)
+
+	cache := newSchemaResourceCache(func(ctx context.Context, migrationDir, format string) (string, error) {
+		return extractSchemaCore(ctx, registry, migrationDir, format, "native", "postgres:16-alpine", "")
+	})
+	s.AddResourceTemplate(schemaResource, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return handleReadSchemaResource(ctx, request, cache)
+	})
+
+	return s
 }
 
 // handleExtractSchema processes the extract_schema tool request
-func handleExtractSchema(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func handleExtractSchema(ctx context.Context, request mcp.CallToolRequest, registry *providers.ProviderRegistry) (*mcp.CallToolResult, error) {
 	migrationDir, err := request.RequireString("migration_directory")
 	if err != nil {
 		return mcp.NewToolResultError("migration_directory parameter is required"), nil
 	}
 
 	format := request.GetString("format", "sql")
+	providerName := request.GetString("provider", "native")
 	pgImage := request.GetString("postgres_image", "postgres:16-alpine")
-	// Always use pg_dump provider in MCP mode
-	providerName := "pg_dump"
+	targetVersion := request.GetString("target_version", "")
+	if err := checkExtractOptions(providerName, format); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
-	output, err := extractSchemaCore(ctx, migrationDir, format, providerName, pgImage)
+	output, err := extractSchemaCore(ctx, registry, migrationDir, format, providerName, pgImage, targetVersion)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -78,13 +268,30 @@ func handleExtractSchema(ctx context.Context, request mcp.CallToolRequest) (*mcp
 	return mcp.NewToolResultText(fmt.Sprintf("schema extracted successfully:\n\n%s", output)), nil
 }
 
-// extractSchemaCore contains the core logic for schema extraction, separated for testing
-func extractSchemaCore(ctx context.Context, migrationDir, format, providerName, pgImage string) (string, error) {
-	// Initialize provider registry
-	registry := providers.NewProviderRegistry()
-	registry.Register(providers.NewNativeProvider())
-	registry.Register(providers.NewPgDumpProvider())
+// checkExtractOptions validates the provider and format requested from the
+// extract_schema tool
+func checkExtractOptions(providerName, format string) error {
+	switch format {
+	case "sql", "info", "json":
+	default:
+		return fmt.Errorf("unsupported format '%s': expected 'sql', 'info' or 'json'", format)
+	}
+	switch providerName {
+	case "native":
+	case "pg_dump":
+		if format != "sql" {
+			return fmt.Errorf("provider 'pg_dump' only supports format 'sql'; use provider 'native' for '%s'", format)
+		}
+	default:
+		return fmt.Errorf("unknown provider '%s': expected 'native' or 'pg_dump'", providerName)
+	}
+	return nil
+}
 
+// extractSchemaCore contains the core logic for schema extraction, separated
+// for testing. The provider is looked up in registry. A non-empty
+// targetVersion stops after that migration.
+func extractSchemaCore(ctx context.Context, registry *providers.ProviderRegistry, migrationDir, format, providerName, pgImage, targetVersion string) (string, error) {
 	provider, exists := registry.Get(providerName)
 	if !exists {
 		return "", fmt.Errorf("unknown provider: %s", providerName)
@@ -97,11 +304,11 @@ func extractSchemaCore(ctx context.Context, migrationDir, format, providerName,
 	migrationReader := NewFileMigrationReader()
 	dbManager := NewPostgreSQLManager(pgImage)
 	
-	return extractSchemaCoreWithProvider(ctx, migrationDir, format, migrationReader, dbManager, provider)
+	return extractSchemaCoreWithProvider(ctx, migrationDir, format, targetVersion, migrationReader, dbManager, provider)
 }
 
 // extractSchemaCoreWithProvider is the provider-based extraction function
-func extractSchemaCoreWithProvider(ctx context.Context, migrationDir, format string, 
+func extractSchemaCoreWithProvider(ctx context.Context, migrationDir, format, targetVersion string,
 	migrationReader MigrationReader, dbManager DatabaseManager, provider providers.SchemaProvider) (string, error) {
 	if _, err := os.Stat(migrationDir); os.IsNotExist(err) {
 		return "", fmt.Errorf("migration directory does not exist: %s", migrationDir)
@@ -116,6 +323,12 @@ func extractSchemaCoreWithProvider(ctx context.Context, migrationDir, format str
 		return "", fmt.Errorf("no migration files found in directory")
 	}
 
+	if targetVersion != "" {
+		if migrations, err = targetMigrations(migrations, targetVersion); err != nil {
+			return "", err
+		}
+	}
+
 	if err := dbManager.Setup(ctx); err != nil {
 		return "", fmt.Errorf("failed to setup postgresql: %v", err)
 	}
@@ -125,7 +338,7 @@ func extractSchemaCoreWithProvider(ctx context.Context, migrationDir, format str
 		}
 	}()
 
-	if err := dbManager.RunMigrations(migrations); err != nil {
+	if err := dbManager.RunMigrations(ctx, migrations); err != nil {
 		return "", fmt.Errorf("failed to run migrations: %v", err)
 	}
 
@@ -134,6 +347,8 @@ func extractSchemaCoreWithProvider(ctx context.Context, migrationDir, format str
 	switch format {
 	case "sql":
 		schemaFormat = providers.FormatSQL
+	case "json":
+		schemaFormat = providers.FormatJSON
 	default:
 		schemaFormat = providers.FormatInfo
 	}
@@ -151,14 +366,14 @@ func extractSchemaCoreWithProvider(ctx context.Context, migrationDir, format str
 	}
 
 	// Format output based on result
-	var output string
-	if schemaFormat == providers.FormatSQL {
-		output = result.RawSQL
-	} else {
-		output = providers.FormatSchemaInfo(result.Tables)
+	switch schemaFormat {
+	case providers.FormatSQL:
+		return versionHeader(schemaFormat, result.ServerVersion) + result.RawSQL, nil
+	case providers.FormatJSON:
+		return providers.FormatSchemaJSON(result.Schema)
+	default:
+		return providers.FormatFullSchemaInfo(result.Schema), nil
 	}
-
-	return output, nil
 }
 
 // extractSchemaCoreWithDeps is the testable version with dependency injection
@@ -186,7 +401,7 @@ func extractSchemaCoreWithDeps(ctx context.Context, migrationDir, format string,
 		}
 	}()
 
-	if err := dbManager.RunMigrations(migrations); err != nil {
+	if err := dbManager.RunMigrations(ctx, migrations); err != nil {
 		return "", fmt.Errorf("failed to run migrations: %v", err)
 	}
 
@@ -205,6 +420,69 @@ func extractSchemaCoreWithDeps(ctx context.Context, migrationDir, format string,
 	return output, nil
 }
 
+// handleGenerateMigration processes the generate_migration tool request
+func handleGenerateMigration(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	migrationDir, err := request.RequireString("migration_directory")
+	if err != nil {
+		return mcp.NewToolResultError("migration_directory parameter is required"), nil
+	}
+
+	targetSchema, err := request.RequireString("target_schema")
+	if err != nil {
+		return mcp.NewToolResultError("target_schema parameter is required"), nil
+	}
+
+	pgImage := request.GetString("postgres_image", "postgres:16-alpine")
+
+	output, err := generateMigrationCore(ctx, migrationDir, targetSchema, pgImage)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("migration generated successfully:\n\n%s", output)), nil
+}
+
+// handleDescribeTable processes the describe_table tool request
+func handleDescribeTable(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	migrationDir, err := request.RequireString("migration_directory")
+	if err != nil {
+		return mcp.NewToolResultError("migration_directory parameter is required"), nil
+	}
+
+	tableName, err := request.RequireString("table_name")
+	if err != nil {
+		return mcp.NewToolResultError("table_name parameter is required"), nil
+	}
+
+	pgImage := request.GetString("postgres_image", "postgres:16-alpine")
+
+	output, err := describeTableCore(ctx, migrationDir, tableName, pgImage)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(output), nil
+}
+
+// handleListTables processes the list_tables tool request
+func handleListTables(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	migrationDir, err := request.RequireString("migration_directory")
+	if err != nil {
+		return mcp.NewToolResultError("migration_directory parameter is required"), nil
+	}
+
+	schemaName := request.GetString("schema", "public")
+	includeViews := request.GetBool("include_views", false)
+	pgImage := request.GetString("postgres_image", "postgres:16-alpine")
+
+	output, err := listTablesCore(ctx, migrationDir, schemaName, includeViews, pgImage)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(output), nil
+}
+
 // handleValidateMigrations processes the validate_migrations tool request
 func handleValidateMigrations(_ context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	migrationDir, err := request.RequireString("migration_directory")
@@ -212,7 +490,13 @@ func handleValidateMigrations(_ context.Context, request mcp.CallToolRequest) (*
 		return mcp.NewToolResultError("migration_directory parameter is required"), nil
 	}
 
-	output, err := validateMigrationsCore(migrationDir)
+	opts := validateOptions{
+		StrictValidate: request.GetBool("strict_validate", false),
+		Format:         request.GetString("format", validateFormatJSON),
+		SequenceScheme: request.GetString("sequence_scheme", sequenceAuto),
+	}
+
+	output, err := validateMigrationsCore(migrationDir, opts)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -220,8 +504,25 @@ func handleValidateMigrations(_ context.Context, request mcp.CallToolRequest) (*
 	return mcp.NewToolResultText(fmt.Sprintf("migration validation completed:\n\n%s", output)), nil
 }
 
+// Output formats of validateMigrationsCore
+const (
+	validateFormatJSON  = "json"
+	validateFormatJSONL = "jsonl"
+)
+
+// validateOptions controls the optional checks performed by validateMigrationsCore
+type validateOptions struct {
+	// StrictValidate runs the SQL check of --dry-run on every up file
+	StrictValidate bool
+	// Format selects the output format, json when empty
+	Format string
+	// SequenceScheme is the numbering scheme for the sequence check, auto
+	// when empty
+	SequenceScheme string
+}
+
 // validateMigrationsCore contains the core logic for migration validation, separated for testing
-func validateMigrationsCore(migrationDir string) (string, error) {
+func validateMigrationsCore(migrationDir string, opts validateOptions) (string, error) {
 	if _, err := os.Stat(migrationDir); os.IsNotExist(err) {
 		return "", fmt.Errorf("migration directory does not exist: %s", migrationDir)
 	}
@@ -231,22 +532,82 @@ func validateMigrationsCore(migrationDir string) (string, error) {
 		return "", fmt.Errorf("failed to parse migrations: %v", err)
 	}
 
-	result := map[string]interface{}{
-		"valid":           true,
-		"migration_count": len(migrations),
-		"migrations":      make([]map[string]interface{}, len(migrations)),
+	format := opts.Format
+	if format == "" {
+		format = validateFormatJSON
 	}
+	if format != validateFormatJSON && format != validateFormatJSONL {
+		return "", fmt.Errorf("unsupported validation format: %s (expected %s or %s)", format, validateFormatJSON, validateFormatJSONL)
+	}
+
+	sequenceIssues, err := checkMigrationSequence(migrations, opts.SequenceScheme)
+	if err != nil {
+		return "", err
+	}
+
+	valid := true
+	migrationInfos := make([]map[string]interface{}, len(migrations))
 
 	for i, migration := range migrations {
+		content, err := migration.readUpSQL()
+		if err != nil {
+			return "", err
+		}
+
+		statements := providers.SplitStatements(string(content))
+		breakdown := map[string]int{
+			statementCreateTable: 0,
+			statementAlterTable:  0,
+			statementCreateIndex: 0,
+			statementDrop:        0,
+			statementOther:       0,
+		}
+		destructive := false
+		for _, stmt := range statements {
+			breakdown[classifyStatement(stmt)]++
+			if statementIsDestructive(stmt) {
+				destructive = true
+			}
+		}
+
 		migrationInfo := map[string]interface{}{
-			"name":          migration.Name,
-			"up_file":       migration.UpFile,
-			"has_down_file": migration.DownFile != "",
+			"name":            migration.Name,
+			"up_file":         migration.UpFile,
+			"has_down_file":   migration.DownFile != "",
+			"size_bytes":      len(content),
+			"statement_count": len(statements),
+			"statements":      breakdown,
+			"destructive":     destructive,
 		}
 		if migration.DownFile != "" {
 			migrationInfo["down_file"] = migration.DownFile
 		}
-		result["migrations"].([]map[string]interface{})[i] = migrationInfo
+		if opts.StrictValidate {
+			syntaxErrors := providers.CheckSyntax(string(content))
+			errorList := make([]map[string]interface{}, len(syntaxErrors))
+			for j, syntaxErr := range syntaxErrors {
+				errorList[j] = map[string]interface{}{
+					"line":    syntaxErr.Line,
+					"message": syntaxErr.Message,
+				}
+			}
+			migrationInfo["syntax_errors"] = errorList
+			if len(syntaxErrors) > 0 {
+				valid = false
+			}
+		}
+		migrationInfos[i] = migrationInfo
+	}
+
+	if format == validateFormatJSONL {
+		return formatValidationJSONL(migrationInfos, sequenceIssues, valid)
+	}
+
+	result := map[string]interface{}{
+		"valid":           valid,
+		"migration_count": len(migrations),
+		"migrations":      migrationInfos,
+		"sequence_issues": sequenceIssues,
 	}
 
 	jsonOutput, err := json.MarshalIndent(result, "", "  ")
@@ -256,3 +617,191 @@ func validateMigrationsCore(migrationDir string) (string, error) {
 
 	return string(jsonOutput), nil
 }
+
+// formatValidationJSONL writes one compact JSON object per migration followed
+// by a summary object holding the aggregate results. Each line carries a
+// "type" of either "migration" or "summary" so consumers can tell them apart.
+func formatValidationJSONL(migrationInfos []map[string]interface{}, sequenceIssues []sequenceIssue, valid bool) (string, error) {
+	var sb strings.Builder
+
+	for _, migrationInfo := range migrationInfos {
+		migrationInfo["type"] = "migration"
+		line, err := json.Marshal(migrationInfo)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal result to JSON: %w", err)
+		}
+		sb.Write(line)
+		sb.WriteString("\n")
+	}
+
+	summary, err := json.Marshal(map[string]interface{}{
+		"type":            "summary",
+		"valid":           valid,
+		"migration_count": len(migrationInfos),
+		"sequence_issues": sequenceIssues,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result to JSON: %w", err)
+	}
+	sb.Write(summary)
+	sb.WriteString("\n")
+
+	return sb.String(), nil
+}
+
+// handleReadSchemaResource serves schema:// resources, extracting the schema
+// of the referenced migration directory
+func handleReadSchemaResource(ctx context.Context, request mcp.ReadResourceRequest, cache *schemaResourceCache) ([]mcp.ResourceContents, error) {
+	migrationDir, format, err := parseSchemaURI(request.Params.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := cache.get(ctx, migrationDir, format)
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := "text/plain"
+	if format == "sql" {
+		mimeType = "application/sql"
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: mimeType,
+			Text:     output,
+		},
+	}, nil
+}
+
+// parseSchemaURI splits a schema://<migration_directory>[?format=sql|info]
+// URI into its URL-decoded directory and output format
+func parseSchemaURI(uri string) (string, string, error) {
+	rest, ok := strings.CutPrefix(uri, "schema://")
+	if !ok {
+		return "", "", fmt.Errorf("invalid schema resource uri: %s", uri)
+	}
+
+	rawPath, rawQuery, _ := strings.Cut(rest, "?")
+	migrationDir, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid migration directory in uri %s: %w", uri, err)
+	}
+	if migrationDir == "" {
+		return "", "", fmt.Errorf("schema resource uri must include a migration directory: %s", uri)
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid query in uri %s: %w", uri, err)
+	}
+
+	format := query.Get("format")
+	switch format {
+	case "":
+		format = "sql"
+	case "sql", "info":
+	default:
+		return "", "", fmt.Errorf("unsupported format: %s", format)
+	}
+
+	return migrationDir, format, nil
+}
+
+// maxSchemaResourceEntries bounds the schemas kept by schemaResourceCache.
+// Every edit of a migration makes a new key, so a long-lived server would
+// otherwise keep the schema of each state a directory went through.
+const maxSchemaResourceEntries = 32
+
+// schemaResourceCache memoizes extracted schemas so that repeated reads of an
+// unchanged migration directory do not start a new container. Concurrent
+// reads of the same key share one extraction; reads of other keys do not wait
+// for it. Once full, the oldest entry is evicted first.
+type schemaResourceCache struct {
+	mu       sync.Mutex
+	entries  map[string]string
+	order    []string
+	inFlight map[string]*schemaExtraction
+	extract  func(ctx context.Context, migrationDir, format string) (string, error)
+}
+
+// schemaExtraction is an extraction in progress, done is closed once output
+// and err are set
+type schemaExtraction struct {
+	done   chan struct{}
+	output string
+	err    error
+}
+
+func newSchemaResourceCache(extract func(ctx context.Context, migrationDir, format string) (string, error)) *schemaResourceCache {
+	return &schemaResourceCache{
+		entries:  make(map[string]string),
+		inFlight: make(map[string]*schemaExtraction),
+		extract:  extract,
+	}
+}
+
+// get returns the cached schema for the directory, extracting it when any
+// migration file was added, removed or modified since the last read
+func (c *schemaResourceCache) get(ctx context.Context, migrationDir, format string) (string, error) {
+	key, err := schemaCacheKey(migrationDir, format)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	if output, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		slog.Debug("serving cached schema", "directory", migrationDir, "format", format)
+		return output, nil
+	}
+	if call, ok := c.inFlight[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.output, call.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	call := &schemaExtraction{done: make(chan struct{})}
+	c.inFlight[key] = call
+	c.mu.Unlock()
+
+	call.output, call.err = c.extract(ctx, migrationDir, format)
+
+	c.mu.Lock()
+	delete(c.inFlight, key)
+	if call.err == nil {
+		c.entries[key] = call.output
+		c.order = append(c.order, key)
+		if len(c.order) > maxSchemaResourceEntries {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+	}
+	c.mu.Unlock()
+	close(call.done)
+	return call.output, call.err
+}
+
+// schemaCacheKey identifies the state of a migration directory by its path
+// and the names and content of its migrations, as the output cache does
+func schemaCacheKey(migrationDir, format string) (string, error) {
+	absDir, err := filepath.Abs(migrationDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve migration directory: %w", err)
+	}
+
+	migrations, err := NewFileMigrationReader().DiscoverMigrations(absDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to scan migration directory: %w", err)
+	}
+	key, err := migrationsCacheKey(migrations, absDir, format)
+	if err != nil {
+		return "", fmt.Errorf("failed to read migrations: %w", err)
+	}
+	return key, nil
+}
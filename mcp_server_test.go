@@ -1,14 +1,25 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/alc6/mig2schema/providers"
@@ -33,7 +44,7 @@ func TestValidateMigrationsCore(t *testing.T) {
 			require.NoError(t, err)
 		}
 		
-		result, err := validateMigrationsCore(tempDir)
+		result, err := validateMigrationsCore(tempDir, validateOptions{})
 		require.NoError(t, err)
 		assert.Contains(t, result, `"migration_count": 2`)
 	})
@@ -41,13 +52,13 @@ func TestValidateMigrationsCore(t *testing.T) {
 	t.Run("empty_directory", func(t *testing.T) {
 		tempDir := t.TempDir()
 		
-		result, err := validateMigrationsCore(tempDir)
+		result, err := validateMigrationsCore(tempDir, validateOptions{})
 		require.NoError(t, err)
 		assert.Contains(t, result, `"migration_count": 0`)
 	})
 
 	t.Run("nonexistent_directory", func(t *testing.T) {
-		_, err := validateMigrationsCore("/path/that/does/not/exist")
+		_, err := validateMigrationsCore("/path/that/does/not/exist", validateOptions{})
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "migration directory does not exist")
 	})
@@ -63,11 +74,125 @@ func TestValidateMigrationsCore(t *testing.T) {
 			require.NoError(t, err)
 		}
 		
-		result, err := validateMigrationsCore(tempDir)
+		result, err := validateMigrationsCore(tempDir, validateOptions{})
 		require.NoError(t, err)
 		assert.Contains(t, result, `"has_down_file": true`)
 	})
 
+	t.Run("strict_validate", func(t *testing.T) {
+		tempDir := t.TempDir()
+		files := map[string]string{
+			"001_ok.up.sql":     "create table ok (id int);",
+			"002_broken.up.sql": "create table broken (id int;",
+		}
+		for filename, content := range files {
+			err := os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644)
+			require.NoError(t, err)
+		}
+
+		result, err := validateMigrationsCore(tempDir, validateOptions{})
+		require.NoError(t, err)
+		assert.Contains(t, result, `"valid": true`)
+		assert.NotContains(t, result, "syntax_errors")
+
+		result, err = validateMigrationsCore(tempDir, validateOptions{StrictValidate: true})
+		require.NoError(t, err)
+		assert.Contains(t, result, `"valid": false`)
+		assert.Contains(t, result, `"message": "unclosed parenthesis"`)
+	})
+
+	t.Run("jsonl_format", func(t *testing.T) {
+		tempDir := t.TempDir()
+		files := map[string]string{
+			"001_users.up.sql":   "create table users (id int);\ncreate index idx_users_id on users (id);",
+			"001_users.down.sql": "drop table users;",
+			"002_posts.up.sql":   "create table posts (id int);",
+		}
+		for filename, content := range files {
+			err := os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644)
+			require.NoError(t, err)
+		}
+
+		result, err := validateMigrationsCore(tempDir, validateOptions{Format: validateFormatJSONL})
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(result), "\n")
+		require.Len(t, lines, 3)
+
+		var first map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+		assert.Equal(t, "migration", first["type"])
+		assert.Equal(t, "001_users", first["name"])
+		assert.Equal(t, true, first["has_down_file"])
+		assert.Equal(t, float64(len(files["001_users.up.sql"])), first["size_bytes"])
+		assert.Equal(t, float64(2), first["statement_count"])
+
+		var summary map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(lines[2]), &summary))
+		assert.Equal(t, "summary", summary["type"])
+		assert.Equal(t, true, summary["valid"])
+		assert.Equal(t, float64(2), summary["migration_count"])
+	})
+
+	t.Run("statement_breakdown", func(t *testing.T) {
+		tempDir := t.TempDir()
+		files := map[string]string{
+			"001_users.up.sql":   "create table users (id int, email text);\ncreate index idx_users_email on users (email);",
+			"002_cleanup.up.sql": "alter table users drop column email;\ndrop index idx_users_email;\ntruncate users;",
+		}
+		for filename, content := range files {
+			err := os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644)
+			require.NoError(t, err)
+		}
+
+		result, err := validateMigrationsCore(tempDir, validateOptions{})
+		require.NoError(t, err)
+
+		var parsed struct {
+			Migrations []struct {
+				Name           string         `json:"name"`
+				StatementCount int            `json:"statement_count"`
+				Statements     map[string]int `json:"statements"`
+				Destructive    bool           `json:"destructive"`
+			} `json:"migrations"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(result), &parsed))
+		require.Len(t, parsed.Migrations, 2)
+
+		users := parsed.Migrations[0]
+		assert.Equal(t, 2, users.StatementCount)
+		assert.Equal(t, map[string]int{"create_table": 1, "alter_table": 0, "create_index": 1, "drop": 0, "other": 0}, users.Statements)
+		assert.False(t, users.Destructive)
+
+		cleanup := parsed.Migrations[1]
+		assert.Equal(t, 3, cleanup.StatementCount)
+		assert.Equal(t, map[string]int{"create_table": 0, "alter_table": 1, "create_index": 0, "drop": 1, "other": 1}, cleanup.Statements)
+		assert.True(t, cleanup.Destructive)
+	})
+
+	t.Run("sequence_issues", func(t *testing.T) {
+		tempDir := t.TempDir()
+		for _, filename := range []string{"001_users.up.sql", "003_posts.up.sql"} {
+			err := os.WriteFile(filepath.Join(tempDir, filename), []byte("select 1;"), 0644)
+			require.NoError(t, err)
+		}
+
+		result, err := validateMigrationsCore(tempDir, validateOptions{})
+		require.NoError(t, err)
+		assert.Contains(t, result, `"kind": "gap"`)
+		assert.Contains(t, result, "003_posts follows 001_users, version 2 is missing")
+
+		result, err = validateMigrationsCore(tempDir, validateOptions{SequenceScheme: sequenceTimestamp})
+		require.NoError(t, err)
+		assert.Contains(t, result, `"kind": "invalid_version"`)
+	})
+
+	t.Run("unsupported_format", func(t *testing.T) {
+		_, err := validateMigrationsCore(t.TempDir(), validateOptions{Format: "xml"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported validation format")
+	})
+
 	t.Run("parse_error", func(t *testing.T) {
 		if os.Getuid() == 0 {
 			t.Skip("test setup failed or running as root")
@@ -83,7 +208,7 @@ func TestValidateMigrationsCore(t *testing.T) {
 		}
 		defer os.Chmod(tempDir, 0755)
 
-		_, err = validateMigrationsCore(tempDir)
+		_, err = validateMigrationsCore(tempDir, validateOptions{})
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "permission denied")
 	})
@@ -110,7 +235,7 @@ func TestExtractSchemaCore(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 		defer cancel()
 		
-		result, err := extractSchemaCore(ctx, tempDir, "info", "native", "postgres:16-alpine")
+		result, err := extractSchemaCore(ctx, providers.NewDefaultRegistry(), tempDir, "info", "native", "postgres:16-alpine", "")
 		require.NoError(t, err)
 		assert.Contains(t, result, "Table: test_table")
 	})
@@ -126,7 +251,7 @@ func TestExtractSchemaCore(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 		defer cancel()
 		
-		result, err := extractSchemaCore(ctx, tempDir, "sql", "native", "postgres:16-alpine")
+		result, err := extractSchemaCore(ctx, providers.NewDefaultRegistry(), tempDir, "sql", "native", "postgres:16-alpine", "")
 		require.NoError(t, err)
 		assert.Contains(t, result, "create table sql_test")
 	})
@@ -137,7 +262,7 @@ func TestExtractSchemaCore(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 		defer cancel()
 		
-		_, err := extractSchemaCore(ctx, tempDir, "info", "native", "postgres:16-alpine")
+		_, err := extractSchemaCore(ctx, providers.NewDefaultRegistry(), tempDir, "info", "native", "postgres:16-alpine", "")
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "no migration files found")
 	})
@@ -146,7 +271,7 @@ func TestExtractSchemaCore(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 		defer cancel()
 		
-		_, err := extractSchemaCore(ctx, "/nonexistent/path", "info", "native", "postgres:16-alpine")
+		_, err := extractSchemaCore(ctx, providers.NewDefaultRegistry(), "/nonexistent/path", "info", "native", "postgres:16-alpine", "")
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "migration directory does not exist")
 	})
@@ -378,7 +503,7 @@ func TestExtractSchemaCoreWithDeps(t *testing.T) {
 			},
 		}
 		mockDB := &MockDatabaseManager{
-			RunMigrationsFunc: func(migrations []Migration) error {
+			RunMigrationsFunc: func(ctx context.Context, migrations []Migration) error {
 				return fmt.Errorf("syntax error in SQL")
 			},
 		}
@@ -415,4 +540,374 @@ func TestExtractSchemaCoreWithDeps(t *testing.T) {
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to extract schema")
 	})
-}
\ No newline at end of file
+}
+
+func TestParseSchemaURI(t *testing.T) {
+	tests := []struct {
+		uri         string
+		expectedDir string
+		format      string
+		expectError bool
+	}{
+		{uri: "schema://./migrations", expectedDir: "./migrations", format: "sql"},
+		{uri: "schema:///abs/path/migrations", expectedDir: "/abs/path/migrations", format: "sql"},
+		{uri: "schema://my%20migrations/v1", expectedDir: "my migrations/v1", format: "sql"},
+		{uri: "schema://%2Ftmp%2Fmigrations?format=info", expectedDir: "/tmp/migrations", format: "info"},
+		{uri: "schema://", expectError: true},
+		{uri: "file:///tmp/migrations", expectError: true},
+		{uri: "schema://migrations?format=yaml", expectError: true},
+		{uri: "schema://bad%zzpath", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			dir, format, err := parseSchemaURI(tt.uri)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedDir, dir)
+			assert.Equal(t, tt.format, format)
+		})
+	}
+}
+
+func TestSchemaResourceCache(t *testing.T) {
+	tempDir := t.TempDir()
+	upFile := filepath.Join(tempDir, "001_users.up.sql")
+	require.NoError(t, os.WriteFile(upFile, []byte("create table users (id int);"), 0644))
+
+	calls := 0
+	cache := newSchemaResourceCache(func(ctx context.Context, migrationDir, format string) (string, error) {
+		calls++
+		return fmt.Sprintf("schema %d (%s)", calls, format), nil
+	})
+	ctx := context.Background()
+
+	first, err := cache.get(ctx, tempDir, "sql")
+	require.NoError(t, err)
+	second, err := cache.get(ctx, tempDir, "sql")
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, calls)
+
+	_, err = cache.get(ctx, tempDir, "info")
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls, "a different format should not share the cache entry")
+
+	// The content counts, not the modification time
+	info, err := os.Stat(upFile)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(upFile, []byte("create table users (id bigint);"), 0644))
+	require.NoError(t, os.Chtimes(upFile, info.ModTime(), info.ModTime()))
+	third, err := cache.get(ctx, tempDir, "sql")
+	require.NoError(t, err)
+	assert.NotEqual(t, first, third)
+	assert.Equal(t, 3, calls, "modified migrations should invalidate the cache")
+
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(upFile, later, later))
+	_, err = cache.get(ctx, tempDir, "sql")
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls, "touching a migration should keep the cache entry")
+
+	require.NoError(t, os.Rename(upFile, filepath.Join(tempDir, "001_accounts.up.sql")))
+	_, err = cache.get(ctx, tempDir, "sql")
+	require.NoError(t, err)
+	assert.Equal(t, 4, calls, "renamed migrations should invalidate the cache")
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "002_posts.up.sql"), []byte("create table posts (id int);"), 0644))
+	_, err = cache.get(ctx, tempDir, "sql")
+	require.NoError(t, err)
+	assert.Equal(t, 5, calls, "added migrations should invalidate the cache")
+}
+
+func TestSchemaResourceCacheBounded(t *testing.T) {
+	tempDir := t.TempDir()
+	upFile := filepath.Join(tempDir, "001_users.up.sql")
+
+	calls := 0
+	cache := newSchemaResourceCache(func(ctx context.Context, migrationDir, format string) (string, error) {
+		calls++
+		return "schema", nil
+	})
+	ctx := context.Background()
+
+	for i := 0; i <= maxSchemaResourceEntries; i++ {
+		require.NoError(t, os.WriteFile(upFile, []byte(fmt.Sprintf("create table users_%d (id int);", i)), 0644))
+		_, err := cache.get(ctx, tempDir, "sql")
+		require.NoError(t, err)
+	}
+	assert.Len(t, cache.entries, maxSchemaResourceEntries)
+
+	// The newest entry is kept, the oldest was evicted
+	_, err := cache.get(ctx, tempDir, "sql")
+	require.NoError(t, err)
+	assert.Equal(t, maxSchemaResourceEntries+1, calls)
+	require.NoError(t, os.WriteFile(upFile, []byte("create table users_0 (id int);"), 0644))
+	_, err = cache.get(ctx, tempDir, "sql")
+	require.NoError(t, err)
+	assert.Equal(t, maxSchemaResourceEntries+2, calls)
+}
+
+func TestSchemaResourceCacheConcurrent(t *testing.T) {
+	slowDir, fastDir := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(slowDir, "001_users.up.sql"), []byte("create table users (id int);"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(fastDir, "001_posts.up.sql"), []byte("create table posts (id int);"), 0644))
+
+	release := make(chan struct{})
+	var slowCalls atomic.Int32
+	cache := newSchemaResourceCache(func(ctx context.Context, migrationDir, format string) (string, error) {
+		if migrationDir == slowDir {
+			slowCalls.Add(1)
+			<-release
+		}
+		return "schema of " + migrationDir, nil
+	})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	results := make([]string, 3)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = cache.get(ctx, slowDir, "sql")
+		}()
+	}
+
+	// Another key is served while the slow extraction is still running
+	output, err := cache.get(ctx, fastDir, "sql")
+	require.NoError(t, err)
+	assert.Equal(t, "schema of "+fastDir, output)
+
+	close(release)
+	wg.Wait()
+	for _, result := range results {
+		assert.Equal(t, "schema of "+slowDir, result)
+	}
+	assert.Equal(t, int32(1), slowCalls.Load(), "concurrent reads of one key should share the extraction")
+}
+
+func TestHandleReadSchemaResource(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "001_users.up.sql"), []byte("create table users (id int);"), 0644))
+
+	cache := newSchemaResourceCache(func(ctx context.Context, migrationDir, format string) (string, error) {
+		return "create table users (\n    id integer\n);\n", nil
+	})
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = "schema://" + url.PathEscape(tempDir)
+
+	contents, err := handleReadSchemaResource(context.Background(), request, cache)
+	require.NoError(t, err)
+	require.Len(t, contents, 1)
+
+	text, ok := contents[0].(mcp.TextResourceContents)
+	require.True(t, ok)
+	assert.Equal(t, request.Params.URI, text.URI)
+	assert.Equal(t, "application/sql", text.MIMEType)
+	assert.Contains(t, text.Text, "create table users")
+}
+
+func TestExtractSchemaCoreTargetVersion(t *testing.T) {
+	tempDir := t.TempDir()
+	mockReader := &MockMigrationReader{
+		DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+			return []Migration{{Name: "001_create_users"}, {Name: "002_create_posts"}, {Name: "003_add_tags"}}, nil
+		},
+	}
+	provider := &MockSchemaProvider{}
+
+	t.Run("runs_up_to_target", func(t *testing.T) {
+		var ran []string
+		mockDB := &MockDatabaseManager{
+			RunMigrationsFunc: func(ctx context.Context, migrations []Migration) error {
+				for _, migration := range migrations {
+					ran = append(ran, migration.Name)
+				}
+				return nil
+			},
+		}
+
+		_, err := extractSchemaCoreWithProvider(context.Background(), tempDir, "sql", "2", mockReader, mockDB, provider)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"001_create_users", "002_create_posts"}, ran)
+	})
+
+	t.Run("unknown_target", func(t *testing.T) {
+		mockDB := &MockDatabaseManager{}
+		_, err := extractSchemaCoreWithProvider(context.Background(), tempDir, "sql", "7", mockReader, mockDB, provider)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "available versions: 001, 002, 003")
+		assert.False(t, mockDB.SetupCalled, "no database should be started for an unknown target")
+	})
+}
+
+func TestServeMCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	sse := server.NewSSEServer(newMCPServer(providers.NewDefaultRegistry()))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serveMCP(ctx, addr, sse.Start, sse.Shutdown)
+	}()
+
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		resp, err = http.Get("http://" + addr + "/sse")
+		return err == nil
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: endpoint\n", line)
+	resp.Body.Close()
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(mcpShutdownTimeout + time.Second):
+		t.Fatal("mcp server did not stop")
+	}
+}
+
+func TestServeMCPStartFailure(t *testing.T) {
+	err := serveMCP(context.Background(), "127.0.0.1:0", func(string) error {
+		return errors.New("address already in use")
+	}, func(context.Context) error { return nil })
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "address already in use")
+}
+
+func TestMCPTransportFlags(t *testing.T) {
+	defer resetCommand()
+
+	resetCommand()
+	mcpTransport = mcpTransportSSE
+	err := executeMig2Schema(context.Background(), []string{"migrations"}, providers.NewDefaultRegistry())
+	require.Error(t, err)
+	assert.Equal(t, exitUsage, exitCode(err))
+	assert.Contains(t, err.Error(), "--mcp-transport and --mcp-addr require --mcp")
+
+	resetCommand()
+	mcpMode = true
+	mcpTransport = "websocket"
+	err = executeMig2Schema(context.Background(), nil, providers.NewDefaultRegistry())
+	require.Error(t, err)
+	assert.Equal(t, exitUsage, exitCode(err))
+	assert.Contains(t, err.Error(), "unsupported mcp transport: websocket")
+}
+
+func TestCheckExtractOptions(t *testing.T) {
+	tests := []struct {
+		provider string
+		format   string
+		wantErr  string
+	}{
+		{"native", "sql", ""},
+		{"native", "info", ""},
+		{"native", "json", ""},
+		{"pg_dump", "sql", ""},
+		{"pg_dump", "info", "provider 'pg_dump' only supports format 'sql'; use provider 'native' for 'info'"},
+		{"pg_dump", "json", "provider 'pg_dump' only supports format 'sql'"},
+		{"native", "yaml", "unsupported format 'yaml'"},
+		{"atlas", "sql", "unknown provider 'atlas'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider+"_"+tt.format, func(t *testing.T) {
+			err := checkExtractOptions(tt.provider, tt.format)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestHandleExtractSchemaRejectsProviderMismatch(t *testing.T) {
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{
+		"migration_directory": t.TempDir(),
+		"provider":            "pg_dump",
+		"format":              "info",
+	}
+
+	result, err := handleExtractSchema(context.Background(), request, providers.NewDefaultRegistry())
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	text, ok := result.Content[0].(mcp.TextContent)
+	require.True(t, ok)
+	assert.Contains(t, text.Text, "provider 'pg_dump' only supports format 'sql'")
+}
+
+func TestExtractSchemaCoreJSON(t *testing.T) {
+	mockReader := &MockMigrationReader{
+		DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+			return []Migration{{Name: "001_create_users"}}, nil
+		},
+	}
+	var requested providers.SchemaFormat
+	provider := &MockSchemaProvider{ExtractSchemaFunc: func(ctx context.Context, params providers.ExtractParams) (*providers.SchemaResult, error) {
+		requested = params.Format
+		return &providers.SchemaResult{Schema: providers.Schema{Tables: []providers.Table{{
+			Name:    "users",
+			Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}},
+		}}}}, nil
+	}}
+
+	output, err := extractSchemaCoreWithProvider(context.Background(), t.TempDir(), "json", "", mockReader, &MockDatabaseManager{}, provider)
+	require.NoError(t, err)
+	assert.Equal(t, providers.FormatJSON, requested)
+
+	tables, err := parseTargetTables([]byte(output))
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	assert.Equal(t, "users", tables[0].Name)
+	assert.True(t, tables[0].Columns[0].IsPrimaryKey)
+}
+
+func TestExtractSchemaToolDefinition(t *testing.T) {
+	response := newMCPServer(providers.NewDefaultRegistry()).HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc": "2.0", "id": 1, "method": "tools/list"}`))
+	data, err := json.Marshal(response)
+	require.NoError(t, err)
+
+	var list struct {
+		Result struct {
+			Tools []struct {
+				Name        string `json:"name"`
+				InputSchema struct {
+					Properties map[string]struct {
+						Enum    []string `json:"enum"`
+						Default string   `json:"default"`
+					} `json:"properties"`
+				} `json:"inputSchema"`
+			} `json:"tools"`
+		} `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(data, &list))
+
+	for _, tool := range list.Result.Tools {
+		if tool.Name != "extract_schema" {
+			continue
+		}
+		format := tool.InputSchema.Properties["format"]
+		assert.Equal(t, []string{"sql", "info", "json"}, format.Enum)
+		assert.Equal(t, "sql", format.Default)
+		provider := tool.InputSchema.Properties["provider"]
+		assert.Equal(t, []string{"native", "pg_dump"}, provider.Enum)
+		assert.Equal(t, "native", provider.Default)
+		return
+	}
+	t.Fatal("extract_schema tool not registered")
+}
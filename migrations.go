@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/fs"
 	"log/slog"
+	"os"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -13,14 +14,100 @@ type Migration struct {
 	Name     string
 	UpFile   string
 	DownFile string
+	// UpSQL and DownSQL hold the migration content as loaded by the reader,
+	// so that execution does not depend on where the migration came from.
+	// When UpSQL is nil, UpFile is read from disk instead.
+	UpSQL   []byte
+	DownSQL []byte
 }
 
+// readUpSQL returns the content of the up migration, falling back to reading
+// UpFile when the reader did not load it
+func (m Migration) readUpSQL() ([]byte, error) {
+	if m.UpSQL != nil {
+		return m.UpSQL, nil
+	}
+	content, err := os.ReadFile(m.UpFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration file %s: %w", m.UpFile, err)
+	}
+	return content, nil
+}
+
+// readDownSQL returns the content of the down migration, falling back to
+// reading DownFile when the reader did not load it. It returns nil when the
+// migration has no down file.
+func (m Migration) readDownSQL() ([]byte, error) {
+	if m.DownSQL != nil || m.DownFile == "" {
+		return m.DownSQL, nil
+	}
+	content, err := os.ReadFile(m.DownFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration file %s: %w", m.DownFile, err)
+	}
+	return content, nil
+}
+
+// migrationFileName reports whether fileName is an up or down migration and
+// returns its base name
+func migrationFileName(fileName string) (baseName string, up bool, ok bool) {
+	switch {
+	case strings.HasSuffix(fileName, ".up.sql"):
+		return strings.TrimSuffix(fileName, ".up.sql"), true, true
+	case strings.HasSuffix(fileName, ".down.sql"):
+		return strings.TrimSuffix(fileName, ".down.sql"), false, true
+	}
+	return "", false, false
+}
+
+// MigrationReaderOption configures how a migration reader handles unusual
+// directory layouts
+type MigrationReaderOption func(*migrationParseOptions)
+
+type migrationParseOptions struct {
+	// allowDuplicates logs colliding migration names instead of failing
+	allowDuplicates bool
+}
+
+// WithDuplicateWarnings makes the reader log a warning instead of returning an
+// error when two files resolve to the same migration name. The file found
+// first is used.
+func WithDuplicateWarnings() MigrationReaderOption {
+	return func(o *migrationParseOptions) {
+		o.allowDuplicates = true
+	}
+}
+
+func newMigrationParseOptions(opts []MigrationReaderOption) migrationParseOptions {
+	var options migrationParseOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// ParseMigrations discovers the migrations in a directory on disk. UpFile and
+// DownFile are paths below migrationDir. Two files resolving to the same
+// migration name are reported as an error.
 func ParseMigrations(migrationDir string) ([]Migration, error) {
-	slog.Debug("scanning migration directory", "directory", migrationDir)
-	upFiles := make(map[string]string)
-	downFiles := make(map[string]string)
+	return parseMigrationsDir(migrationDir, migrationParseOptions{})
+}
+
+func parseMigrationsDir(migrationDir string, options migrationParseOptions) ([]Migration, error) {
+	return parseMigrationsFS(os.DirFS(migrationDir), ".", func(name string) string {
+		return filepath.Join(migrationDir, filepath.FromSlash(name))
+	}, options)
+}
+
+// parseMigrationsFS walks root in fsys and loads every up and down migration
+// found below it. filePath maps an fs path to the value stored in UpFile and
+// DownFile.
+func parseMigrationsFS(fsys fs.FS, root string, filePath func(name string) string, options migrationParseOptions) ([]Migration, error) {
+	slog.Debug("scanning migration directory", "directory", filePath(root))
+	upFiles := make(map[string][]string)
+	downFiles := make(map[string][]string)
 
-	err := filepath.WalkDir(migrationDir, func(path string, d fs.DirEntry, err error) error {
+	err := fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -32,13 +119,11 @@ func ParseMigrations(migrationDir string) ([]Migration, error) {
 		fileName := d.Name()
 		slog.Debug("found file", "file", fileName, "path", path)
 		
-		if strings.HasSuffix(fileName, ".up.sql") {
-			baseName := strings.TrimSuffix(fileName, ".up.sql")
-			upFiles[baseName] = path
+		if baseName, up, ok := migrationFileName(fileName); ok && up {
+			upFiles[baseName] = append(upFiles[baseName], path)
 			slog.Debug("found up migration", "name", baseName, "file", path)
-		} else if strings.HasSuffix(fileName, ".down.sql") {
-			baseName := strings.TrimSuffix(fileName, ".down.sql")
-			downFiles[baseName] = path
+		} else if ok {
+			downFiles[baseName] = append(downFiles[baseName], path)
 			slog.Debug("found down migration", "name", baseName, "file", path)
 		}
 
@@ -49,16 +134,33 @@ func ParseMigrations(migrationDir string) ([]Migration, error) {
 		return nil, fmt.Errorf("failed to walk migration directory: %w", err)
 	}
 
+	if err := checkMigrationNames(upFiles, downFiles, filePath, options); err != nil {
+		return nil, err
+	}
+
 	var migrations []Migration
-	for baseName, upFile := range upFiles {
+	for baseName, upPaths := range upFiles {
+		upFile := upPaths[0]
+		upSQL, err := fs.ReadFile(fsys, upFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file %s: %w", filePath(upFile), err)
+		}
+
 		migration := Migration{
 			Name:   baseName,
-			UpFile: upFile,
+			UpFile: filePath(upFile),
+			UpSQL:  upSQL,
 		}
 		
-		if downFile, exists := downFiles[baseName]; exists {
-			migration.DownFile = downFile
-			slog.Debug("migration has down file", "name", baseName, "downFile", downFile)
+		if downPaths, exists := downFiles[baseName]; exists {
+			downFile := downPaths[0]
+			downSQL, err := fs.ReadFile(fsys, downFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read migration file %s: %w", filePath(downFile), err)
+			}
+			migration.DownFile = filePath(downFile)
+			migration.DownSQL = downSQL
+			slog.Debug("migration has down file", "name", baseName, "downFile", migration.DownFile)
 		}
 		
 		migrations = append(migrations, migration)
@@ -70,4 +172,40 @@ func ParseMigrations(migrationDir string) ([]Migration, error) {
 
 	slog.Info("parsed migrations", "count", len(migrations), "upFiles", len(upFiles), "downFiles", len(downFiles))
 	return migrations, nil
-}
\ No newline at end of file
+}
+
+// checkMigrationNames reports migration names claimed by more than one up or
+// down file, and warns about down files without a matching up file. Paths are
+// expected in walk order, so the first one is the file that is kept when
+// duplicates are allowed.
+func checkMigrationNames(upFiles, downFiles map[string][]string, filePath func(name string) string, options migrationParseOptions) error {
+	var conflicts []string
+	for _, files := range []map[string][]string{upFiles, downFiles} {
+		for baseName, paths := range files {
+			if len(paths) < 2 {
+				continue
+			}
+			resolved := make([]string, len(paths))
+			for i, p := range paths {
+				resolved[i] = filePath(p)
+			}
+			if options.allowDuplicates {
+				slog.Warn("duplicate migration name, using the first file", "name", baseName, "files", resolved)
+				continue
+			}
+			conflicts = append(conflicts, fmt.Sprintf("%s (%s)", baseName, strings.Join(resolved, ", ")))
+		}
+	}
+
+	for baseName, paths := range downFiles {
+		if _, exists := upFiles[baseName]; !exists {
+			slog.Warn("down migration has no matching up migration", "name", baseName, "file", filePath(paths[0]))
+		}
+	}
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return fmt.Errorf("duplicate migration names: %s", strings.Join(conflicts, "; "))
+	}
+	return nil
+}
@@ -31,10 +31,37 @@ func TestParseMigrations(t *testing.T) {
 	migration1 := migrations[0]
 	assert.Equal(t, "001_create_users", migration1.Name)
 	assert.NotEmpty(t, migration1.DownFile)
+	assert.Equal(t, testFiles["001_create_users.up.sql"], string(migration1.UpSQL))
+	assert.Equal(t, testFiles["001_create_users.down.sql"], string(migration1.DownSQL))
 
 	migration3 := migrations[2]
 	assert.Equal(t, "003_no_down", migration3.Name)
 	assert.Empty(t, migration3.DownFile)
+	assert.Nil(t, migration3.DownSQL)
+}
+
+func TestMigrationReadUpSQL(t *testing.T) {
+	t.Run("uses_loaded_content", func(t *testing.T) {
+		migration := Migration{Name: "001_test", UpFile: "/non/existent/001_test.up.sql", UpSQL: []byte("select 1;")}
+
+		content, err := migration.readUpSQL()
+		require.NoError(t, err)
+		assert.Equal(t, "select 1;", string(content))
+	})
+
+	t.Run("falls_back_to_file", func(t *testing.T) {
+		upFile := filepath.Join(t.TempDir(), "001_test.up.sql")
+		require.NoError(t, os.WriteFile(upFile, []byte("select 2;"), 0644))
+
+		content, err := Migration{Name: "001_test", UpFile: upFile}.readUpSQL()
+		require.NoError(t, err)
+		assert.Equal(t, "select 2;", string(content))
+	})
+
+	t.Run("missing_file", func(t *testing.T) {
+		_, err := Migration{Name: "001_test", UpFile: "/non/existent/001_test.up.sql"}.readUpSQL()
+		assert.Error(t, err)
+	})
 }
 
 func TestParseMigrationsEmptyDirectory(t *testing.T) {
@@ -108,4 +135,45 @@ func TestParseMigrationsPermissionError(t *testing.T) {
 
 	_, err := ParseMigrations(tempDir)
 	assert.Error(t, err)
-}
\ No newline at end of file
+}
+
+func TestParseMigrationsDuplicateNames(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"a/001_users.up.sql": "create table users (id int);",
+		"b/001_users.up.sql": "create table users (id bigint);",
+		"b/002_posts.up.sql": "create table posts (id int);",
+	}
+	for fileName, content := range files {
+		path := filepath.Join(tempDir, filepath.FromSlash(fileName))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	t.Run("error_by_default", func(t *testing.T) {
+		_, err := ParseMigrations(tempDir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "duplicate migration names: 001_users")
+		assert.Contains(t, err.Error(), filepath.Join(tempDir, "a", "001_users.up.sql"))
+		assert.Contains(t, err.Error(), filepath.Join(tempDir, "b", "001_users.up.sql"))
+	})
+
+	t.Run("warning_keeps_first_file", func(t *testing.T) {
+		migrations, err := NewFileMigrationReader(WithDuplicateWarnings()).DiscoverMigrations(tempDir)
+		require.NoError(t, err)
+		require.Len(t, migrations, 2)
+		assert.Equal(t, filepath.Join(tempDir, "a", "001_users.up.sql"), migrations[0].UpFile)
+		assert.Equal(t, files["a/001_users.up.sql"], string(migrations[0].UpSQL))
+	})
+}
+
+func TestParseMigrationsOrphanDownFile(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "001_users.up.sql"), []byte("create table users (id int);"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "002_posts.down.sql"), []byte("drop table posts;"), 0644))
+
+	migrations, err := ParseMigrations(tempDir)
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	assert.Equal(t, "001_users", migrations[0].Name)
+}
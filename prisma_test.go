@@ -0,0 +1,142 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+// defaultOf returns a column default as read from the catalog
+func defaultOf(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: true}
+}
+
+// ormTestTables returns the schema the ORM and schema-as-code formats are
+// tested with: a serial key, a unique varchar, literal and function defaults,
+// a foreign key with referential actions, an index, an array, an extension
+// type, a table without a primary key and a view
+func ormTestTables() []providers.Table {
+	return []providers.Table{
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", FullType: "integer", IsPrimaryKey: true, IsSerial: true, DefaultValue: defaultOf("nextval('users_id_seq'::regclass)")},
+				{Name: "email", DataType: "character varying", FullType: "character varying(320)", CharacterLength: sql.NullInt64{Int64: 320, Valid: true}},
+				{Name: "status", DataType: "text", FullType: "text", DefaultValue: defaultOf("'active'::text")},
+				{Name: "created_at", DataType: "timestamp with time zone", FullType: "timestamp with time zone", DefaultValue: defaultOf("now()")},
+			},
+			UniqueConstraints: []providers.UniqueConstraint{{Name: "users_email_key", Columns: []string{"email"}}},
+		},
+		{
+			Name: "posts",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "uuid", FullType: "uuid", IsPrimaryKey: true, DefaultValue: defaultOf("gen_random_uuid()")},
+				{Name: "user_id", DataType: "integer", FullType: "integer"},
+				{Name: "tags", DataType: "ARRAY", FullType: "text[]", IsNullable: true},
+				{Name: "location", DataType: "geometry", FullType: "geometry(Point,4326)", IsNullable: true},
+			},
+			Indexes: []providers.Index{{Name: "idx_posts_user_id", Columns: []string{"user_id"}}},
+			ForeignKeys: []providers.ForeignKey{
+				{Name: "posts_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}, OnDelete: "CASCADE", OnUpdate: "NO ACTION"},
+			},
+		},
+		{
+			Name:    "audit_log",
+			Columns: []providers.Column{{Name: "message", DataType: "text", FullType: "text"}},
+		},
+		{Name: "active_users", Columns: []providers.Column{{Name: "id", DataType: "integer", FullType: "integer"}}, IsView: true},
+	}
+}
+
+func TestFormatSchemaPrisma(t *testing.T) {
+	expected := `generator client {
+  provider = "prisma-client-js"
+}
+
+datasource db {
+  provider = "postgresql"
+  url      = env("DATABASE_URL")
+}
+
+model users {
+  id         Int      @id @default(autoincrement())
+  email      String   @unique @db.VarChar(320)
+  status     String   @default("active")
+  created_at DateTime @default(now()) @db.Timestamptz(6)
+  posts      posts[]
+}
+
+model posts {
+  id       String                               @id @default(dbgenerated("gen_random_uuid()")) @db.Uuid
+  user_id  Int
+  tags     String[]
+  location Unsupported("geometry(Point,4326)")?
+  users    users                                @relation(fields: [user_id], references: [id], onDelete: Cascade, onUpdate: NoAction)
+
+  @@index([user_id], map: "idx_posts_user_id")
+}
+
+model audit_log {
+  message String
+
+  @@ignore
+}
+`
+	assert.Equal(t, expected, providers.FormatSchemaPrisma(ormTestTables()))
+}
+
+func TestFormatSchemaPrismaNames(t *testing.T) {
+	tables := ormTestTables()
+	tables[0].Columns = append(tables[0].Columns, providers.Column{Name: "display name", DataType: "text", FullType: "text", IsNullable: true})
+	tables[1].Columns = append(tables[1].Columns, providers.Column{Name: "editor_id", DataType: "integer", FullType: "integer", IsNullable: true})
+	tables[1].ForeignKeys = append(tables[1].ForeignKeys, providers.ForeignKey{
+		Name: "posts_editor_id_fkey", Columns: []string{"editor_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}, OnDelete: "SET NULL",
+	})
+
+	output := providers.FormatSchemaPrisma(tables)
+	// Field names are valid identifiers mapped to the column name
+	assert.Contains(t, output, `  display_name             String?  @map("display name")`+"\n")
+	// Two relations between the same models are named by their constraint
+	assert.Contains(t, output, `  posts                    posts[]  @relation("posts_editor_id_fkey")`+"\n")
+	assert.Contains(t, output, `  posts_posts_user_id_fkey posts[]  @relation("posts_user_id_fkey")`+"\n")
+	assert.Contains(t, output, `@relation("posts_editor_id_fkey", fields: [editor_id], references: [id], onDelete: SetNull)`+"\n")
+	assert.Contains(t, output, `@relation("posts_user_id_fkey", fields: [user_id], references: [id], onDelete: Cascade, onUpdate: NoAction)`+"\n")
+}
+
+func TestFormatSchemaPrismaRelations(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "bigint", FullType: "bigint", IsPrimaryKey: true},
+				{Name: "manager_id", DataType: "bigint", FullType: "bigint", IsNullable: true},
+			},
+			ForeignKeys: []providers.ForeignKey{
+				{Name: "users_manager_id_fkey", Columns: []string{"manager_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+			},
+		},
+		{
+			Name: "profiles",
+			Columns: []providers.Column{
+				{Name: "user_id", DataType: "bigint", FullType: "bigint", IsPrimaryKey: true},
+				{Name: "tenant", DataType: "integer", FullType: "integer"},
+				{Name: "slug", DataType: "text", FullType: "text"},
+			},
+			Indexes: []providers.Index{{Name: "profiles_tenant_slug_key", Columns: []string{"tenant", "slug"}, IsUnique: true}},
+			ForeignKeys: []providers.ForeignKey{
+				{Name: "fk_profile_user", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+			},
+		},
+	}
+
+	output := providers.FormatSchemaPrisma(tables)
+	// A self-relation is named and gets both sides on the same model
+	assert.Contains(t, output, `  users                       users?    @relation("users_manager_id_fkey", fields: [manager_id], references: [id])`+"\n")
+	assert.Contains(t, output, `  users_users_manager_id_fkey users[]   @relation("users_manager_id_fkey")`)
+	// A foreign key on unique columns is one-to-one, with its name kept
+	assert.Contains(t, output, `  users   users  @relation(fields: [user_id], references: [id], map: "fk_profile_user")`+"\n")
+	assert.Contains(t, output, "  profiles                    profiles?\n")
+	assert.Contains(t, output, "  @@unique([tenant, slug])\n")
+}
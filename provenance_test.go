@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvenanceMapRecord(t *testing.T) {
+	users := providers.Table{
+		Name: "users",
+		Columns: []providers.Column{
+			{Name: "id", DataType: "integer", IsPrimaryKey: true},
+			{Name: "email", DataType: "text"},
+		},
+	}
+	usersWithName := users
+	usersWithName.Columns = []providers.Column{
+		{Name: "id", DataType: "integer", IsPrimaryKey: true},
+		{Name: "email", DataType: "character varying"},
+		{Name: "name", DataType: "text", IsNullable: true},
+	}
+	posts := providers.Table{Name: "posts", Columns: []providers.Column{{Name: "id", DataType: "integer"}}}
+
+	provenance := make(providers.ProvenanceMap)
+	provenance.Record("001_create_users", providers.DiffSchemas(nil, []providers.Table{users}))
+	provenance.Record("002_create_posts", providers.DiffSchemas([]providers.Table{users}, []providers.Table{users, posts}))
+	provenance.Record("003_add_name", providers.DiffSchemas([]providers.Table{users, posts}, []providers.Table{usersWithName, posts}))
+
+	assert.Equal(t, providers.ProvenanceMap{
+		"users":       {CreatedBy: "001_create_users", ModifiedBy: "003_add_name"},
+		"users.id":    {CreatedBy: "001_create_users"},
+		"users.email": {CreatedBy: "001_create_users", ModifiedBy: "003_add_name"},
+		"users.name":  {CreatedBy: "003_add_name"},
+		"posts":       {CreatedBy: "002_create_posts"},
+		"posts.id":    {CreatedBy: "002_create_posts"},
+	}, provenance)
+
+	table, ok := provenance.Table("users")
+	assert.True(t, ok)
+	assert.Equal(t, "001_create_users", table.CreatedBy)
+	_, ok = provenance.Column("users", "missing")
+	assert.False(t, ok)
+
+	// A table dropped and created again belongs to the migration recreating it
+	provenance.Record("004_drop_posts", providers.DiffSchemas([]providers.Table{usersWithName, posts}, []providers.Table{usersWithName}))
+	assert.NotContains(t, provenance, "posts")
+	assert.NotContains(t, provenance, "posts.id")
+	provenance.Record("005_recreate_posts", providers.DiffSchemas([]providers.Table{usersWithName}, []providers.Table{usersWithName, posts}))
+	assert.Equal(t, providers.Provenance{CreatedBy: "005_recreate_posts"}, provenance["posts"])
+}
+
+func TestFormatSchemaInfoProvenance(t *testing.T) {
+	tables := []providers.Table{{
+		Name: "users",
+		Columns: []providers.Column{
+			{Name: "id", DataType: "integer", IsPrimaryKey: true},
+			{Name: "email", DataType: "text"},
+			{Name: "name", DataType: "text", IsNullable: true},
+		},
+	}}
+	provenance := providers.ProvenanceMap{
+		"users":       {CreatedBy: "001_create_users", ModifiedBy: "003_add_name"},
+		"users.id":    {CreatedBy: "001_create_users"},
+		"users.email": {CreatedBy: "001_create_users", ModifiedBy: "002_widen_email"},
+		"users.name":  {CreatedBy: "003_add_name"},
+	}
+
+	output := providers.FormatSchemaInfoWithOptions(tables, providers.InfoOptions{Provenance: provenance})
+	assert.Contains(t, output, "Table: users (from 001_create_users, modified by 003_add_name)\n")
+	assert.Contains(t, output, "  - id INTEGER NOT NULL (PRIMARY KEY)\n")
+	assert.Contains(t, output, "  - email TEXT NOT NULL (modified by 002_widen_email)\n")
+	assert.Contains(t, output, "  - name TEXT NULL (from 003_add_name)\n")
+
+	assert.Contains(t, providers.FormatSchemaInfo(tables), "Table: users\n")
+}
@@ -0,0 +1,189 @@
+package providers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// atlasAttribute is a name = value line inside an Atlas HCL block
+type atlasAttribute struct {
+	Name  string
+	Value string
+}
+
+// FormatSchemaAtlasHCL formats the schema as Atlas HCL: a public schema block
+// and one table block per table with its columns, primary key, indexes,
+// unique constraints and foreign keys. Views and partitions are left out.
+func FormatSchemaAtlasHCL(tables []Table) string {
+	var sb strings.Builder
+	tables = normalizeSchema(tables)
+
+	for _, table := range tables {
+		if table.IsView || table.IsPartition() {
+			continue
+		}
+		writeAtlasTable(&sb, table)
+	}
+
+	sb.WriteString("schema \"public\" {\n}\n")
+	return sb.String()
+}
+
+// writeAtlasTable writes the table block of a table
+func writeAtlasTable(sb *strings.Builder, table Table) {
+	sb.WriteString(fmt.Sprintf("table %q {\n", table.Name))
+	sb.WriteString("  schema = schema.public\n")
+
+	var primaryKeys []string
+	for _, col := range table.Columns {
+		attributes := []atlasAttribute{
+			{Name: "null", Value: fmt.Sprint(col.IsNullable)},
+			{Name: "type", Value: atlasType(col)},
+		}
+		if col.DefaultValue.Valid && !col.IsSerial {
+			attributes = append(attributes, atlasAttribute{Name: "default", Value: atlasDefault(col.DefaultValue.String)})
+		}
+		writeAtlasBlock(sb, fmt.Sprintf("column %q", col.Name), attributes)
+
+		if col.IsPrimaryKey {
+			primaryKeys = append(primaryKeys, col.Name)
+		}
+	}
+
+	if len(primaryKeys) > 0 {
+		writeAtlasBlock(sb, "primary_key", []atlasAttribute{{Name: "columns", Value: atlasColumnList(primaryKeys)}})
+	}
+
+	for _, fk := range table.ForeignKeys {
+		references := make([]string, len(fk.ReferencedColumns))
+		for i, column := range fk.ReferencedColumns {
+			references[i] = "table" + atlasReference(fk.ReferencedTable) + ".column" + atlasReference(column)
+		}
+		attributes := []atlasAttribute{
+			{Name: "columns", Value: atlasColumnList(fk.Columns)},
+			{Name: "ref_columns", Value: "[" + strings.Join(references, ", ") + "]"},
+		}
+		if fk.OnUpdate != "" {
+			attributes = append(attributes, atlasAttribute{Name: "on_update", Value: strings.ReplaceAll(fk.OnUpdate, " ", "_")})
+		}
+		if fk.OnDelete != "" {
+			attributes = append(attributes, atlasAttribute{Name: "on_delete", Value: strings.ReplaceAll(fk.OnDelete, " ", "_")})
+		}
+		writeAtlasBlock(sb, fmt.Sprintf("foreign_key %q", fk.Name), attributes)
+	}
+
+	for _, idx := range table.Indexes {
+		var attributes []atlasAttribute
+		if idx.IsUnique {
+			attributes = append(attributes, atlasAttribute{Name: "unique", Value: "true"})
+		}
+		attributes = append(attributes, atlasAttribute{Name: "columns", Value: atlasColumnList(idx.Columns)})
+		if idx.Method != "" {
+			attributes = append(attributes, atlasAttribute{Name: "type", Value: strings.ToUpper(idx.Method)})
+		}
+		writeAtlasBlock(sb, fmt.Sprintf("index %q", idx.Name), attributes)
+	}
+
+	for _, uc := range table.UniqueConstraints {
+		writeAtlasBlock(sb, fmt.Sprintf("unique %q", uc.Name), []atlasAttribute{{Name: "columns", Value: atlasColumnList(uc.Columns)}})
+	}
+
+	sb.WriteString("}\n")
+}
+
+// writeAtlasBlock writes a nested block with its attributes aligned on the
+// equals sign, as atlas schema inspect does
+func writeAtlasBlock(sb *strings.Builder, header string, attributes []atlasAttribute) {
+	width := 0
+	for _, attribute := range attributes {
+		width = max(width, len(attribute.Name))
+	}
+
+	sb.WriteString(fmt.Sprintf("  %s {\n", header))
+	for _, attribute := range attributes {
+		sb.WriteString(fmt.Sprintf("    %-*s = %s\n", width, attribute.Name, attribute.Value))
+	}
+	sb.WriteString("  }\n")
+}
+
+// atlasType maps a column to an Atlas type expression. Atlas spells
+// PostgreSQL types with underscores instead of spaces and keeps their
+// parameters; types it does not model are wrapped in sql().
+func atlasType(col Column) string {
+	if serial, ok := serialTypes[col.DataType]; ok && col.IsSerial {
+		return serial
+	}
+
+	switch col.DataType {
+	case "character varying", "character", "bit", "bit varying":
+		if col.CharacterLength.Valid {
+			return fmt.Sprintf("%s(%d)", strings.ReplaceAll(col.DataType, " ", "_"), col.CharacterLength.Int64)
+		}
+		return strings.ReplaceAll(col.DataType, " ", "_")
+	case "numeric", "decimal":
+		if col.NumericPrecision.Valid && col.NumericScale.Valid {
+			return fmt.Sprintf("numeric(%d,%d)", col.NumericPrecision.Int64, col.NumericScale.Int64)
+		} else if col.NumericPrecision.Valid {
+			return fmt.Sprintf("numeric(%d)", col.NumericPrecision.Int64)
+		}
+		return "numeric"
+	case "timestamp without time zone":
+		return withDatetimePrecision("timestamp", col)
+	case "timestamp with time zone":
+		return withDatetimePrecision("timestamptz", col)
+	case "time without time zone":
+		return withDatetimePrecision("time", col)
+	case "time with time zone":
+		return withDatetimePrecision("timetz", col)
+	case "double precision":
+		return "double_precision"
+	case "smallint", "integer", "bigint", "real", "boolean", "text", "date", "interval",
+		"uuid", "json", "jsonb", "xml", "bytea", "money", "inet", "cidr", "macaddr",
+		"tsvector", "tsquery":
+		return col.DataType
+	default:
+		fullType := col.FullType
+		if fullType == "" {
+			fullType = col.DataType
+		}
+		return fmt.Sprintf("sql(%q)", fullType)
+	}
+}
+
+var (
+	atlasNumberPattern     = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+	atlasStringPattern     = regexp.MustCompile(`^'((?:[^']|'')*)'::(?:text|character varying|bpchar|character)$`)
+	atlasIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// atlasDefault renders a column default: numbers, booleans and string
+// literals as HCL values, other expressions wrapped in sql()
+func atlasDefault(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "true" || value == "false" || atlasNumberPattern.MatchString(value) {
+		return value
+	}
+	if match := atlasStringPattern.FindStringSubmatch(value); match != nil {
+		return fmt.Sprintf("%q", strings.ReplaceAll(match[1], "''", "'"))
+	}
+	return fmt.Sprintf("sql(%q)", value)
+}
+
+// atlasColumnList renders column references such as [column.a, column.b]
+func atlasColumnList(columns []string) string {
+	references := make([]string, len(columns))
+	for i, column := range columns {
+		references[i] = "column" + atlasReference(column)
+	}
+	return "[" + strings.Join(references, ", ") + "]"
+}
+
+// atlasReference returns the step of a reference traversal selecting name:
+// .name, or ["name"] when it is not a plain identifier
+func atlasReference(name string) string {
+	if atlasIdentifierPattern.MatchString(name) {
+		return "." + name
+	}
+	return fmt.Sprintf("[%q]", name)
+}
@@ -0,0 +1,54 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// catalogRelation is a relation read by getTables, with its fields exported
+// for the catalog dump
+type catalogRelation struct {
+	Name    string   `json:"name"`
+	IsView  bool     `json:"is_view"`
+	OID     uint32   `json:"oid"`
+	Options []string `json:"options"`
+}
+
+// catalogDump holds the rows the table, column and index queries returned,
+// before they were assembled into tables
+type catalogDump struct {
+	Schema  string              `json:"schema"`
+	Tables  []catalogRelation   `json:"tables"`
+	Columns map[string][]Column `json:"columns"`
+	Indexes map[string][]Index  `json:"indexes"`
+}
+
+// newCatalogDump returns a dump of the relations of schemaName, to which
+// the columns and indexes are added as they are read
+func newCatalogDump(schemaName string, relations []relation) *catalogDump {
+	dump := &catalogDump{
+		Schema:  schemaName,
+		Tables:  make([]catalogRelation, len(relations)),
+		Columns: make(map[string][]Column, len(relations)),
+		Indexes: make(map[string][]Index, len(relations)),
+	}
+	for i, relation := range relations {
+		dump.Tables[i] = catalogRelation{Name: relation.name, IsView: relation.isView, OID: relation.oid, Options: relation.options}
+	}
+	return dump
+}
+
+// write writes the dump to w as indented JSON. Columns are written as
+// scanned, with nullable fields as {"String": ..., "Valid": ...} objects, so
+// that mapping bugs can be told apart from extraction bugs.
+func (d *catalogDump) write(w io.Writer) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode catalog: %w", err)
+	}
+	if _, err := fmt.Fprintf(w, "%s\n", data); err != nil {
+		return fmt.Errorf("failed to dump catalog: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package providers
+
+// ANSI escape codes used by colorized info output
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiYellow = "\x1b[33m"
+)
+
+// colorizer wraps text in ANSI escape codes when enabled and returns it
+// unchanged otherwise, so that plain output is not affected by coloring
+type colorizer bool
+
+func (c colorizer) wrap(code, s string) string {
+	if !c || s == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// bold is used for table names
+func (c colorizer) bold(s string) string {
+	return c.wrap(ansiBold, s)
+}
+
+// highlight is used for primary key columns
+func (c colorizer) highlight(s string) string {
+	return c.wrap(ansiBold+ansiYellow, s)
+}
+
+// dim is used for NULL
+func (c colorizer) dim(s string) string {
+	return c.wrap(ansiDim, s)
+}
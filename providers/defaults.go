@@ -0,0 +1,116 @@
+package providers
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// literalCastPattern matches a default that is a string literal followed by a
+// cast, such as 'active'::character varying or '{}'::jsonb. It is anchored to
+// the whole default: a cast inside a larger expression, as in
+// ('1'::int + x), changes its meaning and is kept.
+var literalCastPattern = regexp.MustCompile(`^('(?:[^']|'')*')::[a-z_][a-z0-9_]*(?: [a-z_][a-z0-9_]*)*(?:\([0-9, ]*\))?(?:\[\])*$`)
+
+// serialTypes maps integer types to the serial pseudo-type that creates them
+var serialTypes = map[string]string{
+	"smallint": "smallserial",
+	"integer":  "serial",
+	"bigint":   "bigserial",
+}
+
+// normalizeDefaults returns a copy of tables with column defaults simplified
+// to read like hand-written DDL: a cast on a default that is a string literal
+// is dropped. Serial columns are rendered as such whether or not defaults are
+// normalized, see Column.IsSerial.
+func normalizeDefaults(tables []Table) []Table {
+	normalized := make([]Table, len(tables))
+	for i, table := range tables {
+		normalized[i] = table
+		normalized[i].Columns = normalizeColumnDefaults(table.Columns)
+	}
+	return normalized
+}
+
+// normalizeColumnDefaults returns a copy of columns with their defaults
+// simplified as normalizeDefaults does
+func normalizeColumnDefaults(columns []Column) []Column {
+	normalized := make([]Column, len(columns))
+	for i, col := range columns {
+		if col.DefaultValue.Valid {
+			col.DefaultValue.String = literalCastPattern.ReplaceAllString(col.DefaultValue.String, "$1")
+		}
+		normalized[i] = col
+	}
+	return normalized
+}
+
+// unqualifiedSchema is the schema whose qualification unqualifyDefault drops.
+// Only the public schema is extracted, and it is on the default search_path.
+const unqualifiedSchema = "public"
+
+// unqualifyDefaults returns columns with unqualifyDefault applied to their
+// defaults, or columns itself when no default changes
+func unqualifyDefaults(columns []Column) []Column {
+	var unqualified []Column
+	for i, col := range columns {
+		if !col.DefaultValue.Valid {
+			continue
+		}
+		value := unqualifyDefault(col.DefaultValue.String)
+		if value == col.DefaultValue.String {
+			continue
+		}
+		if unqualified == nil {
+			unqualified = slices.Clone(columns)
+		}
+		unqualified[i].DefaultValue.String = value
+	}
+	if unqualified == nil {
+		return columns
+	}
+	return unqualified
+}
+
+// unqualifyDefault drops the public. qualification of the functions, types
+// and operators a default expression refers to, such as
+// public.gen_random_uuid() or 'draft'::public.status, which PostgreSQL adds
+// when public was not on the search_path. String literals, such as the
+// sequence name in nextval('public.users_id_seq'::regclass), are kept as
+// they are, escape strings such as E'it\'s' included. The rest of the
+// expression is not changed.
+func unqualifyDefault(expr string) string {
+	if !strings.Contains(expr, unqualifiedSchema+".") && !strings.Contains(expr, unqualifiedSchema+`".`) {
+		return expr
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		if c == '\'' {
+			end, _ := skipQuoted(expr, i, c)
+			sb.WriteString(expr[i:end])
+			i = end
+			continue
+		}
+		if i == 0 || !isIdentifierByte(expr[i-1]) {
+			if rest, ok := strings.CutPrefix(expr[i:], unqualifiedSchema+"."); ok && rest != "" {
+				i += len(unqualifiedSchema) + 1
+				continue
+			}
+			if rest, ok := strings.CutPrefix(expr[i:], `"`+unqualifiedSchema+`".`); ok && rest != "" {
+				i += len(unqualifiedSchema) + 3
+				continue
+			}
+		}
+		sb.WriteByte(c)
+		i++
+	}
+	return sb.String()
+}
+
+// isIdentifierByte reports whether c can be part of an unquoted identifier
+// or a qualified name, so that my_public.f() or x.public.f() are left alone
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c == '$' || c == '.' || c == '"' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c >= 0x80
+}
@@ -0,0 +1,485 @@
+package providers
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SchemaDiff describes the structural changes that turn one schema into another
+type SchemaDiff struct {
+	AddedTables    []Table
+	RemovedTables  []Table
+	ModifiedTables []TableDiff
+}
+
+// TableDiff describes the changes to a table present in both schemas
+type TableDiff struct {
+	Name                     string
+	AddedColumns             []Column
+	RemovedColumns           []Column
+	ChangedColumns           []ColumnChange
+	OldPrimaryKey            []string
+	NewPrimaryKey            []string
+	AddedIndexes             []Index
+	RemovedIndexes           []Index
+	AddedUniqueConstraints   []UniqueConstraint
+	RemovedUniqueConstraints []UniqueConstraint
+	AddedExclusions          []ExclusionConstraint
+	RemovedExclusions        []ExclusionConstraint
+	AddedForeignKeys         []ForeignKey
+	RemovedForeignKeys       []ForeignKey
+	// AddedTriggers and RemovedTriggers are matched by name; a trigger whose
+	// definition changed is in both
+	AddedTriggers   []Trigger
+	RemovedTriggers []Trigger
+	// ColumnOrderChanged is set when the columns present in both versions
+	// appear in a different order, such as after a column was dropped and
+	// added again
+	ColumnOrderChanged bool
+	// OldPrimaryKeyName is the name of the primary key constraint being
+	// replaced, empty when it is not known
+	OldPrimaryKeyName string
+}
+
+// ColumnChange holds both versions of a column whose definition changed
+type ColumnChange struct {
+	Name   string
+	Before Column
+	After  Column
+}
+
+// IsEmpty reports whether the diff contains no changes
+func (d SchemaDiff) IsEmpty() bool {
+	return len(d.AddedTables) == 0 && len(d.RemovedTables) == 0 && len(d.ModifiedTables) == 0
+}
+
+// primaryKeyName returns the name of the primary key being replaced. Tables
+// read without it, such as from a JSON target schema, fall back to the name
+// PostgreSQL gives an unnamed primary key.
+func (d TableDiff) primaryKeyName() string {
+	if d.OldPrimaryKeyName != "" {
+		return d.OldPrimaryKeyName
+	}
+	return d.Name + "_pkey"
+}
+
+// PrimaryKeyChanged reports whether the primary key columns differ
+func (d TableDiff) PrimaryKeyChanged() bool {
+	return !slices.Equal(d.OldPrimaryKey, d.NewPrimaryKey)
+}
+
+func (d TableDiff) isEmpty() bool {
+	return len(d.AddedColumns) == 0 && len(d.RemovedColumns) == 0 && len(d.ChangedColumns) == 0 &&
+		!d.PrimaryKeyChanged() &&
+		len(d.AddedIndexes) == 0 && len(d.RemovedIndexes) == 0 &&
+		len(d.AddedUniqueConstraints) == 0 && len(d.RemovedUniqueConstraints) == 0 &&
+		len(d.AddedExclusions) == 0 && len(d.RemovedExclusions) == 0 &&
+		len(d.AddedForeignKeys) == 0 && len(d.RemovedForeignKeys) == 0 &&
+		len(d.AddedTriggers) == 0 && len(d.RemovedTriggers) == 0 &&
+		!d.ColumnOrderChanged
+}
+
+// DiffSchemas compares two schemas and returns the changes needed to go from
+// the first to the second. Tables, columns and constraints are matched by
+// name; a renamed object shows up as a removal plus an addition.
+func DiffSchemas(from, to []Table) SchemaDiff {
+	var diff SchemaDiff
+	from, to = normalizeSchema(from), normalizeSchema(to)
+
+	fromTables := make(map[string]Table, len(from))
+	for _, table := range from {
+		fromTables[table.Name] = table
+	}
+	toTables := make(map[string]Table, len(to))
+	for _, table := range to {
+		toTables[table.Name] = table
+	}
+
+	for _, table := range to {
+		before, exists := fromTables[table.Name]
+		if !exists {
+			diff.AddedTables = append(diff.AddedTables, table)
+			continue
+		}
+		if tableDiff := diffTable(before, table); !tableDiff.isEmpty() {
+			diff.ModifiedTables = append(diff.ModifiedTables, tableDiff)
+		}
+	}
+
+	for _, table := range from {
+		if _, exists := toTables[table.Name]; !exists {
+			diff.RemovedTables = append(diff.RemovedTables, table)
+		}
+	}
+
+	sort.Slice(diff.AddedTables, func(i, j int) bool { return diff.AddedTables[i].Name < diff.AddedTables[j].Name })
+	sort.Slice(diff.RemovedTables, func(i, j int) bool { return diff.RemovedTables[i].Name < diff.RemovedTables[j].Name })
+	sort.Slice(diff.ModifiedTables, func(i, j int) bool { return diff.ModifiedTables[i].Name < diff.ModifiedTables[j].Name })
+
+	return diff
+}
+
+func diffTable(from, to Table) TableDiff {
+	diff := TableDiff{
+		Name:              to.Name,
+		OldPrimaryKey:     primaryKeyColumns(from),
+		NewPrimaryKey:     primaryKeyColumns(to),
+		OldPrimaryKeyName: from.PrimaryKeyName,
+	}
+
+	fromColumns := make(map[string]Column, len(from.Columns))
+	for _, col := range from.Columns {
+		fromColumns[col.Name] = col
+	}
+	toColumns := make(map[string]bool, len(to.Columns))
+	for _, col := range to.Columns {
+		toColumns[col.Name] = true
+		before, exists := fromColumns[col.Name]
+		if !exists {
+			diff.AddedColumns = append(diff.AddedColumns, col)
+			continue
+		}
+		if !columnsEqual(before, col) {
+			diff.ChangedColumns = append(diff.ChangedColumns, ColumnChange{Name: col.Name, Before: before, After: col})
+		}
+	}
+	var fromOrder []string
+	for _, col := range from.Columns {
+		if !toColumns[col.Name] {
+			diff.RemovedColumns = append(diff.RemovedColumns, col)
+			continue
+		}
+		fromOrder = append(fromOrder, col.Name)
+	}
+	var toOrder []string
+	for _, col := range to.Columns {
+		if _, exists := fromColumns[col.Name]; exists {
+			toOrder = append(toOrder, col.Name)
+		}
+	}
+	diff.ColumnOrderChanged = !slices.Equal(fromOrder, toOrder)
+
+	diff.AddedIndexes, diff.RemovedIndexes = diffNamed(from.Indexes, to.Indexes,
+		func(idx Index) string { return idx.Name },
+		func(a, b Index) bool {
+			return a.IsUnique == b.IsUnique && a.Method == b.Method && slices.Equal(a.Columns, b.Columns)
+		})
+	diff.AddedUniqueConstraints, diff.RemovedUniqueConstraints = diffNamed(from.UniqueConstraints, to.UniqueConstraints,
+		func(uc UniqueConstraint) string { return uc.Name },
+		func(a, b UniqueConstraint) bool { return slices.Equal(a.Columns, b.Columns) })
+	diff.AddedExclusions, diff.RemovedExclusions = diffNamed(from.ExclusionConstraints, to.ExclusionConstraints,
+		func(ec ExclusionConstraint) string { return ec.Name },
+		func(a, b ExclusionConstraint) bool { return a.definition(false) == b.definition(false) })
+	diff.AddedForeignKeys, diff.RemovedForeignKeys = diffNamed(from.ForeignKeys, to.ForeignKeys,
+		func(fk ForeignKey) string { return fk.Name },
+		func(a, b ForeignKey) bool {
+			return a.ReferencedTable == b.ReferencedTable &&
+				slices.Equal(a.Columns, b.Columns) && slices.Equal(a.ReferencedColumns, b.ReferencedColumns) &&
+				a.referentialActions(false) == b.referentialActions(false)
+		})
+	diff.AddedTriggers, diff.RemovedTriggers = diffNamed(from.Triggers, to.Triggers,
+		func(trigger Trigger) string { return trigger.Name },
+		func(a, b Trigger) bool { return a.Definition == b.Definition })
+
+	return diff
+}
+
+// diffNamed matches objects by name and returns those added and removed. An
+// object whose definition changed is reported as both removed and added.
+func diffNamed[T any](from, to []T, name func(T) string, equal func(a, b T) bool) (added, removed []T) {
+	fromByName := make(map[string]T, len(from))
+	for _, item := range from {
+		fromByName[name(item)] = item
+	}
+	toByName := make(map[string]T, len(to))
+	for _, item := range to {
+		toByName[name(item)] = item
+	}
+
+	for _, item := range to {
+		if before, exists := fromByName[name(item)]; !exists || !equal(before, item) {
+			added = append(added, item)
+		}
+	}
+	for _, item := range from {
+		if after, exists := toByName[name(item)]; !exists || !equal(item, after) {
+			removed = append(removed, item)
+		}
+	}
+	return added, removed
+}
+
+func columnsEqual(a, b Column) bool {
+	return storageType(a) == storageType(b) &&
+		a.IsNullable == b.IsNullable &&
+		a.DefaultValue == b.DefaultValue &&
+		a.Collation == b.Collation
+}
+
+func primaryKeyColumns(table Table) []string {
+	var columns []string
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey {
+			columns = append(columns, col.Name)
+		}
+	}
+	return columns
+}
+
+// FormatMigrationSQL renders the statements that apply a diff. Statements are
+// ordered so that dependencies are respected: foreign keys, triggers and
+// indexes that go away are dropped first, new tables are created with
+// referenced tables ahead of referencing ones, their foreign keys to modified
+// tables are added after the ALTER statements that may add the referenced
+// key, triggers are created once the tables and keys exist, and tables are
+// dropped last. Operations that can
+// lose data are preceded by a "-- DESTRUCTIVE:" comment.
+func FormatMigrationSQL(diff SchemaDiff) string {
+	return FormatMigrationSQLWithOptions(diff, FormatOptions{})
+}
+
+// FormatMigrationSQLWithOptions renders a diff like FormatMigrationSQL. New
+// tables are written as FormatSchemaSQLWithOptions writes them, with their
+// partitions, grants and triggers, and the type map and NormalizeDefaults
+// also apply to the columns added to or changed in existing tables.
+// IfNotExists and Clean are ignored: the ALTER statements cannot be guarded.
+func FormatMigrationSQLWithOptions(diff SchemaDiff, opts FormatOptions) string {
+	var sb strings.Builder
+	opts.IfNotExists, opts.Clean = false, false
+	if opts.NormalizeDefaults {
+		diff = normalizeDiffDefaults(diff)
+	}
+
+	for _, td := range diff.ModifiedTables {
+		for _, trigger := range td.RemovedTriggers {
+			sb.WriteString(fmt.Sprintf("drop trigger %s on %s;\n", quoteIdent(trigger.Name), quoteIdent(td.Name)))
+		}
+	}
+	for _, td := range diff.ModifiedTables {
+		for _, fk := range td.RemovedForeignKeys {
+			sb.WriteString(fmt.Sprintf("alter table %s drop constraint %s;\n", quoteIdent(td.Name), quoteIdent(fk.Name)))
+		}
+	}
+	for _, table := range diff.RemovedTables {
+		for _, fk := range table.ForeignKeys {
+			sb.WriteString(fmt.Sprintf("alter table %s drop constraint %s;\n", quoteIdent(table.Name), quoteIdent(fk.Name)))
+		}
+	}
+	for _, td := range diff.ModifiedTables {
+		for _, uc := range td.RemovedUniqueConstraints {
+			sb.WriteString(fmt.Sprintf("alter table %s drop constraint %s;\n", quoteIdent(td.Name), quoteIdent(uc.Name)))
+		}
+		for _, ec := range td.RemovedExclusions {
+			sb.WriteString(fmt.Sprintf("alter table %s drop constraint %s;\n", quoteIdent(td.Name), quoteIdent(ec.Name)))
+		}
+		for _, idx := range td.RemovedIndexes {
+			sb.WriteString(fmt.Sprintf("drop index %s;\n", quoteIdent(idx.Name)))
+		}
+		if td.PrimaryKeyChanged() && len(td.OldPrimaryKey) > 0 {
+			sb.WriteString(fmt.Sprintf("alter table %s drop constraint %s;\n", quoteIdent(td.Name), quoteIdent(td.primaryKeyName())))
+		}
+	}
+	separate(&sb)
+
+	ordered, deferred := orderTablesByDependency(diff.AddedTables, opts.TableOrder == TableOrderCreated)
+	if opts.ForeignKeyStyle == ForeignKeyAlter {
+		deferred = allForeignKeys(ordered)
+	} else {
+		deferred = append(deferred, foreignKeysToModifiedTables(ordered, diff.ModifiedTables, deferred)...)
+	}
+	omitted := omittedForeignKeys(deferred)
+	for _, table := range ordered {
+		if table.IsPartition() {
+			writeCreatePartition(&sb, table, false)
+			writeGrants(&sb, table)
+			continue
+		}
+		writeCreateTable(&sb, table, omitted[table.Name], opts)
+		writeCreateIndexes(&sb, table, false)
+		writeGrants(&sb, table)
+	}
+
+	for _, td := range diff.ModifiedTables {
+		table := quoteIdent(td.Name)
+		for _, col := range td.AddedColumns {
+			sb.WriteString(fmt.Sprintf("alter table %s add column %s;\n", table, columnDefinition(col, opts.TypeMap)))
+		}
+		for _, change := range td.ChangedColumns {
+			writeColumnChange(&sb, td.Name, change, opts.TypeMap)
+		}
+		if td.PrimaryKeyChanged() && len(td.NewPrimaryKey) > 0 {
+			sb.WriteString(fmt.Sprintf("alter table %s add primary key (%s);\n", table, quoteIdents(td.NewPrimaryKey)))
+		}
+		for _, uc := range td.AddedUniqueConstraints {
+			sb.WriteString(fmt.Sprintf("alter table %s add constraint %s unique (%s);\n", table, quoteIdent(uc.Name), quoteIdents(uc.Columns)))
+		}
+		for _, ec := range td.AddedExclusions {
+			sb.WriteString(fmt.Sprintf("alter table %s add constraint %s %s;\n", table, quoteIdent(ec.Name), ec.definition(false)))
+		}
+		for _, idx := range td.AddedIndexes {
+			sb.WriteString(createIndexStatement(td.Name, idx, false))
+		}
+		if td.ColumnOrderChanged {
+			sb.WriteString(fmt.Sprintf("-- column order of %s differs; columns cannot be reordered in place\n", td.Name))
+		}
+	}
+	separate(&sb)
+
+	for _, td := range diff.ModifiedTables {
+		for _, fk := range td.AddedForeignKeys {
+			sb.WriteString(fmt.Sprintf("alter table %s add %s;\n", quoteIdent(td.Name), foreignKeyDefinition(fk)))
+		}
+	}
+	writeDeferredForeignKeys(&sb, deferred, false)
+	separate(&sb)
+
+	// Partitions get the triggers of their parent when they are attached
+	for _, table := range ordered {
+		if !table.IsPartition() {
+			writeCreateTriggers(&sb, table, false)
+		}
+	}
+	for _, td := range diff.ModifiedTables {
+		for _, trigger := range td.AddedTriggers {
+			sb.WriteString(trigger.Definition + ";\n")
+		}
+	}
+	separate(&sb)
+
+	for _, td := range diff.ModifiedTables {
+		for _, col := range td.RemovedColumns {
+			sb.WriteString(fmt.Sprintf("-- DESTRUCTIVE: drops column %s.%s and its data\n", td.Name, col.Name))
+			sb.WriteString(fmt.Sprintf("alter table %s drop column %s;\n", quoteIdent(td.Name), quoteIdent(col.Name)))
+		}
+	}
+	for i := len(diff.RemovedTables) - 1; i >= 0; i-- {
+		table := diff.RemovedTables[i]
+		sb.WriteString(fmt.Sprintf("-- DESTRUCTIVE: drops table %s and all of its data\n", table.Name))
+		sb.WriteString(fmt.Sprintf("drop table %s;\n", quoteIdent(table.Name)))
+	}
+
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
+
+// foreignKeysToModifiedTables returns the foreign keys of added tables that
+// reference a modified table and are not deferred yet. The key or column they
+// reference may be added by the ALTER statements that follow CREATE TABLE.
+func foreignKeysToModifiedTables(tables []Table, modified []TableDiff, deferred []deferredForeignKey) []deferredForeignKey {
+	names := make(map[string]bool, len(modified))
+	for _, td := range modified {
+		names[td.Name] = true
+	}
+	omitted := omittedForeignKeys(deferred)
+
+	var more []deferredForeignKey
+	for _, table := range tables {
+		if table.IsPartition() {
+			continue
+		}
+		for _, fk := range table.ForeignKeys {
+			if names[fk.ReferencedTable] && !omitted[table.Name][fk.Name] {
+				more = append(more, deferredForeignKey{Table: table.Name, ForeignKey: fk})
+			}
+		}
+	}
+	return more
+}
+
+func writeColumnChange(sb *strings.Builder, tableName string, change ColumnChange, typeMap TypeMap) {
+	table := quoteIdent(tableName)
+	column := quoteIdent(change.Name)
+	before, after := change.Before, change.After
+
+	// Changing only the collation keeps the data; without a collate clause
+	// the column gets the default collation of its type
+	typeChanged := storageType(before) != storageType(after)
+	if typeChanged || before.Collation != after.Collation {
+		if typeChanged {
+			sb.WriteString(fmt.Sprintf("-- DESTRUCTIVE: changing %s.%s from %s to %s may fail or lose data\n",
+				tableName, change.Name, sqlStorageType(before, nil), sqlStorageType(after, nil)))
+		}
+		collation := ""
+		if after.Collation != "" {
+			collation = " collate " + quoteCollation(after.Collation)
+		}
+		sb.WriteString(fmt.Sprintf("alter table %s alter column %s type %s%s;\n", table, column, sqlStorageType(after, typeMap), collation))
+	}
+
+	if before.DefaultValue != after.DefaultValue {
+		// A column becoming serial needs the sequence its default uses
+		if sequence, ok := serialSequence(after); ok {
+			sb.WriteString(fmt.Sprintf("create sequence %s owned by %s.%s;\n", sequence, table, column))
+		}
+		if after.DefaultValue.Valid {
+			sb.WriteString(fmt.Sprintf("alter table %s alter column %s set default %s;\n", table, column, after.DefaultValue.String))
+		} else {
+			sb.WriteString(fmt.Sprintf("alter table %s alter column %s drop default;\n", table, column))
+		}
+	}
+
+	if before.IsNullable != after.IsNullable {
+		if after.IsNullable {
+			sb.WriteString(fmt.Sprintf("alter table %s alter column %s drop not null;\n", table, column))
+		} else {
+			sb.WriteString(fmt.Sprintf("alter table %s alter column %s set not null;\n", table, column))
+		}
+	}
+}
+
+// storageType is the type a column is stored as. Serial types are only a
+// shorthand for an integer type defaulting to an owned sequence, so a column
+// gaining or losing its sequence keeps its type.
+func storageType(col Column) string {
+	col.IsSerial = false
+	return mapDataType(col, nil)
+}
+
+// sqlStorageType is storageType as written in SQL output, with the
+// overrides of typeMap
+func sqlStorageType(col Column, typeMap TypeMap) string {
+	col.IsSerial = false
+	return sqlDataType(col, typeMap)
+}
+
+// normalizeDiffDefaults returns a copy of diff with the defaults of the
+// tables and columns it adds or changes normalized, see normalizeDefaults
+func normalizeDiffDefaults(diff SchemaDiff) SchemaDiff {
+	diff.AddedTables = normalizeDefaults(diff.AddedTables)
+	modified := make([]TableDiff, len(diff.ModifiedTables))
+	for i, td := range diff.ModifiedTables {
+		td.AddedColumns = normalizeColumnDefaults(td.AddedColumns)
+		td.ChangedColumns = slices.Clone(td.ChangedColumns)
+		for j, change := range td.ChangedColumns {
+			columns := normalizeColumnDefaults([]Column{change.Before, change.After})
+			td.ChangedColumns[j].Before, td.ChangedColumns[j].After = columns[0], columns[1]
+		}
+		modified[i] = td
+	}
+	diff.ModifiedTables = modified
+	return diff
+}
+
+// serialSequence returns the sequence the default of a serial column takes
+// its values from, as nextval names it
+func serialSequence(col Column) (string, bool) {
+	if !col.IsSerial || !col.DefaultValue.Valid {
+		return "", false
+	}
+	name, ok := strings.CutPrefix(col.DefaultValue.String, "nextval('")
+	if !ok {
+		return "", false
+	}
+	if name, ok = strings.CutSuffix(name, "'::regclass)"); !ok {
+		return "", false
+	}
+	return strings.ReplaceAll(name, "''", "'"), true
+}
+
+// separate ends the current group of statements with a blank line
+func separate(sb *strings.Builder) {
+	if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n\n") {
+		sb.WriteString("\n")
+	}
+}
@@ -0,0 +1,149 @@
+package providers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DiffCategory is a kind of change reported in a SchemaDiff
+type DiffCategory string
+
+const (
+	// DiffTables covers added and removed tables
+	DiffTables DiffCategory = "tables"
+	// DiffColumns covers added and removed columns and changes to their type,
+	// nullability and collation
+	DiffColumns DiffCategory = "columns"
+	// DiffDefaults covers changes to column defaults
+	DiffDefaults DiffCategory = "defaults"
+	// DiffIndexes covers added, removed and redefined indexes
+	DiffIndexes DiffCategory = "indexes"
+	// DiffConstraints covers primary keys, unique and exclusion constraints
+	// and foreign keys
+	DiffConstraints DiffCategory = "constraints"
+	// DiffColumnOrder covers columns present in both schemas whose order
+	// changed
+	DiffColumnOrder DiffCategory = "column-order"
+	// DiffTriggers covers added, removed and redefined triggers
+	DiffTriggers DiffCategory = "triggers"
+	// DiffComments covers comments on tables and columns. Comments are not
+	// extracted, so there is nothing to report in this category yet.
+	DiffComments DiffCategory = "comments"
+)
+
+// DiffCategories lists every category, in the order they are documented
+var DiffCategories = []DiffCategory{
+	DiffTables, DiffColumns, DiffDefaults, DiffIndexes, DiffConstraints, DiffColumnOrder, DiffTriggers, DiffComments,
+}
+
+// DiffFilter selects the categories of changes kept in a SchemaDiff. A change
+// is kept when its category is in Only, or Only is empty, and not in Ignore.
+type DiffFilter struct {
+	Only   []DiffCategory
+	Ignore []DiffCategory
+}
+
+// NewDiffFilter parses the category names of an allowlist and an ignore list
+func NewDiffFilter(only, ignore []string) (DiffFilter, error) {
+	var filter DiffFilter
+	var err error
+	if filter.Only, err = parseDiffCategories(only); err != nil {
+		return DiffFilter{}, err
+	}
+	if filter.Ignore, err = parseDiffCategories(ignore); err != nil {
+		return DiffFilter{}, err
+	}
+	return filter, nil
+}
+
+func parseDiffCategories(names []string) ([]DiffCategory, error) {
+	var categories []DiffCategory
+	for _, name := range names {
+		category := DiffCategory(strings.ToLower(strings.TrimSpace(name)))
+		if !slices.Contains(DiffCategories, category) {
+			valid := make([]string, len(DiffCategories))
+			for i, c := range DiffCategories {
+				valid[i] = string(c)
+			}
+			return nil, fmt.Errorf("unknown diff category %q, expected one of %s", name, strings.Join(valid, ", "))
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+// IsEmpty reports whether the filter keeps every change
+func (f DiffFilter) IsEmpty() bool {
+	return len(f.Only) == 0 && len(f.Ignore) == 0
+}
+
+// Keeps reports whether changes of the category are kept
+func (f DiffFilter) Keeps(category DiffCategory) bool {
+	if len(f.Only) > 0 && !slices.Contains(f.Only, category) {
+		return false
+	}
+	return !slices.Contains(f.Ignore, category)
+}
+
+// Apply returns the diff without the changes of the filtered out categories.
+// A modified table left without changes is dropped from the diff, and a
+// changed column whose remaining differences are all filtered out is no
+// longer reported. The input is not modified.
+func (f DiffFilter) Apply(diff SchemaDiff) SchemaDiff {
+	if f.IsEmpty() {
+		return diff
+	}
+
+	var filtered SchemaDiff
+	if f.Keeps(DiffTables) {
+		filtered.AddedTables = diff.AddedTables
+		filtered.RemovedTables = diff.RemovedTables
+	}
+
+	for _, td := range diff.ModifiedTables {
+		kept := TableDiff{Name: td.Name}
+		if f.Keeps(DiffColumns) {
+			kept.AddedColumns = td.AddedColumns
+			kept.RemovedColumns = td.RemovedColumns
+		}
+		for _, change := range td.ChangedColumns {
+			if change, ok := f.filterColumnChange(change); ok {
+				kept.ChangedColumns = append(kept.ChangedColumns, change)
+			}
+		}
+		if f.Keeps(DiffConstraints) {
+			kept.OldPrimaryKey, kept.NewPrimaryKey = td.OldPrimaryKey, td.NewPrimaryKey
+			kept.AddedUniqueConstraints, kept.RemovedUniqueConstraints = td.AddedUniqueConstraints, td.RemovedUniqueConstraints
+			kept.AddedExclusions, kept.RemovedExclusions = td.AddedExclusions, td.RemovedExclusions
+			kept.AddedForeignKeys, kept.RemovedForeignKeys = td.AddedForeignKeys, td.RemovedForeignKeys
+		}
+		if f.Keeps(DiffIndexes) {
+			kept.AddedIndexes, kept.RemovedIndexes = td.AddedIndexes, td.RemovedIndexes
+		}
+		if f.Keeps(DiffTriggers) {
+			kept.AddedTriggers, kept.RemovedTriggers = td.AddedTriggers, td.RemovedTriggers
+		}
+		kept.ColumnOrderChanged = td.ColumnOrderChanged && f.Keeps(DiffColumnOrder)
+
+		if !kept.isEmpty() {
+			filtered.ModifiedTables = append(filtered.ModifiedTables, kept)
+		}
+	}
+	return filtered
+}
+
+// filterColumnChange removes the filtered out differences from a column
+// change by copying them from the old version, and reports whether any
+// difference is left
+func (f DiffFilter) filterColumnChange(change ColumnChange) (ColumnChange, bool) {
+	if !f.Keeps(DiffDefaults) {
+		change.Before.DefaultValue = change.After.DefaultValue
+	}
+	if !f.Keeps(DiffColumns) {
+		defaultValue := change.Before.DefaultValue
+		change.Before = change.After
+		change.Before.DefaultValue = defaultValue
+	}
+	return change, !columnsEqual(change.Before, change.After)
+}
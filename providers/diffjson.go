@@ -0,0 +1,166 @@
+package providers
+
+import "encoding/json"
+
+// DiffReport is the JSON form of a SchemaDiff. Its fields are part of the
+// documented output, so existing names must not change. Lists are never
+// null: a category without changes is an empty array.
+type DiffReport struct {
+	From           string            `json:"from"`
+	To             string            `json:"to"`
+	AddedTables    []DiffTable       `json:"added_tables"`
+	RemovedTables  []DiffTable       `json:"removed_tables"`
+	ModifiedTables []DiffTableChange `json:"modified_tables"`
+}
+
+// DiffTable is a table added or removed as a whole
+type DiffTable struct {
+	Name    string       `json:"name"`
+	Columns []DiffColumn `json:"columns"`
+}
+
+// DiffColumn describes a column. Type is rendered as in SQL output, such as
+// varchar(255); Default and Collation are null when the column has none.
+type DiffColumn struct {
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Nullable  bool    `json:"nullable"`
+	Default   *string `json:"default"`
+	Collation *string `json:"collation"`
+}
+
+// DiffColumnChange holds both versions of a column whose definition changed
+type DiffColumnChange struct {
+	Name   string     `json:"name"`
+	Before DiffColumn `json:"before"`
+	After  DiffColumn `json:"after"`
+}
+
+// DiffPrimaryKeyChange holds the primary key columns before and after. A
+// table without a primary key has an empty list.
+type DiffPrimaryKeyChange struct {
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+}
+
+// DiffTableChange lists the changes to a table present in both schemas.
+// PrimaryKey is null when the primary key did not change.
+type DiffTableChange struct {
+	Name                     string                `json:"name"`
+	AddedColumns             []DiffColumn          `json:"added_columns"`
+	RemovedColumns           []DiffColumn          `json:"removed_columns"`
+	ChangedColumns           []DiffColumnChange    `json:"changed_columns"`
+	ColumnOrderChanged       bool                  `json:"column_order_changed"`
+	PrimaryKey               *DiffPrimaryKeyChange `json:"primary_key"`
+	AddedIndexes             []Index               `json:"added_indexes"`
+	RemovedIndexes           []Index               `json:"removed_indexes"`
+	AddedUniqueConstraints   []UniqueConstraint    `json:"added_unique_constraints"`
+	RemovedUniqueConstraints []UniqueConstraint    `json:"removed_unique_constraints"`
+	AddedExclusions          []ExclusionConstraint `json:"added_exclusion_constraints"`
+	RemovedExclusions        []ExclusionConstraint `json:"removed_exclusion_constraints"`
+	AddedForeignKeys         []ForeignKey          `json:"added_foreign_keys"`
+	RemovedForeignKeys       []ForeignKey          `json:"removed_foreign_keys"`
+	AddedTriggers            []Trigger             `json:"added_triggers"`
+	RemovedTriggers          []Trigger             `json:"removed_triggers"`
+}
+
+// NewDiffReport converts a diff between the schemas after the migrations
+// from and to into its JSON form
+func NewDiffReport(from, to string, diff SchemaDiff) DiffReport {
+	report := DiffReport{
+		From:           from,
+		To:             to,
+		AddedTables:    make([]DiffTable, 0, len(diff.AddedTables)),
+		RemovedTables:  make([]DiffTable, 0, len(diff.RemovedTables)),
+		ModifiedTables: make([]DiffTableChange, 0, len(diff.ModifiedTables)),
+	}
+	for _, table := range diff.AddedTables {
+		report.AddedTables = append(report.AddedTables, newDiffTable(table))
+	}
+	for _, table := range diff.RemovedTables {
+		report.RemovedTables = append(report.RemovedTables, newDiffTable(table))
+	}
+	for _, td := range diff.ModifiedTables {
+		report.ModifiedTables = append(report.ModifiedTables, newDiffTableChange(td))
+	}
+	return report
+}
+
+// FormatDiffJSON renders the diff between the schemas after the migrations
+// from and to as an indented DiffReport
+func FormatDiffJSON(from, to string, diff SchemaDiff) (string, error) {
+	data, err := json.MarshalIndent(NewDiffReport(from, to, diff), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+func newDiffTable(table Table) DiffTable {
+	return DiffTable{Name: table.Name, Columns: newDiffColumns(table.Columns)}
+}
+
+func newDiffTableChange(td TableDiff) DiffTableChange {
+	change := DiffTableChange{
+		Name:                     td.Name,
+		AddedColumns:             newDiffColumns(td.AddedColumns),
+		RemovedColumns:           newDiffColumns(td.RemovedColumns),
+		ChangedColumns:           make([]DiffColumnChange, 0, len(td.ChangedColumns)),
+		ColumnOrderChanged:       td.ColumnOrderChanged,
+		AddedIndexes:             nonNil(td.AddedIndexes),
+		RemovedIndexes:           nonNil(td.RemovedIndexes),
+		AddedUniqueConstraints:   nonNil(td.AddedUniqueConstraints),
+		RemovedUniqueConstraints: nonNil(td.RemovedUniqueConstraints),
+		AddedExclusions:          nonNil(td.AddedExclusions),
+		RemovedExclusions:        nonNil(td.RemovedExclusions),
+		AddedForeignKeys:         nonNil(td.AddedForeignKeys),
+		RemovedForeignKeys:       nonNil(td.RemovedForeignKeys),
+		AddedTriggers:            nonNil(td.AddedTriggers),
+		RemovedTriggers:          nonNil(td.RemovedTriggers),
+	}
+	for _, column := range td.ChangedColumns {
+		change.ChangedColumns = append(change.ChangedColumns, DiffColumnChange{
+			Name:   column.Name,
+			Before: newDiffColumn(column.Before),
+			After:  newDiffColumn(column.After),
+		})
+	}
+	if td.PrimaryKeyChanged() {
+		change.PrimaryKey = &DiffPrimaryKeyChange{Before: nonNil(td.OldPrimaryKey), After: nonNil(td.NewPrimaryKey)}
+	}
+	return change
+}
+
+func newDiffColumns(columns []Column) []DiffColumn {
+	converted := make([]DiffColumn, 0, len(columns))
+	for _, col := range columns {
+		converted = append(converted, newDiffColumn(col))
+	}
+	return converted
+}
+
+func newDiffColumn(col Column) DiffColumn {
+	column := DiffColumn{
+		Name:     col.Name,
+		Type:     sqlDataType(col, nil),
+		Nullable: col.IsNullable,
+	}
+	if col.DefaultValue.Valid {
+		value := col.DefaultValue.String
+		column.Default = &value
+	}
+	if col.Collation != "" {
+		collation := col.Collation
+		column.Collation = &collation
+	}
+	return column
+}
+
+// nonNil returns items, or an empty slice when it is nil, so that it is
+// encoded as [] rather than null
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,144 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatDiffReport describes a diff for review, one line per change: added
+// (+), removed (-) and modified (~) tables, and below a modified table its
+// changed columns, keys, indexes and constraints
+func FormatDiffReport(diff SchemaDiff) string {
+	var sb strings.Builder
+	for _, table := range diff.AddedTables {
+		sb.WriteString(fmt.Sprintf("+ table %s\n", table.Name))
+	}
+	for _, table := range diff.RemovedTables {
+		sb.WriteString(fmt.Sprintf("- table %s\n", table.Name))
+	}
+	for _, td := range diff.ModifiedTables {
+		sb.WriteString(fmt.Sprintf("~ table %s\n", td.Name))
+		for _, col := range td.AddedColumns {
+			sb.WriteString(fmt.Sprintf("    + column %s %s\n", col.Name, describeColumn(col)))
+		}
+		for _, col := range td.RemovedColumns {
+			sb.WriteString(fmt.Sprintf("    - column %s %s\n", col.Name, describeColumn(col)))
+		}
+		for _, change := range td.ChangedColumns {
+			sb.WriteString(fmt.Sprintf("    ~ column %s: %s\n", change.Name, describeColumnChange(change)))
+		}
+		if td.ColumnOrderChanged {
+			sb.WriteString("    ~ column order\n")
+		}
+		if td.PrimaryKeyChanged() {
+			sb.WriteString(fmt.Sprintf("    ~ primary key %s -> %s\n", describeKey(td.OldPrimaryKey), describeKey(td.NewPrimaryKey)))
+		}
+		for _, idx := range td.AddedIndexes {
+			sb.WriteString(fmt.Sprintf("    + index %s\n", describeIndex(idx)))
+		}
+		for _, idx := range td.RemovedIndexes {
+			sb.WriteString(fmt.Sprintf("    - index %s\n", describeIndex(idx)))
+		}
+		for _, uc := range td.AddedUniqueConstraints {
+			sb.WriteString(fmt.Sprintf("    + unique constraint %s (%s)\n", uc.Name, strings.Join(uc.Columns, ", ")))
+		}
+		for _, uc := range td.RemovedUniqueConstraints {
+			sb.WriteString(fmt.Sprintf("    - unique constraint %s (%s)\n", uc.Name, strings.Join(uc.Columns, ", ")))
+		}
+		for _, ec := range td.AddedExclusions {
+			sb.WriteString(fmt.Sprintf("    + exclusion constraint %s %s\n", ec.Name, ec.definition(false)))
+		}
+		for _, ec := range td.RemovedExclusions {
+			sb.WriteString(fmt.Sprintf("    - exclusion constraint %s %s\n", ec.Name, ec.definition(false)))
+		}
+		for _, fk := range td.AddedForeignKeys {
+			sb.WriteString(fmt.Sprintf("    + foreign key %s\n", describeForeignKey(fk)))
+		}
+		for _, fk := range td.RemovedForeignKeys {
+			sb.WriteString(fmt.Sprintf("    - foreign key %s\n", describeForeignKey(fk)))
+		}
+		for _, trigger := range td.AddedTriggers {
+			sb.WriteString(fmt.Sprintf("    + trigger %s\n", trigger.Name))
+		}
+		for _, trigger := range td.RemovedTriggers {
+			sb.WriteString(fmt.Sprintf("    - trigger %s\n", trigger.Name))
+		}
+	}
+	return sb.String()
+}
+
+// describeColumn renders the type, nullability and default of a column
+func describeColumn(col Column) string {
+	parts := []string{sqlDataType(col, nil)}
+	if !col.IsNullable {
+		parts = append(parts, "not null")
+	}
+	if col.DefaultValue.Valid {
+		parts = append(parts, "default "+col.DefaultValue.String)
+	}
+	return strings.Join(parts, " ")
+}
+
+// describeColumnChange lists the differences between both versions of a
+// column as before -> after
+func describeColumnChange(change ColumnChange) string {
+	before, after := change.Before, change.After
+	var changes []string
+	if beforeType, afterType := sqlStorageType(before, nil), sqlStorageType(after, nil); beforeType != afterType {
+		changes = append(changes, fmt.Sprintf("type %s -> %s", beforeType, afterType))
+	}
+	if before.IsNullable != after.IsNullable {
+		changes = append(changes, fmt.Sprintf("%s -> %s", describeNullable(before), describeNullable(after)))
+	}
+	if before.DefaultValue != after.DefaultValue {
+		changes = append(changes, fmt.Sprintf("default %s -> %s", describeDefault(before), describeDefault(after)))
+	}
+	if before.Collation != after.Collation {
+		changes = append(changes, fmt.Sprintf("collation %s -> %s", describeCollation(before), describeCollation(after)))
+	}
+	return strings.Join(changes, ", ")
+}
+
+func describeNullable(col Column) string {
+	if col.IsNullable {
+		return "null"
+	}
+	return "not null"
+}
+
+func describeDefault(col Column) string {
+	if !col.DefaultValue.Valid {
+		return "none"
+	}
+	return col.DefaultValue.String
+}
+
+func describeCollation(col Column) string {
+	if col.Collation == "" {
+		return "default"
+	}
+	return col.Collation
+}
+
+func describeKey(columns []string) string {
+	if len(columns) == 0 {
+		return "none"
+	}
+	return "(" + strings.Join(columns, ", ") + ")"
+}
+
+func describeIndex(idx Index) string {
+	description := fmt.Sprintf("%s (%s)", idx.Name, strings.Join(idx.Columns, ", "))
+	if idx.IsUnique {
+		description += " unique"
+	}
+	if idx.Method != "" {
+		description += " using " + idx.Method
+	}
+	return description
+}
+
+func describeForeignKey(fk ForeignKey) string {
+	return fmt.Sprintf("%s (%s) references %s (%s)",
+		fk.Name, strings.Join(fk.Columns, ", "), fk.ReferencedTable, strings.Join(fk.ReferencedColumns, ", "))
+}
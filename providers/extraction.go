@@ -3,154 +3,1004 @@ package providers
 import (
 	"database/sql"
 	"fmt"
+	"io"
 	"log/slog"
+	"slices"
+	"strconv"
 	"strings"
+
+	"github.com/lib/pq"
 )
 
 // ExtractSchemaFromDB extracts schema using SQL queries
 func ExtractSchemaFromDB(db *sql.DB) ([]Table, error) {
-	slog.Debug("starting schema extraction")
-	tables, err := getTables(db)
+	return ExtractTables(db, "public", false)
+}
+
+// batchThreshold is the number of tables above which the columns and
+// indexes of all tables are read with one query each rather than one query
+// per table, since the round-trips dominate for many small tables
+const batchThreshold = 50
+
+// ExtractTables extracts the tables of schemaName, and its views as well
+// when includeViews is set. Views are marked with IsView and have columns
+// only. Relations created by an extension are left out.
+func ExtractTables(db *sql.DB, schemaName string, includeViews bool) ([]Table, error) {
+	return extractTables(db, schemaName, includeViews, ExtractOptions{}, batchThreshold)
+}
+
+// extractTables is ExtractTables reading columns and indexes in batches when
+// there are more than threshold tables, with the options of opts
+func extractTables(db *sql.DB, schemaName string, includeViews bool, opts ExtractOptions, threshold int) ([]Table, error) {
+	slog.Debug("starting schema extraction", "schema", schemaName)
+	relations, err := getTables(db, schemaName, includeViews, opts.IncludeExtensionObjects)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tables: %w", err)
 	}
-	slog.Info("found database tables", "count", len(tables), "tables", tables)
+	slog.Info("found database tables", "count", len(relations), "tables", relationNames(relations))
+
+	var dump *catalogDump
+	if opts.CatalogDump != nil {
+		dump = newCatalogDump(schemaName, relations)
+	}
+
+	loadColumns, loadIndexes := getColumns, getIndexes
+	if len(relations) > threshold {
+		if loadColumns, loadIndexes, err = batchLoaders(db, schemaName, relations); err != nil {
+			return nil, err
+		}
+	}
 
 	var schema []Table
-	for _, tableName := range tables {
+	for _, relation := range relations {
+		tableName := relation.name
 		slog.Debug("processing table", "table", tableName)
 
-		columns, err := getColumns(db, tableName)
+		columns, err := loadColumns(db, schemaName, tableName)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get columns for table %s: %w", tableName, err)
 		}
 		slog.Debug("found table columns", "table", tableName, "count", len(columns))
 
-		indexes, err := getIndexes(db, tableName)
+		indexes, err := loadIndexes(db, schemaName, tableName)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get indexes for table %s: %w", tableName, err)
 		}
 		slog.Debug("found table indexes", "table", tableName, "count", len(indexes))
 
-		schema = append(schema, Table{
-			Name:    tableName,
-			Columns: columns,
-			Indexes: indexes,
-		})
+		if dump != nil {
+			dump.Columns[tableName] = slices.Clone(columns)
+			dump.Indexes[tableName] = slices.Clone(indexes)
+		}
+
+		uniqueConstraints, err := getUniqueConstraints(db, schemaName, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get unique constraints for table %s: %w", tableName, err)
+		}
+		slog.Debug("found table unique constraints", "table", tableName, "count", len(uniqueConstraints))
+
+		exclusionConstraints, err := getExclusionConstraints(db, schemaName, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get exclusion constraints for table %s: %w", tableName, err)
+		}
+		slog.Debug("found table exclusion constraints", "table", tableName, "count", len(exclusionConstraints))
+
+		foreignKeys, err := getForeignKeys(db, schemaName, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get foreign keys for table %s: %w", tableName, err)
+		}
+		slog.Debug("found table foreign keys", "table", tableName, "count", len(foreignKeys))
+
+		triggers, err := getTriggers(db, schemaName, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get triggers for table %s: %w", tableName, err)
+		}
+		slog.Debug("found table triggers", "table", tableName, "count", len(triggers))
+
+		table := Table{
+			Name:                 tableName,
+			IsView:               relation.isView,
+			ObjectID:             relation.oid,
+			PrimaryKeyName:       relation.primaryKey,
+			Columns:              columns,
+			Indexes:              indexes,
+			UniqueConstraints:    uniqueConstraints,
+			ForeignKeys:          foreignKeys,
+			Triggers:             triggers,
+			ExclusionConstraints: exclusionConstraints,
+		}
+		if !relation.isView {
+			if err := getPartitioning(db, schemaName, &table); err != nil {
+				return nil, fmt.Errorf("failed to get partitioning for table %s: %w", tableName, err)
+			}
+			if table.Inherits, err = getInherits(db, schemaName, tableName); err != nil {
+				return nil, fmt.Errorf("failed to get inherited tables for table %s: %w", tableName, err)
+			}
+			table.StorageParams = parseStorageParams(relation.options)
+		}
+		schema = append(schema, table)
+	}
+
+	if dump != nil {
+		if err := dump.write(opts.CatalogDump); err != nil {
+			return nil, err
+		}
 	}
 
 	slog.Info("schema extraction completed", "tables", len(schema))
 	return schema, nil
 }
 
-func getTables(db *sql.DB) ([]string, error) {
+// batchLoaders reads the columns and indexes of all relations up front and
+// returns loaders serving them per table
+func batchLoaders(db *sql.DB, schemaName string, relations []relation) (
+	func(*sql.DB, string, string) ([]Column, error), func(*sql.DB, string, string) ([]Index, error), error) {
+	names := relationNames(relations)
+	slog.Debug("reading columns and indexes in batches", "tables", len(names))
+
+	columns, err := getColumnsByTable(db, schemaName, names)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+	indexes, err := getIndexesByTable(db, schemaName, names)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get indexes: %w", err)
+	}
+
+	loadColumns := func(_ *sql.DB, _, tableName string) ([]Column, error) {
+		return columns[tableName], nil
+	}
+	loadIndexes := func(_ *sql.DB, _, tableName string) ([]Index, error) {
+		return indexes[tableName], nil
+	}
+	return loadColumns, loadIndexes, nil
+}
+
+// ExtractOptions controls what ExtractFullSchemaWithOptions reads
+type ExtractOptions struct {
+	// IncludeExtensionObjects keeps the tables, views and sequences that an
+	// extension created in the schema, such as spatial_ref_sys and
+	// geometry_columns of postgis. The functions and types of extensions
+	// are always left out, since CREATE EXTENSION creates them again.
+	IncludeExtensionObjects bool
+
+	// CatalogDump receives the table, column and index rows the extraction
+	// read, as they were scanned, when set. It is meant for debugging.
+	CatalogDump io.Writer
+}
+
+// ExtractFullSchema extracts the tables of the public schema together with
+// its views, enum types, sequences, user-defined functions and procedures
+// and the installed extensions. Objects created by an extension are left out.
+func ExtractFullSchema(db *sql.DB) (Schema, error) {
+	return ExtractFullSchemaWithOptions(db, ExtractOptions{})
+}
+
+// ExtractFullSchemaWithOptions extracts the schema like ExtractFullSchema
+func ExtractFullSchemaWithOptions(db *sql.DB, opts ExtractOptions) (Schema, error) {
+	tables, err := extractTables(db, "public", false, opts, batchThreshold)
+	if err != nil {
+		return Schema{}, err
+	}
+
+	views, err := getViews(db, "public", opts.IncludeExtensionObjects)
+	if err != nil {
+		return Schema{}, fmt.Errorf("failed to get views: %w", err)
+	}
+	slog.Debug("found views", "count", len(views))
+
+	enums, err := getEnums(db, "public")
+	if err != nil {
+		return Schema{}, fmt.Errorf("failed to get enums: %w", err)
+	}
+	slog.Debug("found enums", "count", len(enums))
+
+	compositeTypes, err := getCompositeTypes(db, "public")
+	if err != nil {
+		return Schema{}, fmt.Errorf("failed to get composite types: %w", err)
+	}
+	slog.Debug("found composite types", "count", len(compositeTypes))
+
+	sequences, err := getSequences(db, "public", opts.IncludeExtensionObjects)
+	if err != nil {
+		return Schema{}, fmt.Errorf("failed to get sequences: %w", err)
+	}
+	slog.Debug("found sequences", "count", len(sequences))
+
+	functions, err := getFunctions(db, "public")
+	if err != nil {
+		return Schema{}, fmt.Errorf("failed to get functions: %w", err)
+	}
+	slog.Debug("found functions", "count", len(functions))
+
+	extensions, err := getExtensions(db)
+	if err != nil {
+		return Schema{}, fmt.Errorf("failed to get extensions: %w", err)
+	}
+	slog.Debug("found extensions", "count", len(extensions))
+
+	version, err := getServerVersion(db)
+	if err != nil {
+		return Schema{}, fmt.Errorf("failed to get server version: %w", err)
+	}
+	slog.Debug("found server version", "version", FormatServerVersion(version))
+
+	return Schema{
+		ServerVersion:  version,
+		Extensions:     extensions,
+		Enums:          enums,
+		Sequences:      sequences,
+		Tables:         tables,
+		Views:          views,
+		Functions:      functions,
+		CompositeTypes: compositeTypes,
+	}, nil
+}
+
+// getServerVersion returns server_version_num, such as 160004 for 16.4.
+// Unlike server_version it is a plain number on every build.
+func getServerVersion(db *sql.DB) (int, error) {
+	var version string
+	if err := db.QueryRow("SHOW server_version_num").Scan(&version); err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(version)
+}
+
+// relation is a table or view found by getTables
+type relation struct {
+	name   string
+	isView bool
+	oid    uint32
+	// options are the reloptions of the relation, such as fillfactor=70
+	options []string
+	// primaryKey is the name of the primary key constraint, if any
+	primaryKey string
+}
+
+// relationNames returns the names of relations, in order
+func relationNames(relations []relation) []string {
+	names := make([]string, len(relations))
+	for i, relation := range relations {
+		names[i] = relation.name
+	}
+	return names
+}
+
+func getTables(db *sql.DB, schemaName string, includeViews, includeExtensionObjects bool) ([]relation, error) {
 	query := `
-		SELECT table_name 
-		FROM information_schema.tables 
-		WHERE table_schema = 'public' 
-		AND table_type = 'BASE TABLE'
+		SELECT table_name, table_type = 'VIEW', pc.oid, COALESCE(pc.reloptions, '{}'),
+			COALESCE((SELECT conname FROM pg_constraint WHERE conrelid = pc.oid AND contype = 'p'), '')
+		FROM information_schema.tables
+		JOIN pg_class pc ON pc.oid = format('%I.%I', table_schema, table_name)::regclass
+		WHERE table_schema = $1 
+		AND (table_type = 'BASE TABLE' OR ($2 AND table_type = 'VIEW'))
+		AND ($3 OR NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.classid = 'pg_class'::regclass
+			AND d.objid = pc.oid
+			AND d.deptype = 'e'
+		))
 		ORDER BY table_name
 	`
 
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, schemaName, includeViews, includeExtensionObjects)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var tables []string
+	var tables []relation
 	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
+		var table relation
+		if err := rows.Scan(&table.name, &table.isView, &table.oid, pq.Array(&table.options), &table.primaryKey); err != nil {
 			return nil, err
 		}
-		tables = append(tables, tableName)
+		tables = append(tables, table)
 	}
 
 	return tables, rows.Err()
 }
 
-func getColumns(db *sql.DB, tableName string) ([]Column, error) {
+func getColumns(db *sql.DB, schemaName, tableName string) ([]Column, error) {
+	columns, err := getColumnsByTable(db, schemaName, []string{tableName})
+	return columns[tableName], err
+}
+
+// getColumnsByTable reads the columns of the given tables, keyed by table
+func getColumnsByTable(db *sql.DB, schemaName string, tableNames []string) (map[string][]Column, error) {
 	query := `
 		SELECT 
+			c.table_name,
 			c.column_name,
 			c.data_type,
 			c.is_nullable = 'YES' as is_nullable,
 			c.column_default,
-			COALESCE(tc.constraint_type = 'PRIMARY KEY', false) as is_primary_key,
+			EXISTS (
+				SELECT 1
+				FROM information_schema.key_column_usage kcu
+				JOIN information_schema.table_constraints tc ON
+					kcu.constraint_name = tc.constraint_name
+					AND kcu.constraint_schema = tc.constraint_schema
+				WHERE tc.constraint_type = 'PRIMARY KEY'
+				AND kcu.table_schema = c.table_schema
+				AND kcu.table_name = c.table_name
+				AND kcu.column_name = c.column_name
+			) as is_primary_key,
 			c.character_maximum_length,
 			c.numeric_precision,
-			c.numeric_scale
+			c.numeric_scale,
+			c.datetime_precision,
+			c.udt_name,
+			format_type(a.atttypid, a.atttypmod),
+			COALESCE(c.column_default = format(
+				'nextval(%L::regclass)',
+				pg_get_serial_sequence(format('%I.%I', c.table_schema, c.table_name), c.column_name)::regclass::text
+			), false) as is_serial,
+			c.ordinal_position,
+			NOT a.attislocal as is_inherited,
+			COALESCE(CASE WHEN a.attcollation <> t.typcollation THEN co.collname END, '') as collation,
+			t.typtype = 'c' as is_composite
 		FROM information_schema.columns c
-		LEFT JOIN information_schema.key_column_usage kcu ON 
-			c.table_name = kcu.table_name AND c.column_name = kcu.column_name
-		LEFT JOIN information_schema.table_constraints tc ON 
-			kcu.constraint_name = tc.constraint_name AND tc.constraint_type = 'PRIMARY KEY'
-		WHERE c.table_name = $1 AND c.table_schema = 'public'
-		ORDER BY c.ordinal_position
+		JOIN pg_attribute a ON
+			a.attrelid = format('%I.%I', c.table_schema, c.table_name)::regclass
+			AND a.attname = c.column_name
+		JOIN pg_type t ON t.oid = a.atttypid
+		LEFT JOIN pg_collation co ON co.oid = a.attcollation
+		WHERE c.table_name = ANY($2) AND c.table_schema = $1
+		ORDER BY c.table_name, c.ordinal_position
 	`
 
-	rows, err := db.Query(query, tableName)
+	rows, err := db.Query(query, schemaName, pq.Array(tableNames))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var columns []Column
+	columns := make(map[string][]Column)
 	for rows.Next() {
+		var tableName string
 		var col Column
 		var defaultValue sql.NullString
+		var udtName string
 
-		if err := rows.Scan(&col.Name, &col.DataType, &col.IsNullable, &defaultValue, &col.IsPrimaryKey, &col.CharacterLength, &col.NumericPrecision, &col.NumericScale); err != nil {
+		if err := rows.Scan(&tableName, &col.Name, &col.DataType, &col.IsNullable, &defaultValue, &col.IsPrimaryKey, &col.CharacterLength, &col.NumericPrecision, &col.NumericScale, &col.DatetimePrecision, &udtName, &col.FullType, &col.IsSerial, &col.OrdinalPosition, &col.IsInherited, &col.Collation, &col.IsComposite); err != nil {
 			return nil, err
 		}
 
+		// Extension and custom types are reported as USER-DEFINED; use the
+		// actual type name so the formatter can recognize them
+		if col.DataType == "USER-DEFINED" {
+			col.DataType = udtName
+		}
+
 		col.DefaultValue = defaultValue
-		columns = append(columns, col)
+		columns[tableName] = append(columns[tableName], col)
 	}
 
 	return columns, rows.Err()
 }
 
-func getIndexes(db *sql.DB, tableName string) ([]Index, error) {
+func getIndexes(db *sql.DB, schemaName, tableName string) ([]Index, error) {
+	indexes, err := getIndexesByTable(db, schemaName, []string{tableName})
+	return indexes[tableName], err
+}
+
+// getIndexesByTable reads the indexes of the given tables, keyed by table
+func getIndexesByTable(db *sql.DB, schemaName string, tableNames []string) (map[string][]Index, error) {
 	query := `
 		SELECT 
+			i.tablename,
 			i.indexname,
-			array_agg(a.attname ORDER BY a.attnum) as columns,
-			i.indexdef LIKE '%UNIQUE%' as is_unique
+			array_agg(a.attname::text ORDER BY k.ord) as columns,
+			i.indexdef LIKE '%UNIQUE%' as is_unique,
+			am.amname
 		FROM pg_indexes i
-		JOIN pg_class c ON c.relname = i.tablename
-		JOIN pg_index idx ON idx.indexrelid = (
-			SELECT oid FROM pg_class WHERE relname = i.indexname
-		)
-		JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = ANY(idx.indkey)
-		WHERE i.tablename = $1 
-		AND i.schemaname = 'public'
+		JOIN pg_class c ON c.oid = format('%I.%I', i.schemaname, i.tablename)::regclass
+		JOIN pg_index idx ON idx.indexrelid = format('%I.%I', i.schemaname, i.indexname)::regclass
+		JOIN pg_class ic ON ic.oid = idx.indexrelid
+		JOIN pg_am am ON am.oid = ic.relam
+		CROSS JOIN LATERAL unnest(idx.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = k.attnum
+		WHERE i.tablename = ANY($2)
+		AND i.schemaname = $1
 		AND NOT idx.indisprimary
-		GROUP BY i.indexname, i.indexdef
-		ORDER BY i.indexname
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_constraint con
+			WHERE con.conindid = idx.indexrelid AND con.contype IN ('u', 'x')
+		)
+		GROUP BY i.tablename, i.indexname, i.indexdef, am.amname
+		ORDER BY i.tablename, i.indexname
 	`
 
-	rows, err := db.Query(query, tableName)
+	rows, err := db.Query(query, schemaName, pq.Array(tableNames))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var indexes []Index
+	indexes := make(map[string][]Index)
 	for rows.Next() {
+		var tableName string
 		var index Index
-		var columnsArray string
 
-		if err := rows.Scan(&index.Name, &columnsArray, &index.IsUnique); err != nil {
+		if err := rows.Scan(&tableName, &index.Name, pq.Array(&index.Columns), &index.IsUnique, &index.Method); err != nil {
 			return nil, err
 		}
+		if index.Method == defaultIndexMethod {
+			index.Method = ""
+		}
 
-		columnsArray = strings.Trim(columnsArray, "{}")
-		index.Columns = strings.Split(columnsArray, ",")
-
-		indexes = append(indexes, index)
+		indexes[tableName] = append(indexes[tableName], index)
 	}
 
 	return indexes, rows.Err()
-}
\ No newline at end of file
+}
+
+func getUniqueConstraints(db *sql.DB, schemaName, tableName string) ([]UniqueConstraint, error) {
+	query := `
+		SELECT
+			tc.constraint_name,
+			kcu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu ON
+			kcu.constraint_name = tc.constraint_name
+			AND kcu.constraint_schema = tc.constraint_schema
+			AND kcu.table_name = tc.table_name
+		WHERE tc.table_name = $2
+		AND tc.table_schema = $1
+		AND tc.constraint_type = 'UNIQUE'
+		ORDER BY tc.constraint_name, kcu.ordinal_position
+	`
+
+	rows, err := db.Query(query, schemaName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var constraints []UniqueConstraint
+	for rows.Next() {
+		var constraintName, columnName string
+		if err := rows.Scan(&constraintName, &columnName); err != nil {
+			return nil, err
+		}
+
+		if n := len(constraints); n > 0 && constraints[n-1].Name == constraintName {
+			constraints[n-1].Columns = append(constraints[n-1].Columns, columnName)
+			continue
+		}
+		constraints = append(constraints, UniqueConstraint{
+			Name:    constraintName,
+			Columns: []string{columnName},
+		})
+	}
+
+	return constraints, rows.Err()
+}
+
+// getExclusionConstraints reads the EXCLUDE constraints of a table, one row
+// per element. Elements are rendered by pg_get_indexdef so that expressions
+// such as tstzrange(starts_at, ends_at) come out as they would be written.
+func getExclusionConstraints(db *sql.DB, schemaName, tableName string) ([]ExclusionConstraint, error) {
+	query := `
+		SELECT
+			con.conname,
+			am.amname,
+			pg_get_indexdef(con.conindid, k.ord::int, true),
+			op.oprname,
+			coalesce(pg_get_expr(idx.indpred, idx.indrelid, true), '')
+		FROM pg_constraint con
+		JOIN pg_class t ON t.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_class ic ON ic.oid = con.conindid
+		JOIN pg_am am ON am.oid = ic.relam
+		JOIN pg_index idx ON idx.indexrelid = con.conindid
+		CROSS JOIN LATERAL unnest(con.conexclop) WITH ORDINALITY AS k(opoid, ord)
+		JOIN pg_operator op ON op.oid = k.opoid
+		WHERE con.contype = 'x'
+		AND n.nspname = $1
+		AND t.relname = $2
+		ORDER BY con.conname, k.ord
+	`
+
+	rows, err := db.Query(query, schemaName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var constraints []ExclusionConstraint
+	for rows.Next() {
+		var constraint ExclusionConstraint
+		var element ExclusionElement
+		if err := rows.Scan(&constraint.Name, &constraint.Method, &element.Expression, &element.Operator, &constraint.Where); err != nil {
+			return nil, err
+		}
+
+		if n := len(constraints); n > 0 && constraints[n-1].Name == constraint.Name {
+			constraints[n-1].Elements = append(constraints[n-1].Elements, element)
+			continue
+		}
+		constraint.Elements = []ExclusionElement{element}
+		constraints = append(constraints, constraint)
+	}
+
+	return constraints, rows.Err()
+}
+
+func getForeignKeys(db *sql.DB, schemaName, tableName string) ([]ForeignKey, error) {
+	query := `
+		SELECT
+			con.conname,
+			array_agg(a.attname::text ORDER BY k.ord) as columns,
+			rt.relname,
+			array_agg(ra.attname::text ORDER BY k.ord) as referenced_columns,
+			con.confdeltype,
+			con.confupdtype
+		FROM pg_constraint con
+		JOIN pg_class t ON t.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_class rt ON rt.oid = con.confrelid
+		CROSS JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(attnum, refattnum, ord)
+		JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+		JOIN pg_attribute ra ON ra.attrelid = con.confrelid AND ra.attnum = k.refattnum
+		WHERE con.contype = 'f'
+		AND n.nspname = $1
+		AND t.relname = $2
+		GROUP BY con.conname, rt.relname, con.confdeltype, con.confupdtype
+		ORDER BY con.conname
+	`
+
+	rows, err := db.Query(query, schemaName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []ForeignKey
+	for rows.Next() {
+		var fk ForeignKey
+		var onDelete, onUpdate string
+
+		if err := rows.Scan(&fk.Name, pq.Array(&fk.Columns), &fk.ReferencedTable, pq.Array(&fk.ReferencedColumns), &onDelete, &onUpdate); err != nil {
+			return nil, err
+		}
+
+		fk.OnDelete = referentialAction(onDelete)
+		fk.OnUpdate = referentialAction(onUpdate)
+
+		foreignKeys = append(foreignKeys, fk)
+	}
+
+	return foreignKeys, rows.Err()
+}
+
+// referentialAction spells a pg_constraint action code the way
+// information_schema.referential_constraints does. The view itself is not
+// joined since constraint names are only unique per table, not per schema.
+func referentialAction(code string) string {
+	switch code {
+	case "c":
+		return "CASCADE"
+	case "n":
+		return "SET NULL"
+	case "d":
+		return "SET DEFAULT"
+	case "r":
+		return "RESTRICT"
+	default:
+		return foreignKeyNoAction
+	}
+}
+
+// getTriggers reads the triggers of a table. information_schema.triggers
+// returns one row per event, so rows of a multi-event trigger (INSERT OR
+// UPDATE) are merged into a single Trigger.
+func getTriggers(db *sql.DB, schemaName, tableName string) ([]Trigger, error) {
+	query := `
+		SELECT
+			t.trigger_name,
+			t.action_timing,
+			t.event_manipulation,
+			p.proname,
+			pg_get_triggerdef(pt.oid)
+		FROM information_schema.triggers t
+		JOIN pg_trigger pt ON
+			pt.tgname = t.trigger_name
+			AND pt.tgrelid = format('%I.%I', t.event_object_schema, t.event_object_table)::regclass
+		JOIN pg_proc p ON p.oid = pt.tgfoid
+		WHERE t.event_object_table = $2
+		AND t.event_object_schema = $1
+		ORDER BY t.trigger_name, t.event_manipulation
+	`
+
+	rows, err := db.Query(query, schemaName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var triggers []Trigger
+	for rows.Next() {
+		var trigger Trigger
+		var event string
+
+		if err := rows.Scan(&trigger.Name, &trigger.Timing, &event, &trigger.FunctionName, &trigger.Definition); err != nil {
+			return nil, err
+		}
+
+		if n := len(triggers); n > 0 && triggers[n-1].Name == trigger.Name {
+			triggers[n-1].Events = append(triggers[n-1].Events, event)
+			continue
+		}
+
+		trigger.Table = tableName
+		trigger.Events = []string{event}
+		triggers = append(triggers, trigger)
+	}
+
+	return triggers, rows.Err()
+}
+
+// getPartitioning fills in the partition strategy and key of a partitioned
+// table, and the parent and bound of a partition. Tables that use classic
+// inheritance are not partitions: relispartition is only set for partitions.
+func getPartitioning(db *sql.DB, schemaName string, table *Table) error {
+	query := `
+		SELECT
+			COALESCE(pg_get_partkeydef(c.oid), ''),
+			COALESCE(parent.relname, ''),
+			COALESCE(pg_get_expr(c.relpartbound, c.oid), '')
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_inherits i ON i.inhrelid = c.oid AND c.relispartition
+		LEFT JOIN pg_class parent ON parent.oid = i.inhparent
+		WHERE n.nspname = $1
+		AND c.relname = $2
+	`
+
+	var keyDef string
+	err := db.QueryRow(query, schemaName, table.Name).Scan(&keyDef, &table.PartitionOf, &table.PartitionBound)
+	if err != nil {
+		return err
+	}
+
+	table.PartitionStrategy, table.PartitionKey = parsePartitionKey(keyDef)
+	return nil
+}
+
+// getInherits returns the parents of a table using classic inheritance, in
+// declaration order. pg_inherits also links partitions to their parent;
+// those rows are skipped since getPartitioning covers them.
+func getInherits(db *sql.DB, schemaName, tableName string) ([]string, error) {
+	query := `
+		SELECT parent.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_class parent ON parent.oid = i.inhparent
+		WHERE n.nspname = $1
+		AND c.relname = $2
+		AND NOT c.relispartition
+		ORDER BY i.inhseqno
+	`
+
+	rows, err := db.Query(query, schemaName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parents []string
+	for rows.Next() {
+		var parent string
+		if err := rows.Scan(&parent); err != nil {
+			return nil, err
+		}
+		parents = append(parents, parent)
+	}
+
+	return parents, rows.Err()
+}
+
+// parseStorageParams turns reloptions such as fillfactor=70 into a map, nil
+// when there are none
+func parseStorageParams(options []string) map[string]string {
+	if len(options) == 0 {
+		return nil
+	}
+	params := make(map[string]string, len(options))
+	for _, option := range options {
+		name, value, _ := strings.Cut(option, "=")
+		params[name] = value
+	}
+	return params
+}
+
+// parsePartitionKey splits the output of pg_get_partkeydef, such as
+// "RANGE (created_at)", into a lower-case strategy and the key
+func parsePartitionKey(keyDef string) (strategy, key string) {
+	strategy, key, found := strings.Cut(keyDef, " ")
+	if !found {
+		return "", ""
+	}
+	key = strings.TrimSpace(key)
+	key = strings.TrimPrefix(key, "(")
+	key = strings.TrimSuffix(key, ")")
+	return strings.ToLower(strategy), key
+}
+
+// getFunctions reads the functions and procedures defined in schemaName.
+// Aggregates and window functions are skipped, as are functions owned by an
+// extension since they are recreated by CREATE EXTENSION.
+func getFunctions(db *sql.DB, schemaName string) ([]Function, error) {
+	query := `
+		SELECT
+			p.proname,
+			CASE p.prokind WHEN 'p' THEN 'procedure' ELSE 'function' END,
+			pg_get_function_identity_arguments(p.oid),
+			pg_get_functiondef(p.oid)
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = $1
+		AND p.prokind IN ('f', 'p')
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.classid = 'pg_proc'::regclass
+			AND d.objid = p.oid
+			AND d.deptype = 'e'
+		)
+		ORDER BY p.proname, pg_get_function_identity_arguments(p.oid)
+	`
+
+	rows, err := db.Query(query, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var functions []Function
+	for rows.Next() {
+		var function Function
+		if err := rows.Scan(&function.Name, &function.Kind, &function.Arguments, &function.Definition); err != nil {
+			return nil, err
+		}
+		function.Definition = strings.TrimRight(function.Definition, "\n")
+		functions = append(functions, function)
+	}
+
+	return functions, rows.Err()
+}
+
+// getViews reads the views of schemaName in creation order, so that a view
+// built on another one comes after it. Views owned by an extension are
+// skipped.
+func getViews(db *sql.DB, schemaName string, includeExtensionObjects bool) ([]View, error) {
+	query := `
+		SELECT c.relname, pg_get_viewdef(c.oid)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1
+		AND c.relkind = 'v'
+		AND ($2 OR NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.classid = 'pg_class'::regclass
+			AND d.objid = c.oid
+			AND d.deptype = 'e'
+		))
+		ORDER BY c.oid
+	`
+
+	rows, err := db.Query(query, schemaName, includeExtensionObjects)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []View
+	for rows.Next() {
+		var view View
+		if err := rows.Scan(&view.Name, &view.Definition); err != nil {
+			return nil, err
+		}
+		view.Definition = strings.TrimSuffix(strings.TrimSpace(view.Definition), ";")
+		views = append(views, view)
+	}
+
+	return views, rows.Err()
+}
+
+// getEnums reads the enum types of schemaName with their labels in sort
+// order. Types owned by an extension are skipped.
+func getEnums(db *sql.DB, schemaName string) ([]Enum, error) {
+	query := `
+		SELECT t.typname, array_agg(e.enumlabel ORDER BY e.enumsortorder)
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		JOIN pg_enum e ON e.enumtypid = t.oid
+		WHERE n.nspname = $1
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.classid = 'pg_type'::regclass
+			AND d.objid = t.oid
+			AND d.deptype = 'e'
+		)
+		GROUP BY t.typname
+		ORDER BY t.typname
+	`
+
+	rows, err := db.Query(query, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var enums []Enum
+	for rows.Next() {
+		var enum Enum
+		if err := rows.Scan(&enum.Name, pq.Array(&enum.Values)); err != nil {
+			return nil, err
+		}
+		enums = append(enums, enum)
+	}
+
+	return enums, rows.Err()
+}
+
+// getCompositeTypes reads the composite types of schemaName created with
+// CREATE TYPE, leaving out the row types of tables and views. Types are in
+// creation order, so a type comes after the types of its attributes. Types
+// owned by an extension are skipped.
+func getCompositeTypes(db *sql.DB, schemaName string) ([]CompositeType, error) {
+	query := `
+		SELECT t.typname, a.attname, format_type(a.atttypid, a.atttypmod)
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		JOIN pg_class c ON c.oid = t.typrelid
+		JOIN pg_attribute a ON a.attrelid = c.oid
+		WHERE n.nspname = $1
+		AND t.typtype = 'c'
+		AND c.relkind = 'c'
+		AND a.attnum > 0
+		AND NOT a.attisdropped
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.classid = 'pg_type'::regclass
+			AND d.objid = t.oid
+			AND d.deptype = 'e'
+		)
+		ORDER BY t.oid, a.attnum
+	`
+
+	rows, err := db.Query(query, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var types []CompositeType
+	for rows.Next() {
+		var typeName string
+		var attribute CompositeAttribute
+		if err := rows.Scan(&typeName, &attribute.Name, &attribute.Type); err != nil {
+			return nil, err
+		}
+
+		if n := len(types); n > 0 && types[n-1].Name == typeName {
+			types[n-1].Attributes = append(types[n-1].Attributes, attribute)
+			continue
+		}
+		types = append(types, CompositeType{Name: typeName, Attributes: []CompositeAttribute{attribute}})
+	}
+
+	return types, rows.Err()
+}
+
+// getSequences reads the sequences of schemaName. Sequences backing a
+// serial or identity column name it in OwnedBy.
+func getSequences(db *sql.DB, schemaName string, includeExtensionObjects bool) ([]Sequence, error) {
+	query := `
+		SELECT
+			s.sequencename,
+			s.data_type::text,
+			s.start_value,
+			s.increment_by,
+			COALESCE((
+				SELECT format('%s.%s', t.relname, a.attname)
+				FROM pg_depend d
+				JOIN pg_class t ON t.oid = d.refobjid
+				JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+				WHERE d.classid = 'pg_class'::regclass
+				AND d.objid = format('%I.%I', s.schemaname, s.sequencename)::regclass
+				AND d.refclassid = 'pg_class'::regclass
+				AND d.deptype IN ('a', 'i')
+			), '')
+		FROM pg_sequences s
+		WHERE s.schemaname = $1
+		AND ($2 OR NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.classid = 'pg_class'::regclass
+			AND d.objid = format('%I.%I', s.schemaname, s.sequencename)::regclass
+			AND d.deptype = 'e'
+		))
+		ORDER BY s.sequencename
+	`
+
+	rows, err := db.Query(query, schemaName, includeExtensionObjects)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sequences []Sequence
+	for rows.Next() {
+		var sequence Sequence
+		if err := rows.Scan(&sequence.Name, &sequence.DataType, &sequence.Start, &sequence.Increment, &sequence.OwnedBy); err != nil {
+			return nil, err
+		}
+		sequences = append(sequences, sequence)
+	}
+
+	return sequences, rows.Err()
+}
+
+// getExtensions lists installed extensions other than plpgsql, which every
+// database has
+func getExtensions(db *sql.DB) ([]string, error) {
+	rows, err := db.Query(`SELECT extname FROM pg_extension WHERE extname <> 'plpgsql' ORDER BY extname`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var extensions []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, name)
+	}
+
+	return extensions, rows.Err()
+}
+
+// getGrants reads the privileges granted on the tables of schemaName, by
+// table name. Privileges the owner holds on its own tables are left out,
+// since they come with creating the table. Grantees are sorted bytewise so
+// that the order does not depend on the database collation.
+func getGrants(db *sql.DB, schemaName string) (map[string][]GrantInfo, error) {
+	query := `
+		SELECT
+			g.table_name,
+			g.grantee,
+			g.is_grantable = 'YES',
+			array_agg(lower(g.privilege_type) ORDER BY lower(g.privilege_type))
+		FROM information_schema.role_table_grants g
+		JOIN pg_namespace n ON n.nspname = g.table_schema
+		JOIN pg_class c ON c.relnamespace = n.oid AND c.relname = g.table_name
+		WHERE g.table_schema = $1
+		AND g.grantee <> pg_get_userbyid(c.relowner)
+		GROUP BY g.table_name, g.grantee, g.is_grantable
+		ORDER BY g.table_name, g.grantee COLLATE "C", g.is_grantable
+	`
+
+	rows, err := db.Query(query, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := make(map[string][]GrantInfo)
+	for rows.Next() {
+		var tableName string
+		var grant GrantInfo
+		if err := rows.Scan(&tableName, &grant.Grantee, &grant.WithGrantOption, pq.Array(&grant.Privileges)); err != nil {
+			return nil, err
+		}
+		grants[tableName] = append(grants[tableName], grant)
+	}
+
+	return grants, rows.Err()
+}
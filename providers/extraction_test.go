@@ -0,0 +1,232 @@
+package providers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func TestParsePartitionKey(t *testing.T) {
+	tests := []struct {
+		keyDef   string
+		strategy string
+		key      string
+	}{
+		{keyDef: "RANGE (created_at)", strategy: "range", key: "created_at"},
+		{keyDef: "LIST (region, kind)", strategy: "list", key: "region, kind"},
+		{keyDef: "HASH (lower(email))", strategy: "hash", key: "lower(email)"},
+		{keyDef: "", strategy: "", key: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.keyDef, func(t *testing.T) {
+			strategy, key := parsePartitionKey(tt.keyDef)
+			assert.Equal(t, tt.strategy, strategy)
+			assert.Equal(t, tt.key, key)
+		})
+	}
+}
+
+// startTestDatabase starts a PostgreSQL container for the tests and
+// benchmarks that need a database, skipping them when Docker is not available
+func startTestDatabase(tb testing.TB) *sql.DB {
+	tb.Helper()
+	return startTestDatabaseImage(tb, "postgres:16-alpine")
+}
+
+// startTestDatabaseImage is startTestDatabase running the given image
+func startTestDatabaseImage(tb testing.TB, image string) *sql.DB {
+	tb.Helper()
+	if testing.Short() {
+		tb.Skip("skipping database test in short mode")
+	}
+
+	ctx := context.Background()
+	var container *postgres.PostgresContainer
+	var err error
+	func() {
+		// testcontainers panics when it finds no Docker host at all
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%v", r)
+			}
+		}()
+		container, err = postgres.Run(ctx, image,
+			postgres.WithDatabase("testdb"),
+			postgres.WithUsername("testuser"),
+			postgres.WithPassword("testpass"),
+			testcontainers.WithWaitStrategy(wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(2*time.Minute)),
+		)
+	}()
+	if container != nil {
+		tb.Cleanup(func() { _ = container.Terminate(ctx) })
+	}
+	if err != nil {
+		tb.Skipf("docker not available, skipping database test: %v", err)
+	}
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(tb, err)
+	db, err := sql.Open("postgres", connStr)
+	require.NoError(tb, err)
+	tb.Cleanup(func() { db.Close() })
+	return db
+}
+
+// createTables creates count tables with a few columns, a foreign key and
+// indexes each
+func createTables(tb testing.TB, db *sql.DB, count int) {
+	tb.Helper()
+	var sb strings.Builder
+	sb.WriteString("create table parent (id serial primary key);\n")
+	for i := 0; i < count; i++ {
+		sb.WriteString(fmt.Sprintf(`create table t%03d (
+			id bigserial primary key,
+			parent_id int references parent (id),
+			name varchar(100) collate "C" not null unique,
+			tags text[],
+			created_at timestamp(3) default now()
+		);
+		create index t%03d_parent_idx on t%03d (parent_id, created_at);
+		create index t%03d_tags_idx on t%03d using gin (tags);
+		`, i, i, i, i, i))
+	}
+	_, err := db.Exec(sb.String())
+	require.NoError(tb, err)
+}
+
+func TestExtractTablesBatched(t *testing.T) {
+	db := startTestDatabase(t)
+	createTables(t, db, 5)
+
+	perTable, err := extractTables(db, "public", false, ExtractOptions{}, math.MaxInt)
+	require.NoError(t, err)
+	batched, err := extractTables(db, "public", false, ExtractOptions{}, 0)
+	require.NoError(t, err)
+
+	require.Len(t, perTable, 6)
+	assert.Equal(t, perTable, batched)
+}
+
+func TestExtractTablesKeyNames(t *testing.T) {
+	db := startTestDatabase(t)
+	_, err := db.Exec(`
+		create table parents ("a,b" int, "say ""hi""" int, constraint parents_key primary key ("a,b", "say ""hi"""));
+		create table children (id int, "x,y" int, "q""z" int, foreign key ("x,y", "q""z") references parents);
+	`)
+	require.NoError(t, err)
+
+	tables, err := extractTables(db, "public", false, ExtractOptions{}, math.MaxInt)
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+	children, parents := tables[0], tables[1]
+
+	assert.Equal(t, "parents_key", parents.PrimaryKeyName)
+	assert.Empty(t, children.PrimaryKeyName)
+	require.Len(t, children.ForeignKeys, 1)
+	assert.Equal(t, []string{"x,y", `q"z`}, children.ForeignKeys[0].Columns)
+	assert.Equal(t, []string{"a,b", `say "hi"`}, children.ForeignKeys[0].ReferencedColumns)
+}
+
+func TestExtractFullSchemaExtensionObjects(t *testing.T) {
+	db := startTestDatabaseImage(t, "postgis/postgis:16-3.4-alpine")
+	_, err := db.Exec(`
+		create extension if not exists postgis;
+		create table places (id serial primary key, location geometry(Point, 4326));
+	`)
+	require.NoError(t, err)
+
+	tableNames := func(schema Schema) []string {
+		var names []string
+		for _, table := range schema.Tables {
+			names = append(names, table.Name)
+		}
+		return names
+	}
+	viewNames := func(schema Schema) []string {
+		var names []string
+		for _, view := range schema.Views {
+			names = append(names, view.Name)
+		}
+		return names
+	}
+
+	schema, err := ExtractFullSchema(db)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"places"}, tableNames(schema))
+	assert.NotContains(t, viewNames(schema), "geometry_columns")
+	assert.Contains(t, FormatFullSchemaSQL(schema, FormatOptions{}), "create extension if not exists postgis")
+	assert.NotContains(t, FormatFullSchemaSQL(schema, FormatOptions{}), "spatial_ref_sys")
+
+	schema, err = ExtractFullSchemaWithOptions(db, ExtractOptions{IncludeExtensionObjects: true})
+	require.NoError(t, err)
+	assert.Contains(t, tableNames(schema), "spatial_ref_sys")
+	assert.Contains(t, tableNames(schema), "places")
+	assert.Contains(t, viewNames(schema), "geometry_columns")
+}
+
+func TestDumpCatalog(t *testing.T) {
+	db := startTestDatabase(t)
+	_, err := db.Exec(`
+		create table users (id serial primary key, email varchar(255) not null default 'x');
+		create unique index users_email_idx on users (email);
+	`)
+	require.NoError(t, err)
+
+	var sb strings.Builder
+	schema, err := ExtractFullSchemaWithOptions(db, ExtractOptions{CatalogDump: &sb})
+	require.NoError(t, err)
+
+	var dump struct {
+		Schema string `json:"schema"`
+		Tables []struct {
+			Name string `json:"name"`
+		} `json:"tables"`
+		Columns map[string][]Column `json:"columns"`
+		Indexes map[string][]Index  `json:"indexes"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(sb.String()), &dump))
+	assert.Equal(t, "public", dump.Schema)
+	require.Len(t, dump.Tables, 1)
+	assert.Equal(t, "users", dump.Tables[0].Name)
+	require.Len(t, dump.Columns["users"], 2)
+	assert.Equal(t, "character varying", dump.Columns["users"][1].DataType)
+	assert.Equal(t, int64(255), dump.Columns["users"][1].CharacterLength.Int64)
+	assert.Contains(t, sb.String(), `"Valid": true`, "nullable fields are written as scanned")
+	assert.Len(t, dump.Indexes["users"], 2)
+	// The dump holds the rows the schema was built from
+	assert.Equal(t, schema.Tables[0].Columns, dump.Columns["users"])
+}
+
+func BenchmarkExtractTables(b *testing.B) {
+	db := startTestDatabase(b)
+	createTables(b, db, 200)
+
+	b.Run("per_table", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := extractTables(db, "public", false, ExtractOptions{}, math.MaxInt); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("batched", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := extractTables(db, "public", false, ExtractOptions{}, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -2,35 +2,178 @@ package providers
 
 import (
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 )
 
+// ForeignKeyStyle controls where foreign keys are emitted in SQL output
+type ForeignKeyStyle string
+
+const (
+	// ForeignKeyInline declares foreign keys inside CREATE TABLE, except those
+	// that close a dependency cycle
+	ForeignKeyInline ForeignKeyStyle = "inline"
+	// ForeignKeyAlter adds every foreign key with ALTER TABLE after all tables
+	ForeignKeyAlter ForeignKeyStyle = "alter"
+)
+
+// ParseForeignKeyStyle validates a foreign key style name. An empty name
+// selects the inline style.
+func ParseForeignKeyStyle(s string) (ForeignKeyStyle, error) {
+	switch style := ForeignKeyStyle(s); style {
+	case "":
+		return ForeignKeyInline, nil
+	case ForeignKeyInline, ForeignKeyAlter:
+		return style, nil
+	default:
+		return "", fmt.Errorf("unsupported foreign key style: %s (expected inline or alter)", s)
+	}
+}
+
+// ColumnOrder controls the order in which columns are emitted
+type ColumnOrder string
+
+const (
+	// ColumnOrderPhysical keeps the order of the columns in the database
+	ColumnOrderPhysical ColumnOrder = "physical"
+	// ColumnOrderLogical emits primary key columns first, then required
+	// columns, then nullable columns
+	ColumnOrderLogical ColumnOrder = "logical"
+)
+
+// TableOrder controls the order in which tables are emitted
+type TableOrder string
+
+const (
+	// TableOrderName sorts tables alphabetically
+	TableOrderName TableOrder = "name"
+	// TableOrderCreated sorts tables in the order they were created
+	TableOrderCreated TableOrder = "created"
+	// TableOrderDependency puts tables referenced by foreign keys before
+	// the tables referencing them
+	TableOrderDependency TableOrder = "dependency"
+)
+
+// ParseTableOrder validates a table order name. An empty name selects the
+// alphabetical order.
+func ParseTableOrder(s string) (TableOrder, error) {
+	switch order := TableOrder(s); order {
+	case "":
+		return TableOrderName, nil
+	case TableOrderName, TableOrderCreated, TableOrderDependency:
+		return order, nil
+	default:
+		return "", fmt.Errorf("unsupported table order: %s (expected name, created or dependency)", s)
+	}
+}
+
+// ParseColumnOrder validates a column order name. An empty name selects the
+// physical order.
+func ParseColumnOrder(s string) (ColumnOrder, error) {
+	switch order := ColumnOrder(s); order {
+	case "":
+		return ColumnOrderPhysical, nil
+	case ColumnOrderPhysical, ColumnOrderLogical:
+		return order, nil
+	default:
+		return "", fmt.Errorf("unsupported column order: %s (expected physical or logical)", s)
+	}
+}
+
+// FormatOptions controls how SQL output is rendered. The zero value matches
+// FormatSchemaSQL.
+type FormatOptions struct {
+	ForeignKeyStyle ForeignKeyStyle
+	// NormalizeDefaults simplifies column defaults, see normalizeDefaults
+	NormalizeDefaults bool
+	// ColumnOrder selects the column order, see OrderColumns
+	ColumnOrder ColumnOrder
+	// TableOrder selects the table order, see OrderTables
+	TableOrder TableOrder
+	// TypeMap overrides the rendering of column types
+	TypeMap TypeMap
+	// IfNotExists guards every CREATE statement so that the output can be
+	// replayed on a database that already has some of the objects
+	IfNotExists bool
+	// Clean starts the output of FormatFullSchemaSQL with DROP ... IF EXISTS
+	// statements for every object, see writeDropStatements
+	Clean bool
+}
+
+// InfoOptions controls the human-readable info output
+type InfoOptions struct {
+	// Summary appends a block of schema totals, see schemaSummary
+	Summary bool
+	// TypeMap overrides the rendering of column types
+	TypeMap TypeMap
+	// Provenance annotates tables and columns with the migrations that
+	// created and last modified them
+	Provenance ProvenanceMap
+	// RowCounts adds the number of rows of each table, see CountRows
+	RowCounts RowCounts
+	// Color highlights table names, primary key columns and NULL with ANSI
+	// escape codes. The text is otherwise the same as without it.
+	Color bool
+}
+
 // FormatSchemaInfo formats schema as human-readable text
 func FormatSchemaInfo(tables []Table) string {
+	return FormatSchemaInfoWithOptions(tables, InfoOptions{})
+}
+
+// FormatSchemaInfoWithOptions formats schema as human-readable text with
+// custom options
+func FormatSchemaInfoWithOptions(tables []Table, opts InfoOptions) string {
 	var sb strings.Builder
+	tables = normalizeSchema(tables)
+	writeTablesInfo(&sb, tables, opts)
+	if opts.Summary {
+		sb.WriteString(schemaSummary(tables))
+	}
+	return sb.String()
+}
+
+// writeTablesInfo writes the human-readable description of each table
+func writeTablesInfo(sb *strings.Builder, tables []Table, opts InfoOptions) {
+	byName := make(map[string]Table, len(tables))
+	for _, table := range tables {
+		byName[table.Name] = table
+	}
 
+	color := colorizer(opts.Color)
 	for _, table := range tables {
-		sb.WriteString(fmt.Sprintf("Table: %s\n", table.Name))
+		tableProvenance, _ := opts.Provenance.Table(table.Name)
+		sb.WriteString(fmt.Sprintf("Table: %s%s\n", color.bold(table.Name), describeProvenance(tableProvenance, "")))
+		if count, ok := opts.RowCounts[table.Name]; ok {
+			sb.WriteString(fmt.Sprintf("Rows: %s\n", describeRowCount(count)))
+		}
+		if table.IsPartition() {
+			sb.WriteString(fmt.Sprintf("Partition of: %s %s\n", table.PartitionOf, table.PartitionBound))
+		}
+		if table.PartitionStrategy != "" {
+			sb.WriteString(fmt.Sprintf("Partitioned by: %s (%s)\n", table.PartitionStrategy, table.PartitionKey))
+		}
+		if len(table.Inherits) > 0 {
+			sb.WriteString(fmt.Sprintf("Inherits: %s\n", inheritanceChain(table.Inherits, byName, nil)))
+		}
+		if len(table.StorageParams) > 0 {
+			sb.WriteString(fmt.Sprintf("Storage parameters: %s\n", describeStorageParams(table.StorageParams)))
+		}
+		// Partitions have the columns, keys and indexes of their parent
+		if table.IsPartition() {
+			sb.WriteString("\n")
+			continue
+		}
 		sb.WriteString("Columns:\n")
 
 		for _, col := range table.Columns {
-			nullable := "NOT NULL"
-			if col.IsNullable {
-				nullable = "NULL"
+			// Columns created with their table are not annotated again
+			origin := ""
+			if provenance, ok := opts.Provenance.Column(table.Name, col.Name); ok {
+				origin = strings.TrimSpace(describeProvenance(provenance, tableProvenance.CreatedBy))
 			}
-
-			pk := ""
-			if col.IsPrimaryKey {
-				pk = " (PRIMARY KEY)"
-			}
-
-			defaultVal := ""
-			if col.DefaultValue.Valid {
-				defaultVal = fmt.Sprintf(" DEFAULT %s", col.DefaultValue.String)
-			}
-
-			sb.WriteString(fmt.Sprintf("  - %s %s %s%s%s\n",
-				col.Name, mapDataType(col), nullable, defaultVal, pk))
+			sb.WriteString("  - " + describeInfoColumn(col, opts.TypeMap, origin, color) + "\n")
 		}
 
 		if len(table.Indexes) > 0 {
@@ -40,149 +183,913 @@ func FormatSchemaInfo(tables []Table) string {
 				if idx.IsUnique {
 					unique = " (UNIQUE)"
 				}
-				sb.WriteString(fmt.Sprintf("  - %s on (%s)%s\n",
-					idx.Name, strings.Join(idx.Columns, ", "), unique))
+				method := ""
+				if idx.Method != "" {
+					method = " using " + idx.Method
+				}
+				sb.WriteString(fmt.Sprintf("  - %s on (%s)%s%s\n",
+					idx.Name, strings.Join(idx.Columns, ", "), method, unique))
+			}
+		}
+
+		if len(table.UniqueConstraints) > 0 || len(table.ExclusionConstraints) > 0 || len(table.ForeignKeys) > 0 {
+			sb.WriteString("Constraints:\n")
+			for _, uc := range table.UniqueConstraints {
+				sb.WriteString(fmt.Sprintf("  - %s UNIQUE (%s)\n",
+					uc.Name, strings.Join(uc.Columns, ", ")))
+			}
+			for _, ec := range table.ExclusionConstraints {
+				sb.WriteString(fmt.Sprintf("  - %s %s\n", ec.Name, ec.definition(true)))
+			}
+			for _, fk := range table.ForeignKeys {
+				sb.WriteString(fmt.Sprintf("  - %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+					fk.Name, strings.Join(fk.Columns, ", "), fk.ReferencedTable, strings.Join(fk.ReferencedColumns, ", ")))
+				if actions := fk.referentialActions(true); actions != "" {
+					sb.WriteString(" " + actions)
+				}
+				sb.WriteString("\n")
+			}
+		}
+
+		if len(table.Triggers) > 0 {
+			sb.WriteString("Triggers:\n")
+			for _, trigger := range table.Triggers {
+				sb.WriteString(fmt.Sprintf("  - %s %s %s EXECUTE FUNCTION %s()\n",
+					trigger.Name, trigger.Timing, strings.Join(trigger.Events, " OR "), trigger.FunctionName))
+			}
+		}
+
+		if len(table.Grants) > 0 {
+			sb.WriteString("Grants:\n")
+			for _, grant := range table.Grants {
+				grantOption := ""
+				if grant.WithGrantOption {
+					grantOption = " WITH GRANT OPTION"
+				}
+				sb.WriteString(fmt.Sprintf("  - %s: %s%s\n", grant.Grantee, strings.ToUpper(strings.Join(grant.Privileges, ", ")), grantOption))
 			}
 		}
 
 		sb.WriteString("\n")
 	}
+}
 
-	return sb.String()
+// describeInfoColumn renders a column of info output, its parts separated by
+// single spaces: name TYPE [COLLATE x] NULL|NOT NULL [DEFAULT x]
+// [(PRIMARY KEY)] [(INHERITED)] [origin]. Parts that do not apply are left
+// out, so the line never has doubled or trailing spaces.
+func describeInfoColumn(col Column, typeMap TypeMap, origin string, color colorizer) string {
+	name := col.Name
+	if col.IsPrimaryKey {
+		name = color.highlight(name)
+	}
+	parts := []string{name, mapDataType(col, typeMap)}
+	if col.Collation != "" {
+		parts = append(parts, "COLLATE "+quoteCollation(col.Collation))
+	}
+	if col.IsNullable {
+		parts = append(parts, color.dim("NULL"))
+	} else {
+		parts = append(parts, "NOT NULL")
+	}
+	if col.DefaultValue.Valid && !col.IsSerial {
+		parts = append(parts, "DEFAULT "+strings.TrimSpace(col.DefaultValue.String))
+	}
+	if col.IsPrimaryKey {
+		parts = append(parts, "(PRIMARY KEY)")
+	}
+	if col.IsInherited {
+		parts = append(parts, "(INHERITED)")
+	}
+	parts = append(parts, origin)
+
+	var kept []string
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, " ")
 }
 
-// FormatSchemaSQL formats schema as SQL CREATE statements
-func FormatSchemaSQL(tables []Table) string {
-	var sb strings.Builder
+// describeProvenance returns the annotation of an object, such as
+// " (from 001_create_users, modified by 004_add_email)". The creating
+// migration is left out when it equals inherited.
+func describeProvenance(provenance Provenance, inherited string) string {
+	var parts []string
+	if provenance.CreatedBy != "" && provenance.CreatedBy != inherited {
+		parts = append(parts, "from "+provenance.CreatedBy)
+	}
+	if provenance.ModifiedBy != "" {
+		parts = append(parts, "modified by "+provenance.ModifiedBy)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
 
-	for _, table := range tables {
-		sb.WriteString(fmt.Sprintf("create table %s (\n", table.Name))
+// inheritanceChain describes the parents of a table and, in parentheses,
+// what each of them inherits in turn, such as "b (inherits a)". visited
+// guards against cycles, which PostgreSQL does not allow anyway.
+func inheritanceChain(parents []string, byName map[string]Table, visited map[string]bool) string {
+	if visited == nil {
+		visited = make(map[string]bool)
+	}
 
-		var columnDefs []string
-		var primaryKeys []string
+	described := make([]string, len(parents))
+	for i, parent := range parents {
+		described[i] = parent
+		grandparents := byName[parent].Inherits
+		if len(grandparents) > 0 && !visited[parent] {
+			visited[parent] = true
+			described[i] += fmt.Sprintf(" (inherits %s)", inheritanceChain(grandparents, byName, visited))
+		}
+	}
+	return strings.Join(described, ", ")
+}
 
+// schemaSummary returns totals over all tables: tables, columns, nullable
+// columns, indexes and foreign keys. Tables without a primary key are listed
+// by name since they are usually a mistake.
+func schemaSummary(tables []Table) string {
+	var columns, nullable, indexes, foreignKeys int
+	var withoutPrimaryKey []string
+	for _, table := range tables {
+		hasPrimaryKey := false
 		for _, col := range table.Columns {
-			var colDef strings.Builder
-			colDef.WriteString(fmt.Sprintf("    %s %s", col.Name, strings.ToLower(mapDataType(col))))
-
-			if !col.IsNullable {
-				colDef.WriteString(" not null")
+			if col.IsNullable {
+				nullable++
 			}
-
-			if col.DefaultValue.Valid {
-				colDef.WriteString(fmt.Sprintf(" default %s", col.DefaultValue.String))
+			if col.IsPrimaryKey {
+				hasPrimaryKey = true
 			}
+		}
+		columns += len(table.Columns)
+		indexes += len(table.Indexes)
+		foreignKeys += len(table.ForeignKeys)
+		if !hasPrimaryKey && !table.IsView {
+			withoutPrimaryKey = append(withoutPrimaryKey, table.Name)
+		}
+	}
 
-			columnDefs = append(columnDefs, colDef.String())
+	var sb strings.Builder
+	sb.WriteString("Summary:\n")
+	sb.WriteString(fmt.Sprintf("  Tables: %d\n", len(tables)))
+	sb.WriteString(fmt.Sprintf("  Columns: %d (%d nullable)\n", columns, nullable))
+	sb.WriteString(fmt.Sprintf("  Indexes: %d\n", indexes))
+	sb.WriteString(fmt.Sprintf("  Foreign keys: %d\n", foreignKeys))
+	if len(withoutPrimaryKey) > 0 {
+		sb.WriteString(fmt.Sprintf("  WARNING: %d table(s) without a primary key: %s\n",
+			len(withoutPrimaryKey), strings.Join(withoutPrimaryKey, ", ")))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
 
-			if col.IsPrimaryKey {
-				primaryKeys = append(primaryKeys, col.Name)
+// FormatFullSchemaInfo formats tables, views, enums, sequences, functions
+// and extensions as human-readable text
+func FormatFullSchemaInfo(schema Schema) string {
+	return FormatFullSchemaInfoWithOptions(schema, InfoOptions{})
+}
+
+// FormatFullSchemaInfoWithOptions formats tables, views, enums, sequences,
+// functions and extensions as human-readable text with custom options. A
+// header names the server version when known and the summary comes last.
+// Sequences owned by a column are implied by it and not listed.
+func FormatFullSchemaInfoWithOptions(schema Schema, opts InfoOptions) string {
+	var sb strings.Builder
+	if schema.ServerVersion > 0 {
+		sb.WriteString(fmt.Sprintf("PostgreSQL version: %s\n\n", FormatServerVersion(schema.ServerVersion)))
+	}
+	writeTablesInfo(&sb, normalizeSchema(schema.Tables), opts)
+
+	if len(schema.Views) > 0 {
+		sb.WriteString("Views:\n")
+		for _, view := range schema.Views {
+			sb.WriteString(fmt.Sprintf("  - %s\n", view.Name))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(schema.Enums) > 0 {
+		sb.WriteString("Enums:\n")
+		for _, enum := range schema.Enums {
+			sb.WriteString(fmt.Sprintf("  - %s (%s)\n", enum.Name, strings.Join(enum.Values, ", ")))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(schema.CompositeTypes) > 0 {
+		sb.WriteString("Composite types:\n")
+		for _, compositeType := range schema.CompositeTypes {
+			attributes := make([]string, len(compositeType.Attributes))
+			for i, attribute := range compositeType.Attributes {
+				attributes[i] = attribute.Name + " " + attribute.Type
 			}
+			sb.WriteString(fmt.Sprintf("  - %s (%s)\n", compositeType.Name, strings.Join(attributes, ", ")))
+		}
+		sb.WriteString("\n")
+	}
+
+	if sequences := StandaloneSequences(schema); len(sequences) > 0 {
+		sb.WriteString("Sequences:\n")
+		for _, sequence := range sequences {
+			sb.WriteString(fmt.Sprintf("  - %s %s (start %d, increment %d)\n",
+				sequence.Name, strings.ToUpper(sequence.DataType), sequence.Start, sequence.Increment))
 		}
+		sb.WriteString("\n")
+	}
+
+	if len(schema.Extensions) > 0 {
+		sb.WriteString(fmt.Sprintf("Extensions: %s\n\n", strings.Join(schema.Extensions, ", ")))
+	}
+
+	if len(schema.Functions) > 0 {
+		sb.WriteString("Functions:\n")
+		for _, function := range schema.Functions {
+			sb.WriteString(fmt.Sprintf("  - %s(%s) %s\n", function.Name, function.Arguments, strings.ToUpper(function.Kind)))
+		}
+		sb.WriteString("\n")
+	}
+
+	if opts.Summary {
+		sb.WriteString(schemaSummary(schema.Tables))
+	}
+
+	return sb.String()
+}
+
+// FormatFullSchemaSQL formats a schema as SQL. Extensions come first
+// because they provide types and functions, then enums, composite types and
+// sequences used by columns, then functions because column defaults and
+// triggers may call them. Function bodies are not checked while they are
+// created, as pg_dump does, since a body may select from a table that comes
+// later. Views come last, after the tables they select from.
+func FormatFullSchemaSQL(schema Schema, opts FormatOptions) string {
+	var sb strings.Builder
+	writeSchemaPreamble(&sb, schema, opts)
+	sb.WriteString(FormatSchemaSQLWithOptions(schema.Tables, opts))
+	writeCreateViews(&sb, schema.Views, opts.IfNotExists)
+	return sb.String()
+}
+
+// writeSchemaPreamble writes what FormatFullSchemaSQL emits before the
+// tables: the DROP statements of a clean script, then extensions, enums,
+// composite types, standalone sequences and functions
+func writeSchemaPreamble(sb *strings.Builder, schema Schema, opts FormatOptions) {
+	if opts.Clean {
+		writeDropStatements(sb, schema, opts)
+	}
+
+	for _, extension := range schema.Extensions {
+		sb.WriteString(fmt.Sprintf("create extension if not exists %s;\n", quoteIdent(extension)))
+	}
+	if len(schema.Extensions) > 0 {
+		sb.WriteString("\n")
+	}
+
+	for _, enum := range schema.Enums {
+		if opts.IfNotExists {
+			// CREATE TYPE has no IF NOT EXISTS
+			sb.WriteString(ignoreDuplicate(createEnumStatement(enum)))
+		} else {
+			sb.WriteString(createEnumStatement(enum))
+		}
+	}
+	if len(schema.Enums) > 0 {
+		sb.WriteString("\n")
+	}
+
+	for _, compositeType := range schema.CompositeTypes {
+		if opts.IfNotExists {
+			sb.WriteString(ignoreDuplicate(createCompositeTypeStatement(compositeType)))
+		} else {
+			sb.WriteString(createCompositeTypeStatement(compositeType))
+		}
+	}
+	if len(schema.CompositeTypes) > 0 {
+		sb.WriteString("\n")
+	}
+
+	sequences := StandaloneSequences(schema)
+	for _, sequence := range sequences {
+		sb.WriteString(fmt.Sprintf("create sequence %s%s as %s start with %d increment by %d;\n",
+			ifNotExistsClause(opts.IfNotExists), quoteIdent(sequence.Name), sequence.DataType, sequence.Start, sequence.Increment))
+	}
+	if len(sequences) > 0 {
+		sb.WriteString("\n")
+	}
+
+	// A LANGUAGE sql body is validated on creation and may reference tables
+	// that are only created further down
+	if len(schema.Functions) > 0 {
+		sb.WriteString("set check_function_bodies = off;\n\n")
+	}
+	// Function definitions already read CREATE OR REPLACE
+	for _, function := range schema.Functions {
+		sb.WriteString(function.Definition + ";\n\n")
+	}
+}
+
+// writeCreateViews writes the CREATE VIEW statements of views, replacing
+// existing views when orReplace is set
+func writeCreateViews(sb *strings.Builder, views []View, orReplace bool) {
+	create := "create view"
+	if orReplace {
+		create = "create or replace view"
+	}
+	for _, view := range views {
+		sb.WriteString(fmt.Sprintf("%s %s as\n%s;\n\n", create, quoteIdent(view.Name), view.Definition))
+	}
+}
+
+// writeDropStatements writes DROP ... IF EXISTS statements for the objects of
+// schema in the reverse order of their creation. Foreign keys closing a
+// cycle are dropped first, so that no table needs to be dropped with
+// CASCADE. Triggers and indexes go with their table.
+func writeDropStatements(sb *strings.Builder, schema Schema, opts FormatOptions) {
+	for i := len(schema.Views) - 1; i >= 0; i-- {
+		sb.WriteString(fmt.Sprintf("drop view if exists %s;\n", quoteIdent(schema.Views[i].Name)))
+	}
+
+	ordered, deferred := orderTablesByDependency(normalizeSchema(schema.Tables), opts.TableOrder == TableOrderCreated)
+	for _, d := range deferred {
+		sb.WriteString(fmt.Sprintf("alter table if exists %s drop constraint if exists %s;\n", quoteIdent(d.Table), quoteIdent(d.ForeignKey.Name)))
+	}
+	for i := len(ordered) - 1; i >= 0; i-- {
+		sb.WriteString(fmt.Sprintf("drop table if exists %s;\n", quoteIdent(ordered[i].Name)))
+	}
 
-		sb.WriteString(strings.Join(columnDefs, ",\n"))
+	for i := len(schema.Functions) - 1; i >= 0; i-- {
+		function := schema.Functions[i]
+		sb.WriteString(fmt.Sprintf("drop %s if exists %s(%s);\n", function.Kind, quoteIdent(function.Name), function.Arguments))
+	}
+	sequences := StandaloneSequences(schema)
+	for i := len(sequences) - 1; i >= 0; i-- {
+		sb.WriteString(fmt.Sprintf("drop sequence if exists %s;\n", quoteIdent(sequences[i].Name)))
+	}
+	for i := len(schema.CompositeTypes) - 1; i >= 0; i-- {
+		sb.WriteString(fmt.Sprintf("drop type if exists %s;\n", quoteIdent(schema.CompositeTypes[i].Name)))
+	}
+	for i := len(schema.Enums) - 1; i >= 0; i-- {
+		sb.WriteString(fmt.Sprintf("drop type if exists %s;\n", quoteIdent(schema.Enums[i].Name)))
+	}
+	for i := len(schema.Extensions) - 1; i >= 0; i-- {
+		sb.WriteString(fmt.Sprintf("drop extension if exists %s;\n", quoteIdent(schema.Extensions[i])))
+	}
+	sb.WriteString("\n")
+}
+
+// ifNotExistsClause returns "if not exists " when guarded is set
+func ifNotExistsClause(guarded bool) string {
+	if guarded {
+		return "if not exists "
+	}
+	return ""
+}
+
+// ignoreDuplicate wraps a statement that has no IF NOT EXISTS form in a
+// block that skips it when the object already exists
+func ignoreDuplicate(statement string) string {
+	return fmt.Sprintf("do $$ begin\n    %s;\nexception when duplicate_object then null;\nend $$;\n",
+		strings.TrimSuffix(strings.TrimSpace(statement), ";"))
+}
 
-		if len(primaryKeys) > 0 {
-			sb.WriteString(fmt.Sprintf(",\n    primary key (%s)", strings.Join(primaryKeys, ", ")))
+// createEnumStatement returns the CREATE TYPE statement of an enum
+func createEnumStatement(enum Enum) string {
+	values := make([]string, len(enum.Values))
+	for i, value := range enum.Values {
+		values[i] = quoteLiteral(value)
+	}
+	return fmt.Sprintf("create type %s as enum (%s);\n", quoteIdent(enum.Name), strings.Join(values, ", "))
+}
+
+// AddEnumValuesSQL returns the ALTER TYPE statements adding the labels of
+// enum missing from existing, the labels the type has now. Labels are added
+// in order, each after the one preceding it, or before the first existing
+// label when it comes first.
+func AddEnumValuesSQL(enum Enum, existing []string) string {
+	var sb strings.Builder
+	for i, value := range enum.Values {
+		if slices.Contains(existing, value) {
+			continue
+		}
+		position := "before " + quoteLiteral(existing[0])
+		if i > 0 {
+			position = "after " + quoteLiteral(enum.Values[i-1])
 		}
+		sb.WriteString(fmt.Sprintf("alter type %s add value %s %s;\n", quoteIdent(enum.Name), quoteLiteral(value), position))
+	}
+	return sb.String()
+}
 
-		sb.WriteString("\n);\n\n")
+// createCompositeTypeStatement returns the CREATE TYPE statement of a
+// composite type
+func createCompositeTypeStatement(compositeType CompositeType) string {
+	attributes := make([]string, len(compositeType.Attributes))
+	for i, attribute := range compositeType.Attributes {
+		attributes[i] = quoteIdent(attribute.Name) + " " + attribute.Type
+	}
+	return fmt.Sprintf("create type %s as (%s);\n", quoteIdent(compositeType.Name), strings.Join(attributes, ", "))
+}
 
-		for _, idx := range table.Indexes {
-			unique := ""
-			if idx.IsUnique {
-				unique = "unique "
+// StandaloneSequences returns the sequences of schema that are not created
+// by a serial column of its tables. Sequences owned by any other column,
+// such as an identity column, are created on their own, since identity
+// columns are not extracted.
+func StandaloneSequences(schema Schema) []Sequence {
+	serials := make(map[string]bool)
+	for _, table := range schema.Tables {
+		for _, col := range table.Columns {
+			if col.IsSerial {
+				serials[table.Name+"."+col.Name] = true
 			}
-			sb.WriteString(fmt.Sprintf("create %sindex %s on %s (%s);\n",
-				unique, idx.Name, table.Name, strings.Join(idx.Columns, ", ")))
 		}
+	}
 
-		if len(table.Indexes) > 0 {
-			sb.WriteString("\n")
+	var standalone []Sequence
+	for _, sequence := range schema.Sequences {
+		if !serials[sequence.OwnedBy] {
+			standalone = append(standalone, sequence)
+		}
+	}
+	return standalone
+}
+
+// FormatSchemaSQL formats schema as SQL CREATE statements. Tables are
+// ordered so that referenced tables are created before the tables whose
+// foreign keys point at them; foreign keys that form a cycle are added with
+// ALTER TABLE once all tables exist.
+func FormatSchemaSQL(tables []Table) string {
+	return FormatSchemaSQLWithOptions(tables, FormatOptions{})
+}
+
+// FormatSchemaSQLWithOptions formats schema as SQL CREATE statements like
+// FormatSchemaSQL. With ForeignKeyAlter, every foreign key is added with
+// ALTER TABLE after all tables are created.
+func FormatSchemaSQLWithOptions(tables []Table, opts FormatOptions) string {
+	var sb strings.Builder
+	tables = normalizeSchema(tables)
+
+	if opts.NormalizeDefaults {
+		tables = normalizeDefaults(tables)
+	}
+
+	// Referenced tables always come first so the output can be replayed;
+	// with the created order, independent tables keep their input order
+	ordered, deferred := orderTablesByDependency(tables, opts.TableOrder == TableOrderCreated)
+	if opts.ForeignKeyStyle == ForeignKeyAlter {
+		deferred = allForeignKeys(ordered)
+	}
+	omitted := omittedForeignKeys(deferred)
+	for _, table := range ordered {
+		if table.IsPartition() {
+			writeCreatePartition(&sb, table, opts.IfNotExists)
+			writeGrants(&sb, table)
+			continue
+		}
+		writeCreateTable(&sb, table, omitted[table.Name], opts)
+		writeCreateIndexes(&sb, table, opts.IfNotExists)
+		writeGrants(&sb, table)
+	}
+
+	if len(deferred) > 0 {
+		writeDeferredForeignKeys(&sb, deferred, opts.IfNotExists)
+		sb.WriteString("\n")
+	}
+
+	// Triggers come last so that every table they reference already exists.
+	// Partitions get the triggers of their parent when they are attached.
+	for _, table := range tables {
+		if !table.IsPartition() {
+			writeCreateTriggers(&sb, table, opts.IfNotExists)
 		}
 	}
 
 	return sb.String()
 }
 
-func mapDataType(col Column) string {
+// writeCreateTable writes the CREATE TABLE statement for a table, inlining its
+// constraints. Foreign keys whose names are in omitFKs are left out so they
+// can be added separately. Columns inherited from a parent table are left
+// to the INHERITS clause.
+func writeCreateTable(sb *strings.Builder, table Table, omitFKs map[string]bool, opts FormatOptions) {
+	sb.WriteString(fmt.Sprintf("create table %s%s (\n", ifNotExistsClause(opts.IfNotExists), quoteIdent(table.Name)))
+
+	var definitions []string
+	var primaryKeys []string
+
+	for _, col := range table.Columns {
+		if !col.IsInherited {
+			definitions = append(definitions, "    "+columnDefinition(col, opts.TypeMap))
+		}
+
+		if col.IsPrimaryKey {
+			primaryKeys = append(primaryKeys, col.Name)
+		}
+	}
+
+	if len(primaryKeys) > 0 {
+		definitions = append(definitions, fmt.Sprintf("    primary key (%s)", quoteIdents(primaryKeys)))
+	}
+
+	for _, uc := range table.UniqueConstraints {
+		definitions = append(definitions, fmt.Sprintf("    constraint %s unique (%s)", quoteIdent(uc.Name), quoteIdents(uc.Columns)))
+	}
+
+	for _, ec := range table.ExclusionConstraints {
+		definitions = append(definitions, fmt.Sprintf("    constraint %s %s", quoteIdent(ec.Name), ec.definition(false)))
+	}
+
+	for _, fk := range table.ForeignKeys {
+		if omitFKs[fk.Name] {
+			continue
+		}
+		definitions = append(definitions, "    "+foreignKeyDefinition(fk))
+	}
+
+	if len(definitions) > 0 {
+		sb.WriteString(strings.Join(definitions, ",\n") + "\n")
+	}
+	sb.WriteString(")" + inheritsClause(table) + partitionByClause(table) + storageParamsClause(table) + ";\n\n")
+}
+
+// inheritsClause returns the INHERITS clause of a table using classic
+// inheritance, or "" for other tables
+func inheritsClause(table Table) string {
+	if len(table.Inherits) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" inherits (%s)", quoteIdents(table.Inherits))
+}
+
+// writeCreatePartition writes the CREATE TABLE statement for a partition.
+// Columns, keys, indexes and foreign keys are inherited from the parent, so
+// only the bound is written.
+func writeCreatePartition(sb *strings.Builder, table Table, ifNotExists bool) {
+	sb.WriteString(fmt.Sprintf("create table %s%s partition of %s %s%s%s;\n\n",
+		ifNotExistsClause(ifNotExists), quoteIdent(table.Name), quoteIdent(table.PartitionOf), table.PartitionBound, partitionByClause(table), storageParamsClause(table)))
+}
+
+// partitionByClause returns the PARTITION BY clause of a partitioned table,
+// or "" for other tables
+func partitionByClause(table Table) string {
+	if table.PartitionStrategy == "" {
+		return ""
+	}
+	return fmt.Sprintf(" partition by %s (%s)", table.PartitionStrategy, table.PartitionKey)
+}
+
+// storageParamsClause returns the WITH clause setting the storage parameters
+// of a table, or "" when it has none
+func storageParamsClause(table Table) string {
+	if len(table.StorageParams) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" with (%s)", describeStorageParams(table.StorageParams))
+}
+
+// describeStorageParams lists storage parameters as name=value, sorted by
+// name
+func describeStorageParams(params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	settings := make([]string, len(names))
+	for i, name := range names {
+		settings[i] = name + "=" + params[name]
+	}
+	return strings.Join(settings, ", ")
+}
+
+// writeCreateIndexes writes the CREATE INDEX statements for a table
+func writeCreateIndexes(sb *strings.Builder, table Table, ifNotExists bool) {
+	for _, idx := range table.Indexes {
+		sb.WriteString(createIndexStatement(table.Name, idx, ifNotExists))
+	}
+
+	if len(table.Indexes) > 0 {
+		sb.WriteString("\n")
+	}
+}
+
+// writeGrants writes the GRANT statements for a table, which need no guard
+// as granting a privilege twice is not an error
+func writeGrants(sb *strings.Builder, table Table) {
+	for _, grant := range table.Grants {
+		sb.WriteString(grantStatement(table.Name, grant))
+	}
+
+	if len(table.Grants) > 0 {
+		sb.WriteString("\n")
+	}
+}
+
+// grantStatement returns the GRANT statement giving the privileges of grant
+// on a table
+func grantStatement(tableName string, grant GrantInfo) string {
+	grantee := quoteIdent(grant.Grantee)
+	if grant.Grantee == granteePublic {
+		grantee = "public"
+	}
+	statement := fmt.Sprintf("grant %s on %s to %s", strings.Join(grant.Privileges, ", "), quoteIdent(tableName), grantee)
+	if grant.WithGrantOption {
+		statement += " with grant option"
+	}
+	return statement + ";\n"
+}
+
+// writeCreateTriggers writes the CREATE TRIGGER statements for a table
+func writeCreateTriggers(sb *strings.Builder, table Table, ifNotExists bool) {
+	for _, trigger := range table.Triggers {
+		if ifNotExists {
+			sb.WriteString(ignoreDuplicate(trigger.Definition))
+		} else {
+			sb.WriteString(trigger.Definition + ";\n")
+		}
+	}
+
+	if len(table.Triggers) > 0 {
+		sb.WriteString("\n")
+	}
+}
+
+// columnDefinition renders a column as it appears inside CREATE TABLE
+func columnDefinition(col Column, typeMap TypeMap) string {
+	var colDef strings.Builder
+	colDef.WriteString(fmt.Sprintf("%s %s", quoteIdent(col.Name), sqlDataType(col, typeMap)))
+
+	if col.Collation != "" {
+		colDef.WriteString(" collate " + quoteCollation(col.Collation))
+	}
+
+	if !col.IsNullable {
+		colDef.WriteString(" not null")
+	}
+
+	// The default of a serial column is implied by the type
+	if col.DefaultValue.Valid && !col.IsSerial {
+		colDef.WriteString(fmt.Sprintf(" default %s", col.DefaultValue.String))
+	}
+
+	return colDef.String()
+}
+
+// foreignKeyDefinition renders a foreign key as a table constraint
+func foreignKeyDefinition(fk ForeignKey) string {
+	definition := fmt.Sprintf("constraint %s foreign key (%s) references %s (%s)",
+		quoteIdent(fk.Name), quoteIdents(fk.Columns), quoteIdent(fk.ReferencedTable), quoteIdents(fk.ReferencedColumns))
+	if actions := fk.referentialActions(false); actions != "" {
+		definition += " " + actions
+	}
+	return definition
+}
+
+// defaultIndexMethod is the access method of an index created without a
+// using clause
+const defaultIndexMethod = "btree"
+
+func createIndexStatement(tableName string, idx Index, ifNotExists bool) string {
+	unique := ""
+	if idx.IsUnique {
+		unique = "unique "
+	}
+	method := ""
+	if idx.Method != "" && idx.Method != defaultIndexMethod {
+		method = " using " + idx.Method
+	}
+	return fmt.Sprintf("create %sindex %s%s on %s%s (%s);\n",
+		unique, ifNotExistsClause(ifNotExists), quoteIdent(idx.Name), quoteIdent(tableName), method, quoteIdents(idx.Columns))
+}
+
+// reservedKeywords lists PostgreSQL keywords that cannot be used as bare
+// column or table names
+var reservedKeywords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true, "array": true,
+	"as": true, "asc": true, "asymmetric": true, "authorization": true, "binary": true,
+	"both": true, "case": true, "cast": true, "check": true, "collate": true,
+	"collation": true, "column": true, "concurrently": true, "constraint": true,
+	"create": true, "cross": true, "current_catalog": true, "current_date": true,
+	"current_role": true, "current_schema": true, "current_time": true,
+	"current_timestamp": true, "current_user": true, "default": true, "deferrable": true,
+	"desc": true, "distinct": true, "do": true, "else": true, "end": true, "except": true,
+	"false": true, "fetch": true, "for": true, "foreign": true, "freeze": true, "from": true,
+	"full": true, "grant": true, "group": true, "having": true, "ilike": true, "in": true,
+	"initially": true, "inner": true, "intersect": true, "into": true, "is": true,
+	"isnull": true, "join": true, "lateral": true, "leading": true, "left": true,
+	"like": true, "limit": true, "localtime": true, "localtimestamp": true,
+	"natural": true, "not": true, "notnull": true, "null": true, "offset": true, "on": true,
+	"only": true, "or": true, "order": true, "outer": true, "overlaps": true,
+	"placing": true, "primary": true, "references": true, "returning": true,
+	"right": true, "select": true, "session_user": true, "similar": true, "some": true,
+	"symmetric": true, "system_user": true, "table": true, "tablesample": true,
+	"then": true, "to": true, "trailing": true, "true": true, "union": true,
+	"unique": true, "user": true, "using": true, "variadic": true, "verbose": true,
+	"when": true, "where": true, "window": true, "with": true,
+}
+
+// quoteIdent returns name as a valid SQL identifier, wrapping it in double
+// quotes when it contains upper-case or special characters, starts with a
+// digit or is a reserved keyword
+func quoteIdent(name string) string {
+	if name != "" && !reservedKeywords[name] && !needsQuoting(name) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteLiteral quotes value as a string literal
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// quoteCollation quotes a collation name. Collation names are always quoted
+// since most of them, such as "C" or "en-US-x-icu", need it anyway.
+func quoteCollation(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteIdents quotes each name and joins them into a comma-separated list
+func quoteIdents(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdent(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func needsQuoting(name string) bool {
+	for i, r := range name {
+		switch {
+		case r == '_' || (r >= 'a' && r <= 'z'):
+		case i > 0 && ((r >= '0' && r <= '9') || r == '$'):
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// defaultDatetimePrecision is the precision of timestamp and time columns
+// declared without one
+const defaultDatetimePrecision = 6
+
+// withDatetimePrecision appends the precision of a timestamp or time column
+// to its type name unless it is the default
+func withDatetimePrecision(dataType string, col Column) string {
+	if !col.DatetimePrecision.Valid || col.DatetimePrecision.Int64 == defaultDatetimePrecision {
+		return dataType
+	}
+	return fmt.Sprintf("%s(%d)", dataType, col.DatetimePrecision.Int64)
+}
+
+func mapDataType(col Column, typeMap TypeMap) string {
+	dataType, _ := lookupDataType(col, typeMap)
+	return dataType
+}
+
+// sqlDataType returns the type of a column as written in SQL output: lower
+// case, except for the names of composite types, which may be quoted
+func sqlDataType(col Column, typeMap TypeMap) string {
+	dataType := mapDataType(col, typeMap)
+	if _, overridden := typeMap.lookup(col); col.IsComposite && !overridden {
+		return dataType
+	}
+	return strings.ToLower(dataType)
+}
+
+// lookupDataType maps a column type to its SQL spelling and reports whether
+// the type is known. Overrides in typeMap take precedence over the built-in
+// mapping. Unknown types are passed through upper-cased.
+func lookupDataType(col Column, typeMap TypeMap) (string, bool) {
+	if dataType, ok := typeMap.lookup(col); ok {
+		return dataType, true
+	}
+
+	// Composite types are referenced by their name as created
+	if col.IsComposite {
+		return quoteIdent(col.DataType), true
+	}
+
+	if serial, ok := serialTypes[col.DataType]; ok && col.IsSerial {
+		return strings.ToUpper(serial), true
+	}
+
 	switch col.DataType {
 	case "character varying":
 		if col.CharacterLength.Valid {
-			return fmt.Sprintf("VARCHAR(%d)", col.CharacterLength.Int64)
+			return fmt.Sprintf("VARCHAR(%d)", col.CharacterLength.Int64), true
 		}
-		return "VARCHAR(255)"
+		return "VARCHAR(255)", true
 	case "character", "char":
 		if col.CharacterLength.Valid {
-			return fmt.Sprintf("CHAR(%d)", col.CharacterLength.Int64)
+			return fmt.Sprintf("CHAR(%d)", col.CharacterLength.Int64), true
 		}
-		return "CHAR"
+		return "CHAR", true
 	case "text":
-		return "TEXT"
+		return "TEXT", true
 	case "integer":
-		return "INTEGER"
+		return "INTEGER", true
 	case "bigint":
-		return "BIGINT"
+		return "BIGINT", true
 	case "smallint":
-		return "SMALLINT"
+		return "SMALLINT", true
 	case "serial":
-		return "SERIAL"
+		return "SERIAL", true
 	case "bigserial":
-		return "BIGSERIAL"
+		return "BIGSERIAL", true
 	case "smallserial":
-		return "SMALLSERIAL"
+		return "SMALLSERIAL", true
 	case "boolean":
-		return "BOOLEAN"
+		return "BOOLEAN", true
 	case "real":
-		return "REAL"
+		return "REAL", true
 	case "double precision":
-		return "DOUBLE PRECISION"
+		return "DOUBLE PRECISION", true
 	case "numeric", "decimal":
 		if col.NumericPrecision.Valid && col.NumericScale.Valid {
-			return fmt.Sprintf("DECIMAL(%d,%d)", col.NumericPrecision.Int64, col.NumericScale.Int64)
+			return fmt.Sprintf("DECIMAL(%d,%d)", col.NumericPrecision.Int64, col.NumericScale.Int64), true
 		} else if col.NumericPrecision.Valid {
-			return fmt.Sprintf("DECIMAL(%d)", col.NumericPrecision.Int64)
+			return fmt.Sprintf("DECIMAL(%d)", col.NumericPrecision.Int64), true
 		}
-		return "DECIMAL"
+		return "DECIMAL", true
 	case "money":
-		return "MONEY"
+		return "MONEY", true
 	case "timestamp without time zone":
-		return "TIMESTAMP"
+		return withDatetimePrecision("TIMESTAMP", col), true
 	case "timestamp with time zone":
-		return "TIMESTAMPTZ"
+		return withDatetimePrecision("TIMESTAMPTZ", col), true
 	case "date":
-		return "DATE"
+		return "DATE", true
 	case "time without time zone":
-		return "TIME"
+		return withDatetimePrecision("TIME", col), true
 	case "time with time zone":
-		return "TIMETZ"
+		return withDatetimePrecision("TIMETZ", col), true
 	case "interval":
-		return "INTERVAL"
+		return "INTERVAL", true
 	case "uuid":
-		return "UUID"
+		return "UUID", true
 	case "json":
-		return "JSON"
+		return "JSON", true
 	case "jsonb":
-		return "JSONB"
+		return "JSONB", true
 	case "xml":
-		return "XML"
+		return "XML", true
 	case "bytea":
-		return "BYTEA"
+		return "BYTEA", true
 	case "bit":
-		return "BIT"
+		return "BIT", true
 	case "varbit", "bit varying":
-		return "VARBIT"
+		return "VARBIT", true
 	case "cidr":
-		return "CIDR"
+		return "CIDR", true
 	case "inet":
-		return "INET"
+		return "INET", true
 	case "macaddr":
-		return "MACADDR"
+		return "MACADDR", true
 	case "tsvector":
-		return "TSVECTOR"
+		return "TSVECTOR", true
 	case "tsquery":
-		return "TSQUERY"
+		return "TSQUERY", true
+	case "geometry", "geography", "hstore", "ltree", "lquery", "citext", "vector", "halfvec", "sparsevec":
+		return extensionType(col), true
 	default:
-		return strings.ToUpper(col.DataType)
+		return strings.ToUpper(col.DataType), false
+	}
+}
+
+// extensionType renders a type provided by an extension (PostGIS, hstore,
+// ltree, citext, pgvector), keeping modifiers such as (Point,4326) or (1536)
+func extensionType(col Column) string {
+	dataType := strings.ToUpper(col.DataType)
+	if i := strings.IndexByte(col.FullType, '('); i >= 0 {
+		dataType += col.FullType[i:]
 	}
-}
\ No newline at end of file
+	return dataType
+}
+
+// UnmappedType identifies a column whose type the formatter does not know
+type UnmappedType struct {
+	Table    string
+	Column   string
+	DataType string
+}
+
+// UnmappedTypes lists the columns whose types are passed through as-is by
+// the formatter, which usually points at an extension or custom type. Types
+// overridden in typeMap count as mapped.
+func UnmappedTypes(tables []Table, typeMap TypeMap) []UnmappedType {
+	var unmapped []UnmappedType
+	for _, table := range tables {
+		for _, col := range table.Columns {
+			if _, ok := lookupDataType(col, typeMap); !ok {
+				unmapped = append(unmapped, UnmappedType{Table: table.Name, Column: col.Name, DataType: col.DataType})
+			}
+		}
+	}
+	return unmapped
+}
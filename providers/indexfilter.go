@@ -0,0 +1,84 @@
+package providers
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// IndexFilter selects the indexes kept in an extracted schema by name. An
+// index is kept when it matches one of the Include patterns, or there are
+// none, and none of the Exclude patterns. Constraints are not affected, even
+// when an excluded index backs one.
+type IndexFilter struct {
+	Include []*regexp.Regexp
+	Exclude []*regexp.Regexp
+}
+
+// NewIndexFilter compiles include and exclude patterns, which are regular
+// expressions matched anywhere in the index name
+func NewIndexFilter(include, exclude []string) (IndexFilter, error) {
+	var filter IndexFilter
+	var err error
+	if filter.Include, err = compilePatterns(include); err != nil {
+		return IndexFilter{}, err
+	}
+	if filter.Exclude, err = compilePatterns(exclude); err != nil {
+		return IndexFilter{}, err
+	}
+	return filter, nil
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// IsEmpty reports whether the filter keeps every index
+func (f IndexFilter) IsEmpty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Keep reports whether an index with the given name is kept
+func (f IndexFilter) Keep(name string) bool {
+	if len(f.Include) > 0 && !matchesAny(f.Include, name) {
+		return false
+	}
+	return !matchesAny(f.Exclude, name)
+}
+
+func matchesAny(patterns []*regexp.Regexp, name string) bool {
+	for _, re := range patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns the tables with the filtered out indexes removed. The input
+// is not modified.
+func (f IndexFilter) Apply(tables []Table) []Table {
+	if f.IsEmpty() {
+		return tables
+	}
+
+	filtered := make([]Table, len(tables))
+	for i, table := range tables {
+		var indexes []Index
+		for _, idx := range table.Indexes {
+			if f.Keep(idx.Name) {
+				indexes = append(indexes, idx)
+			}
+		}
+		table.Indexes = indexes
+		filtered[i] = table
+	}
+	return filtered
+}
@@ -3,6 +3,9 @@ package providers
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"io"
+	"strings"
 )
 
 // SchemaProvider defines the interface for different schema extraction providers
@@ -28,21 +31,59 @@ type ExtractParams struct {
 	
 	// Format specifies the output format
 	Format SchemaFormat
+
+	// FormatOptions controls SQL rendering for providers that generate it
+	FormatOptions FormatOptions
+
+	// IncludeExtensions keeps CREATE EXTENSION statements in pg_dump output
+	IncludeExtensions bool
+
+	// IncludeExtensionObjects keeps the tables, views and sequences created
+	// by extensions, see ExtractOptions. pg_dump always leaves them out.
+	IncludeExtensionObjects bool
+
+	// CatalogDump receives the raw catalog rows the native provider read,
+	// see ExtractOptions, when set. It is meant for debugging.
+	CatalogDump io.Writer
+
+	// IncludePrivileges extracts the privileges granted on tables, which
+	// are otherwise left out of the schema
+	IncludePrivileges bool
+
+	// IndexFilter drops indexes by name before formatting. pg_dump output
+	// is not filtered.
+	IndexFilter IndexFilter
+
+	// RenameCase rewrites table, view, column, index and constraint names in
+	// the output, see RenameSchema. The pg_dump provider does not support it.
+	RenameCase RenameCase
+
+	// ExcludeSchemas are left out of pg_dump output, such as the schema of
+	// the migration ledger. Other providers only read the public schema.
+	ExcludeSchemas []string
 }
 
 // SchemaFormat represents the desired output format
 type SchemaFormat string
 
 const (
-	FormatInfo SchemaFormat = "info" // Human-readable format
-	FormatSQL  SchemaFormat = "sql"  // SQL DDL format
+	FormatInfo       SchemaFormat = "info"       // Human-readable format
+	FormatSQL        SchemaFormat = "sql"        // SQL DDL format
+	FormatTypeScript SchemaFormat = "typescript" // TypeScript interfaces
+	FormatPrisma     SchemaFormat = "prisma"     // Prisma schema
+	FormatSQLAlchemy SchemaFormat = "sqlalchemy" // SQLAlchemy declarative models
+	FormatAtlas      SchemaFormat = "atlas"      // Atlas HCL
+	FormatMarkdown   SchemaFormat = "markdown"   // Markdown data dictionary
+	FormatJSON       SchemaFormat = "json"       // The extracted schema as JSON
 )
 
 // SchemaResult contains the extracted schema in the requested format
 type SchemaResult struct {
-	// Tables contains parsed table information (for info format)
-	Tables []Table
-	
+	// Schema holds the parsed objects. Providers that only produce SQL,
+	// such as pg_dump, leave it empty apart from the server version. Its
+	// fields are promoted, so result.Tables still names the tables.
+	Schema
+
 	// RawSQL contains the raw SQL DDL (for sql format)
 	RawSQL string
 	
@@ -62,7 +103,31 @@ func NewProviderRegistry() *ProviderRegistry {
 	}
 }
 
-// Register adds a provider to the registry
+// RegistryOption configures a registry built by NewDefaultRegistry
+type RegistryOption func(*ProviderRegistry)
+
+// WithProvider registers a custom provider, such as another SQL dialect or a
+// test double. It replaces a built-in provider of the same name.
+func WithProvider(provider SchemaProvider) RegistryOption {
+	return func(r *ProviderRegistry) {
+		r.Register(provider)
+	}
+}
+
+// NewDefaultRegistry creates a registry holding the built-in native and
+// pg_dump providers, then applies opts
+func NewDefaultRegistry(opts ...RegistryOption) *ProviderRegistry {
+	r := NewProviderRegistry()
+	r.Register(NewNativeProvider())
+	r.Register(NewPgDumpProvider())
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Register adds a provider to the registry, replacing any provider with the
+// same name
 func (r *ProviderRegistry) Register(provider SchemaProvider) {
 	r.providers[provider.Name()] = provider
 }
@@ -82,4 +147,27 @@ func (r *ProviderRegistry) ListAvailable() []string {
 		}
 	}
 	return available
-}
\ No newline at end of file
+}
+
+// GetFirstAvailable returns the first provider of names, in order, that is
+// registered and available. The error lists every name tried and why it was
+// skipped.
+func (r *ProviderRegistry) GetFirstAvailable(names []string) (SchemaProvider, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no provider given")
+	}
+
+	tried := make([]string, len(names))
+	for i, name := range names {
+		provider, exists := r.providers[name]
+		switch {
+		case !exists:
+			tried[i] = name + " (unknown)"
+		case !provider.IsAvailable():
+			tried[i] = name + " (not available)"
+		default:
+			return provider, nil
+		}
+	}
+	return nil, fmt.Errorf("no available provider, tried: %s", strings.Join(tried, ", "))
+}
@@ -0,0 +1,38 @@
+package providers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubProvider struct{ name string }
+
+func (p stubProvider) Name() string { return p.name }
+
+func (p stubProvider) ExtractSchema(ctx context.Context, params ExtractParams) (*SchemaResult, error) {
+	return &SchemaResult{Format: params.Format}, nil
+}
+
+func (p stubProvider) IsAvailable() bool { return true }
+
+func TestNewDefaultRegistry(t *testing.T) {
+	registry := NewDefaultRegistry()
+	native, ok := registry.Get("native")
+	require.True(t, ok)
+	assert.IsType(t, &NativeProvider{}, native)
+	_, ok = registry.Get("pg_dump")
+	assert.True(t, ok)
+	_, ok = registry.Get("cockroach")
+	assert.False(t, ok)
+
+	registry = NewDefaultRegistry(WithProvider(stubProvider{name: "cockroach"}), WithProvider(stubProvider{name: "native"}))
+	provider, ok := registry.Get("cockroach")
+	require.True(t, ok)
+	assert.Equal(t, stubProvider{name: "cockroach"}, provider)
+	provider, ok = registry.Get("native")
+	require.True(t, ok)
+	assert.Equal(t, stubProvider{name: "native"}, provider)
+}
@@ -0,0 +1,101 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JSONOptions controls the JSON output
+type JSONOptions struct {
+	// Compact writes the schema on a single line instead of indenting it
+	Compact bool
+}
+
+// FormatSchemaJSON formats the schema as indented JSON. Tables use the same
+// representation generate_migration accepts as a target schema, so the
+// output can be edited and fed back as one.
+func FormatSchemaJSON(schema Schema) (string, error) {
+	return FormatSchemaJSONWithOptions(schema, JSONOptions{})
+}
+
+// FormatSchemaJSONWithOptions formats the schema as JSON like
+// FormatSchemaJSON, on a single line with Compact
+func FormatSchemaJSONWithOptions(schema Schema, opts JSONOptions) (string, error) {
+	var sb strings.Builder
+	if err := WriteSchemaJSON(&sb, schema, opts); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// WriteSchemaJSON writes the schema to w as FormatSchemaJSONWithOptions
+// formats it. Tables are encoded one at a time with a json.Encoder and
+// written as they are, so the tables are never held in memory as a whole
+// document. The output is the same as encoding the whole schema with
+// json.MarshalIndent, or json.Marshal with Compact, followed by a newline.
+func WriteSchemaJSON(w io.Writer, schema Schema, opts JSONOptions) error {
+	tables := normalizeSchema(schema.Tables)
+
+	// Everything but the tables is encoded from the struct tags of Schema,
+	// and the tables are written in place of the empty list
+	schema.Tables = []Table{}
+	var head []byte
+	var err error
+	placeholder, tableIndent, closeTables := `"tables": []`, "\n    ", "\n  "
+	if opts.Compact {
+		head, err = json.Marshal(schema)
+		placeholder, tableIndent, closeTables = `"tables":[]`, "", ""
+	} else {
+		head, err = json.MarshalIndent(schema, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode schema: %w", err)
+	}
+	// Keys and string values are quoted with their quotes escaped, so the
+	// first match is the tables member
+	before, after, ok := bytes.Cut(head, []byte(placeholder))
+	if !ok {
+		return fmt.Errorf("failed to encode schema: no tables member")
+	}
+
+	if len(tables) == 0 {
+		_, err := w.Write(append(head, '\n'))
+		return err
+	}
+
+	if _, err := w.Write(before); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, placeholder[:len(placeholder)-1]); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if !opts.Compact {
+		enc.SetIndent("    ", "  ")
+	}
+	for i, table := range tables {
+		buf.Reset()
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(tableIndent)
+		if err := enc.Encode(table); err != nil {
+			return fmt.Errorf("failed to encode table %s: %w", table.Name, err)
+		}
+		// Drop the newline the encoder ends each value with
+		if _, err := w.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.WriteString(w, closeTables+"]"); err != nil {
+		return err
+	}
+	_, err = w.Write(append(after, '\n'))
+	return err
+}
@@ -0,0 +1,141 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// FormatSchemaMarkdown formats the schema as a Markdown data dictionary: a
+// table of contents linking to one section per table, each with a table of
+// its columns and a list of its indexes. Views are left out and partitions
+// only link to their parent. The comment column is left blank since
+// comments are not extracted.
+func FormatSchemaMarkdown(tables []Table) string {
+	tables = normalizeSchema(tables)
+
+	var documented []Table
+	for _, table := range tables {
+		if !table.IsView {
+			documented = append(documented, table)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Data Dictionary\n\n")
+	for _, table := range documented {
+		sb.WriteString(fmt.Sprintf("- [%s](#%s)\n", escapeMarkdown(table.Name), markdownTableAnchor(table.Name)))
+	}
+
+	for _, table := range documented {
+		sb.WriteString(fmt.Sprintf("\n## %s\n\n", markdownTableHeading(table.Name)))
+		if table.IsPartition() {
+			sb.WriteString(fmt.Sprintf("Partition of [%s](#%s) %s.\n",
+				escapeMarkdown(table.PartitionOf), markdownTableAnchor(table.PartitionOf), escapeMarkdown(table.PartitionBound)))
+			continue
+		}
+		if table.PartitionStrategy != "" {
+			sb.WriteString(fmt.Sprintf("Partitioned by %s (%s).\n\n", table.PartitionStrategy, escapeMarkdown(table.PartitionKey)))
+		}
+		writeMarkdownColumns(&sb, table)
+		writeMarkdownIndexes(&sb, table)
+	}
+	return sb.String()
+}
+
+// writeMarkdownColumns writes the column table of a table
+func writeMarkdownColumns(sb *strings.Builder, table Table) {
+	sb.WriteString("| Column | Type | Nullable | Default | PK | References | Comment |\n")
+	sb.WriteString("| --- | --- | --- | --- | --- | --- | --- |\n")
+	for _, col := range table.Columns {
+		nullable := "no"
+		if col.IsNullable {
+			nullable = "yes"
+		}
+		defaultValue := ""
+		if col.DefaultValue.Valid && !col.IsSerial {
+			defaultValue = markdownCode(col.DefaultValue.String)
+		}
+		pk := ""
+		if col.IsPrimaryKey {
+			pk = "yes"
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s |  |\n",
+			escapeMarkdown(col.Name), markdownCode(sqlDataType(col, nil)), nullable, defaultValue, pk,
+			markdownReferences(table.ForeignKeys, col.Name)))
+	}
+}
+
+// writeMarkdownIndexes writes the index list of a table, if it has any
+func writeMarkdownIndexes(sb *strings.Builder, table Table) {
+	if len(table.Indexes) == 0 {
+		return
+	}
+	sb.WriteString("\n**Indexes**\n\n")
+	for _, idx := range table.Indexes {
+		var details []string
+		if idx.IsUnique {
+			details = append(details, "unique")
+		}
+		if idx.Method != "" {
+			details = append(details, "using "+idx.Method)
+		}
+		line := fmt.Sprintf("- %s on (%s)", markdownCode(idx.Name), escapeMarkdown(strings.Join(idx.Columns, ", ")))
+		if len(details) > 0 {
+			line += ", " + strings.Join(details, ", ")
+		}
+		sb.WriteString(line + "\n")
+	}
+}
+
+// markdownReferences links the columns referenced by the foreign keys that
+// include column, such as [users](#table-users).id
+func markdownReferences(foreignKeys []ForeignKey, column string) string {
+	var references []string
+	for _, fk := range foreignKeys {
+		for i, name := range fk.Columns {
+			if name == column && i < len(fk.ReferencedColumns) {
+				references = append(references, fmt.Sprintf("[%s](#%s).%s",
+					escapeMarkdown(fk.ReferencedTable), markdownTableAnchor(fk.ReferencedTable), escapeMarkdown(fk.ReferencedColumns[i])))
+			}
+		}
+	}
+	return strings.Join(references, ", ")
+}
+
+func markdownTableHeading(name string) string {
+	return "Table: " + name
+}
+
+// markdownTableAnchor returns the anchor GitHub generates for the heading of
+// a table: lower-cased, spaces turned into hyphens and punctuation other
+// than hyphens and underscores dropped
+func markdownTableAnchor(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(markdownTableHeading(name)) {
+		switch {
+		case r == ' ':
+			sb.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// markdownCode wraps text in a code span. Pipes are escaped even there,
+// since they would otherwise end the table cell.
+func markdownCode(text string) string {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\n", " "), "|", `\|`)
+	if strings.Contains(text, "`") {
+		return "`` " + text + " ``"
+	}
+	return "`" + text + "`"
+}
+
+// escapeMarkdown escapes the characters that would change the rendering of
+// a name inside a table cell or list item
+func escapeMarkdown(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "|", `\|`, "*", `\*`, "`", "\\`", "[", `\[`, "]", `\]`, "\n", " ")
+	return replacer.Replace(text)
+}
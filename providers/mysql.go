@@ -0,0 +1,391 @@
+package providers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SQL dialects FormatSchemaSQLDialect writes
+const (
+	DialectPostgres = "postgres"
+	DialectMySQL    = "mysql"
+)
+
+// ParseSQLDialect validates a SQL dialect. An empty name selects PostgreSQL.
+func ParseSQLDialect(s string) (string, error) {
+	switch s {
+	case "":
+		return DialectPostgres, nil
+	case DialectPostgres, DialectMySQL:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unsupported dialect: %s (expected postgres or mysql)", s)
+	}
+}
+
+// FormatSchemaSQLDialect formats tables as CREATE statements of the given
+// dialect. PostgreSQL output is that of FormatSchemaSQLWithOptions. MySQL
+// output is a best-effort translation for porting a schema: types are
+// mapped to their closest MySQL equivalent, identifiers are quoted with
+// backticks and serial columns become auto_increment. Text and binary
+// columns that are part of a key become varchar(255) and varbinary(255),
+// since MySQL only indexes them with a length. What has no MySQL
+// counterpart, such as exclusion constraints, GIN indexes, partitions or
+// triggers, is left out with a comment saying so. Tables are ordered and
+// foreign keys placed as in PostgreSQL output. The output should be reviewed
+// before use.
+func FormatSchemaSQLDialect(tables []Table, dialect string, opts FormatOptions) string {
+	if dialect != DialectMySQL {
+		return FormatSchemaSQLWithOptions(tables, opts)
+	}
+
+	ordered, deferred := orderTablesByDependency(normalizeSchema(tables), opts.TableOrder == TableOrderCreated)
+	if opts.ForeignKeyStyle == ForeignKeyAlter {
+		deferred = allForeignKeys(ordered)
+	}
+	omitted := omittedForeignKeys(deferred)
+
+	var sb strings.Builder
+	for _, table := range ordered {
+		switch {
+		case table.IsView:
+			continue
+		case table.IsPartition():
+			sb.WriteString(fmt.Sprintf("-- %s: partition of %s left out, MySQL partitions are declared on the table\n\n", table.Name, table.PartitionOf))
+			continue
+		}
+		writeMySQLCreateTable(&sb, table, omitted[table.Name])
+	}
+
+	for _, d := range deferred {
+		sb.WriteString(fmt.Sprintf("alter table %s add %s;\n", mysqlQuoteIdent(d.Table), mysqlForeignKeyDefinition(d.ForeignKey)))
+	}
+	if len(deferred) > 0 {
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// writeMySQLCreateTable writes the CREATE TABLE statement of a table and its
+// indexes, preceded by comments on what could not be translated
+func writeMySQLCreateTable(sb *strings.Builder, table Table, omitFKs map[string]bool) {
+	keyColumns := mysqlKeyColumns(table)
+	var notes, definitions, primaryKeys []string
+	for _, col := range table.Columns {
+		definition, columnNotes := mysqlColumnDefinition(col, keyColumns[col.Name])
+		definitions = append(definitions, "    "+definition)
+		for _, note := range columnNotes {
+			notes = append(notes, fmt.Sprintf("%s.%s: %s", table.Name, col.Name, note))
+		}
+		if col.IsPrimaryKey {
+			primaryKeys = append(primaryKeys, col.Name)
+		}
+	}
+
+	if len(primaryKeys) > 0 {
+		definitions = append(definitions, fmt.Sprintf("    primary key (%s)", mysqlQuoteIdents(primaryKeys)))
+	}
+	for _, uc := range table.UniqueConstraints {
+		definitions = append(definitions, fmt.Sprintf("    constraint %s unique (%s)", mysqlQuoteIdent(uc.Name), mysqlQuoteIdents(uc.Columns)))
+	}
+	for _, fk := range table.ForeignKeys {
+		if !omitFKs[fk.Name] {
+			definitions = append(definitions, "    "+mysqlForeignKeyDefinition(fk))
+		}
+	}
+
+	for _, ec := range table.ExclusionConstraints {
+		notes = append(notes, fmt.Sprintf("%s: exclusion constraint %s left out, MySQL has none", table.Name, ec.Name))
+	}
+	if table.PartitionStrategy != "" {
+		notes = append(notes, fmt.Sprintf("%s: partition by %s (%s) left out", table.Name, table.PartitionStrategy, table.PartitionKey))
+	}
+	if len(table.Inherits) > 0 {
+		notes = append(notes, fmt.Sprintf("%s: inheritance from %s left out, MySQL has none", table.Name, strings.Join(table.Inherits, ", ")))
+	}
+	for _, trigger := range table.Triggers {
+		notes = append(notes, fmt.Sprintf("%s: trigger %s left out, its PostgreSQL definition must be rewritten", table.Name, trigger.Name))
+	}
+
+	var indexes []string
+	for _, idx := range table.Indexes {
+		statement, note := mysqlCreateIndexStatement(table.Name, idx)
+		if note != "" {
+			notes = append(notes, fmt.Sprintf("%s: %s", table.Name, note))
+			continue
+		}
+		indexes = append(indexes, statement)
+	}
+
+	for _, note := range notes {
+		sb.WriteString("-- " + note + "\n")
+	}
+	sb.WriteString(fmt.Sprintf("create table %s (\n", mysqlQuoteIdent(table.Name)))
+	if len(definitions) > 0 {
+		sb.WriteString(strings.Join(definitions, ",\n") + "\n")
+	}
+	sb.WriteString(");\n\n")
+
+	for _, statement := range indexes {
+		sb.WriteString(statement)
+	}
+	if len(indexes) > 0 {
+		sb.WriteString("\n")
+	}
+}
+
+// mysqlKeyColumns returns the columns of table that are part of its primary
+// key, a unique constraint, a foreign key or an index MySQL output keeps
+func mysqlKeyColumns(table Table) map[string]bool {
+	keys := make(map[string]bool)
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey {
+			keys[col.Name] = true
+		}
+	}
+	for _, uc := range table.UniqueConstraints {
+		for _, column := range uc.Columns {
+			keys[column] = true
+		}
+	}
+	for _, fk := range table.ForeignKeys {
+		for _, column := range fk.Columns {
+			keys[column] = true
+		}
+	}
+	for _, idx := range table.Indexes {
+		if idx.Method != "" && idx.Method != defaultIndexMethod && idx.Method != "hash" {
+			continue
+		}
+		for _, column := range idx.Columns {
+			keys[strings.Trim(column, `"`)] = true
+		}
+	}
+	return keys
+}
+
+// mysqlKeyTypes maps the MySQL types that cannot be indexed without a length
+// to the type their columns get when they are part of a key
+var mysqlKeyTypes = map[string]string{
+	"text":     "varchar(255)",
+	"longtext": "varchar(255)",
+	"longblob": "varbinary(255)",
+}
+
+// mysqlColumnDefinition renders a column as it appears inside a MySQL CREATE
+// TABLE, along with notes on what could not be translated. Columns that are
+// part of a key get a type MySQL can index.
+func mysqlColumnDefinition(col Column, key bool) (string, []string) {
+	dataType, autoIncrement, note := mysqlDataType(col)
+	var notes []string
+	if note != "" {
+		notes = append(notes, note)
+	}
+	if keyType, ok := mysqlKeyTypes[dataType]; ok && key {
+		notes = append(notes, fmt.Sprintf("%s key column written as %s", dataType, keyType))
+		dataType = keyType
+	}
+
+	var colDef strings.Builder
+	colDef.WriteString(fmt.Sprintf("%s %s", mysqlQuoteIdent(col.Name), dataType))
+	if !col.IsNullable {
+		colDef.WriteString(" not null")
+	}
+	if autoIncrement {
+		colDef.WriteString(" auto_increment")
+	} else if col.DefaultValue.Valid {
+		if value, ok := mysqlDefault(col, dataType); ok {
+			colDef.WriteString(" default " + value)
+		} else {
+			notes = append(notes, fmt.Sprintf("default %s left out", col.DefaultValue.String))
+		}
+	}
+	if col.Collation != "" {
+		notes = append(notes, fmt.Sprintf("collation %q left out", col.Collation))
+	}
+	return colDef.String(), notes
+}
+
+// mysqlDataType maps a column type to MySQL and reports whether the column
+// is auto-incremented. Types without an equivalent become text, with a note.
+func mysqlDataType(col Column) (string, bool, string) {
+	if _, ok := serialTypes[col.DataType]; ok && col.IsSerial {
+		return mysqlIntegerTypes[col.DataType], true, ""
+	}
+	if dataType, ok := mysqlIntegerTypes[col.DataType]; ok {
+		return dataType, false, ""
+	}
+
+	precision := int64(defaultDatetimePrecision)
+	if col.DatetimePrecision.Valid {
+		precision = col.DatetimePrecision.Int64
+	}
+
+	switch col.DataType {
+	case "serial":
+		return "int", true, ""
+	case "bigserial":
+		return "bigint", true, ""
+	case "smallserial":
+		return "smallint", true, ""
+	case "character varying":
+		if col.CharacterLength.Valid {
+			return fmt.Sprintf("varchar(%d)", col.CharacterLength.Int64), false, ""
+		}
+		return "varchar(255)", false, ""
+	case "character", "char":
+		if col.CharacterLength.Valid {
+			return fmt.Sprintf("char(%d)", col.CharacterLength.Int64), false, ""
+		}
+		return "char(1)", false, ""
+	case "text", "citext", "tsvector":
+		return "text", false, ""
+	case "xml":
+		return "longtext", false, ""
+	case "boolean":
+		return "tinyint(1)", false, ""
+	case "real":
+		return "float", false, ""
+	case "double precision":
+		return "double", false, ""
+	case "numeric", "decimal":
+		if col.NumericPrecision.Valid && col.NumericScale.Valid {
+			return fmt.Sprintf("decimal(%d,%d)", col.NumericPrecision.Int64, col.NumericScale.Int64), false, ""
+		} else if col.NumericPrecision.Valid {
+			return fmt.Sprintf("decimal(%d)", col.NumericPrecision.Int64), false, ""
+		}
+		return "decimal(65,30)", false, "unbounded numeric written as decimal(65,30)"
+	case "money":
+		return "decimal(19,2)", false, ""
+	case "timestamp without time zone", "timestamp with time zone":
+		return fmt.Sprintf("datetime(%d)", precision), false, ""
+	case "date":
+		return "date", false, ""
+	case "time without time zone", "time with time zone":
+		return fmt.Sprintf("time(%d)", precision), false, ""
+	case "uuid":
+		return "char(36)", false, ""
+	case "json", "jsonb", "ARRAY":
+		return "json", false, ""
+	case "bytea":
+		return "longblob", false, ""
+	case "inet", "cidr":
+		return "varchar(43)", false, ""
+	case "macaddr":
+		return "varchar(17)", false, ""
+	case "interval":
+		return "varchar(64)", false, "interval written as text"
+	case "geometry", "geography":
+		return "geometry", false, ""
+	default:
+		fullType := col.FullType
+		if fullType == "" {
+			fullType = col.DataType
+		}
+		return "text", false, fmt.Sprintf("type %s has no MySQL equivalent, written as text", fullType)
+	}
+}
+
+// mysqlIntegerTypes maps PostgreSQL integer types to MySQL
+var mysqlIntegerTypes = map[string]string{
+	"smallint": "smallint",
+	"integer":  "int",
+	"bigint":   "bigint",
+}
+
+var (
+	mysqlLiteralPattern     = regexp.MustCompile(`^(-?[0-9]+(\.[0-9]+)?|'(?:[^']|'')*')$`)
+	mysqlCurrentTimePattern = regexp.MustCompile(`^(?i)(now\(\)|current_timestamp|localtimestamp|transaction_timestamp\(\)|statement_timestamp\(\)|clock_timestamp\(\))$`)
+)
+
+// mysqlDefault translates a column default to MySQL and reports whether it
+// could. Literal casts are dropped, booleans become 0 and 1 and well-known
+// functions their MySQL counterparts. Other expressions are kept as
+// expression defaults, which MySQL accepts from 8.0.13. Sequence defaults
+// cannot be translated.
+func mysqlDefault(col Column, dataType string) (string, bool) {
+	value := strings.TrimSpace(literalCastPattern.ReplaceAllString(col.DefaultValue.String, "$1"))
+	switch {
+	case strings.HasPrefix(value, "nextval("):
+		return "", false
+	case value == "true" || value == "false":
+		if value == "true" {
+			return "1", true
+		}
+		return "0", true
+	case mysqlCurrentTimePattern.MatchString(value):
+		if strings.HasPrefix(dataType, "datetime(") && dataType != "datetime(0)" {
+			return "current_timestamp" + strings.TrimPrefix(dataType, "datetime"), true
+		}
+		return "current_timestamp", true
+	case strings.EqualFold(value, "current_date"):
+		return "(curdate())", true
+	case value == "gen_random_uuid()" || value == "uuid_generate_v4()":
+		return "(uuid())", true
+	case mysqlLiteralPattern.MatchString(value):
+		// Text, blob and JSON columns only take expression defaults
+		switch dataType {
+		case "text", "longtext", "longblob", "json", "geometry":
+			return "(" + value + ")", true
+		}
+		return value, true
+	default:
+		return "(" + value + ")", true
+	}
+}
+
+// mysqlForeignKeyDefinition renders a foreign key as a MySQL table
+// constraint
+func mysqlForeignKeyDefinition(fk ForeignKey) string {
+	definition := fmt.Sprintf("constraint %s foreign key (%s) references %s (%s)",
+		mysqlQuoteIdent(fk.Name), mysqlQuoteIdents(fk.Columns), mysqlQuoteIdent(fk.ReferencedTable), mysqlQuoteIdents(fk.ReferencedColumns))
+	if actions := fk.referentialActions(false); actions != "" {
+		definition += " " + actions
+	}
+	return definition
+}
+
+// mysqlCreateIndexStatement returns the CREATE INDEX statement of an index,
+// or a note when MySQL has no matching index type. Expressions become
+// functional key parts.
+func mysqlCreateIndexStatement(tableName string, idx Index) (string, string) {
+	method := ""
+	switch idx.Method {
+	case "", defaultIndexMethod:
+	case "hash":
+		method = " using hash"
+	default:
+		return "", fmt.Sprintf("%s index %s left out, MySQL has no equivalent", idx.Method, idx.Name)
+	}
+
+	unique := ""
+	if idx.IsUnique {
+		unique = "unique "
+	}
+	keyParts := make([]string, len(idx.Columns))
+	for i, column := range idx.Columns {
+		if strings.ContainsAny(column, "( ") {
+			keyParts[i] = "(" + column + ")"
+		} else {
+			keyParts[i] = mysqlQuoteIdent(strings.Trim(column, `"`))
+		}
+	}
+	return fmt.Sprintf("create %sindex %s on %s (%s)%s;\n",
+		unique, mysqlQuoteIdent(idx.Name), mysqlQuoteIdent(tableName), strings.Join(keyParts, ", "), method), ""
+}
+
+// mysqlQuoteIdent quotes name with backticks
+func mysqlQuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// mysqlQuoteIdents quotes each name and joins them into a comma-separated
+// list
+func mysqlQuoteIdents(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = mysqlQuoteIdent(name)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -32,24 +32,43 @@ func (p *NativeProvider) ExtractSchema(ctx context.Context, params ExtractParams
 
 	slog.Debug("extracting schema using native provider", "format", params.Format)
 
-	// Extract tables using the SQL queries
-	tables, err := ExtractSchemaFromDB(params.DB)
+	// Extract tables and functions using the SQL queries
+	schema, err := ExtractFullSchemaWithOptions(params.DB, ExtractOptions{
+		IncludeExtensionObjects: params.IncludeExtensionObjects,
+		CatalogDump:             params.CatalogDump,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract schema: %w", err)
 	}
+	if params.IncludePrivileges {
+		grants, err := getGrants(params.DB, "public")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get grants: %w", err)
+		}
+		for i := range schema.Tables {
+			schema.Tables[i].Grants = grants[schema.Tables[i].Name]
+		}
+	}
+	schema.Tables = params.IndexFilter.Apply(schema.Tables)
+	schema, err = RenameSchema(schema, params.RenameCase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename identifiers: %w", err)
+	}
+	schema.Tables = OrderTables(schema.Tables, params.FormatOptions.TableOrder)
+	schema.Tables = OrderColumns(schema.Tables, params.FormatOptions.ColumnOrder)
 
 	result := &SchemaResult{
-		Tables: tables,
+		Schema: schema,
 		Format: params.Format,
 	}
 
 	// Format based on requested format
 	switch params.Format {
 	case FormatSQL:
-		result.RawSQL = FormatSchemaSQL(tables)
-	case FormatInfo:
-		// For info format, we'll handle formatting at the output layer
-		// Just return the tables
+		result.RawSQL = FormatFullSchemaSQL(schema, params.FormatOptions)
+	case FormatInfo, FormatTypeScript, FormatPrisma, FormatSQLAlchemy, FormatAtlas, FormatMarkdown, FormatJSON:
+		// For info and the model formats, we'll handle formatting at the
+		// output layer. Just return the tables
 	default:
 		return nil, fmt.Errorf("unsupported format: %s", params.Format)
 	}
@@ -0,0 +1,239 @@
+package providers
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// deferredForeignKey is a foreign key that has to be added with ALTER TABLE
+// once every table it involves exists
+type deferredForeignKey struct {
+	Table      string
+	ForeignKey ForeignKey
+}
+
+// orderTablesByDependency sorts tables so that tables referenced by foreign
+// keys come before the tables referencing them, keeping alphabetical order
+// among tables that are ready at the same time. References to tables outside
+// the given set are ignored. When the remaining tables form a cycle, the
+// alphabetically first one is emitted and its foreign keys to tables not yet
+// emitted are returned as deferred. Partitions and inheriting tables come
+// after their parents. With keepOrder, the input order is kept wherever the
+// dependencies allow it, instead of the alphabetical order.
+func orderTablesByDependency(tables []Table, keepOrder bool) ([]Table, []deferredForeignKey) {
+	byName := make(map[string]Table, len(tables))
+	for _, table := range tables {
+		byName[table.Name] = table
+	}
+
+	remaining := make([]string, 0, len(tables))
+	for _, table := range tables {
+		remaining = append(remaining, table.Name)
+	}
+	if !keepOrder {
+		sort.Strings(remaining)
+	}
+
+	emitted := make(map[string]bool, len(tables))
+	// parentsEmitted reports whether the partition parent and inherited
+	// tables of a table, which have to exist first, have been emitted
+	parentsEmitted := func(name string) bool {
+		parents := byName[name].Inherits
+		if byName[name].IsPartition() {
+			parents = []string{byName[name].PartitionOf}
+		}
+		for _, parent := range parents {
+			if _, inSet := byName[parent]; inSet && !emitted[parent] {
+				return false
+			}
+		}
+		return true
+	}
+	ready := func(name string) bool {
+		if !parentsEmitted(name) {
+			return false
+		}
+		// A partition shares the foreign keys of its parent
+		if byName[name].IsPartition() {
+			return true
+		}
+		for _, fk := range byName[name].ForeignKeys {
+			if _, inSet := byName[fk.ReferencedTable]; inSet && fk.ReferencedTable != name && !emitted[fk.ReferencedTable] {
+				return false
+			}
+		}
+		return true
+	}
+
+	var ordered []Table
+	var deferred []deferredForeignKey
+	for len(remaining) > 0 {
+		var level, rest []string
+		for _, name := range remaining {
+			// Keeping the input order means emitting one table at a time,
+			// so a later table never overtakes an earlier ready one
+			if ready(name) && !(keepOrder && len(level) > 0) {
+				level = append(level, name)
+			} else {
+				rest = append(rest, name)
+			}
+		}
+
+		if len(level) == 0 {
+			// Every remaining table waits on another one: break the cycle
+			// at a table whose parents exist, since only foreign keys can
+			// be deferred
+			at := 0
+			for i, candidate := range rest {
+				if parentsEmitted(candidate) {
+					at = i
+					break
+				}
+			}
+			name := rest[at]
+			for _, fk := range byName[name].ForeignKeys {
+				if _, inSet := byName[fk.ReferencedTable]; inSet && fk.ReferencedTable != name && !emitted[fk.ReferencedTable] {
+					deferred = append(deferred, deferredForeignKey{Table: name, ForeignKey: fk})
+				}
+			}
+			level, rest = []string{name}, append(rest[:at:at], rest[at+1:]...)
+		}
+
+		for _, name := range level {
+			emitted[name] = true
+			ordered = append(ordered, byName[name])
+		}
+		remaining = rest
+	}
+
+	return ordered, deferred
+}
+
+// allForeignKeys returns every foreign key of tables as deferred, for the
+// ForeignKeyAlter style. Partitions share the foreign keys of their parent.
+func allForeignKeys(tables []Table) []deferredForeignKey {
+	var deferred []deferredForeignKey
+	for _, table := range tables {
+		if table.IsPartition() {
+			continue
+		}
+		for _, fk := range table.ForeignKeys {
+			deferred = append(deferred, deferredForeignKey{Table: table.Name, ForeignKey: fk})
+		}
+	}
+	return deferred
+}
+
+// omittedForeignKeys indexes deferred foreign keys by table and name, in the
+// shape writeCreateTable expects
+func omittedForeignKeys(deferred []deferredForeignKey) map[string]map[string]bool {
+	omitted := make(map[string]map[string]bool)
+	for _, d := range deferred {
+		if omitted[d.Table] == nil {
+			omitted[d.Table] = make(map[string]bool)
+		}
+		omitted[d.Table][d.ForeignKey.Name] = true
+	}
+	return omitted
+}
+
+// writeDeferredForeignKeys adds foreign keys that were left out of CREATE TABLE
+func writeDeferredForeignKeys(sb *strings.Builder, deferred []deferredForeignKey, ifNotExists bool) {
+	for _, d := range deferred {
+		statement := fmt.Sprintf("alter table %s add %s;\n", quoteIdent(d.Table), foreignKeyDefinition(d.ForeignKey))
+		if ifNotExists {
+			// ADD CONSTRAINT has no IF NOT EXISTS
+			statement = ignoreDuplicate(statement)
+		}
+		sb.WriteString(statement)
+	}
+}
+
+// OrderTables returns the tables arranged according to order. The created
+// order relies on ObjectID; the dependency order is only meaningful when the
+// foreign keys were extracted. The input is not modified.
+func OrderTables(tables []Table, order TableOrder) []Table {
+	ordered := make([]Table, len(tables))
+	copy(ordered, tables)
+
+	switch order {
+	case TableOrderCreated:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].ObjectID < ordered[j].ObjectID
+		})
+	case TableOrderDependency:
+		ordered, _ = orderTablesByDependency(ordered, false)
+	default:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].Name < ordered[j].Name
+		})
+	}
+	return ordered
+}
+
+// OrderColumns returns the tables with their columns arranged according to
+// order. The logical order is stable, so columns within each group keep their
+// physical order. The input is not modified.
+func OrderColumns(tables []Table, order ColumnOrder) []Table {
+	if order != ColumnOrderLogical {
+		return tables
+	}
+
+	ordered := make([]Table, len(tables))
+	for i, table := range tables {
+		columns := make([]Column, len(table.Columns))
+		copy(columns, table.Columns)
+		sort.SliceStable(columns, func(a, b int) bool {
+			return logicalColumnRank(columns[a]) < logicalColumnRank(columns[b])
+		})
+		table.Columns = columns
+		ordered[i] = table
+	}
+	return ordered
+}
+
+// logicalColumnRank groups primary key columns before required columns and
+// required columns before nullable ones
+func logicalColumnRank(col Column) int {
+	switch {
+	case col.IsPrimaryKey:
+		return 0
+	case !col.IsNullable:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// normalizeSchema returns the tables with their indexes, unique and exclusion
+// constraints, foreign keys and triggers sorted by name, so that output does
+// not depend on the order the catalog returned them in. Columns within a key
+// keep the order of the key definition, which extraction preserves. Column
+// defaults lose their redundant public. qualification, see unqualifyDefault.
+// The input is not modified.
+func normalizeSchema(tables []Table) []Table {
+	normalized := make([]Table, len(tables))
+	for i, table := range tables {
+		table.Columns = unqualifyDefaults(table.Columns)
+		table.Indexes = sortedByName(table.Indexes, func(idx Index) string { return idx.Name })
+		table.UniqueConstraints = sortedByName(table.UniqueConstraints, func(uc UniqueConstraint) string { return uc.Name })
+		table.ExclusionConstraints = sortedByName(table.ExclusionConstraints, func(ec ExclusionConstraint) string { return ec.Name })
+		table.ForeignKeys = sortedByName(table.ForeignKeys, func(fk ForeignKey) string { return fk.Name })
+		table.Triggers = sortedByName(table.Triggers, func(trigger Trigger) string { return trigger.Name })
+		normalized[i] = table
+	}
+	return normalized
+}
+
+// sortedByName returns a copy of items sorted by name, or items itself when
+// it is empty
+func sortedByName[T any](items []T, name func(T) string) []T {
+	if len(items) == 0 {
+		return items
+	}
+	sorted := slices.Clone(items)
+	sort.SliceStable(sorted, func(i, j int) bool { return name(sorted[i]) < name(sorted[j]) })
+	return sorted
+}
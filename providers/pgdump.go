@@ -7,9 +7,14 @@ import (
 	"log/slog"
 	"net/url"
 	"os/exec"
+	"regexp"
 	"strings"
 )
 
+// createExtensionPattern matches a CREATE EXTENSION statement and captures
+// the extension name
+var createExtensionPattern = regexp.MustCompile(`^CREATE EXTENSION (?:IF NOT EXISTS )?("[^"]+"|[\w-]+)`)
+
 // PgDumpProvider uses the pg_dump binary to extract schema
 type PgDumpProvider struct{}
 
@@ -39,24 +44,38 @@ func (p *PgDumpProvider) ExtractSchema(ctx context.Context, params ExtractParams
 	if params.Format != FormatSQL {
 		return nil, fmt.Errorf("pg_dump provider only supports SQL format")
 	}
+	if params.FormatOptions.IfNotExists {
+		return nil, fmt.Errorf("pg_dump provider does not support IF NOT EXISTS guards")
+	}
+	if params.RenameCase != "" && params.RenameCase != RenameCasePreserve {
+		return nil, fmt.Errorf("pg_dump provider does not support renaming identifiers")
+	}
 
 	slog.Debug("extracting schema using pg_dump provider")
 
 	// Validate connection string format
-	_, err := url.Parse(params.ConnectionString)
+	connURL, err := url.Parse(params.ConnectionString)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+		return nil, fmt.Errorf("failed to parse connection string")
 	}
 
 	// Build pg_dump command
 	args := []string{
 		"--schema-only",    // Only dump schema, no data
 		"--no-owner",       // Don't include ownership information
-		"--no-privileges",  // Don't include privilege information
 		"--no-tablespaces", // Don't include tablespace information
 		"--no-comments",    // Don't include comments
-		params.ConnectionString,
 	}
+	if !params.IncludePrivileges {
+		args = append(args, "--no-privileges") // Don't include privilege information
+	}
+	if params.FormatOptions.Clean {
+		args = append(args, "--clean", "--if-exists")
+	}
+	for _, schema := range params.ExcludeSchemas {
+		args = append(args, "--exclude-schema="+schema)
+	}
+	args = append(args, params.ConnectionString)
 
 	cmd := exec.CommandContext(ctx, "pg_dump", args...)
 
@@ -64,117 +83,125 @@ func (p *PgDumpProvider) ExtractSchema(ctx context.Context, params ExtractParams
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	slog.Debug("executing pg_dump", "command", cmd.String())
+	slog.Debug("executing pg_dump", "database", connURL.Redacted())
 
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("pg_dump failed: %w\nstderr: %s", err, stderr.String())
+		return nil, pgDumpError(err, stderr.String())
 	}
 
 	rawSQL := stdout.String()
 
 	// Clean up the output
-	rawSQL = p.cleanupPgDumpOutput(rawSQL)
+	rawSQL = p.cleanupPgDumpOutput(rawSQL, params.IncludeExtensions)
 
-	return &SchemaResult{
+	result := &SchemaResult{
 		RawSQL: rawSQL,
 		Format: FormatSQL,
-	}, nil
+	}
+
+	// The version is informational, so a failure does not fail the dump
+	if params.DB != nil {
+		if version, err := getServerVersion(params.DB); err != nil {
+			slog.Debug("failed to get server version", "error", err)
+		} else {
+			result.ServerVersion = version
+		}
+	}
+
+	return result, nil
 }
 
-// cleanupPgDumpOutput removes unnecessary parts from pg_dump output
-func (p *PgDumpProvider) cleanupPgDumpOutput(sql string) string {
-	lines := strings.Split(sql, "\n")
-	var cleaned []string
-	skipSection := false
+// pgDumpAuthFailures are stderr fragments reported by libpq when the server
+// rejects the credentials
+var pgDumpAuthFailures = []string{
+	"password authentication failed",
+	"no password supplied",
+	"no pg_hba.conf entry",
+	"role \"",
+}
 
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
+// pgDumpError turns a failed pg_dump run into an error carrying its message,
+// calling out authentication failures
+func pgDumpError(err error, stderr string) error {
+	message := strings.TrimSpace(stderr)
+	message = strings.TrimPrefix(message, "pg_dump: error: ")
+	if message == "" {
+		return fmt.Errorf("pg_dump failed: %w", err)
+	}
 
-		// Skip empty lines and comments
-		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
-			continue
+	for _, fragment := range pgDumpAuthFailures {
+		if strings.Contains(message, fragment) {
+			return fmt.Errorf("pg_dump could not authenticate, check the user and password in the connection string: %s (%w)", message, err)
 		}
+	}
+	return fmt.Errorf("pg_dump failed: %s (%w)", message, err)
+}
 
-		// Skip PostgreSQL extension-related statements
-		if strings.Contains(trimmed, "CREATE EXTENSION") ||
-			strings.Contains(trimmed, "COMMENT ON EXTENSION") {
-			continue
-		}
+// cleanupPgDumpOutput removes unnecessary parts from pg_dump output.
+// The output is split into statements first, respecting quotes and
+// dollar-quoted bodies, so every decision applies to a whole statement and a
+// function body containing lines such as "SET ..." is left intact.
+// CREATE EXTENSION statements are moved to the top when includeExtensions is
+// set; otherwise they are dropped and listed in a trailing comment.
+func (p *PgDumpProvider) cleanupPgDumpOutput(sql string, includeExtensions bool) string {
+	var cleaned []string
+	var extensions, extensionNames []string
 
-		// Skip SET statements
-		if strings.HasPrefix(trimmed, "SET ") {
+	for _, stmt := range SplitStatements(sql) {
+		stmt = StripComments(stmt)
+		if stmt == "" {
 			continue
 		}
 
-		// Skip SELECT statements (usually for version checks)
-		if strings.HasPrefix(trimmed, "SELECT ") {
+		// Collect CREATE EXTENSION statements
+		if match := createExtensionPattern.FindStringSubmatch(stmt); match != nil {
+			extensionNames = append(extensionNames, strings.Trim(match[1], `"`))
+			if !strings.Contains(stmt, "IF NOT EXISTS") {
+				stmt = strings.Replace(stmt, "CREATE EXTENSION ", "CREATE EXTENSION IF NOT EXISTS ", 1)
+			}
+			extensions = append(extensions, stmt+";")
 			continue
 		}
 
-		// Skip search_path settings
-		if strings.Contains(trimmed, "search_path") {
-			skipSection = true
+		if p.skipStatement(stmt) {
 			continue
 		}
 
-		// End of search_path section
-		if skipSection && strings.HasSuffix(trimmed, ";") {
-			skipSection = false
-			continue
-		}
+		// Ensure consistent formatting
+		stmt = strings.Replace(stmt, "CREATE TABLE public.", "CREATE TABLE ", 1)
+		stmt = strings.Replace(stmt, "ALTER TABLE public.", "ALTER TABLE ", 1)
 
-		if !skipSection {
-			cleaned = append(cleaned, line)
-		}
+		cleaned = append(cleaned, stmt+";")
 	}
 
-	// Join and format
-	result := strings.Join(cleaned, "\n")
+	result := strings.Join(cleaned, "\n\n") + "\n"
 
-	// Ensure consistent formatting
-	result = strings.ReplaceAll(result, "CREATE TABLE public.", "CREATE TABLE ")
-	result = strings.ReplaceAll(result, "ALTER TABLE public.", "ALTER TABLE ")
-	result = strings.ReplaceAll(result, "CREATE INDEX", "CREATE INDEX")
-	result = strings.ReplaceAll(result, "CREATE UNIQUE INDEX", "CREATE UNIQUE INDEX")
+	if len(extensions) == 0 {
+		return result
+	}
 
-	// Normalize sequences
-	result = p.normalizeSequences(result)
+	if includeExtensions {
+		return strings.Join(extensions, "\n") + "\n\n" + result
+	}
 
-	return strings.TrimSpace(result) + "\n"
+	return result + fmt.Sprintf("\n-- extensions required by this schema (use --include-extensions to emit them): %s\n", strings.Join(extensionNames, ", "))
 }
 
-// normalizeSequences converts sequence-related statements to a more readable format
-func (p *PgDumpProvider) normalizeSequences(sql string) string {
-	// This is a simplified version - in production you might want more sophisticated parsing
-	lines := strings.Split(sql, "\n")
-	var result []string
-	skipSequence := false
-
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-
-		// Detect sequence creation
-		if strings.Contains(trimmed, "CREATE SEQUENCE") {
-			skipSequence = true
-			continue
-		}
-
-		// Skip sequence details
-		if skipSequence {
-			if strings.HasSuffix(trimmed, ";") {
-				skipSequence = false
-			}
-			continue
-		}
-
-		// Convert DEFAULT nextval() to simpler form if needed
-		if strings.Contains(line, "DEFAULT nextval(") {
-			// Keep the line as-is for now, but we could simplify it
-			result = append(result, line)
-		} else {
-			result = append(result, line)
-		}
+// skipStatement reports whether a pg_dump statement is session setup or noise
+// that does not describe the schema
+func (p *PgDumpProvider) skipStatement(stmt string) bool {
+	switch {
+	case strings.HasPrefix(stmt, "COMMENT ON EXTENSION"):
+		return true
+	// SET statements, including search_path and other session settings
+	case strings.HasPrefix(stmt, "SET "):
+		return true
+	// SELECT statements (usually pg_catalog.set_config for search_path)
+	case strings.HasPrefix(stmt, "SELECT "):
+		return true
+	// Sequences backing serial columns are implied by their defaults
+	case strings.HasPrefix(stmt, "CREATE SEQUENCE"):
+		return true
 	}
-
-	return strings.Join(result, "\n")
+	return false
 }
@@ -0,0 +1,145 @@
+package providers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const pgDumpWithExtensions = `--
+-- PostgreSQL database dump
+--
+
+SET statement_timeout = 0;
+SELECT pg_catalog.set_config('search_path', '', false);
+
+CREATE EXTENSION IF NOT EXISTS pgcrypto WITH SCHEMA public;
+
+COMMENT ON EXTENSION pgcrypto IS 'cryptographic functions';
+
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp" WITH SCHEMA public;
+
+CREATE TABLE public.users (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL
+);
+`
+
+func TestCleanupPgDumpOutputExtensions(t *testing.T) {
+	p := &PgDumpProvider{}
+
+	t.Run("stripped_by_default", func(t *testing.T) {
+		result := p.cleanupPgDumpOutput(pgDumpWithExtensions, false)
+
+		assert.NotContains(t, result, "CREATE EXTENSION")
+		assert.NotContains(t, result, "COMMENT ON EXTENSION")
+		assert.True(t, strings.HasPrefix(result, "CREATE TABLE users ("))
+		assert.True(t, strings.HasSuffix(result, "-- extensions required by this schema (use --include-extensions to emit them): pgcrypto, uuid-ossp\n"))
+	})
+
+	t.Run("included_at_top", func(t *testing.T) {
+		result := p.cleanupPgDumpOutput(pgDumpWithExtensions, true)
+
+		assert.True(t, strings.HasPrefix(result,
+			"CREATE EXTENSION IF NOT EXISTS pgcrypto WITH SCHEMA public;\n"+
+				"CREATE EXTENSION IF NOT EXISTS \"uuid-ossp\" WITH SCHEMA public;\n\n"+
+				"CREATE TABLE users ("))
+		assert.NotContains(t, result, "COMMENT ON EXTENSION")
+		assert.NotContains(t, result, "extensions required")
+	})
+
+	t.Run("no_extensions", func(t *testing.T) {
+		result := p.cleanupPgDumpOutput("CREATE TABLE public.t (\n    id integer\n);\n", false)
+		assert.Equal(t, "CREATE TABLE t (\n    id integer\n);\n", result)
+	})
+}
+
+const pgDumpWithFunction = `--
+-- PostgreSQL database dump
+--
+
+SET statement_timeout = 0;
+SET client_encoding = 'UTF8';
+SELECT pg_catalog.set_config('search_path', '', false);
+SET default_table_access_method = heap;
+
+--
+-- Name: touch(); Type: FUNCTION; Schema: public; Owner: -
+--
+
+CREATE FUNCTION public.touch() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    SET LOCAL statement_timeout = '5s';
+    SELECT now() INTO NEW.updated_at;
+    PERFORM set_config('search_path', 'public', true);
+    RETURN NEW;
+END;
+$$;
+
+--
+-- Name: users; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.users (
+    id integer NOT NULL,
+    note text DEFAULT 'SET x; SELECT y'::text,
+    updated_at timestamp without time zone
+);
+
+CREATE SEQUENCE public.users_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+`
+
+func TestCleanupPgDumpOutputStatements(t *testing.T) {
+	p := &PgDumpProvider{}
+	result := p.cleanupPgDumpOutput(pgDumpWithFunction, false)
+
+	statements := SplitStatements(result)
+	assert.Len(t, statements, 3)
+
+	assert.Contains(t, result, `CREATE FUNCTION public.touch() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    SET LOCAL statement_timeout = '5s';
+    SELECT now() INTO NEW.updated_at;
+    PERFORM set_config('search_path', 'public', true);
+    RETURN NEW;
+END;
+$$;`)
+	assert.Contains(t, result, "CREATE TABLE users (\n    id integer NOT NULL,\n    note text DEFAULT 'SET x; SELECT y'::text,")
+	assert.Contains(t, result, "ALTER TABLE ONLY public.users\n    ADD CONSTRAINT users_pkey PRIMARY KEY (id);")
+
+	assert.NotContains(t, result, "client_encoding")
+	assert.NotContains(t, result, "default_table_access_method")
+	assert.NotContains(t, result, "CREATE SEQUENCE")
+	assert.NotContains(t, result, "-- Name:")
+	assert.Empty(t, LexicalCheck(result))
+}
+
+func TestPgDumpError(t *testing.T) {
+	exitErr := errors.New("exit status 1")
+
+	err := pgDumpError(exitErr, "pg_dump: error: connection to server at \"db.example.com\" (10.0.0.1), port 5432 failed: FATAL:  password authentication failed for user \"app\"\n")
+	assert.Contains(t, err.Error(), "pg_dump could not authenticate")
+	assert.Contains(t, err.Error(), `password authentication failed for user "app"`)
+	assert.ErrorIs(t, err, exitErr)
+
+	err = pgDumpError(exitErr, "pg_dump: error: server version: 17.0; pg_dump version: 15.4\n")
+	assert.Equal(t, "pg_dump failed: server version: 17.0; pg_dump version: 15.4 (exit status 1)", err.Error())
+	assert.ErrorIs(t, err, exitErr)
+
+	err = pgDumpError(exitErr, "")
+	assert.Equal(t, "pg_dump failed: exit status 1", err.Error())
+}
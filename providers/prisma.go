@@ -0,0 +1,493 @@
+package providers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// prismaField is one line of a model block: a field name, its type with
+// modifiers and its attributes
+type prismaField struct {
+	Name       string
+	Type       string
+	Attributes []string
+}
+
+// prismaModel collects the fields and block attributes of one model
+type prismaModel struct {
+	Name            string
+	Fields          []prismaField
+	BlockAttributes []string
+	// taken holds the field names in use, so relation fields do not clash
+	taken map[string]bool
+}
+
+// addField appends a field, renaming it with suffix when its name is taken
+func (m *prismaModel) addField(field prismaField, suffix string) {
+	if m.taken[field.Name] {
+		field.Name += "_" + suffix
+	}
+	m.taken[field.Name] = true
+	m.Fields = append(m.Fields, field)
+}
+
+// FormatSchemaPrisma formats the schema as a Prisma schema with a PostgreSQL
+// datasource and one model per table. Models and fields keep the table and
+// column names, with @map where a name is not a valid Prisma identifier.
+// Foreign keys become @relation fields with back-relations on the
+// referenced model. Views and partitions are left out.
+func FormatSchemaPrisma(tables []Table) string {
+	var sb strings.Builder
+	tables = normalizeSchema(tables)
+	sb.WriteString("generator client {\n  provider = \"prisma-client-js\"\n}\n\n")
+	sb.WriteString("datasource db {\n  provider = \"postgresql\"\n  url      = env(\"DATABASE_URL\")\n}\n")
+
+	byName := make(map[string]*prismaModel)
+	var models []*prismaModel
+	for _, table := range tables {
+		if table.IsView || table.IsPartition() {
+			continue
+		}
+		model := newPrismaModel(table)
+		byName[table.Name] = model
+		models = append(models, model)
+	}
+
+	for _, table := range tables {
+		if byName[table.Name] != nil {
+			addPrismaRelations(table, byName, tables)
+		}
+	}
+
+	for _, model := range models {
+		sb.WriteString("\n")
+		writePrismaModel(&sb, model)
+	}
+
+	return sb.String()
+}
+
+// newPrismaModel builds the scalar fields and block attributes of a table
+func newPrismaModel(table Table) *prismaModel {
+	model := &prismaModel{Name: prismaIdentifier(table.Name), taken: make(map[string]bool)}
+
+	var primaryKeys []string
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey {
+			primaryKeys = append(primaryKeys, col.Name)
+		}
+	}
+
+	// Single column unique indexes and constraints become @unique on the
+	// field, the others block attributes
+	uniqueOn := make(map[string]string)
+	var uniques [][]string
+	var uniqueNames []string
+	for _, uc := range table.UniqueConstraints {
+		uniques, uniqueNames = append(uniques, uc.Columns), append(uniqueNames, uc.Name)
+	}
+	for _, idx := range table.Indexes {
+		if idx.IsUnique {
+			uniques, uniqueNames = append(uniques, idx.Columns), append(uniqueNames, idx.Name)
+		}
+	}
+	for i, columns := range uniques {
+		if len(columns) == 1 {
+			uniqueOn[columns[0]] = uniqueNames[i]
+			continue
+		}
+		model.BlockAttributes = append(model.BlockAttributes,
+			fmt.Sprintf("@@unique(%s%s)", prismaFieldList(columns), prismaMapArgument(uniqueNames[i], table.Name, columns, "key")))
+	}
+
+	for _, col := range table.Columns {
+		fieldType, nativeType := prismaType(col)
+		if col.IsNullable && !strings.HasSuffix(fieldType, "[]") {
+			fieldType += "?"
+		}
+
+		var attributes []string
+		if col.IsPrimaryKey && len(primaryKeys) == 1 {
+			attributes = append(attributes, "@id")
+		}
+		if name, ok := uniqueOn[col.Name]; ok {
+			attributes = append(attributes, "@unique"+prismaArguments(prismaMapArgument(name, table.Name, []string{col.Name}, "key")))
+		}
+		if value, ok := prismaDefault(col); ok {
+			attributes = append(attributes, fmt.Sprintf("@default(%s)", value))
+		}
+		if name := prismaIdentifier(col.Name); name != col.Name {
+			attributes = append(attributes, fmt.Sprintf("@map(%q)", col.Name))
+		}
+		if nativeType != "" {
+			attributes = append(attributes, "@db."+nativeType)
+		}
+
+		model.addField(prismaField{Name: prismaIdentifier(col.Name), Type: fieldType, Attributes: attributes}, "")
+	}
+
+	if len(primaryKeys) > 1 {
+		model.BlockAttributes = append([]string{"@@id(" + prismaFieldList(primaryKeys) + ")"}, model.BlockAttributes...)
+	}
+	for _, idx := range table.Indexes {
+		if !idx.IsUnique {
+			model.BlockAttributes = append(model.BlockAttributes,
+				fmt.Sprintf("@@index(%s%s%s)", prismaFieldList(idx.Columns), prismaMapArgument(idx.Name, table.Name, idx.Columns, "idx"), prismaIndexType(idx.Method)))
+		}
+	}
+	if model.Name != table.Name {
+		model.BlockAttributes = append(model.BlockAttributes, fmt.Sprintf("@@map(%q)", table.Name))
+	}
+	// Prisma Client needs a unique identifier to address rows
+	if len(primaryKeys) == 0 && len(uniques) == 0 {
+		model.BlockAttributes = append(model.BlockAttributes, "@@ignore")
+	}
+
+	return model
+}
+
+// addPrismaRelations adds a relation field to table for each of its foreign
+// keys, and the matching back-relation field to the referenced model. Several
+// relations between the same models, and self-relations, are named after
+// their constraint as Prisma requires.
+func addPrismaRelations(table Table, byName map[string]*prismaModel, tables []Table) {
+	model := byName[table.Name]
+
+	toTable := make(map[string]int)
+	for _, fk := range table.ForeignKeys {
+		toTable[fk.ReferencedTable]++
+	}
+
+	for _, fk := range table.ForeignKeys {
+		referenced := byName[fk.ReferencedTable]
+		if referenced == nil {
+			continue
+		}
+
+		relationName := ""
+		if toTable[fk.ReferencedTable] > 1 || fk.ReferencedTable == table.Name {
+			relationName = fmt.Sprintf("%q", fk.Name)
+		}
+
+		optional := ""
+		for _, col := range table.Columns {
+			if col.IsNullable && containsString(fk.Columns, col.Name) {
+				optional = "?"
+			}
+		}
+
+		arguments := []string{relationName,
+			"fields: " + prismaFieldList(fk.Columns),
+			"references: " + prismaFieldList(fk.ReferencedColumns)}
+		if action := prismaReferentialAction(fk.OnDelete); action != "" {
+			arguments = append(arguments, "onDelete: "+action)
+		}
+		if action := prismaReferentialAction(fk.OnUpdate); action != "" {
+			arguments = append(arguments, "onUpdate: "+action)
+		}
+		arguments = append(arguments, strings.TrimPrefix(prismaMapArgument(fk.Name, table.Name, fk.Columns, "fkey"), ", "))
+
+		model.addField(prismaField{
+			Name:       referenced.Name,
+			Type:       referenced.Name + optional,
+			Attributes: []string{"@relation" + prismaArguments(arguments...)},
+		}, strings.Join(fk.Columns, "_"))
+
+		// The back-relation is a list unless the foreign key columns are
+		// unique, which makes the relation one-to-one
+		backType := model.Name + "[]"
+		if prismaColumnsUnique(table, fk.Columns) {
+			backType = model.Name + "?"
+		}
+		var backAttributes []string
+		if relationName != "" {
+			backAttributes = append(backAttributes, "@relation("+relationName+")")
+		}
+		referenced.addField(prismaField{Name: model.Name, Type: backType, Attributes: backAttributes}, fk.Name)
+	}
+}
+
+// prismaColumnsUnique reports whether columns are the primary key of a table
+// or covered exactly by one of its unique constraints or indexes
+func prismaColumnsUnique(table Table, columns []string) bool {
+	var primaryKeys []string
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey {
+			primaryKeys = append(primaryKeys, col.Name)
+		}
+	}
+	if sameColumns(primaryKeys, columns) {
+		return true
+	}
+	for _, uc := range table.UniqueConstraints {
+		if sameColumns(uc.Columns, columns) {
+			return true
+		}
+	}
+	for _, idx := range table.Indexes {
+		if idx.IsUnique && sameColumns(idx.Columns, columns) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameColumns reports whether a and b hold the same columns in any order
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) || len(a) == 0 {
+		return false
+	}
+	for _, column := range a {
+		if !containsString(b, column) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// writePrismaModel writes a model block with its fields aligned in columns
+// the way prisma format does
+func writePrismaModel(sb *strings.Builder, model *prismaModel) {
+	nameWidth, typeWidth := 0, 0
+	for _, field := range model.Fields {
+		nameWidth = max(nameWidth, len(field.Name))
+		typeWidth = max(typeWidth, len(field.Type))
+	}
+
+	sb.WriteString(fmt.Sprintf("model %s {\n", model.Name))
+	for _, field := range model.Fields {
+		line := fmt.Sprintf("  %-*s %-*s %s", nameWidth, field.Name, typeWidth, field.Type, strings.Join(field.Attributes, " "))
+		sb.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	if len(model.BlockAttributes) > 0 {
+		sb.WriteString("\n")
+		for _, attribute := range model.BlockAttributes {
+			sb.WriteString("  " + attribute + "\n")
+		}
+	}
+	sb.WriteString("}\n")
+}
+
+// prismaType maps a column to a Prisma scalar type and, when the PostgreSQL
+// type is not the default one for that scalar, the native type attribute
+// without its @db. prefix. Types Prisma does not know are Unsupported.
+func prismaType(col Column) (string, string) {
+	if col.DataType == "ARRAY" {
+		element := strings.TrimSuffix(col.FullType, "[]")
+		dataType, _, _ := strings.Cut(element, "(")
+		fieldType, nativeType := prismaType(Column{DataType: dataType, FullType: element})
+		if strings.HasPrefix(fieldType, "Unsupported(") {
+			return fmt.Sprintf("Unsupported(%q)", col.FullType), ""
+		}
+		return fieldType + "[]", nativeType
+	}
+
+	modifier := ""
+	if i := strings.IndexByte(col.FullType, '('); i >= 0 {
+		if j := strings.IndexByte(col.FullType[i:], ')'); j >= 0 {
+			modifier = col.FullType[i : i+j+1]
+		}
+	}
+	precision := modifier
+	if precision == "" {
+		precision = "(6)"
+	}
+
+	switch col.DataType {
+	case "integer":
+		return "Int", ""
+	case "smallint":
+		return "Int", "SmallInt"
+	case "bigint":
+		return "BigInt", ""
+	case "real":
+		return "Float", "Real"
+	case "double precision":
+		return "Float", ""
+	case "numeric", "decimal":
+		if col.NumericPrecision.Valid && col.NumericScale.Valid {
+			return "Decimal", fmt.Sprintf("Decimal(%d, %d)", col.NumericPrecision.Int64, col.NumericScale.Int64)
+		}
+		return "Decimal", ""
+	case "money":
+		return "Decimal", "Money"
+	case "boolean":
+		return "Boolean", ""
+	case "text":
+		return "String", ""
+	case "character varying":
+		if col.CharacterLength.Valid {
+			return "String", fmt.Sprintf("VarChar(%d)", col.CharacterLength.Int64)
+		}
+		return "String", "VarChar" + modifier
+	case "character", "char":
+		if col.CharacterLength.Valid {
+			return "String", fmt.Sprintf("Char(%d)", col.CharacterLength.Int64)
+		}
+		return "String", "Char" + modifier
+	case "uuid":
+		return "String", "Uuid"
+	case "citext":
+		return "String", "Citext"
+	case "inet":
+		return "String", "Inet"
+	case "xml":
+		return "String", "Xml"
+	case "bit":
+		return "String", "Bit" + modifier
+	case "varbit", "bit varying":
+		return "String", "VarBit" + modifier
+	case "timestamp without time zone":
+		return "DateTime", "Timestamp" + precision
+	case "timestamp with time zone":
+		return "DateTime", "Timestamptz" + precision
+	case "date":
+		return "DateTime", "Date"
+	case "time without time zone":
+		return "DateTime", "Time" + precision
+	case "time with time zone":
+		return "DateTime", "Timetz" + precision
+	case "json":
+		return "Json", "Json"
+	case "jsonb":
+		return "Json", ""
+	case "bytea":
+		return "Bytes", ""
+	default:
+		fullType := col.FullType
+		if fullType == "" {
+			fullType = col.DataType
+		}
+		return fmt.Sprintf("Unsupported(%q)", fullType), ""
+	}
+}
+
+var (
+	prismaNumberPattern     = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+	prismaStringPattern     = regexp.MustCompile(`^'((?:[^']|'')*)'(?:::[a-z_][a-z0-9_ ]*(?:\([0-9, ]*\))?)?$`)
+	prismaIdentifierPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+)
+
+// prismaDefault returns the @default argument of a column. Sequences become
+// autoincrement(), now() and literals are kept, and other expressions are
+// wrapped in dbgenerated().
+func prismaDefault(col Column) (string, bool) {
+	if !col.DefaultValue.Valid {
+		return "", false
+	}
+	value := strings.TrimSpace(col.DefaultValue.String)
+
+	switch {
+	case col.IsSerial || strings.HasPrefix(value, "nextval("):
+		return "autoincrement()", true
+	case strings.EqualFold(value, "now()") || strings.EqualFold(value, "CURRENT_TIMESTAMP"):
+		return "now()", true
+	case value == "true" || value == "false" || prismaNumberPattern.MatchString(value):
+		return value, true
+	}
+	if match := prismaStringPattern.FindStringSubmatch(value); match != nil {
+		return fmt.Sprintf("%q", strings.ReplaceAll(match[1], "''", "'")), true
+	}
+	return fmt.Sprintf("dbgenerated(%q)", value), true
+}
+
+// prismaReferentialAction spells a referential action as a Prisma enum value
+func prismaReferentialAction(action string) string {
+	switch action {
+	case "CASCADE":
+		return "Cascade"
+	case "SET NULL":
+		return "SetNull"
+	case "SET DEFAULT":
+		return "SetDefault"
+	case "RESTRICT":
+		return "Restrict"
+	case foreignKeyNoAction:
+		return "NoAction"
+	default:
+		return ""
+	}
+}
+
+// prismaMapArgument returns `, map: "name"` when a constraint or index name
+// differs from the one Prisma would generate, table_columns_suffix, and ""
+// otherwise
+func prismaMapArgument(name, table string, columns []string, suffix string) string {
+	if name == "" || name == table+"_"+strings.Join(columns, "_")+"_"+suffix {
+		return ""
+	}
+	return fmt.Sprintf(", map: %q", name)
+}
+
+// prismaIndexTypes maps index access methods to Prisma's index types
+var prismaIndexTypes = map[string]string{
+	"hash":   "Hash",
+	"gist":   "Gist",
+	"gin":    "Gin",
+	"spgist": "SpGist",
+	"brin":   "Brin",
+}
+
+// prismaIndexType returns `, type: Gin` for an index not using btree, and ""
+// for btree and methods Prisma does not know
+func prismaIndexType(method string) string {
+	if indexType, ok := prismaIndexTypes[method]; ok {
+		return ", type: " + indexType
+	}
+	return ""
+}
+
+// prismaArguments joins the non-empty arguments in parentheses, or returns
+// "" when there are none
+func prismaArguments(arguments ...string) string {
+	var nonEmpty []string
+	for _, argument := range arguments {
+		if argument = strings.TrimPrefix(argument, ", "); argument != "" {
+			nonEmpty = append(nonEmpty, argument)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(nonEmpty, ", ") + ")"
+}
+
+// prismaFieldList renders columns as a Prisma field list such as [a, b]
+func prismaFieldList(columns []string) string {
+	fields := make([]string, len(columns))
+	for i, column := range columns {
+		fields[i] = prismaIdentifier(column)
+	}
+	return "[" + strings.Join(fields, ", ") + "]"
+}
+
+// prismaIdentifier turns a table or column name into a valid Prisma
+// identifier, replacing other characters with underscores
+func prismaIdentifier(name string) string {
+	if prismaIdentifierPattern.MatchString(name) {
+		return name
+	}
+	var sb strings.Builder
+	for _, r := range name {
+		if r == '_' || (r < 128 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')) {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	identifier := sb.String()
+	if identifier == "" || !(identifier[0] >= 'a' && identifier[0] <= 'z' || identifier[0] >= 'A' && identifier[0] <= 'Z') {
+		identifier = "x" + identifier
+	}
+	return identifier
+}
@@ -0,0 +1,82 @@
+package providers
+
+// Provenance records which migrations created and last modified a table or
+// column. ModifiedBy is empty until a later migration changes the object.
+type Provenance struct {
+	CreatedBy  string `json:"created_by"`
+	ModifiedBy string `json:"modified_by,omitempty"`
+}
+
+// ProvenanceMap holds the provenance of tables, keyed by table name, and of
+// columns, keyed by table.column
+type ProvenanceMap map[string]Provenance
+
+// provenanceKey returns the key of a column, or of a table when column is
+// empty
+func provenanceKey(table, column string) string {
+	if column == "" {
+		return table
+	}
+	return table + "." + column
+}
+
+// Record updates the provenance with the changes made by a migration. Added
+// tables and columns are created by it; a table whose columns, keys,
+// indexes or constraints changed, and a column whose definition changed,
+// are modified by it. Removed objects are forgotten, so a table dropped and
+// created again is attributed to the migration that recreated it.
+func (p ProvenanceMap) Record(migration string, diff SchemaDiff) {
+	for _, table := range diff.RemovedTables {
+		p.forgetTable(table)
+	}
+
+	for _, table := range diff.AddedTables {
+		p.forgetTable(table)
+		p[provenanceKey(table.Name, "")] = Provenance{CreatedBy: migration}
+		for _, col := range table.Columns {
+			p[provenanceKey(table.Name, col.Name)] = Provenance{CreatedBy: migration}
+		}
+	}
+
+	for _, tableDiff := range diff.ModifiedTables {
+		p.modify(provenanceKey(tableDiff.Name, ""), migration)
+		for _, col := range tableDiff.RemovedColumns {
+			delete(p, provenanceKey(tableDiff.Name, col.Name))
+		}
+		for _, col := range tableDiff.AddedColumns {
+			p[provenanceKey(tableDiff.Name, col.Name)] = Provenance{CreatedBy: migration}
+		}
+		for _, change := range tableDiff.ChangedColumns {
+			p.modify(provenanceKey(tableDiff.Name, change.Name), migration)
+		}
+	}
+}
+
+// Table returns the provenance of a table
+func (p ProvenanceMap) Table(table string) (Provenance, bool) {
+	provenance, ok := p[provenanceKey(table, "")]
+	return provenance, ok
+}
+
+// Column returns the provenance of a column
+func (p ProvenanceMap) Column(table, column string) (Provenance, bool) {
+	provenance, ok := p[provenanceKey(table, column)]
+	return provenance, ok
+}
+
+// modify marks an object as last modified by a migration. Objects that
+// existed before provenance was recorded are created by an unknown
+// migration.
+func (p ProvenanceMap) modify(key, migration string) {
+	provenance := p[key]
+	provenance.ModifiedBy = migration
+	p[key] = provenance
+}
+
+// forgetTable removes a table and its columns
+func (p ProvenanceMap) forgetTable(table Table) {
+	delete(p, provenanceKey(table.Name, ""))
+	for _, col := range table.Columns {
+		delete(p, provenanceKey(table.Name, col.Name))
+	}
+}
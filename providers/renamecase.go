@@ -0,0 +1,301 @@
+package providers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// RenameCase selects how table, column, index and constraint names are
+// rewritten in the output. The database is never changed.
+type RenameCase string
+
+const (
+	// RenameCasePreserve keeps names as they are in the database
+	RenameCasePreserve RenameCase = "preserve"
+	// RenameCaseLower lowercases names, such as UserAccounts to useraccounts
+	RenameCaseLower RenameCase = "lower"
+	// RenameCaseSnake converts names to snake_case, such as UserAccounts to
+	// user_accounts and userID to user_id
+	RenameCaseSnake RenameCase = "snake"
+)
+
+// ParseRenameCase validates a rename case. An empty name keeps names as
+// they are.
+func ParseRenameCase(s string) (RenameCase, error) {
+	switch renameCase := RenameCase(s); renameCase {
+	case "":
+		return RenameCasePreserve, nil
+	case RenameCasePreserve, RenameCaseLower, RenameCaseSnake:
+		return renameCase, nil
+	default:
+		return "", fmt.Errorf("unsupported rename case: %s (expected snake, lower or preserve)", s)
+	}
+}
+
+// apply returns name in the rename case
+func (c RenameCase) apply(name string) string {
+	switch c {
+	case RenameCaseLower:
+		return strings.ToLower(name)
+	case RenameCaseSnake:
+		return snakeCase(name)
+	default:
+		return name
+	}
+}
+
+// snakeCase converts a name to snake_case. An underscore goes before an
+// upper case letter that starts a word, so HTTPServer becomes http_server;
+// spaces and hyphens become underscores.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var sb strings.Builder
+	for i, r := range runes {
+		if r == ' ' || r == '-' {
+			r = '_'
+		}
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				sb.WriteRune('_')
+			}
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String()
+}
+
+// RenameSchema returns schema with its tables renamed as by RenameTables and
+// its views renamed too. Quoted table, view and column names in view
+// definitions follow the renamed objects. The input is not modified.
+func RenameSchema(schema Schema, renameCase RenameCase) (Schema, error) {
+	if renameCase == RenameCasePreserve || renameCase == "" {
+		return schema, nil
+	}
+
+	tables, err := RenameTables(schema.Tables, renameCase)
+	if err != nil {
+		return Schema{}, err
+	}
+
+	names := identifierNames(schema.Tables, renameCase)
+	renamedFrom := make(map[string]string, len(schema.Views))
+	for _, view := range schema.Views {
+		renamed := renameCase.apply(view.Name)
+		if other, taken := renamedFrom[renamed]; taken {
+			return Schema{}, fmt.Errorf("views %s and %s are both renamed to %s", other, view.Name, renamed)
+		}
+		renamedFrom[renamed] = view.Name
+		names[view.Name] = renamed
+	}
+
+	views := slices.Clone(schema.Views)
+	for i := range views {
+		views[i].Name = names[views[i].Name]
+		views[i].Definition = renameQuotedIdents(views[i].Definition, names)
+	}
+
+	schema.Tables = tables
+	schema.Views = views
+	return schema, nil
+}
+
+// RenameTables returns tables with their names, column names and the names
+// of their indexes and constraints in the given case. References follow the
+// renamed objects: foreign keys, index and constraint columns, partition
+// parents, inherited tables and quoted names in trigger definitions. Other
+// SQL text, such as defaults, index expressions and partial index
+// predicates, is not rewritten. Two tables, or two columns of a table, that
+// end up with the same name are an error. The input is not modified.
+func RenameTables(tables []Table, renameCase RenameCase) ([]Table, error) {
+	if renameCase == RenameCasePreserve || renameCase == "" {
+		return tables, nil
+	}
+
+	tableNames := make(map[string]string, len(tables))
+	columnNames := make(map[string]map[string]string, len(tables))
+	renamedFrom := make(map[string]string, len(tables))
+	for _, table := range tables {
+		renamed := renameCase.apply(table.Name)
+		if other, taken := renamedFrom[renamed]; taken {
+			return nil, fmt.Errorf("tables %s and %s are both renamed to %s", other, table.Name, renamed)
+		}
+		renamedFrom[renamed] = table.Name
+		tableNames[table.Name] = renamed
+
+		columns := make(map[string]string, len(table.Columns))
+		columnFrom := make(map[string]string, len(table.Columns))
+		for _, col := range table.Columns {
+			renamed := renameCase.apply(col.Name)
+			if other, taken := columnFrom[renamed]; taken {
+				return nil, fmt.Errorf("columns %s.%s and %s.%s are both renamed to %s", table.Name, other, table.Name, col.Name, renamed)
+			}
+			columnFrom[renamed] = col.Name
+			columns[col.Name] = renamed
+		}
+		columnNames[table.Name] = columns
+	}
+
+	tableName := func(name string) string {
+		if renamed, ok := tableNames[name]; ok {
+			return renamed
+		}
+		return name
+	}
+
+	names := identifierNames(tables, renameCase)
+	renamed := make([]Table, len(tables))
+	for i, table := range tables {
+		columns := columnNames[table.Name]
+		t := table
+		t.Name = tableNames[table.Name]
+		t.PartitionOf = tableName(table.PartitionOf)
+		if _, ok := columns[table.PartitionKey]; ok {
+			t.PartitionKey = columns[table.PartitionKey]
+		}
+
+		t.Inherits = slices.Clone(table.Inherits)
+		for j, parent := range t.Inherits {
+			t.Inherits[j] = tableName(parent)
+		}
+
+		t.Columns = slices.Clone(table.Columns)
+		for j := range t.Columns {
+			t.Columns[j].Name = columns[t.Columns[j].Name]
+		}
+
+		t.Indexes = slices.Clone(table.Indexes)
+		for j := range t.Indexes {
+			t.Indexes[j].Name = renameCase.apply(t.Indexes[j].Name)
+			t.Indexes[j].Columns = renameColumnRefs(t.Indexes[j].Columns, columns)
+		}
+
+		t.UniqueConstraints = slices.Clone(table.UniqueConstraints)
+		for j := range t.UniqueConstraints {
+			t.UniqueConstraints[j].Name = renameCase.apply(t.UniqueConstraints[j].Name)
+			t.UniqueConstraints[j].Columns = renameColumnRefs(t.UniqueConstraints[j].Columns, columns)
+		}
+
+		t.ExclusionConstraints = slices.Clone(table.ExclusionConstraints)
+		for j := range t.ExclusionConstraints {
+			ec := &t.ExclusionConstraints[j]
+			ec.Name = renameCase.apply(ec.Name)
+			ec.Elements = slices.Clone(ec.Elements)
+			for k := range ec.Elements {
+				ec.Elements[k].Expression = renameColumnRef(ec.Elements[k].Expression, columns)
+			}
+		}
+
+		t.ForeignKeys = slices.Clone(table.ForeignKeys)
+		for j := range t.ForeignKeys {
+			fk := &t.ForeignKeys[j]
+			fk.Name = renameCase.apply(fk.Name)
+			fk.Columns = renameColumnRefs(fk.Columns, columns)
+			if referenced, ok := columnNames[fk.ReferencedTable]; ok {
+				fk.ReferencedColumns = renameColumnRefs(fk.ReferencedColumns, referenced)
+			}
+			fk.ReferencedTable = tableName(fk.ReferencedTable)
+		}
+
+		t.Triggers = slices.Clone(table.Triggers)
+		for j := range t.Triggers {
+			t.Triggers[j].Table = tableName(t.Triggers[j].Table)
+			t.Triggers[j].Definition = renameQuotedIdents(t.Triggers[j].Definition, names)
+		}
+
+		renamed[i] = t
+	}
+	return renamed, nil
+}
+
+// renameColumnRefs renames the entries of refs that name a column, see
+// renameColumnRef
+func renameColumnRefs(refs []string, columns map[string]string) []string {
+	if refs == nil {
+		return nil
+	}
+	renamed := make([]string, len(refs))
+	for i, ref := range refs {
+		renamed[i] = renameColumnRef(ref, columns)
+	}
+	return renamed
+}
+
+// renameColumnRef renames ref when it names a column, plain or quoted.
+// Expressions such as lower(email) are kept as they are.
+func renameColumnRef(ref string, columns map[string]string) string {
+	if name, ok := columns[ref]; ok {
+		return name
+	}
+	if unquoted, ok := strings.CutPrefix(ref, `"`); ok && strings.HasSuffix(unquoted, `"`) {
+		if name, ok := columns[strings.ReplaceAll(strings.TrimSuffix(unquoted, `"`), `""`, `"`)]; ok {
+			return quoteIdent(name)
+		}
+	}
+	return ref
+}
+
+// identifierNames maps the names of tables and of their columns to their
+// names in renameCase
+func identifierNames(tables []Table, renameCase RenameCase) map[string]string {
+	names := make(map[string]string)
+	for _, table := range tables {
+		names[table.Name] = renameCase.apply(table.Name)
+		for _, col := range table.Columns {
+			names[col.Name] = renameCase.apply(col.Name)
+		}
+	}
+	return names
+}
+
+// renameQuotedIdents rewrites the quoted identifiers of sql found in names,
+// such as "UserAccounts" to user_accounts. Unquoted identifiers are already
+// lower case, which neither rename case changes. String literals, dollar
+// quoted bodies and comments are kept as they are.
+func renameQuotedIdents(sql string, names map[string]string) string {
+	var sb strings.Builder
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			sb.WriteString(sql[i : i+end])
+			i += end
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end, _ := skipBlockComment(sql, i)
+			sb.WriteString(sql[i:end])
+			i = end
+		case c == '\'':
+			end, _ := skipQuoted(sql, i, c)
+			sb.WriteString(sql[i:end])
+			i = end
+		case c == '"':
+			end, terminated := skipQuoted(sql, i, c)
+			quoted := sql[i:end]
+			if terminated {
+				if renamed, ok := names[strings.ReplaceAll(sql[i+1:end-1], `""`, `"`)]; ok {
+					quoted = quoteIdent(renamed)
+				}
+			}
+			sb.WriteString(quoted)
+			i = end
+		case c == '$':
+			end := i + 1
+			if tag, ok := dollarTag(sql, i); ok {
+				end, _ = skipDollarQuoted(sql, i, tag)
+			}
+			sb.WriteString(sql[i:end])
+			i = end
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String()
+}
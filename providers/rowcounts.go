@@ -0,0 +1,100 @@
+package providers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/lib/pq"
+)
+
+// insufficientPrivilege is the SQLSTATE of a permission denied error
+const insufficientPrivilege pq.ErrorCode = "42501"
+
+// RowCount is the number of rows of a table. Estimated counts come from the
+// planner statistics and are negative for a table that was never analyzed.
+type RowCount struct {
+	Rows      int64
+	Estimated bool
+}
+
+// RowCounts holds the row counts of tables, keyed by table name
+type RowCounts map[string]RowCount
+
+// CountRows counts the rows of the tables of schemaName. By default the
+// estimates kept in pg_class are read in a single query; with exact every
+// table is counted with count(*), which reads the whole table. Views are
+// skipped, and so are tables the user is not allowed to read.
+func CountRows(ctx context.Context, db *sql.DB, schemaName string, tables []Table, exact bool) (RowCounts, error) {
+	if !exact {
+		return estimateRows(ctx, db, schemaName, tables)
+	}
+
+	counts := make(RowCounts, len(tables))
+	for _, table := range tables {
+		if table.IsView {
+			continue
+		}
+		var rows int64
+		query := fmt.Sprintf("SELECT count(*) FROM %s.%s", quoteIdent(schemaName), quoteIdent(table.Name))
+		if err := db.QueryRowContext(ctx, query).Scan(&rows); err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == insufficientPrivilege {
+				slog.Warn("skipping row count, permission denied", "table", table.Name)
+				continue
+			}
+			return nil, fmt.Errorf("failed to count rows of %s: %w", table.Name, err)
+		}
+		counts[table.Name] = RowCount{Rows: rows}
+	}
+	return counts, nil
+}
+
+// estimateRows reads the planner estimates of the tables of schemaName
+func estimateRows(ctx context.Context, db *sql.DB, schemaName string, tables []Table) (RowCounts, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.relname, c.reltuples::bigint
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('r', 'p')
+	`, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read row estimates: %w", err)
+	}
+	defer rows.Close()
+
+	estimates := make(map[string]int64)
+	for rows.Next() {
+		var name string
+		var estimate int64
+		if err := rows.Scan(&name, &estimate); err != nil {
+			return nil, fmt.Errorf("failed to read row estimates: %w", err)
+		}
+		estimates[name] = estimate
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read row estimates: %w", err)
+	}
+
+	counts := make(RowCounts, len(tables))
+	for _, table := range tables {
+		if estimate, ok := estimates[table.Name]; ok && !table.IsView {
+			counts[table.Name] = RowCount{Rows: estimate, Estimated: true}
+		}
+	}
+	return counts, nil
+}
+
+// describeRowCount renders a row count for info output
+func describeRowCount(count RowCount) string {
+	switch {
+	case !count.Estimated:
+		return fmt.Sprintf("%d", count.Rows)
+	case count.Rows < 0:
+		return "unknown (estimate, table not analyzed yet)"
+	default:
+		return fmt.Sprintf("~%d (estimate)", count.Rows)
+	}
+}
@@ -0,0 +1,90 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SchemaIndexFile is the file of a split schema that creates everything but
+// the tables and includes the table files in dependency order
+const SchemaIndexFile = "_schema.sql"
+
+// SchemaFile is one file of a schema split by SplitSchemaSQL
+type SchemaFile struct {
+	Name    string
+	Content string
+}
+
+// SplitSchemaSQL formats a schema as SQL like FormatFullSchemaSQL, with one
+// file per table holding its CREATE TABLE, indexes, grants and triggers. The
+// index file comes first: it creates the objects tables depend on, includes
+// the table files with psql's \ir in dependency order, then adds the
+// deferred foreign keys and the views. Running it with psql gives the same
+// schema as the single script.
+func SplitSchemaSQL(schema Schema, opts FormatOptions) []SchemaFile {
+	tables := normalizeSchema(schema.Tables)
+	if opts.NormalizeDefaults {
+		tables = normalizeDefaults(tables)
+	}
+
+	ordered, deferred := orderTablesByDependency(tables, opts.TableOrder == TableOrderCreated)
+	if opts.ForeignKeyStyle == ForeignKeyAlter {
+		deferred = allForeignKeys(ordered)
+	}
+	omitted := omittedForeignKeys(deferred)
+
+	var index strings.Builder
+	writeSchemaPreamble(&index, schema, opts)
+
+	files := []SchemaFile{{Name: SchemaIndexFile}}
+	used := map[string]bool{SchemaIndexFile: true}
+	for _, table := range ordered {
+		var sb strings.Builder
+		if table.IsPartition() {
+			writeCreatePartition(&sb, table, opts.IfNotExists)
+			writeGrants(&sb, table)
+		} else {
+			writeCreateTable(&sb, table, omitted[table.Name], opts)
+			writeCreateIndexes(&sb, table, opts.IfNotExists)
+			writeGrants(&sb, table)
+			writeCreateTriggers(&sb, table, opts.IfNotExists)
+		}
+
+		name := tableFileName(table.Name, used)
+		files = append(files, SchemaFile{Name: name, Content: strings.TrimSuffix(sb.String(), "\n")})
+		index.WriteString(fmt.Sprintf("\\ir %s\n", name))
+	}
+	if len(ordered) > 0 {
+		index.WriteString("\n")
+	}
+
+	if len(deferred) > 0 {
+		writeDeferredForeignKeys(&index, deferred, opts.IfNotExists)
+		index.WriteString("\n")
+	}
+	writeCreateViews(&index, schema.Views, opts.IfNotExists)
+
+	files[0].Content = index.String()
+	return files
+}
+
+// tableFileName returns the file name of a table in a split schema. Runes
+// other than letters, digits, '-' and '_' become '_', and a numeric suffix
+// keeps the name apart from those in used, ignoring case for file systems
+// that do. The name is added to used.
+func tableFileName(table string, used map[string]bool) string {
+	base := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, table)
+
+	name := base + ".sql"
+	for i := 2; used[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s_%d.sql", base, i)
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
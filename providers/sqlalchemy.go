@@ -0,0 +1,446 @@
+package providers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// sqlAlchemyClass collects what is written for one declarative model
+type sqlAlchemyClass struct {
+	Name          string
+	Table         Table
+	Columns       []string
+	Relationships []string
+	TableArgs     []string
+	// attributes maps column names to attribute names; taken holds every
+	// attribute name in use so relationships do not clash with columns
+	attributes map[string]string
+	taken      map[string]bool
+}
+
+// attribute reserves an attribute name, adding suffix when it is taken
+func (c *sqlAlchemyClass) attribute(name, suffix string) string {
+	if c.taken[name] {
+		name += "_" + suffix
+	}
+	c.taken[name] = true
+	return name
+}
+
+// sqlAlchemyImports records the names used by the generated code, by module
+type sqlAlchemyImports map[string]map[string]bool
+
+func (imports sqlAlchemyImports) add(module, name string) string {
+	if imports[module] == nil {
+		imports[module] = make(map[string]bool)
+	}
+	imports[module][name] = true
+	return name
+}
+
+// FormatSchemaSQLAlchemy formats the schema as SQLAlchemy declarative models,
+// one class per table, with ForeignKey columns and relationship() pairs
+// linked by back_populates. Tables without a primary key cannot be mapped by
+// the ORM and are declared as Core Table objects instead. Views and
+// partitions are left out.
+func FormatSchemaSQLAlchemy(tables []Table) string {
+	tables = normalizeSchema(tables)
+	imports := sqlAlchemyImports{}
+	imports.add("sqlalchemy", "Column")
+	imports.add("sqlalchemy.orm", "declarative_base")
+
+	byName := make(map[string]*sqlAlchemyClass)
+	var classes []*sqlAlchemyClass
+	for _, table := range tables {
+		if table.IsView || table.IsPartition() {
+			continue
+		}
+		class := newSQLAlchemyClass(table, imports)
+		byName[table.Name] = class
+		classes = append(classes, class)
+	}
+
+	for _, class := range classes {
+		if sqlAlchemyMapped(class.Table) {
+			addSQLAlchemyRelationships(class, byName, imports)
+		}
+	}
+
+	var sb strings.Builder
+	writeSQLAlchemyImports(&sb, imports)
+	sb.WriteString("\nBase = declarative_base()\n")
+	for _, class := range classes {
+		sb.WriteString("\n\n")
+		writeSQLAlchemyClass(&sb, class)
+	}
+	return sb.String()
+}
+
+// sqlAlchemyMapped reports whether a table can be an ORM class, which needs
+// a primary key
+func sqlAlchemyMapped(table Table) bool {
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey {
+			return true
+		}
+	}
+	return false
+}
+
+// newSQLAlchemyClass builds the columns and table arguments of a table
+func newSQLAlchemyClass(table Table, imports sqlAlchemyImports) *sqlAlchemyClass {
+	class := &sqlAlchemyClass{
+		Name:       pascalCase(table.Name),
+		Table:      table,
+		attributes: make(map[string]string),
+		taken:      map[string]bool{"metadata": true, "registry": true},
+	}
+
+	// Single column foreign keys are declared on the column, the others as
+	// table arguments
+	foreignKeyOn := make(map[string]ForeignKey)
+	for _, fk := range table.ForeignKeys {
+		if len(fk.Columns) == 1 {
+			foreignKeyOn[fk.Columns[0]] = fk
+			continue
+		}
+		referenced := make([]string, len(fk.ReferencedColumns))
+		for i, column := range fk.ReferencedColumns {
+			referenced[i] = fk.ReferencedTable + "." + column
+		}
+		class.TableArgs = append(class.TableArgs, fmt.Sprintf("%s(%s, %s%s)",
+			imports.add("sqlalchemy", "ForeignKeyConstraint"), pythonStringList(fk.Columns), pythonStringList(referenced), sqlAlchemyForeignKeyOptions(fk)))
+	}
+
+	unique := make(map[string]bool)
+	for _, uc := range table.UniqueConstraints {
+		if len(uc.Columns) == 1 {
+			unique[uc.Columns[0]] = true
+			continue
+		}
+		class.TableArgs = append(class.TableArgs, fmt.Sprintf("%s(%s, name=%q)",
+			imports.add("sqlalchemy", "UniqueConstraint"), strings.Join(pythonStrings(uc.Columns), ", "), uc.Name))
+	}
+	for _, idx := range table.Indexes {
+		arguments := append([]string{fmt.Sprintf("%q", idx.Name)}, pythonStrings(idx.Columns)...)
+		if idx.IsUnique {
+			arguments = append(arguments, "unique=True")
+		}
+		if idx.Method != "" {
+			arguments = append(arguments, fmt.Sprintf("postgresql_using=%q", idx.Method))
+		}
+		class.TableArgs = append(class.TableArgs, fmt.Sprintf("%s(%s)", imports.add("sqlalchemy", "Index"), strings.Join(arguments, ", ")))
+	}
+
+	if !sqlAlchemyMapped(table) {
+		imports.add("sqlalchemy", "Table")
+	}
+
+	for _, col := range table.Columns {
+		columnType, comment := sqlAlchemyType(col, imports)
+		arguments := []string{columnType}
+		if fk, ok := foreignKeyOn[col.Name]; ok {
+			arguments = append(arguments, fmt.Sprintf("%s(%q%s)",
+				imports.add("sqlalchemy", "ForeignKey"), fk.ReferencedTable+"."+fk.ReferencedColumns[0], sqlAlchemyForeignKeyOptions(fk)))
+		}
+		if col.IsPrimaryKey {
+			arguments = append(arguments, "primary_key=True")
+		} else if !col.IsNullable {
+			arguments = append(arguments, "nullable=False")
+		}
+		if unique[col.Name] {
+			arguments = append(arguments, "unique=True")
+		}
+		// The default of a serial column comes with its type
+		if col.DefaultValue.Valid && !col.IsSerial {
+			arguments = append(arguments, fmt.Sprintf("server_default=%s(%q)", imports.add("sqlalchemy", "text"), col.DefaultValue.String))
+		}
+
+		if comment != "" {
+			comment = "  # " + comment
+		}
+		if !sqlAlchemyMapped(table) {
+			class.Columns = append(class.Columns, fmt.Sprintf("Column(%q, %s),%s", col.Name, strings.Join(arguments, ", "), comment))
+			continue
+		}
+		name := class.attribute(pythonIdentifier(col.Name), "column")
+		class.attributes[col.Name] = name
+		if name != col.Name {
+			arguments = append([]string{fmt.Sprintf("%q", col.Name)}, arguments...)
+		}
+		class.Columns = append(class.Columns, fmt.Sprintf("%s = Column(%s)%s", name, strings.Join(arguments, ", "), comment))
+	}
+
+	return class
+}
+
+// addSQLAlchemyRelationships adds a many-to-one relationship to class for
+// each of its foreign keys to a mapped class, and the one-to-many side to the
+// referenced class. When a relationship could use several foreign keys, as
+// for self-references or repeated references to one table, foreign_keys
+// tells SQLAlchemy which one it is.
+func addSQLAlchemyRelationships(class *sqlAlchemyClass, byName map[string]*sqlAlchemyClass, imports sqlAlchemyImports) {
+	toTable := make(map[string]int)
+	for _, fk := range class.Table.ForeignKeys {
+		toTable[fk.ReferencedTable]++
+	}
+
+	for _, fk := range class.Table.ForeignKeys {
+		referenced := byName[fk.ReferencedTable]
+		if referenced == nil || !sqlAlchemyMapped(referenced.Table) {
+			continue
+		}
+		imports.add("sqlalchemy.orm", "relationship")
+
+		name := fk.ReferencedTable
+		if len(fk.Columns) == 1 && len(fk.Columns[0]) > len("_id") && strings.HasSuffix(fk.Columns[0], "_id") {
+			name = strings.TrimSuffix(fk.Columns[0], "_id")
+		}
+		name = class.attribute(pythonIdentifier(name), "rel")
+		backName := referenced.attribute(pythonIdentifier(class.Table.Name), name)
+
+		var forwardOptions, backOptions []string
+		if toTable[fk.ReferencedTable] > 1 || referenced == class {
+			keys := make([]string, len(fk.Columns))
+			for i, column := range fk.Columns {
+				keys[i] = class.Name + "." + class.attributes[column]
+			}
+			foreignKeys := fmt.Sprintf("foreign_keys=%q", "["+strings.Join(keys, ", ")+"]")
+			forwardOptions = append(forwardOptions, foreignKeys)
+			backOptions = append(backOptions, foreignKeys)
+		}
+		if referenced == class {
+			remote := make([]string, len(fk.ReferencedColumns))
+			for i, column := range fk.ReferencedColumns {
+				remote[i] = class.Name + "." + class.attributes[column]
+			}
+			forwardOptions = append(forwardOptions, fmt.Sprintf("remote_side=%q", "["+strings.Join(remote, ", ")+"]"))
+		}
+		// Unique foreign key columns make the relationship one-to-one
+		if prismaColumnsUnique(class.Table, fk.Columns) {
+			backOptions = append(backOptions, "uselist=False")
+		}
+
+		class.Relationships = append(class.Relationships, fmt.Sprintf("%s = relationship(%q, back_populates=%q%s)",
+			name, referenced.Name, backName, pythonOptions(forwardOptions)))
+		referenced.Relationships = append(referenced.Relationships, fmt.Sprintf("%s = relationship(%q, back_populates=%q%s)",
+			backName, class.Name, name, pythonOptions(backOptions)))
+	}
+}
+
+// sqlAlchemyForeignKeyOptions returns the ondelete, onupdate and name
+// arguments of a foreign key, each preceded by a comma
+func sqlAlchemyForeignKeyOptions(fk ForeignKey) string {
+	var options []string
+	if fk.OnDelete != "" && fk.OnDelete != foreignKeyNoAction {
+		options = append(options, fmt.Sprintf("ondelete=%q", fk.OnDelete))
+	}
+	if fk.OnUpdate != "" && fk.OnUpdate != foreignKeyNoAction {
+		options = append(options, fmt.Sprintf("onupdate=%q", fk.OnUpdate))
+	}
+	if fk.Name != "" {
+		options = append(options, fmt.Sprintf("name=%q", fk.Name))
+	}
+	return pythonOptions(options)
+}
+
+// sqlAlchemyType maps a column to a SQLAlchemy type expression, using the
+// PostgreSQL dialect for types without a generic equivalent. Unknown types
+// become NullType; the PostgreSQL type is then returned as a comment.
+func sqlAlchemyType(col Column, imports sqlAlchemyImports) (string, string) {
+	const postgresql = "sqlalchemy.dialects.postgresql"
+
+	switch col.DataType {
+	case "integer":
+		return imports.add("sqlalchemy", "Integer"), ""
+	case "smallint":
+		return imports.add("sqlalchemy", "SmallInteger"), ""
+	case "bigint":
+		return imports.add("sqlalchemy", "BigInteger"), ""
+	case "real":
+		return imports.add("sqlalchemy", "REAL"), ""
+	case "double precision":
+		return imports.add("sqlalchemy", "Double"), ""
+	case "numeric", "decimal":
+		numeric := imports.add("sqlalchemy", "Numeric")
+		if col.NumericPrecision.Valid && col.NumericScale.Valid {
+			return fmt.Sprintf("%s(%d, %d)", numeric, col.NumericPrecision.Int64, col.NumericScale.Int64), ""
+		}
+		return numeric, ""
+	case "boolean":
+		return imports.add("sqlalchemy", "Boolean"), ""
+	case "text":
+		return imports.add("sqlalchemy", "Text"), ""
+	case "character varying":
+		if col.CharacterLength.Valid {
+			return fmt.Sprintf("%s(%d)", imports.add("sqlalchemy", "String"), col.CharacterLength.Int64), ""
+		}
+		return imports.add("sqlalchemy", "String"), ""
+	case "character", "char":
+		if col.CharacterLength.Valid {
+			return fmt.Sprintf("%s(%d)", imports.add("sqlalchemy", "CHAR"), col.CharacterLength.Int64), ""
+		}
+		return imports.add("sqlalchemy", "CHAR"), ""
+	case "timestamp without time zone":
+		return imports.add("sqlalchemy", "DateTime"), ""
+	case "timestamp with time zone":
+		return imports.add("sqlalchemy", "DateTime") + "(timezone=True)", ""
+	case "date":
+		return imports.add("sqlalchemy", "Date"), ""
+	case "time without time zone":
+		return imports.add("sqlalchemy", "Time"), ""
+	case "time with time zone":
+		return imports.add("sqlalchemy", "Time") + "(timezone=True)", ""
+	case "interval":
+		return imports.add("sqlalchemy", "Interval"), ""
+	case "json":
+		return imports.add("sqlalchemy", "JSON"), ""
+	case "bytea":
+		return imports.add("sqlalchemy", "LargeBinary"), ""
+	case "uuid":
+		return imports.add(postgresql, "UUID"), ""
+	case "jsonb":
+		return imports.add(postgresql, "JSONB"), ""
+	case "money":
+		return imports.add(postgresql, "MONEY"), ""
+	case "inet":
+		return imports.add(postgresql, "INET"), ""
+	case "cidr":
+		return imports.add(postgresql, "CIDR"), ""
+	case "macaddr":
+		return imports.add(postgresql, "MACADDR"), ""
+	case "citext":
+		return imports.add(postgresql, "CITEXT"), ""
+	case "hstore":
+		return imports.add(postgresql, "HSTORE"), ""
+	case "tsvector":
+		return imports.add(postgresql, "TSVECTOR"), ""
+	case "ARRAY":
+		element := strings.TrimSuffix(col.FullType, "[]")
+		dataType, _, _ := strings.Cut(element, "(")
+		elementType, comment := sqlAlchemyType(Column{DataType: dataType, FullType: element}, imports)
+		return fmt.Sprintf("%s(%s)", imports.add(postgresql, "ARRAY"), elementType), comment
+	default:
+		fullType := col.FullType
+		if fullType == "" {
+			fullType = col.DataType
+		}
+		return imports.add("sqlalchemy.types", "NullType") + "()", fullType
+	}
+}
+
+// writeSQLAlchemyImports writes one import line per module, sorted
+func writeSQLAlchemyImports(sb *strings.Builder, imports sqlAlchemyImports) {
+	modules := make([]string, 0, len(imports))
+	for module := range imports {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+
+	for _, module := range modules {
+		names := make([]string, 0, len(imports[module]))
+		for name := range imports[module] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		sb.WriteString(fmt.Sprintf("from %s import %s\n", module, strings.Join(names, ", ")))
+	}
+}
+
+// writeSQLAlchemyClass writes a declarative class, or a Core Table for a
+// table without a primary key
+func writeSQLAlchemyClass(sb *strings.Builder, class *sqlAlchemyClass) {
+	if !sqlAlchemyMapped(class.Table) {
+		sb.WriteString(fmt.Sprintf("# %s has no primary key, which the ORM requires to map it\n", class.Table.Name))
+		sb.WriteString(fmt.Sprintf("%s = Table(\n    %q,\n    Base.metadata,\n", pythonIdentifier(class.Table.Name), class.Table.Name))
+		for _, column := range class.Columns {
+			sb.WriteString(fmt.Sprintf("    %s\n", column))
+		}
+		for _, arg := range class.TableArgs {
+			sb.WriteString(fmt.Sprintf("    %s,\n", arg))
+		}
+		sb.WriteString(")\n")
+		return
+	}
+
+	sb.WriteString(fmt.Sprintf("class %s(Base):\n", class.Name))
+	sb.WriteString(fmt.Sprintf("    __tablename__ = %q\n", class.Table.Name))
+	if len(class.TableArgs) > 0 {
+		sb.WriteString("    __table_args__ = (\n")
+		for _, arg := range class.TableArgs {
+			sb.WriteString(fmt.Sprintf("        %s,\n", arg))
+		}
+		sb.WriteString("    )\n")
+	}
+
+	sb.WriteString("\n")
+	for _, column := range class.Columns {
+		sb.WriteString(fmt.Sprintf("    %s\n", column))
+	}
+
+	if len(class.Relationships) > 0 {
+		sb.WriteString("\n")
+		for _, relationship := range class.Relationships {
+			sb.WriteString(fmt.Sprintf("    %s\n", relationship))
+		}
+	}
+}
+
+var pythonIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// pythonKeywords cannot be used as attribute names
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true, "async": true,
+	"await": true, "break": true, "class": true, "continue": true, "def": true, "del": true, "elif": true,
+	"else": true, "except": true, "finally": true, "for": true, "from": true, "global": true, "if": true,
+	"import": true, "in": true, "is": true, "lambda": true, "nonlocal": true, "not": true, "or": true,
+	"pass": true, "raise": true, "return": true, "try": true, "while": true, "with": true, "yield": true,
+}
+
+// pythonIdentifier turns a column or table name into a valid Python
+// identifier, replacing other characters with underscores and suffixing
+// keywords with one
+func pythonIdentifier(name string) string {
+	if !pythonIdentifierPattern.MatchString(name) {
+		var sb strings.Builder
+		for _, r := range name {
+			if r == '_' || (r < 128 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')) {
+				sb.WriteRune(r)
+			} else {
+				sb.WriteRune('_')
+			}
+		}
+		name = sb.String()
+		if name == "" || (name[0] >= '0' && name[0] <= '9') {
+			name = "_" + name
+		}
+	}
+	if pythonKeywords[name] {
+		name += "_"
+	}
+	return name
+}
+
+// pythonStrings quotes each value as a Python string literal
+func pythonStrings(values []string) []string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
+	}
+	return quoted
+}
+
+// pythonStringList renders values as a Python list of strings
+func pythonStringList(values []string) string {
+	return "[" + strings.Join(pythonStrings(values), ", ") + "]"
+}
+
+// pythonOptions joins keyword arguments, each preceded by a comma
+func pythonOptions(options []string) string {
+	if len(options) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(options, ", ")
+}
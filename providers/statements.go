@@ -0,0 +1,181 @@
+package providers
+
+import (
+	"strings"
+)
+
+// SplitStatements splits a SQL script into individual statements on top-level
+// semicolons. Semicolons inside quoted strings, quoted identifiers,
+// dollar-quoted bodies and comments are ignored. Returned statements are
+// trimmed, keep their leading comments and exclude the terminating semicolon.
+// Fragments consisting solely of whitespace or comments are dropped.
+func SplitStatements(sql string) []string {
+	var statements []string
+	start := 0
+
+	flush := func(end int) {
+		text := strings.TrimSpace(sql[start:end])
+		if StripComments(text) != "" {
+			statements = append(statements, text)
+		}
+	}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end
+			}
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			i, _ = skipBlockComment(sql, i)
+		case c == '\'' || c == '"':
+			i, _ = skipQuoted(sql, i, c)
+		case c == '$':
+			if tag, ok := dollarTag(sql, i); ok {
+				i, _ = skipDollarQuoted(sql, i, tag)
+			} else {
+				i++
+			}
+		case c == ';':
+			flush(i)
+			i++
+			start = i
+		default:
+			i++
+		}
+	}
+	flush(len(sql))
+
+	return statements
+}
+
+// StripComments removes line and block comments from a SQL fragment and trims
+// the result. Quoted strings and dollar-quoted bodies are preserved.
+func StripComments(sql string) string {
+	var sb strings.Builder
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end
+			}
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			i, _ = skipBlockComment(sql, i)
+			sb.WriteByte(' ')
+		case c == '\'' || c == '"':
+			end, _ := skipQuoted(sql, i, c)
+			sb.WriteString(sql[i:end])
+			i = end
+		case c == '$':
+			if tag, ok := dollarTag(sql, i); ok {
+				end, _ := skipDollarQuoted(sql, i, tag)
+				sb.WriteString(sql[i:end])
+				i = end
+			} else {
+				sb.WriteByte(c)
+				i++
+			}
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// skipBlockComment returns the index just past the block comment starting at
+// i, honouring PostgreSQL's nested comment syntax, and whether the comment was
+// terminated
+func skipBlockComment(sql string, i int) (int, bool) {
+	depth := 0
+	for i < len(sql) {
+		if sql[i] == '/' && i+1 < len(sql) && sql[i+1] == '*' {
+			depth++
+			i += 2
+			continue
+		}
+		if sql[i] == '*' && i+1 < len(sql) && sql[i+1] == '/' {
+			depth--
+			i += 2
+			if depth == 0 {
+				return i, true
+			}
+			continue
+		}
+		i++
+	}
+	return len(sql), false
+}
+
+// skipQuoted returns the index just past the quoted section starting at i and
+// whether it was terminated. Doubled quote characters are treated as escapes,
+// as are backslashes in E'...' escape strings.
+func skipQuoted(sql string, i int, quote byte) (int, bool) {
+	escapes := quote == '\'' && isEscapeStringQuote(sql, i)
+	i++
+	for i < len(sql) {
+		if escapes && sql[i] == '\\' {
+			i += 2
+			continue
+		}
+		if sql[i] == quote {
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1, true
+		}
+		i++
+	}
+	return len(sql), false
+}
+
+// isEscapeStringQuote reports whether the quote at i opens an E'...' escape
+// string, in which backslashes escape the next character
+func isEscapeStringQuote(sql string, i int) bool {
+	if i == 0 || (sql[i-1] != 'E' && sql[i-1] != 'e') {
+		return false
+	}
+	return i == 1 || !isIdentChar(sql[i-2])
+}
+
+// skipDollarQuoted returns the index just past the dollar-quoted body opened by
+// tag at i and whether the closing tag was found
+func skipDollarQuoted(sql string, i int, tag string) (int, bool) {
+	end := strings.Index(sql[i+len(tag):], tag)
+	if end < 0 {
+		return len(sql), false
+	}
+	return i + len(tag) + end + len(tag), true
+}
+
+// dollarTag reports whether a dollar-quote delimiter such as $$ or $body$
+// starts at i and returns it
+func dollarTag(sql string, i int) (string, bool) {
+	// A dollar sign directly following an identifier character is part of
+	// that identifier (or a positional parameter), not a quote
+	if i > 0 && isIdentChar(sql[i-1]) {
+		return "", false
+	}
+	for j := i + 1; j < len(sql); j++ {
+		if sql[j] == '$' {
+			return sql[i : j+1], true
+		}
+		if !isIdentChar(sql[j]) || (j == i+1 && sql[j] >= '0' && sql[j] <= '9') {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c >= 0x80
+}
@@ -0,0 +1,164 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyntaxError describes a problem found while checking a SQL script
+type SyntaxError struct {
+	Line    int
+	Message string
+}
+
+func (e SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// knownCommands lists the keywords a PostgreSQL statement can start with
+var knownCommands = map[string]bool{
+	"ABORT": true, "ALTER": true, "ANALYZE": true, "ANALYSE": true, "BEGIN": true,
+	"CALL": true, "CHECKPOINT": true, "CLOSE": true, "CLUSTER": true, "COMMENT": true,
+	"COMMIT": true, "COPY": true, "CREATE": true, "DEALLOCATE": true, "DECLARE": true,
+	"DELETE": true, "DISCARD": true, "DO": true, "DROP": true, "END": true,
+	"EXECUTE": true, "EXPLAIN": true, "FETCH": true, "GRANT": true, "IMPORT": true,
+	"INSERT": true, "LISTEN": true, "LOAD": true, "LOCK": true, "MERGE": true,
+	"MOVE": true, "NOTIFY": true, "PREPARE": true, "REASSIGN": true, "REFRESH": true,
+	"REINDEX": true, "RELEASE": true, "RESET": true, "REVOKE": true, "ROLLBACK": true,
+	"SAVEPOINT": true, "SECURITY": true, "SELECT": true, "SET": true, "SHOW": true,
+	"START": true, "TABLE": true, "TRUNCATE": true, "UNLISTEN": true, "UPDATE": true,
+	"VACUUM": true, "VALUES": true, "WITH": true,
+}
+
+// CheckSyntax checks a SQL script without executing it. It reports the
+// problems found by LexicalCheck and, once the script passes it, the first
+// error of the PostgreSQL parser when SyntaxParser is set.
+func CheckSyntax(sql string) []SyntaxError {
+	if errs := LexicalCheck(sql); len(errs) > 0 {
+		return errs
+	}
+	return parseCheck(sql)
+}
+
+// LexicalCheck scans a SQL script for lexical problems without executing it.
+// It reports unterminated strings, quoted identifiers, dollar-quoted bodies
+// and comments, unbalanced parentheses and statements that do not start with
+// a known command. It is a heuristic, not validation: it does not parse the
+// statements, so a script that passes may still be rejected by PostgreSQL.
+func LexicalCheck(sql string) []SyntaxError {
+	var errs []SyntaxError
+	line := 1
+	start := 0
+	stmtLine := 0
+	var openParens []int
+
+	flush := func(end int) {
+		if stmtLine > 0 {
+			if keyword := firstKeyword(StripComments(sql[start:end])); !knownCommands[keyword] {
+				errs = append(errs, SyntaxError{Line: stmtLine, Message: fmt.Sprintf("unknown statement starting with %q", keyword)})
+			}
+		}
+		for _, l := range openParens {
+			errs = append(errs, SyntaxError{Line: l, Message: "unclosed parenthesis"})
+		}
+		openParens = nil
+		stmtLine = 0
+	}
+
+	unterminated := func(from, to int, what string) {
+		errs = append(errs, SyntaxError{Line: line, Message: "unterminated " + what})
+		line += strings.Count(sql[from:to], "\n")
+	}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		if stmtLine == 0 && !isSpace(c) && c != ';' && !strings.HasPrefix(sql[i:], "--") && !strings.HasPrefix(sql[i:], "/*") {
+			stmtLine = line
+		}
+
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end
+			}
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end, ok := skipBlockComment(sql, i)
+			if !ok {
+				unterminated(i, end, "block comment")
+			} else {
+				line += strings.Count(sql[i:end], "\n")
+			}
+			i = end
+		case c == '\'' || c == '"':
+			end, ok := skipQuoted(sql, i, c)
+			if !ok {
+				what := "string literal"
+				if c == '"' {
+					what = "quoted identifier"
+				}
+				unterminated(i, end, what)
+			} else {
+				line += strings.Count(sql[i:end], "\n")
+			}
+			i = end
+		case c == '$':
+			tag, ok := dollarTag(sql, i)
+			if !ok {
+				i++
+				continue
+			}
+			end, ok := skipDollarQuoted(sql, i, tag)
+			if !ok {
+				unterminated(i, end, fmt.Sprintf("dollar-quoted string %s", tag))
+			} else {
+				line += strings.Count(sql[i:end], "\n")
+			}
+			i = end
+		case c == '(':
+			openParens = append(openParens, line)
+			i++
+		case c == ')':
+			if len(openParens) == 0 {
+				errs = append(errs, SyntaxError{Line: line, Message: "unexpected closing parenthesis"})
+			} else {
+				openParens = openParens[:len(openParens)-1]
+			}
+			i++
+		case c == ';':
+			flush(i)
+			i++
+			start = i
+		default:
+			i++
+		}
+	}
+	flush(len(sql))
+
+	return errs
+}
+
+// firstKeyword returns the leading word of a statement in upper case
+func firstKeyword(stmt string) string {
+	end := 0
+	for end < len(stmt) && (stmt[end] == '_' || (stmt[end] >= 'a' && stmt[end] <= 'z') || (stmt[end] >= 'A' && stmt[end] <= 'Z')) {
+		end++
+	}
+	if end == 0 {
+		fields := strings.Fields(stmt)
+		if len(fields) == 0 {
+			return ""
+		}
+		return fields[0]
+	}
+	return strings.ToUpper(stmt[:end])
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
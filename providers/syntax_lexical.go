@@ -0,0 +1,12 @@
+//go:build !pg_query
+
+package providers
+
+// SyntaxParser reports whether CheckSyntax parses scripts with the
+// PostgreSQL parser, which takes building with the pg_query tag
+const SyntaxParser = false
+
+// parseCheck finds nothing without the parser
+func parseCheck(string) []SyntaxError {
+	return nil
+}
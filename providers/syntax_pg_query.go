@@ -0,0 +1,35 @@
+//go:build pg_query
+
+package providers
+
+import (
+	"errors"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+	"github.com/pganalyze/pg_query_go/v6/parser"
+)
+
+// SyntaxParser reports whether CheckSyntax parses scripts with the
+// PostgreSQL parser, which takes building with the pg_query tag
+const SyntaxParser = true
+
+// parseCheck parses a script with the parser of PostgreSQL, as vendored by
+// pg_query_go, and reports the first error it stops at
+func parseCheck(sql string) []SyntaxError {
+	_, err := pg_query.Parse(sql)
+	if err == nil {
+		return nil
+	}
+
+	var parseErr *parser.Error
+	if !errors.As(err, &parseErr) {
+		return []SyntaxError{{Line: 1, Message: err.Error()}}
+	}
+	// The cursor counts characters from 1, and is 0 when unknown
+	line := 1
+	if runes := []rune(sql); parseErr.Cursorpos > 0 && parseErr.Cursorpos <= len(runes) {
+		line += strings.Count(string(runes[:parseErr.Cursorpos-1]), "\n")
+	}
+	return []SyntaxError{{Line: line, Message: parseErr.Message}}
+}
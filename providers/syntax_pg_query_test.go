@@ -0,0 +1,30 @@
+//go:build pg_query
+
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSyntaxParses(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []SyntaxError
+	}{
+		{"valid", "create table users (id int);\nalter table users add column name text;\n", nil},
+		{"misspelled_keyword", "create table users (id int);\ncreate tabel posts ();\n",
+			[]SyntaxError{{Line: 2, Message: `syntax error at or near "tabel"`}}},
+		{"misspelled_clause", "alter table users\n  add colum name text;\n",
+			[]SyntaxError{{Line: 2, Message: `syntax error at or near "text"`}}},
+		{"lexical_first", "select 'unterminated;\n",
+			[]SyntaxError{{Line: 1, Message: "unterminated string literal"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CheckSyntax(tt.sql))
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TypeMap overrides how column types are rendered in info and SQL output. Keys
+// are PostgreSQL type names in lower case, such as citext or the name of a
+// domain; values are written as-is in place of the built-in mapping.
+type TypeMap map[string]string
+
+// ParseTypeMapping parses a name=type override such as citext=TEXT. The name
+// is lower-cased so that it matches the spelling reported by PostgreSQL.
+func ParseTypeMapping(s string) (string, string, error) {
+	name, dataType, ok := strings.Cut(s, "=")
+	name, dataType = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(dataType)
+	if !ok || name == "" || dataType == "" {
+		return "", "", fmt.Errorf("invalid type mapping %q, expected name=type", s)
+	}
+	return name, dataType, nil
+}
+
+// lookup returns the override for a column. The declared type name is tried
+// first, so that a domain is matched before its base type, then the type
+// reported by information_schema. The override of an array's element type
+// keeps the array brackets.
+func (m TypeMap) lookup(col Column) (string, bool) {
+	if len(m) == 0 {
+		return "", false
+	}
+	if declared, brackets := declaredTypeName(col); declared != "" {
+		if dataType, ok := m[declared]; ok {
+			return dataType + brackets, true
+		}
+	}
+	dataType, ok := m[strings.ToLower(col.DataType)]
+	return dataType, ok
+}
+
+// declaredTypeName returns the type name of a column as spelled by
+// format_type without modifiers, and its array brackets: email for a domain,
+// character varying for varchar(100), citext and [] for citext[]
+func declaredTypeName(col Column) (string, string) {
+	name := strings.TrimSpace(col.FullType)
+	base := strings.TrimRight(name, "[]")
+	brackets := name[len(base):]
+	if i := strings.IndexByte(base, '('); i >= 0 {
+		base = base[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(base)), brackets
+}
@@ -1,29 +1,359 @@
 package providers
 
-import "database/sql"
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Schema is everything extracted from a database: its tables and views, the
+// enum and composite types and sequences they use, the functions and
+// procedures they may depend on and the extensions providing types
+type Schema struct {
+	// ServerVersion is the server_version_num of the database the schema was
+	// extracted from, such as 160004 for 16.4, or 0 when unknown
+	ServerVersion int        `json:"server_version,omitempty"`
+	Extensions    []string   `json:"extensions,omitempty"`
+	Enums         []Enum     `json:"enums,omitempty"`
+	Sequences     []Sequence `json:"sequences,omitempty"`
+	Tables        []Table    `json:"tables"`
+	Views         []View     `json:"views,omitempty"`
+	Functions     []Function `json:"functions,omitempty"`
+	// CompositeTypes are the types created with CREATE TYPE ... AS (...), in
+	// creation order so that a type comes after those it is made of
+	CompositeTypes []CompositeType `json:"composite_types,omitempty"`
+}
+
+// Enum is an enum type with its labels in sort order
+type Enum struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// CompositeType is a composite (row) type and its attributes in order
+type CompositeType struct {
+	Name       string               `json:"name"`
+	Attributes []CompositeAttribute `json:"attributes"`
+}
+
+// CompositeAttribute is an attribute of a composite type. Type is spelled
+// by format_type, such as character varying(100).
+type CompositeAttribute struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Sequence is a sequence. OwnedBy names the table.column owning it, such as
+// the column of a serial or identity; sequences owned by a serial column are
+// created with their column, see StandaloneSequences.
+type Sequence struct {
+	Name      string `json:"name"`
+	DataType  string `json:"type"`
+	Start     int64  `json:"start"`
+	Increment int64  `json:"increment"`
+	OwnedBy   string `json:"owned_by,omitempty"`
+}
+
+// View is a view and the query defining it, as returned by pg_get_viewdef
+type View struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+// FormatServerVersion renders a server_version_num as a version string:
+// 160004 is 16.4 and 90624 is 9.6.24
+func FormatServerVersion(num int) string {
+	major := num / 10000
+	if major >= 10 {
+		return fmt.Sprintf("%d.%d", major, num%10000)
+	}
+	return fmt.Sprintf("%d.%d.%d", major, num/100%100, num%100)
+}
 
 // Table represents a database table with its columns and indexes
 type Table struct {
-	Name    string
-	Columns []Column
-	Indexes []Index
+	Name              string             `json:"name"`
+	Columns           []Column           `json:"columns"`
+	Indexes           []Index            `json:"indexes,omitempty"`
+	UniqueConstraints []UniqueConstraint `json:"unique_constraints,omitempty"`
+	ForeignKeys       []ForeignKey       `json:"foreign_keys,omitempty"`
+	Triggers          []Trigger          `json:"triggers,omitempty"`
+	// PrimaryKeyName is the name of the primary key constraint, such as
+	// users_pkey
+	PrimaryKeyName string `json:"primary_key_name,omitempty"`
+	// ExclusionConstraints are the EXCLUDE constraints of the table. Their
+	// indexes are not listed in Indexes.
+	ExclusionConstraints []ExclusionConstraint `json:"exclusion_constraints,omitempty"`
+	// IsView is set for views, which are only extracted on request
+	IsView bool `json:"is_view,omitempty"`
+	// PartitionStrategy is range, list or hash for a partitioned table and
+	// PartitionKey its key, such as created_at
+	PartitionStrategy string `json:"partition_strategy,omitempty"`
+	PartitionKey      string `json:"partition_key,omitempty"`
+	// PartitionOf names the parent of a partition and PartitionBound its
+	// bound, such as FOR VALUES IN ('eu') or DEFAULT
+	PartitionOf    string `json:"partition_of,omitempty"`
+	PartitionBound string `json:"partition_bound,omitempty"`
+	// Inherits lists the parents of a table using classic inheritance
+	// (INHERITS), in declaration order. Partitions are not listed here.
+	Inherits []string `json:"inherits,omitempty"`
+	// StorageParams are the storage parameters set with WITH (...), such as
+	// fillfactor: 70
+	StorageParams map[string]string `json:"storage_params,omitempty"`
+	// Grants are the privileges granted on the table to roles other than
+	// its owner. They are only extracted on request.
+	Grants []GrantInfo `json:"grants,omitempty"`
+	// ObjectID is the oid of the table in pg_class. OIDs are assigned in
+	// increasing order, so they give the creation order of the tables.
+	ObjectID uint32 `json:"-"`
+}
+
+// IsPartition reports whether the table is a partition of another table. Its
+// columns, keys and indexes come from the parent.
+func (t Table) IsPartition() bool {
+	return t.PartitionOf != ""
 }
 
 // Column represents a database column
 type Column struct {
-	Name              string
-	DataType          string
-	IsNullable        bool
-	DefaultValue      sql.NullString
-	IsPrimaryKey      bool
-	CharacterLength   sql.NullInt64
-	NumericPrecision  sql.NullInt64
-	NumericScale      sql.NullInt64
+	Name             string
+	DataType         string
+	IsNullable       bool
+	DefaultValue     sql.NullString
+	IsPrimaryKey     bool
+	CharacterLength  sql.NullInt64
+	NumericPrecision sql.NullInt64
+	NumericScale     sql.NullInt64
+	// DatetimePrecision is the number of fractional digits of the seconds
+	// of timestamp and time columns
+	DatetimePrecision sql.NullInt64
+	// FullType is the type as spelled by format_type, including modifiers
+	// such as geometry(Point,4326) that information_schema does not expose
+	FullType string
+	// IsSerial is set when the column defaults to nextval on a sequence it
+	// owns, i.e. it was declared as serial/bigserial
+	IsSerial bool
+	// OrdinalPosition is the physical position of the column in its table,
+	// starting at 1. Dropped columns leave gaps.
+	OrdinalPosition int
+	// IsInherited is set when the column only exists because the table
+	// inherits it, i.e. it is not declared by the table itself
+	IsInherited bool
+	// Collation is the collation of the column when it differs from the
+	// default collation of its type, empty otherwise
+	Collation string
+	// IsComposite is set when DataType names a composite type, which is
+	// then written by name
+	IsComposite bool
 }
 
 // Index represents a database index
 type Index struct {
-	Name     string
-	Columns  []string
-	IsUnique bool
-}
\ No newline at end of file
+	Name     string   `json:"name"`
+	Columns  []string `json:"columns"`
+	IsUnique bool     `json:"unique,omitempty"`
+	// Method is the access method, such as gin, gist, brin or hash, and is
+	// empty for the default btree
+	Method string `json:"method,omitempty"`
+}
+
+// UniqueConstraint represents a table-level UNIQUE constraint
+type UniqueConstraint struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+}
+
+// ExclusionConstraint represents an EXCLUDE constraint: no two rows may have
+// elements that all compare true with their operators, such as overlapping
+// ranges with &&. Method is the access method of its index, usually gist.
+type ExclusionConstraint struct {
+	Name     string             `json:"name"`
+	Method   string             `json:"method"`
+	Elements []ExclusionElement `json:"elements"`
+	// Where is the predicate of a partial constraint, empty otherwise
+	Where string `json:"where,omitempty"`
+}
+
+// ExclusionElement is a column or expression of an exclusion constraint and
+// the operator it is compared with
+type ExclusionElement struct {
+	Expression string `json:"expression"`
+	Operator   string `json:"operator"`
+}
+
+// definition renders the constraint without its name, in the given case:
+// "EXCLUDE USING gist (room_id WITH =, during WITH &&)" or
+// "exclude using gist (room_id with =, during with &&)". Expressions and the
+// predicate are written as extracted.
+func (ec ExclusionConstraint) definition(upper bool) string {
+	exclude, using, with, where := "exclude", "using", "with", "where"
+	if upper {
+		exclude, using, with, where = "EXCLUDE", "USING", "WITH", "WHERE"
+	}
+	elements := make([]string, len(ec.Elements))
+	for i, element := range ec.Elements {
+		elements[i] = fmt.Sprintf("%s %s %s", element.Expression, with, element.Operator)
+	}
+	definition := fmt.Sprintf("%s %s %s (%s)", exclude, using, ec.Method, strings.Join(elements, ", "))
+	if ec.Where != "" {
+		definition += fmt.Sprintf(" %s (%s)", where, ec.Where)
+	}
+	return definition
+}
+
+// ForeignKey represents a foreign key constraint. OnDelete and OnUpdate hold
+// the referential actions as spelled by information_schema (CASCADE, SET
+// NULL, SET DEFAULT, RESTRICT or NO ACTION); empty means NO ACTION.
+type ForeignKey struct {
+	Name              string   `json:"name"`
+	Columns           []string `json:"columns"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnDelete          string   `json:"on_delete,omitempty"`
+	OnUpdate          string   `json:"on_update,omitempty"`
+}
+
+// foreignKeyNoAction is the default referential action
+const foreignKeyNoAction = "NO ACTION"
+
+// referentialActions returns the ON DELETE and ON UPDATE clauses that differ
+// from the default, in the given case: "ON DELETE CASCADE" or
+// "on delete cascade"
+func (fk ForeignKey) referentialActions(upper bool) string {
+	var clauses []string
+	if fk.OnDelete != "" && fk.OnDelete != foreignKeyNoAction {
+		clauses = append(clauses, "ON DELETE "+fk.OnDelete)
+	}
+	if fk.OnUpdate != "" && fk.OnUpdate != foreignKeyNoAction {
+		clauses = append(clauses, "ON UPDATE "+fk.OnUpdate)
+	}
+	actions := strings.Join(clauses, " ")
+	if !upper {
+		actions = strings.ToLower(actions)
+	}
+	return actions
+}
+
+// Trigger represents a trigger defined on a table. Definition holds the full
+// CREATE TRIGGER statement as reported by pg_get_triggerdef.
+type Trigger struct {
+	Name         string   `json:"name"`
+	Table        string   `json:"table"`
+	Timing       string   `json:"timing"`
+	Events       []string `json:"events"`
+	FunctionName string   `json:"function_name"`
+	Definition   string   `json:"definition"`
+}
+
+// GrantInfo holds the privileges granted on a table to one role, such as
+// select and insert, in lower case and alphabetical order. Grantee is PUBLIC
+// for privileges granted to every role.
+type GrantInfo struct {
+	Grantee         string   `json:"grantee"`
+	Privileges      []string `json:"privileges"`
+	WithGrantOption bool     `json:"with_grant_option,omitempty"`
+}
+
+// granteePublic is the grantee of privileges granted to every role
+const granteePublic = "PUBLIC"
+
+// Function represents a user-defined function or procedure. Definition holds
+// the CREATE statement as reported by pg_get_functiondef, including the
+// dollar-quoted body.
+type Function struct {
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	Arguments  string `json:"arguments"`
+	Definition string `json:"definition"`
+}
+
+// columnJSON is the JSON representation of a Column. Nullable is a pointer so
+// that hand-written input omitting it defaults to a nullable column, as in SQL.
+type columnJSON struct {
+	Name              string  `json:"name"`
+	DataType          string  `json:"type"`
+	IsNullable        *bool   `json:"nullable,omitempty"`
+	DefaultValue      *string `json:"default,omitempty"`
+	IsPrimaryKey      bool    `json:"primary_key,omitempty"`
+	CharacterLength   *int64  `json:"character_length,omitempty"`
+	NumericPrecision  *int64  `json:"numeric_precision,omitempty"`
+	NumericScale      *int64  `json:"numeric_scale,omitempty"`
+	DatetimePrecision *int64  `json:"datetime_precision,omitempty"`
+	FullType          string  `json:"full_type,omitempty"`
+	IsSerial          bool    `json:"serial,omitempty"`
+	OrdinalPosition   int     `json:"ordinal_position,omitempty"`
+	IsInherited       bool    `json:"inherited,omitempty"`
+	Collation         string  `json:"collation,omitempty"`
+	IsComposite       bool    `json:"composite,omitempty"`
+}
+
+// MarshalJSON encodes the column with plain values instead of sql.Null wrappers
+func (c Column) MarshalJSON() ([]byte, error) {
+	nullable := c.IsNullable
+	out := columnJSON{
+		Name:            c.Name,
+		DataType:        c.DataType,
+		IsNullable:      &nullable,
+		IsPrimaryKey:    c.IsPrimaryKey,
+		FullType:        c.FullType,
+		IsSerial:        c.IsSerial,
+		OrdinalPosition: c.OrdinalPosition,
+		IsInherited:     c.IsInherited,
+		Collation:       c.Collation,
+		IsComposite:     c.IsComposite,
+	}
+	if c.DefaultValue.Valid {
+		out.DefaultValue = &c.DefaultValue.String
+	}
+	if c.CharacterLength.Valid {
+		out.CharacterLength = &c.CharacterLength.Int64
+	}
+	if c.NumericPrecision.Valid {
+		out.NumericPrecision = &c.NumericPrecision.Int64
+	}
+	if c.NumericScale.Valid {
+		out.NumericScale = &c.NumericScale.Int64
+	}
+	if c.DatetimePrecision.Valid {
+		out.DatetimePrecision = &c.DatetimePrecision.Int64
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes the representation produced by MarshalJSON
+func (c *Column) UnmarshalJSON(data []byte) error {
+	var in columnJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	*c = Column{
+		Name:            in.Name,
+		DataType:        in.DataType,
+		IsNullable:      in.IsNullable == nil || *in.IsNullable,
+		IsPrimaryKey:    in.IsPrimaryKey,
+		FullType:        in.FullType,
+		IsSerial:        in.IsSerial,
+		OrdinalPosition: in.OrdinalPosition,
+		IsInherited:     in.IsInherited,
+		Collation:       in.Collation,
+		IsComposite:     in.IsComposite,
+	}
+	if in.DefaultValue != nil {
+		c.DefaultValue = sql.NullString{String: *in.DefaultValue, Valid: true}
+	}
+	if in.CharacterLength != nil {
+		c.CharacterLength = sql.NullInt64{Int64: *in.CharacterLength, Valid: true}
+	}
+	if in.NumericPrecision != nil {
+		c.NumericPrecision = sql.NullInt64{Int64: *in.NumericPrecision, Valid: true}
+	}
+	if in.NumericScale != nil {
+		c.NumericScale = sql.NullInt64{Int64: *in.NumericScale, Valid: true}
+	}
+	if in.DatetimePrecision != nil {
+		c.DatetimePrecision = sql.NullInt64{Int64: *in.DatetimePrecision, Valid: true}
+	}
+	return nil
+}
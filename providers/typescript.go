@@ -0,0 +1,121 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// TypeScriptOptions controls how FormatSchemaTypeScriptWithOptions renders fields
+type TypeScriptOptions struct {
+	// OptionalDefaults marks columns that have a default value as optional
+	// (`field?: T`), matching the shape of an insert payload
+	OptionalDefaults bool
+}
+
+// FormatSchemaTypeScript formats the schema as one exported TypeScript
+// interface per table
+func FormatSchemaTypeScript(tables []Table) string {
+	return FormatSchemaTypeScriptWithOptions(tables, TypeScriptOptions{})
+}
+
+// FormatSchemaTypeScriptWithOptions formats the schema as one exported
+// TypeScript interface per table. Fields keep the column order of the schema
+// and nullable columns are typed as `T | null`.
+func FormatSchemaTypeScriptWithOptions(tables []Table, opts TypeScriptOptions) string {
+	var sb strings.Builder
+	tables = normalizeSchema(tables)
+
+	for _, table := range tables {
+		// A partition has the shape of its parent
+		if table.IsPartition() {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+
+		sb.WriteString(fmt.Sprintf("export interface %s {\n", pascalCase(table.Name)))
+		for _, col := range table.Columns {
+			optional := ""
+			if opts.OptionalDefaults && col.DefaultValue.Valid {
+				optional = "?"
+			}
+
+			tsType := mapTypeScriptType(col.DataType)
+			if col.IsNullable {
+				tsType += " | null"
+			}
+
+			sb.WriteString(fmt.Sprintf("  %s%s: %s;\n", typeScriptPropertyName(col.Name), optional, tsType))
+		}
+		sb.WriteString("}\n")
+	}
+
+	return sb.String()
+}
+
+// mapTypeScriptType maps an information_schema data type to a TypeScript type.
+// Dates and timestamps are strings because that is how JSON carries them.
+func mapTypeScriptType(dataType string) string {
+	switch dataType {
+	case "smallint", "integer", "bigint", "smallserial", "serial", "bigserial",
+		"real", "double precision", "numeric", "decimal", "money":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "text", "character varying", "character", "char", "uuid", "citext",
+		"date", "time without time zone", "time with time zone",
+		"timestamp without time zone", "timestamp with time zone", "interval",
+		"inet", "cidr", "macaddr", "bytea", "ltree", "lquery":
+		return "string"
+	case "hstore":
+		return "Record<string, string | null>"
+	case "vector", "halfvec":
+		return "number[]"
+	case "json", "jsonb":
+		return "unknown"
+	case "ARRAY":
+		return "unknown[]"
+	default:
+		return "unknown"
+	}
+}
+
+// pascalCase converts a table name such as "user_accounts" to "UserAccounts"
+func pascalCase(name string) string {
+	var sb strings.Builder
+	upperNext := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upperNext = true
+			continue
+		}
+		if upperNext {
+			sb.WriteRune(unicode.ToUpper(r))
+			upperNext = false
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+
+	result := sb.String()
+	if result == "" || unicode.IsDigit(rune(result[0])) {
+		result = "T" + result
+	}
+	return result
+}
+
+// typeScriptPropertyName quotes column names that are not valid identifiers
+func typeScriptPropertyName(name string) string {
+	for i, r := range name {
+		if r == '_' || r == '$' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		return fmt.Sprintf("%q", name)
+	}
+	if name == "" {
+		return `""`
+	}
+	return name
+}
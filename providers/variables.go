@@ -0,0 +1,111 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SubstituteVariables replaces psql-style variable references in a SQL
+// script: :'name' becomes a quoted string literal, :"name" a quoted
+// identifier and :name the raw value. References inside comments, quoted
+// strings and dollar-quoted bodies are left alone, as are :: casts. A bare
+// :name that is not defined is kept as written, like psql does, since it may
+// be part of an array slice; a quoted reference to an undefined variable is
+// an error.
+func SubstituteVariables(sql string, vars map[string]string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql)
+			} else {
+				end += i
+			}
+			sb.WriteString(sql[i:end])
+			i = end
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end, _ := skipBlockComment(sql, i)
+			sb.WriteString(sql[i:end])
+			i = end
+		case c == '\'' || c == '"':
+			end, _ := skipQuoted(sql, i, c)
+			sb.WriteString(sql[i:end])
+			i = end
+		case c == '$':
+			end := i + 1
+			if tag, ok := dollarTag(sql, i); ok {
+				end, _ = skipDollarQuoted(sql, i, tag)
+			}
+			sb.WriteString(sql[i:end])
+			i = end
+		case c == ':' && i+1 < len(sql) && sql[i+1] == ':':
+			sb.WriteString("::")
+			i += 2
+		case c == ':':
+			replacement, end, err := substituteVariable(sql, i, vars)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(replacement)
+			i = end
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String(), nil
+}
+
+// substituteVariable resolves the variable reference starting at the colon
+// sql[i] and returns its replacement and the position after it
+func substituteVariable(sql string, i int, vars map[string]string) (string, int, error) {
+	if i+1 < len(sql) && (sql[i+1] == '\'' || sql[i+1] == '"') {
+		quote := sql[i+1]
+		end := strings.IndexByte(sql[i+2:], quote)
+		if end < 0 {
+			return sql[i : i+1], i + 1, nil
+		}
+		name := sql[i+2 : i+2+end]
+		if !isVariableName(name) {
+			return sql[i : i+1], i + 1, nil
+		}
+		value, ok := vars[name]
+		if !ok {
+			return "", 0, fmt.Errorf("variable %q is not defined", name)
+		}
+		if quote == '\'' {
+			return "'" + strings.ReplaceAll(value, "'", "''") + "'", i + 3 + end, nil
+		}
+		return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`, i + 3 + end, nil
+	}
+
+	end := i + 1
+	for end < len(sql) && isVariableByte(sql[end]) {
+		end++
+	}
+	value, ok := vars[sql[i+1:end]]
+	if end == i+1 || !ok {
+		return sql[i:end], end, nil
+	}
+	return value, end, nil
+}
+
+// isVariableName reports whether name is a valid psql variable name
+func isVariableName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isVariableByte(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isVariableByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Statuses of a run report
+const (
+	reportStatusOK     = "ok"
+	reportStatusFailed = "failed"
+)
+
+// RunReport is the machine-readable summary of a run written by --report.
+// Durations are in milliseconds.
+type RunReport struct {
+	Status       string            `json:"status"`
+	Error        string            `json:"error,omitempty"`
+	ExitCode     int               `json:"exit_code"`
+	StartedAt    time.Time         `json:"started_at"`
+	DurationMS   int64             `json:"duration_ms"`
+	Provider     string            `json:"provider,omitempty"`
+	Cached       bool              `json:"cached"`
+	SetupMS      int64             `json:"setup_ms"`
+	MigrationsMS int64             `json:"migrations_ms"`
+	ExtractionMS int64             `json:"extraction_ms"`
+	Migrations   []MigrationTiming `json:"migrations"`
+	Tables       int               `json:"tables"`
+}
+
+// MigrationTiming is the time a single migration took to run
+type MigrationTiming struct {
+	Name       string `json:"name"`
+	DurationMS int64  `json:"duration_ms"`
+	Failed     bool   `json:"failed,omitempty"`
+}
+
+// runReport collects the metrics of the current run while --report is set.
+// executeMig2Schema sets it, and the steps of the run record into it.
+var runReport *RunReport
+
+// newRunReport returns a report for a run starting now
+func newRunReport() *RunReport {
+	return &RunReport{StartedAt: time.Now().UTC(), Migrations: []MigrationTiming{}}
+}
+
+// finish records the outcome of the run and its total duration. The error
+// is redacted like the logs, as it may carry a password or a --var-file value.
+func (r *RunReport) finish(err error) {
+	r.DurationMS = time.Since(r.StartedAt).Milliseconds()
+	r.ExitCode = exitCode(err)
+	r.Status = reportStatusOK
+	if err != nil {
+		r.Status = reportStatusFailed
+		r.Error = logSecrets.redact(err.Error())
+	}
+}
+
+// runMigrationsTimed runs the migrations with dbManager. Without a report
+// they run in one call, with one they run one at a time so that the duration
+// of each is recorded, the failing one included.
+func runMigrationsTimed(ctx context.Context, dbManager DatabaseManager, migrations []Migration) error {
+	if runReport == nil {
+		return dbManager.RunMigrations(ctx, migrations)
+	}
+
+	start := time.Now()
+	defer func() { runReport.MigrationsMS = time.Since(start).Milliseconds() }()
+	for _, migration := range migrations {
+		migrationStart := time.Now()
+		err := dbManager.RunMigrations(ctx, []Migration{migration})
+		runReport.Migrations = append(runReport.Migrations, MigrationTiming{
+			Name:       migration.Name,
+			DurationMS: time.Since(migrationStart).Milliseconds(),
+			Failed:     err != nil,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRunReport writes report to path as indented JSON
+func writeRunReport(path string, report *RunReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write run report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readRunReport reads the report written to path
+func readRunReport(t *testing.T, path string) RunReport {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var report RunReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	return report
+}
+
+func TestProcessSchemaWithProviderReport(t *testing.T) {
+	defer resetCommand()
+
+	reader := &MockMigrationReader{
+		DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+			return []Migration{
+				{Name: "001_users", UpSQL: []byte("create table users (id integer);")},
+				{Name: "002_posts", UpSQL: []byte("create table posts (id integer);")},
+			}, nil
+		},
+	}
+	provider := &MockSchemaProvider{
+		ExtractSchemaFunc: func(ctx context.Context, params providers.ExtractParams) (*providers.SchemaResult, error) {
+			return &providers.SchemaResult{Format: params.Format, Schema: providers.Schema{Tables: []providers.Table{{Name: "users"}, {Name: "posts"}}}}, nil
+		},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		resetCommand()
+		runReport = newRunReport()
+
+		var ran [][]Migration
+		dbManager := &MockDatabaseManager{
+			RunMigrationsFunc: func(ctx context.Context, migrations []Migration) error {
+				ran = append(ran, migrations)
+				return nil
+			},
+		}
+		require.NoError(t, processSchemaWithProvider(context.Background(), t.TempDir(), reader, dbManager, provider, nil, nil))
+		assert.Len(t, ran, 2, "migrations run one at a time to time each")
+
+		report := runReport
+		assert.Equal(t, "mock", report.Provider)
+		assert.False(t, report.Cached)
+		assert.Equal(t, 2, report.Tables)
+		require.Len(t, report.Migrations, 2)
+		assert.Equal(t, "001_users", report.Migrations[0].Name)
+		assert.Equal(t, "002_posts", report.Migrations[1].Name)
+		assert.False(t, report.StartedAt.IsZero())
+	})
+
+	t.Run("migration_failure", func(t *testing.T) {
+		resetCommand()
+		runReport = newRunReport()
+
+		dbManager := &MockDatabaseManager{
+			RunMigrationsFunc: func(ctx context.Context, migrations []Migration) error {
+				if migrations[0].Name == "002_posts" {
+					return errors.New("syntax error")
+				}
+				return nil
+			},
+		}
+		err := processSchemaWithProvider(context.Background(), t.TempDir(), reader, dbManager, provider, nil, nil)
+		require.Error(t, err)
+
+		report := runReport
+		assert.Equal(t, []MigrationTiming{
+			{Name: "001_users", DurationMS: report.Migrations[0].DurationMS},
+			{Name: "002_posts", DurationMS: report.Migrations[1].DurationMS, Failed: true},
+		}, report.Migrations)
+		assert.Zero(t, report.Tables)
+	})
+
+	t.Run("setup_failure", func(t *testing.T) {
+		resetCommand()
+		runReport = newRunReport()
+
+		dbManager := &MockDatabaseManager{
+			SetupFunc: func(ctx context.Context) error { return errors.New("docker not running") },
+		}
+		require.Error(t, processSchemaWithProvider(context.Background(), t.TempDir(), reader, dbManager, provider, nil, nil))
+		assert.Empty(t, runReport.Migrations)
+		assert.Zero(t, runReport.SetupMS)
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		resetCommand()
+		runReport = newRunReport()
+		cache, err := newSchemaCache(t.TempDir())
+		require.NoError(t, err)
+
+		require.NoError(t, processSchemaWithProvider(context.Background(), t.TempDir(), reader, &MockDatabaseManager{}, provider, cache, nil))
+		runReport = newRunReport()
+		dbManager := &MockDatabaseManager{}
+		require.NoError(t, processSchemaWithProvider(context.Background(), t.TempDir(), reader, dbManager, provider, cache, nil))
+		assert.False(t, dbManager.SetupCalled)
+		assert.True(t, runReport.Cached)
+		assert.Empty(t, runReport.Migrations)
+	})
+}
+
+func TestExecuteMig2SchemaReport(t *testing.T) {
+	defer resetCommand()
+
+	t.Run("failure_before_database", func(t *testing.T) {
+		resetCommand()
+		reportPath = filepath.Join(t.TempDir(), "report.json")
+		providerName = "missing"
+
+		err := executeMig2Schema(context.Background(), []string{t.TempDir()}, providers.NewDefaultRegistry())
+		require.Error(t, err)
+		assert.Nil(t, runReport)
+
+		report := readRunReport(t, reportPath)
+		assert.Equal(t, reportStatusFailed, report.Status)
+		assert.Contains(t, report.Error, "missing")
+		assert.Equal(t, exitCode(err), report.ExitCode)
+		assert.Empty(t, report.Migrations)
+		assert.False(t, report.StartedAt.IsZero())
+	})
+
+	t.Run("usage_error", func(t *testing.T) {
+		resetCommand()
+		reportPath = filepath.Join(t.TempDir(), "report.json")
+		incremental = true
+
+		require.Error(t, executeMig2Schema(context.Background(), []string{t.TempDir()}, providers.NewDefaultRegistry()))
+
+		report := readRunReport(t, reportPath)
+		assert.Equal(t, reportStatusFailed, report.Status)
+		assert.Equal(t, exitUsage, report.ExitCode)
+	})
+}
+
+func TestRunReportFinish(t *testing.T) {
+	defer func(saved *secretRedactor) { logSecrets = saved }(logSecrets)
+	logSecrets = &secretRedactor{}
+	logSecrets.add("s3cret")
+
+	report := newRunReport()
+	report.finish(withExitCode(exitSetupFailed, errors.New("pq: password authentication failed for s3cret")))
+	assert.Equal(t, reportStatusFailed, report.Status)
+	assert.Equal(t, exitSetupFailed, report.ExitCode)
+	assert.Equal(t, "pq: password authentication failed for "+redactedValue, report.Error)
+
+	report = newRunReport()
+	report.finish(nil)
+	assert.Equal(t, reportStatusOK, report.Status)
+	assert.Empty(t, report.Error)
+	assert.Equal(t, exitOK, report.ExitCode)
+}
+
+func TestReportFlags(t *testing.T) {
+	defer resetCommand()
+
+	tests := []struct {
+		name    string
+		set     func()
+		wantErr string
+	}{
+		{"with_watch", func() { watchMode = true }, "--report cannot be combined with"},
+		{"with_check", func() { checkMode = true }, "--report cannot be combined with"},
+		{"with_diff_from", func() { diffFrom = "001" }, "--report cannot be combined with"},
+		{"with_matrix", func() { pgImages = []string{"postgres:15-alpine", "postgres:16-alpine"} }, "--report cannot be combined with"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetCommand()
+			reportPath = filepath.Join(t.TempDir(), "report.json")
+			tt.set()
+
+			err := executeMig2Schema(context.Background(), []string{"migrations"}, providers.NewDefaultRegistry())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			assert.Equal(t, exitUsage, exitCode(err))
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alc6/mig2schema/providers"
+)
+
+func TestFormatSchemaRowCounts(t *testing.T) {
+	tables := []providers.Table{
+		{Name: "users", Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}}},
+		{Name: "events", Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}}},
+		{Name: "audit", Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}}},
+		{Name: "secrets", Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}}},
+	}
+
+	output := providers.FormatSchemaInfoWithOptions(tables, providers.InfoOptions{
+		RowCounts: providers.RowCounts{
+			"users":  {Rows: 42},
+			"events": {Rows: 1200, Estimated: true},
+			"audit":  {Rows: -1, Estimated: true},
+		},
+	})
+	assert.Contains(t, output, "Table: users\nRows: 42\nColumns:\n")
+	assert.Contains(t, output, "Table: events\nRows: ~1200 (estimate)\nColumns:\n")
+	assert.Contains(t, output, "Table: audit\nRows: unknown (estimate, table not analyzed yet)\nColumns:\n")
+	assert.Contains(t, output, "Table: secrets\nColumns:\n")
+
+	assert.NotContains(t, providers.FormatSchemaInfo(tables), "Rows:")
+}
+
+func TestRowCountFlags(t *testing.T) {
+	defer resetCommand()
+
+	t.Run("exact_requires_row_counts", func(t *testing.T) {
+		resetCommand()
+		exactCounts = true
+		err := executeMig2Schema(context.Background(), []string{"migrations"}, providers.NewDefaultRegistry())
+		require.Error(t, err)
+		assert.Equal(t, exitUsage, exitCode(err))
+		assert.Contains(t, err.Error(), "--exact-counts requires --with-row-counts")
+	})
+
+	t.Run("info_only", func(t *testing.T) {
+		resetCommand()
+		withRowCounts = true
+		extractMode = true
+		err := executeMig2Schema(context.Background(), []string{"migrations"}, providers.NewDefaultRegistry())
+		require.Error(t, err)
+		assert.Equal(t, exitUsage, exitCode(err))
+		assert.Contains(t, err.Error(), "--with-row-counts only supports info output")
+	})
+
+	t.Run("skipped_without_connection", func(t *testing.T) {
+		resetCommand()
+		withRowCounts = true
+		assert.Nil(t, tableRowCounts(nil, []providers.Table{{Name: "users"}}))
+	})
+}
+
+func TestMigrationToSchemaRowCounts(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping row count test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	manager := NewPostgreSQLManager("postgres:16-alpine")
+	require.NoError(t, manager.Setup(ctx))
+	defer manager.Close(ctx)
+
+	migrations := []Migration{{
+		Name: "001_seed",
+		UpSQL: []byte(`create table users (id int primary key);
+insert into users select generate_series(1, 25);
+create table secrets (id int primary key);
+create view active_users as select * from users;
+analyze users;
+create role reader login password 'reader';
+grant select on users to reader;`),
+	}}
+	require.NoError(t, manager.RunMigrations(ctx, migrations))
+
+	db := manager.GetDB()
+	tables, err := providers.ExtractTables(db, "public", true)
+	require.NoError(t, err)
+
+	counts, err := providers.CountRows(ctx, db, "public", tables, true)
+	require.NoError(t, err)
+	assert.Equal(t, providers.RowCounts{"users": {Rows: 25}, "secrets": {Rows: 0}}, counts)
+
+	counts, err = providers.CountRows(ctx, db, "public", tables, false)
+	require.NoError(t, err)
+	assert.Equal(t, providers.RowCount{Rows: 25, Estimated: true}, counts["users"])
+	assert.Equal(t, providers.RowCount{Rows: -1, Estimated: true}, counts["secrets"])
+	assert.NotContains(t, counts, "active_users")
+
+	// A table the user cannot read is left out
+	u, err := url.Parse(manager.GetConnectionString())
+	require.NoError(t, err)
+	u.User = url.UserPassword("reader", "reader")
+	readerDB, err := sql.Open("postgres", u.String())
+	require.NoError(t, err)
+	defer readerDB.Close()
+
+	counts, err = providers.CountRows(ctx, readerDB, "public", tables, true)
+	require.NoError(t, err)
+	assert.Equal(t, providers.RowCounts{"users": {Rows: 25}}, counts)
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"bytes"
 	"database/sql"
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/alc6/mig2schema/providers"
 )
 
@@ -183,4 +190,1529 @@ func TestFormatSchemaComplexIndexes(t *testing.T) {
 	
 	sqlResult := FormatSchemaAsSQL(tables)
 	assert.Contains(t, sqlResult, "create index idx_orders_user_status on orders (user_id, status)")
-}
\ No newline at end of file
+}
+
+func TestFormatSchemaUniqueConstraints(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "accounts",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsNullable: false, IsPrimaryKey: true},
+				{Name: "tenant_id", DataType: "integer", IsNullable: false},
+				{Name: "email", DataType: "character varying", IsNullable: false},
+			},
+			Indexes: []providers.Index{
+				{Name: "idx_accounts_email", Columns: []string{"email"}, IsUnique: true},
+			},
+			UniqueConstraints: []providers.UniqueConstraint{
+				{Name: "accounts_tenant_id_email_key", Columns: []string{"tenant_id", "email"}},
+			},
+		},
+	}
+
+	t.Run("info_format", func(t *testing.T) {
+		result := FormatSchema(tables)
+		assert.Contains(t, result, "Constraints:\n  - accounts_tenant_id_email_key UNIQUE (tenant_id, email)")
+		assert.Contains(t, result, "idx_accounts_email on (email) (UNIQUE)")
+	})
+
+	t.Run("sql_format", func(t *testing.T) {
+		result := FormatSchemaAsSQL(tables)
+		assert.Contains(t, result, "    primary key (id),\n    constraint accounts_tenant_id_email_key unique (tenant_id, email)\n);")
+		assert.Contains(t, result, "create unique index idx_accounts_email on accounts (email);")
+		assert.NotContains(t, result, "index accounts_tenant_id_email_key")
+	})
+}
+
+func TestFormatSchemaAsSQLQuotesIdentifiers(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "UserProfiles",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsNullable: false, IsPrimaryKey: true},
+				{Name: "order", DataType: "integer", IsNullable: true},
+				{Name: "displayName", DataType: "text", IsNullable: true},
+				{Name: `odd"name`, DataType: "text", IsNullable: true},
+			},
+			Indexes: []providers.Index{
+				{Name: "idx_user_profiles_order", Columns: []string{"order", "displayName"}},
+			},
+		},
+	}
+
+	result := FormatSchemaAsSQL(tables)
+
+	assert.Contains(t, result, `create table "UserProfiles" (`)
+	assert.Contains(t, result, `    id integer not null,`)
+	assert.Contains(t, result, `    "order" integer,`)
+	assert.Contains(t, result, `    "displayName" text,`)
+	assert.Contains(t, result, `    "odd""name" text,`)
+	assert.Contains(t, result, `primary key (id)`)
+	assert.Contains(t, result, `create index idx_user_profiles_order on "UserProfiles" ("order", "displayName");`)
+}
+
+func TestFormatSchemaForeignKeys(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "posts",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsNullable: false, IsPrimaryKey: true},
+				{Name: "user_id", DataType: "integer", IsNullable: false},
+			},
+			ForeignKeys: []providers.ForeignKey{
+				{Name: "posts_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+			},
+		},
+	}
+
+	info := FormatSchema(tables)
+	assert.Contains(t, info, "Constraints:\n  - posts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)")
+
+	sqlOutput := FormatSchemaAsSQL(tables)
+	assert.Contains(t, sqlOutput, "    primary key (id),\n    constraint posts_user_id_fkey foreign key (user_id) references users (id)\n);")
+}
+
+func TestFormatSchemaForeignKeyActions(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "posts",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsNullable: false, IsPrimaryKey: true},
+				{Name: "user_id", DataType: "integer", IsNullable: true},
+				{Name: "category_id", DataType: "integer", IsNullable: true},
+			},
+			ForeignKeys: []providers.ForeignKey{
+				{Name: "posts_category_id_fkey", Columns: []string{"category_id"}, ReferencedTable: "categories", ReferencedColumns: []string{"id"},
+					OnDelete: "NO ACTION", OnUpdate: "NO ACTION"},
+				{Name: "posts_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"},
+					OnDelete: "SET NULL", OnUpdate: "CASCADE"},
+			},
+		},
+	}
+
+	info := FormatSchema(tables)
+	assert.Contains(t, info, "  - posts_category_id_fkey FOREIGN KEY (category_id) REFERENCES categories (id)\n")
+	assert.Contains(t, info, "  - posts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL ON UPDATE CASCADE\n")
+
+	sqlOutput := FormatSchemaAsSQL(tables)
+	assert.Contains(t, sqlOutput, "constraint posts_category_id_fkey foreign key (category_id) references categories (id),\n")
+	assert.Contains(t, sqlOutput, "constraint posts_user_id_fkey foreign key (user_id) references users (id) on delete set null on update cascade\n")
+}
+
+func TestFormatSchemaTriggers(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "posts",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsNullable: false, IsPrimaryKey: true},
+			},
+			Triggers: []providers.Trigger{
+				{
+					Name:         "posts_touch",
+					Table:        "posts",
+					Timing:       "BEFORE",
+					Events:       []string{"INSERT", "UPDATE"},
+					FunctionName: "touch",
+					Definition:   "CREATE TRIGGER posts_touch BEFORE INSERT OR UPDATE ON public.posts FOR EACH ROW EXECUTE FUNCTION touch()",
+				},
+			},
+		},
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsNullable: false, IsPrimaryKey: true},
+			},
+		},
+	}
+
+	info := FormatSchema(tables)
+	assert.Contains(t, info, "Triggers:\n  - posts_touch BEFORE INSERT OR UPDATE EXECUTE FUNCTION touch()\n")
+
+	sqlOutput := FormatSchemaAsSQL(tables)
+	triggerAt := strings.Index(sqlOutput, "CREATE TRIGGER posts_touch")
+	require.NotEqual(t, -1, triggerAt)
+	assert.Greater(t, triggerAt, strings.Index(sqlOutput, "create table users"), "triggers should follow all tables")
+	assert.Contains(t, sqlOutput, "EXECUTE FUNCTION touch();\n")
+}
+
+func TestFormatFullSchema(t *testing.T) {
+	schema := providers.Schema{
+		Tables: []providers.Table{
+			{
+				Name: "accounts",
+				Columns: []providers.Column{
+					{Name: "id", DataType: "integer", IsNullable: false, IsPrimaryKey: true},
+					{Name: "code", DataType: "text", IsNullable: false, DefaultValue: sql.NullString{String: "next_code()", Valid: true}},
+				},
+			},
+		},
+		Functions: []providers.Function{
+			{
+				Name:       "next_code",
+				Kind:       "function",
+				Definition: "CREATE OR REPLACE FUNCTION public.next_code()\n RETURNS text\n LANGUAGE plpgsql\nAS $function$\nbegin\n  return 'x;y';\nend;\n$function$",
+			},
+			{
+				Name:       "archive",
+				Kind:       "procedure",
+				Arguments:  "days integer",
+				Definition: "CREATE OR REPLACE PROCEDURE public.archive(IN days integer)\n LANGUAGE sql\nAS $procedure$select 1$procedure$",
+			},
+		},
+	}
+
+	info := providers.FormatFullSchemaInfo(schema)
+	assert.Contains(t, info, "Functions:\n  - next_code() FUNCTION\n  - archive(days integer) PROCEDURE\n")
+
+	sqlOutput := providers.FormatFullSchemaSQL(schema, providers.FormatOptions{})
+	assert.Less(t, strings.Index(sqlOutput, "FUNCTION public.next_code"), strings.Index(sqlOutput, "create table accounts"))
+	assert.Contains(t, sqlOutput, "$function$;\n")
+	assert.Empty(t, providers.LexicalCheck(sqlOutput), "emitted DDL should keep dollar-quoted bodies intact")
+	assert.Len(t, providers.SplitStatements(sqlOutput), 4)
+	assert.True(t, strings.HasPrefix(sqlOutput, "set check_function_bodies = off;\n"), "function bodies may reference tables created later")
+
+	schema.Functions = nil
+	assert.NotContains(t, providers.FormatFullSchemaSQL(schema, providers.FormatOptions{}), "check_function_bodies")
+}
+
+func TestFormatCompositeTypes(t *testing.T) {
+	schema := providers.Schema{
+		CompositeTypes: []providers.CompositeType{
+			{Name: "address", Attributes: []providers.CompositeAttribute{
+				{Name: "street", Type: "text"},
+				{Name: "zip", Type: "character varying(10)"},
+			}},
+			{Name: "Contact", Attributes: []providers.CompositeAttribute{
+				{Name: "email", Type: "text"},
+				{Name: "home", Type: "address"},
+			}},
+		},
+		Tables: []providers.Table{
+			{
+				Name: "customers",
+				Columns: []providers.Column{
+					{Name: "id", DataType: "integer", IsPrimaryKey: true},
+					{Name: "billing", DataType: "address", IsNullable: true, IsComposite: true},
+					{Name: "contact", DataType: "Contact", IsComposite: true},
+				},
+			},
+		},
+	}
+
+	sqlOutput := providers.FormatFullSchemaSQL(schema, providers.FormatOptions{})
+	assert.Contains(t, sqlOutput, "create type address as (street text, zip character varying(10));\n"+
+		"create type \"Contact\" as (email text, home address);\n")
+	assert.Less(t, strings.Index(sqlOutput, "create type address"), strings.Index(sqlOutput, "create table customers"))
+	assert.Contains(t, sqlOutput, "    billing address,\n")
+	assert.Contains(t, sqlOutput, "    contact \"Contact\" not null,\n")
+
+	info := providers.FormatFullSchemaInfo(schema)
+	assert.Contains(t, info, "  - billing address NULL\n")
+	assert.Contains(t, info, "Composite types:\n  - address (street text, zip character varying(10))\n  - Contact (email text, home address)\n")
+	assert.Empty(t, providers.UnmappedTypes(schema.Tables, nil))
+
+	clean := providers.FormatFullSchemaSQL(schema, providers.FormatOptions{Clean: true})
+	assert.Less(t, strings.Index(clean, "drop type if exists \"Contact\""), strings.Index(clean, "drop type if exists address"))
+}
+
+func TestSplitSchemaSQL(t *testing.T) {
+	schema := providers.Schema{
+		Enums: []providers.Enum{{Name: "status", Values: []string{"draft", "published"}}},
+		Tables: []providers.Table{
+			{
+				Name: "posts",
+				Columns: []providers.Column{
+					{Name: "id", DataType: "integer", IsPrimaryKey: true},
+					{Name: "user_id", DataType: "integer"},
+					{Name: "status", DataType: "status"},
+				},
+				Indexes:     []providers.Index{{Name: "posts_user_id_idx", Columns: []string{"user_id"}}},
+				ForeignKeys: []providers.ForeignKey{{Name: "posts_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}}},
+			},
+			{
+				Name:    "users",
+				Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}},
+			},
+			{
+				Name:    "User Logs",
+				Columns: []providers.Column{{Name: "id", DataType: "integer"}},
+			},
+		},
+		Views: []providers.View{{Name: "drafts", Definition: " SELECT id\n   FROM posts\n  WHERE status = 'draft'::status"}},
+	}
+
+	files := providers.SplitSchemaSQL(schema, providers.FormatOptions{})
+	require.Len(t, files, 4)
+	assert.Equal(t, "_schema.sql", files[0].Name)
+	assert.Equal(t, "create type status as enum ('draft', 'published');\n\n"+
+		"\\ir User_Logs.sql\n\\ir users.sql\n\\ir posts.sql\n\n"+
+		"create view drafts as\n SELECT id\n   FROM posts\n  WHERE status = 'draft'::status;\n\n", files[0].Content)
+
+	assert.Equal(t, "posts.sql", files[3].Name)
+	assert.Equal(t, "create table posts (\n"+
+		"    id integer not null,\n"+
+		"    user_id integer not null,\n"+
+		"    status status not null,\n"+
+		"    primary key (id),\n"+
+		"    constraint posts_user_id_fkey foreign key (user_id) references users (id)\n"+
+		");\n\n"+
+		"create index posts_user_id_idx on posts (user_id);\n", files[3].Content)
+
+	// Every table file together with the index holds the single script
+	var combined strings.Builder
+	for _, file := range files {
+		combined.WriteString(file.Content)
+	}
+	single := providers.FormatFullSchemaSQL(schema, providers.FormatOptions{})
+	for _, stmt := range providers.SplitStatements(single) {
+		assert.Contains(t, combined.String(), stmt)
+	}
+
+	// With the alter style, foreign keys move to the index file
+	files = providers.SplitSchemaSQL(schema, providers.FormatOptions{ForeignKeyStyle: providers.ForeignKeyAlter})
+	assert.NotContains(t, files[3].Content, "foreign key")
+	assert.Contains(t, files[0].Content, "alter table posts add constraint posts_user_id_fkey foreign key (user_id) references users (id);\n")
+}
+
+func TestFormatSchemaSQLDialect(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "posts",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "bigint", IsPrimaryKey: true, IsSerial: true, DefaultValue: sql.NullString{String: "nextval('posts_id_seq'::regclass)", Valid: true}},
+				{Name: "user_id", DataType: "integer"},
+				{Name: "title", DataType: "character varying", CharacterLength: sql.NullInt64{Int64: 200, Valid: true}},
+				{Name: "body", DataType: "text", IsNullable: true},
+				{Name: "published", DataType: "boolean", DefaultValue: sql.NullString{String: "false", Valid: true}},
+				{Name: "metadata", DataType: "jsonb", DefaultValue: sql.NullString{String: "'{}'::jsonb", Valid: true}},
+				{Name: "tags", DataType: "ARRAY", FullType: "text[]", IsNullable: true},
+				{Name: "created_at", DataType: "timestamp with time zone", DefaultValue: sql.NullString{String: "now()", Valid: true}},
+				{Name: "mood", DataType: "mood", FullType: "mood", IsNullable: true},
+			},
+			Indexes: []providers.Index{
+				{Name: "posts_title_idx", Columns: []string{"lower((title)::text)"}},
+				{Name: "posts_tags_idx", Columns: []string{"tags"}, Method: "gin"},
+				{Name: "posts_user_id_idx", Columns: []string{"user_id"}},
+			},
+			ForeignKeys: []providers.ForeignKey{{Name: "posts_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}, OnDelete: "CASCADE"}},
+		},
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true, IsSerial: true},
+				{Name: "email", DataType: "character varying", CharacterLength: sql.NullInt64{Int64: 255, Valid: true}},
+			},
+			UniqueConstraints: []providers.UniqueConstraint{{Name: "users_email_key", Columns: []string{"email"}}},
+		},
+	}
+
+	assert.Equal(t, providers.FormatSchemaSQL(tables), providers.FormatSchemaSQLDialect(tables, providers.DialectPostgres, providers.FormatOptions{}))
+
+	assert.Equal(t, "create table `users` (\n"+
+		"    `id` int not null auto_increment,\n"+
+		"    `email` varchar(255) not null,\n"+
+		"    primary key (`id`),\n"+
+		"    constraint `users_email_key` unique (`email`)\n"+
+		");\n\n"+
+		"-- posts.mood: type mood has no MySQL equivalent, written as text\n"+
+		"-- posts: gin index posts_tags_idx left out, MySQL has no equivalent\n"+
+		"create table `posts` (\n"+
+		"    `id` bigint not null auto_increment,\n"+
+		"    `user_id` int not null,\n"+
+		"    `title` varchar(200) not null,\n"+
+		"    `body` text,\n"+
+		"    `published` tinyint(1) not null default 0,\n"+
+		"    `metadata` json not null default ('{}'),\n"+
+		"    `tags` json,\n"+
+		"    `created_at` datetime(6) not null default current_timestamp(6),\n"+
+		"    `mood` text,\n"+
+		"    primary key (`id`),\n"+
+		"    constraint `posts_user_id_fkey` foreign key (`user_id`) references `users` (`id`) on delete cascade\n"+
+		");\n\n"+
+		"create index `posts_title_idx` on `posts` ((lower((title)::text)));\n"+
+		"create index `posts_user_id_idx` on `posts` (`user_id`);\n\n",
+		providers.FormatSchemaSQLDialect(tables, providers.DialectMySQL, providers.FormatOptions{}))
+
+	// Foreign keys of a cycle are added once both tables exist
+	cycle := []providers.Table{
+		{Name: "a", Columns: []providers.Column{{Name: "b_id", DataType: "integer"}},
+			ForeignKeys: []providers.ForeignKey{{Name: "a_b_fkey", Columns: []string{"b_id"}, ReferencedTable: "b", ReferencedColumns: []string{"id"}}}},
+		{Name: "b", Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}, {Name: "a_id", DataType: "integer"}},
+			ForeignKeys: []providers.ForeignKey{{Name: "b_a_fkey", Columns: []string{"a_id"}, ReferencedTable: "a", ReferencedColumns: []string{"b_id"}}}},
+	}
+	assert.Contains(t, providers.FormatSchemaSQLDialect(cycle, providers.DialectMySQL, providers.FormatOptions{}),
+		"alter table `a` add constraint `a_b_fkey` foreign key (`b_id`) references `b` (`id`);\n")
+
+	// Table order and foreign key style apply as in PostgreSQL output
+	altered := providers.FormatSchemaSQLDialect(tables, providers.DialectMySQL, providers.FormatOptions{ForeignKeyStyle: providers.ForeignKeyAlter})
+	assert.NotContains(t, altered, "    constraint `posts_user_id_fkey`")
+	assert.Contains(t, altered, "alter table `posts` add constraint `posts_user_id_fkey` foreign key (`user_id`) references `users` (`id`) on delete cascade;\n")
+	independent := []providers.Table{{Name: "b", Columns: []providers.Column{{Name: "id", DataType: "integer"}}}, {Name: "a", Columns: []providers.Column{{Name: "id", DataType: "integer"}}}}
+	created := providers.FormatSchemaSQLDialect(independent, providers.DialectMySQL, providers.FormatOptions{TableOrder: providers.TableOrderCreated})
+	assert.Less(t, strings.Index(created, "create table `b`"), strings.Index(created, "create table `a`"))
+	byName := providers.FormatSchemaSQLDialect(independent, providers.DialectMySQL, providers.FormatOptions{})
+	assert.Less(t, strings.Index(byName, "create table `a`"), strings.Index(byName, "create table `b`"))
+
+	// Text and binary key columns get a length MySQL can index
+	keyed := []providers.Table{{
+		Name: "documents",
+		Columns: []providers.Column{
+			{Name: "slug", DataType: "text", IsPrimaryKey: true},
+			{Name: "checksum", DataType: "bytea"},
+			{Name: "body", DataType: "text"},
+			{Name: "search", DataType: "tsvector"},
+		},
+		Indexes: []providers.Index{
+			{Name: "documents_checksum_idx", Columns: []string{"checksum"}},
+			{Name: "documents_search_idx", Columns: []string{"search"}, Method: "gin"},
+		},
+	}}
+	assert.Equal(t, "-- documents.slug: text key column written as varchar(255)\n"+
+		"-- documents.checksum: longblob key column written as varbinary(255)\n"+
+		"-- documents: gin index documents_search_idx left out, MySQL has no equivalent\n"+
+		"create table `documents` (\n"+
+		"    `slug` varchar(255) not null,\n"+
+		"    `checksum` varbinary(255) not null,\n"+
+		"    `body` text not null,\n"+
+		"    `search` text not null,\n"+
+		"    primary key (`slug`)\n"+
+		");\n\n"+
+		"create index `documents_checksum_idx` on `documents` (`checksum`);\n\n",
+		providers.FormatSchemaSQLDialect(keyed, providers.DialectMySQL, providers.FormatOptions{}))
+}
+
+func TestParseSQLDialect(t *testing.T) {
+	dialect, err := providers.ParseSQLDialect("")
+	require.NoError(t, err)
+	assert.Equal(t, providers.DialectPostgres, dialect)
+
+	dialect, err = providers.ParseSQLDialect("mysql")
+	require.NoError(t, err)
+	assert.Equal(t, providers.DialectMySQL, dialect)
+
+	_, err = providers.ParseSQLDialect("oracle")
+	assert.ErrorContains(t, err, "unsupported dialect: oracle")
+}
+
+func TestFormatSchemaFunctionDefaults(t *testing.T) {
+	tests := []struct {
+		name         string
+		dataType     string
+		defaultValue string
+		want         string
+	}{
+		{"gen_random_uuid", "uuid", "gen_random_uuid()", "gen_random_uuid()"},
+		{"qualified_gen_random_uuid", "uuid", "public.gen_random_uuid()", "gen_random_uuid()"},
+		{"quoted_qualification", "uuid", `"public".gen_random_uuid()`, "gen_random_uuid()"},
+		{"now", "timestamp with time zone", "now()", "now()"},
+		{"jsonb_literal", "jsonb", "'{}'::jsonb", "'{}'::jsonb"},
+		{"qualified_cast", "status", "'draft'::public.status", "'draft'::status"},
+		{"nested_calls", "text", "public.slugify(public.random_name())", "slugify(random_name())"},
+		{"literal_kept", "text", "'public.users'::text", "'public.users'::text"},
+		{"doubled_quote_literal_kept", "text", "'it''s public.users'::text", "'it''s public.users'::text"},
+		{"escape_string_kept", "text", `E'it\'s public.users'::text`, `E'it\'s public.users'::text`},
+		{"qualified_after_escape_string", "text", `concat(E'\'', public.random_name())`, `concat(E'\'', random_name())`},
+		{"other_schema_kept", "uuid", "extensions.uuid_generate_v4()", "extensions.uuid_generate_v4()"},
+		{"suffix_kept", "text", "my_public.f()", "my_public.f()"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tables := []providers.Table{{
+				Name: "items",
+				Columns: []providers.Column{
+					{Name: "value", DataType: tt.dataType, DefaultValue: sql.NullString{String: tt.defaultValue, Valid: true}},
+				},
+			}}
+
+			assert.Contains(t, providers.FormatSchemaSQL(tables), " not null default "+tt.want+"\n")
+			assert.Contains(t, providers.FormatSchemaInfo(tables), " NOT NULL DEFAULT "+tt.want+"\n")
+			assert.Equal(t, tt.defaultValue, tables[0].Columns[0].DefaultValue.String, "the input is not modified")
+		})
+	}
+}
+
+func TestFormatSchemaGrants(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name:    "users",
+			Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}},
+			Indexes: []providers.Index{{Name: "users_id_idx", Columns: []string{"id"}}},
+			Grants: []providers.GrantInfo{
+				{Grantee: "PUBLIC", Privileges: []string{"select"}},
+				{Grantee: "App Writer", Privileges: []string{"insert", "update"}, WithGrantOption: true},
+			},
+		},
+	}
+
+	sqlOutput := providers.FormatSchemaSQL(tables)
+	assert.Contains(t, sqlOutput, "create index users_id_idx on users (id);\n\n"+
+		"grant select on users to public;\n"+
+		"grant insert, update on users to \"App Writer\" with grant option;\n\n")
+
+	info := providers.FormatSchemaInfo(tables)
+	assert.Contains(t, info, "Grants:\n  - PUBLIC: SELECT\n  - App Writer: INSERT, UPDATE WITH GRANT OPTION\n")
+
+	tables[0].Grants = nil
+	assert.NotContains(t, providers.FormatSchemaSQL(tables), "grant")
+	assert.NotContains(t, providers.FormatSchemaInfo(tables), "Grants:")
+}
+
+func TestFormatFullSchemaObjects(t *testing.T) {
+	schema := providers.Schema{
+		Enums: []providers.Enum{{Name: "mood", Values: []string{"happy", "it's fine"}}},
+		Sequences: []providers.Sequence{
+			{Name: "order_number", DataType: "bigint", Start: 1000, Increment: 10},
+			{Name: "orders_id_seq", DataType: "integer", Start: 1, Increment: 1, OwnedBy: "orders.id"},
+		},
+		Tables: []providers.Table{
+			{
+				Name: "orders",
+				Columns: []providers.Column{
+					{Name: "id", DataType: "integer", IsPrimaryKey: true, IsSerial: true, DefaultValue: sql.NullString{String: "nextval('orders_id_seq'::regclass)", Valid: true}},
+					{Name: "number", DataType: "bigint", DefaultValue: sql.NullString{String: "nextval('order_number'::regclass)", Valid: true}},
+					{Name: "mood", DataType: "mood", IsNullable: true},
+				},
+			},
+		},
+		Views: []providers.View{{Name: "happy_orders", Definition: " SELECT id,\n    number\n   FROM orders\n  WHERE mood = 'happy'::mood"}},
+	}
+
+	info := providers.FormatFullSchemaInfo(schema)
+	assert.Contains(t, info, "Views:\n  - happy_orders\n")
+	assert.Contains(t, info, "Enums:\n  - mood (happy, it's fine)\n")
+	assert.Contains(t, info, "Sequences:\n  - order_number BIGINT (start 1000, increment 10)\n\n")
+
+	sqlOutput := providers.FormatFullSchemaSQL(schema, providers.FormatOptions{})
+	assert.Contains(t, sqlOutput, "create type mood as enum ('happy', 'it''s fine');\n")
+	assert.Contains(t, sqlOutput, "create sequence order_number as bigint start with 1000 increment by 10;\n")
+	assert.NotContains(t, sqlOutput, "create sequence orders_id_seq")
+	assert.Contains(t, sqlOutput, "create view happy_orders as\n SELECT id,\n    number\n   FROM orders\n  WHERE mood = 'happy'::mood;\n")
+
+	// Types and sequences come before the table using them, views after it
+	assert.Less(t, strings.Index(sqlOutput, "create type mood"), strings.Index(sqlOutput, "create table orders"))
+	assert.Less(t, strings.Index(sqlOutput, "create sequence order_number"), strings.Index(sqlOutput, "create table orders"))
+	assert.Less(t, strings.Index(sqlOutput, "create table orders"), strings.Index(sqlOutput, "create view happy_orders"))
+	assert.Empty(t, providers.LexicalCheck(sqlOutput))
+	assert.Len(t, providers.SplitStatements(sqlOutput), 4)
+
+	// The tables stay reachable from a provider result
+	result := providers.SchemaResult{Schema: schema}
+	assert.Equal(t, "orders", result.Tables[0].Name)
+
+	// Only the sequence of a serial column is left to its column; identity
+	// columns are not extracted, so their sequence is created on its own
+	schema.Sequences = append(schema.Sequences, providers.Sequence{Name: "orders_number_seq", DataType: "bigint", Start: 1, Increment: 1, OwnedBy: "orders.number"})
+	sqlOutput = providers.FormatFullSchemaSQL(schema, providers.FormatOptions{})
+	assert.Contains(t, sqlOutput, "create sequence orders_number_seq as bigint start with 1 increment by 1;\n")
+	assert.NotContains(t, sqlOutput, "create sequence orders_id_seq")
+	assert.Contains(t, providers.FormatFullSchemaInfo(schema), "  - orders_number_seq BIGINT (start 1, increment 1)\n")
+}
+
+func TestFormatFullSchemaIdempotent(t *testing.T) {
+	schema := providers.Schema{
+		Extensions: []string{"pgcrypto"},
+		Enums:      []providers.Enum{{Name: "mood", Values: []string{"happy", "sad"}}},
+		Sequences:  []providers.Sequence{{Name: "order_number", DataType: "bigint", Start: 1, Increment: 1}},
+		Functions: []providers.Function{{Name: "touch", Kind: "function", Arguments: "",
+			Definition: "CREATE OR REPLACE FUNCTION public.touch()\n RETURNS trigger\n LANGUAGE plpgsql\nAS $function$ begin return new; end $function$"}},
+		Tables: []providers.Table{
+			{
+				Name:        "users",
+				Columns:     []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}, {Name: "team_id", DataType: "integer"}},
+				Indexes:     []providers.Index{{Name: "idx_users_team_id", Columns: []string{"team_id"}}},
+				ForeignKeys: []providers.ForeignKey{{Name: "users_team_id_fkey", Columns: []string{"team_id"}, ReferencedTable: "teams", ReferencedColumns: []string{"id"}}},
+				Triggers:    []providers.Trigger{{Name: "users_touch", Definition: "CREATE TRIGGER users_touch BEFORE UPDATE ON public.users FOR EACH ROW EXECUTE FUNCTION touch()"}},
+			},
+			{
+				Name:        "teams",
+				Columns:     []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}, {Name: "owner_id", DataType: "integer"}},
+				ForeignKeys: []providers.ForeignKey{{Name: "teams_owner_id_fkey", Columns: []string{"owner_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}}},
+			},
+		},
+		Views: []providers.View{{Name: "team_users", Definition: " SELECT id\n   FROM users"}},
+	}
+
+	sqlOutput := providers.FormatFullSchemaSQL(schema, providers.FormatOptions{IfNotExists: true, Clean: true})
+	assert.True(t, strings.HasPrefix(sqlOutput, "drop view if exists team_users;\n"+
+		"alter table if exists teams drop constraint if exists teams_owner_id_fkey;\n"+
+		"drop table if exists users;\n"+
+		"drop table if exists teams;\n"+
+		"drop function if exists touch();\n"+
+		"drop sequence if exists order_number;\n"+
+		"drop type if exists mood;\n"+
+		"drop extension if exists pgcrypto;\n\n"+
+		"create extension if not exists pgcrypto;\n\n"), sqlOutput)
+	assert.Contains(t, sqlOutput, "do $$ begin\n    create type mood as enum ('happy', 'sad');\nexception when duplicate_object then null;\nend $$;\n")
+	assert.Contains(t, sqlOutput, "create sequence if not exists order_number as bigint start with 1 increment by 1;\n")
+	assert.Contains(t, sqlOutput, "create table if not exists teams (\n")
+	assert.Contains(t, sqlOutput, "create table if not exists users (\n")
+	assert.Contains(t, sqlOutput, "create index if not exists idx_users_team_id on users (team_id);\n")
+	assert.Contains(t, sqlOutput, "do $$ begin\n    alter table teams add constraint teams_owner_id_fkey foreign key (owner_id) references users (id);\nexception when duplicate_object then null;\nend $$;\n")
+	assert.Contains(t, sqlOutput, "do $$ begin\n    CREATE TRIGGER users_touch BEFORE UPDATE ON public.users FOR EACH ROW EXECUTE FUNCTION touch();\nexception when duplicate_object then null;\nend $$;\n")
+	assert.Contains(t, sqlOutput, "create or replace view team_users as\n")
+	assert.Empty(t, providers.LexicalCheck(sqlOutput))
+
+	// The default output has no guards
+	plain := providers.FormatFullSchemaSQL(schema, providers.FormatOptions{})
+	assert.NotContains(t, plain, "if not exists teams")
+	assert.NotContains(t, plain, "drop ")
+	assert.NotContains(t, plain, "do $$")
+}
+
+func TestFormatServerVersion(t *testing.T) {
+	assert.Equal(t, "16.4", providers.FormatServerVersion(160004))
+	assert.Equal(t, "10.23", providers.FormatServerVersion(100023))
+	assert.Equal(t, "9.6.24", providers.FormatServerVersion(90624))
+
+	info := providers.FormatFullSchemaInfo(providers.Schema{ServerVersion: 170002})
+	assert.True(t, strings.HasPrefix(info, "PostgreSQL version: 17.2\n\n"), info)
+	assert.NotContains(t, providers.FormatFullSchemaInfo(providers.Schema{}), "PostgreSQL version")
+}
+
+func TestFormatSchemaInfoSummary(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "email", DataType: "text", IsNullable: true},
+			},
+			Indexes: []providers.Index{{Name: "idx_users_email", Columns: []string{"email"}}},
+		},
+		{
+			Name: "audit_log",
+			Columns: []providers.Column{
+				{Name: "user_id", DataType: "integer"},
+				{Name: "message", DataType: "text", IsNullable: true},
+			},
+			ForeignKeys: []providers.ForeignKey{{Name: "audit_log_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}}},
+		},
+	}
+
+	assert.NotContains(t, providers.FormatSchemaInfo(tables), "Summary:")
+
+	info := providers.FormatSchemaInfoWithOptions(tables, providers.InfoOptions{Summary: true})
+	assert.True(t, strings.HasSuffix(info, "Summary:\n"+
+		"  Tables: 2\n"+
+		"  Columns: 4 (2 nullable)\n"+
+		"  Indexes: 1\n"+
+		"  Foreign keys: 1\n"+
+		"  WARNING: 1 table(s) without a primary key: audit_log\n\n"), info)
+
+	full := providers.FormatFullSchemaInfoWithOptions(providers.Schema{
+		Tables:     tables[:1],
+		Extensions: []string{"citext"},
+	}, providers.InfoOptions{Summary: true})
+	assert.Greater(t, strings.Index(full, "Summary:"), strings.Index(full, "Extensions: citext"), "summary should come last")
+	assert.NotContains(t, full, "WARNING")
+}
+
+func TestFormatSchemaPartitions(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name:           "events_2024",
+			Columns:        []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}, {Name: "created_at", DataType: "date", IsPrimaryKey: true}},
+			Indexes:        []providers.Index{{Name: "events_2024_kind_idx", Columns: []string{"kind"}}},
+			PartitionOf:    "events",
+			PartitionBound: "FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')",
+		},
+		{
+			Name:              "events",
+			Columns:           []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}, {Name: "created_at", DataType: "date", IsPrimaryKey: true}},
+			Indexes:           []providers.Index{{Name: "idx_events_kind", Columns: []string{"kind"}}},
+			PartitionStrategy: "range",
+			PartitionKey:      "created_at",
+		},
+		{
+			Name:              "events_default",
+			Columns:           []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}, {Name: "created_at", DataType: "date", IsPrimaryKey: true}},
+			PartitionOf:       "events",
+			PartitionBound:    "DEFAULT",
+			PartitionStrategy: "list",
+			PartitionKey:      "id",
+		},
+	}
+
+	sqlOutput := providers.FormatSchemaSQL(tables)
+	assert.Contains(t, sqlOutput, "    primary key (id, created_at)\n) partition by range (created_at);\n")
+	assert.Contains(t, sqlOutput, "create table events_2024 partition of events FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');\n")
+	assert.Contains(t, sqlOutput, "create table events_default partition of events DEFAULT partition by list (id);\n")
+	assert.Less(t, strings.Index(sqlOutput, "create table events ("), strings.Index(sqlOutput, "create table events_2024"), "partitions should follow their parent")
+	assert.NotContains(t, sqlOutput, "events_2024_kind_idx", "partition indexes are created from the parent")
+	assert.Equal(t, 1, strings.Count(sqlOutput, "create index"))
+	assert.Empty(t, providers.LexicalCheck(sqlOutput))
+
+	info := providers.FormatSchemaInfo(tables)
+	assert.Contains(t, info, "Table: events\nPartitioned by: range (created_at)\nColumns:\n")
+	assert.Contains(t, info, "Table: events_2024\nPartition of: events FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')\n\n")
+	assert.NotContains(t, info, "events_2024_kind_idx")
+
+	ts := providers.FormatSchemaTypeScript(tables)
+	assert.Contains(t, ts, "export interface Events {")
+	assert.NotContains(t, ts, "Events2024")
+}
+
+func TestFormatSchemaInheritance(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "audited_users",
+			Columns: []providers.Column{
+				{Name: "created_at", DataType: "date", IsInherited: true},
+				{Name: "id", DataType: "integer", IsInherited: true},
+				{Name: "audited_by", DataType: "text", IsNullable: true},
+			},
+			Inherits: []string{"users"},
+		},
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "created_at", DataType: "date", IsInherited: true},
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+			},
+			Inherits: []string{"records"},
+		},
+		{Name: "records", Columns: []providers.Column{{Name: "created_at", DataType: "date"}}},
+		{
+			Name:     "archived_records",
+			Columns:  []providers.Column{{Name: "created_at", DataType: "date", IsInherited: true}},
+			Inherits: []string{"records"},
+		},
+	}
+
+	sqlOutput := providers.FormatSchemaSQL(tables)
+	assert.Contains(t, sqlOutput, "create table users (\n    id integer not null,\n    primary key (id)\n) inherits (records);\n")
+	assert.Contains(t, sqlOutput, "create table audited_users (\n    audited_by text\n) inherits (users);\n")
+	assert.Contains(t, sqlOutput, "create table archived_records (\n) inherits (records);\n")
+	assert.Less(t, strings.Index(sqlOutput, "create table records"), strings.Index(sqlOutput, "create table archived_records"), "parents should come first")
+	assert.Less(t, strings.Index(sqlOutput, "create table users"), strings.Index(sqlOutput, "create table audited_users"), "parents should come first")
+	assert.Empty(t, providers.LexicalCheck(sqlOutput))
+
+	info := providers.FormatSchemaInfo(tables)
+	assert.Contains(t, info, "Table: audited_users\nInherits: users (inherits records)\n")
+	assert.Contains(t, info, "  - id INTEGER NOT NULL (INHERITED)\n")
+}
+
+func TestFormatSchemaAsSQLDependencyOrder(t *testing.T) {
+	fk := func(name, column, table string) providers.ForeignKey {
+		return providers.ForeignKey{Name: name, Columns: []string{column}, ReferencedTable: table, ReferencedColumns: []string{"id"}}
+	}
+	idColumn := providers.Column{Name: "id", DataType: "integer", IsNullable: false, IsPrimaryKey: true}
+	refColumn := func(name string) providers.Column {
+		return providers.Column{Name: name, DataType: "integer", IsNullable: true}
+	}
+
+	t.Run("referenced_tables_first", func(t *testing.T) {
+		tables := []providers.Table{
+			{Name: "comments", Columns: []providers.Column{idColumn, refColumn("post_id")}, ForeignKeys: []providers.ForeignKey{fk("comments_post_id_fkey", "post_id", "posts")}},
+			{Name: "posts", Columns: []providers.Column{idColumn, refColumn("user_id")}, ForeignKeys: []providers.ForeignKey{fk("posts_user_id_fkey", "user_id", "users")}},
+			{Name: "tags", Columns: []providers.Column{idColumn}},
+			{Name: "users", Columns: []providers.Column{idColumn, refColumn("manager_id")}, ForeignKeys: []providers.ForeignKey{fk("users_manager_id_fkey", "manager_id", "users")}},
+		}
+
+		result := FormatSchemaAsSQL(tables)
+
+		tags := strings.Index(result, "create table tags")
+		users := strings.Index(result, "create table users")
+		posts := strings.Index(result, "create table posts")
+		comments := strings.Index(result, "create table comments")
+		assert.Less(t, tags, users, "independent tables keep alphabetical order within a level")
+		assert.Less(t, users, posts)
+		assert.Less(t, posts, comments)
+		assert.Contains(t, result, "constraint users_manager_id_fkey foreign key (manager_id) references users (id)", "self references stay inline")
+		assert.NotContains(t, result, "alter table")
+	})
+
+	t.Run("cycle_is_broken_with_alter_table", func(t *testing.T) {
+		tables := []providers.Table{
+			{Name: "departments", Columns: []providers.Column{idColumn, refColumn("head_id")}, ForeignKeys: []providers.ForeignKey{fk("departments_head_id_fkey", "head_id", "employees")}},
+			{Name: "employees", Columns: []providers.Column{idColumn, refColumn("department_id")}, ForeignKeys: []providers.ForeignKey{fk("employees_department_id_fkey", "department_id", "departments")}},
+		}
+
+		done := make(chan string, 1)
+		go func() { done <- FormatSchemaAsSQL(tables) }()
+
+		var result string
+		select {
+		case result = <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("formatting a cyclic schema did not terminate")
+		}
+
+		departments := strings.Index(result, "create table departments")
+		employees := strings.Index(result, "create table employees")
+		alter := strings.Index(result, "alter table departments add constraint departments_head_id_fkey foreign key (head_id) references employees (id);")
+		require.NotEqual(t, -1, alter)
+		assert.Less(t, departments, employees)
+		assert.Greater(t, alter, employees, "deferred foreign keys come after all tables")
+		assert.Equal(t, 1, strings.Count(result, "departments_head_id_fkey"))
+		assert.Contains(t, result, "constraint employees_department_id_fkey foreign key (department_id) references departments (id)")
+	})
+}
+
+func TestFormatSchemaAsSQLForeignKeyStyle(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name:    "posts",
+			Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}, {Name: "user_id", DataType: "integer"}},
+			ForeignKeys: []providers.ForeignKey{
+				{Name: "posts_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+			},
+		},
+		{
+			Name:    "users",
+			Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}},
+		},
+	}
+
+	inline := providers.FormatSchemaSQLWithOptions(tables, providers.FormatOptions{ForeignKeyStyle: providers.ForeignKeyInline})
+	assert.Equal(t, FormatSchemaAsSQL(tables), inline)
+	assert.Contains(t, inline, "    constraint posts_user_id_fkey foreign key (user_id) references users (id)\n);")
+
+	alter := providers.FormatSchemaSQLWithOptions(tables, providers.FormatOptions{ForeignKeyStyle: providers.ForeignKeyAlter})
+	assert.Contains(t, alter, "create table posts (\n    id integer not null,\n    user_id integer not null,\n    primary key (id)\n);")
+	alterAt := strings.Index(alter, "alter table posts add constraint posts_user_id_fkey foreign key (user_id) references users (id);")
+	require.NotEqual(t, -1, alterAt)
+	assert.Greater(t, alterAt, strings.Index(alter, "create table posts"))
+	assert.Greater(t, alterAt, strings.Index(alter, "create table users"))
+}
+
+func TestParseForeignKeyStyle(t *testing.T) {
+	style, err := providers.ParseForeignKeyStyle("alter")
+	require.NoError(t, err)
+	assert.Equal(t, providers.ForeignKeyAlter, style)
+
+	style, err = providers.ParseForeignKeyStyle("")
+	require.NoError(t, err)
+	assert.Equal(t, providers.ForeignKeyInline, style)
+
+	_, err = providers.ParseForeignKeyStyle("deferred")
+	assert.Error(t, err)
+}
+
+func TestParseColumnOrder(t *testing.T) {
+	order, err := providers.ParseColumnOrder("logical")
+	require.NoError(t, err)
+	assert.Equal(t, providers.ColumnOrderLogical, order)
+
+	order, err = providers.ParseColumnOrder("")
+	require.NoError(t, err)
+	assert.Equal(t, providers.ColumnOrderPhysical, order)
+
+	_, err = providers.ParseColumnOrder("alphabetical")
+	assert.Error(t, err)
+}
+
+func TestOrderColumns(t *testing.T) {
+	tables := []providers.Table{{
+		Name: "users",
+		Columns: []providers.Column{
+			{Name: "bio", DataType: "text", IsNullable: true, OrdinalPosition: 1},
+			{Name: "email", DataType: "text", OrdinalPosition: 2},
+			{Name: "id", DataType: "integer", IsPrimaryKey: true, OrdinalPosition: 4},
+			{Name: "nickname", DataType: "text", IsNullable: true, OrdinalPosition: 5},
+			{Name: "created_at", DataType: "timestamp with time zone", OrdinalPosition: 6},
+		},
+	}}
+
+	columnNames := func(tables []providers.Table) []string {
+		var names []string
+		for _, col := range tables[0].Columns {
+			names = append(names, col.Name)
+		}
+		return names
+	}
+
+	physical := providers.OrderColumns(tables, providers.ColumnOrderPhysical)
+	assert.Equal(t, []string{"bio", "email", "id", "nickname", "created_at"}, columnNames(physical))
+
+	logical := providers.OrderColumns(tables, providers.ColumnOrderLogical)
+	assert.Equal(t, []string{"id", "email", "created_at", "bio", "nickname"}, columnNames(logical))
+	assert.Equal(t, 4, logical[0].Columns[0].OrdinalPosition, "ordinal position should follow the column")
+
+	assert.Equal(t, []string{"bio", "email", "id", "nickname", "created_at"}, columnNames(tables), "input should not be modified")
+}
+
+func TestParseTableOrder(t *testing.T) {
+	for _, name := range []string{"name", "created", "dependency"} {
+		order, err := providers.ParseTableOrder(name)
+		require.NoError(t, err)
+		assert.Equal(t, providers.TableOrder(name), order)
+	}
+
+	order, err := providers.ParseTableOrder("")
+	require.NoError(t, err)
+	assert.Equal(t, providers.TableOrderName, order)
+
+	_, err = providers.ParseTableOrder("size")
+	assert.Error(t, err)
+}
+
+func TestParseRenameCase(t *testing.T) {
+	for _, name := range []string{"snake", "lower", "preserve"} {
+		renameCase, err := providers.ParseRenameCase(name)
+		require.NoError(t, err)
+		assert.Equal(t, providers.RenameCase(name), renameCase)
+	}
+
+	renameCase, err := providers.ParseRenameCase("")
+	require.NoError(t, err)
+	assert.Equal(t, providers.RenameCasePreserve, renameCase)
+
+	_, err = providers.ParseRenameCase("camel")
+	assert.Error(t, err)
+}
+
+func TestRenameTables(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name:    "UserAccounts",
+			Columns: []providers.Column{{Name: "ID", DataType: "integer", IsPrimaryKey: true}, {Name: "EmailAddress", DataType: "text"}},
+			Indexes: []providers.Index{{Name: "IX_UserAccounts_Email", Columns: []string{"EmailAddress"}}, {Name: "IX_Lower", Columns: []string{"lower(\"EmailAddress\")"}}},
+		},
+		{
+			Name:              "BlogPosts",
+			Columns:           []providers.Column{{Name: "ID", DataType: "integer", IsPrimaryKey: true}, {Name: "authorID", DataType: "integer"}},
+			UniqueConstraints: []providers.UniqueConstraint{{Name: "UQ_BlogPosts_Author", Columns: []string{"authorID"}}},
+			ForeignKeys: []providers.ForeignKey{{
+				Name: "FK_BlogPosts_Author", Columns: []string{"authorID"}, ReferencedTable: "UserAccounts", ReferencedColumns: []string{"ID"},
+			}},
+			ExclusionConstraints: []providers.ExclusionConstraint{{
+				Name: "EX_Author", Method: "gist", Elements: []providers.ExclusionElement{{Expression: "\"authorID\"", Operator: "="}},
+			}},
+		},
+	}
+
+	renamed, err := providers.RenameTables(tables, providers.RenameCaseSnake)
+	require.NoError(t, err)
+	assert.Equal(t, "user_accounts", renamed[0].Name)
+	assert.Equal(t, []string{"email_address"}, renamed[0].Indexes[0].Columns)
+	assert.Equal(t, "ix_user_accounts_email", renamed[0].Indexes[0].Name)
+	// Expressions are kept verbatim
+	assert.Equal(t, []string{"lower(\"EmailAddress\")"}, renamed[0].Indexes[1].Columns)
+
+	posts := renamed[1]
+	assert.Equal(t, "blog_posts", posts.Name)
+	assert.Equal(t, "author_id", posts.Columns[1].Name)
+	assert.Equal(t, []string{"author_id"}, posts.UniqueConstraints[0].Columns)
+	assert.Equal(t, "author_id", posts.ExclusionConstraints[0].Elements[0].Expression)
+	assert.Equal(t, providers.ForeignKey{
+		Name: "fk_blog_posts_author", Columns: []string{"author_id"}, ReferencedTable: "user_accounts", ReferencedColumns: []string{"id"},
+	}, posts.ForeignKeys[0])
+
+	// The input is not modified
+	assert.Equal(t, "UserAccounts", tables[0].Name)
+	assert.Equal(t, "authorID", tables[1].ForeignKeys[0].Columns[0])
+
+	sqlOutput := providers.FormatSchemaSQL(renamed)
+	assert.Contains(t, sqlOutput, "create table user_accounts (\n    id integer not null,\n    email_address text not null,\n")
+	assert.Contains(t, sqlOutput, "constraint fk_blog_posts_author foreign key (author_id) references user_accounts (id)")
+	assert.NotContains(t, sqlOutput, "\"UserAccounts\"")
+
+	lower, err := providers.RenameTables(tables, providers.RenameCaseLower)
+	require.NoError(t, err)
+	assert.Equal(t, "blogposts", lower[1].Name)
+	assert.Equal(t, "authorid", lower[1].ForeignKeys[0].Columns[0])
+
+	preserved, err := providers.RenameTables(tables, providers.RenameCasePreserve)
+	require.NoError(t, err)
+	assert.Equal(t, tables, preserved)
+
+	_, err = providers.RenameTables([]providers.Table{{Name: "userAccounts"}, {Name: "user_accounts"}}, providers.RenameCaseSnake)
+	assert.ErrorContains(t, err, "tables userAccounts and user_accounts are both renamed to user_accounts")
+}
+
+func TestRenameSchema(t *testing.T) {
+	schema := providers.Schema{
+		Tables: []providers.Table{{
+			Name:    "UserAccounts",
+			Columns: []providers.Column{{Name: "ID", DataType: "integer"}, {Name: "UpdatedAt", DataType: "timestamp"}},
+			Triggers: []providers.Trigger{{
+				Name:       "SetUpdated",
+				Table:      "UserAccounts",
+				Definition: `CREATE TRIGGER "SetUpdated" BEFORE UPDATE ON public."UserAccounts" FOR EACH ROW WHEN ((old."UpdatedAt" IS NOT NULL)) EXECUTE FUNCTION set_updated()`,
+			}},
+		}},
+		Views: []providers.View{
+			{Name: "ActiveUsers", Definition: ` SELECT "UserAccounts"."ID"
+   FROM "UserAccounts"
+  WHERE ("UserAccounts"."UpdatedAt" > '2024-01-01 "ID"'::timestamp without time zone);`},
+			{Name: "RecentUsers", Definition: ` SELECT "ActiveUsers"."ID"
+   FROM "ActiveUsers";`},
+		},
+	}
+
+	renamed, err := providers.RenameSchema(schema, providers.RenameCaseSnake)
+	require.NoError(t, err)
+	// Only quoted names of renamed objects change: the trigger and its
+	// function keep theirs
+	assert.Equal(t, `CREATE TRIGGER "SetUpdated" BEFORE UPDATE ON public.user_accounts FOR EACH ROW WHEN ((old.updated_at IS NOT NULL)) EXECUTE FUNCTION set_updated()`, renamed.Tables[0].Triggers[0].Definition)
+	assert.Equal(t, "user_accounts", renamed.Tables[0].Triggers[0].Table)
+
+	assert.Equal(t, "active_users", renamed.Views[0].Name)
+	// String literals are kept
+	assert.Equal(t, ` SELECT user_accounts.id
+   FROM user_accounts
+  WHERE (user_accounts.updated_at > '2024-01-01 "ID"'::timestamp without time zone);`, renamed.Views[0].Definition)
+	assert.Equal(t, ` SELECT active_users.id
+   FROM active_users;`, renamed.Views[1].Definition)
+
+	// The input is not modified
+	assert.Equal(t, "ActiveUsers", schema.Views[0].Name)
+	assert.Contains(t, schema.Tables[0].Triggers[0].Definition, `"UserAccounts"`)
+
+	_, err = providers.RenameSchema(providers.Schema{Views: []providers.View{{Name: "userAccounts"}, {Name: "UserAccounts"}}}, providers.RenameCaseSnake)
+	assert.ErrorContains(t, err, "views userAccounts and UserAccounts are both renamed to user_accounts")
+}
+
+func TestSnakeCaseNames(t *testing.T) {
+	tests := map[string]string{
+		"UserAccounts":  "user_accounts",
+		"userID":        "user_id",
+		"HTTPServer":    "http_server",
+		"already_snake": "already_snake",
+		"Order Items":   "order_items",
+		"Table2Name":    "table2_name",
+	}
+	for name, want := range tests {
+		renamed, err := providers.RenameTables([]providers.Table{{Name: name}}, providers.RenameCaseSnake)
+		require.NoError(t, err)
+		assert.Equal(t, want, renamed[0].Name, name)
+	}
+}
+
+func TestOrderTables(t *testing.T) {
+	fk := func(table string) []providers.ForeignKey {
+		return []providers.ForeignKey{{Name: "fk_" + table, Columns: []string{table + "_id"}, ReferencedTable: table, ReferencedColumns: []string{"id"}}}
+	}
+	tables := []providers.Table{
+		{Name: "comments", ObjectID: 16400, ForeignKeys: fk("posts")},
+		{Name: "accounts", ObjectID: 16420},
+		{Name: "posts", ObjectID: 16390, ForeignKeys: fk("users")},
+		{Name: "users", ObjectID: 16385},
+	}
+
+	tableNames := func(tables []providers.Table) []string {
+		var names []string
+		for _, table := range tables {
+			names = append(names, table.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"accounts", "comments", "posts", "users"}, tableNames(providers.OrderTables(tables, providers.TableOrderName)))
+	assert.Equal(t, []string{"users", "posts", "comments", "accounts"}, tableNames(providers.OrderTables(tables, providers.TableOrderCreated)))
+	assert.Equal(t, []string{"accounts", "users", "posts", "comments"}, tableNames(providers.OrderTables(tables, providers.TableOrderDependency)))
+	assert.Equal(t, "comments", tables[0].Name, "input should not be modified")
+
+	// SQL output always creates referenced tables first; the created order
+	// only decides between independent tables
+	created := providers.OrderTables(tables, providers.TableOrderCreated)
+	sqlOutput := providers.FormatSchemaSQLWithOptions(created, providers.FormatOptions{TableOrder: providers.TableOrderCreated})
+	assert.Less(t, strings.Index(sqlOutput, "create table users"), strings.Index(sqlOutput, "create table accounts"))
+	assert.Less(t, strings.Index(sqlOutput, "create table comments"), strings.Index(sqlOutput, "create table accounts"))
+	sqlOutput = providers.FormatSchemaSQLWithOptions(created, providers.FormatOptions{})
+	assert.Less(t, strings.Index(sqlOutput, "create table accounts"), strings.Index(sqlOutput, "create table users"))
+}
+
+func TestUnmappedTypes(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "places",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer"},
+				{Name: "address", DataType: "postal_address"},
+				{Name: "tags", DataType: "ARRAY"},
+			},
+		},
+		{
+			Name:    "users",
+			Columns: []providers.Column{{Name: "email", DataType: "character varying"}},
+		},
+	}
+
+	unmapped := providers.UnmappedTypes(tables, nil)
+	assert.Equal(t, []providers.UnmappedType{
+		{Table: "places", Column: "address", DataType: "postal_address"},
+		{Table: "places", Column: "tags", DataType: "ARRAY"},
+	}, unmapped)
+
+	assert.Contains(t, FormatSchemaAsSQL(tables), "address postal_address")
+}
+
+func TestFormatSchemaTypeMap(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", FullType: "integer", IsPrimaryKey: true},
+				{Name: "email", DataType: "character varying", FullType: "email", IsNullable: true},
+				{Name: "name", DataType: "citext", FullType: "citext"},
+				{Name: "address", DataType: "postal_address", FullType: "postal_address", IsNullable: true},
+				{Name: "bio", DataType: "character varying", FullType: "character varying(500)", CharacterLength: sql.NullInt64{Int64: 500, Valid: true}},
+				{Name: "aliases", DataType: "ARRAY", FullType: "citext[]"},
+			},
+		},
+	}
+	typeMap := providers.TypeMap{"email": "VARCHAR(320)", "citext": "TEXT", "postal_address": "JSONB"}
+
+	sqlOutput := providers.FormatSchemaSQLWithOptions(tables, providers.FormatOptions{TypeMap: typeMap})
+	assert.Contains(t, sqlOutput, "    email varchar(320),\n")
+	assert.Contains(t, sqlOutput, "    name text not null,\n")
+	assert.Contains(t, sqlOutput, "    address jsonb,\n")
+	assert.Contains(t, sqlOutput, "    bio varchar(500) not null,\n")
+	assert.Contains(t, sqlOutput, "    aliases text[] not null,\n", "arrays keep their brackets")
+
+	info := providers.FormatSchemaInfoWithOptions(tables, providers.InfoOptions{TypeMap: typeMap})
+	assert.Contains(t, info, "  - email VARCHAR(320) NULL\n")
+	assert.Contains(t, info, "  - name TEXT NOT NULL\n")
+	assert.Contains(t, info, "  - aliases TEXT[] NOT NULL\n")
+
+	// Without overrides, the domain renders as its base type
+	assert.Contains(t, providers.FormatSchemaSQL(tables), "    email varchar(255),\n")
+	assert.Contains(t, providers.FormatSchemaSQL(tables), "    name citext not null,\n")
+
+	assert.Len(t, providers.UnmappedTypes(tables, nil), 2)
+	assert.Empty(t, providers.UnmappedTypes(tables, typeMap))
+}
+
+func TestFormatSchemaExtensionTypes(t *testing.T) {
+	schema := providers.Schema{
+		Extensions: []string{"postgis", "vector"},
+		Tables: []providers.Table{
+			{
+				Name: "places",
+				Columns: []providers.Column{
+					{Name: "id", DataType: "integer", IsPrimaryKey: true},
+					{Name: "location", DataType: "geometry", FullType: "geometry(Point,4326)"},
+					{Name: "area", DataType: "geography", FullType: "geography", IsNullable: true},
+					{Name: "embedding", DataType: "vector", FullType: "vector(1536)", IsNullable: true},
+					{Name: "attrs", DataType: "hstore", FullType: "hstore", IsNullable: true},
+					{Name: "path", DataType: "ltree", FullType: "ltree", IsNullable: true},
+					{Name: "email", DataType: "citext", FullType: "citext", IsNullable: true},
+				},
+			},
+		},
+	}
+
+	sqlOutput := providers.FormatFullSchemaSQL(schema, providers.FormatOptions{})
+	assert.True(t, strings.HasPrefix(sqlOutput, "create extension if not exists postgis;\ncreate extension if not exists vector;\n\n"))
+	assert.Contains(t, sqlOutput, "    location geometry(point,4326) not null,\n")
+	assert.Contains(t, sqlOutput, "    area geography,\n")
+	assert.Contains(t, sqlOutput, "    embedding vector(1536),\n")
+	assert.Contains(t, sqlOutput, "    attrs hstore,\n")
+
+	info := providers.FormatFullSchemaInfo(schema)
+	assert.Contains(t, info, "  - location GEOMETRY(Point,4326) NOT NULL\n")
+	assert.Contains(t, info, "Extensions: postgis, vector\n")
+
+	assert.Empty(t, providers.UnmappedTypes(schema.Tables, nil))
+}
+
+func TestFormatSchemaNormalizeDefaults(t *testing.T) {
+	defaultOf := func(value string) sql.NullString {
+		return sql.NullString{String: value, Valid: true}
+	}
+	tables := []providers.Table{
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true, DefaultValue: defaultOf("nextval('users_id_seq'::regclass)")},
+				{Name: "shared_id", DataType: "integer", DefaultValue: defaultOf("nextval('global_seq'::regclass)")},
+				{Name: "score", DataType: "integer", DefaultValue: defaultOf("('1'::integer + 2)")},
+				{Name: "status", DataType: "character varying", CharacterLength: sql.NullInt64{Int64: 20, Valid: true}, DefaultValue: defaultOf("'active'::character varying")},
+				{Name: "settings", DataType: "jsonb", DefaultValue: defaultOf("'{}'::jsonb")},
+				{Name: "label", DataType: "text", DefaultValue: defaultOf("'it''s'::text")},
+				{Name: "created_at", DataType: "timestamp without time zone", DefaultValue: defaultOf("now()")},
+			},
+		},
+	}
+
+	raw := FormatSchemaAsSQL(tables)
+	assert.Contains(t, raw, "id integer not null default nextval('users_id_seq'::regclass)")
+	assert.Contains(t, raw, "status varchar(20) not null default 'active'::character varying")
+
+	normalized := providers.FormatSchemaSQLWithOptions(tables, providers.FormatOptions{NormalizeDefaults: true})
+	assert.Contains(t, normalized, "    id integer not null default nextval('users_id_seq'::regclass),\n", "serial columns are detected at extraction, see IsSerial")
+	assert.Contains(t, normalized, "    shared_id integer not null default nextval('global_seq'::regclass),\n")
+	assert.Contains(t, normalized, "    score integer not null default ('1'::integer + 2),\n", "casts inside expressions are kept")
+	assert.Contains(t, normalized, "    status varchar(20) not null default 'active',\n")
+	assert.Contains(t, normalized, "    settings jsonb not null default '{}',\n")
+	assert.Contains(t, normalized, "    label text not null default 'it''s',\n")
+	assert.Contains(t, normalized, "    created_at timestamp not null default now(),\n")
+
+	assert.Equal(t, "nextval('users_id_seq'::regclass)", tables[0].Columns[0].DefaultValue.String, "input tables must not be modified")
+}
+
+func TestFormatSchemaSerialColumns(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "events",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "bigint", IsPrimaryKey: true, IsSerial: true, DefaultValue: sql.NullString{String: "nextval('events_id_seq'::regclass)", Valid: true}},
+				{Name: "seq", DataType: "integer", DefaultValue: sql.NullString{String: "nextval('shared_seq'::regclass)", Valid: true}},
+			},
+		},
+	}
+
+	sqlOutput := FormatSchemaAsSQL(tables)
+	assert.Contains(t, sqlOutput, "    id bigserial not null,\n")
+	assert.Contains(t, sqlOutput, "    seq integer not null default nextval('shared_seq'::regclass),\n")
+
+	info := FormatSchema(tables)
+	assert.Contains(t, info, "  - id BIGSERIAL NOT NULL (PRIMARY KEY)\n")
+	assert.Contains(t, info, "  - seq INTEGER NOT NULL DEFAULT nextval('shared_seq'::regclass)\n")
+}
+
+func TestFormatSchemaDeterministicOrder(t *testing.T) {
+	table := func(reversed bool) providers.Table {
+		table := providers.Table{
+			Name: "posts",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "user_id", DataType: "integer"},
+				{Name: "editor_id", DataType: "integer"},
+				{Name: "slug", DataType: "text"},
+				{Name: "title", DataType: "text"},
+			},
+			Indexes: []providers.Index{
+				{Name: "idx_posts_title", Columns: []string{"title"}},
+				{Name: "idx_posts_editor_user", Columns: []string{"editor_id", "user_id"}},
+			},
+			UniqueConstraints: []providers.UniqueConstraint{
+				{Name: "uq_posts_title", Columns: []string{"title"}},
+				{Name: "uq_posts_slug", Columns: []string{"slug"}},
+			},
+			ForeignKeys: []providers.ForeignKey{
+				{Name: "fk_posts_user", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+				{Name: "fk_posts_editor", Columns: []string{"editor_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+			},
+			Triggers: []providers.Trigger{
+				{Name: "trg_posts_touch", Timing: "BEFORE", Events: []string{"UPDATE"}, FunctionName: "touch"},
+				{Name: "trg_posts_audit", Timing: "AFTER", Events: []string{"INSERT"}, FunctionName: "audit"},
+			},
+		}
+		if reversed {
+			slices.Reverse(table.Indexes)
+			slices.Reverse(table.UniqueConstraints)
+			slices.Reverse(table.ForeignKeys)
+			slices.Reverse(table.Triggers)
+		}
+		return table
+	}
+	users := providers.Table{Name: "users", Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}}}
+
+	formatters := map[string]func([]providers.Table) string{
+		"info":       providers.FormatSchemaInfo,
+		"sql":        providers.FormatSchemaSQL,
+		"typescript": providers.FormatSchemaTypeScript,
+		"prisma":     providers.FormatSchemaPrisma,
+		"sqlalchemy": providers.FormatSchemaSQLAlchemy,
+		"atlas":      providers.FormatSchemaAtlasHCL,
+	}
+	for name, format := range formatters {
+		t.Run(name, func(t *testing.T) {
+			reversed := []providers.Table{table(true), users}
+			assert.Equal(t, format([]providers.Table{table(false), users}), format(reversed))
+			assert.Equal(t, "idx_posts_title", reversed[0].Indexes[1].Name, "input should not be modified")
+		})
+	}
+
+	output := providers.FormatSchemaInfo([]providers.Table{table(false)})
+	assert.Less(t, strings.Index(output, "idx_posts_editor_user on (editor_id, user_id)"), strings.Index(output, "idx_posts_title"))
+	assert.Less(t, strings.Index(output, "fk_posts_editor"), strings.Index(output, "fk_posts_user"))
+	assert.Less(t, strings.Index(output, "trg_posts_audit"), strings.Index(output, "trg_posts_touch"))
+
+	diff := providers.DiffSchemas(nil, []providers.Table{table(true)})
+	assert.Equal(t, "idx_posts_editor_user", diff.AddedTables[0].Indexes[0].Name)
+}
+
+func TestFormatSchemaCollation(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "name", DataType: "text", Collation: "C"},
+				{Name: "city", DataType: "character varying", CharacterLength: sql.NullInt64{Int64: 80, Valid: true}, IsNullable: true, Collation: "en-US-x-icu"},
+				{Name: "bio", DataType: "text", IsNullable: true},
+			},
+		},
+	}
+
+	sqlOutput := FormatSchemaAsSQL(tables)
+	assert.Contains(t, sqlOutput, "    name text collate \"C\" not null,\n")
+	assert.Contains(t, sqlOutput, "    city varchar(80) collate \"en-US-x-icu\",\n")
+	assert.Contains(t, sqlOutput, "    bio text\n")
+
+	info := FormatSchema(tables)
+	assert.Contains(t, info, "  - name TEXT COLLATE \"C\" NOT NULL\n")
+	assert.Contains(t, info, "  - bio TEXT NULL\n")
+}
+
+func TestFormatSchemaStorageParams(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name:          "events",
+			Columns:       []providers.Column{{Name: "id", DataType: "integer"}},
+			StorageParams: map[string]string{"fillfactor": "70", "autovacuum_enabled": "false"},
+		},
+		{
+			Name:              "measurements",
+			Columns:           []providers.Column{{Name: "taken_on", DataType: "date"}},
+			PartitionStrategy: "range",
+			PartitionKey:      "taken_on",
+		},
+		{
+			Name:           "measurements_2024",
+			PartitionOf:    "measurements",
+			PartitionBound: "FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')",
+			StorageParams:  map[string]string{"fillfactor": "90"},
+		},
+	}
+
+	sqlOutput := FormatSchemaAsSQL(tables)
+	assert.Contains(t, sqlOutput, "create table events (\n    id integer not null\n) with (autovacuum_enabled=false, fillfactor=70);\n")
+	assert.Contains(t, sqlOutput, "create table measurements_2024 partition of measurements FOR VALUES FROM ('2024-01-01') TO ('2025-01-01') with (fillfactor=90);\n")
+	assert.Contains(t, sqlOutput, "create table measurements (\n    taken_on date not null\n) partition by range (taken_on);\n")
+
+	info := FormatSchema(tables)
+	assert.Contains(t, info, "Table: events\nStorage parameters: autovacuum_enabled=false, fillfactor=70\nColumns:\n")
+}
+
+func TestFormatSchemaDatetimePrecision(t *testing.T) {
+	precision := func(p int64) sql.NullInt64 { return sql.NullInt64{Int64: p, Valid: true} }
+	tables := []providers.Table{
+		{
+			Name: "events",
+			Columns: []providers.Column{
+				{Name: "created_at", DataType: "timestamp without time zone", DatetimePrecision: precision(0)},
+				{Name: "updated_at", DataType: "timestamp with time zone", DatetimePrecision: precision(6)},
+				{Name: "seen_at", DataType: "timestamp with time zone", DatetimePrecision: precision(3)},
+				{Name: "starts", DataType: "time without time zone", DatetimePrecision: precision(2)},
+				{Name: "ends", DataType: "time with time zone"},
+			},
+		},
+	}
+
+	sqlOutput := FormatSchemaAsSQL(tables)
+	assert.Contains(t, sqlOutput, "    created_at timestamp(0) not null,\n")
+	assert.Contains(t, sqlOutput, "    updated_at timestamptz not null,\n")
+	assert.Contains(t, sqlOutput, "    seen_at timestamptz(3) not null,\n")
+	assert.Contains(t, sqlOutput, "    starts time(2) not null,\n")
+	assert.Contains(t, sqlOutput, "    ends timetz not null\n")
+
+	info := FormatSchema(tables)
+	assert.Contains(t, info, "  - created_at TIMESTAMP(0) NOT NULL\n")
+	assert.Contains(t, info, "  - updated_at TIMESTAMPTZ NOT NULL\n")
+
+	atlas := providers.FormatSchemaAtlasHCL(tables)
+	assert.Contains(t, atlas, "type = timestamp(0)")
+	assert.Contains(t, atlas, "type = timestamptz\n")
+}
+
+func TestFormatSchemaIndexMethods(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "documents",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "tags", DataType: "jsonb"},
+				{Name: "created_at", DataType: "timestamp without time zone"},
+			},
+			Indexes: []providers.Index{
+				{Name: "idx_documents_tags", Columns: []string{"tags"}, Method: "gin"},
+				{Name: "idx_documents_created_at", Columns: []string{"created_at"}, Method: "brin"},
+				{Name: "idx_documents_id_tags", Columns: []string{"id"}},
+			},
+		},
+	}
+
+	sqlOutput := FormatSchemaAsSQL(tables)
+	assert.Contains(t, sqlOutput, "create index idx_documents_tags on documents using gin (tags);\n")
+	assert.Contains(t, sqlOutput, "create index idx_documents_created_at on documents using brin (created_at);\n")
+	assert.Contains(t, sqlOutput, "create index idx_documents_id_tags on documents (id);\n")
+
+	info := FormatSchema(tables)
+	assert.Contains(t, info, "  - idx_documents_tags on (tags) using gin\n")
+	assert.Contains(t, info, "  - idx_documents_id_tags on (id)\n")
+
+	assert.Contains(t, providers.FormatSchemaAtlasHCL(tables), "type    = GIN")
+	assert.Contains(t, providers.FormatSchemaPrisma(tables), "@@index([tags], map: \"idx_documents_tags\", type: Gin)")
+	assert.Contains(t, providers.FormatSchemaSQLAlchemy(tables), `Index("idx_documents_tags", "tags", postgresql_using="gin")`)
+
+	// Changing the method recreates the index
+	btree := []providers.Table{tables[0]}
+	btree[0].Indexes = []providers.Index{{Name: "idx_documents_tags", Columns: []string{"tags"}}}
+	migration := providers.FormatMigrationSQL(providers.DiffSchemas(btree, tables[:1]))
+	assert.Contains(t, migration, "drop index idx_documents_tags;\n")
+	assert.Contains(t, migration, "create index idx_documents_tags on documents using gin (tags);\n")
+}
+
+func TestFormatSchemaColumnSpacing(t *testing.T) {
+	tests := []struct {
+		name     string
+		column   providers.Column
+		expected string
+	}{
+		{"nullable", providers.Column{Name: "bio", DataType: "text", IsNullable: true}, "  - bio TEXT NULL\n"},
+		{"not_null", providers.Column{Name: "id", DataType: "integer"}, "  - id INTEGER NOT NULL\n"},
+		{"primary_key", providers.Column{Name: "id", DataType: "integer", IsPrimaryKey: true}, "  - id INTEGER NOT NULL (PRIMARY KEY)\n"},
+		{"default_and_primary_key", providers.Column{
+			Name: "code", DataType: "text", IsPrimaryKey: true, DefaultValue: sql.NullString{String: "'x'::text", Valid: true},
+		}, "  - code TEXT NOT NULL DEFAULT 'x'::text (PRIMARY KEY)\n"},
+		{"default_with_trailing_space", providers.Column{
+			Name: "status", DataType: "text", IsNullable: true, DefaultValue: sql.NullString{String: "'new'::text ", Valid: true},
+		}, "  - status TEXT NULL DEFAULT 'new'::text\n"},
+		{"serial", providers.Column{
+			Name: "id", DataType: "integer", IsSerial: true, IsPrimaryKey: true, DefaultValue: sql.NullString{String: "nextval('t_id_seq'::regclass)", Valid: true},
+		}, "  - id SERIAL NOT NULL (PRIMARY KEY)\n"},
+		{"collation", providers.Column{Name: "name", DataType: "text", Collation: "C", IsNullable: true}, "  - name TEXT COLLATE \"C\" NULL\n"},
+		{"inherited", providers.Column{Name: "created_at", DataType: "date", IsInherited: true}, "  - created_at DATE NOT NULL (INHERITED)\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := FormatSchema([]providers.Table{{Name: "t", Columns: []providers.Column{tt.column}}})
+			assert.Equal(t, "Table: t\nColumns:\n"+tt.expected+"\n", info)
+		})
+	}
+}
+
+func TestFormatSchemaInfoColor(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "users",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "bio", DataType: "text", IsNullable: true},
+			},
+		},
+	}
+
+	plain := providers.FormatSchemaInfoWithOptions(tables, providers.InfoOptions{})
+	assert.NotContains(t, plain, "\x1b[")
+
+	colored := providers.FormatSchemaInfoWithOptions(tables, providers.InfoOptions{Color: true})
+	assert.Contains(t, colored, "Table: \x1b[1musers\x1b[0m\n")
+	assert.Contains(t, colored, "  - \x1b[1m\x1b[33mid\x1b[0m INTEGER NOT NULL (PRIMARY KEY)\n")
+	assert.Contains(t, colored, "  - bio TEXT \x1b[2mNULL\x1b[0m\n")
+
+	// Without the escape codes the text is the plain output
+	assert.Equal(t, plain, regexp.MustCompile("\x1b\\[[0-9;]*m").ReplaceAllString(colored, ""))
+}
+
+func TestFormatSchemaExclusionConstraints(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "bookings",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "room_id", DataType: "integer"},
+				{Name: "during", DataType: "tstzrange"},
+			},
+			ExclusionConstraints: []providers.ExclusionConstraint{
+				{
+					Name:   "bookings_no_overlap",
+					Method: "gist",
+					Elements: []providers.ExclusionElement{
+						{Expression: "room_id", Operator: "="},
+						{Expression: "during", Operator: "&&"},
+					},
+					Where: "NOT cancelled",
+				},
+			},
+		},
+	}
+
+	sqlOutput := FormatSchemaAsSQL(tables)
+	assert.Contains(t, sqlOutput, "    primary key (id),\n    constraint bookings_no_overlap exclude using gist (room_id with =, during with &&) where (NOT cancelled)\n);\n")
+
+	info := FormatSchema(tables)
+	assert.Contains(t, info, "Constraints:\n  - bookings_no_overlap EXCLUDE USING gist (room_id WITH =, during WITH &&) WHERE (NOT cancelled)\n")
+
+	// Changing the operators replaces the constraint
+	before := []providers.Table{tables[0]}
+	before[0].ExclusionConstraints = []providers.ExclusionConstraint{{
+		Name:     "bookings_no_overlap",
+		Method:   "gist",
+		Elements: []providers.ExclusionElement{{Expression: "during", Operator: "&&"}},
+	}}
+	diff := providers.DiffSchemas(before, tables)
+	migration := providers.FormatMigrationSQL(diff)
+	assert.Contains(t, migration, "alter table bookings drop constraint bookings_no_overlap;\n")
+	assert.Contains(t, migration, "alter table bookings add constraint bookings_no_overlap exclude using gist (room_id with =, during with &&) where (NOT cancelled);\n")
+	assert.Contains(t, providers.FormatDiffReport(diff), "    + exclusion constraint bookings_no_overlap exclude using gist (room_id with =, during with &&) where (NOT cancelled)\n")
+
+	filtered, err := providers.NewDiffFilter(nil, []string{"constraints"})
+	require.NoError(t, err)
+	assert.True(t, filtered.Apply(diff).IsEmpty())
+}
+
+func TestFormatSchemaJSON(t *testing.T) {
+	output, err := providers.FormatSchemaJSON(providers.Schema{})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"tables": []}`, output)
+
+	output, err = providers.FormatSchemaJSON(providers.Schema{
+		Enums: []providers.Enum{{Name: "mood", Values: []string{"happy", "sad"}}},
+		Tables: []providers.Table{{
+			Name:    "users",
+			Columns: []providers.Column{{Name: "mood", DataType: "USER-DEFINED", FullType: "mood", IsNullable: true}},
+			Indexes: []providers.Index{{Name: "b_idx", Columns: []string{"mood"}}, {Name: "a_idx", Columns: []string{"mood"}}},
+		}},
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(output, "}\n"))
+	assert.Less(t, strings.Index(output, `"a_idx"`), strings.Index(output, `"b_idx"`), "indexes are sorted by name")
+
+	tables, err := parseTargetTables([]byte(output))
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	assert.Equal(t, "users", tables[0].Name)
+}
+
+func TestFormatSchemaJSONCompact(t *testing.T) {
+	schema := providers.Schema{
+		ServerVersion: 160004,
+		Extensions:    []string{"citext"},
+		Enums:         []providers.Enum{{Name: "mood", Values: []string{"happy", "<sad>"}}},
+		Tables: []providers.Table{
+			{Name: "users", Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}}},
+			{
+				Name:        "posts",
+				Columns:     []providers.Column{{Name: "user_id", DataType: "integer", DefaultValue: sql.NullString{String: "0", Valid: true}}},
+				ForeignKeys: []providers.ForeignKey{{Name: "posts_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}}},
+			},
+		},
+		Views: []providers.View{{Name: "user_ids", Definition: " SELECT id\n   FROM users"}},
+	}
+
+	indented, err := providers.FormatSchemaJSON(schema)
+	require.NoError(t, err)
+	compact, err := providers.FormatSchemaJSONWithOptions(schema, providers.JSONOptions{Compact: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, strings.Count(compact, "\n"), "compact JSON is a single line")
+	assert.NotContains(t, compact, `"on_delete"`, "default referential actions are omitted")
+	assert.Greater(t, len(indented), len(compact))
+
+	var fromIndented, fromCompact any
+	require.NoError(t, json.Unmarshal([]byte(indented), &fromIndented))
+	require.NoError(t, json.Unmarshal([]byte(compact), &fromCompact))
+	assert.Equal(t, fromIndented, fromCompact)
+
+	// Streaming writes what encoding the whole document would
+	whole, err := json.Marshal(schema)
+	require.NoError(t, err)
+	assert.Equal(t, string(whole)+"\n", compact)
+	var reindented bytes.Buffer
+	require.NoError(t, json.Indent(&reindented, []byte(strings.TrimSuffix(compact, "\n")), "", "  "))
+	assert.Equal(t, reindented.String()+"\n", indented)
+
+	var streamed bytes.Buffer
+	require.NoError(t, providers.WriteSchemaJSON(&streamed, schema, providers.JSONOptions{}))
+	assert.Equal(t, indented, streamed.String())
+
+	empty, err := providers.FormatSchemaJSONWithOptions(providers.Schema{}, providers.JSONOptions{Compact: true})
+	require.NoError(t, err)
+	assert.Equal(t, "{\"tables\":[]}\n", empty)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Numbering schemes understood by checkMigrationSequence
+const (
+	// sequenceAuto uses timestamps when every prefix has 14 digits and
+	// integers otherwise
+	sequenceAuto = "auto"
+	// sequenceInteger expects contiguous integers such as 001, 002, 003
+	sequenceInteger = "integer"
+	// sequenceTimestamp expects YYYYMMDDHHMMSS prefixes, which may have gaps
+	sequenceTimestamp = "timestamp"
+)
+
+// timestampVersionLayout is the layout of a timestamp migration version
+const timestampVersionLayout = "20060102150405"
+
+// Kinds of sequenceIssue
+const (
+	sequenceIssueMissingVersion = "missing_version"
+	sequenceIssueInvalidVersion = "invalid_version"
+	sequenceIssueDuplicate      = "duplicate"
+	sequenceIssueOutOfOrder     = "out_of_order"
+	sequenceIssueGap            = "gap"
+)
+
+// sequenceIssue describes a problem with the version numbering of a migration
+type sequenceIssue struct {
+	Kind      string `json:"kind"`
+	Migration string `json:"migration"`
+	Message   string `json:"message"`
+}
+
+// migrationVersion returns the leading digits of a migration name
+func migrationVersion(name string) string {
+	end := 0
+	for end < len(name) && name[end] >= '0' && name[end] <= '9' {
+		end++
+	}
+	return name[:end]
+}
+
+// checkMigrationSequence checks that migration versions are unique and
+// strictly increasing in execution order. With the integer scheme versions
+// must also be contiguous. Migrations are expected in execution order.
+func checkMigrationSequence(migrations []Migration, scheme string) ([]sequenceIssue, error) {
+	if scheme == "" {
+		scheme = sequenceAuto
+	}
+	if scheme == sequenceAuto {
+		scheme = detectSequenceScheme(migrations)
+	}
+	if scheme != sequenceInteger && scheme != sequenceTimestamp {
+		return nil, fmt.Errorf("unsupported sequence scheme: %s (expected %s, %s or %s)", scheme, sequenceAuto, sequenceInteger, sequenceTimestamp)
+	}
+
+	issues := []sequenceIssue{}
+	var previous *Migration
+	var previousVersion int64
+
+	for i := range migrations {
+		migration := &migrations[i]
+		version := migrationVersion(migration.Name)
+		if version == "" {
+			issues = append(issues, sequenceIssue{
+				Kind:      sequenceIssueMissingVersion,
+				Migration: migration.Name,
+				Message:   fmt.Sprintf("%s has no numeric version prefix", migration.Name),
+			})
+			continue
+		}
+
+		value, err := parseMigrationVersion(version, scheme)
+		if err != nil {
+			issues = append(issues, sequenceIssue{
+				Kind:      sequenceIssueInvalidVersion,
+				Migration: migration.Name,
+				Message:   fmt.Sprintf("%s: %v", migration.Name, err),
+			})
+			continue
+		}
+
+		if previous != nil {
+			switch {
+			case value == previousVersion:
+				issues = append(issues, sequenceIssue{
+					Kind:      sequenceIssueDuplicate,
+					Migration: migration.Name,
+					Message:   fmt.Sprintf("%s has the same version as %s", migration.Name, previous.Name),
+				})
+			case value < previousVersion:
+				issues = append(issues, sequenceIssue{
+					Kind:      sequenceIssueOutOfOrder,
+					Migration: migration.Name,
+					Message:   fmt.Sprintf("%s runs after %s but has a lower version", migration.Name, previous.Name),
+				})
+			case scheme == sequenceInteger && value > previousVersion+1:
+				issues = append(issues, sequenceIssue{
+					Kind:      sequenceIssueGap,
+					Migration: migration.Name,
+					Message:   fmt.Sprintf("%s follows %s, %s", migration.Name, previous.Name, missingVersions(previousVersion, value)),
+				})
+			}
+		}
+
+		if previous == nil || value > previousVersion {
+			previousVersion = value
+		}
+		previous = migration
+	}
+
+	return issues, nil
+}
+
+// detectSequenceScheme picks the timestamp scheme when every version prefix
+// is 14 digits long
+func detectSequenceScheme(migrations []Migration) string {
+	if len(migrations) == 0 {
+		return sequenceInteger
+	}
+	for _, migration := range migrations {
+		if len(migrationVersion(migration.Name)) != len(timestampVersionLayout) {
+			return sequenceInteger
+		}
+	}
+	return sequenceTimestamp
+}
+
+// parseMigrationVersion converts a version prefix to a comparable number
+func parseMigrationVersion(version, scheme string) (int64, error) {
+	if scheme == sequenceTimestamp {
+		if _, err := time.Parse(timestampVersionLayout, version); err != nil {
+			return 0, fmt.Errorf("version %s is not a YYYYMMDDHHMMSS timestamp", version)
+		}
+	}
+	value, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("version %s is not a valid number", version)
+	}
+	return value, nil
+}
+
+// missingVersions describes the integers strictly between from and to
+func missingVersions(from, to int64) string {
+	if to-from == 2 {
+		return fmt.Sprintf("version %d is missing", from+1)
+	}
+	return fmt.Sprintf("versions %d-%d are missing", from+1, to-1)
+}
+
+// runSequenceCheck discovers the migrations in migrationDir and writes every
+// numbering issue to w. It reports whether the sequence is clean.
+func runSequenceCheck(migrationDir string, migrationReader MigrationReader, scheme string, w io.Writer) (bool, error) {
+	if migrationDirMissing(migrationDir) {
+		return false, withExitCode(exitMigrationDirNotFound, fmt.Errorf("migration directory does not exist: %s", migrationDir))
+	}
+
+	migrations, err := migrationReader.DiscoverMigrations(migrationDir)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse migrations: %w", err)
+	}
+
+	issues, err := checkMigrationSequence(migrations, scheme)
+	if err != nil {
+		return false, err
+	}
+
+	for _, issue := range issues {
+		fmt.Fprintf(w, "%s: %s\n", issue.Kind, issue.Message)
+	}
+	if len(issues) > 0 {
+		fmt.Fprintf(w, "sequence check found %d issue(s) in %d migration(s)\n", len(issues), len(migrations))
+	}
+
+	return len(issues) == 0, nil
+}
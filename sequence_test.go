@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func migrationsNamed(names ...string) []Migration {
+	migrations := make([]Migration, len(names))
+	for i, name := range names {
+		migrations[i] = Migration{Name: name}
+	}
+	return migrations
+}
+
+func TestCheckMigrationSequence(t *testing.T) {
+	tests := []struct {
+		name     string
+		names    []string
+		scheme   string
+		expected []string
+	}{
+		{"contiguous_integers", []string{"001_users", "002_posts", "003_tags"}, sequenceAuto, nil},
+		{"gap", []string{"001_users", "002_posts", "005_tags"}, sequenceAuto, []string{sequenceIssueGap}},
+		{"duplicate", []string{"001_users", "002_posts", "002_tags"}, sequenceAuto, []string{sequenceIssueDuplicate}},
+		{"out_of_order", []string{"10_tags", "8_users", "9_posts"}, sequenceAuto, []string{sequenceIssueOutOfOrder, sequenceIssueOutOfOrder}},
+		{"missing_version", []string{"001_users", "init"}, sequenceInteger, []string{sequenceIssueMissingVersion}},
+		{"timestamps_allow_gaps", []string{"20240115120000_users", "20240220143000_posts"}, sequenceAuto, nil},
+		{"timestamp_duplicate", []string{"20240115120000_users", "20240115120000_posts"}, sequenceTimestamp, []string{sequenceIssueDuplicate}},
+		{"invalid_timestamp", []string{"20241315120000_users"}, sequenceTimestamp, []string{sequenceIssueInvalidVersion}},
+		{"timestamps_as_integers", []string{"20240115120000_users", "20240115120002_posts"}, sequenceInteger, []string{sequenceIssueGap}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := checkMigrationSequence(migrationsNamed(tt.names...), tt.scheme)
+			require.NoError(t, err)
+
+			var kinds []string
+			for _, issue := range issues {
+				kinds = append(kinds, issue.Kind)
+			}
+			assert.Equal(t, tt.expected, kinds)
+		})
+	}
+
+	t.Run("gap_message", func(t *testing.T) {
+		issues, err := checkMigrationSequence(migrationsNamed("001_users", "005_tags"), sequenceInteger)
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		assert.Equal(t, "005_tags follows 001_users, versions 2-4 are missing", issues[0].Message)
+	})
+
+	t.Run("unsupported_scheme", func(t *testing.T) {
+		_, err := checkMigrationSequence(migrationsNamed("001_users"), "semver")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported sequence scheme")
+	})
+}
+
+func TestRunSequenceCheck(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"001_users.up.sql", "002_posts.up.sql", "004_tags.up.sql"} {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte("select 1;"), 0644))
+	}
+
+	var out bytes.Buffer
+	passed, err := runSequenceCheck(tempDir, NewFileMigrationReader(), sequenceAuto, &out)
+	require.NoError(t, err)
+	assert.False(t, passed)
+	assert.Contains(t, out.String(), "gap: 004_tags follows 002_posts, version 3 is missing")
+	assert.Contains(t, out.String(), "sequence check found 1 issue(s) in 3 migration(s)")
+
+	_, err = runSequenceCheck("/non/existent/directory", NewFileMigrationReader(), sequenceAuto, &out)
+	assert.Error(t, err)
+}
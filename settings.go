@@ -0,0 +1,63 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// settingNamePattern matches a configuration parameter name, including
+// custom ones qualified with a prefix such as app.tenant
+var settingNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// DBSetting is a session setting applied before migrations run, given with
+// --db-setting name=value
+type DBSetting struct {
+	Name  string
+	Value string
+}
+
+// parseDBSetting parses a name=value setting such as search_path=app,public.
+// The name is only checked for its shape; PostgreSQL rejects unknown
+// parameters when the setting is applied.
+func parseDBSetting(s string) (DBSetting, error) {
+	name, value, ok := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	if !ok || !settingNamePattern.MatchString(name) {
+		return DBSetting{}, fmt.Errorf("invalid database setting %q, expected name=value", s)
+	}
+	return DBSetting{Name: name, Value: strings.TrimSpace(value)}, nil
+}
+
+// parseDBSettings parses every --db-setting flag, keeping their order
+func parseDBSettings(values []string) ([]DBSetting, error) {
+	settings := make([]DBSetting, 0, len(values))
+	for _, value := range values {
+		setting, err := parseDBSetting(value)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, setting)
+	}
+	return settings, nil
+}
+
+// applyDBSettings sets each setting for the session, in order. Settings are
+// session state, so the pool is limited to the one connection that holds
+// them; extraction queries run one after another anyway.
+func applyDBSettings(db *sql.DB, settings []DBSetting) error {
+	if len(settings) == 0 {
+		return nil
+	}
+
+	db.SetMaxOpenConns(1)
+	for _, setting := range settings {
+		slog.Debug("applying database setting", "name", setting.Name, "value", setting.Value)
+		if _, err := db.Exec("SELECT set_config($1, $2, false)", setting.Name, setting.Value); err != nil {
+			return fmt.Errorf("failed to apply database setting %s: %w", setting.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDBSetting(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected DBSetting
+		wantErr  bool
+	}{
+		{input: "search_path=app,public", expected: DBSetting{Name: "search_path", Value: "app,public"}},
+		{input: " timezone = UTC ", expected: DBSetting{Name: "timezone", Value: "UTC"}},
+		{input: "statement_timeout=5s", expected: DBSetting{Name: "statement_timeout", Value: "5s"}},
+		{input: "app.tenant=acme", expected: DBSetting{Name: "app.tenant", Value: "acme"}},
+		{input: "search_path=", expected: DBSetting{Name: "search_path", Value: ""}},
+		{input: "search_path", wantErr: true},
+		{input: "=app", wantErr: true},
+		{input: "search path=app", wantErr: true},
+		{input: "search_path; drop table users=app", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			setting, err := parseDBSetting(tt.input)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "expected name=value")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, setting)
+		})
+	}
+
+	settings, err := parseDBSettings([]string{"search_path=app", "timezone=UTC"})
+	require.NoError(t, err)
+	assert.Equal(t, []DBSetting{{Name: "search_path", Value: "app"}, {Name: "timezone", Value: "UTC"}}, settings)
+
+	_, err = parseDBSettings([]string{"search_path=app", "bogus"})
+	assert.Error(t, err)
+}
+
+func TestMigrationToSchemaDBSettings(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping database settings test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	manager := NewPostgreSQLManager("postgres:16-alpine", WithSettings([]DBSetting{
+		{Name: "timezone", Value: "Europe/Paris"},
+		{Name: "search_path", Value: "public"},
+	}))
+	require.NoError(t, manager.Setup(ctx))
+	defer manager.Close(ctx)
+
+	migrations := []Migration{{
+		Name: "001_create_events",
+		UpSQL: []byte(`do $$ begin if current_setting('timezone') <> 'Europe/Paris' then raise 'timezone not set'; end if; end $$;
+create table events (id int primary key);`),
+	}}
+	require.NoError(t, manager.RunMigrations(ctx, migrations))
+
+	tables, err := providers.ExtractSchemaFromDB(manager.GetDB())
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+
+	failing := NewPostgreSQLManager("postgres:16-alpine", WithSettings([]DBSetting{{Name: "no_such_setting", Value: "1"}}))
+	err = failing.Setup(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to apply database setting no_such_setting")
+}
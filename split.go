@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alc6/mig2schema/providers"
+)
+
+// writeSplitResult writes an extraction result to --split-output, one file
+// per table
+func writeSplitResult(params providers.ExtractParams, result *providers.SchemaResult, providerName string) error {
+	if len(result.Tables) == 0 && result.RawSQL != "" {
+		return withExitCode(exitUsage, fmt.Errorf("--split-output needs table details, which the %s provider does not return", providerName))
+	}
+	return writeSplitOutput(splitOutput, providers.SplitSchemaSQL(result.Schema, params.FormatOptions), splitClean)
+}
+
+// checkSplitClean refuses --split-clean when the split output directory is
+// the migration directory or one of its parents, where removing .sql files
+// would delete migrations
+func checkSplitClean(splitDir, migrationDir string) error {
+	split, err := filepath.Abs(splitDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve split output directory: %w", err)
+	}
+	migrations, err := filepath.Abs(migrationDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve migration directory: %w", err)
+	}
+	rel, err := filepath.Rel(split, migrations)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil
+	}
+	return withExitCode(exitUsage, fmt.Errorf("--split-clean cannot be used when --split-output contains the migration directory %s", migrationDir))
+}
+
+// writeSplitOutput writes the files of a split schema to dir, creating it
+// when missing. With clean, other .sql files in dir are removed, so that
+// tables dropped by later migrations do not leave stale files behind. Other
+// files are never touched.
+func writeSplitOutput(dir string, files []providers.SchemaFile, clean bool) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create split output directory: %w", err)
+	}
+
+	written := make(map[string]bool, len(files))
+	for _, file := range files {
+		if err := os.WriteFile(filepath.Join(dir, file.Name), []byte(file.Content), 0644); err != nil {
+			return fmt.Errorf("failed to write split output: %w", err)
+		}
+		written[file.Name] = true
+	}
+
+	if clean {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("failed to read split output directory: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || written[entry.Name()] || !strings.HasSuffix(entry.Name(), ".sql") {
+				continue
+			}
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+				return fmt.Errorf("failed to remove stale file: %w", err)
+			}
+			slog.Info("removed stale file", "file", entry.Name())
+		}
+	}
+
+	slog.Info("wrote split schema", "directory", dir, "files", len(files))
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSplitOutput(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "schema")
+	files := []providers.SchemaFile{
+		{Name: providers.SchemaIndexFile, Content: "\\ir users.sql\n"},
+		{Name: "users.sql", Content: "create table users (\n    id integer not null\n);\n"},
+	}
+
+	// The directory is created when missing
+	require.NoError(t, writeSplitOutput(dir, files, false))
+	content, err := os.ReadFile(filepath.Join(dir, "users.sql"))
+	require.NoError(t, err)
+	assert.Equal(t, files[1].Content, string(content))
+
+	writeMigrationTree(t, dir, map[string]string{
+		"posts.sql": "create table posts (id integer);\n",
+		"README.md": "generated by mig2schema",
+	})
+
+	t.Run("keeps_stale_files", func(t *testing.T) {
+		require.NoError(t, writeSplitOutput(dir, files, false))
+		assert.FileExists(t, filepath.Join(dir, "posts.sql"))
+	})
+
+	t.Run("clean_removes_stale_sql_files", func(t *testing.T) {
+		require.NoError(t, writeSplitOutput(dir, files, true))
+		assert.NoFileExists(t, filepath.Join(dir, "posts.sql"))
+		assert.FileExists(t, filepath.Join(dir, "README.md"))
+		assert.FileExists(t, filepath.Join(dir, providers.SchemaIndexFile))
+		assert.FileExists(t, filepath.Join(dir, "users.sql"))
+	})
+}
+
+func TestCheckSplitClean(t *testing.T) {
+	root := t.TempDir()
+	migrations := filepath.Join(root, "db", "migrations")
+
+	tests := []struct {
+		name     string
+		splitDir string
+		wantErr  bool
+	}{
+		{"same_directory", migrations, true},
+		{"parent_directory", root, true},
+		{"relative_path", filepath.Join(root, "db", ".", "migrations", ".."), true},
+		{"sibling_directory", filepath.Join(root, "db", "schema"), false},
+		{"child_directory", filepath.Join(migrations, "schema"), false},
+		{"name_prefix", migrations + "-schema", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSplitClean(tt.splitDir, migrations)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "--split-clean cannot be used when --split-output contains the migration directory")
+			assert.Equal(t, exitUsage, exitCode(err))
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSchemaSQLAlchemy(t *testing.T) {
+	output := providers.FormatSchemaSQLAlchemy(ormTestTables())
+
+	assert.True(t, strings.HasPrefix(output, `from sqlalchemy import Column, DateTime, ForeignKey, Index, Integer, String, Table, Text, text
+from sqlalchemy.dialects.postgresql import ARRAY, UUID
+from sqlalchemy.orm import declarative_base, relationship
+from sqlalchemy.types import NullType
+`), output)
+	// Top-level definitions are separated by two blank lines, as in PEP 8
+	assert.Contains(t, output, "\n\n\nclass Users(Base):\n    __tablename__ = \"users\"\n")
+	assert.Contains(t, output, "\n\n\nclass Posts(Base):\n")
+
+	for _, line := range []string{
+		`    id = Column(Integer, primary_key=True)`,
+		`    email = Column(String(320), nullable=False, unique=True)`,
+		`    status = Column(Text, nullable=False, server_default=text("'active'::text"))`,
+		`    created_at = Column(DateTime(timezone=True), nullable=False, server_default=text("now()"))`,
+		`    posts = relationship("Posts", back_populates="user")`,
+		`        Index("idx_posts_user_id", "user_id"),`,
+		`    id = Column(UUID, primary_key=True, server_default=text("gen_random_uuid()"))`,
+		`    user_id = Column(Integer, ForeignKey("users.id", ondelete="CASCADE", name="posts_user_id_fkey"), nullable=False)`,
+		`    tags = Column(ARRAY(Text))`,
+		`    location = Column(NullType())  # geometry(Point,4326)`,
+		`    user = relationship("Users", back_populates="posts")`,
+		`    Column("message", Text, nullable=False),`,
+	} {
+		assert.Contains(t, output, line+"\n")
+	}
+	// Tables without a primary key cannot be mapped to a class, and views
+	// are left out
+	assert.Contains(t, output, "# audit_log has no primary key, which the ORM requires to map it\naudit_log = Table(\n")
+	assert.NotContains(t, output, "active_users")
+}
+
+func TestFormatSchemaSQLAlchemyColumns(t *testing.T) {
+	tables := []providers.Table{{
+		Name: "employees",
+		Columns: []providers.Column{
+			{Name: "id", DataType: "integer", FullType: "integer", IsPrimaryKey: true},
+			{Name: "class", DataType: "jsonb", FullType: "jsonb", IsNullable: true},
+			{Name: "salary", DataType: "numeric", FullType: "numeric(12,2)", IsNullable: true,
+				NumericPrecision: sql.NullInt64{Int64: 12, Valid: true}, NumericScale: sql.NullInt64{Int64: 2, Valid: true}},
+			{Name: "manager_id", DataType: "integer", FullType: "integer", IsNullable: true},
+		},
+		ForeignKeys: []providers.ForeignKey{
+			{Name: "employees_manager_id_fkey", Columns: []string{"manager_id"}, ReferencedTable: "employees", ReferencedColumns: []string{"id"}},
+		},
+	}}
+
+	output := providers.FormatSchemaSQLAlchemy(tables)
+	// Python keywords get a trailing underscore and keep the column name
+	assert.Contains(t, output, `    class_ = Column("class", JSONB)`+"\n")
+	assert.Contains(t, output, `    salary = Column(Numeric(12, 2))`+"\n")
+	// A self-reference gets both sides, the parent one with remote_side
+	assert.Contains(t, output, `    manager = relationship("Employees", back_populates="employees", foreign_keys="[Employees.manager_id]", remote_side="[Employees.id]")`+"\n")
+	assert.Contains(t, output, `    employees = relationship("Employees", back_populates="manager", foreign_keys="[Employees.manager_id]")`+"\n")
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/lib/pq"
+)
+
+// noTransactionPatterns match statements that PostgreSQL refuses to run inside
+// a transaction block. ALTER TYPE ... ADD VALUE is only restricted before
+// PostgreSQL 12 but running it on its own is harmless on newer servers.
+var noTransactionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^CREATE (UNIQUE )?INDEX CONCURRENTLY\b`),
+	regexp.MustCompile(`^DROP INDEX CONCURRENTLY\b`),
+	regexp.MustCompile(`^REINDEX\b.*\bCONCURRENTLY\b`),
+	regexp.MustCompile(`^ALTER TABLE\b.*\bDETACH PARTITION\b.*\bCONCURRENTLY\b`),
+	regexp.MustCompile(`^ALTER TYPE\b.*\bADD VALUE\b`),
+	regexp.MustCompile(`^VACUUM\b`),
+	regexp.MustCompile(`^(CREATE|DROP) DATABASE\b`),
+	regexp.MustCompile(`^(CREATE|DROP) TABLESPACE\b`),
+	regexp.MustCompile(`^(CREATE|DROP) SUBSCRIPTION\b`),
+	regexp.MustCompile(`^ALTER SYSTEM\b`),
+}
+
+// Statement categories reported by classifyStatement
+const (
+	statementCreateTable = "create_table"
+	statementAlterTable  = "alter_table"
+	statementCreateIndex = "create_index"
+	statementDrop        = "drop"
+	statementOther       = "other"
+)
+
+var (
+	createTablePattern = regexp.MustCompile(`^CREATE ((GLOBAL|LOCAL) )?((TEMP|TEMPORARY|UNLOGGED) )?TABLE\b`)
+	alterTablePattern  = regexp.MustCompile(`^ALTER TABLE\b`)
+	createIndexPattern = regexp.MustCompile(`^CREATE (UNIQUE )?INDEX\b`)
+	dropPattern        = regexp.MustCompile(`^DROP\b`)
+)
+
+// destructivePatterns match statements that remove objects or data. An
+// ALTER TABLE is destructive when it drops a column or constraint, spelled
+// out or as the DROP name shorthand, which is the first action or follows a
+// comma. DROP DEFAULT, DROP NOT NULL and the like follow ALTER COLUMN instead.
+var destructivePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^DROP\b`),
+	regexp.MustCompile(`^TRUNCATE\b`),
+	regexp.MustCompile(`^ALTER TABLE\b.*\bDROP (COLUMN|CONSTRAINT)\b`),
+	regexp.MustCompile(`^ALTER TABLE (IF EXISTS )?(ONLY )?[^ ]+ DROP\b`),
+	regexp.MustCompile(`^ALTER TABLE\b.*, DROP\b`),
+}
+
+// normalizeStatement strips comments, collapses whitespace and upper-cases a
+// statement so that it can be matched against keyword patterns
+func normalizeStatement(sql string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(providers.StripComments(sql)), " "))
+}
+
+// classifyStatement returns the category of a single SQL statement
+func classifyStatement(sql string) string {
+	normalized := normalizeStatement(sql)
+	switch {
+	case createTablePattern.MatchString(normalized):
+		return statementCreateTable
+	case alterTablePattern.MatchString(normalized):
+		return statementAlterTable
+	case createIndexPattern.MatchString(normalized):
+		return statementCreateIndex
+	case dropPattern.MatchString(normalized):
+		return statementDrop
+	default:
+		return statementOther
+	}
+}
+
+// statementIsDestructive reports whether a single SQL statement drops objects,
+// columns or constraints, or truncates a table
+func statementIsDestructive(sql string) bool {
+	normalized := normalizeStatement(sql)
+	for _, pattern := range destructivePatterns {
+		if pattern.MatchString(normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+// statementRequiresNoTransaction reports whether a single SQL statement cannot
+// be executed inside a transaction block
+func statementRequiresNoTransaction(sql string) bool {
+	normalized := normalizeStatement(sql)
+	for _, pattern := range noTransactionPatterns {
+		if pattern.MatchString(normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+// StatementHook is called with each statement of a migration before it is
+// executed, for logging, metrics or policies such as refusing DROP DATABASE.
+// Returning an error aborts the migration with that error; returning
+// ErrSkipStatement skips the statement and carries on with the next one.
+// A migration sent in a single round-trip has the hook called for all of its
+// statements before any of them executes, so the hook cannot observe the
+// effects of earlier statements of the same migration.
+type StatementHook func(ctx context.Context, migration Migration, stmt string) error
+
+// ErrSkipStatement is returned by a StatementHook to skip a statement
+var ErrSkipStatement = errors.New("skip statement")
+
+// joinStatements rebuilds a script from statements as SplitStatements
+// returns them. Each semicolon goes on a line of its own, so that a
+// statement ending in a line comment does not swallow it.
+func joinStatements(statements []string) string {
+	return strings.Join(statements, "\n;\n") + "\n;\n"
+}
+
+// execMigration executes the content of a single migration. The script is
+// normally sent in one round-trip, which PostgreSQL runs as an implicit
+// transaction. When it contains a statement that cannot run inside a
+// transaction block, every statement is executed on its own instead. A
+// non-nil hook sees every statement first; in a single round-trip it is
+// called for all of them before the script is sent without the skipped ones.
+func execMigration(ctx context.Context, db *sql.DB, migration Migration, content string, hook StatementHook) error {
+	statements := providers.SplitStatements(content)
+
+	wrapped := true
+	for _, stmt := range statements {
+		if statementRequiresNoTransaction(stmt) {
+			wrapped = false
+			break
+		}
+	}
+
+	if wrapped {
+		// kept holds the indexes of the statements the script runs
+		script, kept := content, make([]int, len(statements))
+		for i := range statements {
+			kept[i] = i
+		}
+		if hook != nil {
+			kept = kept[:0]
+			for i, stmt := range statements {
+				skip, err := callStatementHook(ctx, hook, migration, stmt)
+				if err != nil {
+					return migrationStatementError(migration, statements, i, 0, err)
+				}
+				if !skip {
+					kept = append(kept, i)
+				}
+			}
+			if len(kept) == 0 {
+				return nil
+			}
+			if len(kept) < len(statements) {
+				parts := make([]string, len(kept))
+				for j, i := range kept {
+					parts[j] = statements[i]
+				}
+				script = joinStatements(parts)
+			}
+		}
+
+		if _, err := db.ExecContext(ctx, script); err != nil {
+			keptStatements := make([]string, len(kept))
+			for j, i := range kept {
+				keptStatements[j] = statements[i]
+			}
+			index, position := locateStatement(script, keptStatements, errorPosition(err))
+			if index >= 0 {
+				index = kept[index]
+			}
+			return migrationStatementError(migration, statements, index, position, err)
+		}
+		return nil
+	}
+
+	slog.Warn("migration contains statements that cannot run inside a transaction, executing statements individually",
+		"name", migration.Name, "statements", len(statements))
+	for i, stmt := range statements {
+		if hook != nil {
+			skip, err := callStatementHook(ctx, hook, migration, stmt)
+			if err != nil {
+				return migrationStatementError(migration, statements, i, 0, err)
+			}
+			if skip {
+				continue
+			}
+		}
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return migrationStatementError(migration, statements, i, errorPosition(err), err)
+		}
+	}
+	return nil
+}
+
+// callStatementHook calls hook and reports whether it asked to skip stmt
+func callStatementHook(ctx context.Context, hook StatementHook, migration Migration, stmt string) (bool, error) {
+	err := hook(ctx, migration, stmt)
+	if errors.Is(err, ErrSkipStatement) {
+		slog.Debug("statement skipped by hook", "name", migration.Name, "statement", truncateStatement(stmt))
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("statement hook: %w", err)
+	}
+	return false, nil
+}
+
+// maxStatementLength is the number of characters of a failing statement
+// quoted in a migration error
+const maxStatementLength = 120
+
+// migrationStatementError reports the failure of a migration, quoting the
+// failing statement and the character it failed at when they are known. index
+// is the zero-based index of the statement, -1 when unknown; position is the
+// one-based character offset within the statement, 0 when unknown.
+func migrationStatementError(migration Migration, statements []string, index, position int, err error) error {
+	err = describeMigrationError(migration, err)
+	if index < 0 || index >= len(statements) {
+		return fmt.Errorf("failed to execute migration %s: %w", migration.Name, err)
+	}
+
+	location := fmt.Sprintf("statement %d of %d", index+1, len(statements))
+	if position > 0 {
+		location += fmt.Sprintf(", character %d", position)
+	}
+	return fmt.Errorf("failed to execute migration %s (%s: %s): %w",
+		migration.Name, location, truncateStatement(statements[index]), err)
+}
+
+// errorPosition returns the one-based character offset PostgreSQL reported
+// for an error, or 0 when it did not report one
+func errorPosition(err error) int {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return 0
+	}
+	position, err := strconv.Atoi(pqErr.Position)
+	if err != nil || position < 1 {
+		return 0
+	}
+	return position
+}
+
+// locateStatement finds the statement of content containing the one-based
+// character offset position, as reported by PostgreSQL for the whole script,
+// and returns its index along with the offset relative to that statement.
+// The index is -1 when the statement cannot be determined; a script holding
+// a single statement needs no position.
+func locateStatement(content string, statements []string, position int) (int, int) {
+	if position == 0 {
+		if len(statements) == 1 {
+			return 0, 0
+		}
+		return -1, 0
+	}
+
+	// PostgreSQL counts characters, not bytes
+	offset := len(content)
+	if runes := []rune(content); position <= len(runes) {
+		offset = len(string(runes[:position-1]))
+	}
+
+	cursor := 0
+	for i, stmt := range statements {
+		start := strings.Index(content[cursor:], stmt)
+		if start < 0 {
+			break
+		}
+		start += cursor
+		cursor = start + len(stmt)
+		if offset >= start && offset < cursor {
+			return i, utf8.RuneCountInString(content[start:offset]) + 1
+		}
+	}
+	return -1, 0
+}
+
+// truncateStatement redacts the secrets of a statement, collapses its
+// whitespace onto a single line and shortens it to maxStatementLength
+// characters. Secrets are redacted first, so that neither the cut nor the
+// quoting can leave part of one unmatched.
+func truncateStatement(stmt string) string {
+	stmt = strings.Join(strings.Fields(logSecrets.redact(stmt)), " ")
+	if runes := []rune(stmt); len(runes) > maxStatementLength {
+		stmt = string(runes[:maxStatementLength-3]) + "..."
+	}
+	return fmt.Sprintf("%q", stmt)
+}
+
+// undefinedTable is the SQLSTATE of "relation ... does not exist"
+const undefinedTable pq.ErrorCode = "42P01"
+
+var quotedNamePattern = regexp.MustCompile(`"([^"]+)"`)
+
+// describeMigrationError turns a reference to a relation that does not exist
+// into a message naming the migration file and the missing relation, since
+// it usually means migrations run in the wrong order. Other errors are
+// returned unchanged. The original error stays available through errors.As.
+func describeMigrationError(migration Migration, err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != undefinedTable {
+		return err
+	}
+
+	relation := "a relation"
+	if match := quotedNamePattern.FindStringSubmatch(pqErr.Message); match != nil {
+		relation = fmt.Sprintf("relation %q", match[1])
+	}
+	file := migration.UpFile
+	if file == "" {
+		file = migration.Name
+	}
+	return fmt.Errorf("%s references %s, which does not exist: check that an earlier migration creates it and that migrations are ordered correctly: %w",
+		file, relation, err)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// stdinSource is the migration argument that reads a single SQL script from
+// standard input, as in cat schema.sql | mig2schema -
+const stdinSource = "-"
+
+// stdinMigrationName names the migration read from standard input in logs
+// and reports
+const stdinMigrationName = "stdin"
+
+// StdinMigrationReader reads a SQL script and returns it as a single up
+// migration. The script is read on the first call and kept for later ones,
+// since the input can only be consumed once.
+type StdinMigrationReader struct {
+	in         io.Reader
+	once       sync.Once
+	migrations []Migration
+	err        error
+}
+
+func NewStdinMigrationReader(in io.Reader) MigrationReader {
+	return &StdinMigrationReader{in: in}
+}
+
+// DiscoverMigrations returns the script as a migration named stdin. The
+// directory is ignored. A script holding nothing but whitespace is an error.
+func (r *StdinMigrationReader) DiscoverMigrations(string) ([]Migration, error) {
+	r.once.Do(func() {
+		slog.Debug("reading migration from standard input")
+		content, err := io.ReadAll(r.in)
+		if err != nil {
+			r.err = fmt.Errorf("failed to read standard input: %w", err)
+			return
+		}
+		if len(bytes.TrimSpace(content)) == 0 {
+			r.err = withExitCode(exitNoMigrations, fmt.Errorf("no SQL found on standard input"))
+			return
+		}
+		r.migrations = []Migration{{
+			Name:   stdinMigrationName,
+			UpFile: "<" + stdinMigrationName + ">",
+			UpSQL:  content,
+		}}
+	})
+	return r.migrations, r.err
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a pipe
+// or a file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// migrationDirMissing reports whether the migration directory or archive does
+// not exist. Standard input is never missing.
+func migrationDirMissing(migrationDir string) bool {
+	if migrationDir == stdinSource {
+		return false
+	}
+	_, err := os.Stat(migrationDir)
+	return os.IsNotExist(err)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStdinMigrationReader(t *testing.T) {
+	script := "create table users (id serial primary key);\ncreate table posts (id serial primary key);\n"
+	reader := NewStdinMigrationReader(strings.NewReader(script))
+
+	migrations, err := reader.DiscoverMigrations(stdinSource)
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	assert.Equal(t, "stdin", migrations[0].Name)
+	assert.Equal(t, "<stdin>", migrations[0].UpFile)
+	assert.Empty(t, migrations[0].DownFile)
+	assert.Equal(t, script, string(migrations[0].UpSQL))
+
+	// The input is consumed once; later calls return the same migration
+	again, err := reader.DiscoverMigrations(stdinSource)
+	require.NoError(t, err)
+	assert.Equal(t, migrations, again)
+}
+
+func TestStdinMigrationReaderEmpty(t *testing.T) {
+	for _, input := range []string{"", " \n\t\n"} {
+		_, err := NewStdinMigrationReader(strings.NewReader(input)).DiscoverMigrations(stdinSource)
+		require.Error(t, err)
+		assert.Equal(t, exitNoMigrations, exitCode(err))
+		assert.Contains(t, err.Error(), "no SQL found on standard input")
+	}
+}
+
+func TestRunDryRunStdin(t *testing.T) {
+	assert.False(t, migrationDirMissing(stdinSource))
+
+	var out bytes.Buffer
+	reader := NewStdinMigrationReader(strings.NewReader("create table users (id int;"))
+	passed, err := runDryRun(stdinSource, reader, &out)
+	require.NoError(t, err)
+	assert.False(t, passed)
+	assert.Contains(t, out.String(), "<stdin>:1:")
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/alc6/mig2schema/providers"
+)
+
+// maxTableSuggestions is how many similar table names describe_table suggests
+const maxTableSuggestions = 3
+
+// extractTablesWithDeps runs the migrations in migrationDir and returns the
+// resulting tables
+func extractTablesWithDeps(ctx context.Context, migrationDir string,
+	migrationReader MigrationReader, dbManager DatabaseManager, schemaExtractor SchemaExtractor) ([]providers.Table, error) {
+	if _, err := os.Stat(migrationDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("migration directory does not exist: %s", migrationDir)
+	}
+
+	migrations, err := migrationReader.DiscoverMigrations(migrationDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse migrations: %v", err)
+	}
+
+	if len(migrations) == 0 {
+		return nil, fmt.Errorf("no migration files found in directory")
+	}
+
+	if err := dbManager.Setup(ctx); err != nil {
+		return nil, fmt.Errorf("failed to setup postgresql: %v", err)
+	}
+	defer func() {
+		if err := dbManager.Close(ctx); err != nil {
+			slog.Error("failed to cleanup database", "error", err)
+		}
+	}()
+
+	if err := dbManager.RunMigrations(ctx, migrations); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %v", err)
+	}
+
+	tables, err := schemaExtractor.ExtractSchema(dbManager.GetDB())
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract schema: %v", err)
+	}
+	return tables, nil
+}
+
+// describeTableCore returns one table of the schema produced by the
+// migrations as JSON
+func describeTableCore(ctx context.Context, migrationDir, tableName, pgImage string) (string, error) {
+	return describeTableCoreWithDeps(ctx, migrationDir, tableName,
+		NewFileMigrationReader(), NewPostgreSQLManager(pgImage), NewPostgreSQLSchemaExtractor())
+}
+
+// describeTableCoreWithDeps is the testable version of describeTableCore. An
+// unknown table name is an error suggesting the closest existing names.
+func describeTableCoreWithDeps(ctx context.Context, migrationDir, tableName string,
+	migrationReader MigrationReader, dbManager DatabaseManager, schemaExtractor SchemaExtractor) (string, error) {
+	tables, err := extractTablesWithDeps(ctx, migrationDir, migrationReader, dbManager, schemaExtractor)
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, len(tables))
+	for i, table := range tables {
+		if table.Name == tableName {
+			output, err := json.MarshalIndent(table, "", "  ")
+			if err != nil {
+				return "", fmt.Errorf("failed to marshal table to JSON: %w", err)
+			}
+			return string(output), nil
+		}
+		names[i] = table.Name
+	}
+
+	message := fmt.Sprintf("table %q not found", tableName)
+	if suggestions := closestNames(tableName, names, maxTableSuggestions); len(suggestions) > 0 {
+		message += fmt.Sprintf("; did you mean %s?", strings.Join(suggestions, ", "))
+	}
+	return "", fmt.Errorf("%s", message)
+}
+
+// tableSummary is the per-table entry returned by list_tables
+type tableSummary struct {
+	Name          string `json:"name"`
+	ColumnCount   int    `json:"column_count"`
+	IndexCount    int    `json:"index_count"`
+	HasPrimaryKey bool   `json:"has_primary_key"`
+	IsView        bool   `json:"is_view,omitempty"`
+}
+
+// listTablesCore returns a summary of the tables of schemaName produced by
+// the migrations as JSON
+func listTablesCore(ctx context.Context, migrationDir, schemaName string, includeViews bool, pgImage string) (string, error) {
+	return listTablesCoreWithDeps(ctx, migrationDir,
+		NewFileMigrationReader(), NewPostgreSQLManager(pgImage), newScopedSchemaExtractor(schemaName, includeViews))
+}
+
+// listTablesCoreWithDeps is the testable version of listTablesCore. Tables are
+// sorted by name.
+func listTablesCoreWithDeps(ctx context.Context, migrationDir string,
+	migrationReader MigrationReader, dbManager DatabaseManager, schemaExtractor SchemaExtractor) (string, error) {
+	tables, err := extractTablesWithDeps(ctx, migrationDir, migrationReader, dbManager, schemaExtractor)
+	if err != nil {
+		return "", err
+	}
+
+	summaries := make([]tableSummary, 0, len(tables))
+	for _, table := range tables {
+		summaries = append(summaries, summarizeTable(table))
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+
+	output, err := json.MarshalIndent(summaries, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal tables to JSON: %w", err)
+	}
+	return string(output), nil
+}
+
+// summarizeTable projects a table down to its list_tables entry
+func summarizeTable(table providers.Table) tableSummary {
+	summary := tableSummary{
+		Name:        table.Name,
+		ColumnCount: len(table.Columns),
+		IndexCount:  len(table.Indexes),
+		IsView:      table.IsView,
+	}
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey {
+			summary.HasPrimaryKey = true
+			break
+		}
+	}
+	return summary
+}
+
+// closestNames returns up to limit candidates ordered by edit distance to
+// name. Candidates further away than half the length of name are left out.
+func closestNames(name string, candidates []string, limit int) []string {
+	type scored struct {
+		name     string
+		distance int
+	}
+
+	maxDistance := len(name)/2 + 1
+	var matches []scored
+	for _, candidate := range candidates {
+		distance := levenshtein(strings.ToLower(name), strings.ToLower(candidate))
+		if distance <= maxDistance {
+			matches = append(matches, scored{candidate, distance})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	var names []string
+	for i := 0; i < len(matches) && i < limit; i++ {
+		names = append(names, matches[i].name)
+	}
+	return names
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
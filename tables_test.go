@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTablesExtractor() *MockSchemaExtractor {
+	return &MockSchemaExtractor{
+		ExtractSchemaFunc: func(db *sql.DB) ([]providers.Table, error) {
+			return []providers.Table{
+				{
+					Name: "users",
+					Columns: []providers.Column{
+						{Name: "id", DataType: "integer", IsPrimaryKey: true},
+						{Name: "email", DataType: "text"},
+					},
+					UniqueConstraints: []providers.UniqueConstraint{{Name: "users_email_key", Columns: []string{"email"}}},
+				},
+				{
+					Name:        "user_roles",
+					Columns:     []providers.Column{{Name: "user_id", DataType: "integer"}},
+					ForeignKeys: []providers.ForeignKey{{Name: "user_roles_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}}},
+				},
+				{Name: "posts", Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}}},
+			}, nil
+		},
+	}
+}
+
+func testTablesReader() *MockMigrationReader {
+	return &MockMigrationReader{
+		DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+			return []Migration{{Name: "001_init", UpSQL: []byte("select 1;")}}, nil
+		},
+	}
+}
+
+func TestDescribeTableCoreWithDeps(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+
+	t.Run("existing_table", func(t *testing.T) {
+		output, err := describeTableCoreWithDeps(ctx, tempDir, "user_roles", testTablesReader(), &MockDatabaseManager{}, testTablesExtractor())
+		require.NoError(t, err)
+
+		var table providers.Table
+		require.NoError(t, json.Unmarshal([]byte(output), &table))
+		assert.Equal(t, "user_roles", table.Name)
+		require.Len(t, table.ForeignKeys, 1)
+		assert.Equal(t, "users", table.ForeignKeys[0].ReferencedTable)
+		assert.NotContains(t, output, "users_email_key", "other tables should not be included")
+	})
+
+	t.Run("unknown_table_suggests_names", func(t *testing.T) {
+		_, err := describeTableCoreWithDeps(ctx, tempDir, "user", testTablesReader(), &MockDatabaseManager{}, testTablesExtractor())
+		require.Error(t, err)
+		assert.Equal(t, `table "user" not found; did you mean users?`, err.Error())
+	})
+
+	t.Run("unknown_table_without_suggestion", func(t *testing.T) {
+		_, err := describeTableCoreWithDeps(ctx, tempDir, "invoices", testTablesReader(), &MockDatabaseManager{}, testTablesExtractor())
+		require.Error(t, err)
+		assert.Equal(t, `table "invoices" not found`, err.Error())
+	})
+
+	t.Run("nonexistent_directory", func(t *testing.T) {
+		_, err := describeTableCoreWithDeps(ctx, "/non/existent/directory", "users", testTablesReader(), &MockDatabaseManager{}, testTablesExtractor())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "migration directory does not exist")
+	})
+}
+
+func TestListTablesCoreWithDeps(t *testing.T) {
+	ctx := context.Background()
+
+	output, err := listTablesCoreWithDeps(ctx, t.TempDir(), testTablesReader(), &MockDatabaseManager{}, testTablesExtractor())
+	require.NoError(t, err)
+
+	var summaries []tableSummary
+	require.NoError(t, json.Unmarshal([]byte(output), &summaries))
+	assert.Equal(t, []tableSummary{
+		{Name: "posts", ColumnCount: 1, HasPrimaryKey: true},
+		{Name: "user_roles", ColumnCount: 1},
+		{Name: "users", ColumnCount: 2, HasPrimaryKey: true},
+	}, summaries)
+	assert.NotContains(t, output, "data_type", "column details should not be included")
+}
+
+func TestSummarizeTable(t *testing.T) {
+	summary := summarizeTable(providers.Table{
+		Name:    "active_users",
+		Columns: []providers.Column{{Name: "id"}, {Name: "email"}},
+		IsView:  true,
+	})
+	assert.Equal(t, tableSummary{Name: "active_users", ColumnCount: 2, IsView: true}, summary)
+
+	summary = summarizeTable(providers.Table{
+		Name:    "users",
+		Columns: []providers.Column{{Name: "id", IsPrimaryKey: true}},
+		Indexes: []providers.Index{{Name: "idx_users_id", Columns: []string{"id"}}},
+	})
+	assert.Equal(t, tableSummary{Name: "users", ColumnCount: 1, IndexCount: 1, HasPrimaryKey: true}, summary)
+}
+
+func TestClosestNames(t *testing.T) {
+	candidates := []string{"users", "user_roles", "posts", "post_tags"}
+	assert.Equal(t, []string{"users"}, closestNames("usres", candidates, 3))
+	assert.Equal(t, []string{"posts"}, closestNames("post", candidates, 3))
+	assert.Equal(t, []string{"Users", "user"}, closestNames("users", []string{"usrs", "user", "Users"}, 2))
+	assert.Empty(t, closestNames("invoices", candidates, 3))
+}
+
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 0, levenshtein("users", "users"))
+	assert.Equal(t, 1, levenshtein("user", "users"))
+	assert.Equal(t, 2, levenshtein("usres", "users"))
+	assert.Equal(t, 3, levenshtein("kitten", "sitting"))
+	assert.Equal(t, 5, levenshtein("", "posts"))
+}
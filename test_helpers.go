@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sync"
 
 	"github.com/alc6/mig2schema/providers"
 )
@@ -12,7 +13,7 @@ import (
 type MockDatabaseManager struct {
 	SetupFunc            func(ctx context.Context) error
 	CloseFunc            func(ctx context.Context) error
-	RunMigrationsFunc    func(migrations []Migration) error
+	RunMigrationsFunc    func(ctx context.Context, migrations []Migration) error
 	GetDBFunc            func() *sql.DB
 	GetConnectionStringFunc func() string
 	
@@ -40,10 +41,10 @@ func (m *MockDatabaseManager) Close(ctx context.Context) error {
 	return nil
 }
 
-func (m *MockDatabaseManager) RunMigrations(migrations []Migration) error {
+func (m *MockDatabaseManager) RunMigrations(ctx context.Context, migrations []Migration) error {
 	m.RunMigrationsCalled = true
 	if m.RunMigrationsFunc != nil {
-		return m.RunMigrationsFunc(migrations)
+		return m.RunMigrationsFunc(ctx, migrations)
 	}
 	return nil
 }
@@ -104,6 +105,37 @@ func (m *MockMigrationReader) DiscoverMigrations(dir string) ([]Migration, error
 	return []Migration{}, nil
 }
 
+// MockSchemaProvider is a mock implementation of providers.SchemaProvider for testing
+type MockSchemaProvider struct {
+	// ProviderName is returned by Name, "mock" when empty
+	ProviderName string
+	// Unavailable makes IsAvailable report false
+	Unavailable       bool
+	ExtractSchemaFunc func(ctx context.Context, params providers.ExtractParams) (*providers.SchemaResult, error)
+
+	// Track calls for verification
+	ExtractSchemaCalled bool
+}
+
+func (m *MockSchemaProvider) Name() string {
+	if m.ProviderName != "" {
+		return m.ProviderName
+	}
+	return "mock"
+}
+
+func (m *MockSchemaProvider) ExtractSchema(ctx context.Context, params providers.ExtractParams) (*providers.SchemaResult, error) {
+	m.ExtractSchemaCalled = true
+	if m.ExtractSchemaFunc != nil {
+		return m.ExtractSchemaFunc(ctx, params)
+	}
+	return &providers.SchemaResult{Format: params.Format}, nil
+}
+
+func (m *MockSchemaProvider) IsAvailable() bool {
+	return !m.Unavailable
+}
+
 // TestDatabase is a helper for creating test database instances
 type TestDatabase struct {
 	*Database
@@ -132,4 +164,27 @@ func SimulateError(errType string) error {
 	default:
 		return fmt.Errorf("simulated error: %s", errType)
 	}
-}
\ No newline at end of file
+}
+
+// StatementRecorder is a StatementHook recording the statements it sees,
+// skipping those Skip matches and failing on those Refuse matches
+type StatementRecorder struct {
+	mu         sync.Mutex
+	Statements []string
+	Skip       func(stmt string) bool
+	Refuse     func(stmt string) bool
+}
+
+// Hook is the StatementHook recording into r
+func (r *StatementRecorder) Hook(ctx context.Context, migration Migration, stmt string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Statements = append(r.Statements, stmt)
+	if r.Refuse != nil && r.Refuse(stmt) {
+		return fmt.Errorf("statement refused by policy")
+	}
+	if r.Skip != nil && r.Skip(stmt) {
+		return ErrSkipStatement
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/alc6/mig2schema/providers"
+	"gopkg.in/yaml.v3"
+)
+
+// typeMapFile is the --type-map file
+//
+//	types:
+//	  citext: TEXT
+//	  email: VARCHAR(320)
+type typeMapFile struct {
+	Types map[string]string `yaml:"types"`
+}
+
+// loadTypeMap builds the type overrides from the --type-map file, if any,
+// and the --map-type flags, which take precedence. It returns nil when no
+// override is given so that the built-in mapping applies unchanged.
+func loadTypeMap(path string, mappings []string) (providers.TypeMap, error) {
+	typeMap := make(providers.TypeMap)
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read type map: %w", err)
+		}
+
+		var file typeMapFile
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&file); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to parse type map %s: %w", path, err)
+		}
+
+		for name, dataType := range file.Types {
+			name, dataType, err := providers.ParseTypeMapping(name + "=" + dataType)
+			if err != nil {
+				return nil, fmt.Errorf("type map %s: %w", path, err)
+			}
+			typeMap[name] = dataType
+		}
+	}
+
+	for _, mapping := range mappings {
+		name, dataType, err := providers.ParseTypeMapping(mapping)
+		if err != nil {
+			return nil, err
+		}
+		typeMap[name] = dataType
+	}
+
+	if len(typeMap) == 0 {
+		return nil, nil
+	}
+	return typeMap, nil
+}
+
+// typeMapCacheSetting identifies the type overrides for the output cache
+func typeMapCacheSetting(typeMap providers.TypeMap) string {
+	entries := make([]string, 0, len(typeMap))
+	for name, dataType := range typeMap {
+		entries = append(entries, name+"="+dataType)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, "\x00")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTypeMap(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "types.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	t.Run("none", func(t *testing.T) {
+		typeMap, err := loadTypeMap("", nil)
+		require.NoError(t, err)
+		assert.Nil(t, typeMap)
+	})
+
+	t.Run("file_and_flags", func(t *testing.T) {
+		path := write(t, "types:\n  Email: VARCHAR(320)\n  citext: TEXT\n")
+		typeMap, err := loadTypeMap(path, []string{"citext=VARCHAR", " money = NUMERIC(12,2) "})
+		require.NoError(t, err)
+		assert.Equal(t, providers.TypeMap{"email": "VARCHAR(320)", "citext": "VARCHAR", "money": "NUMERIC(12,2)"}, typeMap)
+	})
+
+	tests := []struct {
+		name     string
+		content  string
+		mappings []string
+		wantErr  string
+	}{
+		{name: "invalid_flag", mappings: []string{"citext"}, wantErr: `invalid type mapping "citext", expected name=type`},
+		{name: "empty_type", mappings: []string{"citext="}, wantErr: "expected name=type"},
+		{name: "empty_type_in_file", content: "types:\n  citext: ''\n", wantErr: "expected name=type"},
+		{name: "unknown_field", content: "type:\n  citext: TEXT\n", wantErr: "failed to parse type map"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := ""
+			if tt.content != "" {
+				path = write(t, tt.content)
+			}
+			_, err := loadTypeMap(path, tt.mappings)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	t.Run("missing_file", func(t *testing.T) {
+		_, err := loadTypeMap(filepath.Join(t.TempDir(), "missing.yaml"), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read type map")
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSchemaTypeScript(t *testing.T) {
+	tables := []providers.Table{
+		{
+			Name: "user_accounts",
+			Columns: []providers.Column{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true, DefaultValue: sql.NullString{String: "nextval('user_accounts_id_seq'::regclass)", Valid: true}},
+				{Name: "email", DataType: "character varying"},
+				{Name: "is_active", DataType: "boolean", DefaultValue: sql.NullString{String: "true", Valid: true}},
+				{Name: "balance", DataType: "numeric", IsNullable: true},
+				{Name: "created_at", DataType: "timestamp with time zone"},
+				{Name: "settings", DataType: "jsonb", IsNullable: true},
+				{Name: "display-name", DataType: "text", IsNullable: true},
+			},
+		},
+		{
+			Name:    "tags",
+			Columns: []providers.Column{{Name: "label", DataType: "text"}},
+		},
+	}
+
+	t.Run("default", func(t *testing.T) {
+		expected := `export interface UserAccounts {
+  id: number;
+  email: string;
+  is_active: boolean;
+  balance: number | null;
+  created_at: string;
+  settings: unknown | null;
+  "display-name": string | null;
+}
+
+export interface Tags {
+  label: string;
+}
+`
+		assert.Equal(t, expected, providers.FormatSchemaTypeScript(tables))
+	})
+
+	t.Run("optional_defaults", func(t *testing.T) {
+		result := providers.FormatSchemaTypeScriptWithOptions(tables, providers.TypeScriptOptions{OptionalDefaults: true})
+		assert.Contains(t, result, "  id?: number;\n")
+		assert.Contains(t, result, "  is_active?: boolean;\n")
+		assert.Contains(t, result, "  email: string;\n")
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		assert.Empty(t, providers.FormatSchemaTypeScript(nil))
+	})
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// dirValidation is the outcome of validating the migrations of one directory
+type dirValidation struct {
+	Dir            string
+	Migrations     int
+	SyntaxErrors   int
+	SequenceIssues int
+	Err            error
+}
+
+// passed reports whether the directory is valid. Sequence issues only fail
+// it when checkSequence is set, as they do for a single directory.
+func (v dirValidation) passed(checkSequence bool) bool {
+	return v.Err == nil && v.SyntaxErrors == 0 && (!checkSequence || v.SequenceIssues == 0)
+}
+
+// runValidateAll validates every directory below parentDir that holds
+// migrations, using up to workers concurrent validations, and writes a
+// summary table to w. It reports whether every directory passed.
+func runValidateAll(parentDir string, opts validateOptions, checkSequence bool, workers int, w io.Writer) (bool, error) {
+	if info, err := os.Stat(parentDir); err != nil || !info.IsDir() {
+		return false, withExitCode(exitMigrationDirNotFound, fmt.Errorf("directory does not exist: %s", parentDir))
+	}
+
+	dirs, err := findMigrationDirs(parentDir)
+	if err != nil {
+		return false, err
+	}
+	if len(dirs) == 0 {
+		return false, withExitCode(exitNoMigrations, fmt.Errorf("no migration directories found in: %s", parentDir))
+	}
+
+	results := validateDirs(dirs, opts, workers)
+	writeValidateAll(w, parentDir, results, checkSequence)
+
+	for _, result := range results {
+		if !result.passed(checkSequence) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// findMigrationDirs returns the directories below parentDir, in lexical
+// order, that directly contain .up.sql or .down.sql files. Hidden
+// directories such as .git are skipped.
+func findMigrationDirs(parentDir string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(parentDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || path == parentDir {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if _, _, ok := migrationFileName(entry.Name()); ok && !entry.IsDir() {
+				dirs = append(dirs, path)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for migration directories: %w", err)
+	}
+	return dirs, nil
+}
+
+// validateDirs runs validateMigrationsCore on every directory with a pool of
+// workers, keeping the results in the order of dirs
+func validateDirs(dirs []string, opts validateOptions, workers int) []dirValidation {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	results := make([]dirValidation, len(dirs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(dirs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = validateDir(dirs[i], opts)
+			}
+		}()
+	}
+	for i := range dirs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// validateDir validates one directory and counts the problems in the report
+// of validateMigrationsCore
+func validateDir(dir string, opts validateOptions) dirValidation {
+	result := dirValidation{Dir: dir}
+
+	opts.Format = validateFormatJSON
+	output, err := validateMigrationsCore(dir, opts)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	var report struct {
+		MigrationCount int               `json:"migration_count"`
+		SequenceIssues []json.RawMessage `json:"sequence_issues"`
+		Migrations     []struct {
+			SyntaxErrors []json.RawMessage `json:"syntax_errors"`
+		} `json:"migrations"`
+	}
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		result.Err = fmt.Errorf("failed to read validation result: %w", err)
+		return result
+	}
+
+	result.Migrations = report.MigrationCount
+	result.SequenceIssues = len(report.SequenceIssues)
+	for _, migration := range report.Migrations {
+		result.SyntaxErrors += len(migration.SyntaxErrors)
+	}
+	return result
+}
+
+// writeValidateAll writes one row per directory, relative to parentDir,
+// followed by the errors of the directories that could not be validated and
+// the number of directories that failed
+func writeValidateAll(w io.Writer, parentDir string, results []dirValidation, checkSequence bool) {
+	fmt.Fprintln(w, "=== MIGRATION DIRECTORIES ===")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DIRECTORY\tRESULT\tMIGRATIONS\tSYNTAX ERRORS\tSEQUENCE ISSUES")
+	failed := 0
+	var errs []string
+	for _, result := range results {
+		dir := result.Dir
+		if rel, err := filepath.Rel(parentDir, result.Dir); err == nil {
+			dir = rel
+		}
+
+		status := "ok"
+		if !result.passed(checkSequence) {
+			failed++
+			status = "invalid"
+		}
+		if result.Err != nil {
+			fmt.Fprintf(tw, "%s\tfailed\t-\t-\t-\n", dir)
+			errs = append(errs, fmt.Sprintf("%s: %v", dir, result.Err))
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\n", dir, status, result.Migrations, result.SyntaxErrors, result.SequenceIssues)
+	}
+	tw.Flush()
+	for _, err := range errs {
+		fmt.Fprintln(w, err)
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(w, "%d of %d migration directories failed validation\n", failed, len(results))
+	} else {
+		fmt.Fprintf(w, "all %d migration directories are valid\n", len(results))
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeMigrationTree creates files relative to root, with their directories
+func writeMigrationTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestFindMigrationDirs(t *testing.T) {
+	root := t.TempDir()
+	writeMigrationTree(t, root, map[string]string{
+		"billing/migrations/001_init.up.sql":  "create table invoices (id int);",
+		"users/migrations/001_init.down.sql":  "drop table users;",
+		"users/README.md":                     "users service",
+		".git/hooks/001_init.up.sql":          "create table hidden (id int);",
+		"gateway/config.yaml":                 "port: 8080",
+		"001_top_level.up.sql":                "create table top (id int);",
+		"orders/migrations/nested/001.up.sql": "create table orders (id int);",
+	})
+
+	dirs, err := findMigrationDirs(root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(root, "billing/migrations"),
+		filepath.Join(root, "orders/migrations/nested"),
+		filepath.Join(root, "users/migrations"),
+	}, dirs)
+}
+
+func TestRunValidateAll(t *testing.T) {
+	root := t.TempDir()
+	writeMigrationTree(t, root, map[string]string{
+		"billing/001_create_invoices.up.sql": "create table invoices (id int);",
+		"billing/002_add_total.up.sql":       "alter table invoices add column total numeric;",
+		"users/001_create_users.up.sql":      "create table users (id int;",
+		"users/003_add_email.up.sql":         "alter table users add column email text;",
+		"search/001_a.up.sql":                "create table a (id int);",
+		"search/001_b.up.sql":                "create table b (id int);",
+	})
+
+	t.Run("files_only", func(t *testing.T) {
+		var out bytes.Buffer
+		passed, err := runValidateAll(root, validateOptions{}, false, 2, &out)
+		require.NoError(t, err)
+		assert.True(t, passed)
+		assert.Contains(t, out.String(), "all 3 migration directories are valid")
+	})
+
+	t.Run("syntax_and_sequence", func(t *testing.T) {
+		var out bytes.Buffer
+		passed, err := runValidateAll(root, validateOptions{StrictValidate: true}, true, 2, &out)
+		require.NoError(t, err)
+		assert.False(t, passed)
+
+		lines := out.String()
+		assert.Regexp(t, `billing\s+ok\s+2\s+0\s+0\n`, lines)
+		assert.Regexp(t, `search\s+invalid\s+2\s+0\s+1\n`, lines)
+		assert.Regexp(t, `users\s+invalid\s+2\s+1\s+1\n`, lines)
+		assert.Contains(t, lines, "2 of 3 migration directories failed validation")
+	})
+
+	t.Run("unreadable_directory", func(t *testing.T) {
+		var out bytes.Buffer
+		writeValidateAll(&out, root, []dirValidation{
+			{Dir: filepath.Join(root, "billing"), Migrations: 2},
+			{Dir: filepath.Join(root, "users"), Err: errors.New("permission denied")},
+		}, false)
+
+		lines := strings.Split(out.String(), "\n")
+		require.GreaterOrEqual(t, len(lines), 4)
+		assert.Len(t, strings.Fields(lines[3]), len(strings.Fields(lines[2])), "the error row has a cell per header column")
+		assert.Regexp(t, `^users\s+failed\s+-\s+-\s+-$`, lines[3])
+		assert.Contains(t, out.String(), "users: permission denied\n1 of 2 migration directories failed validation\n")
+	})
+
+	t.Run("missing_directory", func(t *testing.T) {
+		_, err := runValidateAll(filepath.Join(root, "missing"), validateOptions{}, false, 0, &bytes.Buffer{})
+		assert.Equal(t, exitMigrationDirNotFound, exitCode(err))
+	})
+
+	t.Run("no_migration_directories", func(t *testing.T) {
+		_, err := runValidateAll(t.TempDir(), validateOptions{}, false, 0, &bytes.Buffer{})
+		assert.Equal(t, exitNoMigrations, exitCode(err))
+	})
+}
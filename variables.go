@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue replaces secrets in log output
+const redactedValue = "[REDACTED]"
+
+// loadVariables reads the --var-file, a YAML mapping of psql variable names
+// to values:
+//
+//	app_password: s3cret
+//	schema_owner: app
+//
+// It returns nil when no file is given, which disables substitution.
+func loadVariables(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read variable file: %w", err)
+	}
+
+	vars := make(map[string]string)
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&vars); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse variable file %s: %w", path, err)
+	}
+	return vars, nil
+}
+
+// secretRedactor removes known secrets from log records. Every value of the
+// --var-file is treated as a secret, since migrations may embed the
+// substituted text in error messages.
+type secretRedactor struct {
+	mu      sync.RWMutex
+	secrets []string
+}
+
+// logSecrets is consulted by the handler installed in run
+var logSecrets = &secretRedactor{}
+
+// add registers secrets to redact. Empty values are ignored, and longer
+// secrets are replaced first so that a secret containing another one is
+// not partially revealed. A secret is also redacted with its quotes doubled,
+// as it appears once substituted into a quoted literal or identifier.
+func (r *secretRedactor) add(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		r.secrets = append(r.secrets, secret)
+		for _, quote := range []string{"'", `"`} {
+			if strings.Contains(secret, quote) {
+				r.secrets = append(r.secrets, strings.ReplaceAll(secret, quote, quote+quote))
+			}
+		}
+	}
+	sort.SliceStable(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+}
+
+// redact replaces every registered secret in s
+func (r *secretRedactor) redact(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redactedValue)
+	}
+	return s
+}
+
+// replaceAttr is a slog.HandlerOptions.ReplaceAttr function redacting string
+// values and errors
+func (r *secretRedactor) replaceAttr(_ []string, a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if redacted := r.redact(a.Value.String()); redacted != a.Value.String() {
+			a.Value = slog.StringValue(redacted)
+		}
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			if redacted := r.redact(err.Error()); redacted != err.Error() {
+				a.Value = slog.StringValue(redacted)
+			}
+		}
+	}
+	return a
+}
+
+// variablesCacheSetting identifies the --var-file for the output cache. The
+// content is included since substituted values can change the schema; cache
+// keys are hashed, so secrets are not written out.
+func variablesCacheSetting() string {
+	if varFilePath == "" {
+		return ""
+	}
+	data, err := os.ReadFile(varFilePath)
+	if err != nil {
+		return varFilePath
+	}
+	return varFilePath + "\x00" + string(data)
+}
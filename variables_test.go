@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubstituteVariables(t *testing.T) {
+	vars := map[string]string{"pw": "it's secret", "owner": "app", "role": `odd"name`}
+
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+	}{
+		{"literal", "create role app with password :'pw';", "create role app with password 'it''s secret';"},
+		{"identifier", `grant select on users to :"role";`, `grant select on users to "odd""name";`},
+		{"raw", "alter table users owner to :owner;", "alter table users owner to app;"},
+		{"cast", "select '1'::integer, now()::date;", "select '1'::integer, now()::date;"},
+		{"undefined_raw", "select arr[1:n] from t;", "select arr[1:n] from t;"},
+		{"string", "select ':pw', ':''pw''';", "select ':pw', ':''pw''';"},
+		{"comments", "-- uses :'pw'\n/* :owner */ select 1;", "-- uses :'pw'\n/* :owner */ select 1;"},
+		{"dollar_quoted", "do $$ begin perform :'pw'; end $$;", "do $$ begin perform :'pw'; end $$;"},
+		{"quoted_identifier", `select ":owner" from t;`, `select ":owner" from t;`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := providers.SubstituteVariables(tt.sql, vars)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+
+	_, err := providers.SubstituteVariables("create role app with password :'missing';", vars)
+	assert.EqualError(t, err, `variable "missing" is not defined`)
+}
+
+func TestLoadVariables(t *testing.T) {
+	vars, err := loadVariables("")
+	require.NoError(t, err)
+	assert.Nil(t, vars)
+
+	path := filepath.Join(t.TempDir(), "vars.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("pw: s3cret\nport: 5432\n"), 0600))
+	vars, err = loadVariables(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"pw": "s3cret", "port": "5432"}, vars)
+
+	require.NoError(t, os.WriteFile(path, []byte("- not a mapping\n"), 0600))
+	_, err = loadVariables(path)
+	assert.ErrorContains(t, err, "failed to parse variable file")
+
+	_, err = loadVariables(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to read variable file")
+}
+
+func TestSecretRedactor(t *testing.T) {
+	redactor := &secretRedactor{}
+	redactor.add("s3cret", "", "s3cret-longer")
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: redactor.replaceAttr}))
+	logger.Error("password s3cret rejected",
+		"statement", "create role app with password 's3cret-longer'",
+		"error", fmt.Errorf("failed to execute migration: %w", errors.New(`syntax error near "s3cret"`)),
+		"count", 3)
+
+	output := buf.String()
+	assert.NotContains(t, output, "s3cret")
+	assert.Contains(t, output, `"msg":"password [REDACTED] rejected"`)
+	assert.Contains(t, output, `"statement":"create role app with password '[REDACTED]'"`)
+	assert.Contains(t, output, `"error":"failed to execute migration: syntax error near \"[REDACTED]\""`)
+	assert.Contains(t, output, `"count":3`)
+}
+
+func TestMigrationToSchemaVariables(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping variable substitution test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	manager := NewPostgreSQLManager("postgres:16-alpine", WithVariables(map[string]string{"pw": "s3cret", "table": "accounts"}))
+	require.NoError(t, manager.Setup(ctx))
+	defer manager.Close(ctx)
+
+	migrations := []Migration{{
+		Name:  "001_create_role",
+		UpSQL: []byte(`create role app with login password :'pw'; create table :"table" (id int primary key);`),
+	}}
+	require.NoError(t, manager.RunMigrations(ctx, migrations))
+
+	tables, err := providers.ExtractSchemaFromDB(manager.GetDB())
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	assert.Equal(t, "accounts", tables[0].Name)
+}
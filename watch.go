@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/lib/pq"
+
+	"github.com/alc6/mig2schema/providers"
+)
+
+// watchDebounce is how long the watcher waits after the last change before
+// refreshing, so that an editor saving several files triggers one run
+const watchDebounce = 300 * time.Millisecond
+
+// schemaWatcher re-runs the migrations of a directory on a warm database and
+// prints the schema each time they change
+type schemaWatcher struct {
+	migrationDir    string
+	migrationReader MigrationReader
+	dbManager       DatabaseManager
+	provider        providers.SchemaProvider
+	params          providers.ExtractParams
+	out             io.Writer
+	errOut          io.Writer
+	now             func() time.Time
+	// reset empties the database between runs, see resetDatabase
+	reset func(ctx context.Context, db *sql.DB) error
+	// incremental runs only the migrations added since the previous run,
+	// keeping track of them in ledger, see --incremental
+	incremental bool
+	ledger      migrationLedger
+	// rebuild is set after a failed run, when the database may hold part of
+	// a migration and must start from scratch
+	rebuild bool
+
+	// previous is the schema printed by the last successful refresh, if any
+	previous    []providers.Table
+	hasPrevious bool
+	runs        int
+}
+
+// watchMigrations starts the database once, prints the schema and then
+// refreshes it whenever a .sql file under migrationDir changes, until ctx is
+// done. A failing refresh is reported and the watcher keeps going, so that a
+// migration being edited can be fixed in place. Schemas are printed to out
+// and failures to errOut.
+func watchMigrations(ctx context.Context, migrationDir string, migrationReader MigrationReader, dbManager DatabaseManager,
+	provider providers.SchemaProvider, out, errOut io.Writer) error {
+	params, err := extractParams()
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start file watcher: %w", err)
+	}
+	defer watcher.Close()
+	if err := addWatchDirs(watcher, migrationDir); err != nil {
+		return withExitCode(exitMigrationDirNotFound, fmt.Errorf("failed to watch %s: %w", migrationDir, err))
+	}
+
+	slog.Info("setting up database")
+	if err := dbManager.Setup(ctx); err != nil {
+		return withExitCode(exitSetupFailed, fmt.Errorf("failed to setup database: %w", err))
+	}
+	defer func() {
+		// ctx is done by now, cleanup must not be cancelled with it
+		if err := dbManager.Close(context.WithoutCancel(ctx)); err != nil {
+			slog.Error("failed to cleanup", "error", err)
+		}
+	}()
+
+	w := &schemaWatcher{
+		migrationDir:    migrationDir,
+		migrationReader: migrationReader,
+		dbManager:       dbManager,
+		provider:        provider,
+		params:          params,
+		out:             out,
+		errOut:          errOut,
+		now:             time.Now,
+		reset:           resetDatabase,
+		incremental:     incremental,
+		ledger:          newMigrationLedger(dbManager.GetDB()),
+	}
+	if incremental {
+		w.params.ExcludeSchemas = append(w.params.ExcludeSchemas, ledgerSchema)
+	}
+	w.refreshAndReport(ctx)
+
+	slog.Info("watching migrations for changes", "directory", migrationDir)
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Create) {
+				// Directories created later are watched too
+				if err := addWatchDirs(watcher, event.Name); err != nil {
+					slog.Debug("not watching new path", "path", event.Name, "error", err)
+				}
+			}
+			if isWatchedFile(event.Name) {
+				slog.Debug("migration changed", "path", event.Name, "op", event.Op.String())
+				pending = time.After(watchDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("file watcher error", "error", err)
+		case <-pending:
+			pending = nil
+			w.refreshAndReport(ctx)
+		}
+	}
+}
+
+// addWatchDirs adds root and the directories below it to the watcher.
+// Migrations are discovered in subdirectories as well. A root that is a
+// plain file is ignored.
+func addWatchDirs(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		return watcher.Add(path)
+	})
+}
+
+// isWatchedFile reports whether a change to path may change the migrations.
+// Editor swap and backup files are ignored.
+func isWatchedFile(path string) bool {
+	base := filepath.Base(path)
+	return strings.HasSuffix(base, ".sql") && !strings.HasPrefix(base, ".")
+}
+
+// refreshAndReport refreshes the schema and reports a failure on errOut
+// without stopping the watcher
+func (w *schemaWatcher) refreshAndReport(ctx context.Context) {
+	if err := w.refresh(ctx); err != nil {
+		slog.Error("schema refresh failed", "error", err)
+		fmt.Fprintf(w.errOut, "refresh failed: %v\n", err)
+	}
+}
+
+// refresh resets the database, runs every migration again, or only the new
+// ones in incremental mode, and prints the schema, preceded by a separator with the time and, after the first run,
+// a summary of the changes since the previous schema
+func (w *schemaWatcher) refresh(ctx context.Context) error {
+	fmt.Fprintf(w.out, "\n=== SCHEMA REFRESH %s ===\n", w.now().Format(time.DateTime))
+
+	migrations, err := w.migrationReader.DiscoverMigrations(w.migrationDir)
+	if err != nil {
+		return fmt.Errorf("failed to parse migrations: %w", err)
+	}
+
+	var checksums []appliedMigration
+	if w.incremental {
+		if checksums, err = checksumMigrations(migrations); err != nil {
+			return fmt.Errorf("failed to parse migrations: %w", err)
+		}
+	}
+
+	db := w.dbManager.GetDB()
+	start, err := w.prepareDatabase(ctx, db, checksums)
+	if err != nil {
+		return err
+	}
+	w.runs++
+
+	if err := w.dbManager.RunMigrations(ctx, migrations[start:]); err != nil {
+		w.rebuild = true
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if w.incremental {
+		if err := w.ledger.Record(ctx, checksums[start:]); err != nil {
+			w.rebuild = true
+			return err
+		}
+	}
+
+	params := w.params
+	params.DB = db
+	params.ConnectionString = w.dbManager.GetConnectionString()
+	result, err := w.provider.ExtractSchema(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to extract schema: %w", err)
+	}
+
+	// pg_dump results carry no tables, the diff needs them
+	tables := result.Tables
+	if tables == nil {
+		if tables, err = providers.ExtractSchemaFromDB(db); err != nil {
+			return fmt.Errorf("failed to extract schema: %w", err)
+		}
+	}
+
+	output, err := renderCheckedResult(params, result, nil)
+	if err != nil {
+		return err
+	}
+
+	if w.hasPrevious {
+		fmt.Fprint(w.out, describeSchemaChanges(providers.DiffSchemas(w.previous, tables)))
+	}
+	fmt.Fprint(w.out, output)
+	w.previous, w.hasPrevious = tables, true
+	return nil
+}
+
+// prepareDatabase resets the database unless it is fresh and returns the
+// index of the first migration to run. In incremental mode the database is
+// kept when the migrations applied so far are unchanged, and only the new
+// ones run.
+func (w *schemaWatcher) prepareDatabase(ctx context.Context, db *sql.DB, checksums []appliedMigration) (int, error) {
+	if w.runs == 0 {
+		return 0, nil
+	}
+
+	if w.incremental && !w.rebuild {
+		applied, err := w.ledger.Applied(ctx)
+		if err != nil {
+			return 0, err
+		}
+		start, reason := pendingMigrations(checksums, applied)
+		if reason == "" {
+			slog.Info("applying new migrations", "applied", start, "new", len(checksums)-start)
+			return start, nil
+		}
+		slog.Warn("re-running all migrations", "reason", reason)
+	}
+
+	if err := w.reset(ctx, db); err != nil {
+		return 0, err
+	}
+	w.rebuild = false
+	return 0, nil
+}
+
+// describeSchemaChanges summarises a diff as one line per added (+),
+// modified (~) and removed (-) table; a modified table lists its added,
+// changed and removed columns
+func describeSchemaChanges(diff providers.SchemaDiff) string {
+	if diff.IsEmpty() {
+		return "No schema changes since the previous run\n"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Changes since the previous run:\n")
+	for _, table := range diff.AddedTables {
+		sb.WriteString(fmt.Sprintf("  + %s\n", table.Name))
+	}
+	for _, table := range diff.ModifiedTables {
+		var columns []string
+		for _, col := range table.AddedColumns {
+			columns = append(columns, "+"+col.Name)
+		}
+		for _, change := range table.ChangedColumns {
+			columns = append(columns, "~"+change.Name)
+		}
+		for _, col := range table.RemovedColumns {
+			columns = append(columns, "-"+col.Name)
+		}
+		if len(columns) > 0 {
+			sb.WriteString(fmt.Sprintf("  ~ %s (%s)\n", table.Name, strings.Join(columns, ", ")))
+		} else {
+			sb.WriteString(fmt.Sprintf("  ~ %s\n", table.Name))
+		}
+	}
+	for _, table := range diff.RemovedTables {
+		sb.WriteString(fmt.Sprintf("  - %s\n", table.Name))
+	}
+	return sb.String()
+}
+
+// resetDatabase drops every user schema and recreates an empty public
+// schema, so that migrations can run again on the same container. Roles are
+// shared by the cluster and survive the reset.
+func resetDatabase(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT nspname FROM pg_namespace
+		WHERE nspname NOT LIKE 'pg\_%' AND nspname <> 'information_schema'
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to list schemas: %w", err)
+	}
+	var schemas []string
+	for rows.Next() {
+		var schema string
+		if err := rows.Scan(&schema); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to list schemas: %w", err)
+		}
+		schemas = append(schemas, schema)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list schemas: %w", err)
+	}
+
+	for _, schema := range schemas {
+		if _, err := db.ExecContext(ctx, "DROP SCHEMA "+pq.QuoteIdentifier(schema)+" CASCADE"); err != nil {
+			return fmt.Errorf("failed to reset database: %w", err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, "CREATE SCHEMA public"); err != nil {
+		return fmt.Errorf("failed to reset database: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alc6/mig2schema/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsWatchedFile(t *testing.T) {
+	assert.True(t, isWatchedFile("migrations/001_create_users.up.sql"))
+	assert.True(t, isWatchedFile("migrations/001_create_users.down.sql"))
+	assert.False(t, isWatchedFile("migrations/.001_create_users.up.sql.swp"))
+	assert.False(t, isWatchedFile("migrations/.#001_create_users.up.sql"))
+	assert.False(t, isWatchedFile("migrations/README.md"))
+	assert.False(t, isWatchedFile("migrations/001_create_users.up.sql~"))
+}
+
+func TestDescribeSchemaChanges(t *testing.T) {
+	users := providers.Table{Name: "users", Columns: []providers.Column{
+		{Name: "id", DataType: "integer"},
+		{Name: "email", DataType: "text"},
+		{Name: "legacy", DataType: "text"},
+	}}
+	changedUsers := providers.Table{Name: "users", Columns: []providers.Column{
+		{Name: "id", DataType: "integer"},
+		{Name: "email", DataType: "character varying"},
+		{Name: "name", DataType: "text"},
+	}}
+	indexedUsers := users
+	indexedUsers.Indexes = []providers.Index{{Name: "idx_users_email", Columns: []string{"email"}}}
+	posts := providers.Table{Name: "posts", Columns: []providers.Column{{Name: "id", DataType: "integer"}}}
+	tags := providers.Table{Name: "tags", Columns: []providers.Column{{Name: "id", DataType: "integer"}}}
+
+	assert.Equal(t, "No schema changes since the previous run\n",
+		describeSchemaChanges(providers.DiffSchemas([]providers.Table{users}, []providers.Table{users})))
+
+	assert.Equal(t, "Changes since the previous run:\n"+
+		"  + posts\n"+
+		"  ~ users (+name, ~email, -legacy)\n"+
+		"  - tags\n",
+		describeSchemaChanges(providers.DiffSchemas([]providers.Table{users, tags}, []providers.Table{changedUsers, posts})))
+
+	assert.Equal(t, "Changes since the previous run:\n  ~ users\n",
+		describeSchemaChanges(providers.DiffSchemas([]providers.Table{users}, []providers.Table{indexedUsers})))
+}
+
+func TestSchemaWatcherRefresh(t *testing.T) {
+	defer resetCommand()
+
+	tables := []providers.Table{{Name: "users", Columns: []providers.Column{{Name: "id", DataType: "integer", IsPrimaryKey: true}}}}
+	provider := &MockSchemaProvider{
+		ExtractSchemaFunc: func(ctx context.Context, params providers.ExtractParams) (*providers.SchemaResult, error) {
+			return &providers.SchemaResult{Schema: providers.Schema{Tables: tables}, Format: params.Format}, nil
+		},
+	}
+
+	var migrationRuns int
+	dbManager := &MockDatabaseManager{
+		RunMigrationsFunc: func(ctx context.Context, migrations []Migration) error {
+			migrationRuns++
+			if migrationRuns == 3 {
+				return errors.New("syntax error")
+			}
+			return nil
+		},
+	}
+
+	resets := 0
+	var out, errOut bytes.Buffer
+	w := &schemaWatcher{
+		migrationDir: "migrations",
+		migrationReader: &MockMigrationReader{
+			DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+				return []Migration{{Name: "001_create_users"}}, nil
+			},
+		},
+		dbManager: dbManager,
+		provider:  provider,
+		params:    providers.ExtractParams{Format: providers.FormatInfo},
+		out:       &out,
+		errOut:    &errOut,
+		now:       func() time.Time { return time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC) },
+		reset: func(ctx context.Context, db *sql.DB) error {
+			resets++
+			return nil
+		},
+	}
+
+	ctx := context.Background()
+	require.NoError(t, w.refresh(ctx))
+	assert.Equal(t, 0, resets, "the first run starts from an empty database")
+	assert.Contains(t, out.String(), "=== SCHEMA REFRESH 2024-05-01 12:30:00 ===")
+	assert.Contains(t, out.String(), "Table: users")
+	assert.NotContains(t, out.String(), "previous run")
+
+	out.Reset()
+	tables = append(tables, providers.Table{Name: "posts", Columns: []providers.Column{{Name: "id", DataType: "integer"}}})
+	require.NoError(t, w.refresh(ctx))
+	assert.Equal(t, 1, resets)
+	assert.Contains(t, out.String(), "Changes since the previous run:\n  + posts\n")
+	assert.Contains(t, out.String(), "Table: posts")
+
+	// A failed run keeps the last good schema to compare with
+	out.Reset()
+	w.refreshAndReport(ctx)
+	assert.Equal(t, 2, resets)
+	assert.Contains(t, errOut.String(), "refresh failed: failed to run migrations: syntax error")
+	assert.NotContains(t, out.String(), "refresh failed")
+
+	out.Reset()
+	require.NoError(t, w.refresh(ctx))
+	assert.Contains(t, out.String(), "No schema changes since the previous run")
+}
+
+// memoryLedger is a migrationLedger kept in memory
+type memoryLedger struct {
+	applied []appliedMigration
+}
+
+func (l *memoryLedger) Applied(ctx context.Context) ([]appliedMigration, error) {
+	return l.applied, nil
+}
+
+func (l *memoryLedger) Record(ctx context.Context, migrations []appliedMigration) error {
+	l.applied = append(l.applied, migrations...)
+	return nil
+}
+
+func TestSchemaWatcherIncremental(t *testing.T) {
+	defer resetCommand()
+
+	provider := &MockSchemaProvider{
+		ExtractSchemaFunc: func(ctx context.Context, params providers.ExtractParams) (*providers.SchemaResult, error) {
+			return &providers.SchemaResult{Schema: providers.Schema{Tables: []providers.Table{}}, Format: params.Format}, nil
+		},
+	}
+
+	var ran [][]string
+	failNext := false
+	dbManager := &MockDatabaseManager{
+		RunMigrationsFunc: func(ctx context.Context, migrations []Migration) error {
+			var names []string
+			for _, migration := range migrations {
+				names = append(names, migration.Name)
+			}
+			ran = append(ran, names)
+			if failNext {
+				failNext = false
+				return errors.New("syntax error")
+			}
+			return nil
+		},
+	}
+
+	migrations := []Migration{{Name: "001_create_users", UpSQL: []byte("create table users (id int);")}}
+	resets := 0
+	ledger := &memoryLedger{}
+	w := &schemaWatcher{
+		migrationDir: "migrations",
+		migrationReader: &MockMigrationReader{
+			DiscoverMigrationsFunc: func(dir string) ([]Migration, error) {
+				return migrations, nil
+			},
+		},
+		dbManager: dbManager,
+		provider:  provider,
+		params:    providers.ExtractParams{Format: providers.FormatInfo},
+		out:       &bytes.Buffer{},
+		now:       time.Now,
+		reset: func(ctx context.Context, db *sql.DB) error {
+			resets++
+			ledger.applied = nil
+			return nil
+		},
+		incremental: true,
+		ledger:      ledger,
+	}
+
+	ctx := context.Background()
+	require.NoError(t, w.refresh(ctx))
+	assert.Equal(t, [][]string{{"001_create_users"}}, ran)
+
+	// Only the new migration runs
+	migrations = append(migrations, Migration{Name: "002_create_posts", UpSQL: []byte("create table posts (id int);")})
+	require.NoError(t, w.refresh(ctx))
+	assert.Equal(t, []string{"002_create_posts"}, ran[1])
+	assert.Equal(t, 0, resets)
+	assert.Len(t, ledger.applied, 2)
+
+	// Editing an applied migration rebuilds the database
+	migrations[0].UpSQL = []byte("create table users (id bigint);")
+	require.NoError(t, w.refresh(ctx))
+	assert.Equal(t, []string{"001_create_users", "002_create_posts"}, ran[2])
+	assert.Equal(t, 1, resets)
+
+	// So does a failed run, on the next refresh
+	migrations = append(migrations, Migration{Name: "003_broken", UpSQL: []byte("create tabel tags (id int);")})
+	failNext = true
+	require.Error(t, w.refresh(ctx))
+	assert.Equal(t, []string{"003_broken"}, ran[3])
+	require.NoError(t, w.refresh(ctx))
+	assert.Equal(t, []string{"001_create_users", "002_create_posts", "003_broken"}, ran[4])
+	assert.Equal(t, 2, resets)
+}
+
+func TestMigrationToSchemaResetDatabase(t *testing.T) {
+	if testing.Short() || !isDockerAvailable() {
+		t.Skip("docker not available, skipping database reset test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	manager := NewPostgreSQLManager("postgres:16-alpine")
+	require.NoError(t, manager.Setup(ctx))
+	defer manager.Close(ctx)
+
+	migrations := []Migration{{
+		Name:  "001_create_users",
+		UpSQL: []byte(`create schema app; create table app.accounts (id int); create table users (id int primary key);`),
+	}}
+	require.NoError(t, manager.RunMigrations(ctx, migrations))
+	require.NoError(t, resetDatabase(ctx, manager.GetDB()))
+
+	tables, err := providers.ExtractSchemaFromDB(manager.GetDB())
+	require.NoError(t, err)
+	assert.Empty(t, tables)
+
+	// The same migrations run again on the reset database
+	require.NoError(t, manager.RunMigrations(ctx, migrations))
+}